@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -25,11 +26,33 @@ type Agent struct {
 	Machine  machine.Machine
 	ttl      time.Duration
 
-	cache *agentCache
+	cache      *agentCache
+	handoff    *handoff
+	health     *healthMonitor
+	usage      *usageSampler
+	overuse    *overuseMonitor
+	starts     *startThrottle
+	envs       environmentTracker
+	hashes     hashTracker
+	signatures signatureVerifier
+
+	// ClusterKey decrypts the secret configuration values passed to
+	// Units through their FleetEnvironment. Without it, Units using
+	// secret values cannot be loaded.
+	ClusterKey []byte
+
+	// TrustedKeys, if any, are the keys the unit file of a Unit must be
+	// signed with for the Agent to run it.
+	TrustedKeys []*ecdsa.PublicKey
+
+	// Capacity, if set, provides the CPU and memory capacity of the local
+	// machine Units are admitted against, in place of the capacity last
+	// published by the Machine.
+	Capacity *CapacityProvider
 }
 
 func New(mgr unit.UnitManager, uGen *unit.UnitStateGenerator, reg registry.Registry, mach machine.Machine, ttl time.Duration) *Agent {
-	return &Agent{reg, mgr, uGen, mach, ttl, &agentCache{}}
+	return &Agent{reg, mgr, uGen, mach, ttl, &agentCache{}, nil, newHealthMonitor(), newUsageSampler(), nil, nil, environmentTracker{}, hashTracker{}, signatureVerifier{}, nil, nil, nil}
 }
 
 func (a *Agent) MarshalJSON() ([]byte, error) {
@@ -52,7 +75,11 @@ func (a *Agent) heartbeatJobs(ttl time.Duration, stop chan bool) {
 		machID := a.Machine.State().ID
 		launched := a.cache.launchedJobs()
 		for _, j := range launched {
-			go a.registry.UnitHeartbeat(j, machID, ttl)
+			go func(j string) {
+				start := time.Now()
+				a.registry.UnitHeartbeat(j, machID, ttl)
+				metricHeartbeatDuration.ObserveSince(start)
+			}(j)
 		}
 	}
 
@@ -73,12 +100,35 @@ func (a *Agent) heartbeatJobs(ttl time.Duration, stop chan bool) {
 func (a *Agent) loadUnit(u *job.Unit) error {
 	a.cache.setTargetState(u.Name, job.JobStateLoaded)
 	a.uGen.Subscribe(u.Name)
-	return a.um.Load(u.Name, u.Unit)
+	return a.writeUnit(u)
+}
+
+// writeUnit writes the unit file of the given Unit along with its drop-in
+// and environment, remembering the Hash of the unit file written
+func (a *Agent) writeUnit(u *job.Unit) error {
+	if err := a.um.SetDropIn(u.Name, u.ReservationDropIn()); err != nil {
+		return err
+	}
+	if err := a.setEnvironment(u); err != nil {
+		return fmt.Errorf("failed passing environment to Unit(%s): %v", u.Name, err)
+	}
+	var err error
+	if rendered := a.renderUnit(u); rendered != nil {
+		err = a.um.LoadRendered(u.Name, u.Unit, *rendered)
+	} else {
+		err = a.um.Load(u.Name, u.Unit)
+	}
+	if err == nil {
+		a.hashes.set(u.Name, u.Unit.Hash())
+	}
+	return err
 }
 
 func (a *Agent) unloadUnit(unitName string) {
 	a.registry.ClearUnitHeartbeat(unitName)
 	a.cache.dropTargetState(unitName)
+	a.envs.forget(unitName)
+	a.hashes.forget(unitName)
 
 	a.um.TriggerStop(unitName)
 
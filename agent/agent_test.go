@@ -44,6 +44,32 @@ func newTestJobWithXFleetValues(t *testing.T, metadata string) *job.Job {
 	return newNamedTestJobWithXFleetValues(t, "pong.service", metadata)
 }
 
+func TestAgentLoadUnitDropIn(t *testing.T) {
+	uManager := unit.NewFakeUnitManager()
+	usGenerator := unit.NewUnitStateGenerator(uManager)
+	fReg := registry.NewFakeRegistry()
+	mach := &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX"}}
+	a := New(uManager, usGenerator, fReg, mach, time.Second)
+
+	u := newTestUnitFromUnitContents(t, "foo.service", "[X-Fleet]\nMemoryReservation=512\nEnforceReservations=hard")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	d := uManager.DropIn("foo.service")
+	if want := "[Service]\nMemoryLow=512M\nMemoryMax=512M\n"; d == nil || d.String() != want {
+		t.Fatalf("Unexpected drop-in %v, want %q", d, want)
+	}
+
+	// reloading the Unit without enforcement removes the drop-in
+	u = newTestUnitFromUnitContents(t, "foo.service", "[X-Fleet]\nMemoryReservation=512")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	if d := uManager.DropIn("foo.service"); d != nil {
+		t.Errorf("Expected drop-in to be removed, got %v", d)
+	}
+}
+
 func TestAgentLoadUnloadUnit(t *testing.T) {
 	uManager := unit.NewFakeUnitManager()
 	usGenerator := unit.NewUnitStateGenerator(uManager)
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+// AnnotateUnitStates adds what only the Agent knows about its Units, i.e.
+// their health and resource usage, to the UnitStates of the heartbeats
+// received from in before passing them on to the returned channel. Once
+// stop is closed, the Agent's health checks are stopped as well.
+func (a *Agent) AnnotateUnitStates(in <-chan *unit.UnitStateHeartbeat, stop chan bool) <-chan *unit.UnitStateHeartbeat {
+	out := make(chan *unit.UnitStateHeartbeat)
+	go func() {
+		defer a.health.update(nil)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case bt := <-in:
+				a.annotate(bt, time.Now())
+				select {
+				case out <- bt:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func (a *Agent) annotate(bt *unit.UnitStateHeartbeat, now time.Time) {
+	if bt.State == nil {
+		a.usage.forget(bt.Name)
+		return
+	}
+
+	bt.State.Health, _ = a.unitHealth(bt.Name)
+	bt.State.UsedCPUUnits, bt.State.UsedMemory = a.usage.sample(bt.Name, now)
+
+	if reason := a.overuse.observe(bt.Name, bt.State.UsedCPUUnits, bt.State.UsedMemory, now); reason != "" {
+		log.Warningf("Unit(%s) exceeds its reservation: %s", bt.Name, reason)
+		metricOverReservation.Inc()
+		ev := registry.ClusterEvent{Type: registry.EventUnitOverReservation, UnitName: bt.Name, MachineID: bt.State.MachineID, Reason: reason}
+		if err := a.registry.RecordEvent(ev); err != nil {
+			log.Errorf("Failed recording %s event: %v", ev.Type, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+// handleBatchUnits records how the runs of batch Units scheduled to the
+// local machine ended once they finish, so the engine neither restarts
+// nor reschedules those that completed successfully.
+func (ar *AgentReconciler) handleBatchUnits(a *Agent, dState *AgentState) {
+	watched := pkg.NewUnsafeSet()
+	for name, u := range dState.Units {
+		if u.TargetState == job.JobStateLaunched && !u.IsGlobal() && u.IsBatch() {
+			watched.Add(name)
+		}
+	}
+
+	ar.bTracker.forget(watched)
+	if watched.Length() == 0 {
+		return
+	}
+
+	states, err := a.um.GetUnitStates(watched)
+	if err != nil {
+		log.Errorf("Failed fetching states of batch Units: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, name := range watched.Values() {
+		us := states[name]
+		if !ar.bTracker.finished(name, us, now) {
+			continue
+		}
+
+		uc := batchCompletion(a.um, name, us, now)
+		uc.MachineID = dState.MState.ID
+		if err := ar.reg.SaveUnitCompletion(uc); err != nil {
+			log.Errorf("Failed saving completion of Unit(%s): %v", name, err)
+			continue
+		}
+
+		log.Infof("Unit(%s) completed on Machine(%s) with result %s, exit code %d", name, uc.MachineID, uc.Result, uc.ExitCode)
+		ar.bTracker.reported.Add(name)
+	}
+}
+
+// batchCompletion describes how the finished run of the named Unit ended,
+// as far as systemd recorded it. Units whose exit is not recorded, like
+// those that are not services, succeeded unless they failed.
+func batchCompletion(um unit.UnitManager, name string, us *unit.UnitState, now time.Time) registry.UnitCompletion {
+	uc := registry.UnitCompletion{Name: name, Finished: now}
+
+	ue, err := um.GetUnitExit(name)
+	if err != nil {
+		log.V(1).Infof("Failed fetching exit of Unit(%s): %v", name, err)
+	}
+	if ue != nil && ue.Result != "" {
+		uc.Result, uc.ExitCode, uc.Started = ue.Result, ue.Status, ue.Started
+		if !ue.Finished.IsZero() {
+			uc.Finished = ue.Finished
+		}
+		return uc
+	}
+
+	uc.Result = "success"
+	if us.ActiveState == unitActiveStateFailed {
+		uc.Result = "failed"
+	}
+	return uc
+}
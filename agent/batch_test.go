@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+// exitUnitManager reports the given exits of the loaded units
+type exitUnitManager struct {
+	*activeStateUnitManager
+	exits map[string]*unit.UnitExit
+}
+
+func (eum *exitUnitManager) GetUnitExit(name string) (*unit.UnitExit, error) {
+	return eum.exits[name], nil
+}
+
+func TestHandleBatchUnits(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	started := time.Unix(1400000000, 0)
+	eum := &exitUnitManager{
+		&activeStateUnitManager{unit.NewFakeUnitManager(), map[string]string{
+			"running.service": "active",
+			"failed.service":  "failed",
+			"done.service":    "inactive",
+			"target.target":   "inactive",
+			"foo.service":     "inactive",
+		}},
+		map[string]*unit.UnitExit{
+			"failed.service": &unit.UnitExit{Result: "exit-code", Status: 3, Started: started, Finished: started.Add(time.Minute)},
+			"done.service":   &unit.UnitExit{Result: "success", Started: started, Finished: started.Add(time.Hour)},
+		},
+	}
+	a := &Agent{um: eum, ttl: time.Minute}
+	ar := NewReconciler(reg, nil)
+
+	batch := newUF(t, "[X-Fleet]\nBatch=true")
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "running.service", TargetState: job.JobStateLaunched, Unit: batch},
+		&job.Unit{Name: "failed.service", TargetState: job.JobStateLaunched, Unit: batch},
+		&job.Unit{Name: "done.service", TargetState: job.JobStateLaunched, Unit: batch},
+		&job.Unit{Name: "target.target", TargetState: job.JobStateLaunched, Unit: batch},
+		&job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched},
+	} {
+		dState.Units[u.Name] = u
+		eum.Load(u.Name, u.Unit)
+	}
+
+	// done and target were scheduled long enough ago to have started
+	ar.bTracker.scheduled["done.service"] = time.Now().Add(-runStartGrace)
+	ar.bTracker.scheduled["target.target"] = time.Now().Add(-runStartGrace)
+	ar.handleBatchUnits(a, dState)
+
+	completions, err := reg.UnitCompletions()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := make(map[string]registry.UnitCompletion)
+	for _, uc := range completions {
+		if uc.MachineID != "XXX" {
+			t.Errorf("Unexpected machine %q of completion of %s", uc.MachineID, uc.Name)
+		}
+		got[uc.Name] = uc
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 completions, got %v", got)
+	}
+	if uc := got["failed.service"]; uc.Succeeded() || uc.ExitCode != 3 || uc.Duration() != time.Minute {
+		t.Errorf("Unexpected completion of failed.service: %#v", uc)
+	}
+	if uc := got["done.service"]; !uc.Succeeded() || uc.Duration() != time.Hour {
+		t.Errorf("Unexpected completion of done.service: %#v", uc)
+	}
+	if uc := got["target.target"]; !uc.Succeeded() {
+		t.Errorf("Unexpected completion of target.target: %#v", uc)
+	}
+}
+
+func TestDesiredAgentStateBatchCompleted(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{
+			Name:            "done.service",
+			Unit:            newUF(t, "[X-Fleet]\nBatch=true"),
+			TargetMachineID: "this_machine",
+		},
+		job.Job{
+			Name:            "failed.service",
+			Unit:            newUF(t, "[X-Fleet]\nBatch=true"),
+			TargetMachineID: "this_machine",
+		},
+		job.Job{
+			Name:            "pending.service",
+			Unit:            newUF(t, "[X-Fleet]\nBatch=true"),
+			TargetMachineID: "this_machine",
+		},
+	})
+	reg.SaveUnitCompletion(registry.UnitCompletion{Name: "done.service", Result: "success"})
+	reg.SaveUnitCompletion(registry.UnitCompletion{Name: "failed.service", Result: "exit-code", ExitCode: 1})
+
+	a := &Agent{
+		Machine: &machine.FakeMachine{
+			MachineState: machine.MachineState{ID: "this_machine"},
+		},
+	}
+	as, _, err := desiredAgentState(a, reg, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// only successfully completed units are never run again
+	var names []string
+	for _, name := range []string{"done.service", "failed.service", "pending.service"} {
+		if _, ok := as.Units[name]; ok {
+			names = append(names, name)
+		}
+	}
+	want := []string{"failed.service", "pending.service"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Unexpected desired Units: got %v, want %v", names, want)
+	}
+}
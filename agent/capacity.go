@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+)
+
+const (
+	// DefaultMemoryRefreshInterval is the interval at which a
+	// CapacityProvider refreshes the memory of the local machine by default
+	DefaultMemoryRefreshInterval = 5 * time.Second
+
+	// size of the buffer kernel uevents are read into
+	ueventBufferSize = 4096
+)
+
+// A CapacityProvider caches the capacity of the local machine the Agent
+// admits Units against, so admission does not read /proc and the cgroups
+// of fleet. The CPU capacity is determined once, and again whenever the
+// kernel reports a CPU going on- or offline. The memory of the machine is
+// refreshed every interval.
+type CapacityProvider struct {
+	root     string
+	cpu      machine.CPUCapacity
+	interval time.Duration
+
+	mutex     sync.RWMutex
+	cpuUnits  int
+	meminfo   machine.Meminfo
+	refreshed bool
+}
+
+// NewCapacityProvider returns a CapacityProvider determining the CPU
+// capacity of the local machine with cpu and reading its memory from the
+// meminfo file below root every interval
+func NewCapacityProvider(root string, cpu machine.CPUCapacity, interval time.Duration) *CapacityProvider {
+	cp := &CapacityProvider{root: root, cpu: cpu, interval: interval}
+	cp.refreshCPU()
+	cp.refreshMemory()
+	return cp
+}
+
+// Total returns the CPU and memory capacity of the local machine, leaving
+// zero what could not be determined
+func (cp *CapacityProvider) Total() resource.ResourceTuple {
+	cp.mutex.RLock()
+	defer cp.mutex.RUnlock()
+	return resource.ResourceTuple{Cores: cp.cpuUnits, Memory: cp.meminfo.Total}
+}
+
+// MemoryAvailable returns the memory (in MB) the kernel estimates to be
+// available to new processes on the local machine, and whether it is known
+func (cp *CapacityProvider) MemoryAvailable() (int, bool) {
+	cp.mutex.RLock()
+	defer cp.mutex.RUnlock()
+	return cp.meminfo.Available, cp.refreshed
+}
+
+// Run refreshes the cached memory every interval and the CPU capacity on
+// CPU hotplug events until stop is closed
+func (cp *CapacityProvider) Run(stop chan bool) {
+	hotplug := make(chan struct{}, 1)
+	if err := watchCPUHotplug(hotplug, stop); err != nil {
+		log.Warningf("Unable to watch for CPU hotplug events, CPU capacity will not be refreshed: %v", err)
+	}
+
+	ticker := time.NewTicker(cp.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			cp.refreshMemory()
+		case <-hotplug:
+			log.Infof("CPU hotplugged, refreshing CPU capacity")
+			cp.refreshCPU()
+		}
+	}
+}
+
+func (cp *CapacityProvider) refreshCPU() {
+	units, err := cp.cpu.CPUUnits()
+	if err != nil {
+		log.Warningf("Unable to determine CPU capacity: %v", err)
+		return
+	}
+	cp.mutex.Lock()
+	cp.cpuUnits = units
+	cp.mutex.Unlock()
+}
+
+func (cp *CapacityProvider) refreshMemory() {
+	mi, err := machine.ReadLocalMeminfo(cp.root)
+	if err != nil {
+		log.Warningf("Unable to determine local memory: %v", err)
+		return
+	}
+	cp.mutex.Lock()
+	cp.meminfo = mi
+	cp.refreshed = true
+	cp.mutex.Unlock()
+}
+
+// overlay returns the given resources with the CPU and memory capacity
+// replaced by those cached, where known
+func (cp *CapacityProvider) overlay(res resource.ResourceTuple) resource.ResourceTuple {
+	total := cp.Total()
+	if total.Cores > 0 {
+		res.Cores = total.Cores
+	}
+	if total.Memory > 0 {
+		res.Memory = total.Memory
+	}
+	return res
+}
+
+// watchCPUHotplug signals the given channel, without blocking, whenever the
+// kernel reports a CPU added, removed, or brought on- or offline, until
+// stop is closed
+func watchCPUHotplug(hotplug chan<- struct{}, stop chan bool) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return err
+	}
+	// uevents are broadcast to the first multicast group
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+	sock := os.NewFile(uintptr(fd), "uevent")
+
+	go func() {
+		<-stop
+		sock.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, ueventBufferSize)
+		for {
+			n, err := sock.Read(buf)
+			if err != nil {
+				select {
+				case <-stop:
+				default:
+					log.Errorf("Stopped watching for CPU hotplug events: %v", err)
+				}
+				return
+			}
+			if !isCPUHotplugUevent(buf[:n]) {
+				continue
+			}
+			select {
+			case hotplug <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return nil
+}
+
+// isCPUHotplugUevent determines whether the given kernel uevent, a header
+// followed by NUL-separated KEY=VALUE pairs, reports a change to a CPU
+func isCPUHotplugUevent(msg []byte) bool {
+	var action, subsystem string
+	for _, field := range bytes.Split(msg, []byte{0}) {
+		kv := bytes.SplitN(field, []byte("="), 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch string(kv[0]) {
+		case "ACTION":
+			action = string(kv[1])
+		case "SUBSYSTEM":
+			subsystem = string(kv[1])
+		}
+	}
+	if subsystem != "cpu" {
+		return false
+	}
+	switch action {
+	case "add", "remove", "online", "offline":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+)
+
+func TestCapacityProvider(t *testing.T) {
+	root, err := ioutil.TempDir(os.TempDir(), "fleet-")
+	if err != nil {
+		t.Fatalf("Failed creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(root)
+	if err := os.MkdirAll(filepath.Join(root, "proc"), os.FileMode(0755)); err != nil {
+		t.Fatalf("Failed creating proc dir: %v", err)
+	}
+	meminfo := filepath.Join(root, "proc", "meminfo")
+	writeMeminfo := func(contents string) {
+		if err := ioutil.WriteFile(meminfo, []byte(contents), os.FileMode(0644)); err != nil {
+			t.Fatalf("Failed writing fake meminfo file: %v", err)
+		}
+	}
+
+	writeMeminfo("MemTotal:        2048000 kB\nMemFree:          102400 kB\nMemAvailable:    1024000 kB\n")
+	cp := NewCapacityProvider(root, machine.StaticCPUCapacity(400), DefaultMemoryRefreshInterval)
+	if got, want := cp.Total(), (resource.ResourceTuple{Cores: 400, Memory: 2000}); got != want {
+		t.Errorf("Total returned %v, want %v", got, want)
+	}
+	if got, ok := cp.MemoryAvailable(); !ok || got != 1000 {
+		t.Errorf("MemoryAvailable returned %d, %t, want 1000, true", got, ok)
+	}
+
+	// the cached memory is kept until refreshed
+	writeMeminfo("MemTotal:        2048000 kB\nMemAvailable:     512000 kB\n")
+	if got, _ := cp.MemoryAvailable(); got != 1000 {
+		t.Errorf("MemoryAvailable returned %d before refreshing, want 1000", got)
+	}
+	cp.refreshMemory()
+	if got, _ := cp.MemoryAvailable(); got != 500 {
+		t.Errorf("MemoryAvailable returned %d after refreshing, want 500", got)
+	}
+
+	// failed refreshes keep the last known memory
+	writeMeminfo("")
+	cp.refreshMemory()
+	if got, _ := cp.MemoryAvailable(); got != 500 {
+		t.Errorf("MemoryAvailable returned %d after failed refresh, want 500", got)
+	}
+
+	got := cp.overlay(resource.ResourceTuple{Cores: 100, Memory: 1024, Disk: 4096})
+	if want := (resource.ResourceTuple{Cores: 400, Memory: 2000, Disk: 4096}); got != want {
+		t.Errorf("overlay returned %v, want %v", got, want)
+	}
+}
+
+func TestCapacityProviderUnknownMemory(t *testing.T) {
+	cp := NewCapacityProvider(filepath.Join(os.TempDir(), "fleet-missing"), machine.StaticCPUCapacity(200), DefaultMemoryRefreshInterval)
+	if _, ok := cp.MemoryAvailable(); ok {
+		t.Errorf("Expected unknown available memory")
+	}
+	got := cp.overlay(resource.ResourceTuple{Cores: 100, Memory: 1024})
+	if want := (resource.ResourceTuple{Cores: 200, Memory: 1024}); got != want {
+		t.Errorf("overlay returned %v, want %v", got, want)
+	}
+}
+
+func TestIsCPUHotplugUevent(t *testing.T) {
+	for i, tt := range []struct {
+		msg  string
+		want bool
+	}{
+		{"online@/devices/system/cpu/cpu1\x00ACTION=online\x00DEVPATH=/devices/system/cpu/cpu1\x00SUBSYSTEM=cpu\x00SEQNUM=2051", true},
+		{"offline@/devices/system/cpu/cpu1\x00ACTION=offline\x00SUBSYSTEM=cpu", true},
+		{"add@/devices/system/cpu/cpu4\x00ACTION=add\x00SUBSYSTEM=cpu", true},
+		{"change@/devices/system/cpu/cpu1\x00ACTION=change\x00SUBSYSTEM=cpu", false},
+		{"add@/devices/virtual/net/veth0\x00ACTION=add\x00SUBSYSTEM=net", false},
+		{"", false},
+	} {
+		if got := isCPUHotplugUevent([]byte(tt.msg)); got != tt.want {
+			t.Errorf("case %d: isCPUHotplugUevent returned %t, want %t", i, got, tt.want)
+		}
+	}
+}
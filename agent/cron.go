@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+)
+
+// handleCronRuns reports the results of the runs of scheduled template
+// Units that have finished on the local machine, so the engine adds them
+// to the run history.
+func (ar *AgentReconciler) handleCronRuns(a *Agent, dState *AgentState) {
+	watched := pkg.NewUnsafeSet()
+	for name, u := range dState.Units {
+		if u.TargetState == job.JobStateLaunched && !u.IsGlobal() && u.CronSchedule() != nil {
+			watched.Add(name)
+		}
+	}
+
+	ar.cTracker.forget(watched)
+	if watched.Length() == 0 {
+		return
+	}
+
+	states, err := a.um.GetUnitStates(watched)
+	if err != nil {
+		log.Errorf("Failed fetching states of scheduled runs: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, name := range watched.Values() {
+		us := states[name]
+		if !ar.cTracker.finished(name, us, now) {
+			continue
+		}
+
+		result := registry.CronRunSucceeded
+		if us.ActiveState == unitActiveStateFailed {
+			result = registry.CronRunFailed
+		}
+		res := registry.CronRunResult{
+			Name:      name,
+			MachineID: dState.MState.ID,
+			Finished:  now,
+			Result:    result,
+		}
+		if err := ar.reg.ReportCronRunResult(res); err != nil {
+			log.Errorf("Failed reporting result of run Unit(%s): %v", name, err)
+			continue
+		}
+
+		log.Infof("Reported run Unit(%s) as %s on Machine(%s)", name, result, dState.MState.ID)
+		ar.cTracker.reported.Add(name)
+	}
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+// activeStateUnitManager reports the loaded units in the given active states
+type activeStateUnitManager struct {
+	*unit.FakeUnitManager
+	active map[string]string
+}
+
+func (aum *activeStateUnitManager) GetUnitStates(filter pkg.Set) (map[string]*unit.UnitState, error) {
+	states, err := aum.FakeUnitManager.GetUnitStates(filter)
+	for name, us := range states {
+		us.ActiveState = aum.active[name]
+	}
+	return states, err
+}
+
+func TestHandleCronRuns(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	aum := &activeStateUnitManager{unit.NewFakeUnitManager(), map[string]string{
+		"backup@1.service": "active",
+		"backup@2.service": "failed",
+		"backup@3.service": "inactive",
+		"backup@4.service": "inactive",
+		"foo.service":      "inactive",
+	}}
+	a := &Agent{um: aum, ttl: time.Minute}
+	ar := NewReconciler(reg, nil)
+
+	sched := newUF(t, "[X-Fleet]\nSchedule=0 3 * * *")
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "backup@1.service", TargetState: job.JobStateLaunched, Unit: sched},
+		&job.Unit{Name: "backup@2.service", TargetState: job.JobStateLaunched, Unit: sched},
+		&job.Unit{Name: "backup@3.service", TargetState: job.JobStateLaunched, Unit: sched},
+		&job.Unit{Name: "backup@4.service", TargetState: job.JobStateLaunched, Unit: sched},
+		&job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched},
+	} {
+		dState.Units[u.Name] = u
+		aum.Load(u.Name, u.Unit)
+	}
+
+	// backup@4 was scheduled long enough ago to have started
+	ar.cTracker.scheduled["backup@4.service"] = time.Now().Add(-runStartGrace)
+	ar.handleCronRuns(a, dState)
+	assertCronResults(t, reg, map[string]string{
+		"backup@2.service": registry.CronRunFailed,
+		"backup@4.service": registry.CronRunSucceeded,
+	})
+
+	// runs seen running before are finished once inactive
+	aum.active["backup@1.service"] = "inactive"
+	ar.handleCronRuns(a, dState)
+	assertCronResults(t, reg, map[string]string{
+		"backup@1.service": registry.CronRunSucceeded,
+		"backup@2.service": registry.CronRunFailed,
+		"backup@4.service": registry.CronRunSucceeded,
+	})
+}
+
+func assertCronResults(t *testing.T, reg registry.Registry, want map[string]string) {
+	results, err := reg.CronRunResults()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, res := range results {
+		got[res.Name] = res.Result
+		if res.MachineID != "XXX" {
+			t.Errorf("Unexpected machine %q of result of %s", res.MachineID, res.Name)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected results: got %v, want %v", got, want)
+	}
+}
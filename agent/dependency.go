@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	// active state systemd reports for running units
+	unitActiveStateActive = "active"
+)
+
+// DependencyState returns the names of the Units with target state
+// launched and the names of the Units reported active on any machine,
+// against which FleetRequires and FleetAfter dependencies are checked.
+func DependencyState(units []job.Unit, states []*unit.UnitState) (launched, active pkg.Set) {
+	launched = pkg.NewUnsafeSet()
+	for _, u := range units {
+		if u.TargetState == job.JobStateLaunched {
+			launched.Add(u.Name)
+		}
+	}
+
+	active = pkg.NewUnsafeSet()
+	for _, us := range states {
+		if us != nil && us.ActiveState == unitActiveStateActive {
+			active.Add(us.UnitName)
+		}
+	}
+	return
+}
+
+// holdUnmetDependencies keeps the Units in the desired state of an agent
+// from being started until the Units they depend on are active somewhere
+// in the cluster. Such Units are loaded, but not launched. Units already
+// running locally are left alone.
+func holdUnmetDependencies(reg registry.Registry, dState *AgentState, cState unitStates) {
+	var held []*job.Unit
+	for name, u := range dState.Units {
+		if u.TargetState != job.JobStateLaunched || !u.HasDependencies() || cState[name] == job.JobStateLaunched {
+			continue
+		}
+		held = append(held, u)
+	}
+	if len(held) == 0 {
+		return
+	}
+
+	units, err := reg.Units()
+	if err != nil {
+		log.Errorf("Failed fetching Units from Registry: %v", err)
+		return
+	}
+	states, err := reg.UnitStates()
+	if err != nil {
+		log.Errorf("Failed fetching Unit states from Registry: %v", err)
+		return
+	}
+	launched, active := DependencyState(units, states)
+
+	for _, u := range held {
+		reason := u.UnmetDependency(launched, active)
+		if reason == "" {
+			continue
+		}
+		log.V(1).Infof("Agent holding start of Unit(%s): %s", u.Name, reason)
+
+		hu := *u
+		hu.TargetState = job.JobStateLoaded
+		dState.Units[u.Name] = &hu
+	}
+}
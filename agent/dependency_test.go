@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestDependencyState(t *testing.T) {
+	units := []job.Unit{
+		job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched},
+		job.Unit{Name: "bar.service", TargetState: job.JobStateLoaded},
+	}
+	states := []*unit.UnitState{
+		&unit.UnitState{UnitName: "foo.service", ActiveState: "activating"},
+		&unit.UnitState{UnitName: "bar.service", ActiveState: "active"},
+		nil,
+	}
+
+	launched, active := DependencyState(units, states)
+	if got := launched.Values(); !reflect.DeepEqual(got, []string{"foo.service"}) {
+		t.Errorf("Unexpected launched Units: %v", got)
+	}
+	if got := active.Values(); !reflect.DeepEqual(got, []string{"bar.service"}) {
+		t.Errorf("Unexpected active Units: %v", got)
+	}
+}
+
+func TestHoldUnmetDependencies(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "db.service", TargetState: job.JobStateLaunched},
+		job.Job{Name: "cache.service", TargetState: job.JobStateLaunched},
+	})
+	reg.SetUnitStates([]unit.UnitState{
+		unit.UnitState{UnitName: "db.service", ActiveState: "active", MachineID: "YYY"},
+	})
+
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "web.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nFleetRequires=db.service")},
+		&job.Unit{Name: "worker.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nFleetAfter=cache.service")},
+		// already running Units are not stopped
+		&job.Unit{Name: "running.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nFleetRequires=missing.service")},
+	} {
+		dState.Units[u.Name] = u
+	}
+	cState := unitStates{"running.service": job.JobStateLaunched}
+
+	holdUnmetDependencies(reg, dState, cState)
+
+	want := map[string]job.JobState{
+		"web.service":     job.JobStateLaunched,
+		"worker.service":  job.JobStateLoaded,
+		"running.service": job.JobStateLaunched,
+	}
+	for name, js := range want {
+		if got := dState.Units[name].TargetState; got != js {
+			t.Errorf("Unit(%s) has desired state %s, want %s", name, got, js)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+)
+
+// environmentTracker remembers the environment last rendered for each
+// local Unit declaring a FleetEnvironment, indexed by Unit name
+type environmentTracker struct {
+	mutex sync.Mutex
+	envs  map[string]map[string]string
+}
+
+func (et *environmentTracker) get(name string) (env map[string]string, ok bool) {
+	et.mutex.Lock()
+	defer et.mutex.Unlock()
+	env, ok = et.envs[name]
+	return
+}
+
+func (et *environmentTracker) set(name string, env map[string]string) {
+	et.mutex.Lock()
+	defer et.mutex.Unlock()
+	if et.envs == nil {
+		et.envs = make(map[string]map[string]string)
+	}
+	et.envs[name] = env
+}
+
+func (et *environmentTracker) forget(name string) {
+	et.mutex.Lock()
+	defer et.mutex.Unlock()
+	delete(et.envs, name)
+}
+
+// renderEnvironment builds the environment of the given Unit from the
+// configuration values stored in the namespaces of its FleetEnvironment,
+// decrypting secret values with the Agent's cluster key
+func (a *Agent) renderEnvironment(u *job.Unit) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, ns := range u.FleetEnvironment() {
+		values, err := a.registry.ConfigValues(ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed fetching configuration values of namespace %s: %v", ns, err)
+		}
+		for key, cv := range values {
+			val, err := cv.Plaintext(a.ClusterKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading %s of namespace %s: %v", key, ns, err)
+			}
+			env[key] = val
+		}
+	}
+	return env, nil
+}
+
+// setEnvironment passes the environment of the given Unit to it, or stops
+// passing any if it declares no FleetEnvironment
+func (a *Agent) setEnvironment(u *job.Unit) error {
+	if len(u.FleetEnvironment()) == 0 {
+		a.envs.forget(u.Name)
+		return a.um.SetEnvironment(u.Name, nil)
+	}
+
+	env, err := a.renderEnvironment(u)
+	if err != nil {
+		return err
+	}
+	if err := a.um.SetEnvironment(u.Name, env); err != nil {
+		return err
+	}
+	a.envs.set(u.Name, env)
+	return nil
+}
+
+// handleEnvironments renders the environment of each loaded Unit declaring
+// a FleetEnvironment again, passing it to the Unit if it changed. Launched
+// Units are restarted to pick up the change, unless they opt out with
+// `FleetEnvironmentRestart=false`.
+func (ar *AgentReconciler) handleEnvironments(a *Agent, dState *AgentState) {
+	for name, u := range dState.Units {
+		prev, ok := a.envs.get(name)
+		if !ok {
+			continue
+		}
+
+		env, err := a.renderEnvironment(u)
+		if err != nil {
+			log.Errorf("Unable to render environment of Unit(%s): %v", name, err)
+			continue
+		}
+		if reflect.DeepEqual(env, prev) {
+			continue
+		}
+
+		if err := a.um.SetEnvironment(name, env); err != nil {
+			log.Errorf("Failed passing environment to Unit(%s): %v", name, err)
+			continue
+		}
+		a.envs.set(name, env)
+
+		if u.TargetState == job.JobStateLaunched && u.RestartOnEnvironmentChange() {
+			log.Infof("Restarting Unit(%s) as its environment changed", name)
+			a.um.TriggerRestart(name)
+		} else {
+			log.Infof("Environment of Unit(%s) changed, taking effect when it next starts", name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestAgentLoadUnitEnvironment(t *testing.T) {
+	uManager := unit.NewFakeUnitManager()
+	usGenerator := unit.NewUnitStateGenerator(uManager)
+	fReg := registry.NewFakeRegistry()
+	mach := &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX"}}
+	a := New(uManager, usGenerator, fReg, mach, time.Second)
+	a.ClusterKey = bytes.Repeat([]byte{7}, 32)
+
+	secret, err := registry.EncryptConfigValue(a.ClusterKey, "hunter2")
+	if err != nil {
+		t.Fatalf("Failed encrypting value: %v", err)
+	}
+	fReg.SetConfigValue("myapp", "DB_URL", registry.ConfigValue{Value: "db:5432"})
+	fReg.SetConfigValue("myapp", "LOG_LEVEL", registry.ConfigValue{Value: "info"})
+	fReg.SetConfigValue("myapp/prod", "DB_PASSWORD", secret)
+	fReg.SetConfigValue("myapp/prod", "LOG_LEVEL", registry.ConfigValue{Value: "warn"})
+
+	u := newTestUnitFromUnitContents(t, "foo.service", "[X-Fleet]\nFleetEnvironment=myapp\nFleetEnvironment=myapp/prod")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	want := map[string]string{"DB_URL": "db:5432", "DB_PASSWORD": "hunter2", "LOG_LEVEL": "warn"}
+	if got := uManager.Environment("foo.service"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected environment %v, want %v", got, want)
+	}
+
+	// secrets cannot be passed without the cluster key
+	a.ClusterKey = nil
+	if err := a.loadUnit(u); err == nil {
+		t.Error("Expected error loading Unit reading secrets without cluster key")
+	}
+
+	u = newTestUnitFromUnitContents(t, "foo.service", "[Service]\nExecStart=/bin/foo")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	if got := uManager.Environment("foo.service"); got != nil {
+		t.Errorf("Expected environment to be removed, got %v", got)
+	}
+}
+
+func TestHandleEnvironments(t *testing.T) {
+	uManager := unit.NewFakeUnitManager()
+	usGenerator := unit.NewUnitStateGenerator(uManager)
+	fReg := registry.NewFakeRegistry()
+	mach := &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX"}}
+	a := New(uManager, usGenerator, fReg, mach, time.Second)
+	ar := NewReconciler(fReg, nil)
+
+	fReg.SetConfigValue("myapp", "DB_URL", registry.ConfigValue{Value: "db:5432"})
+
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nFleetEnvironment=myapp")},
+		&job.Unit{Name: "bar.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nFleetEnvironment=myapp\nFleetEnvironmentRestart=false")},
+		&job.Unit{Name: "baz.service", TargetState: job.JobStateLoaded, Unit: newUF(t, "[X-Fleet]\nFleetEnvironment=myapp")},
+		&job.Unit{Name: "qux.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[Service]\nExecStart=/bin/qux")},
+	} {
+		dState.Units[u.Name] = u
+		if err := a.loadUnit(u); err != nil {
+			t.Fatalf("Failed loading Unit(%s): %v", u.Name, err)
+		}
+	}
+
+	// nothing changed
+	ar.handleEnvironments(a, dState)
+	if len(uManager.Restarted) != 0 {
+		t.Errorf("Unexpected restarts: %v", uManager.Restarted)
+	}
+
+	fReg.SetConfigValue("myapp", "DB_URL", registry.ConfigValue{Value: "db2:5432"})
+	ar.handleEnvironments(a, dState)
+	if want := []string{"foo.service"}; !reflect.DeepEqual(uManager.Restarted, want) {
+		t.Errorf("Unexpected restarts: got %v, want %v", uManager.Restarted, want)
+	}
+	for _, name := range []string{"foo.service", "bar.service", "baz.service"} {
+		if got := uManager.Environment(name)["DB_URL"]; got != "db2:5432" {
+			t.Errorf("Unit(%s) has DB_URL %q, want db2:5432", name, got)
+		}
+	}
+
+	// the environment is only passed again once it changes
+	ar.handleEnvironments(a, dState)
+	if len(uManager.Restarted) != 1 {
+		t.Errorf("Unexpected restarts: %v", uManager.Restarted)
+	}
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/pkg"
+)
+
+const (
+	// active state systemd reports for units that have failed
+	unitActiveStateFailed = "failed"
+)
+
+// failureTracker remembers recent local failures of Units with a
+// FailurePolicy, so the agent can decide whether to restart them locally
+// or report them to the engine.
+type failureTracker struct {
+	failures map[string][]time.Time
+	reported pkg.Set
+}
+
+func newFailureTracker() *failureTracker {
+	return &failureTracker{
+		failures: make(map[string][]time.Time),
+		reported: pkg.NewUnsafeSet(),
+	}
+}
+
+// record notes a failure of the named Unit at the given time and returns
+// the number of its failures within the given window, including this one.
+func (ft *failureTracker) record(name string, now time.Time, window time.Duration) int {
+	var recent []time.Time
+	for _, t := range ft.failures[name] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	ft.failures[name] = recent
+	return len(recent)
+}
+
+// forget drops all failures of Units that are not in keep, e.g. because
+// they are no longer scheduled to the local machine.
+func (ft *failureTracker) forget(keep pkg.Set) {
+	for name := range ft.failures {
+		if !keep.Contains(name) {
+			delete(ft.failures, name)
+		}
+	}
+	for _, name := range ft.reported.Values() {
+		if !keep.Contains(name) {
+			ft.reported.Remove(name)
+		}
+	}
+}
+
+// handleFailures restarts desired Units with a FailurePolicy that have
+// failed locally, and reports those that failed more often than their
+// policy allows so the engine moves them to another machine. Units found
+// unhealthy by their health check, or exceeding their reservations when
+// usage alerts fail them, are reported right away.
+func (ar *AgentReconciler) handleFailures(a *Agent, dState *AgentState) {
+	watched := pkg.NewUnsafeSet()
+	for name, u := range dState.Units {
+		if u.TargetState == job.JobStateLaunched && !u.IsGlobal() && u.FailurePolicy() != nil {
+			watched.Add(name)
+		}
+	}
+
+	ar.fTracker.forget(watched)
+	if watched.Length() == 0 {
+		return
+	}
+
+	states, err := a.um.GetUnitStates(watched)
+	if err != nil {
+		log.Errorf("Failed fetching states of Units with a failure policy: %v", err)
+		return
+	}
+
+	for name, us := range states {
+		if us == nil || ar.fTracker.reported.Contains(name) {
+			continue
+		}
+
+		p := dState.Units[name].FailurePolicy()
+
+		var reason string
+		health, herr := a.unitHealth(name)
+		overuse := a.overuse.failure(name)
+		switch {
+		case us.ActiveState == unitActiveStateFailed:
+			n := ar.fTracker.record(name, time.Now(), p.RestartWindow)
+			if n <= p.MaxRestarts {
+				log.Infof("Restarting failed Unit(%s), failure %d of %d allowed within %s", name, n, p.MaxRestarts, p.RestartWindow)
+				a.um.TriggerStart(name)
+				continue
+			}
+			reason = fmt.Sprintf("failed %d times within %s", n, p.RestartWindow)
+		case health == unitHealthUnhealthy:
+			reason = fmt.Sprintf("unhealthy: %v", herr)
+		case overuse != "":
+			reason = overuse
+		default:
+			continue
+		}
+
+		// Without a taint, the report only needs to outlive the
+		// engine noticing it
+		ttl := p.Taint
+		if ttl == 0 {
+			ttl = a.ttl
+		}
+
+		if err := ar.reg.ReportUnitFailure(name, dState.MState.ID, reason, ttl); err != nil {
+			log.Errorf("Failed reporting failure of Unit(%s): %v", name, err)
+			continue
+		}
+
+		log.Infof("Reported Unit(%s) as failed on Machine(%s): %s", name, dState.MState.ID, reason)
+		ar.fTracker.reported.Add(name)
+	}
+}
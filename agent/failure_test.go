@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+// failingUnitManager reports all loaded units as failed and counts the
+// restarts triggered for them
+type failingUnitManager struct {
+	*unit.FakeUnitManager
+	starts map[string]int
+}
+
+func (fum *failingUnitManager) TriggerStart(name string) {
+	fum.starts[name]++
+}
+
+func (fum *failingUnitManager) GetUnitStates(filter pkg.Set) (map[string]*unit.UnitState, error) {
+	states, err := fum.FakeUnitManager.GetUnitStates(filter)
+	for _, us := range states {
+		us.ActiveState = unitActiveStateFailed
+		us.SubState = unitActiveStateFailed
+	}
+	return states, err
+}
+
+func TestFailureTrackerRecord(t *testing.T) {
+	ft := newFailureTracker()
+	now := time.Now()
+
+	for i, tt := range []struct {
+		at   time.Time
+		want int
+	}{
+		{now, 1},
+		{now.Add(time.Minute), 2},
+		{now.Add(2 * time.Minute), 3},
+		// failures outside the window are no longer counted
+		{now.Add(5 * time.Minute), 2},
+	} {
+		if got := ft.record("foo.service", tt.at, 4*time.Minute); got != tt.want {
+			t.Errorf("case %d: record returned %d, want %d", i, got, tt.want)
+		}
+	}
+
+	ft.reported.Add("foo.service")
+	ft.forget(pkg.NewUnsafeSet())
+	if len(ft.failures) != 0 || ft.reported.Length() != 0 {
+		t.Errorf("forget left failures behind: %v, %v", ft.failures, ft.reported.Values())
+	}
+}
+
+func TestHandleFailures(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	fum := &failingUnitManager{unit.NewFakeUnitManager(), make(map[string]int)}
+	a := &Agent{um: fum, ttl: time.Minute}
+	ar := NewReconciler(reg, nil)
+
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nOnFailure=reschedule\nMaxRestarts=2")},
+		&job.Unit{Name: "bar.service", TargetState: job.JobStateLaunched},
+		&job.Unit{Name: "baz.service", TargetState: job.JobStateLoaded, Unit: newUF(t, "[X-Fleet]\nOnFailure=reschedule")},
+	} {
+		dState.Units[u.Name] = u
+		fum.Load(u.Name, u.Unit)
+	}
+
+	for i := 0; i < 4; i++ {
+		ar.handleFailures(a, dState)
+	}
+
+	if want := map[string]int{"foo.service": 2}; !reflect.DeepEqual(fum.starts, want) {
+		t.Errorf("Unexpected restarts: got %v, want %v", fum.starts, want)
+	}
+
+	failures, _ := reg.UnitFailures()
+	want := map[string]map[string]string{
+		"foo.service": map[string]string{"XXX": "failed 3 times within 5m0s"},
+	}
+	if !reflect.DeepEqual(failures, want) {
+		t.Errorf("Unexpected failure reports: got %v, want %v", failures, want)
+	}
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/pkg"
+)
+
+const (
+	// handoffPollInterval is the amount of time the Agent waits between
+	// checks of whether its Units have been claimed by other machines
+	handoffPollInterval = time.Second
+)
+
+// handoff records the cordon state of the local machine from before a
+// Handoff, so it can be restored once the machine has left the cluster.
+type handoff struct {
+	cordoned bool
+	draining bool
+}
+
+// Handoff releases the non-global Units scheduled to the local machine back
+// to the engine by draining the machine, then blocks until each of them has
+// been launched by another machine or the timeout expires.
+func (a *Agent) Handoff(timeout time.Duration) {
+	ms, err := a.registryMachineState()
+	if err != nil {
+		log.Errorf("Unable to hand off Units: %v", err)
+		return
+	}
+
+	a.handoff = &handoff{cordoned: ms.Cordoned, draining: ms.Draining}
+	if !ms.Draining {
+		if err := a.registry.CordonMachine(ms.ID, true); err != nil {
+			log.Errorf("Unable to hand off Units: failed draining Machine(%s): %v", ms.ID, err)
+			return
+		}
+	}
+
+	released := pkg.NewUnsafeSet()
+	deadline := time.Now().Add(timeout)
+	for {
+		units, err := a.registry.Units()
+		if err != nil {
+			log.Errorf("Failed fetching Units from Registry: %v", err)
+			return
+		}
+		sUnits, err := a.registry.Schedule()
+		if err != nil {
+			log.Errorf("Failed fetching schedule from Registry: %v", err)
+			return
+		}
+
+		pending := pendingHandoff(ms.ID, released, units, sUnits)
+		if len(pending) == 0 {
+			log.Infof("Handed off all Units of Machine(%s)", ms.ID)
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Warningf("Timed out handing off Units of Machine(%s), %d remaining: %v", ms.ID, len(pending), pending)
+			return
+		}
+
+		log.V(1).Infof("Waiting for %d Unit(s) to be claimed by other machines: %v", len(pending), pending)
+		time.Sleep(handoffPollInterval)
+	}
+}
+
+// FinishHandoff restores the cordon state the local machine had before a
+// previous call to Handoff. It should be called once the machine has left
+// the cluster, and is a no-op if no Handoff was attempted.
+func (a *Agent) FinishHandoff() {
+	if a.handoff == nil {
+		return
+	}
+
+	machID := a.Machine.State().ID
+
+	var err error
+	switch {
+	case a.handoff.draining:
+		return
+	case a.handoff.cordoned:
+		err = a.registry.CordonMachine(machID, false)
+	default:
+		err = a.registry.UncordonMachine(machID)
+	}
+	if err != nil {
+		log.Errorf("Failed restoring cordon state of Machine(%s): %v", machID, err)
+	}
+}
+
+// registryMachineState returns the state of the local machine, including
+// whether it is cordoned, which is only known to the Registry
+func (a *Agent) registryMachineState() (*machine.MachineState, error) {
+	ms := a.Machine.State()
+
+	machines, err := a.registry.Machines()
+	if err != nil {
+		return nil, err
+	}
+	for _, rms := range machines {
+		if rms.ID == ms.ID {
+			ms.Cordoned = rms.Cordoned
+			ms.Draining = rms.Draining
+		}
+	}
+	return &ms, nil
+}
+
+// pendingHandoff determines which non-global Units are still scheduled to
+// the identified machine, or were scheduled to it at some point (as recorded
+// in released) and have not yet been launched by the machine that claimed
+// them. The names of the Units still scheduled to the machine are added to
+// released.
+func pendingHandoff(machID string, released pkg.Set, units []job.Unit, sUnits []job.ScheduledUnit) []string {
+	byName := make(map[string]*job.Unit, len(units))
+	for i := range units {
+		byName[units[i].Name] = &units[i]
+	}
+
+	var pending []string
+	for _, su := range sUnits {
+		u, ok := byName[su.Name]
+		if !ok || u.IsGlobal() {
+			continue
+		}
+
+		if su.TargetMachineID == machID {
+			released.Add(su.Name)
+			pending = append(pending, su.Name)
+			continue
+		}
+
+		if !released.Contains(su.Name) || u.TargetState != job.JobStateLaunched {
+			continue
+		}
+		if su.TargetMachineID == "" || su.State == nil || *su.State != job.JobStateLaunched {
+			pending = append(pending, su.Name)
+		}
+	}
+
+	return pending
+}
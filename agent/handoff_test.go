@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestPendingHandoff(t *testing.T) {
+	units := []job.Unit{
+		job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched},
+		job.Unit{Name: "bar.service", TargetState: job.JobStateLoaded},
+		job.Unit{Name: "baz.service", TargetState: job.JobStateLaunched},
+		job.Unit{Name: "global.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nGlobal=true")},
+	}
+
+	for i, tt := range []struct {
+		released []string
+		sUnits   []job.ScheduledUnit
+		want     []string
+	}{
+		// Units still scheduled locally are pending
+		{
+			nil,
+			[]job.ScheduledUnit{
+				job.ScheduledUnit{Name: "foo.service", TargetMachineID: "XXX", State: &jsLaunched},
+				job.ScheduledUnit{Name: "bar.service", TargetMachineID: "XXX", State: &jsLoaded},
+				job.ScheduledUnit{Name: "global.service"},
+			},
+			[]string{"foo.service", "bar.service"},
+		},
+		// released Units are pending until launched elsewhere
+		{
+			[]string{"foo.service", "bar.service", "baz.service"},
+			[]job.ScheduledUnit{
+				job.ScheduledUnit{Name: "foo.service", TargetMachineID: "YYY", State: &jsLoaded},
+				job.ScheduledUnit{Name: "bar.service", TargetMachineID: "YYY", State: &jsLoaded},
+				job.ScheduledUnit{Name: "baz.service"},
+			},
+			[]string{"foo.service", "baz.service"},
+		},
+		{
+			[]string{"foo.service"},
+			[]job.ScheduledUnit{
+				job.ScheduledUnit{Name: "foo.service", TargetMachineID: "YYY", State: &jsLaunched},
+				job.ScheduledUnit{Name: "baz.service", TargetMachineID: "ZZZ", State: &jsLoaded},
+			},
+			nil,
+		},
+	} {
+		released := pkg.NewUnsafeSet(tt.released...)
+		got := pendingHandoff("XXX", released, units, tt.sUnits)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: pending Units incorrect: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestHandoffRestoresCordon(t *testing.T) {
+	for i, tt := range []struct {
+		cordoned, draining bool
+	}{
+		{false, false},
+		{true, false},
+		{true, true},
+	} {
+		reg := registry.NewFakeRegistry()
+		reg.SetMachines([]machine.MachineState{
+			machine.MachineState{ID: "XXX", Cordoned: tt.cordoned, Draining: tt.draining},
+		})
+		a := &Agent{
+			registry: reg,
+			Machine:  &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX"}},
+		}
+
+		a.Handoff(0)
+		machines, _ := reg.Machines()
+		if !machines[0].Cordoned || !machines[0].Draining {
+			t.Errorf("case %d: machine not draining during handoff", i)
+		}
+
+		a.FinishHandoff()
+		machines, _ = reg.Machines()
+		if machines[0].Cordoned != tt.cordoned || machines[0].Draining != tt.draining {
+			t.Errorf("case %d: cordon state not restored: cordoned=%t draining=%t", i, machines[0].Cordoned, machines[0].Draining)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	// health published for Units passing their health check
+	unitHealthHealthy = "healthy"
+	// health published for Units that failed too many consecutive probes
+	unitHealthUnhealthy = "unhealthy"
+)
+
+// probeFunc runs a single probe of a HealthCheck, returning an error if
+// the probe failed
+type probeFunc func(hc *job.HealthCheck) error
+
+// healthMonitor periodically probes the health of the local Units that
+// declare a health check.
+type healthMonitor struct {
+	mutex  sync.RWMutex
+	probe  probeFunc
+	checks map[string]*healthCheck
+}
+
+type healthCheck struct {
+	spec     job.HealthCheck
+	stop     chan bool
+	health   string
+	failures int
+	lastErr  error
+}
+
+func newHealthMonitor() *healthMonitor {
+	return &healthMonitor{
+		probe:  runProbe,
+		checks: make(map[string]*healthCheck),
+	}
+}
+
+// update starts probing each of the given Units according to its
+// HealthCheck, and stops probing all other Units. Units whose HealthCheck
+// changed are probed anew.
+func (hm *healthMonitor) update(checks map[string]*job.HealthCheck) {
+	hm.mutex.Lock()
+	defer hm.mutex.Unlock()
+
+	for name, hc := range hm.checks {
+		if spec, ok := checks[name]; ok && reflect.DeepEqual(*spec, hc.spec) {
+			continue
+		}
+		close(hc.stop)
+		delete(hm.checks, name)
+	}
+
+	for name, spec := range checks {
+		if _, ok := hm.checks[name]; ok {
+			continue
+		}
+		hc := &healthCheck{spec: *spec, stop: make(chan bool)}
+		hm.checks[name] = hc
+		go hm.run(name, hc)
+	}
+}
+
+func (hm *healthMonitor) run(name string, hc *healthCheck) {
+	ticker := time.NewTicker(hc.spec.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hc.stop:
+			log.V(1).Infof("Stopped health check of Unit(%s)", name)
+			return
+		case <-ticker.C:
+			hm.record(name, hc, hm.probe(&hc.spec))
+		}
+	}
+}
+
+// record updates the health of a Unit with the result of a probe
+func (hm *healthMonitor) record(name string, hc *healthCheck, err error) {
+	hm.mutex.Lock()
+	defer hm.mutex.Unlock()
+
+	if err == nil {
+		if hc.health == unitHealthUnhealthy {
+			log.Infof("Unit(%s) is healthy again", name)
+		}
+		hc.failures = 0
+		hc.lastErr = nil
+		hc.health = unitHealthHealthy
+		return
+	}
+
+	hc.failures++
+	hc.lastErr = err
+	log.V(1).Infof("Health check of Unit(%s) failed (%d/%d): %v", name, hc.failures, hc.spec.Threshold, err)
+
+	if hc.failures >= hc.spec.Threshold && hc.health != unitHealthUnhealthy {
+		log.Warningf("Unit(%s) is unhealthy: %v", name, err)
+		hc.health = unitHealthUnhealthy
+	}
+}
+
+// health returns the current health of the named Unit along with the error
+// of its last failed probe, if any. The health is empty if the Unit has no
+// health check or has not been probed yet.
+func (hm *healthMonitor) health(name string) (string, error) {
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	hc, ok := hm.checks[name]
+	if !ok {
+		return "", nil
+	}
+	return hc.health, hc.lastErr
+}
+
+// runProbe runs the command and/or requests the URL of the given
+// HealthCheck, allowing each to take up to the check's interval.
+func runProbe(hc *job.HealthCheck) error {
+	if hc.Exec != "" {
+		if err := runExecProbe(hc.Exec, hc.Interval); err != nil {
+			return err
+		}
+	}
+
+	if hc.HTTP != "" {
+		client := http.Client{Timeout: hc.Interval}
+		resp, err := client.Get(hc.HTTP)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("GET %s returned %s", hc.HTTP, resp.Status)
+		}
+	}
+
+	return nil
+}
+
+func runExecProbe(command string, timeout time.Duration) error {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%q: %v", command, err)
+		}
+		return nil
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("timed out running %q", command)
+	}
+}
+
+// unitHealth returns the health of the named Unit and the error of its last
+// failed probe, if the Agent monitors its health at all
+func (a *Agent) unitHealth(name string) (string, error) {
+	if a.health == nil {
+		return "", nil
+	}
+	return a.health.health(name)
+}
+
+// healthChecks returns the HealthChecks of the Units in the given
+// AgentState that should be running, indexed by Unit name
+func healthChecks(as *AgentState) map[string]*job.HealthCheck {
+	checks := make(map[string]*job.HealthCheck)
+	for name, u := range as.Units {
+		if u.TargetState != job.JobStateLaunched {
+			continue
+		}
+		if hc := u.HealthCheck(); hc != nil {
+			checks[name] = hc
+		}
+	}
+	return checks
+}
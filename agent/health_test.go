@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestHealthMonitorRecord(t *testing.T) {
+	hm := newHealthMonitor()
+	hc := &healthCheck{spec: job.HealthCheck{Threshold: 2}}
+	hm.checks["foo.service"] = hc
+
+	probeErr := errors.New("connection refused")
+	for i, tt := range []struct {
+		err  error
+		want string
+	}{
+		{nil, unitHealthHealthy},
+		{probeErr, unitHealthHealthy},
+		{probeErr, unitHealthUnhealthy},
+		{probeErr, unitHealthUnhealthy},
+		{nil, unitHealthHealthy},
+		{probeErr, unitHealthHealthy},
+	} {
+		hm.record("foo.service", hc, tt.err)
+		if got, _ := hm.health("foo.service"); got != tt.want {
+			t.Errorf("case %d: health is %q, want %q", i, got, tt.want)
+		}
+	}
+
+	if got, _ := hm.health("bar.service"); got != "" {
+		t.Errorf("Unit without health check has health %q", got)
+	}
+}
+
+func TestHealthMonitorUpdate(t *testing.T) {
+	hm := newHealthMonitor()
+	hm.probe = func(*job.HealthCheck) error { return nil }
+
+	foo := &job.HealthCheck{Exec: "true", Interval: time.Hour, Threshold: 1}
+	hm.update(map[string]*job.HealthCheck{"foo.service": foo})
+	first := hm.checks["foo.service"]
+
+	// unchanged checks keep running
+	hm.update(map[string]*job.HealthCheck{"foo.service": &job.HealthCheck{Exec: "true", Interval: time.Hour, Threshold: 1}})
+	if hm.checks["foo.service"] != first {
+		t.Errorf("Unchanged health check was restarted")
+	}
+
+	// changed checks are restarted
+	hm.update(map[string]*job.HealthCheck{"foo.service": &job.HealthCheck{Exec: "false", Interval: time.Hour, Threshold: 1}})
+	if hm.checks["foo.service"] == first {
+		t.Errorf("Changed health check was not restarted")
+	}
+	select {
+	case <-first.stop:
+	default:
+		t.Errorf("Replaced health check was not stopped")
+	}
+
+	hm.update(nil)
+	if len(hm.checks) != 0 {
+		t.Errorf("Health checks left running: %v", hm.checks)
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/health" {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	for i, tt := range []struct {
+		hc   job.HealthCheck
+		pass bool
+	}{
+		{job.HealthCheck{Exec: "true"}, true},
+		{job.HealthCheck{Exec: "exit 3"}, false},
+		{job.HealthCheck{Exec: "sleep 5", Interval: 10 * time.Millisecond}, false},
+		{job.HealthCheck{HTTP: ts.URL + "/health"}, true},
+		{job.HealthCheck{HTTP: ts.URL + "/broken"}, false},
+		// both probes must pass
+		{job.HealthCheck{Exec: "true", HTTP: ts.URL + "/broken"}, false},
+	} {
+		if tt.hc.Interval == 0 {
+			tt.hc.Interval = time.Second
+		}
+		if err := runProbe(&tt.hc); (err == nil) != tt.pass {
+			t.Errorf("case %d: runProbe returned %v, want pass=%t", i, err, tt.pass)
+		}
+	}
+}
+
+func TestHandleFailuresUnhealthy(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	fum := unit.NewFakeUnitManager()
+	a := &Agent{um: fum, ttl: time.Minute, health: newHealthMonitor()}
+	a.health.checks["foo.service"] = &healthCheck{health: unitHealthUnhealthy, lastErr: errors.New("timed out"), stop: make(chan bool)}
+	a.health.checks["bar.service"] = &healthCheck{health: unitHealthUnhealthy, stop: make(chan bool)}
+	ar := NewReconciler(reg, nil)
+
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nOnFailure=reschedule\nHealthCheckExec=true")},
+		// unhealthy Units are only moved if they ask to be
+		&job.Unit{Name: "bar.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nHealthCheckExec=true")},
+	} {
+		dState.Units[u.Name] = u
+		fum.Load(u.Name, u.Unit)
+	}
+
+	ar.handleFailures(a, dState)
+
+	failures, _ := reg.UnitFailures()
+	want := map[string]map[string]string{
+		"foo.service": map[string]string{"XXX": "unhealthy: timed out"},
+	}
+	if !reflect.DeepEqual(failures, want) {
+		t.Errorf("Unexpected failure reports: got %v, want %v", failures, want)
+	}
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func NewMetadataWatcher(reg registry.Registry, mach *machine.CoreOSMachine) *MetadataWatcher {
+	return &MetadataWatcher{
+		reg:  reg,
+		mach: mach,
+	}
+}
+
+// MetadataWatcher applies the metadata set for the local machine through
+// the Registry, allowing metadata to be changed without restarting fleetd.
+type MetadataWatcher struct {
+	reg  registry.Registry
+	mach *machine.CoreOSMachine
+
+	current map[string]string
+}
+
+// Run refreshes the local machine's metadata at the interval indicated
+// until the stop channel is closed.
+func (mw *MetadataWatcher) Run(ival time.Duration, stop chan bool) {
+	ticker := time.NewTicker(ival)
+	for {
+		select {
+		case <-stop:
+			log.V(1).Info("Halting MetadataWatcher")
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			mw.refresh()
+		}
+	}
+}
+
+func (mw *MetadataWatcher) refresh() {
+	machID := mw.mach.State().ID
+	metadata, err := mw.reg.MachineMetadata(machID)
+	if err != nil {
+		log.Errorf("Failed fetching metadata of Machine(%s) from Registry: %v", machID, err)
+		return
+	}
+
+	if reflect.DeepEqual(metadata, mw.current) {
+		return
+	}
+
+	log.Infof("Applying metadata set for Machine(%s): %v", machID, metadata)
+	mw.mach.SetMetadataOverrides(metadata)
+	mw.current = metadata
+}
@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestMetadataWatcherRefresh(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	static := machine.MachineState{ID: "XXX", Metadata: map[string]string{"region": "us-east"}}
+	mach := machine.NewCoreOSMachine(static, nil, "/", nil)
+	mw := NewMetadataWatcher(reg, mach)
+
+	reg.SetMachineMetadata("XXX", "region", "us-west")
+	reg.SetMachineMetadata("YYY", "region", "eu-west")
+	mw.refresh()
+
+	want := map[string]string{"region": "us-west"}
+	if got := mach.State().Metadata; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected metadata: got %v, want %v", got, want)
+	}
+
+	// removing the value restores the configured metadata
+	reg.DeleteMachineMetadata("XXX", "region")
+	mw.refresh()
+
+	if got := mach.State().Metadata; !reflect.DeepEqual(got, static.Metadata) {
+		t.Errorf("Unexpected metadata: got %v, want %v", got, static.Metadata)
+	}
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/metrics"
+)
+
+var (
+	metricUnits = metrics.NewGauge(
+		"fleet_agent_units",
+		"Number of units the local agent has loaded or launched, by target state.",
+		"state",
+	)
+	metricReservedCPU = metrics.NewGauge(
+		"fleet_agent_reserved_cpu_units",
+		"CPU reserved by the units scheduled to the local machine, in hundredths of a core.",
+	)
+	metricReservedMemory = metrics.NewGauge(
+		"fleet_agent_reserved_memory_megabytes",
+		"Memory reserved by the units scheduled to the local machine, in MB.",
+	)
+	metricReservedDisk = metrics.NewGauge(
+		"fleet_agent_reserved_disk_megabytes",
+		"Disk space reserved by the units scheduled to the local machine, in MB.",
+	)
+	metricHeartbeatDuration = metrics.NewHistogram(
+		"fleet_agent_unit_heartbeat_duration_seconds",
+		"Time taken to publish the heartbeat of a launched unit to the registry.",
+		metrics.DefaultBuckets,
+	)
+	metricStartsWaiting = metrics.NewGauge(
+		"fleet_agent_units_waiting_to_start",
+		"Number of units waiting for the local agent's start limit to allow starting them.",
+	)
+	metricOverReservation = metrics.NewCounter(
+		"fleet_agent_units_over_reservation_total",
+		"Number of times a unit on the local machine used more CPU or memory than it reserved for a sustained period.",
+	)
+)
+
+// updateMetrics publishes the number of Units the Agent has loaded and
+// launched, and the resources reserved by those in the given desired
+// AgentState
+func updateMetrics(a *Agent, dState *AgentState) {
+	metricUnits.Set(float64(len(a.cache.launchedJobs())), string(job.JobStateLaunched))
+	metricUnits.Set(float64(len(a.cache.loadedJobs())), string(job.JobStateLoaded))
+
+	res := dState.AllocatedResources()
+	metricReservedCPU.Set(float64(res.Cores))
+	metricReservedMemory.Set(float64(res.Memory))
+	metricReservedDisk.Set(float64(res.Disk))
+}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/resource"
+)
+
+// overuseMonitor notices local Units using more CPU or memory than they
+// reserved by more than factor, for at least period. Such overuse goes
+// unnoticed by the engine, which places Units by their reservations, and
+// starves the Units sharing the machine.
+type overuseMonitor struct {
+	mutex  sync.Mutex
+	factor float64
+	period time.Duration
+
+	// fail has overusing Units with a FailurePolicy reported as failed
+	fail bool
+
+	// reserved holds the reservations of the launched Units, and over
+	// the overuse of those currently exceeding them, indexed by Unit name
+	reserved map[string]resource.ResourceTuple
+	over     map[string]*overuse
+}
+
+type overuse struct {
+	since time.Time
+	// reason describes the overuse once it lasted for the period
+	reason string
+}
+
+// SetUsageAlerts has the Agent record a UnitOverReservation event for each
+// Unit using more CPU or memory than it reserved by more than factor, for
+// at least period. If fail is set, such Units with a FailurePolicy are also
+// reported as failed, so the engine moves them elsewhere. A factor of zero
+// disables the alerts.
+func (a *Agent) SetUsageAlerts(factor float64, period time.Duration, fail bool) {
+	if factor <= 0 {
+		a.overuse = nil
+		return
+	}
+	a.overuse = &overuseMonitor{
+		factor:   factor,
+		period:   period,
+		fail:     fail,
+		reserved: make(map[string]resource.ResourceTuple),
+		over:     make(map[string]*overuse),
+	}
+}
+
+// update replaces the reservations usage is compared against, forgetting
+// the overuse of Units no longer reserving anything
+func (om *overuseMonitor) update(reserved map[string]resource.ResourceTuple) {
+	if om == nil {
+		return
+	}
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+
+	om.reserved = reserved
+	for name := range om.over {
+		if _, ok := reserved[name]; !ok {
+			delete(om.over, name)
+		}
+	}
+}
+
+// observe compares the given CPU and memory usage of the named Unit with
+// its reservation. Once the Unit has exceeded its reservation for the
+// period, a description of the overuse is returned, only once until the
+// Unit no longer exceeds its reservation.
+func (om *overuseMonitor) observe(name string, cpuUnits, memory int, now time.Time) string {
+	if om == nil {
+		return ""
+	}
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+
+	res, ok := om.reserved[name]
+	if !ok {
+		return ""
+	}
+	excess := om.excess(res, cpuUnits, memory)
+	if excess == "" {
+		delete(om.over, name)
+		return ""
+	}
+
+	ou, ok := om.over[name]
+	if !ok {
+		om.over[name] = &overuse{since: now}
+		return ""
+	}
+	if ou.reason != "" || now.Sub(ou.since) < om.period {
+		return ""
+	}
+	ou.reason = fmt.Sprintf("%s for %s", excess, om.period)
+	return ou.reason
+}
+
+// excess describes by how much the given usage exceeds the given
+// reservation, or returns an empty string if it does not
+func (om *overuseMonitor) excess(res resource.ResourceTuple, cpuUnits, memory int) string {
+	if res.Memory > 0 && float64(memory) > float64(res.Memory)*om.factor {
+		return fmt.Sprintf("memory usage of %dMB exceeded %g times its reservation of %dMB", memory, om.factor, res.Memory)
+	}
+	if res.Cores > 0 && float64(cpuUnits) > float64(res.Cores)*om.factor {
+		return fmt.Sprintf("CPU usage of %d units exceeded %g times its reservation of %d units", cpuUnits, om.factor, res.Cores)
+	}
+	return ""
+}
+
+// failure returns why the named Unit is to be reported as failed for
+// exceeding its reservation, or an empty string if it is not
+func (om *overuseMonitor) failure(name string) string {
+	if om == nil || !om.fail {
+		return ""
+	}
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+
+	if ou, ok := om.over[name]; ok && ou.reason != "" {
+		return "over reservation: " + ou.reason
+	}
+	return ""
+}
+
+// reservations returns the CPU and memory reservations of the Units in the
+// given AgentState that should be running, indexed by Unit name
+func reservations(as *AgentState) map[string]resource.ResourceTuple {
+	reserved := make(map[string]resource.ResourceTuple)
+	for name, u := range as.Units {
+		if u.TargetState != job.JobStateLaunched {
+			continue
+		}
+		if res := u.Resources(); res.Cores > 0 || res.Memory > 0 {
+			reserved[name] = res
+		}
+	}
+	return reserved
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestOveruseMonitorObserve(t *testing.T) {
+	a := &Agent{}
+	a.SetUsageAlerts(1.5, time.Minute, false)
+	om := a.overuse
+	om.update(map[string]resource.ResourceTuple{"foo.service": {Cores: 100, Memory: 512}})
+	now := time.Now()
+
+	for i, tt := range []struct {
+		after  time.Duration
+		cpu    int
+		memory int
+		want   string
+	}{
+		// within the factor
+		{0, 150, 768, ""},
+		// overuse starts
+		{10 * time.Second, 100, 800, ""},
+		{30 * time.Second, 100, 800, ""},
+		// and lasts for the period
+		{70 * time.Second, 100, 800, "memory usage of 800MB exceeded 1.5 times its reservation of 512MB for 1m0s"},
+		// which is reported once only
+		{80 * time.Second, 100, 800, ""},
+		// overuse of CPU starts anew once usage dropped
+		{90 * time.Second, 100, 500, ""},
+		{100 * time.Second, 200, 500, ""},
+		{160 * time.Second, 200, 500, "CPU usage of 200 units exceeded 1.5 times its reservation of 100 units for 1m0s"},
+	} {
+		if got := om.observe("foo.service", tt.cpu, tt.memory, now.Add(tt.after)); got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, got, tt.want)
+		}
+	}
+
+	// Units reserving nothing are not watched
+	if got := om.observe("bar.service", 1000, 1000, now); got != "" {
+		t.Errorf("Unit without reservation reported: %q", got)
+	}
+
+	om.update(nil)
+	if len(om.over) != 0 {
+		t.Errorf("Overuse of Unit no longer reserving kept: %v", om.over)
+	}
+
+	var disabled *overuseMonitor
+	disabled.update(map[string]resource.ResourceTuple{"foo.service": {Memory: 1}})
+	if got := disabled.observe("foo.service", 0, 100, now); got != "" {
+		t.Errorf("Disabled monitor reported %q", got)
+	}
+}
+
+func TestAnnotateRecordsOverReservation(t *testing.T) {
+	defer withCgroupRoot(t)()
+	writeCgroupValue(t, "memory", "foo.service", "memory.usage_in_bytes", "1073741824")
+
+	reg := registry.NewFakeRegistry()
+	a := &Agent{registry: reg, usage: newUsageSampler()}
+	a.SetUsageAlerts(1.5, time.Minute, false)
+	a.overuse.update(map[string]resource.ResourceTuple{"foo.service": {Memory: 512}})
+
+	now := time.Now()
+	for _, after := range []time.Duration{0, 2 * time.Minute} {
+		bt := &unit.UnitStateHeartbeat{Name: "foo.service", State: &unit.UnitState{ActiveState: "active", MachineID: "XXX"}}
+		a.annotate(bt, now.Add(after))
+	}
+
+	events, _ := reg.Events(0)
+	if len(events) != 1 || events[0].Type != registry.EventUnitOverReservation || events[0].UnitName != "foo.service" || events[0].MachineID != "XXX" {
+		t.Errorf("Unexpected events: %#v", events)
+	}
+}
+
+func TestHandleFailuresOverReservation(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	fum := unit.NewFakeUnitManager()
+	a := &Agent{um: fum, ttl: time.Minute}
+	a.SetUsageAlerts(1.5, time.Minute, true)
+	ar := NewReconciler(reg, nil)
+
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nOnFailure=reschedule\nMemoryReservation=512")},
+		&job.Unit{Name: "bar.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[X-Fleet]\nMemoryReservation=512")},
+	} {
+		dState.Units[u.Name] = u
+		fum.Load(u.Name, u.Unit)
+	}
+	a.overuse.update(reservations(dState))
+
+	now := time.Now()
+	for _, name := range []string{"foo.service", "bar.service"} {
+		a.overuse.observe(name, 0, 1024, now)
+		a.overuse.observe(name, 0, 1024, now.Add(time.Minute))
+	}
+	ar.handleFailures(a, dState)
+
+	failures, _ := reg.UnitFailures()
+	want := map[string]map[string]string{
+		"foo.service": map[string]string{"XXX": "over reservation: memory usage of 1024MB exceeded 1.5 times its reservation of 512MB for 1m0s"},
+	}
+	if !reflect.DeepEqual(failures, want) {
+		t.Errorf("Unexpected failure reports: got %v, want %v", failures, want)
+	}
+}
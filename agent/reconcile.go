@@ -9,11 +9,19 @@ import (
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/pkg"
 	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
 )
 
 const (
 	// time between triggering reconciliation routine
 	reconcileInterval = 5 * time.Second
+
+	// sub-state published for global units the agent is unable to run
+	unitSubStateSkipped = "skipped"
+
+	// sub-state published for Units scheduled to the agent that it
+	// refuses to run
+	unitSubStateRejected = "rejected"
 )
 
 func NewReconciler(reg registry.Registry, rStream pkg.EventStream) *AgentReconciler {
@@ -21,6 +29,9 @@ func NewReconciler(reg registry.Registry, rStream pkg.EventStream) *AgentReconci
 		reg:      reg,
 		rStream:  rStream,
 		tManager: newTaskManager(),
+		fTracker: newFailureTracker(),
+		cTracker: newRunTracker(),
+		bTracker: newRunTracker(),
 	}
 }
 
@@ -28,6 +39,15 @@ type AgentReconciler struct {
 	reg      registry.Registry
 	rStream  pkg.EventStream
 	tManager *taskManager
+	fTracker *failureTracker
+	cTracker *runTracker
+	bTracker *runTracker
+
+	// ZombieCleanup determines how Units systemd runs that are not
+	// scheduled to the local machine are handled: ZombieCleanupStop,
+	// ZombieCleanupLog or ZombieCleanupOff. Empty disables it.
+	ZombieCleanup string
+	lastSweep     time.Time
 }
 
 // Run periodically attempts to reconcile the provided Agent until the stop
@@ -53,21 +73,49 @@ func (ar *AgentReconciler) Run(a *Agent, stop chan bool) {
 // Reconcile drives the local Agent's state towards the desired state
 // stored in the Registry.
 func (ar *AgentReconciler) Reconcile(a *Agent) {
-	dAgentState, err := desiredAgentState(a, ar.reg)
+	cAgentState, err := a.units()
 	if err != nil {
-		log.Errorf("Unable to determine agent's desired state: %v", err)
+		log.Errorf("Unable to determine agent's current state: %v", err)
 		return
 	}
 
-	cAgentState, err := a.units()
+	dAgentState, skipped, err := desiredAgentState(a, ar.reg, cAgentState)
 	if err != nil {
-		log.Errorf("Unable to determine agent's current state: %v", err)
+		log.Errorf("Unable to determine agent's desired state: %v", err)
 		return
 	}
 
+	skipped = append(skipped, refuseUnverifiedUnits(a, ar.reg, dAgentState)...)
+
+	if dAgentState.MState.Cordoned {
+		skipped = append(skipped, refuseNewUnits(dAgentState, cAgentState)...)
+	}
+
+	// Skipped global Units and refused Units are never loaded, so nothing
+	// else would publish a state for them on this machine. The states
+	// expire with the agent's TTL once the Units are no longer refused.
+	for _, us := range skipped {
+		ar.reg.SaveUnitState(us.UnitName, us, a.ttl)
+	}
+
+	holdUnmetDependencies(ar.reg, dAgentState, cAgentState)
+
 	for tc := range ar.calculateTaskChainsForJobs(dAgentState, cAgentState) {
 		ar.launchTaskChain(tc, a)
 	}
+	ar.sweepZombies(a, dAgentState, cAgentState, time.Now())
+
+	if a.health != nil {
+		a.health.update(healthChecks(dAgentState))
+	}
+	a.overuse.update(reservations(dAgentState))
+
+	ar.handleFailures(a, dAgentState)
+	ar.handleEnvironments(a, dAgentState)
+	ar.handleUpdates(a, dAgentState)
+	ar.handleCronRuns(a, dAgentState)
+	ar.handleBatchUnits(a, dAgentState)
+	updateMetrics(a, dAgentState)
 }
 
 // Purge attempts to unload all Jobs that have been loaded locally
@@ -99,21 +147,45 @@ func (ar *AgentReconciler) Purge(a *Agent) {
 }
 
 // desiredAgentState builds an *AgentState object that represents what the
-// provided Agent should currently be doing.
-func desiredAgentState(a *Agent, reg registry.Registry) (*AgentState, error) {
+// provided Agent should currently be doing. The states of any global Units
+// the Agent is unable to run are returned alongside it so they can be
+// reported. The Units the Agent currently runs, as given, keep their
+// resources and ports ahead of the Units newly scheduled to it.
+func desiredAgentState(a *Agent, reg registry.Registry, cState unitStates) (*AgentState, []*unit.UnitState, error) {
 	units, err := reg.Units()
 	if err != nil {
 		log.Errorf("Failed fetching Units from Registry: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	sUnits, err := reg.Schedule()
 	if err != nil {
 		log.Errorf("Failed fetching schedule from Registry: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	ms := a.Machine.State()
+	if a.Capacity != nil && !ms.TotalResources.Empty() {
+		ms.TotalResources = a.Capacity.overlay(ms.TotalResources)
+	}
+	// The memory available on the machine already accounts for the Units
+	// running on it, so only their reservations are checked locally
+	ms.MemoryPolicy = machine.MemoryPolicyReserved
+
+	// Whether the machine is cordoned or tainted is only known to the Registry
+	machines, err := reg.Machines()
+	if err != nil {
+		log.Errorf("Failed fetching Machines from Registry: %v", err)
+		return nil, nil, err
+	}
+	for _, rms := range machines {
+		if rms.ID == ms.ID {
+			ms.Cordoned = rms.Cordoned
+			ms.Draining = rms.Draining
+			ms.Taints = rms.Taints
+		}
+	}
+
 	as := AgentState{
 		MState: &ms,
 		Units:  make(map[string]*job.Unit),
@@ -125,6 +197,19 @@ func desiredAgentState(a *Agent, reg registry.Registry) (*AgentState, error) {
 		sUnitMap[sUnit.Name] = &sUnit
 	}
 
+	completions, err := reg.UnitCompletions()
+	if err != nil {
+		log.Errorf("Failed fetching completions from Registry: %v", err)
+		return nil, nil, err
+	}
+	completed := pkg.NewUnsafeSet()
+	for _, uc := range completions {
+		if uc.Succeeded() {
+			completed.Add(uc.Name)
+		}
+	}
+
+	var globals, running, scheduled []*job.Unit
 	for _, u := range units {
 		u := u
 		md := u.RequiredTargetMetadata()
@@ -132,16 +217,103 @@ func desiredAgentState(a *Agent, reg registry.Registry) (*AgentState, error) {
 			log.V(1).Infof("Agent unable to run global unit %s: missing required metadata", u.Name)
 			continue
 		}
+		if u.IsGlobal() && !ms.AcceptsPools(u.Pools()) {
+			log.V(1).Infof("Agent unable to run global unit %s: targets other pools", u.Name)
+			continue
+		}
 		if !u.IsGlobal() {
 			sUnit, ok := sUnitMap[u.Name]
 			if !ok || sUnit.TargetMachineID == "" || sUnit.TargetMachineID != ms.ID {
 				continue
 			}
+			// Batch units that ran to completion are never run again,
+			// even if the engine has yet to unschedule them
+			if u.IsBatch() && completed.Contains(u.Name) {
+				continue
+			}
+			if _, ok := cState[u.Name]; ok {
+				running = append(running, &u)
+			} else {
+				scheduled = append(scheduled, &u)
+			}
+			continue
 		}
-		as.Units[u.Name] = &u
+		globals = append(globals, &u)
 	}
 
-	return &as, nil
+	// Global units are accounted for first, mirroring the engine. Those
+	// that do not fit are skipped on this machine only.
+	var skipped []*unit.UnitState
+	rejected := as.ScheduleGlobalUnits(globals)
+	for _, u := range globals {
+		reason, ok := rejected[u.Name]
+		if !ok {
+			continue
+		}
+		log.Warningf("Agent unable to run global Unit(%s): %s", u.Name, reason)
+		skipped = append(skipped, &unit.UnitState{
+			LoadState:   "not-loaded",
+			ActiveState: "inactive",
+			SubState:    unitSubStateSkipped,
+			MachineID:   ms.ID,
+			UnitHash:    u.Unit.Hash().String(),
+			UnitName:    u.Name,
+			Reason:      fmt.Sprintf("global unit skipped on Machine(%s): %s", ms.ID, reason),
+		})
+	}
+
+	// The engine only schedules units to machines with enough free
+	// resources and ports, but refuse to overcommit the local machine or
+	// to run units binding the same ports in case the schedule was
+	// produced from stale or conflicting data. Units already running are
+	// admitted first, so that a newly scheduled unit never displaces them.
+	for _, u := range append(running, scheduled...) {
+		if able, reason := as.fits(u.Resources(), u.ResourceRequests()); !able {
+			log.Warningf("Agent unable to run Unit(%s): %s", u.Name, reason)
+			skipped = append(skipped, rejectedUnitState(u, &ms, reason))
+			continue
+		}
+		if pExists, pName, port := as.portConflict(u.Name, u.Ports()); pExists {
+			reason := fmt.Sprintf("port %s already bound by Unit(%s)", port, pName)
+			log.Warningf("Agent unable to run Unit(%s): %s", u.Name, reason)
+			skipped = append(skipped, rejectedUnitState(u, &ms, reason))
+			continue
+		}
+		as.Units[u.Name] = u
+	}
+
+	return &as, skipped, nil
+}
+
+// refuseNewUnits removes from the desired state of a cordoned agent any
+// non-global Units it is not already running, in case the engine scheduled
+// them before learning the agent was cordoned. It returns the states to
+// publish for the refused Units.
+func refuseNewUnits(dState *AgentState, cState unitStates) []*unit.UnitState {
+	var refused []*unit.UnitState
+	for name, u := range dState.Units {
+		if _, ok := cState[name]; ok || u.IsGlobal() {
+			continue
+		}
+		log.Infof("Agent refusing to run Unit(%s) while cordoned", name)
+		delete(dState.Units, name)
+		refused = append(refused, rejectedUnitState(u, dState.MState, "machine is cordoned"))
+	}
+	return refused
+}
+
+// rejectedUnitState returns the state published for a Unit scheduled to the
+// given machine that the agent refuses to run, recording why
+func rejectedUnitState(u *job.Unit, ms *machine.MachineState, reason string) *unit.UnitState {
+	return &unit.UnitState{
+		LoadState:   "not-loaded",
+		ActiveState: "inactive",
+		SubState:    unitSubStateRejected,
+		MachineID:   ms.ID,
+		UnitHash:    u.Unit.Hash().String(),
+		UnitName:    u.Name,
+		Reason:      fmt.Sprintf("unit rejected by Machine(%s): %s", ms.ID, reason),
+	}
 }
 
 // calculateTaskChainsForJobs compares the desired and current state of an Agent.
@@ -2,11 +2,13 @@ package agent
 
 import (
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/unit"
 )
 
@@ -236,7 +238,7 @@ MachineMetadata=dog=woof`),
 		reg := registry.NewFakeRegistry()
 		reg.SetJobs(tt.regJobs)
 		a := makeAgentWithMetadata(tt.metadata)
-		as, err := desiredAgentState(a, reg)
+		as, _, err := desiredAgentState(a, reg, nil)
 		if err != nil {
 			t.Errorf("case %d: unexpected error: %v", i, err)
 		} else if !reflect.DeepEqual(as.Units, tt.asUnits) {
@@ -253,6 +255,194 @@ MachineMetadata=dog=woof`),
 	}
 }
 
+func TestDesiredAgentStateResources(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{
+			Name:            "a.service",
+			Unit:            newUF(t, "[X-Fleet]\nMemoryReservation=512"),
+			TargetMachineID: "this_machine",
+		},
+		job.Job{
+			Name:            "b.service",
+			Unit:            newUF(t, "[X-Fleet]\nMemoryReservation=512"),
+			TargetMachineID: "this_machine",
+		},
+		job.Job{
+			Name:            "c.service",
+			Unit:            newUF(t, "blah"),
+			TargetMachineID: "this_machine",
+		},
+		job.Job{
+			Name: "global.service",
+			Unit: newUF(t, "[X-Fleet]\nGlobal=true\nMemoryReservation=256"),
+		},
+	})
+
+	a := &Agent{
+		Machine: &machine.FakeMachine{
+			MachineState: machine.MachineState{
+				ID:             "this_machine",
+				TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280},
+			},
+		},
+	}
+
+	// b.service is already running, so it keeps its reservation even
+	// though a.service comes first
+	cState := unitStates{"b.service": job.JobStateLaunched}
+	as, skipped, err := desiredAgentState(a, reg, cState)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 1280MB total, less 256MB for the host and 256MB for the global
+	// unit, leaves room for only one of the 512MB reservations
+	var names []string
+	for name := range as.Units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	want := []string{"b.service", "c.service", "global.service"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Unexpected desired Units: got %v, want %v", names, want)
+	}
+
+	// the rejection of the new unit is published for this machine
+	if len(skipped) != 1 {
+		t.Fatalf("Expected 1 rejected Unit, got %d", len(skipped))
+	}
+	us := skipped[0]
+	if us.UnitName != "a.service" || us.MachineID != "this_machine" || us.SubState != unitSubStateRejected {
+		t.Errorf("Unexpected rejected UnitState: %#v", us)
+	}
+	wantReason := "unit rejected by Machine(this_machine): insufficient memory: requested 512MB, available 256MB"
+	if us.Reason != wantReason {
+		t.Errorf("Unexpected reason: got %q, want %q", us.Reason, wantReason)
+	}
+}
+
+func TestDesiredAgentStatePorts(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{
+			Name:            "a.service",
+			Unit:            newUF(t, "[X-Fleet]\nPorts=8080"),
+			TargetMachineID: "this_machine",
+		},
+		job.Job{
+			Name:            "b.service",
+			Unit:            newUF(t, "[X-Fleet]\nPorts=8080"),
+			TargetMachineID: "this_machine",
+		},
+	})
+
+	a := &Agent{
+		Machine: &machine.FakeMachine{
+			MachineState: machine.MachineState{ID: "this_machine"},
+		},
+	}
+
+	// the running unit keeps its port over the newly scheduled one
+	cState := unitStates{"b.service": job.JobStateLaunched}
+	as, skipped, err := desiredAgentState(a, reg, cState)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := as.Units["b.service"]; !ok || len(as.Units) != 1 {
+		t.Errorf("Expected only b.service to be desired, got %v", as.Units)
+	}
+	if len(skipped) != 1 || skipped[0].UnitName != "a.service" {
+		t.Fatalf("Expected a.service to be rejected, got %v", skipped)
+	}
+	want := "unit rejected by Machine(this_machine): port 8080/tcp already bound by Unit(b.service)"
+	if skipped[0].Reason != want {
+		t.Errorf("Unexpected reason: got %q, want %q", skipped[0].Reason, want)
+	}
+}
+
+func TestDesiredAgentStateSkippedGlobal(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{
+			Name: "big.service",
+			Unit: newUF(t, "[X-Fleet]\nGlobal=true\nMemoryReservation=1024"),
+		},
+		job.Job{
+			Name: "small.service",
+			Unit: newUF(t, "[X-Fleet]\nGlobal=true\nMemoryReservation=512"),
+		},
+		job.Job{
+			Name: "elsewhere.service",
+			Unit: newUF(t, "[X-Fleet]\nGlobal=true\nMachineMetadata=region=us-west\nMemoryReservation=4096"),
+		},
+	})
+
+	a := &Agent{
+		Machine: &machine.FakeMachine{
+			MachineState: machine.MachineState{
+				ID:             "this_machine",
+				TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1024},
+			},
+		},
+	}
+
+	as, skipped, err := desiredAgentState(a, reg, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, ok := as.Units["small.service"]; !ok || len(as.Units) != 1 {
+		t.Errorf("Expected only small.service to be desired, got %v", as.Units)
+	}
+
+	// units whose metadata does not match are not reported as skipped
+	if len(skipped) != 1 {
+		t.Fatalf("Expected 1 skipped Unit, got %d", len(skipped))
+	}
+	us := skipped[0]
+	if us.UnitName != "big.service" || us.MachineID != "this_machine" || us.SubState != unitSubStateSkipped {
+		t.Errorf("Unexpected skipped UnitState: %#v", us)
+	}
+	want := "global unit skipped on Machine(this_machine): insufficient memory: requested 1024MB, available 768MB"
+	if us.Reason != want {
+		t.Errorf("Unexpected reason: got %q, want %q", us.Reason, want)
+	}
+}
+
+func TestRefuseNewUnits(t *testing.T) {
+	dState := NewAgentState(&machine.MachineState{ID: "XXX", Cordoned: true})
+	dState.Units["running.service"] = &job.Unit{Name: "running.service"}
+	dState.Units["new.service"] = &job.Unit{Name: "new.service"}
+	dState.Units["global.service"] = &job.Unit{Name: "global.service", Unit: newUF(t, "[X-Fleet]\nGlobal=true")}
+	cState := unitStates{"running.service": job.JobStateLaunched}
+
+	refused := refuseNewUnits(dState, cState)
+
+	var names []string
+	for name := range dState.Units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	want := []string{"global.service", "running.service"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Unexpected desired Units: got %v, want %v", names, want)
+	}
+
+	if len(refused) != 1 {
+		t.Fatalf("Expected 1 refused Unit, got %d", len(refused))
+	}
+	us := refused[0]
+	if us.UnitName != "new.service" || us.MachineID != "XXX" || us.SubState != unitSubStateRejected {
+		t.Errorf("Unexpected refused UnitState: %#v", us)
+	}
+	if us.Reason != "unit rejected by Machine(XXX): machine is cordoned" {
+		t.Errorf("Unexpected reason: %q", us.Reason)
+	}
+}
+
 func TestAbleToRun(t *testing.T) {
 	tests := []struct {
 		dState *AgentState
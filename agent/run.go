@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	// active state systemd reports for units that are not running
+	unitActiveStateInactive = "inactive"
+
+	// how long a unit scheduled to the local machine to run to completion
+	// may be inactive before it is considered to have finished, without
+	// having been seen running. This covers the delay of systemd starting
+	// the unit.
+	runStartGrace = 2 * reconcileInterval
+)
+
+// runTracker follows units scheduled to the local machine that run to
+// completion, like batch Units and the runs of scheduled templates, so the
+// agent can tell runs that have not started yet from runs that have
+// already finished.
+type runTracker struct {
+	scheduled map[string]time.Time
+	running   pkg.Set
+	reported  pkg.Set
+}
+
+func newRunTracker() *runTracker {
+	return &runTracker{
+		scheduled: make(map[string]time.Time),
+		running:   pkg.NewUnsafeSet(),
+		reported:  pkg.NewUnsafeSet(),
+	}
+}
+
+// finished notes the given state of the named unit at the given time and
+// determines whether the unit has finished a run that is not reported yet.
+// A unit finished once it failed, or became inactive after it was seen
+// running or had plenty of time to start.
+func (rt *runTracker) finished(name string, us *unit.UnitState, now time.Time) bool {
+	if _, ok := rt.scheduled[name]; !ok {
+		rt.scheduled[name] = now
+	}
+	if us == nil {
+		return false
+	}
+
+	switch us.ActiveState {
+	case unitActiveStateFailed:
+	case unitActiveStateInactive:
+		if !rt.running.Contains(name) && now.Sub(rt.scheduled[name]) < runStartGrace {
+			return false
+		}
+	default:
+		// a unit running again, e.g. after being restarted, finishes
+		// another run
+		rt.running.Add(name)
+		rt.reported.Remove(name)
+		return false
+	}
+	return !rt.reported.Contains(name)
+}
+
+// forget drops all units that are not in keep, e.g. because they are no
+// longer scheduled to the local machine.
+func (rt *runTracker) forget(keep pkg.Set) {
+	for name := range rt.scheduled {
+		if !keep.Contains(name) {
+			delete(rt.scheduled, name)
+		}
+	}
+	for _, set := range []pkg.Set{rt.running, rt.reported} {
+		for _, name := range set.Values() {
+			if !keep.Contains(name) {
+				set.Remove(name)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	gsunit "github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-systemd/unit"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/unit"
+)
+
+// A runtimeDriver translates the container lifecycle of Units declaring a
+// Runtime into the commands systemd runs for them. The container runs in
+// the foreground of the Unit's main process, so the state systemd reports
+// for the Unit is the state of its container.
+type runtimeDriver interface {
+	// commands returns the ExecStartPre=, ExecStart= and ExecStop= options
+	// running the given container for the named Unit, limited to the given
+	// reservations as the Unit asks them to be enforced
+	commands(name string, rt *job.Runtime, res resource.ResourceTuple, enforce string) []*gsunit.UnitOption
+}
+
+// runtimeDrivers holds the available runtimeDrivers, indexed by the name
+// Units declare them with
+var runtimeDrivers = map[string]runtimeDriver{
+	job.RuntimeDocker: &dockerDriver{binary: "/usr/bin/docker"},
+	job.RuntimeRkt:    &rktDriver{binary: "/usr/bin/rkt"},
+}
+
+// renderRuntime returns the unit file of the given Unit with its container
+// lifecycle in place of its own commands, or nil if it declares no usable
+// Runtime
+func renderRuntime(u *job.Unit) *unit.UnitFile {
+	rt := u.Runtime()
+	if rt == nil {
+		return nil
+	}
+	d, ok := runtimeDrivers[rt.Name]
+	if !ok || rt.Image == "" {
+		log.Errorf("Ignoring Runtime=%q of Unit(%s) with RuntimeImage=%q", rt.Name, u.Name, rt.Image)
+		return nil
+	}
+
+	// The commands follow the other [Service] options of the Unit, or its
+	// [Unit] options if it has none, keeping each section in one piece
+	var kept []*gsunit.UnitOption
+	afterService, afterUnit := -1, 0
+	for _, opt := range u.Unit.Options {
+		if opt.Section == "Service" && isExecOption(opt.Name) {
+			continue
+		}
+		kept = append(kept, opt)
+		switch opt.Section {
+		case "Service":
+			afterService = len(kept)
+		case "Unit":
+			afterUnit = len(kept)
+		}
+	}
+	at := afterService
+	if at < 0 {
+		at = afterUnit
+	}
+	cmds := d.commands(u.Name, rt, u.Resources(), u.EnforceReservations())
+	opts := make([]*gsunit.UnitOption, 0, len(kept)+len(cmds))
+	opts = append(opts, kept[:at]...)
+	opts = append(opts, cmds...)
+	opts = append(opts, kept[at:]...)
+	return unit.NewUnitFromOptions(opts)
+}
+
+// isExecOption determines whether the named [Service] option is a command
+// the runtimeDriver replaces
+func isExecOption(name string) bool {
+	switch name {
+	case "ExecStartPre", "ExecStart", "ExecStop":
+		return true
+	}
+	return false
+}
+
+// containerName returns the name of the container of the named Unit, e.g.
+// fleet-web_1 for web@1.service
+func containerName(name string) string {
+	name = strings.TrimSuffix(name, ".service")
+	return "fleet-" + strings.Replace(name, "@", "_", -1)
+}
+
+func serviceOption(name, value string) *gsunit.UnitOption {
+	return &gsunit.UnitOption{Section: "Service", Name: name, Value: value}
+}
+
+// dockerDriver runs containers with Docker. Containers run in the cgroups
+// of the Docker daemon rather than of their Unit, so reservations are passed
+// to Docker: memory is reserved softly and CPU weighted, one core weighing
+// as much as the default of 1024 shares. Hard enforcement also limits
+// memory and CPU.
+type dockerDriver struct {
+	binary string
+}
+
+func (d *dockerDriver) commands(name string, rt *job.Runtime, res resource.ResourceTuple, enforce string) []*gsunit.UnitOption {
+	container := containerName(name)
+
+	run := []string{d.binary, "run", "--rm", "--name", container}
+	if enforce != job.EnforceNone {
+		if res.Memory > 0 {
+			run = append(run, fmt.Sprintf("--memory-reservation=%dm", res.Memory))
+			if enforce == job.EnforceHard {
+				run = append(run, fmt.Sprintf("--memory=%dm", res.Memory))
+			}
+		}
+		if res.Cores > 0 {
+			run = append(run, fmt.Sprintf("--cpu-shares=%d", res.Cores*1024/100))
+			if enforce == job.EnforceHard {
+				run = append(run, "--cpu-period=100000", fmt.Sprintf("--cpu-quota=%d", res.Cores*1000))
+			}
+		}
+	}
+	run = append(run, rt.Image)
+	if rt.Args != "" {
+		run = append(run, rt.Args)
+	}
+
+	return []*gsunit.UnitOption{
+		// remove any container left behind by an earlier run
+		serviceOption("ExecStartPre", fmt.Sprintf("-%s rm -f %s", d.binary, container)),
+		serviceOption("ExecStartPre", fmt.Sprintf("%s pull %s", d.binary, rt.Image)),
+		serviceOption("ExecStart", strings.Join(run, " ")),
+		serviceOption("ExecStop", fmt.Sprintf("%s stop %s", d.binary, container)),
+	}
+}
+
+// rktDriver runs containers with rkt. Containers run in the cgroup of their
+// Unit, where the drop-in enforcing its reservations applies, so only hard
+// enforcement is also passed to rkt as isolators.
+type rktDriver struct {
+	binary string
+}
+
+func (d *rktDriver) commands(name string, rt *job.Runtime, res resource.ResourceTuple, enforce string) []*gsunit.UnitOption {
+	run := []string{d.binary, "run", rt.Image}
+	if enforce == job.EnforceHard {
+		if res.Memory > 0 {
+			run = append(run, fmt.Sprintf("--memory=%dM", res.Memory))
+		}
+		if res.Cores > 0 {
+			run = append(run, fmt.Sprintf("--cpu=%dm", res.Cores*10))
+		}
+	}
+	if rt.Args != "" {
+		run = append(run, "--", rt.Args)
+	}
+
+	return []*gsunit.UnitOption{
+		serviceOption("ExecStartPre", fmt.Sprintf("%s fetch %s", d.binary, rt.Image)),
+		serviceOption("ExecStart", strings.Join(run, " ")),
+	}
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	unitSubStateUnverified = "unverified"
+)
+
+// signatureVerifier remembers the unit files whose signature was verified,
+// indexed by Hash. As a unit file cannot change without changing its Hash,
+// the signature of a verified unit file need not be checked again.
+type signatureVerifier struct {
+	mutex    sync.Mutex
+	verified map[unit.Hash]bool
+}
+
+func (sv *signatureVerifier) isVerified(hash unit.Hash) bool {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+	return sv.verified[hash]
+}
+
+func (sv *signatureVerifier) setVerified(hash unit.Hash) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+	if sv.verified == nil {
+		sv.verified = make(map[unit.Hash]bool)
+	}
+	sv.verified[hash] = true
+}
+
+// verifyUnitFile ensures that the given unit file carries a signature made
+// with one of the Agent's TrustedKeys
+func (a *Agent) verifyUnitFile(reg registry.Registry, uf *unit.UnitFile) error {
+	hash := uf.Hash()
+	if a.signatures.isVerified(hash) {
+		return nil
+	}
+
+	sig, err := reg.UnitSignature(hash)
+	if err != nil {
+		return fmt.Errorf("failed fetching signature: %v", err)
+	}
+	if err := uf.VerifySignature(a.TrustedKeys, sig); err != nil {
+		return err
+	}
+	a.signatures.setVerified(hash)
+	return nil
+}
+
+// refuseUnverifiedUnits removes from the desired state of an Agent with
+// TrustedKeys any Units whose unit file is not signed with one of them,
+// returning the states to publish for the refused Units. Refused Units
+// that are loaded are unloaded, as their unit file may have been tampered
+// with.
+func refuseUnverifiedUnits(a *Agent, reg registry.Registry, dState *AgentState) []*unit.UnitState {
+	if len(a.TrustedKeys) == 0 {
+		return nil
+	}
+
+	var refused []*unit.UnitState
+	for name, u := range dState.Units {
+		err := a.verifyUnitFile(reg, &u.Unit)
+		if err == nil {
+			continue
+		}
+		log.Errorf("Agent refusing to run Unit(%s): %v", name, err)
+		delete(dState.Units, name)
+		refused = append(refused, unverifiedUnitState(name, &u.Unit, dState.MState, err))
+	}
+	return refused
+}
+
+func unverifiedUnitState(name string, uf *unit.UnitFile, ms *machine.MachineState, err error) *unit.UnitState {
+	return &unit.UnitState{
+		LoadState:   "not-loaded",
+		ActiveState: "inactive",
+		SubState:    unitSubStateUnverified,
+		MachineID:   ms.ID,
+		UnitHash:    uf.Hash().String(),
+		UnitName:    name,
+		Reason:      fmt.Sprintf("unit refused on Machine(%s): %v", ms.ID, err),
+	}
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRefuseUnverifiedUnits(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed generating key: %v", err)
+	}
+	untrusted, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	reg := registry.NewFakeRegistry()
+	signed := newUF(t, "[Service]\nExecStart=/bin/signed")
+	sig, _ := signed.Sign(priv)
+	reg.SetUnitSignature(signed.Hash(), sig)
+	other := newUF(t, "[Service]\nExecStart=/bin/other")
+	sig, _ = other.Sign(untrusted)
+	reg.SetUnitSignature(other.Hash(), sig)
+
+	newState := func() *AgentState {
+		dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+		for _, u := range []*job.Unit{
+			&job.Unit{Name: "signed.service", Unit: signed},
+			&job.Unit{Name: "other.service", Unit: other},
+			&job.Unit{Name: "unsigned.service", Unit: newUF(t, "[Service]\nExecStart=/bin/unsigned")},
+		} {
+			dState.Units[u.Name] = u
+		}
+		return dState
+	}
+
+	// without trusted keys, all Units are run
+	a := &Agent{}
+	dState := newState()
+	if refused := refuseUnverifiedUnits(a, reg, dState); len(refused) != 0 || len(dState.Units) != 3 {
+		t.Errorf("Unexpected refused Units %v", refused)
+	}
+
+	a.TrustedKeys = []*ecdsa.PublicKey{&priv.PublicKey}
+	dState = newState()
+	refused := refuseUnverifiedUnits(a, reg, dState)
+	if len(dState.Units) != 1 || dState.Units["signed.service"] == nil {
+		t.Errorf("Unexpected desired Units %v", dState.Units)
+	}
+	if len(refused) != 2 {
+		t.Fatalf("Expected 2 refused Units, got %v", refused)
+	}
+	for _, us := range refused {
+		if us.SubState != unitSubStateUnverified || us.MachineID != "XXX" || us.Reason == "" {
+			t.Errorf("Unexpected state of refused Unit: %#v", us)
+		}
+	}
+
+	// verified unit files are remembered
+	if !a.signatures.isVerified(signed.Hash()) {
+		t.Error("Expected signed unit file to be remembered as verified")
+	}
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+)
+
+var (
+	// startSettleInterval is how often the Agent checks whether a Unit
+	// started under a start limit has finished starting
+	startSettleInterval = time.Second
+	// startSettleTimeout is how long a Unit may take to start before its
+	// slot is handed to the next Unit waiting to start regardless
+	startSettleTimeout = 2 * time.Minute
+)
+
+// SetStartConcurrency limits the number of Units the Agent starts at once,
+// so that a machine coming up with many Units scheduled to it, e.g. after
+// a reboot, is not saturated by starting them all simultaneously. Each Unit
+// holds its slot until systemd has finished starting it, and Units waiting
+// for a slot start in order of their StartPriority. A limit of zero lifts
+// the limit.
+func (a *Agent) SetStartConcurrency(limit int) {
+	if limit <= 0 {
+		a.starts = nil
+		return
+	}
+	a.starts = newStartThrottle(limit)
+}
+
+// startUnitThrottled starts the Unit once the start limit of the Agent
+// allows it
+func (a *Agent) startUnitThrottled(u *job.Unit) {
+	if a.starts == nil {
+		a.startUnit(u.Name)
+		return
+	}
+
+	a.starts.acquire(u.StartPriority())
+	defer a.starts.release()
+	a.startUnit(u.Name)
+	a.awaitStarted(u.Name)
+}
+
+// awaitStarted waits until the named Unit is no longer activating, or
+// until startSettleTimeout elapsed
+func (a *Agent) awaitStarted(name string) {
+	deadline := time.Now().Add(startSettleTimeout)
+	for {
+		time.Sleep(startSettleInterval)
+		us, err := a.um.GetUnitState(name)
+		if err == nil && us != nil && us.ActiveState != "activating" {
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Warningf("Unit(%s) still starting after %v, starting further Units", name, startSettleTimeout)
+			return
+		}
+	}
+}
+
+// startThrottle hands out a limited number of slots to the Units starting
+// at once. Units waiting for a slot get one in order of priority, then in
+// the order they asked for one.
+type startThrottle struct {
+	limit int
+
+	mutex   sync.Mutex
+	running int
+	waiting startWaiters
+	seq     int
+}
+
+type startWaiter struct {
+	priority int
+	seq      int
+	ready    chan struct{}
+}
+
+func newStartThrottle(limit int) *startThrottle {
+	return &startThrottle{limit: limit}
+}
+
+// acquire blocks until a slot is free
+func (st *startThrottle) acquire(priority int) {
+	st.mutex.Lock()
+	if st.running < st.limit && len(st.waiting) == 0 {
+		st.running++
+		st.mutex.Unlock()
+		return
+	}
+
+	w := &startWaiter{priority: priority, seq: st.seq, ready: make(chan struct{})}
+	st.seq++
+	st.waiting = append(st.waiting, w)
+	sort.Sort(st.waiting)
+	metricStartsWaiting.Set(float64(len(st.waiting)))
+	st.mutex.Unlock()
+
+	<-w.ready
+}
+
+// release frees a slot, handing it to the first Unit waiting for one
+func (st *startThrottle) release() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if len(st.waiting) == 0 {
+		st.running--
+		return
+	}
+	w := st.waiting[0]
+	st.waiting = st.waiting[1:]
+	metricStartsWaiting.Set(float64(len(st.waiting)))
+	close(w.ready)
+}
+
+func (st *startThrottle) waitingCount() int {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	return len(st.waiting)
+}
+
+type startWaiters []*startWaiter
+
+func (sw startWaiters) Len() int      { return len(sw) }
+func (sw startWaiters) Swap(i, j int) { sw[i], sw[j] = sw[j], sw[i] }
+
+func (sw startWaiters) Less(i, j int) bool {
+	if sw[i].priority != sw[j].priority {
+		return sw[i].priority > sw[j].priority
+	}
+	return sw[i].seq < sw[j].seq
+}
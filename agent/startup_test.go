@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStartThrottleOrder(t *testing.T) {
+	st := newStartThrottle(1)
+	st.acquire(0)
+
+	started := make(chan string)
+	for i, w := range []struct {
+		name     string
+		priority int
+	}{
+		{"low.service", -1},
+		{"default1.service", 0},
+		{"critical.service", 100},
+		{"default2.service", 0},
+	} {
+		w := w
+		go func() {
+			st.acquire(w.priority)
+			started <- w.name
+		}()
+		// wait for the waiter to queue up before adding the next one
+		for st.waitingCount() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		st.release()
+		order = append(order, <-started)
+	}
+	want := []string{"critical.service", "default1.service", "default2.service", "low.service"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Units started in order %v, want %v", order, want)
+	}
+
+	st.release()
+	if st.running != 0 {
+		t.Errorf("Throttle left with %d slots taken", st.running)
+	}
+}
+
+func TestStartThrottleLimit(t *testing.T) {
+	st := newStartThrottle(2)
+	st.acquire(0)
+	st.acquire(0)
+
+	acquired := make(chan bool)
+	go func() {
+		st.acquire(0)
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatalf("Slot acquired beyond limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	st.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("Slot not handed over on release")
+	}
+}
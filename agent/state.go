@@ -3,15 +3,22 @@ package agent
 import (
 	"fmt"
 	"path"
+	"time"
 
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/log"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/unit"
 )
 
 type AgentState struct {
 	MState *machine.MachineState
 	Units  map[string]*job.Unit
+
+	// Failures holds the reported reasons of Units that failed
+	// persistently on the machine, indexed by Unit name
+	Failures map[string]string
 }
 
 func NewAgentState(ms *machine.MachineState) *AgentState {
@@ -32,19 +39,112 @@ func (as *AgentState) hasConflict(pUnitName string, pConflicts []string) (found
 			continue
 		}
 
-		for _, pConflict := range pConflicts {
-			if globMatches(pConflict, eUnit.Name) {
-				found = true
-				conflict = eUnit.Name
-				return
+		if unitsConflict(pUnitName, pConflicts, eUnit) {
+			found = true
+			conflict = eUnit.Name
+			return
+		}
+	}
+
+	return
+}
+
+// HasDomainConflict determines whether the given Job conflicts with any Unit
+// scheduled to the agent, where either the Job or that Unit has declared a
+// conflict domain (a metadata key) for which the agent's machine shares a
+// value with the given machine.
+func (as *AgentState) HasDomainConflict(ms *machine.MachineState, j *job.Job) (found bool, conflict string) {
+	jDomains := j.ConflictDomains()
+	jConflicts := j.Conflicts()
+
+	for _, eUnit := range as.Units {
+		if j.Name == eUnit.Name {
+			continue
+		}
+
+		domains := append(eUnit.ConflictDomains(), jDomains...)
+		if !sharesDomain(as.MState, ms, domains) {
+			continue
+		}
+
+		if unitsConflict(j.Name, jConflicts, eUnit) {
+			found = true
+			conflict = eUnit.Name
+			return
+		}
+	}
+
+	return
+}
+
+// sharesDomain determines whether two machines have the same, non-empty
+// value for any of the given metadata keys
+func sharesDomain(a, b *machine.MachineState, keys []string) bool {
+	for _, key := range keys {
+		if v := a.Metadata[key]; v != "" && v == b.Metadata[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// unitsConflict determines whether a Unit with the given name and Conflicts
+// conflicts with an existing Unit, in either direction
+func unitsConflict(pUnitName string, pConflicts []string, eUnit *job.Unit) bool {
+	for _, pConflict := range pConflicts {
+		if globMatches(pConflict, eUnit.Name) {
+			return true
+		}
+	}
+
+	for _, eConflict := range eUnit.Conflicts() {
+		if globMatches(eConflict, pUnitName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replacedBy returns the name of a Unit scheduled to the agent that
+// replaces the named Unit, if any
+func (as *AgentState) replacedBy(name string) (string, bool) {
+	for _, eUnit := range as.Units {
+		if eUnit.Name == name {
+			continue
+		}
+		for _, r := range eUnit.Replaces() {
+			if r == name {
+				return eUnit.Name, true
 			}
 		}
+	}
+	return "", false
+}
 
-		for _, eConflict := range eUnit.Conflicts() {
-			if globMatches(eConflict, pUnitName) {
-				found = true
-				conflict = eUnit.Name
-				return
+// portConflict determines whether any Unit scheduled to the agent, other
+// than the named Unit and those named in except, binds any of the given host
+// ports, returning that Unit and the port
+func (as *AgentState) portConflict(name string, ports []string, except ...string) (found bool, conflict, port string) {
+	if len(ports) == 0 {
+		return
+	}
+
+	skip := make(map[string]bool, len(except)+1)
+	skip[name] = true
+	for _, n := range except {
+		skip[n] = true
+	}
+
+	for _, eUnit := range as.Units {
+		if skip[eUnit.Name] {
+			continue
+		}
+		for _, eport := range eUnit.Ports() {
+			for _, p := range ports {
+				if p == eport {
+					return true, eUnit.Name, p
+				}
 			}
 		}
 	}
@@ -52,6 +152,143 @@ func (as *AgentState) hasConflict(pUnitName string, pConflicts []string) (found
 	return
 }
 
+// unitCount returns the number of Units scheduled to the agent, except for
+// those named
+func (as *AgentState) unitCount(except ...string) int {
+	n := len(as.Units)
+	for _, name := range except {
+		if as.unitScheduled(name) {
+			n--
+		}
+	}
+	return n
+}
+
+// allocatedResources returns the sum of the resources reserved by all Units
+// scheduled to the agent, except for those named
+func (as *AgentState) allocatedResources(except ...string) resource.ResourceTuple {
+	skip := make(map[string]bool, len(except))
+	for _, name := range except {
+		skip[name] = true
+	}
+
+	var allocated []resource.ResourceTuple
+	for _, u := range as.Units {
+		if !skip[u.Name] {
+			allocated = append(allocated, u.Resources())
+		}
+	}
+	return resource.Sum(allocated...)
+}
+
+// allocatedExtendedResources returns the sum of the requests for extended
+// resources of all Units scheduled to the agent, except for those named
+func (as *AgentState) allocatedExtendedResources(except ...string) resource.Counts {
+	skip := make(map[string]bool, len(except))
+	for _, name := range except {
+		skip[name] = true
+	}
+
+	var allocated []resource.Counts
+	for _, u := range as.Units {
+		if !skip[u.Name] {
+			allocated = append(allocated, u.ResourceRequests())
+		}
+	}
+	return resource.SumCounts(allocated...)
+}
+
+// allocatedCPUUnits returns the CPU units reserved by all Units scheduled
+// to the agent
+func (as *AgentState) allocatedCPUUnits() int {
+	return as.allocatedResources().Cores
+}
+
+// allocatedMemory returns the memory (in MB) reserved by all Units scheduled
+// to the agent
+func (as *AgentState) allocatedMemory() int {
+	return as.allocatedResources().Memory
+}
+
+// allocatedDisk returns the disk space (in MB) reserved by all Units
+// scheduled to the agent
+func (as *AgentState) allocatedDisk() int {
+	return as.allocatedResources().Disk
+}
+
+// FreeResources returns the resources of the agent's machine that are not
+// reserved for the host or by any scheduled Units. Free memory follows the
+// MemoryPolicy of the machine.
+func (as *AgentState) FreeResources() resource.ResourceTuple {
+	return as.freeResources()
+}
+
+// freeResources returns the free resources of the agent's machine,
+// disregarding the reservations of any Units named in except
+func (as *AgentState) freeResources(except ...string) resource.ResourceTuple {
+	free := resource.Sub(as.MState.AllocatableResources(), as.allocatedResources(except...))
+	if as.MState.UsesAvailableMemory() {
+		avail := as.MState.AvailableMemory
+		if as.MState.MemoryPolicy == machine.MemoryPolicyAvailable || avail < free.Memory {
+			free.Memory = avail
+		}
+	}
+	return free
+}
+
+// fits determines whether the agent has enough free resources to satisfy
+// the given reservation and requests for extended resources, disregarding
+// those of any Units named in except (e.g. those about to be replaced).
+// Agents that do not publish their capacity are assumed to fit any
+// reservation, but only offer the extended resources they advertise. All
+// resources falling short are reported.
+func (as *AgentState) fits(req resource.ResourceTuple, ext resource.Counts, except ...string) (bool, string) {
+	sf := as.resourceShortfalls(req, ext, except...)
+	return len(sf) == 0, job.ShortfallReason(sf)
+}
+
+// resourceShortfalls returns a Shortfall for each resource of which the
+// agent has too little free to satisfy the given reservation and requests
+// for extended resources, as described for fits
+func (as *AgentState) resourceShortfalls(req resource.ResourceTuple, ext resource.Counts, except ...string) []job.Shortfall {
+	var sf []job.Shortfall
+	if len(ext) > 0 {
+		var offered resource.Counts
+		if as.MState != nil {
+			offered = as.MState.ExtendedResources
+		}
+		allocated := as.allocatedExtendedResources(except...)
+		for _, name := range ext.Names() {
+			if free := offered[name] - allocated[name]; ext[name] > free {
+				sf = append(sf, job.Shortfall{Constraint: job.ConstraintResource, Reason: fmt.Sprintf("insufficient %s: requested %d, available %d", name, ext[name], free)})
+			}
+		}
+	}
+
+	if req.Empty() || as.MState == nil || as.MState.TotalResources.Empty() {
+		return sf
+	}
+
+	free := as.freeResources(except...)
+	if req.Cores > free.Cores {
+		sf = append(sf, job.Shortfall{Constraint: job.ConstraintCPU, Reason: fmt.Sprintf("insufficient CPU units: requested %d, available %d", req.Cores, free.Cores)})
+	}
+	if req.Memory > free.Memory {
+		sf = append(sf, job.Shortfall{Constraint: job.ConstraintMemory, Reason: fmt.Sprintf("insufficient memory: requested %dMB, available %dMB", req.Memory, free.Memory)})
+	}
+	if req.Disk > free.Disk {
+		sf = append(sf, job.Shortfall{Constraint: job.ConstraintDisk, Reason: fmt.Sprintf("insufficient disk space: requested %dMB, available %dMB", req.Disk, free.Disk)})
+	}
+	return sf
+}
+
+// Fits determines whether the agent has enough free resources to satisfy
+// the given reservation and requests for extended resources, e.g. those of
+// several Units placed together
+func (as *AgentState) Fits(req resource.ResourceTuple, ext resource.Counts) (bool, string) {
+	return as.fits(req, ext)
+}
+
 func globMatches(pattern, target string) bool {
 	matched, err := path.Match(pattern, target)
 	if err != nil {
@@ -62,35 +299,186 @@ func globMatches(pattern, target string) bool {
 
 // AbleToRun determines if an Agent can run the provided Job based on
 // the Agent's current state. A boolean indicating whether this is the
-// case or not is returned. The following criteria is used:
+// case or not is returned, along with the reasons of all Shortfalls of the
+// agent if it is not, as determined by Shortfalls.
+func (as *AgentState) AbleToRun(j *job.Job) (bool, string) {
+	sf := as.Shortfalls(j)
+	return len(sf) == 0, job.ShortfallReason(sf)
+}
+
+// Shortfalls returns each constraint of the provided Job that the Agent
+// fails to meet in its current state, none meaning that it is able to run
+// the Job. The following criteria is used:
+//   - Job must not be a unit template (only instances may be scheduled);
+//     no other criteria are evaluated for templates
 //   - Agent must meet the Job's machine target requirement (if any)
 //   - Agent must have all of the Job's required metadata (if any)
+//   - Job must tolerate all taints of the Agent's machine, unless it is
+//     already scheduled to it
+//   - Agent must have fewer Units scheduled than the limit of its machine
+//     (if any), unless the Job is already scheduled to it
+//   - Global Jobs are only subject to the metadata, taint, unit limit, port
+//     and resource checks
+//   - Agent must not be draining, nor cordoned unless the Job is already
+//     scheduled to it
+//   - Job must not have been reported failed on the agent
 //   - Agent must have all required Peers of the Job scheduled locally (if any)
 //   - Job must not conflict with any other Units scheduled to the agent
-func (as *AgentState) AbleToRun(j *job.Job) (bool, string) {
+//   - Job must not be replaced by any other Unit scheduled to the agent
+//   - Job must not bind any host ports bound by other Units scheduled to
+//     the agent (if any)
+//   - Current time must fall within the Job's workload window (if any)
+//   - Agent must have enough unreserved CPU, memory and disk for the Job's
+//     reservations (if any)
+func (as *AgentState) Shortfalls(j *job.Job) []job.Shortfall {
+	if uni := unit.NewUnitNameInfo(j.Name); uni != nil && uni.IsTemplate() {
+		return []job.Shortfall{{Constraint: job.ConstraintTemplate, Reason: fmt.Sprintf("Unit(%s) is a template and cannot be scheduled", j.Name)}}
+	}
+
+	var sf []job.Shortfall
+	short := func(constraint, format string, a ...interface{}) {
+		sf = append(sf, job.Shortfall{Constraint: constraint, Reason: fmt.Sprintf(format, a...)})
+	}
+
 	if tgt, ok := j.RequiredTarget(); ok && !as.MState.MatchID(tgt) {
-		return false, fmt.Sprintf("agent ID %q does not match required %q", as.MState.ID, tgt)
+		short(job.ConstraintMachineID, "agent ID %q does not match required %q", as.MState.ID, tgt)
 	}
 
 	metadata := j.RequiredTargetMetadata()
 	if len(metadata) != 0 {
 		if !machine.HasMetadata(as.MState, metadata) {
-			return false, "local Machine metadata insufficient"
+			short(job.ConstraintMetadata, "local Machine metadata insufficient")
+		}
+	}
+
+	if pools := j.Pools(); !as.MState.AcceptsPools(pools) {
+		if pool := as.MState.Pool(); pool != "" {
+			short(job.ConstraintPool, "Machine(%s) is dedicated to pool %s", as.MState.ID, pool)
+		} else {
+			short(job.ConstraintPool, "Machine(%s) is in no pool of %v", as.MState.ID, pools)
 		}
 	}
 
-	peers := j.Peers()
-	if len(peers) != 0 {
-		for _, peer := range peers {
-			if !as.unitScheduled(peer) {
-				return false, fmt.Sprintf("required peer Unit(%s) is not scheduled locally", peer)
+	scheduled := as.unitScheduled(j.Name)
+	if !scheduled {
+		if t, tainted := as.MState.UntoleratedTaint(j.Tolerations()); tainted {
+			short(job.ConstraintTaint, "Machine(%s) has taint %s not tolerated by Unit(%s)", as.MState.ID, t, j.Name)
+		}
+
+		// Units this Job replaces are moved away, making room for it
+		if max := as.MState.UnitLimit(); max > 0 && as.unitCount(j.Replaces()...) >= max {
+			short(job.ConstraintUnitLimit, "Machine(%s) already has its limit of %d units scheduled", as.MState.ID, max)
+		}
+	}
+
+	if u := (&job.Unit{Name: j.Name, Unit: j.Unit}); u.IsGlobal() {
+		if !scheduled {
+			if pExists, pJobName, port := as.portConflict(j.Name, j.Ports()); pExists {
+				short(job.ConstraintPort, "port %s already bound by locally-scheduled Unit(%s)", port, pJobName)
 			}
+			sf = append(sf, as.resourceShortfalls(j.Resources(), j.ResourceRequests())...)
+		}
+		return sf
+	}
+
+	if as.MState.Draining {
+		short(job.ConstraintDraining, "Machine(%s) is draining", as.MState.ID)
+	}
+
+	if as.MState.Cordoned && !scheduled {
+		short(job.ConstraintCordoned, "Machine(%s) is cordoned", as.MState.ID)
+	}
+
+	if reason, ok := as.Failures[j.Name]; ok {
+		short(job.ConstraintFailed, "Unit(%s) failed on Machine(%s): %s", j.Name, as.MState.ID, reason)
+	}
+
+	for _, peer := range j.Peers() {
+		if !as.unitScheduled(peer) {
+			short(job.ConstraintPeer, "required peer Unit(%s) is not scheduled locally", peer)
 		}
 	}
 
 	if cExists, cJobName := as.hasConflict(j.Name, j.Conflicts()); cExists {
-		return false, fmt.Sprintf("found conflict with locally-scheduled Unit(%s)", cJobName)
+		short(job.ConstraintConflict, "found conflict with locally-scheduled Unit(%s)", cJobName)
+	}
+
+	if rJobName, replaced := as.replacedBy(j.Name); replaced {
+		short(job.ConstraintReplaced, "replaced by locally-scheduled Unit(%s)", rJobName)
+	}
+
+	// Units this Job replaces are moved away, releasing their ports
+	if pExists, pJobName, port := as.portConflict(j.Name, j.Ports(), j.Replaces()...); pExists {
+		short(job.ConstraintPort, "port %s already bound by locally-scheduled Unit(%s)", port, pJobName)
+	}
+
+	// This also holds for Jobs already scheduled here, so that running
+	// Units are unscheduled once their window closes
+	if w := j.WorkloadWindow(); w != nil && !w.Contains(time.Now()) {
+		short(job.ConstraintWorkloadWindow, "outside of workload window %s", w)
+	}
+
+	// A Job already scheduled here must not be counted against itself
+	if !scheduled {
+		sf = append(sf, as.resourceShortfalls(j.Resources(), j.ResourceRequests(), j.Replaces()...)...)
+	}
+
+	return sf
+}
+
+// ScheduleGlobalUnits adds to the agent, in order, each of the given global
+// Units that it is able to run. Units whose metadata requirements the agent
+// does not meet, that target other pools, or that do not tolerate the taints
+// of its machine, are ignored; the reasons for which any other Units were
+// rejected are returned, keyed by Unit name.
+func (as *AgentState) ScheduleGlobalUnits(units []*job.Unit) map[string]string {
+	rejected := make(map[string]string)
+	for _, u := range units {
+		if !machine.HasMetadata(as.MState, u.RequiredTargetMetadata()) {
+			continue
+		}
+		if !as.MState.AcceptsPools(u.Pools()) {
+			continue
+		}
+		if _, tainted := as.MState.UntoleratedTaint(u.Tolerations()); tainted {
+			continue
+		}
+
+		j := &job.Job{Name: u.Name, Unit: u.Unit}
+		if able, reason := as.AbleToRun(j); !able {
+			rejected[u.Name] = reason
+			continue
+		}
+		as.Units[u.Name] = u
+	}
+	return rejected
+}
+
+// AllocatedResources returns the sum of the resources reserved by all Units
+// scheduled to the agent
+func (as *AgentState) AllocatedResources() resource.ResourceTuple {
+	return as.allocatedResources()
+}
+
+// AllocatedExtendedResources returns the sum of the requests for extended
+// resources of all Units scheduled to the agent
+func (as *AgentState) AllocatedExtendedResources() resource.Counts {
+	return as.allocatedExtendedResources()
+}
+
+// NextWindowOpen returns the next time at which the workload window of the
+// named Unit opens. If the Unit is not scheduled locally or has no workload
+// window, the zero time is returned.
+func (as *AgentState) NextWindowOpen(name string) time.Time {
+	u, ok := as.Units[name]
+	if !ok {
+		return time.Time{}
+	}
+
+	w := u.WorkloadWindow()
+	if w == nil {
+		return time.Time{}
 	}
 
-	return true, ""
+	return w.NextOpen(time.Now())
 }
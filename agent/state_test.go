@@ -2,10 +2,13 @@ package agent
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/unit"
 )
 
@@ -84,6 +87,533 @@ func TestHasConflicts(t *testing.T) {
 	}
 }
 
+func TestHasDomainConflict(t *testing.T) {
+	east1 := &machine.MachineState{ID: "east1", Metadata: map[string]string{"region": "us-east"}}
+	east2 := &machine.MachineState{ID: "east2", Metadata: map[string]string{"region": "us-east"}}
+	west := &machine.MachineState{ID: "west", Metadata: map[string]string{"region": "us-west"}}
+	bare := &machine.MachineState{ID: "bare"}
+
+	for i, tt := range []struct {
+		existing *job.Unit
+		ms       *machine.MachineState
+		job      *job.Job
+		want     bool
+	}{
+		// domain declared by the new Job
+		{
+			&job.Unit{Name: "web@1.service"},
+			east2,
+			&job.Job{Name: "web@2.service", Unit: fleetUnit(t, "Conflicts=web@*.service", "ConflictsWithMetadata=region")},
+			true,
+		},
+		// domain declared by the existing Unit
+		{
+			&job.Unit{Name: "web@1.service", Unit: fleetUnit(t, "Conflicts=web@*.service", "ConflictsWithMetadata=region")},
+			east2,
+			&job.Job{Name: "web@2.service", Unit: fleetUnit(t)},
+			true,
+		},
+		// different domain
+		{
+			&job.Unit{Name: "web@1.service"},
+			west,
+			&job.Job{Name: "web@2.service", Unit: fleetUnit(t, "Conflicts=web@*.service", "ConflictsWithMetadata=region")},
+			false,
+		},
+		// machines without the metadata share no domain
+		{
+			&job.Unit{Name: "web@1.service"},
+			bare,
+			&job.Job{Name: "web@2.service", Unit: fleetUnit(t, "Conflicts=web@*.service", "ConflictsWithMetadata=region")},
+			false,
+		},
+		// same domain, but no conflict
+		{
+			&job.Unit{Name: "db.service"},
+			east2,
+			&job.Job{Name: "web@2.service", Unit: fleetUnit(t, "Conflicts=web@*.service", "ConflictsWithMetadata=region")},
+			false,
+		},
+		// Conflicts without a domain only apply locally
+		{
+			&job.Unit{Name: "web@1.service"},
+			east2,
+			&job.Job{Name: "web@2.service", Unit: fleetUnit(t, "Conflicts=web@*.service")},
+			false,
+		},
+	} {
+		as := NewAgentState(east1)
+		as.Units[tt.existing.Name] = tt.existing
+
+		got, conflict := as.HasDomainConflict(tt.ms, tt.job)
+		if got != tt.want {
+			t.Errorf("case %d: HasDomainConflict returned %t (%q), want %t", i, got, conflict, tt.want)
+		}
+	}
+}
+
+func TestAbleToRunTemplate(t *testing.T) {
+	as := NewAgentState(&machine.MachineState{ID: "XXX"})
+
+	tmpl := &job.Job{Name: "foo@.service", Unit: unit.UnitFile{}}
+	if able, _ := as.AbleToRun(tmpl); able {
+		t.Errorf("Expected template unit to be unschedulable")
+	}
+
+	inst := &job.Job{Name: "foo@1.service", Unit: unit.UnitFile{}}
+	if able, reason := as.AbleToRun(inst); !able {
+		t.Errorf("Expected instance unit to be schedulable, got reason %q", reason)
+	}
+}
+
+func TestAbleToRunResources(t *testing.T) {
+	ms := &machine.MachineState{
+		ID:             "XXX",
+		TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048, Disk: 10240},
+	}
+	as := NewAgentState(ms)
+	as.Units["existing.service"] = &job.Unit{
+		Name: "existing.service",
+		Unit: fleetUnit(t, "CPUUnits=200", "MemoryReservation=1024", "DiskReservation=8192"),
+	}
+
+	if got := as.allocatedCPUUnits(); got != 200 {
+		t.Errorf("allocatedCPUUnits returned %d, want 200", got)
+	}
+	if got := as.allocatedMemory(); got != 1024 {
+		t.Errorf("allocatedMemory returned %d, want 1024", got)
+	}
+	if got := as.allocatedDisk(); got != 8192 {
+		t.Errorf("allocatedDisk returned %d, want 8192", got)
+	}
+
+	for i, tt := range []struct {
+		opts []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"CPUUnits=100"}, true},
+		{[]string{"CPUUnits=101"}, false},
+		{[]string{"MemoryReservation=768"}, true},
+		{[]string{"MemoryReservation=769"}, false},
+		{[]string{"CPUUnits=100", "MemoryReservation=769"}, false},
+		{[]string{"DiskReservation=2048"}, true},
+		{[]string{"DiskReservation=2049"}, false},
+	} {
+		j := &job.Job{Name: "new.service", Unit: fleetUnit(t, tt.opts...)}
+		if got, reason := as.AbleToRun(j); got != tt.want {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t", i, got, reason, tt.want)
+		}
+	}
+
+	// a Unit already scheduled to the agent is not counted twice
+	j := &job.Job{Name: "existing.service", Unit: as.Units["existing.service"].Unit}
+	if able, reason := as.AbleToRun(j); !able {
+		t.Errorf("Expected scheduled Unit to remain runnable, got reason %q", reason)
+	}
+
+	// machines that do not publish their capacity accept any reservation
+	as = NewAgentState(&machine.MachineState{ID: "YYY"})
+	j = &job.Job{Name: "new.service", Unit: fleetUnit(t, "MemoryReservation=1000000")}
+	if able, reason := as.AbleToRun(j); !able {
+		t.Errorf("Expected Unit to be runnable on machine without capacity, got reason %q", reason)
+	}
+}
+
+func TestAbleToRunReservedResources(t *testing.T) {
+	ms := &machine.MachineState{
+		ID:                "XXX",
+		TotalResources:    resource.ResourceTuple{Cores: 400, Memory: 2048},
+		ReservedResources: &resource.ResourceTuple{Cores: 50, Memory: 1024},
+	}
+	as := NewAgentState(ms)
+
+	if got, want := as.FreeResources(), (resource.ResourceTuple{Cores: 350, Memory: 1024}); got != want {
+		t.Errorf("FreeResources returned %v, want %v", got, want)
+	}
+
+	for i, tt := range []struct {
+		opts []string
+		want bool
+	}{
+		{[]string{"CPUUnits=350"}, true},
+		{[]string{"CPUUnits=351"}, false},
+		{[]string{"MemoryReservation=1024"}, true},
+		{[]string{"MemoryReservation=1025"}, false},
+	} {
+		j := &job.Job{Name: "new.service", Unit: fleetUnit(t, tt.opts...)}
+		if got, reason := as.AbleToRun(j); got != tt.want {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t", i, got, reason, tt.want)
+		}
+	}
+}
+
+func TestAbleToRunOvercommit(t *testing.T) {
+	ms := &machine.MachineState{
+		ID:                "XXX",
+		TotalResources:    resource.ResourceTuple{Cores: 400, Memory: 2048},
+		ReservedResources: &resource.ResourceTuple{},
+		CPUOvercommit:     1.5,
+		Metadata:          map[string]string{"memory-overcommit": "2"},
+	}
+	as := NewAgentState(ms)
+	as.Units["existing.service"] = &job.Unit{
+		Name: "existing.service",
+		Unit: fleetUnit(t, "CPUUnits=400", "MemoryReservation=2048"),
+	}
+
+	for i, tt := range []struct {
+		opts []string
+		want bool
+	}{
+		{[]string{"CPUUnits=200"}, true},
+		{[]string{"CPUUnits=201"}, false},
+		{[]string{"MemoryReservation=2048"}, true},
+		{[]string{"MemoryReservation=2049"}, false},
+	} {
+		j := &job.Job{Name: "new.service", Unit: fleetUnit(t, tt.opts...)}
+		if got, reason := as.AbleToRun(j); got != tt.want {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t", i, got, reason, tt.want)
+		}
+	}
+}
+
+func TestAbleToRunMemoryPolicy(t *testing.T) {
+	for i, tt := range []struct {
+		policy string
+		mem    int
+		want   bool
+	}{
+		// 1024MB are left unreserved, of which the kernel reports 512MB available
+		{"", 1024, true},
+		{"", 1025, false},
+		{machine.MemoryPolicyReserved, 1024, true},
+		{machine.MemoryPolicyAvailable, 512, true},
+		{machine.MemoryPolicyAvailable, 513, false},
+		{machine.MemoryPolicyHybrid, 512, true},
+		{machine.MemoryPolicyHybrid, 513, false},
+	} {
+		ms := &machine.MachineState{
+			ID:                "XXX",
+			TotalResources:    resource.ResourceTuple{Cores: 400, Memory: 2048},
+			ReservedResources: &resource.ResourceTuple{},
+			MemoryPolicy:      tt.policy,
+			AvailableMemory:   512,
+		}
+		as := NewAgentState(ms)
+		as.Units["existing.service"] = &job.Unit{
+			Name: "existing.service",
+			Unit: fleetUnit(t, "MemoryReservation=1024"),
+		}
+
+		j := &job.Job{Name: "new.service", Unit: fleetUnit(t, fmt.Sprintf("MemoryReservation=%d", tt.mem))}
+		if got, reason := as.AbleToRun(j); got != tt.want {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t", i, got, reason, tt.want)
+		}
+	}
+
+	// with hybrid, the lower of the available and unreserved memory is free
+	ms := &machine.MachineState{
+		ID:                "XXX",
+		TotalResources:    resource.ResourceTuple{Cores: 400, Memory: 2048},
+		ReservedResources: &resource.ResourceTuple{},
+		MemoryPolicy:      machine.MemoryPolicyHybrid,
+		AvailableMemory:   1536,
+	}
+	as := NewAgentState(ms)
+	as.Units["existing.service"] = &job.Unit{
+		Name: "existing.service",
+		Unit: fleetUnit(t, "MemoryReservation=1024"),
+	}
+	if got := as.FreeResources().Memory; got != 1024 {
+		t.Errorf("FreeResources returned %dMB of memory, want 1024MB", got)
+	}
+}
+
+func TestAbleToRunPorts(t *testing.T) {
+	as := NewAgentState(&machine.MachineState{ID: "XXX"})
+	as.Units["web.service"] = &job.Unit{
+		Name: "web.service",
+		Unit: fleetUnit(t, "Ports=8080 53/udp"),
+	}
+
+	for i, tt := range []struct {
+		name   string
+		opts   []string
+		want   bool
+		reason string
+	}{
+		{"new.service", []string{"Ports=8443"}, true, ""},
+		{"new.service", []string{"Ports=53"}, true, ""},
+		{"new.service", []string{"Ports=8443,8080/tcp"}, false, "port 8080/tcp already bound by locally-scheduled Unit(web.service)"},
+		{"new.service", []string{"Ports=53/udp"}, false, "port 53/udp already bound by locally-scheduled Unit(web.service)"},
+		// a Unit does not conflict with itself
+		{"web.service", []string{"Ports=8080"}, true, ""},
+		// nor with the Unit it replaces
+		{"new.service", []string{"Ports=8080", "Replaces=web.service"}, true, ""},
+		{"global.service", []string{"Ports=8080", "Global=true"}, false, "port 8080/tcp already bound by locally-scheduled Unit(web.service)"},
+	} {
+		j := &job.Job{Name: tt.name, Unit: fleetUnit(t, tt.opts...)}
+		if got, reason := as.AbleToRun(j); got != tt.want || reason != tt.reason {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t (%q)", i, got, reason, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestAbleToRunExtendedResources(t *testing.T) {
+	ms := &machine.MachineState{
+		ID:                "XXX",
+		ExtendedResources: resource.Counts{"gpu": 2},
+	}
+	as := NewAgentState(ms)
+	as.Units["existing.service"] = &job.Unit{
+		Name: "existing.service",
+		Unit: fleetUnit(t, "ResourceRequest=gpu:1"),
+	}
+
+	if got := as.AllocatedExtendedResources(); !reflect.DeepEqual(got, resource.Counts{"gpu": 1}) {
+		t.Errorf("AllocatedExtendedResources returned %v, want gpu:1", got)
+	}
+
+	for i, tt := range []struct {
+		opts   []string
+		want   bool
+		reason string
+	}{
+		{nil, true, ""},
+		{[]string{"ResourceRequest=gpu:1"}, true, ""},
+		{[]string{"ResourceRequest=gpu:2"}, false, "insufficient gpu: requested 2, available 1"},
+		// machines only offer the extended resources they advertise,
+		// even if they do not publish their capacity
+		{[]string{"ResourceRequest=fpga:1"}, false, "insufficient fpga: requested 1, available 0"},
+	} {
+		j := &job.Job{Name: "new.service", Unit: fleetUnit(t, tt.opts...)}
+		if got, reason := as.AbleToRun(j); got != tt.want || reason != tt.reason {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t (%q)", i, got, reason, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestShortfalls(t *testing.T) {
+	ms := &machine.MachineState{
+		ID:                "XXX",
+		Metadata:          map[string]string{"region": "us"},
+		TotalResources:    resource.ResourceTuple{Cores: 100, Memory: 1024, Disk: 1024},
+		ExtendedResources: resource.Counts{"gpu": 1},
+		Cordoned:          true,
+	}
+	as := NewAgentState(ms)
+
+	j := &job.Job{Name: "new.service", Unit: fleetUnit(t,
+		"MachineMetadata=region=eu",
+		"CPUUnits=200",
+		"MemoryReservation=2048",
+		"ResourceRequest=gpu:2",
+	)}
+	want := []job.Shortfall{
+		{Constraint: job.ConstraintMetadata, Reason: "local Machine metadata insufficient"},
+		{Constraint: job.ConstraintCordoned, Reason: "Machine(XXX) is cordoned"},
+		{Constraint: job.ConstraintResource, Reason: "insufficient gpu: requested 2, available 1"},
+		{Constraint: job.ConstraintCPU, Reason: "insufficient CPU units: requested 200, available 0"},
+		{Constraint: job.ConstraintMemory, Reason: "insufficient memory: requested 2048MB, available 768MB"},
+	}
+	if got := as.Shortfalls(j); !reflect.DeepEqual(got, want) {
+		t.Errorf("Shortfalls returned %v, want %v", got, want)
+	}
+
+	able, reason := as.AbleToRun(j)
+	wantReason := "local Machine metadata insufficient; Machine(XXX) is cordoned; insufficient gpu: requested 2, available 1; " +
+		"insufficient CPU units: requested 200, available 0; insufficient memory: requested 2048MB, available 768MB"
+	if able || reason != wantReason {
+		t.Errorf("AbleToRun returned %t (%q), want false (%q)", able, reason, wantReason)
+	}
+
+	// templates are rejected for being templates only
+	tmpl := &job.Job{Name: "new@.service", Unit: j.Unit}
+	if got := as.Shortfalls(tmpl); len(got) != 1 || got[0].Constraint != job.ConstraintTemplate {
+		t.Errorf("Shortfalls returned %v for template", got)
+	}
+
+	as = NewAgentState(&machine.MachineState{ID: "YYY"})
+	if got := as.Shortfalls(&job.Job{Name: "new.service", Unit: unit.UnitFile{}}); got != nil {
+		t.Errorf("Shortfalls returned %v for runnable Job", got)
+	}
+}
+
+func TestAbleToRunReplaced(t *testing.T) {
+	ms := &machine.MachineState{
+		ID:             "XXX",
+		TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280},
+	}
+	as := NewAgentState(ms)
+	as.Units["old.service"] = &job.Unit{
+		Name: "old.service",
+		Unit: fleetUnit(t, "MemoryReservation=1024"),
+	}
+
+	// the replacement may use the resources of the Unit it replaces
+	j := &job.Job{Name: "new.service", Unit: fleetUnit(t, "Replaces=old.service", "MemoryReservation=1024")}
+	if able, reason := as.AbleToRun(j); !able {
+		t.Errorf("Expected replacement Unit to be runnable, got reason %q", reason)
+	}
+	j = &job.Job{Name: "other.service", Unit: fleetUnit(t, "MemoryReservation=1024")}
+	if able, _ := as.AbleToRun(j); able {
+		t.Errorf("Expected Unit without Replaces to be rejected")
+	}
+
+	// the replaced Unit may no longer run alongside its replacement
+	as.Units["new.service"] = &job.Unit{
+		Name: "new.service",
+		Unit: fleetUnit(t, "Replaces=old.service"),
+	}
+	j = &job.Job{Name: "old.service", Unit: as.Units["old.service"].Unit}
+	able, reason := as.AbleToRun(j)
+	if able {
+		t.Errorf("Expected replaced Unit to be rejected")
+	}
+	if want := "replaced by locally-scheduled Unit(new.service)"; reason != want {
+		t.Errorf("Unexpected reason: got %q, want %q", reason, want)
+	}
+}
+
+func TestAbleToRunPools(t *testing.T) {
+	for i, tt := range []struct {
+		pool   string
+		opts   []string
+		want   bool
+		reason string
+	}{
+		{"", nil, true, ""},
+		{"", []string{"Pool=web"}, false, "Machine(XXX) is in no pool of [web]"},
+		{"", []string{"Pool=*"}, true, ""},
+		{"web", nil, false, "Machine(XXX) is dedicated to pool web"},
+		{"web", []string{"Pool=web"}, true, ""},
+		{"web", []string{"Pool=batch"}, false, "Machine(XXX) is dedicated to pool web"},
+		{"web", []string{"Pool=batch", "Pool=web"}, true, ""},
+		{"web", []string{"Pool=*"}, true, ""},
+	} {
+		ms := &machine.MachineState{ID: "XXX", Metadata: map[string]string{}}
+		if tt.pool != "" {
+			ms.Metadata["pool"] = tt.pool
+		}
+		as := NewAgentState(ms)
+		j := &job.Job{Name: "new.service", Unit: fleetUnit(t, tt.opts...)}
+		if got, reason := as.AbleToRun(j); got != tt.want || reason != tt.reason {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t (%q)", i, got, reason, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestAbleToRunCordoned(t *testing.T) {
+	for i, tt := range []struct {
+		cordoned, draining bool
+		name               string
+		want               bool
+	}{
+		{false, false, "new.service", true},
+		{false, false, "existing.service", true},
+		// cordoned machines keep the Units already scheduled to them
+		{true, false, "new.service", false},
+		{true, false, "existing.service", true},
+		{true, true, "new.service", false},
+		{true, true, "existing.service", false},
+		// global Units are unaffected
+		{true, true, "global.service", true},
+	} {
+		as := NewAgentState(&machine.MachineState{ID: "XXX", Cordoned: tt.cordoned, Draining: tt.draining})
+		as.Units["existing.service"] = &job.Unit{Name: "existing.service"}
+
+		j := &job.Job{Name: tt.name}
+		if tt.name == "global.service" {
+			j.Unit = fleetUnit(t, "Global=true")
+		}
+		if got, reason := as.AbleToRun(j); got != tt.want {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t", i, got, reason, tt.want)
+		}
+	}
+}
+
+func TestAbleToRunTainted(t *testing.T) {
+	taints := []machine.Taint{machine.Taint{Key: "dedicated", Value: "db", Effect: machine.TaintEffectNoSchedule}}
+	for i, tt := range []struct {
+		name     string
+		contents string
+		want     bool
+	}{
+		{"new.service", "", false},
+		{"new.service", "Tolerates=dedicated=db:NoSchedule", true},
+		{"new.service", "Tolerates=dedicated", true},
+		{"new.service", "Tolerates=dedicated=web", false},
+		// the Units already scheduled to the machine stay there
+		{"existing.service", "", true},
+		{"global.service", "Global=true", false},
+		{"global.service", "Global=true\nTolerates=dedicated", true},
+	} {
+		as := NewAgentState(&machine.MachineState{ID: "XXX", Taints: taints})
+		as.Units["existing.service"] = &job.Unit{Name: "existing.service"}
+
+		j := &job.Job{Name: tt.name, Unit: fleetUnit(t, tt.contents)}
+		if got, reason := as.AbleToRun(j); got != tt.want {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t", i, got, reason, tt.want)
+		}
+	}
+}
+
+func TestAbleToRunUnitLimit(t *testing.T) {
+	for i, tt := range []struct {
+		max      int
+		metadata map[string]string
+		name     string
+		contents string
+		want     bool
+	}{
+		{0, nil, "new.service", "", true},
+		{3, nil, "new.service", "", true},
+		{2, nil, "new.service", "", false},
+		{2, map[string]string{"max-units-per-machine": "3"}, "new.service", "", true},
+		{3, map[string]string{"max-units-per-machine": "2"}, "new.service", "", false},
+		// the Units already scheduled count against the limit, but are
+		// not rejected themselves
+		{1, nil, "existing.service", "", true},
+		// replaced Units make room for the Job
+		{2, nil, "new.service", "Replaces=existing.service", true},
+		{2, nil, "global.service", "Global=true", false},
+	} {
+		as := NewAgentState(&machine.MachineState{ID: "XXX", MaxUnits: tt.max, Metadata: tt.metadata})
+		as.Units["existing.service"] = &job.Unit{Name: "existing.service"}
+		as.Units["other.service"] = &job.Unit{Name: "other.service"}
+
+		j := &job.Job{Name: tt.name, Unit: fleetUnit(t, tt.contents)}
+		if got, reason := as.AbleToRun(j); got != tt.want {
+			t.Errorf("case %d: AbleToRun returned %t (%q), want %t", i, got, reason, tt.want)
+		}
+	}
+}
+
+func TestNextWindowOpen(t *testing.T) {
+	as := NewAgentState(&machine.MachineState{ID: "XXX"})
+	as.Units["plain.service"] = &job.Unit{Name: "plain.service"}
+
+	u, err := unit.NewUnitFile("[X-Fleet]\nWorkloadWindow=22:00-06:00")
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit: %v", err)
+	}
+	as.Units["batch.service"] = &job.Unit{Name: "batch.service", Unit: *u}
+
+	if got := as.NextWindowOpen("missing.service"); !got.IsZero() {
+		t.Errorf("Expected zero time for unknown Unit, got %v", got)
+	}
+	if got := as.NextWindowOpen("plain.service"); !got.IsZero() {
+		t.Errorf("Expected zero time for Unit without window, got %v", got)
+	}
+
+	now := time.Now()
+	got := as.NextWindowOpen("batch.service")
+	if got.Before(now.Add(-time.Minute)) || got.After(now.Add(24*time.Hour)) {
+		t.Errorf("Expected window to open within a day, got %v", got)
+	}
+}
+
 func TestGlobMatches(t *testing.T) {
 	tests := []struct {
 		pattern  string
@@ -107,3 +637,60 @@ func TestGlobMatches(t *testing.T) {
 		}
 	}
 }
+
+// newBenchmarkAgentState returns an AgentState with the given number of
+// scheduled units, each carrying a Conflicts requirement so that AbleToRun
+// has to evaluate glob matches against every one of them.
+func newBenchmarkAgentState(b *testing.B, count int) *AgentState {
+	as := NewAgentState(&machine.MachineState{ID: "XXX", Metadata: map[string]string{"region": "us-west"}})
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("existing-%d.service", i)
+		uf, err := unit.NewUnitFile(fmt.Sprintf("[X-Fleet]\nConflicts=existing-%d@*.service", i))
+		if err != nil {
+			b.Fatalf("Failed creating benchmark unit: %v", err)
+		}
+		as.Units[name] = &job.Unit{Name: name, Unit: *uf}
+	}
+	return as
+}
+
+func newBenchmarkJob(b *testing.B, name string) *job.Job {
+	uf, err := unit.NewUnitFile("[X-Fleet]\nConflicts=other-*.service\nMachineMetadata=region=us-west")
+	if err != nil {
+		b.Fatalf("Failed creating benchmark unit: %v", err)
+	}
+	return job.NewJob(name, *uf)
+}
+
+func benchmarkAbleToRun(b *testing.B, count int) {
+	as := newBenchmarkAgentState(b, count)
+	j := newBenchmarkJob(b, "candidate.service")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if able, reason := as.AbleToRun(j); !able {
+			b.Fatalf("Expected job to be schedulable, got reason %q", reason)
+		}
+	}
+}
+
+func BenchmarkAbleToRun10(b *testing.B)   { benchmarkAbleToRun(b, 10) }
+func BenchmarkAbleToRun100(b *testing.B)  { benchmarkAbleToRun(b, 100) }
+func BenchmarkAbleToRun1000(b *testing.B) { benchmarkAbleToRun(b, 1000) }
+
+// BenchmarkAbleToRunCandidates mirrors a single scheduling pass of the
+// engine, in which a batch of unscheduled jobs is offered to one agent.
+func BenchmarkAbleToRunCandidates(b *testing.B) {
+	as := newBenchmarkAgentState(b, 100)
+	jobs := make([]*job.Job, 50)
+	for i := range jobs {
+		jobs[i] = newBenchmarkJob(b, fmt.Sprintf("candidate-%d.service", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, j := range jobs {
+			as.AbleToRun(j)
+		}
+	}
+}
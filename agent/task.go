@@ -121,7 +121,7 @@ func mapTaskToFunc(t task, u *job.Unit, a *Agent) (fn func() error, err error) {
 	case taskTypeUnloadUnit:
 		fn = func() error { a.unloadUnit(u.Name); return nil }
 	case taskTypeStartUnit:
-		fn = func() error { a.startUnit(u.Name); return nil }
+		fn = func() error { a.startUnitThrottled(u); return nil }
 	case taskTypeStopUnit:
 		fn = func() error { a.stopUnit(u.Name); return nil }
 	default:
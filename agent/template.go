@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	variableMachineID       = "FLEET_MACHINE_ID"
+	variableMachinePublicIP = "FLEET_MACHINE_PUBLIC_IP"
+	variableMachineMetadata = "FLEET_MACHINE_METADATA_"
+	variableUnitName        = "FLEET_UNIT_NAME"
+	variableUnitPrefix      = "FLEET_UNIT_PREFIX"
+	variableUnitInstance    = "FLEET_UNIT_INSTANCE"
+)
+
+// unitVariables returns the variables the placeholders of the unit file of
+// the named Unit are substituted with on the given machine
+func unitVariables(name string, ms machine.MachineState) map[string]string {
+	vars := map[string]string{
+		variableMachineID:       ms.ID,
+		variableMachinePublicIP: ms.PublicIP,
+		variableUnitName:        name,
+	}
+	if uni := unit.NewUnitNameInfo(name); uni != nil {
+		vars[variableUnitPrefix] = uni.Prefix
+		vars[variableUnitInstance] = uni.Instance
+	}
+	for key, val := range ms.Metadata {
+		vars[variableMachineMetadata+key] = val
+	}
+	return vars
+}
+
+// renderUnit returns the unit file of the given Unit running its container,
+// if it declares a Runtime, with the fleet variables it refers to
+// substituted, or nil if the Unit runs its unit file unchanged
+func (a *Agent) renderUnit(u *job.Unit) *unit.UnitFile {
+	uf := &u.Unit
+	rendered := renderRuntime(u)
+	if rendered != nil {
+		uf = rendered
+	}
+	if !strings.Contains(uf.String(), "${FLEET_") {
+		return rendered
+	}
+	if expanded, ok := uf.ExpandVariables(unitVariables(u.Name, a.Machine.State())); ok {
+		rendered = expanded
+	}
+	return rendered
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestAgentLoadUnitRendered(t *testing.T) {
+	uManager := unit.NewFakeUnitManager()
+	usGenerator := unit.NewUnitStateGenerator(uManager)
+	fReg := registry.NewFakeRegistry()
+	mach := &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX", PublicIP: "10.0.0.1", Metadata: map[string]string{"region": "us-west"}}}
+	a := New(uManager, usGenerator, fReg, mach, time.Second)
+
+	u := newTestUnitFromUnitContents(t, "foo@1.service", "[Service]\nExecStart=/bin/foo ${FLEET_MACHINE_ID} ${FLEET_MACHINE_PUBLIC_IP} ${FLEET_MACHINE_METADATA_region} ${FLEET_UNIT_PREFIX} ${FLEET_UNIT_INSTANCE} ${FLEET_MACHINE_METADATA_role}")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	r := uManager.Rendered("foo@1.service")
+	if want := "[Service]\nExecStart=/bin/foo XXX 10.0.0.1 us-west foo 1 ${FLEET_MACHINE_METADATA_role}\n"; r == nil || r.String() != want {
+		t.Fatalf("Unexpected rendering %v, want %q", r, want)
+	}
+
+	// units without placeholders are loaded as they are
+	u = newTestUnitFromUnitContents(t, "foo@1.service", "[Service]\nExecStart=/bin/foo ${HOME}")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	if r := uManager.Rendered("foo@1.service"); r != nil {
+		t.Errorf("Expected no rendering, got %v", r)
+	}
+}
+
+func TestAgentLoadUnitRuntime(t *testing.T) {
+	uManager := unit.NewFakeUnitManager()
+	usGenerator := unit.NewUnitStateGenerator(uManager)
+	fReg := registry.NewFakeRegistry()
+	mach := &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX"}}
+	a := New(uManager, usGenerator, fReg, mach, time.Second)
+
+	u := newTestUnitFromUnitContents(t, "web@1.service", "[Service]\nRestart=always\n\n[X-Fleet]\nRuntime=docker\nRuntimeImage=nginx:1.9\nRuntimeArgs=--port ${FLEET_UNIT_INSTANCE}\nMemoryReservation=128\nCPUUnits=50\nEnforceReservations=hard")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	want := `[Service]
+Restart=always
+ExecStartPre=-/usr/bin/docker rm -f fleet-web_1
+ExecStartPre=/usr/bin/docker pull nginx:1.9
+ExecStart=/usr/bin/docker run --rm --name fleet-web_1 --memory-reservation=128m --memory=128m --cpu-shares=512 --cpu-period=100000 --cpu-quota=50000 nginx:1.9 --port 1
+ExecStop=/usr/bin/docker stop fleet-web_1
+
+[X-Fleet]
+Runtime=docker
+RuntimeImage=nginx:1.9
+RuntimeArgs=--port ${FLEET_UNIT_INSTANCE}
+MemoryReservation=128
+CPUUnits=50
+EnforceReservations=hard
+`
+	if r := uManager.Rendered("web@1.service"); r == nil || r.String() != want {
+		t.Fatalf("Unexpected rendering %v, want %q", r, want)
+	}
+
+	u = newTestUnitFromUnitContents(t, "db.service", "[Service]\nExecStartPre=/bin/ignored\n\n[X-Fleet]\nRuntime=rkt\nRuntimeImage=coreos.com/etcd:v2.0.0\nRuntimeArgs=--name db")
+	if err := a.loadUnit(u); err != nil {
+		t.Fatalf("Failed calling Agent.loadUnit: %v", err)
+	}
+	want = `[Service]
+ExecStartPre=/usr/bin/rkt fetch coreos.com/etcd:v2.0.0
+ExecStart=/usr/bin/rkt run coreos.com/etcd:v2.0.0 -- --name db
+
+[X-Fleet]
+Runtime=rkt
+RuntimeImage=coreos.com/etcd:v2.0.0
+RuntimeArgs=--name db
+`
+	if r := uManager.Rendered("db.service"); r == nil || r.String() != want {
+		t.Fatalf("Unexpected rendering %v, want %q", r, want)
+	}
+}
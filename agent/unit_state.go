@@ -2,6 +2,7 @@ package agent
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"sync"
 	"time"
@@ -13,19 +14,29 @@ import (
 	"github.com/coreos/fleet/unit"
 )
 
-const numPublishers = 5
+const (
+	numPublishers = 5
+
+	// batchInterval is the amount of time changed UnitStates are collected
+	// before they are published together
+	batchInterval = time.Second
+)
 
 func NewUnitStatePublisher(reg registry.Registry, mach machine.Machine, ttl time.Duration) *UnitStatePublisher {
+	clock := pkg.NewRealClock()
+	batch := newBatchPublisher(reg, mach, ttl, clock)
 	return &UnitStatePublisher{
 		mach:            mach,
 		ttl:             ttl,
-		publisher:       newPublisher(reg, ttl),
+		publisher:       batch.publish,
+		batch:           batch,
+		recordEvent:     newEventRecorder(reg),
 		cache:           make(map[string]*unit.UnitState),
 		cacheMutex:      sync.RWMutex{},
 		toPublish:       make(chan string),
 		toPublishStates: make(map[string]*unit.UnitState),
 		toPublishMutex:  sync.RWMutex{},
-		clock:           pkg.NewRealClock(),
+		clock:           clock,
 	}
 }
 
@@ -49,6 +60,14 @@ type UnitStatePublisher struct {
 
 	publisher publishFunc
 
+	// batch, if set, collects the UnitStates passed to the publisher and
+	// publishes them to the Registry as a single document
+	batch *batchPublisher
+
+	// recordEvent, if set, is called with a ClusterEvent for each
+	// change in the systemd state of a Unit
+	recordEvent func(ev registry.ClusterEvent)
+
 	clock pkg.Clock
 }
 
@@ -72,6 +91,10 @@ func (p *UnitStatePublisher) Run(beatchan <-chan *unit.UnitStateHeartbeat, stop
 		}
 	}()
 
+	if p.batch != nil {
+		go p.batch.run(stop)
+	}
+
 	machID := p.mach.State().ID
 
 	// Spawn goroutines to publish unit states. Each goroutine waits until
@@ -167,42 +190,183 @@ func (p *UnitStatePublisher) updateCache(update *unit.UnitStateHeartbeat) (chang
 	if !ok || !reflect.DeepEqual(last, update.State) {
 		changed = true
 	}
+
+	if p.recordEvent != nil && stateTransitioned(last, update.State) {
+		go p.recordEvent(registry.ClusterEvent{
+			Type:      registry.EventUnitStateChanged,
+			UnitName:  update.Name,
+			MachineID: update.State.MachineID,
+			Reason:    fmt.Sprintf("%s/%s -> %s/%s", last.ActiveState, last.SubState, update.State.ActiveState, update.State.SubState),
+		})
+	}
 	return
 }
 
+// stateTransitioned returns whether the systemd active or sub state of a
+// Unit differs between two of its UnitStates
+func stateTransitioned(last, cur *unit.UnitState) bool {
+	if last == nil || cur == nil {
+		return false
+	}
+	return last.ActiveState != cur.ActiveState || last.SubState != cur.SubState
+}
+
+// newEventRecorder returns a function recording ClusterEvents in the given
+// Registry, or nil if there is no Registry
+func newEventRecorder(reg registry.Registry) func(ev registry.ClusterEvent) {
+	if reg == nil {
+		return nil
+	}
+	return func(ev registry.ClusterEvent) {
+		if err := reg.RecordEvent(ev); err != nil {
+			log.Errorf("Failed recording %s event of Unit(%s): %v", ev.Type, ev.UnitName, err)
+		}
+	}
+}
+
 // Purge ensures that the UnitStates for all Units known in the
 // UnitStatePublisher's cache are removed from the registry.
 func (p *UnitStatePublisher) Purge() {
+	if p.batch != nil {
+		var names []string
+		for name := range p.cache {
+			names = append(names, name)
+		}
+		p.batch.purge(names)
+		return
+	}
+
 	for name := range p.cache {
 		p.publisher(name, nil)
 	}
 }
 
-// newPublisher returns a publishFunc that publishes a single UnitState
-// by the given name to the provided Registry, with the given TTL
-func newPublisher(reg registry.Registry, ttl time.Duration) publishFunc {
-	return func(name string, us *unit.UnitState) {
-		if us == nil {
-			log.V(1).Infof("Destroying UnitState(%s) in Registry", name)
-			err := reg.RemoveUnitState(name)
-			if err != nil {
-				log.Errorf("Failed to destroy UnitState(%s) in Registry: %v", name, err)
-			}
-		} else {
-			// Sanity check - don't want to publish incomplete UnitStates
-			// TODO(jonboulle): consider teasing apart a separate UnitState-like struct
-			// so we can rely on a UnitState always being fully hydrated?
-
-			// See https://github.com/coreos/fleet/issues/720
-			//if len(us.UnitHash) == 0 {
-			//	log.Errorf("Refusing to push UnitState(%s), no UnitHash: %#v", name, us)
-
-			if len(us.MachineID) == 0 {
-				log.Errorf("Refusing to push UnitState(%s), no MachineID: %#v", name, us)
-			} else {
-				log.V(1).Infof("Pushing UnitState(%s) to Registry: %#v", name, us)
-				reg.SaveUnitState(name, us, ttl)
+// batchPublisher publishes the UnitStates of all Units on the local machine
+// to the Registry as a single document. The document is only written once
+// a change to any of the UnitStates has been collected for batchInterval,
+// and to refresh its TTL.
+type batchPublisher struct {
+	reg   registry.Registry
+	mach  machine.Machine
+	ttl   time.Duration
+	clock pkg.Clock
+
+	mutex     sync.Mutex
+	states    map[string]*unit.UnitState
+	dirty     bool
+	published bool
+
+	// changed is signalled when the collected UnitStates changed
+	changed chan struct{}
+}
+
+func newBatchPublisher(reg registry.Registry, mach machine.Machine, ttl time.Duration, clock pkg.Clock) *batchPublisher {
+	return &batchPublisher{
+		reg:     reg,
+		mach:    mach,
+		ttl:     ttl,
+		clock:   clock,
+		states:  make(map[string]*unit.UnitState),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// publish is a publishFunc collecting the given UnitState, or its removal
+// if nil, for the next write of the document
+func (bp *batchPublisher) publish(name string, us *unit.UnitState) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+
+	last, ok := bp.states[name]
+	if us == nil {
+		if !ok {
+			return
+		}
+		log.V(1).Infof("Destroying UnitState(%s) in Registry", name)
+		delete(bp.states, name)
+	} else {
+		// Sanity check - don't want to publish incomplete UnitStates
+		// See https://github.com/coreos/fleet/issues/720
+		if len(us.MachineID) == 0 {
+			log.Errorf("Refusing to push UnitState(%s), no MachineID: %#v", name, us)
+			return
+		}
+		if ok && reflect.DeepEqual(last, us) {
+			return
+		}
+		log.V(1).Infof("Pushing UnitState(%s) to Registry: %#v", name, us)
+		bp.states[name] = us
+	}
+
+	bp.dirty = true
+	select {
+	case bp.changed <- struct{}{}:
+	default:
+	}
+}
+
+// run writes the document batchInterval after each change, and refreshes
+// it every half TTL, until the stop channel is closed
+func (bp *batchPublisher) run(stop chan bool) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-bp.changed:
+			select {
+			case <-stop:
+				return
+			case <-bp.clock.After(batchInterval):
 			}
+		case <-bp.clock.After(bp.ttl / 2):
 		}
+		bp.flush()
+	}
+}
+
+// flush writes the collected UnitStates to the Registry, unless there are
+// none and there were none before
+func (bp *batchPublisher) flush() {
+	bp.mutex.Lock()
+	if len(bp.states) == 0 && !bp.dirty {
+		bp.mutex.Unlock()
+		return
 	}
+	states := make(map[string]*unit.UnitState, len(bp.states))
+	for name, us := range bp.states {
+		states[name] = us
+	}
+	bp.dirty = false
+	bp.mutex.Unlock()
+
+	machID := bp.mach.State().ID
+	if err := bp.reg.SaveUnitStates(machID, states, bp.ttl); err != nil {
+		log.Errorf("Failed publishing UnitStates of Machine(%s): %v", machID, err)
+		bp.mutex.Lock()
+		bp.dirty = true
+		bp.mutex.Unlock()
+		return
+	}
+
+	bp.mutex.Lock()
+	bp.published = true
+	bp.mutex.Unlock()
+}
+
+// purge removes the document from the Registry, along with the UnitStates
+// of the given Units published individually, e.g. by an earlier version of
+// fleet
+func (bp *batchPublisher) purge(names []string) {
+	for _, name := range names {
+		log.V(1).Infof("Destroying UnitState(%s) in Registry", name)
+		if err := bp.reg.RemoveUnitState(name); err != nil {
+			log.Errorf("Failed to destroy UnitState(%s) in Registry: %v", name, err)
+		}
+	}
+
+	bp.mutex.Lock()
+	bp.states = make(map[string]*unit.UnitState)
+	bp.dirty = bp.published
+	bp.mutex.Unlock()
+	bp.flush()
 }
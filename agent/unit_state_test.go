@@ -107,6 +107,32 @@ func TestUpdateCache(t *testing.T) {
 	}
 }
 
+func TestUpdateCacheRecordsTransitions(t *testing.T) {
+	recorded := make(chan registry.ClusterEvent, 1)
+	usp := NewUnitStatePublisher(nil, &machine.FakeMachine{}, 0)
+	usp.recordEvent = func(ev registry.ClusterEvent) { recorded <- ev }
+
+	usp.updateCache(&unit.UnitStateHeartbeat{Name: "foo.service", State: &unit.UnitState{ActiveState: "inactive", SubState: "dead"}})
+	usp.updateCache(&unit.UnitStateHeartbeat{Name: "foo.service", State: &unit.UnitState{ActiveState: "inactive", SubState: "dead", Health: "healthy"}})
+	usp.updateCache(&unit.UnitStateHeartbeat{Name: "foo.service", State: &unit.UnitState{ActiveState: "active", SubState: "running", MachineID: "XXX"}})
+
+	select {
+	case ev := <-recorded:
+		want := registry.ClusterEvent{Type: registry.EventUnitStateChanged, UnitName: "foo.service", MachineID: "XXX", Reason: "inactive/dead -> active/running"}
+		if !reflect.DeepEqual(ev, want) {
+			t.Errorf("Unexpected event:\ngot\n%#v\nwant\n%#v", ev, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("No event recorded")
+	}
+
+	select {
+	case ev := <-recorded:
+		t.Errorf("Unexpected extra event: %#v", ev)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
 func TestPruneCache(t *testing.T) {
 	tests := []struct {
 		cacheBefore map[string]*unit.UnitState
@@ -197,78 +223,80 @@ func TestPurge(t *testing.T) {
 	}
 }
 
-func TestDefaultPublisher(t *testing.T) {
-	testCases := []struct {
-		name       string
-		state      *unit.UnitState
-		initStates []unit.UnitState
-		want       []*unit.UnitState
-	}{
-		// Simplest case - success
-		{
-			"foo.service",
-			&unit.UnitState{
-				UnitName:    "foo.service",
-				ActiveState: "active",
-				MachineID:   "xyz",
-			},
-			nil,
-			[]*unit.UnitState{
-				&unit.UnitState{
-					UnitName:    "foo.service",
-					ActiveState: "active",
-					MachineID:   "xyz",
-				},
-			},
-		},
-		// Unit state with no machine ID is refused
-		{
-			"foo.service",
-			&unit.UnitState{
-				UnitName:    "foo.service",
-				ActiveState: "active",
-			},
-			nil,
-			[]*unit.UnitState{},
-		},
-		// Destroying existing unit state
-		{
-			"foo.service",
-			nil,
-			[]unit.UnitState{
-				unit.UnitState{
-					UnitName:    "foo.service",
-					ActiveState: "active",
-				},
-			},
-			[]*unit.UnitState{},
-		},
-		// Destroying non-existent unit state should not fail
-		{
-			"foo.service",
-			nil,
-			[]unit.UnitState{},
-			[]*unit.UnitState{},
-		},
-	}
+// countingRegistry counts the documents of UnitStates saved to a
+// FakeRegistry
+type countingRegistry struct {
+	*registry.FakeRegistry
+	saves int
+}
+
+func (cr *countingRegistry) SaveUnitStates(machID string, states map[string]*unit.UnitState, ttl time.Duration) error {
+	cr.saves++
+	return cr.FakeRegistry.SaveUnitStates(machID, states, ttl)
+}
 
-	for i, tt := range testCases {
-		freg := registry.NewFakeRegistry()
-		freg.SetUnitStates(tt.initStates)
-		usp := NewUnitStatePublisher(freg, &machine.FakeMachine{}, 0)
-		usp.publisher(tt.name, tt.state)
-		us, err := freg.UnitStates()
+func TestBatchPublisher(t *testing.T) {
+	freg := &countingRegistry{FakeRegistry: registry.NewFakeRegistry()}
+	mach := &machine.FakeMachine{MachineState: machine.MachineState{ID: "xyz"}}
+	bp := newBatchPublisher(freg, mach, time.Minute, pkg.NewFakeClock())
+
+	foo := &unit.UnitState{UnitName: "foo.service", ActiveState: "active", MachineID: "xyz"}
+	bar := &unit.UnitState{UnitName: "bar.service", ActiveState: "active", MachineID: "xyz"}
+
+	assertStates := func(desc string, saves int, want []*unit.UnitState) {
+		if freg.saves != saves {
+			t.Errorf("%s: %d documents saved, want %d", desc, freg.saves, saves)
+		}
+		got, err := freg.UnitStates()
 		if err != nil {
-			t.Errorf("case %d: unexpected error retrieving unit states: %v", err)
-			continue
+			t.Fatalf("%s: unexpected error retrieving unit states: %v", desc, err)
 		}
-		if !reflect.DeepEqual(us, tt.want) {
-			msg := fmt.Sprintf("case %d: received unexpected unit states\n", i)
-			msg += fmt.Sprintf("got: %#v\n", us)
-			msg += fmt.Sprintf("want: %#v\n", tt.want)
-			t.Error(msg)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: received unexpected unit states\ngot: %#v\nwant: %#v", desc, got, want)
 		}
 	}
+
+	// Nothing is written before the first state is collected
+	bp.flush()
+	assertStates("empty", 0, []*unit.UnitState{})
+
+	// Changes are written together
+	bp.publish("foo.service", foo)
+	bp.publish("bar.service", bar)
+	bp.flush()
+	assertStates("first changes", 1, []*unit.UnitState{bar, foo})
+
+	select {
+	case <-bp.changed:
+	default:
+		t.Fatalf("changes not signalled")
+	}
+
+	// Publishing unchanged states is a no-op
+	bp.publish("foo.service", &unit.UnitState{UnitName: "foo.service", ActiveState: "active", MachineID: "xyz"})
+	select {
+	case <-bp.changed:
+		t.Errorf("unchanged state signalled as change")
+	default:
+	}
+
+	// Unit states with no machine ID are refused
+	bp.publish("baz.service", &unit.UnitState{UnitName: "baz.service", ActiveState: "active"})
+
+	// Destroying a unit state rewrites the document
+	bp.publish("bar.service", nil)
+	bp.publish("qux.service", nil)
+	bp.flush()
+	assertStates("destroy", 2, []*unit.UnitState{foo})
+
+	// Refreshing the TTL rewrites the document even without changes
+	bp.flush()
+	assertStates("refresh", 3, []*unit.UnitState{foo})
+
+	bp.purge([]string{"foo.service"})
+	assertStates("purge", 4, []*unit.UnitState{})
+	bp.flush()
+	assertStates("after purge", 4, []*unit.UnitState{})
 }
 
 func TestUnitStatePublisherRunTiming(t *testing.T) {
@@ -714,7 +742,7 @@ func TestMarshalJSON(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error marshalling: %v", err)
 	}
-	want = `{"Cache":{"bar.service":{"LoadState":"","ActiveState":"inactive","SubState":"","MachineID":"asdf","UnitHash":"","UnitName":"bar.service"},"foo.service":{"LoadState":"","ActiveState":"active","SubState":"","MachineID":"asdf","UnitHash":"","UnitName":"foo.service"}},"ToPublish":{"woof.service":{"LoadState":"","ActiveState":"active","SubState":"","MachineID":"asdf","UnitHash":"","UnitName":"woof.service"}}}`
+	want = `{"Cache":{"bar.service":{"LoadState":"","ActiveState":"inactive","SubState":"","MachineID":"asdf","UnitHash":"","UnitName":"bar.service","Reason":"","Health":"","UsedCPUUnits":0,"UsedMemory":0},"foo.service":{"LoadState":"","ActiveState":"active","SubState":"","MachineID":"asdf","UnitHash":"","UnitName":"foo.service","Reason":"","Health":"","UsedCPUUnits":0,"UsedMemory":0}},"ToPublish":{"woof.service":{"LoadState":"","ActiveState":"active","SubState":"","MachineID":"asdf","UnitHash":"","UnitName":"woof.service","Reason":"","Health":"","UsedCPUUnits":0,"UsedMemory":0}}}`
 	if string(got) != want {
 		t.Fatalf("Bad JSON representation: got\n%s\n\nwant\n%s", string(got), want)
 	}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/unit"
+)
+
+// hashTracker remembers the Hash of the unit file last written for each
+// local Unit, indexed by Unit name
+type hashTracker struct {
+	mutex  sync.Mutex
+	hashes map[string]unit.Hash
+}
+
+func (ht *hashTracker) get(name string) (h unit.Hash, ok bool) {
+	ht.mutex.Lock()
+	defer ht.mutex.Unlock()
+	h, ok = ht.hashes[name]
+	return
+}
+
+func (ht *hashTracker) set(name string, h unit.Hash) {
+	ht.mutex.Lock()
+	defer ht.mutex.Unlock()
+	if ht.hashes == nil {
+		ht.hashes = make(map[string]unit.Hash)
+	}
+	ht.hashes[name] = h
+}
+
+func (ht *hashTracker) forget(name string) {
+	ht.mutex.Lock()
+	defer ht.mutex.Unlock()
+	delete(ht.hashes, name)
+}
+
+// handleUpdates writes the unit file of each loaded Unit whose unit file was
+// replaced since it was loaded, restarting the Unit if launched. Units with
+// `UpdatePolicy=manual` keep their earlier unit file until they are next
+// loaded. Units with `UpdatePolicy=reschedule` are unscheduled when replaced,
+// so they are only updated here if scheduled back to this machine before it
+// unloaded them, or if they are global.
+func (ar *AgentReconciler) handleUpdates(a *Agent, dState *AgentState) {
+	for name, u := range dState.Units {
+		prev, ok := a.hashes.get(name)
+		if !ok || prev == u.Unit.Hash() {
+			continue
+		}
+
+		if u.UpdatePolicy() == job.UpdateManual {
+			log.V(1).Infof("Unit(%s) keeps running its earlier unit file %s until it is next loaded", name, prev.Short())
+			continue
+		}
+
+		if err := a.writeUnit(u); err != nil {
+			log.Errorf("Failed writing replaced unit file of Unit(%s): %v", name, err)
+			continue
+		}
+
+		if u.TargetState == job.JobStateLaunched {
+			log.Infof("Restarting Unit(%s) as its unit file was replaced", name)
+			a.um.TriggerRestart(name)
+		} else {
+			log.Infof("Unit file of Unit(%s) replaced, taking effect when it next starts", name)
+		}
+	}
+}
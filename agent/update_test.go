@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestHandleUpdates(t *testing.T) {
+	uManager := unit.NewFakeUnitManager()
+	usGenerator := unit.NewUnitStateGenerator(uManager)
+	fReg := registry.NewFakeRegistry()
+	mach := &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX"}}
+	a := New(uManager, usGenerator, fReg, mach, time.Second)
+	ar := NewReconciler(fReg, nil)
+
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	for _, u := range []*job.Unit{
+		&job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[Service]\nExecStart=/bin/foo")},
+		&job.Unit{Name: "bar.service", TargetState: job.JobStateLoaded, Unit: newUF(t, "[Service]\nExecStart=/bin/bar")},
+		&job.Unit{Name: "baz.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[Service]\nExecStart=/bin/baz")},
+		&job.Unit{Name: "qux.service", TargetState: job.JobStateLaunched, Unit: newUF(t, "[Service]\nExecStart=/bin/qux")},
+	} {
+		dState.Units[u.Name] = u
+		if err := a.loadUnit(u); err != nil {
+			t.Fatalf("Failed loading Unit(%s): %v", u.Name, err)
+		}
+	}
+
+	// nothing changed
+	ar.handleUpdates(a, dState)
+	if len(uManager.Restarted) != 0 {
+		t.Errorf("Unexpected restarts: %v", uManager.Restarted)
+	}
+
+	dState.Units["foo.service"].Unit = newUF(t, "[Service]\nExecStart=/bin/foo --v2")
+	dState.Units["bar.service"].Unit = newUF(t, "[Service]\nExecStart=/bin/bar --v2")
+	dState.Units["baz.service"].Unit = newUF(t, "[Service]\nExecStart=/bin/baz --v2\n[X-Fleet]\nUpdatePolicy=manual")
+	ar.handleUpdates(a, dState)
+
+	// only launched Units are restarted, and only unless updated manually
+	if want := []string{"foo.service"}; !reflect.DeepEqual(uManager.Restarted, want) {
+		t.Errorf("Unexpected restarts: got %v, want %v", uManager.Restarted, want)
+	}
+	for name, current := range map[string]bool{"foo.service": true, "bar.service": true, "baz.service": false, "qux.service": true} {
+		h, _ := a.hashes.get(name)
+		if got := h == dState.Units[name].Unit.Hash(); got != current {
+			t.Errorf("Unit(%s) runs its current unit file %t, want %t", name, got, current)
+		}
+	}
+
+	// units are only updated once
+	ar.handleUpdates(a, dState)
+	if len(uManager.Restarted) != 1 {
+		t.Errorf("Unexpected restarts: %v", uManager.Restarted)
+	}
+
+	// unloaded units are forgotten
+	a.unloadUnit("baz.service")
+	if _, ok := a.hashes.get("baz.service"); ok {
+		t.Errorf("Expected hash of unloaded Unit to be forgotten")
+	}
+}
@@ -0,0 +1,263 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-semver/semver"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+// UpgradeVersionPlaceholder is replaced with the version to upgrade to in
+// the path of the fleetd binary given to an Upgrader
+const UpgradeVersionPlaceholder = "{version}"
+
+// upgradeVersionRegexp matches the characters semantic versions are made of,
+// none of which separate paths
+var upgradeVersionRegexp = regexp.MustCompile(`^[0-9A-Za-z.+-]+$`)
+
+// NewUpgrader creates an Upgrader restarting the local fleetd into the
+// binary found at the given path, in which UpgradeVersionPlaceholder stands
+// for the version to upgrade to. restart replaces the running fleetd with
+// the given binary, returning only if it fails to.
+func NewUpgrader(reg registry.Registry, mach machine.Machine, binary string, restart func(path string) error) *Upgrader {
+	return &Upgrader{
+		reg:     reg,
+		mach:    mach,
+		binary:  binary,
+		restart: restart,
+		version: binaryVersion,
+	}
+}
+
+// Upgrader follows the UpgradePlan of the cluster: once it is the turn of
+// the failure domain of the local machine, it cordons the machine so that no
+// new units are scheduled to it, and restarts fleetd into the planned
+// version. Units keep running while fleetd restarts. Once the new fleetd
+// is up, it uncordons the machine and reports the upgrade done, letting the
+// next failure domain proceed.
+type Upgrader struct {
+	reg     registry.Registry
+	mach    machine.Machine
+	binary  string
+	restart func(path string) error
+
+	// version returns the version of the fleetd binary at the given path
+	version func(path string) (string, error)
+
+	// failed holds the UpgradePlan the local machine failed to follow,
+	// which is not attempted again until planned anew
+	failed *registry.UpgradePlan
+}
+
+// Run follows the UpgradePlan of the cluster at the interval indicated
+// until the stop channel is closed.
+func (u *Upgrader) Run(ival time.Duration, stop chan bool) {
+	ticker := time.NewTicker(ival)
+	for {
+		select {
+		case <-stop:
+			log.V(1).Info("Halting Upgrader")
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			u.check()
+		}
+	}
+}
+
+func (u *Upgrader) check() {
+	plan, err := u.reg.UpgradePlan()
+	if err != nil {
+		log.Errorf("Failed fetching upgrade plan: %v", err)
+		return
+	}
+	if plan == nil {
+		return
+	}
+
+	ms := u.mach.State()
+	statuses, err := u.reg.UpgradeStatuses()
+	if err != nil {
+		log.Errorf("Failed fetching upgrade statuses: %v", err)
+		return
+	}
+	var prev registry.UpgradeStatus
+	for _, us := range statuses {
+		if us.MachineID == ms.ID {
+			prev = us
+		}
+	}
+
+	if ms.Version == plan.Version {
+		if prev.State == registry.UpgradeStateDone {
+			return
+		}
+		if prev.Cordoned {
+			if err := u.reg.UncordonMachine(ms.ID); err != nil {
+				log.Errorf("Failed uncordoning Machine(%s) after upgrade: %v", ms.ID, err)
+				return
+			}
+		}
+		log.Infof("Upgrade of Machine(%s) to fleetd %s done", ms.ID, plan.Version)
+		u.report(ms.ID, plan.Version, registry.UpgradeStateDone, "", false)
+		return
+	}
+
+	if u.failed != nil && *u.failed == *plan {
+		return
+	}
+
+	machines, err := u.reg.Machines()
+	if err != nil {
+		log.Errorf("Failed fetching machines: %v", err)
+		return
+	}
+	// Only the Registry knows whether the machine is cordoned
+	for _, m := range machines {
+		if m.ID == ms.ID {
+			ms.Cordoned = m.Cordoned
+		}
+	}
+	if !upgradeTurn(plan, machines, ms) {
+		if prev.State != registry.UpgradeStatePending {
+			u.report(ms.ID, plan.Version, registry.UpgradeStatePending, "", false)
+		}
+		return
+	}
+
+	if err := u.upgrade(plan, ms); err != nil {
+		log.Errorf("Failed upgrading Machine(%s) to fleetd %s: %v", ms.ID, plan.Version, err)
+		failed := *plan
+		u.failed = &failed
+		u.report(ms.ID, plan.Version, registry.UpgradeStateFailed, err.Error(), false)
+	}
+}
+
+// upgrade cordons the local machine, unless it already is, and restarts
+// fleetd into the planned version. It only returns if that fails, in which
+// case the machine is uncordoned again.
+func (u *Upgrader) upgrade(plan *registry.UpgradePlan, ms machine.MachineState) error {
+	if u.binary == "" {
+		return fmt.Errorf("upgrade_binary_path not configured")
+	}
+	path, err := upgradeBinary(u.binary, plan.Version)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	if v, err := u.version(path); err != nil {
+		return fmt.Errorf("unable to determine version of %s: %v", path, err)
+	} else if v != plan.Version {
+		return fmt.Errorf("%s is fleetd %s, not %s", path, v, plan.Version)
+	}
+
+	cordon := !ms.Cordoned
+	if cordon {
+		if err := u.reg.CordonMachine(ms.ID, false); err != nil {
+			return fmt.Errorf("unable to cordon machine: %v", err)
+		}
+	}
+	if err := u.report(ms.ID, plan.Version, registry.UpgradeStateRestarting, "", cordon); err != nil {
+		return err
+	}
+
+	log.Infof("Restarting into fleetd %s at %s", plan.Version, path)
+	err = u.restart(path)
+	if cordon {
+		if uerr := u.reg.UncordonMachine(ms.ID); uerr != nil {
+			log.Errorf("Failed uncordoning Machine(%s): %v", ms.ID, uerr)
+		}
+	}
+	return err
+}
+
+// upgradeBinary returns the path of the fleetd binary of the given version,
+// substituting it for UpgradeVersionPlaceholder in the given path. The
+// version must be a semantic version, and the binary must be found within
+// the directory the path names before the placeholder, so that no plan can
+// make fleetd restart into any other binary.
+func upgradeBinary(binary, version string) (string, error) {
+	if _, err := semver.NewVersion(version); err != nil || !upgradeVersionRegexp.MatchString(version) {
+		return "", fmt.Errorf("invalid version %q", version)
+	}
+
+	dir := binary
+	if i := strings.Index(binary, UpgradeVersionPlaceholder); i >= 0 {
+		dir = binary[:i]
+	}
+	dir = filepath.Dir(dir)
+
+	path := filepath.Clean(strings.Replace(binary, UpgradeVersionPlaceholder, version, -1))
+	if !strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+		return "", fmt.Errorf("binary %s of version %s is outside %s", path, version, dir)
+	}
+	return path, nil
+}
+
+func (u *Upgrader) report(machID, version, state, reason string, cordoned bool) error {
+	us := registry.UpgradeStatus{
+		MachineID: machID,
+		Version:   version,
+		State:     state,
+		Reason:    reason,
+		Cordoned:  cordoned,
+		Time:      time.Now(),
+	}
+	err := u.reg.SaveUpgradeStatus(us)
+	if err != nil {
+		log.Errorf("Failed reporting upgrade status of Machine(%s): %v", machID, err)
+	}
+	return err
+}
+
+// upgradeTurn determines whether the given machine is to upgrade now: the
+// failure domains of the cluster upgrade in the order of their names, and
+// each waits for all machines of the domains before it to run the planned
+// version. Without a DomainKey, each machine is a domain of its own.
+func upgradeTurn(plan *registry.UpgradePlan, machines []machine.MachineState, self machine.MachineState) bool {
+	domain := func(ms machine.MachineState) string {
+		if plan.DomainKey == "" {
+			return ms.ID
+		}
+		return ms.Metadata[plan.DomainKey]
+	}
+
+	pending := map[string]bool{domain(self): true}
+	for _, ms := range machines {
+		if ms.Version != plan.Version {
+			pending[domain(ms)] = true
+		}
+	}
+	domains := make([]string, 0, len(pending))
+	for d := range pending {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	return domains[0] == domain(self)
+}
+
+// binaryVersion runs the fleetd binary at the given path to determine its
+// version
+func binaryVersion(path string) (string, error) {
+	out, err := exec.Command(path, "-version").Output()
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("no version printed")
+	}
+	return fields[len(fields)-1], nil
+}
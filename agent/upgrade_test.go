@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestUpgradeTurn(t *testing.T) {
+	machines := []machine.MachineState{
+		{ID: "a1", Version: "0.9.0", Metadata: map[string]string{"az": "a"}},
+		{ID: "a2", Version: "0.9.0", Metadata: map[string]string{"az": "a"}},
+		{ID: "b1", Version: "0.8.3", Metadata: map[string]string{"az": "b"}},
+		{ID: "b2", Version: "0.9.0", Metadata: map[string]string{"az": "b"}},
+		{ID: "c1", Version: "0.8.3", Metadata: map[string]string{"az": "c"}},
+	}
+	byDomain := &registry.UpgradePlan{Version: "0.9.0", DomainKey: "az"}
+	oneByOne := &registry.UpgradePlan{Version: "0.9.0"}
+
+	for i, tt := range []struct {
+		plan *registry.UpgradePlan
+		self machine.MachineState
+		want bool
+	}{
+		{byDomain, machines[2], true},
+		{byDomain, machines[3], true},
+		{byDomain, machines[4], false},
+		{oneByOne, machines[2], true},
+		{oneByOne, machines[4], false},
+	} {
+		if got := upgradeTurn(tt.plan, machines, tt.self); got != tt.want {
+			t.Errorf("case %d: got %t, want %t", i, got, tt.want)
+		}
+	}
+}
+
+func TestUpgraderCheck(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "fleet-")
+	if err != nil {
+		t.Fatalf("Failed creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "fleetd-0.9.0"), nil, 0755); err != nil {
+		t.Fatalf("Failed writing binary: %v", err)
+	}
+
+	reg := registry.NewFakeRegistry()
+	self := machine.MachineState{ID: "XXX", Version: "0.8.3"}
+	reg.SetMachines([]machine.MachineState{self, {ID: "YYY", Version: "0.8.3"}})
+	mach := &machine.FakeMachine{MachineState: self}
+
+	var restarted []string
+	u := NewUpgrader(reg, mach, filepath.Join(dir, "fleetd-{version}"), func(path string) error {
+		restarted = append(restarted, path)
+		return errors.New("exec failed")
+	})
+	u.version = func(path string) (string, error) { return "0.9.0", nil }
+
+	// nothing happens without a plan
+	u.check()
+	if statuses, _ := reg.UpgradeStatuses(); len(statuses) != 0 || len(restarted) != 0 {
+		t.Fatalf("Unexpected upgrade without plan: %v", statuses)
+	}
+
+	reg.SetUpgradePlan(registry.UpgradePlan{Version: "0.9.0"})
+	u.check()
+	if len(restarted) != 1 || restarted[0] != filepath.Join(dir, "fleetd-0.9.0") {
+		t.Fatalf("Unexpected restarts: %v", restarted)
+	}
+	statuses, _ := reg.UpgradeStatuses()
+	if len(statuses) != 1 || statuses[0].State != registry.UpgradeStateFailed || statuses[0].Reason != "exec failed" {
+		t.Fatalf("Unexpected statuses: %v", statuses)
+	}
+	if ms, _ := reg.Machines(); ms[0].Cordoned {
+		t.Errorf("Machine left cordoned after failed upgrade")
+	}
+
+	// failed upgrades are not retried
+	u.check()
+	if len(restarted) != 1 {
+		t.Errorf("Failed upgrade retried")
+	}
+
+	// the new fleetd finishes the upgrade
+	reg.SetUpgradePlan(registry.UpgradePlan{Version: "0.9.0"})
+	reg.CordonMachine("XXX", false)
+	reg.SaveUpgradeStatus(registry.UpgradeStatus{MachineID: "XXX", Version: "0.9.0", State: registry.UpgradeStateRestarting, Cordoned: true})
+	mach.MachineState.Version = "0.9.0"
+	u.check()
+	statuses, _ = reg.UpgradeStatuses()
+	if len(statuses) != 1 || statuses[0].State != registry.UpgradeStateDone {
+		t.Fatalf("Unexpected statuses: %v", statuses)
+	}
+	if ms, _ := reg.Machines(); ms[0].Cordoned {
+		t.Errorf("Machine left cordoned after upgrade")
+	}
+}
+
+func TestUpgraderCheckWaitsForTurn(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	self := machine.MachineState{ID: "YYY", Version: "0.8.3"}
+	reg.SetMachines([]machine.MachineState{{ID: "XXX", Version: "0.8.3"}, self})
+	reg.SetUpgradePlan(registry.UpgradePlan{Version: "0.9.0"})
+
+	u := NewUpgrader(reg, &machine.FakeMachine{MachineState: self}, "/opt/bin/fleetd-{version}", func(path string) error {
+		t.Fatalf("Unexpected restart into %s", path)
+		return nil
+	})
+	u.check()
+
+	statuses, _ := reg.UpgradeStatuses()
+	if len(statuses) != 1 || statuses[0].MachineID != "YYY" || statuses[0].State != registry.UpgradeStatePending {
+		t.Errorf("Unexpected statuses: %v", statuses)
+	}
+}
+
+func TestUpgradeBinary(t *testing.T) {
+	for i, tt := range []struct {
+		binary  string
+		version string
+		path    string
+	}{
+		{"/opt/fleet/fleetd-{version}", "0.9.0", "/opt/fleet/fleetd-0.9.0"},
+		{"/opt/fleet/{version}/fleetd", "0.9.0-rc.1+build.2", "/opt/fleet/0.9.0-rc.1+build.2/fleetd"},
+		// versions must be semantic versions
+		{"/opt/fleet/fleetd-{version}", "latest", ""},
+		{"/opt/fleet/fleetd-{version}", "0.9.0-x/../../../bin/sh", ""},
+		{"/opt/fleet/{version}/fleetd", "0.9.0/../../../../bin/sh", ""},
+		// binaries must be within the directory before the placeholder
+		{"/opt/fleet/{version}/../../bin/fleetd", "0.9.0", ""},
+	} {
+		path, err := upgradeBinary(tt.binary, tt.version)
+		if tt.path == "" {
+			if err == nil {
+				t.Errorf("case %d: expected error, got path %s", i, path)
+			}
+		} else if err != nil || path != tt.path {
+			t.Errorf("case %d: expected %s, got %s and error %v", i, tt.path, path, err)
+		}
+	}
+}
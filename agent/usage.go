@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// usageSampleInterval is the minimum amount of time between two
+	// samples of a Unit's resource usage, which keeps UnitStates from
+	// being republished on every heartbeat
+	usageSampleInterval = 10 * time.Second
+)
+
+// cgroupRoot is where the cgroup hierarchies systemd places units in are
+// mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// usageSampler reads the CPU and memory usage of local Units from the
+// cgroups systemd runs them in.
+type usageSampler struct {
+	mutex   sync.Mutex
+	samples map[string]*usageSample
+}
+
+type usageSample struct {
+	at       time.Time
+	cpuTime  uint64
+	cpuUnits int
+	memory   int
+}
+
+func newUsageSampler() *usageSampler {
+	return &usageSampler{samples: make(map[string]*usageSample)}
+}
+
+// sample returns the CPU usage of the named Unit, in hundredths of a core
+// averaged since the previous sample, and its memory usage in MB. Units
+// sampled less than usageSampleInterval ago return their last sample.
+// Usage that cannot be read is reported as 0.
+func (us *usageSampler) sample(name string, now time.Time) (cpuUnits, memory int) {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	last, ok := us.samples[name]
+	if ok && now.Sub(last.at) < usageSampleInterval {
+		return last.cpuUnits, last.memory
+	}
+
+	s := usageSample{at: now}
+	if mem, err := readCgroupValue("memory", name, "memory.usage_in_bytes"); err == nil {
+		s.memory = int(mem / (1024 * 1024))
+	}
+	if cpu, err := readCgroupValue("cpuacct", name, "cpuacct.usage"); err == nil {
+		s.cpuTime = cpu
+		if ok && cpu >= last.cpuTime {
+			elapsed := now.Sub(last.at).Nanoseconds()
+			s.cpuUnits = int((cpu - last.cpuTime) * 100 / uint64(elapsed))
+		}
+	}
+
+	us.samples[name] = &s
+	return s.cpuUnits, s.memory
+}
+
+// forget drops the last sample of the named Unit
+func (us *usageSampler) forget(name string) {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	delete(us.samples, name)
+}
+
+func readCgroupValue(controller, name, file string) (uint64, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(cgroupRoot, controller, "system.slice", name, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/unit"
+)
+
+func writeCgroupValue(t *testing.T, controller, name, file, value string) {
+	dir := filepath.Join(cgroupRoot, controller, "system.slice", name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed creating cgroup dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value+"\n"), 0644); err != nil {
+		t.Fatalf("Failed writing cgroup value: %v", err)
+	}
+}
+
+func withCgroupRoot(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "fleet-cgroup-")
+	if err != nil {
+		t.Fatalf("Failed creating temp dir: %v", err)
+	}
+	orig := cgroupRoot
+	cgroupRoot = dir
+	return func() {
+		cgroupRoot = orig
+		os.RemoveAll(dir)
+	}
+}
+
+func TestUsageSamplerSample(t *testing.T) {
+	defer withCgroupRoot(t)()
+
+	us := newUsageSampler()
+	now := time.Now()
+
+	writeCgroupValue(t, "memory", "foo.service", "memory.usage_in_bytes", "268435456")
+	writeCgroupValue(t, "cpuacct", "foo.service", "cpuacct.usage", "1000000000")
+
+	// the first sample has no previous CPU time to compare to
+	if cpu, mem := us.sample("foo.service", now); cpu != 0 || mem != 256 {
+		t.Errorf("first sample: got cpu=%d mem=%d, want cpu=0 mem=256", cpu, mem)
+	}
+
+	// 15s of CPU time over 20s is three quarters of a core
+	writeCgroupValue(t, "cpuacct", "foo.service", "cpuacct.usage", "16000000000")
+	if cpu, _ := us.sample("foo.service", now.Add(5*time.Second)); cpu != 0 {
+		t.Errorf("sample within interval was not cached: cpu=%d", cpu)
+	}
+	if cpu, mem := us.sample("foo.service", now.Add(20*time.Second)); cpu != 75 || mem != 256 {
+		t.Errorf("second sample: got cpu=%d mem=%d, want cpu=75 mem=256", cpu, mem)
+	}
+
+	us.forget("foo.service")
+	if _, ok := us.samples["foo.service"]; ok {
+		t.Errorf("forget left sample behind")
+	}
+
+	// Units without cgroups use nothing
+	if cpu, mem := us.sample("bar.service", now); cpu != 0 || mem != 0 {
+		t.Errorf("Unit without cgroup: got cpu=%d mem=%d", cpu, mem)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	defer withCgroupRoot(t)()
+	writeCgroupValue(t, "memory", "foo.service", "memory.usage_in_bytes", "1048576")
+
+	a := &Agent{health: newHealthMonitor(), usage: newUsageSampler()}
+	a.health.checks["foo.service"] = &healthCheck{health: unitHealthUnhealthy, stop: make(chan bool)}
+
+	bt := &unit.UnitStateHeartbeat{Name: "foo.service", State: &unit.UnitState{ActiveState: "active"}}
+	a.annotate(bt, time.Now())
+	if bt.State.Health != unitHealthUnhealthy || bt.State.UsedMemory != 1 {
+		t.Errorf("UnitState not annotated: %#v", bt.State)
+	}
+
+	// removed Units are passed on untouched
+	bt = &unit.UnitStateHeartbeat{Name: "foo.service"}
+	a.annotate(bt, time.Now())
+	if bt.State != nil {
+		t.Errorf("Removed Unit gained a UnitState: %#v", bt.State)
+	}
+	if _, ok := a.usage.samples["foo.service"]; ok {
+		t.Errorf("Sample of removed Unit left behind")
+	}
+}
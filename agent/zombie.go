@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	// ZombieCleanupStop stops and unloads zombie Units
+	ZombieCleanupStop = "stop"
+	// ZombieCleanupLog only logs zombie Units, leaving them running
+	ZombieCleanupLog = "log"
+	// ZombieCleanupOff disables looking for zombie Units
+	ZombieCleanupOff = "off"
+
+	// time between two sweeps for zombie Units
+	zombieSweepInterval = time.Minute
+)
+
+// ValidateZombieCleanup returns an error if the given mode of handling
+// zombie Units is unknown
+func ValidateZombieCleanup(mode string) error {
+	switch mode {
+	case ZombieCleanupStop, ZombieCleanupLog, ZombieCleanupOff:
+		return nil
+	}
+	return fmt.Errorf("unknown zombie cleanup mode %q: must be %s, %s or %s", mode, ZombieCleanupStop, ZombieCleanupLog, ZombieCleanupOff)
+}
+
+// sweepZombies looks for zombie Units, which systemd still runs from the
+// units directory of fleet although they are not scheduled to the local
+// machine and the agent no longer knows them, e.g. after the agent crashed
+// while unloading them or the machine rejoined the cluster. Zombies are
+// stopped and unloaded, or only logged, depending on the ZombieCleanup mode
+// of the reconciler. Sweeps happen on the first reconciliation and then at
+// most every zombieSweepInterval.
+func (ar *AgentReconciler) sweepZombies(a *Agent, dState *AgentState, cState unitStates, now time.Time) {
+	if ar.ZombieCleanup == "" || ar.ZombieCleanup == ZombieCleanupOff {
+		return
+	}
+	if !ar.lastSweep.IsZero() && now.Sub(ar.lastSweep) < zombieSweepInterval {
+		return
+	}
+	ar.lastSweep = now
+
+	active, err := a.um.ActiveUnits()
+	if err != nil {
+		log.Errorf("Failed fetching active units from UnitManager: %v", err)
+		return
+	}
+
+	for _, name := range zombieUnits(active, dState, cState) {
+		if ar.ZombieCleanup == ZombieCleanupLog {
+			log.Warningf("Unit(%s) is running but not scheduled to Machine(%s), leaving it running", name, dState.MState.ID)
+			continue
+		}
+		log.Warningf("Stopping Unit(%s) running but not scheduled to Machine(%s)", name, dState.MState.ID)
+		a.um.TriggerStop(name)
+		a.um.Unload(name)
+	}
+}
+
+// zombieUnits returns those of the given active Units that are neither
+// scheduled to the local machine nor known to the agent. Units the agent
+// knows but that are not scheduled are unloaded by the regular
+// reconciliation.
+func zombieUnits(active []string, dState *AgentState, cState unitStates) []string {
+	var zombies []string
+	for _, name := range active {
+		if _, ok := dState.Units[name]; ok {
+			continue
+		}
+		if _, ok := cState[name]; ok {
+			continue
+		}
+		zombies = append(zombies, name)
+	}
+	return zombies
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+// zombieUnitManager reports the given units active in addition to the
+// loaded ones, recording which units are stopped
+type zombieUnitManager struct {
+	*unit.FakeUnitManager
+	zombies []string
+	stopped []string
+}
+
+func (zum *zombieUnitManager) ActiveUnits() ([]string, error) {
+	units, _ := zum.FakeUnitManager.ActiveUnits()
+	return append(units, zum.zombies...), nil
+}
+
+func (zum *zombieUnitManager) TriggerStop(name string) {
+	zum.stopped = append(zum.stopped, name)
+}
+
+func TestSweepZombies(t *testing.T) {
+	for i, tt := range []struct {
+		mode    string
+		stopped []string
+	}{
+		{ZombieCleanupStop, []string{"zombie.service"}},
+		{ZombieCleanupLog, nil},
+		{ZombieCleanupOff, nil},
+		{"", nil},
+	} {
+		zum := &zombieUnitManager{FakeUnitManager: unit.NewFakeUnitManager(), zombies: []string{"zombie.service"}}
+		a := &Agent{um: zum, cache: &agentCache{}}
+		ar := NewReconciler(registry.NewFakeRegistry(), nil)
+		ar.ZombieCleanup = tt.mode
+
+		dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+		dState.Units["running.service"] = &job.Unit{Name: "running.service", TargetState: job.JobStateLaunched}
+		zum.Load("running.service", unit.UnitFile{})
+		// known to the agent, but unloaded by the regular reconciliation
+		zum.Load("unscheduled.service", unit.UnitFile{})
+		cState, err := a.units()
+		if err != nil {
+			t.Fatalf("case %d: unexpected error fetching current state: %v", i, err)
+		}
+
+		now := time.Now()
+		ar.sweepZombies(a, dState, cState, now)
+		if !reflect.DeepEqual(tt.stopped, zum.stopped) {
+			t.Errorf("case %d: stopped %v, want %v", i, zum.stopped, tt.stopped)
+		}
+
+		// sweeps do not happen on every reconciliation
+		zum.stopped = nil
+		ar.sweepZombies(a, dState, cState, now.Add(time.Second))
+		if len(zum.stopped) != 0 {
+			t.Errorf("case %d: unexpected sweep before interval passed, stopped %v", i, zum.stopped)
+		}
+		ar.sweepZombies(a, dState, cState, now.Add(zombieSweepInterval))
+		if !reflect.DeepEqual(tt.stopped, zum.stopped) {
+			t.Errorf("case %d: stopped %v after interval, want %v", i, zum.stopped, tt.stopped)
+		}
+	}
+}
+
+func TestZombieUnits(t *testing.T) {
+	dState := NewAgentState(&machine.MachineState{ID: "XXX"})
+	dState.Units["desired.service"] = &job.Unit{Name: "desired.service"}
+	cState := unitStates{"known.service": job.JobStateInactive}
+
+	got := zombieUnits([]string{"desired.service", "known.service", "a.service", "b.service"}, dState, cState)
+	sort.Strings(got)
+	want := []string{"a.service", "b.service"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("zombieUnits returned %v, want %v", got, want)
+	}
+}
+
+func TestValidateZombieCleanup(t *testing.T) {
+	for _, mode := range []string{ZombieCleanupStop, ZombieCleanupLog, ZombieCleanupOff} {
+		if err := ValidateZombieCleanup(mode); err != nil {
+			t.Errorf("unexpected error for %q: %v", mode, err)
+		}
+	}
+	if err := ValidateZombieCleanup("kill"); err == nil {
+		t.Errorf("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/schema"
+)
+
+func wireUpAuditResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "audit")
+	ar := auditResource{cAPI, base}
+	mux.Handle(base, &ar)
+}
+
+type auditResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (ar *auditResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		return
+	}
+
+	entries, err := ar.cAPI.AuditLog()
+	if err != nil {
+		log.Errorf("Failed fetching audit log: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	page := schema.AuditEntryPage{Entries: entries}
+	sendResponse(rw, http.StatusOK, &page)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestAuditList(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	at := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	fr.RecordAudit(registry.AuditEntry{Time: at, User: "cert:alice", Action: registry.AuditUnitCreated, UnitName: "foo.service", State: "launched"})
+	ar := auditResource{&client.RegistryClient{Registry: fr}, "/audit"}
+
+	req, err := http.NewRequest("GET", "http://example.com/audit", nil)
+	if err != nil {
+		t.Fatalf("Failed creating http.Request: %v", err)
+	}
+	rw := httptest.NewRecorder()
+	ar.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	var page schema.AuditEntryPage
+	if err := json.Unmarshal(rw.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Received unparseable body: %v", err)
+	}
+	want := []*schema.AuditEntry{
+		&schema.AuditEntry{Index: 1, Time: "2014-10-01T12:00:00Z", User: "cert:alice", Action: "create", UnitName: "foo.service", State: "launched"},
+	}
+	if !reflect.DeepEqual(page.Entries, want) {
+		t.Errorf("Unexpected entries: got %#v, want %#v", page.Entries, want)
+	}
+
+	req, _ = http.NewRequest("DELETE", "http://example.com/audit", nil)
+	rw = httptest.NewRecorder()
+	ar.ServeHTTP(rw, req)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rw.Code)
+	}
+}
+
+func TestUnitsAudited(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{{Name: "foo.service", TargetState: job.JobStateLaunched}})
+	var entries []registry.AuditEntry
+	record := func(ae registry.AuditEntry) { entries = append(entries, ae) }
+	ur := &unitsResource{&client.RegistryClient{Registry: fr}, "/units", record}
+
+	req, err := http.NewRequest("DELETE", "http://example.com/units/foo.service", nil)
+	if err != nil {
+		t.Fatalf("Failed creating http.Request: %v", err)
+	}
+	req.RemoteAddr = "10.0.0.1:4001"
+
+	rw := httptest.NewRecorder()
+	ur.ServeHTTP(&authResponseWriter{ResponseWriter: rw, user: "token:ci"}, req)
+	if rw.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rw.Code)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 AuditEntry, got %d", len(entries))
+	}
+	ae := entries[0]
+	if ae.User != "token:ci" || ae.Action != registry.AuditUnitDestroyed || ae.UnitName != "foo.service" || ae.PrevState != "launched" {
+		t.Errorf("Unexpected AuditEntry: %#v", ae)
+	}
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/coreos/fleet/job"
+)
+
+const (
+	// RoleReadOnly allows clients to read, but not change, the state of
+	// the cluster
+	RoleReadOnly = "read-only"
+	// RoleAdmin allows clients to make any request
+	RoleAdmin = "admin"
+
+	// prefix of the organizational units of certificates naming the
+	// namespaces their clients are restricted to
+	namespacePrefix = "namespace="
+	// prefix of the field of the tokens file listing the namespaces a
+	// token is restricted to
+	tokenNamespacesPrefix = "namespaces="
+)
+
+// Token is a token API clients may authenticate with, granting the given
+// role. Changes made with the token are attributed to its name, if any. If
+// Namespaces is not nil, the token only grants access to the Units of the
+// listed namespaces.
+type Token struct {
+	Role       string
+	Name       string
+	Namespaces []string
+}
+
+// NewAuthHandler wraps the given http.Handler so that it only serves
+// requests made by authenticated clients with a sufficient role. Clients
+// authenticate with a verified TLS client certificate or with one of the
+// given tokens, sent as "Authorization: Bearer <token>". The role of a
+// certificate is taken from its organizational unit, defaulting to read-only.
+// Read-only clients may only make GET and HEAD requests. Clients restricted
+// to namespaces, by their token or by "namespace=<name>" organizational
+// units of their certificate, may only access the Units of those
+// namespaces, their states and journals, and list machines and the quotas
+// of their namespaces.
+func NewAuthHandler(next http.Handler, tokens map[string]Token) http.Handler {
+	return &authMiddleware{next: next, tokens: tokens}
+}
+
+type authMiddleware struct {
+	next   http.Handler
+	tokens map[string]Token
+}
+
+func (am *authMiddleware) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	user, role, namespaces, ok := am.authenticate(req)
+	if !ok {
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+		sendError(rw, http.StatusUnauthorized, errors.New("client certificate or token required"))
+		return
+	}
+
+	if role != RoleAdmin && req.Method != "GET" && req.Method != "HEAD" {
+		sendError(rw, http.StatusForbidden, fmt.Errorf("role %s may not make %s requests", role, req.Method))
+		return
+	}
+
+	if namespaces != nil {
+		if err := checkNamespaces(req, namespaces); err != nil {
+			sendError(rw, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	am.next.ServeHTTP(&authResponseWriter{ResponseWriter: rw, user: user, namespaces: namespaces}, req)
+}
+
+// authResponseWriter carries the user authenticated for a request, and the
+// namespaces it is restricted to if any, to the handlers serving it
+type authResponseWriter struct {
+	http.ResponseWriter
+	user       string
+	namespaces []string
+}
+
+func (aw *authResponseWriter) Flush() {
+	if f, ok := aw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (aw *authResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := aw.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// checkNamespaces determines whether a client restricted to the given
+// namespaces may make the given request. Listings of Units and their states
+// are narrowed down to the namespaces by their resources.
+func checkNamespaces(req *http.Request, namespaces []string) error {
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/v1-alpha/"), "/", 3)
+	switch {
+	case len(parts) == 1 && (parts[0] == "units" || parts[0] == "state"):
+		return nil
+	case len(parts) == 1 && (parts[0] == "machines" || parts[0] == "discovery.json" || parts[0] == "quotas"):
+		if req.Method != "GET" && req.Method != "HEAD" {
+			return fmt.Errorf("clients restricted to namespaces may not make %s requests against %s", req.Method, parts[0])
+		}
+		return nil
+	case len(parts) >= 2 && (parts[0] == "units" || parts[0] == "journals"):
+		if !job.InNamespace(parts[1], namespaces) {
+			return fmt.Errorf("unit %s is outside the namespaces %v", parts[1], namespaces)
+		}
+		return nil
+	}
+	return fmt.Errorf("clients restricted to namespaces %v may not access %s", namespaces, req.URL.Path)
+}
+
+// authenticate determines the user and role of the client making the given
+// request, and the namespaces it is restricted to if any, if it is
+// authenticated
+func (am *authMiddleware) authenticate(req *http.Request) (user, role string, namespaces []string, ok bool) {
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		var tok Token
+		tok, ok = am.tokens[strings.TrimPrefix(auth, "Bearer ")]
+		if !ok {
+			return
+		}
+		user = "token:" + tok.Name
+		if tok.Name == "" {
+			user = fmt.Sprintf("token (%s)", tok.Role)
+		}
+		return user, tok.Role, tok.Namespaces, true
+	}
+
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+		cert := req.TLS.VerifiedChains[0][0]
+		return "cert:" + cert.Subject.CommonName, certificateRole(cert), certificateNamespaces(cert), true
+	}
+
+	return
+}
+
+// requestNamespaces returns the namespaces the client of the given response
+// is restricted to, or nil if it is not restricted
+func requestNamespaces(rw http.ResponseWriter) []string {
+	if aw, ok := rw.(*authResponseWriter); ok {
+		return aw.namespaces
+	}
+	return nil
+}
+
+// requestUser returns the user on whose behalf the given request, answered
+// with the given response, is made. Without authentication, this is the
+// address of the client.
+func requestUser(rw http.ResponseWriter, req *http.Request) string {
+	if aw, ok := rw.(*authResponseWriter); ok {
+		return aw.user
+	}
+	if req.RemoteAddr != "" && req.RemoteAddr != "@" {
+		return "anonymous@" + req.RemoteAddr
+	}
+	return "anonymous"
+}
+
+func certificateRole(cert *x509.Certificate) string {
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if ou == RoleAdmin {
+			return RoleAdmin
+		}
+	}
+	return RoleReadOnly
+}
+
+// certificateNamespaces returns the namespaces listed as "namespace=<name>"
+// organizational units of the given certificate, or nil if there are none
+func certificateNamespaces(cert *x509.Certificate) []string {
+	var namespaces []string
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if strings.HasPrefix(ou, namespacePrefix) {
+			namespaces = append(namespaces, strings.TrimPrefix(ou, namespacePrefix))
+		}
+	}
+	return namespaces
+}
+
+// ReadTokensFile reads the tokens clients may authenticate with from the
+// given file, indexed by token. Each line of the file holds a token, a role,
+// optionally a name and optionally the namespaces the token is restricted
+// to, e.g. "namespaces=team-a,team-b", separated by whitespace. Empty lines
+// and lines starting with # are ignored.
+func ReadTokensFile(file string) (map[string]Token, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseTokens(f)
+}
+
+func parseTokens(r io.Reader) (map[string]Token, error) {
+	tokens := make(map[string]Token)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		var namespaces []string
+		if last := fields[len(fields)-1]; len(fields) > 2 && strings.HasPrefix(last, tokenNamespacesPrefix) {
+			for _, ns := range strings.Split(strings.TrimPrefix(last, tokenNamespacesPrefix), ",") {
+				if err := job.ValidateNamespace(ns); err != nil {
+					return nil, fmt.Errorf("line %d: invalid namespace %q: %v", n, ns, err)
+				}
+				namespaces = append(namespaces, ns)
+			}
+			fields = fields[:len(fields)-1]
+		}
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected a token, a role, an optional name and optional namespaces", n)
+		}
+		if fields[1] != RoleAdmin && fields[1] != RoleReadOnly {
+			return nil, fmt.Errorf("line %d: unknown role %q", n, fields[1])
+		}
+		tok := Token{Role: fields[1], Namespaces: namespaces}
+		if len(fields) == 3 {
+			tok.Name = fields[2]
+		}
+		tokens[fields[0]] = tok
+	}
+	return tokens, scanner.Err()
+}
+
+// ReadServerTLSConfig builds the TLS configuration of the API from the
+// given files. If a CA file is given, client certificates signed by it are
+// verified, and required unless clients may also authenticate with tokens.
+func ReadServerTLSConfig(cafile, certfile, keyfile string, tokens bool) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certfile, keyfile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS10,
+	}
+
+	if cafile != "" {
+		ca, err := ioutil.ReadFile(cafile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", cafile)
+		}
+
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		if tokens {
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return cfg, nil
+}
+
+// NewTLSListeners serves TLS with the given configuration on all TCP
+// listeners. Other listeners, like Unix domain sockets, are left as they
+// are, as they are normally protected by file permissions.
+func NewTLSListeners(listeners []net.Listener, cfg *tls.Config) []net.Listener {
+	wrapped := make([]net.Listener, len(listeners))
+	for i, l := range listeners {
+		if l.Addr().Network() == "tcp" {
+			l = tls.NewListener(l, cfg)
+		}
+		wrapped[i] = l
+	}
+	return wrapped
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuthHandler(t *testing.T) {
+	verified := func(ou ...string) *tls.ConnectionState {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client", OrganizationalUnit: ou}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+
+	tests := []struct {
+		method string
+		token  string
+		tls    *tls.ConnectionState
+		code   int
+	}{
+		// unauthenticated clients are refused
+		{"GET", "", nil, http.StatusUnauthorized},
+		{"GET", "bogus", nil, http.StatusUnauthorized},
+		// unverified certificates are ignored
+		{"GET", "", &tls.ConnectionState{}, http.StatusUnauthorized},
+
+		{"GET", "reader", nil, http.StatusOK},
+		{"HEAD", "reader", nil, http.StatusOK},
+		{"DELETE", "reader", nil, http.StatusForbidden},
+		{"PUT", "writer", nil, http.StatusOK},
+
+		{"GET", "", verified(), http.StatusOK},
+		{"PUT", "", verified(), http.StatusForbidden},
+		{"PUT", "", verified("ops", "admin"), http.StatusOK},
+		// tokens take precedence over certificates
+		{"PUT", "reader", verified("admin"), http.StatusForbidden},
+	}
+
+	tokens := map[string]Token{"reader": Token{Role: RoleReadOnly}, "writer": Token{Role: RoleAdmin, Name: "ci"}}
+	hdlr := NewAuthHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), tokens)
+
+	for i, tt := range tests {
+		req, err := http.NewRequest(tt.method, "/v1-alpha/units", nil)
+		if err != nil {
+			t.Fatalf("case %d: failed setting up http.Request for test: %v", i, err)
+		}
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		req.TLS = tt.tls
+
+		rr := httptest.NewRecorder()
+		hdlr.ServeHTTP(rr, req)
+
+		if rr.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rr.Code)
+		}
+	}
+}
+
+func TestAuthHandlerNamespaces(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		token  string
+		code   int
+	}{
+		{"GET", "/v1-alpha/units", "team", http.StatusOK},
+		{"GET", "/v1-alpha/state", "team", http.StatusOK},
+		{"GET", "/v1-alpha/machines", "team", http.StatusOK},
+		{"GET", "/v1-alpha/quotas", "team", http.StatusOK},
+		{"PUT", "/v1-alpha/units/team-a:web.service", "team", http.StatusOK},
+		{"GET", "/v1-alpha/units/team-b:db.service/versions", "team", http.StatusOK},
+		{"GET", "/v1-alpha/journals/team-a:web.service", "team", http.StatusOK},
+
+		// Units of other namespaces, including the default one, are refused
+		{"PUT", "/v1-alpha/units/team-c:web.service", "team", http.StatusForbidden},
+		{"DELETE", "/v1-alpha/units/web.service", "team", http.StatusForbidden},
+		{"GET", "/v1-alpha/journals/web.service", "team", http.StatusForbidden},
+		// as are cluster-wide resources
+		{"PATCH", "/v1-alpha/machines", "team", http.StatusForbidden},
+		{"GET", "/v1-alpha/events", "team", http.StatusForbidden},
+		{"PUT", "/v1-alpha/config/myapp/DB_URL", "team", http.StatusForbidden},
+		{"PUT", "/v1-alpha/quotas/team-a", "team", http.StatusForbidden},
+
+		{"PUT", "/v1-alpha/config/myapp/DB_URL", "admin", http.StatusOK},
+	}
+
+	tokens := map[string]Token{
+		"team":  Token{Role: RoleAdmin, Namespaces: []string{"team-a", "team-b"}},
+		"admin": Token{Role: RoleAdmin},
+	}
+	var namespaces []string
+	hdlr := NewAuthHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		namespaces = requestNamespaces(rw)
+	}), tokens)
+
+	for i, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.path, nil)
+		if err != nil {
+			t.Fatalf("case %d: failed setting up http.Request for test: %v", i, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+
+		namespaces = nil
+		rr := httptest.NewRecorder()
+		hdlr.ServeHTTP(rr, req)
+
+		if rr.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rr.Code)
+		}
+		if rr.Code == http.StatusOK && !reflect.DeepEqual(namespaces, tokens[tt.token].Namespaces) {
+			t.Errorf("case %d: unexpected request namespaces %v", i, namespaces)
+		}
+	}
+
+	cert := &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: []string{"admin", "namespace=team-a"}}}
+	if got := certificateNamespaces(cert); !reflect.DeepEqual(got, []string{"team-a"}) {
+		t.Errorf("Unexpected certificate namespaces %v", got)
+	}
+}
+
+func TestParseTokens(t *testing.T) {
+	contents := `# fleet API tokens
+abc123 admin ci
+
+  def456   read-only
+ghi789 admin deploy namespaces=team-a,team-b
+jkl012 read-only namespaces=team-c
+`
+	got, err := parseTokens(strings.NewReader(contents))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]Token{
+		"abc123": Token{Role: RoleAdmin, Name: "ci"},
+		"def456": Token{Role: RoleReadOnly},
+		"ghi789": Token{Role: RoleAdmin, Name: "deploy", Namespaces: []string{"team-a", "team-b"}},
+		"jkl012": Token{Role: RoleReadOnly, Namespaces: []string{"team-c"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected tokens: got %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"abc123", "abc123 root", "abc123 admin ci extra", "abc123 namespaces=team-a", "abc123 admin namespaces=Team-A", "abc123 admin namespaces=", "abc123 admin ci extra namespaces=team-a"} {
+		if _, err := parseTokens(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error parsing %q", bad)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/config"
+	"github.com/coreos/fleet/engine"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func wireUpClusterConfigResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "cluster-config")
+	ccr := clusterConfigResource{cAPI}
+	mux.Handle(base, &ccr)
+}
+
+// clusterConfigResource serves the options set for the whole cluster. The
+// version of a new configuration must be that of the configuration it
+// replaces, so that concurrent changes are refused rather than lost.
+type clusterConfigResource struct {
+	cAPI client.API
+}
+
+func (ccr *clusterConfigResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+		ccr.get(rw)
+	case "PUT":
+		ccr.set(rw, req)
+	default:
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET and PUT supported against this resource"))
+	}
+}
+
+func (ccr *clusterConfigResource) get(rw http.ResponseWriter) {
+	cc, err := ccr.cAPI.ClusterConfig()
+	if err != nil {
+		log.Errorf("Failed fetching cluster configuration: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	sendResponse(rw, http.StatusOK, cc)
+}
+
+func (ccr *clusterConfigResource) set(rw http.ResponseWriter, req *http.Request) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var cc schema.ClusterConfig
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&cc); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if err := ValidateClusterConfig(cc.Values); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := ccr.cAPI.SetClusterConfig(&cc)
+	if err == registry.ErrClusterConfigChanged {
+		sendError(rw, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		log.Errorf("Failed setting cluster configuration: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	sendResponse(rw, http.StatusOK, updated)
+}
+
+// ValidateClusterConfig returns an error if any of the given options cannot
+// be set for the whole cluster, including unknown scheduling strategies.
+func ValidateClusterConfig(values map[string]string) error {
+	for name, value := range values {
+		if err := config.ValidateClusterOption(name, value); err != nil {
+			return err
+		}
+		if name == config.ClusterSchedulingStrategy {
+			if _, err := engine.NewScheduler(value); err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestClusterConfigResource(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fAPI := &client.RegistryClient{Registry: fr}
+	ccr := &clusterConfigResource{fAPI}
+
+	for i, tt := range []struct {
+		method  string
+		body    string
+		code    int
+		version int64
+	}{
+		{"GET", "", http.StatusOK, 0},
+		{"PUT", `{`, http.StatusBadRequest, 0},
+		{"PUT", `{"version":0,"values":{"etcd_servers":"http://127.0.0.1:2379"}}`, http.StatusBadRequest, 0},
+		{"PUT", `{"version":0,"values":{"scheduling_strategy":"fastest"}}`, http.StatusBadRequest, 0},
+		{"PUT", `{"version":0,"values":{"scheduling_strategy":"binpack","cpu_overcommit":"1.5"}}`, http.StatusOK, 1},
+		{"PUT", `{"version":0,"values":{"cpu_overcommit":"2"}}`, http.StatusConflict, 0},
+		{"GET", "", http.StatusOK, 1},
+		{"POST", "", http.StatusMethodNotAllowed, 0},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com/cluster-config", bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ccr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+
+		if tt.code == http.StatusOK {
+			var got schema.ClusterConfig
+			if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+				t.Errorf("case %d: received unparseable body: %v", i, err)
+				continue
+			}
+			if got.Version != tt.version {
+				t.Errorf("case %d: expected version %d, got %d", i, tt.version, got.Version)
+			}
+			if tt.version == 1 && got.Values["cpu_overcommit"] != "1.5" {
+				t.Errorf("case %d: unexpected values %v", i, got.Values)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/schema"
+)
+
+func wireUpCompletionsResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "completions")
+	cr := completionsResource{cAPI, base}
+	mux.Handle(base, &cr)
+}
+
+type completionsResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (cr *completionsResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		return
+	}
+
+	cr.list(rw)
+}
+
+func (cr *completionsResource) list(rw http.ResponseWriter) {
+	completions, err := cr.cAPI.UnitCompletions()
+	if err != nil {
+		log.Errorf("Failed fetching Unit completions: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	page := schema.UnitCompletionPage{Completions: completions}
+	sendResponse(rw, http.StatusOK, &page)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestCompletionsList(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	started := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	fr.SaveUnitCompletion(registry.UnitCompletion{Name: "b.service", MachineID: "XXX", Result: "exit-code", ExitCode: 2, Started: started, Finished: started.Add(2 * time.Hour)})
+	fr.SaveUnitCompletion(registry.UnitCompletion{Name: "a.service", MachineID: "YYY", Result: "success", Finished: started.Add(time.Hour)})
+	cr := completionsResource{&client.RegistryClient{Registry: fr}, "/completions"}
+
+	req, err := http.NewRequest("GET", "http://example.com/completions", nil)
+	if err != nil {
+		t.Fatalf("Failed creating http.Request: %v", err)
+	}
+	rw := httptest.NewRecorder()
+	cr.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rw.Code, rw.Body.String())
+	}
+	var page schema.UnitCompletionPage
+	if err := json.Unmarshal(rw.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Received unparseable body: %v", err)
+	}
+	want := []*schema.UnitCompletion{
+		&schema.UnitCompletion{Name: "a.service", MachineID: "YYY", Result: "success", Finished: "2014-10-01T13:00:00Z"},
+		&schema.UnitCompletion{Name: "b.service", MachineID: "XXX", Result: "exit-code", ExitCode: 2, Started: "2014-10-01T12:00:00Z", Finished: "2014-10-01T14:00:00Z"},
+	}
+	if !reflect.DeepEqual(page.Completions, want) {
+		t.Errorf("Unexpected completions:\ngot\n%#v\nwant\n%#v", page.Completions, want)
+	}
+}
+
+func TestCompletionsBadMethod(t *testing.T) {
+	cr := completionsResource{&client.RegistryClient{Registry: registry.NewFakeRegistry()}, "/completions"}
+	req, err := http.NewRequest("POST", "http://example.com/completions", nil)
+	if err != nil {
+		t.Fatalf("Failed creating http.Request: %v", err)
+	}
+
+	rw := httptest.NewRecorder()
+	cr.ServeHTTP(rw, req)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rw.Code)
+	}
+}
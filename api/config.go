@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/schema"
+)
+
+func wireUpConfigResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "config")
+	cr := configResource{cAPI, base}
+	mux.Handle(base+"/", &cr)
+}
+
+// configResource serves the configuration values of namespaces, which
+// unlike other resources may be nested, e.g. config/myapp/prod. Values
+// are listed by namespace and set or deleted by key, the last element of
+// the path.
+type configResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (cr *configResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	p := strings.TrimPrefix(req.URL.Path, cr.basePath+"/")
+	if p == req.URL.Path || p == "" {
+		sendError(rw, http.StatusNotFound, nil)
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		if err := job.ValidateConfigNamespace(p); err != nil {
+			sendError(rw, http.StatusBadRequest, err)
+			return
+		}
+		cr.list(rw, p)
+	case "PUT", "DELETE":
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against a namespace"))
+			return
+		}
+		ns, key := p[:i], p[i+1:]
+		if err := job.ValidateConfigNamespace(ns); err != nil {
+			sendError(rw, http.StatusBadRequest, err)
+			return
+		}
+		if err := job.ValidateConfigKey(key); err != nil {
+			sendError(rw, http.StatusBadRequest, err)
+			return
+		}
+		if req.Method == "PUT" {
+			cr.set(rw, req, ns, key)
+		} else {
+			cr.destroy(rw, ns, key)
+		}
+	default:
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET, PUT and DELETE supported against this resource"))
+	}
+}
+
+func (cr *configResource) list(rw http.ResponseWriter, ns string) {
+	values, err := cr.cAPI.ConfigValues(ns)
+	if err != nil {
+		log.Errorf("Failed fetching configuration values of namespace %s: %v", ns, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	page := schema.ConfigValuePage{Values: values}
+	sendResponse(rw, http.StatusOK, &page)
+}
+
+func (cr *configResource) set(rw http.ResponseWriter, req *http.Request, ns, key string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var cv schema.ConfigValue
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&cv); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if cv.Key == "" {
+		cv.Key = key
+	}
+	if cv.Key != key {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("key in URL %q differs from key in request body %q", key, cv.Key))
+		return
+	}
+
+	if err := cr.cAPI.SetConfigValue(ns, &cv); err != nil {
+		log.Errorf("Failed setting %s of namespace %s: %v", key, ns, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (cr *configResource) destroy(rw http.ResponseWriter, ns, key string) {
+	if err := cr.cAPI.DeleteConfigValue(ns, key); err != nil {
+		log.Errorf("Failed deleting %s of namespace %s: %v", key, ns, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestConfigResource(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fAPI := &client.RegistryClient{Registry: fr}
+	cr := &configResource{fAPI, "/config"}
+
+	for i, tt := range []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		values map[string]registry.ConfigValue
+	}{
+		{"GET", "/config/myapp/prod", "", http.StatusOK, map[string]registry.ConfigValue{}},
+		{"PUT", "/config/myapp/prod/DB_URL", `{"value":"db:5432"}`, http.StatusNoContent, map[string]registry.ConfigValue{"DB_URL": registry.ConfigValue{Value: "db:5432"}}},
+		{"PUT", "/config/myapp/prod/TOKEN", `{"key":"TOKEN","value":"c2VjcmV0","secret":true}`, http.StatusNoContent, map[string]registry.ConfigValue{"DB_URL": registry.ConfigValue{Value: "db:5432"}, "TOKEN": registry.ConfigValue{Value: "c2VjcmV0", Secret: true}}},
+		{"PUT", "/config/myapp/prod/TOKEN", `{"key":"OTHER","value":"x"}`, http.StatusBadRequest, nil},
+		{"PUT", "/config/myapp/prod/1TOKEN", `{"value":"x"}`, http.StatusBadRequest, nil},
+		{"PUT", "/config/myapp/../TOKEN", `{"value":"x"}`, http.StatusBadRequest, nil},
+		{"PUT", "/config/TOKEN", `{"value":"x"}`, http.StatusMethodNotAllowed, nil},
+		{"POST", "/config/myapp/prod", "", http.StatusMethodNotAllowed, nil},
+		{"GET", "/config/", "", http.StatusNotFound, nil},
+		{"DELETE", "/config/myapp/prod/DB_URL", "", http.StatusNoContent, map[string]registry.ConfigValue{"TOKEN": registry.ConfigValue{Value: "c2VjcmV0", Secret: true}}},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		cr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+
+		if tt.values == nil {
+			continue
+		}
+		values, _ := fr.ConfigValues("myapp/prod")
+		if !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("case %d: unexpected values: got %v, want %v", i, values, tt.values)
+		}
+	}
+}
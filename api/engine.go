@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/schema"
+)
+
+func wireUpEngineResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "engine")
+	er := engineResource{cAPI, base}
+	mux.Handle(base, &er)
+	mux.Handle(base+"/", &er)
+}
+
+// engineResource serves the status of the engines leading the cluster, and
+// lets them be asked to step down
+type engineResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (er *engineResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case er.basePath:
+		if req.Method != "GET" {
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+			return
+		}
+		er.get(rw)
+	case path.Join(er.basePath, "step-down"):
+		if req.Method != "POST" {
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only POST supported against this resource"))
+			return
+		}
+		er.stepDown(rw, req)
+	default:
+		sendError(rw, http.StatusNotFound, nil)
+	}
+}
+
+func (er *engineResource) get(rw http.ResponseWriter) {
+	statuses, err := er.cAPI.EngineStatuses()
+	if err != nil {
+		log.Errorf("Failed fetching engine statuses: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	page := schema.EngineStatusPage{Engines: statuses}
+	sendResponse(rw, http.StatusOK, &page)
+}
+
+func (er *engineResource) stepDown(rw http.ResponseWriter, req *http.Request) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var sd schema.StepDown
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&sd); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if sd.MachineID == "" {
+		sendError(rw, http.StatusBadRequest, errors.New("machineID must be provided"))
+		return
+	}
+	if sd.Hold <= 0 {
+		sendError(rw, http.StatusBadRequest, errors.New("hold must be a positive number of seconds"))
+		return
+	}
+
+	if err := er.cAPI.RequestEngineStepDown(sd.MachineID, time.Duration(sd.Hold)*time.Second); err != nil {
+		log.Errorf("Failed requesting engine of Machine(%s) to step down: %v", sd.MachineID, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
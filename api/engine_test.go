@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestEngineResource(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	start := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	fr.SaveEngineStatus(registry.EngineStatus{
+		MachineID:             "XXX",
+		Version:               1,
+		LeaseExpires:          start.Add(25 * time.Second),
+		Reconciles:            3,
+		LastReconcile:         start,
+		LastReconcileDuration: 250 * time.Millisecond,
+	}, time.Minute)
+	fAPI := &client.RegistryClient{Registry: fr}
+	er := &engineResource{fAPI, "/engine"}
+
+	for i, tt := range []struct {
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"GET", "/engine", "", http.StatusOK},
+		{"PUT", "/engine", "", http.StatusMethodNotAllowed},
+		{"POST", "/engine/step-down", `{"machineID":"XXX"}`, http.StatusBadRequest},
+		{"POST", "/engine/step-down", `{"hold":60}`, http.StatusBadRequest},
+		{"POST", "/engine/step-down", `{`, http.StatusBadRequest},
+		{"GET", "/engine/step-down", "", http.StatusMethodNotAllowed},
+		{"POST", "/engine/step-down", `{"machineID":"XXX","hold":60}`, http.StatusNoContent},
+		{"GET", "/engine/other", "", http.StatusNotFound},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		er.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+
+		if tt.method == "GET" && tt.code == http.StatusOK {
+			var got schema.EngineStatusPage
+			if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+				t.Errorf("case %d: received unparseable body: %v", i, err)
+				continue
+			}
+			want := schema.EngineStatusPage{Engines: []*schema.EngineStatus{
+				&schema.EngineStatus{
+					MachineID:             "XXX",
+					Version:               1,
+					LeaseExpires:          "2014-10-01T12:00:25Z",
+					Reconciles:            3,
+					LastReconcile:         "2014-10-01T12:00:00Z",
+					LastReconcileDuration: 0.25,
+				},
+			}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("case %d: got %#v, want %#v", i, got, want)
+			}
+		}
+	}
+
+	if requested, _ := fr.EngineStepDownRequested("XXX"); !requested {
+		t.Errorf("Expected step-down of engine to be requested")
+	}
+}
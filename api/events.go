@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/schema"
+)
+
+const (
+	// maxEventsWait is the longest a request may wait for new events
+	maxEventsWait = time.Minute
+)
+
+func wireUpEventsResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "events")
+	er := eventsResource{cAPI, base}
+	mux.Handle(base, &er)
+}
+
+type eventsResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (er *eventsResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		return
+	}
+
+	er.list(rw, req)
+}
+
+func (er *eventsResource) list(rw http.ResponseWriter, req *http.Request) {
+	since, wait, err := parseEventsQuery(req)
+	if err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := er.cAPI.Events(since, wait)
+	if err != nil {
+		log.Errorf("Failed fetching Events: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	page := schema.EventPage{Events: events}
+	sendResponse(rw, http.StatusOK, &page)
+}
+
+// parseEventsQuery returns the index after which events are requested, and
+// how long the request is willing to wait for one, from the since and wait
+// (in seconds) query parameters of the given request
+func parseEventsQuery(req *http.Request) (since uint64, wait time.Duration, err error) {
+	query := req.URL.Query()
+
+	if val := query.Get("since"); val != "" {
+		since, err = strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			err = fmt.Errorf("invalid value for since: %q", val)
+			return
+		}
+	}
+
+	if val := query.Get("wait"); val != "" {
+		var secs uint64
+		secs, err = strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			err = fmt.Errorf("invalid value for wait: %q", val)
+			return
+		}
+		wait = time.Duration(secs) * time.Second
+		if wait > maxEventsWait {
+			wait = maxEventsWait
+		}
+	}
+
+	return
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestEventsList(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	at := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	fr.RecordEvent(registry.ClusterEvent{Time: at, Type: registry.EventUnitScheduled, UnitName: "foo.service", MachineID: "XXX"})
+	fr.RecordEvent(registry.ClusterEvent{Time: at, Type: registry.EventMachineLeft, MachineID: "YYY"})
+	er := eventsResource{&client.RegistryClient{Registry: fr}, "/events"}
+
+	for i, tt := range []struct {
+		url  string
+		code int
+		want []*schema.Event
+	}{
+		{
+			"http://example.com/events",
+			http.StatusOK,
+			[]*schema.Event{
+				&schema.Event{Index: 1, Time: "2014-10-01T12:00:00Z", Type: "UnitScheduled", UnitName: "foo.service", MachineID: "XXX"},
+				&schema.Event{Index: 2, Time: "2014-10-01T12:00:00Z", Type: "MachineLeft", MachineID: "YYY"},
+			},
+		},
+		{
+			"http://example.com/events?since=1&wait=5",
+			http.StatusOK,
+			[]*schema.Event{
+				&schema.Event{Index: 2, Time: "2014-10-01T12:00:00Z", Type: "MachineLeft", MachineID: "YYY"},
+			},
+		},
+		{"http://example.com/events?since=2", http.StatusOK, nil},
+		{"http://example.com/events?since=-1", http.StatusBadRequest, nil},
+		{"http://example.com/events?wait=soon", http.StatusBadRequest, nil},
+	} {
+		req, err := http.NewRequest("GET", tt.url, nil)
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+
+		rw := httptest.NewRecorder()
+		er.ServeHTTP(rw, req)
+
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+
+		var page schema.EventPage
+		if err := json.Unmarshal(rw.Body.Bytes(), &page); err != nil {
+			t.Fatalf("case %d: received unparseable body: %v", i, err)
+		}
+		if !reflect.DeepEqual(page.Events, tt.want) {
+			t.Errorf("case %d: unexpected events:\ngot\n%#v\nwant\n%#v", i, page.Events, tt.want)
+		}
+	}
+}
+
+func TestEventsBadMethod(t *testing.T) {
+	er := eventsResource{&client.RegistryClient{Registry: registry.NewFakeRegistry()}, "/events"}
+	req, err := http.NewRequest("POST", "http://example.com/events", nil)
+	if err != nil {
+		t.Fatalf("Failed creating http.Request: %v", err)
+	}
+
+	rw := httptest.NewRecorder()
+	er.ServeHTTP(rw, req)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rw.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/coreos/fleet/client"
+)
+
+// findFilter builds the client.Filter described by the machineID, unitName,
+// state and fields query parameters of a listing request. The fields must
+// be JSON fields of the given listed entity.
+func findFilter(u *url.URL, entity interface{}) (f client.Filter, err error) {
+	query := u.Query()
+	for param, val := range map[string]*string{
+		"machineID": &f.MachineID,
+		"unitName":  &f.Name,
+		"state":     &f.State,
+		"selector":  &f.Selector,
+	} {
+		if values := query[param]; len(values) > 1 {
+			return f, fmt.Errorf("too many values for %s", param)
+		} else if len(values) == 1 {
+			*val = values[0]
+		}
+	}
+	if err = f.Validate(); err != nil {
+		return
+	}
+
+	values := query["fields"]
+	if len(values) > 1 {
+		return f, fmt.Errorf("too many values for fields")
+	} else if len(values) == 0 || values[0] == "" {
+		return
+	}
+
+	known := jsonFields(entity)
+	for _, field := range strings.Split(values[0], ",") {
+		if !known[field] {
+			return f, fmt.Errorf("unknown field %q", field)
+		}
+		f.Fields = append(f.Fields, field)
+	}
+	return
+}
+
+// jsonFields returns the names of the JSON fields of the given struct
+func jsonFields(entity interface{}) map[string]bool {
+	t := reflect.Indirect(reflect.ValueOf(entity)).Type()
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// sparsePage encodes the given page, leaving out all fields of the entities
+// listed under the given key but the given ones
+func sparsePage(page interface{}, key string, fields []string) (interface{}, error) {
+	enc, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+	var sparse map[string]json.RawMessage
+	if err := json.Unmarshal(enc, &sparse); err != nil {
+		return nil, err
+	}
+
+	raw, ok := sparse[key]
+	if !ok {
+		return sparse, nil
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	kept := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		kept[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if val, ok := item[field]; ok {
+				kept[i][field] = val
+			}
+		}
+	}
+
+	enc, err = json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	sparse[key] = enc
+	return sparse, nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/coreos/fleet/log"
+)
+
+// journalReattachInterval is how often the machine a Unit is scheduled to is
+// checked while following its journal from machine to machine
+var journalReattachInterval = 5 * time.Second
+
+// stream follows the journal of the named Unit, scheduled to the identified
+// machine if any, attaching to the journal on whichever machine the Unit is
+// scheduled to as it is rescheduled. Each attachment starts with the given
+// number of recent entries, and is announced by a line starting with "--".
+// The stream ends once the Unit is destroyed or the client goes away.
+func (jr *journalsResource) stream(rw http.ResponseWriter, req *http.Request, name, machID string, lines int) {
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.WriteHeader(http.StatusOK)
+	out := newFlushWriter(rw)
+
+	gone, release := clientGone(rw)
+	defer release()
+	ticker := time.NewTicker(journalReattachInterval)
+	defer ticker.Stop()
+
+	for {
+		var done chan error
+		stop := make(chan struct{})
+		if machID != "" {
+			done = make(chan error, 1)
+			go func(machID string) {
+				done <- jr.attach(stop, out, req, name, machID, lines)
+			}(machID)
+		} else {
+			fmt.Fprintf(out, "-- Unit %s is not scheduled to a machine --\n", name)
+		}
+
+		// detach stops the current attachment, waiting for it to be
+		// done writing to the client
+		detach := func() {
+			close(stop)
+			if done != nil {
+				<-done
+			}
+		}
+
+		moved := false
+		for !moved {
+			select {
+			case <-gone:
+				detach()
+				return
+			case err := <-done:
+				done = nil
+				if err != nil {
+					fmt.Fprintf(out, "-- Unable to read journal of Unit %s from Machine(%s): %v --\n", name, machID, err)
+				}
+			case <-ticker.C:
+				u, err := jr.cAPI.Unit(name)
+				if err != nil {
+					log.Errorf("Failed fetching Unit(%s) from Registry: %v", name, err)
+					continue
+				}
+				if u == nil {
+					detach()
+					fmt.Fprintf(out, "-- Unit %s was destroyed --\n", name)
+					return
+				}
+				if u.MachineID != machID {
+					detach()
+					machID, moved = u.MachineID, true
+					if machID != "" {
+						fmt.Fprintf(out, "-- Unit %s moved to Machine(%s) --\n", name, machID)
+					}
+				}
+			}
+		}
+	}
+}
+
+// attach copies the journal of the named Unit on the identified machine to
+// out, following new entries until stop is closed
+func (jr *journalsResource) attach(stop <-chan struct{}, out io.Writer, req *http.Request, name, machID string, lines int) error {
+	if machID == jr.node.Machine.State().ID {
+		return jr.readJournal(stop, out, name, lines, true)
+	}
+
+	q := url.Values{}
+	q.Set("lines", strconv.Itoa(lines))
+	q.Set("follow", "true")
+	uri := (&url.URL{Path: jr.basePath + "/" + name, RawQuery: q.Encode()}).RequestURI()
+
+	resp, rerr := jr.node.forward(stop, req, uri, jr.cAPI, machID)
+	if rerr != nil {
+		return rerr
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var eresp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&eresp); err != nil || eresp.Error.Message == "" {
+			return fmt.Errorf("unexpected response %s", resp.Status)
+		}
+		return fmt.Errorf("%s", eresp.Error.Message)
+	}
+
+	if _, err := io.Copy(out, resp.Body); err != nil && !stopped(stop) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestJournalsReattach(t *testing.T) {
+	defer func(ival time.Duration) {
+		journalReattachInterval = ival
+	}(journalReattachInterval)
+	journalReattachInterval = 10 * time.Millisecond
+
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{{Name: "foo.service", TargetMachineID: "XXX"}})
+
+	remote := &fakeCommand{script: "echo remote; exec sleep 10"}
+	rsrv := httptest.NewServer(NewServeMux(fr, nil, newFakeNode("YYY", false, remote)))
+	defer rsrv.Close()
+	fr.SetMachines([]machine.MachineState{
+		{ID: "XXX"},
+		{ID: "YYY", APIURL: rsrv.URL},
+	})
+
+	local := &fakeCommand{script: "echo local; exec sleep 10"}
+	lsrv := httptest.NewServer(NewServeMux(fr, nil, newFakeNode("XXX", false, local)))
+	defer lsrv.Close()
+
+	c, err := client.NewHTTPClient(http.DefaultClient, lsrv.URL)
+	if err != nil {
+		t.Fatalf("Failed building client: %v", err)
+	}
+	journal, err := c.UnitJournal("foo.service", 5, true)
+	if err != nil {
+		t.Fatalf("Unexpected error reading journal: %v", err)
+	}
+	defer journal.Close()
+
+	lines := make(chan string)
+	go func() {
+		r := bufio.NewScanner(journal)
+		for r.Scan() {
+			lines <- r.Text()
+		}
+		close(lines)
+	}()
+	expect := func(want string) {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("Expected line %q, got %q", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for line %q", want)
+		}
+	}
+
+	expect("local")
+	fr.SetJobs([]job.Job{{Name: "foo.service", TargetMachineID: "YYY"}})
+	expect("-- Unit foo.service moved to Machine(YYY) --")
+	expect("remote")
+	fr.DestroyUnit("foo.service")
+	expect("-- Unit foo.service was destroyed --")
+
+	if _, ok := <-lines; ok {
+		t.Error("Expected journal to end once the unit was destroyed")
+	}
+	if len(remote.ran) != 1 || remote.ran[0][len(remote.ran[0])-1] != "-f" {
+		t.Errorf("Expected journal followed on remote machine, got %v", remote.ran)
+	}
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
@@ -12,16 +14,59 @@ import (
 )
 
 func wireUpMachinesResource(mux *http.ServeMux, prefix string, cAPI client.API) {
-	res := path.Join(prefix, "machines")
-	mr := machinesResource{cAPI}
-	mux.Handle(res, &mr)
+	base := path.Join(prefix, "machines")
+	mr := machinesResource{cAPI, base}
+	mux.Handle(base, &mr)
+	mux.Handle(base+"/", &mr)
 }
 
 type machinesResource struct {
-	cAPI client.API
+	cAPI     client.API
+	basePath string
 }
 
 func (mr *machinesResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if machID, key, ok := isMetadataPath(mr.basePath, req.URL.Path); ok {
+		switch req.Method {
+		case "PUT":
+			mr.setMetadata(rw, req, machID, key)
+		case "DELETE":
+			mr.deleteMetadata(rw, machID, key)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only PUT and DELETE supported against this resource"))
+		}
+		return
+	}
+
+	if machID, key, ok := isTaintPath(mr.basePath, req.URL.Path); ok {
+		switch req.Method {
+		case "PUT":
+			mr.taint(rw, req, machID, key)
+		case "DELETE":
+			mr.untaint(rw, machID, key)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only PUT and DELETE supported against this resource"))
+		}
+		return
+	}
+
+	if machID, ok := isCordonPath(mr.basePath, req.URL.Path); ok {
+		switch req.Method {
+		case "PUT":
+			mr.cordon(rw, req, machID)
+		case "DELETE":
+			mr.uncordon(rw, machID)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only PUT and DELETE supported against this resource"))
+		}
+		return
+	}
+
+	if !isCollectionPath(mr.basePath, req.URL.Path) {
+		sendError(rw, http.StatusNotFound, nil)
+		return
+	}
+
 	if req.Method != "GET" {
 		sendError(rw, http.StatusBadRequest, fmt.Errorf("only HTTP GET supported against this resource"))
 		return
@@ -48,6 +93,166 @@ func (mr *machinesResource) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 	sendResponse(rw, http.StatusOK, page)
 }
 
+// isMetadataPath determines whether the given path identifies a metadata
+// key of a machine, i.e. matches <base>/<machineID>/metadata/<key>
+func isMetadataPath(base, p string) (machID, key string, matched bool) {
+	matched, err := path.Match(path.Join(base, "*", "metadata", "*"), p)
+	if err != nil {
+		log.Errorf("Failed to determine if %q is a metadata path: %v", p, err)
+		return "", "", false
+	} else if !matched {
+		return
+	}
+
+	key = path.Base(p)
+	machID = path.Base(path.Dir(path.Dir(p)))
+	return
+}
+
+func (mr *machinesResource) setMetadata(rw http.ResponseWriter, req *http.Request, machID, key string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var mv schema.MetadataValue
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&mv); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if mv.Value == "" {
+		sendError(rw, http.StatusBadRequest, errors.New("value must not be empty, use DELETE to remove a metadata key"))
+		return
+	}
+
+	if err := mr.cAPI.SetMachineMetadata(machID, key, mv.Value); err != nil {
+		log.Errorf("Failed setting metadata %s of Machine(%s): %v", key, machID, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (mr *machinesResource) deleteMetadata(rw http.ResponseWriter, machID, key string) {
+	if err := mr.cAPI.DeleteMachineMetadata(machID, key); err != nil {
+		log.Errorf("Failed removing metadata %s of Machine(%s): %v", key, machID, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// isTaintPath determines whether the given path identifies a taint of a
+// machine, i.e. matches <base>/<machineID>/taints/<key>
+func isTaintPath(base, p string) (machID, key string, matched bool) {
+	matched, err := path.Match(path.Join(base, "*", "taints", "*"), p)
+	if err != nil {
+		log.Errorf("Failed to determine if %q is a taint path: %v", p, err)
+		return "", "", false
+	} else if !matched {
+		return
+	}
+
+	key = path.Base(p)
+	machID = path.Base(path.Dir(path.Dir(p)))
+	return
+}
+
+func (mr *machinesResource) taint(rw http.ResponseWriter, req *http.Request, machID, key string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var st schema.Taint
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&st); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if st.Key == "" {
+		st.Key = key
+	}
+	if st.Key != key {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("key in URL %q differs from key in request body %q", key, st.Key))
+		return
+	}
+
+	t := machine.Taint{Key: st.Key, Value: st.Value, Effect: st.Effect}
+	if err := t.Validate(); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := mr.cAPI.TaintMachine(machID, t); err != nil {
+		log.Errorf("Failed tainting Machine(%s): %v", machID, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (mr *machinesResource) untaint(rw http.ResponseWriter, machID, key string) {
+	if err := mr.cAPI.UntaintMachine(machID, key); err != nil {
+		log.Errorf("Failed removing taint %s of Machine(%s): %v", key, machID, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// isCordonPath determines whether the given path identifies the cordon
+// of a machine, i.e. matches <base>/<machineID>/cordon
+func isCordonPath(base, p string) (machID string, matched bool) {
+	matched, err := path.Match(path.Join(base, "*", "cordon"), p)
+	if err != nil {
+		log.Errorf("Failed to determine if %q is a cordon path: %v", p, err)
+		return "", false
+	} else if !matched {
+		return
+	}
+
+	machID = path.Base(path.Dir(p))
+	return
+}
+
+func (mr *machinesResource) cordon(rw http.ResponseWriter, req *http.Request, machID string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var c schema.Cordon
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&c); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+
+	if err := mr.cAPI.CordonMachine(machID, c.Drain); err != nil {
+		log.Errorf("Failed cordoning Machine(%s): %v", machID, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (mr *machinesResource) uncordon(rw http.ResponseWriter, machID string) {
+	if err := mr.cAPI.UncordonMachine(machID); err != nil {
+		log.Errorf("Failed uncordoning Machine(%s): %v", machID, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
 func getMachinePage(cAPI client.API, tok PageToken) (*schema.MachinePage, error) {
 	all, err := cAPI.Machines()
 	if err != nil {
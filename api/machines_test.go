@@ -5,11 +5,14 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
 )
 
 func TestMachinesList(t *testing.T) {
@@ -19,9 +22,9 @@ func TestMachinesList(t *testing.T) {
 		{ID: "YYY", PublicIP: "1.2.3.4", Metadata: map[string]string{"ping": "pong"}},
 	})
 	fAPI := &client.RegistryClient{fr}
-	resource := &machinesResource{fAPI}
+	resource := &machinesResource{fAPI, "/machines"}
 	rw := httptest.NewRecorder()
-	req, err := http.NewRequest("GET", "http://example.com", nil)
+	req, err := http.NewRequest("GET", "http://example.com/machines", nil)
 	if err != nil {
 		t.Fatalf("Failed creating http.Request: %v", err)
 	}
@@ -49,10 +52,44 @@ func TestMachinesList(t *testing.T) {
 	}
 }
 
+func TestMachinesListResources(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetMachines([]machine.MachineState{
+		{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048}},
+		{ID: "YYY"},
+	})
+	fr.SetJobs([]job.Job{
+		{Name: "a.service", Unit: newUnit(t, "[X-Fleet]\nCPUUnits=100\nMemoryReservation=512"), TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+		{Name: "b.service", Unit: newUnit(t, "[X-Fleet]\nMemoryReservation=256"), TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+		{Name: "c.service", Unit: newUnit(t, "[X-Fleet]\nMemoryReservation=1024"), TargetState: job.JobStateInactive, TargetMachineID: "XXX"},
+		{Name: "d.service", Unit: newUnit(t, "[X-Fleet]\nMemoryReservation=1024"), TargetState: job.JobStateLaunched},
+		// too large for XXX, so only counted against YYY
+		{Name: "e.service", Unit: newUnit(t, "[X-Fleet]\nGlobal=true\nMemoryReservation=4096"), TargetState: job.JobStateLaunched},
+	})
+	fAPI := &client.RegistryClient{Registry: fr}
+	mr := &machinesResource{fAPI, "/machines"}
+	rw := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "http://example.com/machines", nil)
+	if err != nil {
+		t.Fatalf("Failed creating http.Request: %v", err)
+	}
+
+	mr.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rw.Code)
+	}
+
+	body := rw.Body.String()
+	expected := `{"machines":[{"allocatedCPUUnits":100,"allocatedMemory":768,"id":"XXX","reservedCPUUnits":100,"reservedMemory":256,"totalCPUUnits":400,"totalMemory":2048},{"allocatedMemory":4096,"id":"YYY"}]}`
+	if body != expected {
+		t.Errorf("Expected body:\n%s\n\nReceived body:\n%s\n", expected, body)
+	}
+}
+
 func TestMachinesListBadNextPageToken(t *testing.T) {
 	fr := registry.NewFakeRegistry()
 	fAPI := &client.RegistryClient{fr}
-	resource := &machinesResource{fAPI}
+	resource := &machinesResource{fAPI, "/machines"}
 	rw := httptest.NewRecorder()
 	req, err := http.NewRequest("GET", "http://example.com/machines?nextPageToken=EwBMLg==", nil)
 	if err != nil {
@@ -122,3 +159,121 @@ func TestExtractMachinePage(t *testing.T) {
 		}
 	}
 }
+
+func TestMachinesMetadata(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fAPI := &client.RegistryClient{Registry: fr}
+	mr := &machinesResource{fAPI, "/machines"}
+
+	for i, tt := range []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		want   map[string]string
+	}{
+		{"PUT", "/machines/XXX/metadata/region", `{"value":"us-west"}`, http.StatusNoContent, map[string]string{"region": "us-west"}},
+		{"PUT", "/machines/XXX/metadata/role", `{"value":"db"}`, http.StatusNoContent, map[string]string{"region": "us-west", "role": "db"}},
+		// empty values must be removed with DELETE instead
+		{"PUT", "/machines/XXX/metadata/role", `{}`, http.StatusBadRequest, map[string]string{"region": "us-west", "role": "db"}},
+		{"DELETE", "/machines/XXX/metadata/role", "", http.StatusNoContent, map[string]string{"region": "us-west"}},
+		{"GET", "/machines/XXX/metadata/region", "", http.StatusMethodNotAllowed, map[string]string{"region": "us-west"}},
+		{"PUT", "/machines/XXX/region", `{"value":"us-east"}`, http.StatusNotFound, map[string]string{"region": "us-west"}},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		mr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rw.Code)
+		}
+
+		got, _ := fr.MachineMetadata("XXX")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: unexpected metadata: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestMachinesCordon(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetMachines([]machine.MachineState{{ID: "XXX"}})
+	fAPI := &client.RegistryClient{Registry: fr}
+	mr := &machinesResource{fAPI, "/machines"}
+
+	for i, tt := range []struct {
+		method   string
+		body     string
+		code     int
+		cordoned bool
+		draining bool
+	}{
+		{"PUT", `{}`, http.StatusNoContent, true, false},
+		{"PUT", `{"drain":true}`, http.StatusNoContent, true, true},
+		{"PUT", `{"drain":`, http.StatusBadRequest, true, true},
+		{"GET", "", http.StatusMethodNotAllowed, true, true},
+		{"DELETE", "", http.StatusNoContent, false, false},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com/machines/XXX/cordon", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		mr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rw.Code)
+		}
+
+		machines, _ := fr.Machines()
+		if machines[0].Cordoned != tt.cordoned || machines[0].Draining != tt.draining {
+			t.Errorf("case %d: unexpected state: cordoned=%t draining=%t", i, machines[0].Cordoned, machines[0].Draining)
+		}
+	}
+}
+
+func TestMachinesTaint(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetMachines([]machine.MachineState{{ID: "XXX"}})
+	fAPI := &client.RegistryClient{Registry: fr}
+	mr := &machinesResource{fAPI, "/machines"}
+
+	dedicated := machine.Taint{Key: "dedicated", Value: "db", Effect: machine.TaintEffectNoSchedule}
+	maintenance := machine.Taint{Key: "maintenance", Effect: machine.TaintEffectNoSchedule}
+	for i, tt := range []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		want   []machine.Taint
+	}{
+		{"PUT", "/machines/XXX/taints/maintenance", `{"effect":"NoSchedule"}`, http.StatusNoContent, []machine.Taint{maintenance}},
+		{"PUT", "/machines/XXX/taints/dedicated", `{"key":"dedicated","value":"db","effect":"NoSchedule"}`, http.StatusNoContent, []machine.Taint{dedicated, maintenance}},
+		{"PUT", "/machines/XXX/taints/dedicated", `{"key":"other","effect":"NoSchedule"}`, http.StatusBadRequest, []machine.Taint{dedicated, maintenance}},
+		{"PUT", "/machines/XXX/taints/dedicated", `{"value":"web","effect":"NoExecute"}`, http.StatusBadRequest, []machine.Taint{dedicated, maintenance}},
+		{"GET", "/machines/XXX/taints/dedicated", "", http.StatusMethodNotAllowed, []machine.Taint{dedicated, maintenance}},
+		{"DELETE", "/machines/XXX/taints/maintenance", "", http.StatusNoContent, []machine.Taint{dedicated}},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		mr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rw.Code)
+		}
+
+		machines, _ := fr.Machines()
+		if !reflect.DeepEqual(machines[0].Taints, tt.want) {
+			t.Errorf("case %d: unexpected taints: got %v, want %v", i, machines[0].Taints, tt.want)
+		}
+	}
+}
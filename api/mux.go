@@ -9,20 +9,42 @@ import (
 	"github.com/coreos/fleet/version"
 )
 
-func NewServeMux(reg registry.Registry) http.Handler {
+// NewServeMux returns the handler of the API. If record is not nil, it is
+// passed every change made to the Units of the cluster through the API. If
+// node is not nil, the journals of units and commands run on machines are
+// also served.
+func NewServeMux(reg registry.Registry, record client.AuditFunc, node *Node) http.Handler {
 	sm := http.NewServeMux()
 	cAPI := &client.RegistryClient{reg}
 
 	prefix := "/v1-alpha"
+	wireUpAuditResource(sm, prefix, cAPI)
 	wireUpDiscoveryResource(sm, prefix)
+	wireUpEngineResource(sm, prefix, cAPI)
+	wireUpEventsResource(sm, prefix, cAPI)
 	wireUpMachinesResource(sm, prefix, cAPI)
+	wireUpQuotasResource(sm, prefix, cAPI)
+	wireUpStacksResource(sm, prefix, cAPI)
+	wireUpCompletionsResource(sm, prefix, cAPI)
+	wireUpClusterConfigResource(sm, prefix, cAPI)
+	wireUpConfigResource(sm, prefix, cAPI)
 	wireUpStateResource(sm, prefix, cAPI)
-	wireUpUnitsResource(sm, prefix, cAPI)
+	wireUpUnitFilesResource(sm, prefix, cAPI)
+	wireUpUnitsResource(sm, prefix, cAPI, record)
+	wireUpUpgradeResource(sm, prefix, cAPI)
+	if node != nil {
+		wireUpNodeResources(sm, prefix, cAPI, node)
+	}
 
 	sm.HandleFunc(prefix, methodNotAllowedHandler)
 	sm.HandleFunc("/", baseHandler)
 
-	lm := &loggingMiddleware{sm}
+	var hdlr http.Handler = sm
+	if node != nil && node.Standby != "" && node.Standby != StandbyOff {
+		hdlr = newStandbyMiddleware(sm, prefix, cAPI, node)
+	}
+
+	lm := &loggingMiddleware{hdlr}
 	sim := &serverInfoMiddleware{lm}
 
 	return sim
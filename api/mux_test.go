@@ -22,7 +22,7 @@ func TestDefaultHandlers(t *testing.T) {
 
 	for i, tt := range tests {
 		fr := registry.NewFakeRegistry()
-		hdlr := NewServeMux(fr)
+		hdlr := NewServeMux(fr, nil, nil)
 		rr := httptest.NewRecorder()
 
 		req, err := http.NewRequest(tt.method, tt.path, nil)
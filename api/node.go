@@ -0,0 +1,506 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/schema"
+)
+
+const (
+	// defaultJournalLines is the number of recent journal entries returned
+	// if a request does not ask for a number
+	defaultJournalLines = 10
+
+	// proxiedHeader marks requests relayed by the API of another machine,
+	// which are never relayed again
+	proxiedHeader = "X-Fleet-Proxied-By"
+)
+
+// Node describes the machine serving the API to the resources that act on
+// the machine itself rather than on the Registry: the journals of its units
+// and commands run on it. Requests concerning other machines are relayed to
+// the API they advertise.
+type Node struct {
+	Machine machine.Machine
+
+	// AllowExec permits running arbitrary commands on the machine
+	AllowExec bool
+
+	// Proxy relays requests to the API of other machines
+	Proxy *http.Client
+
+	// Standby is how requests changing the cluster are handled while the
+	// engine of the machine does not lead the cluster: StandbyProxy relays
+	// them to the API of the machine whose engine does, StandbyRedirect
+	// redirects clients to it, and StandbyOff serves them locally. An
+	// empty Standby is StandbyOff.
+	Standby string
+
+	// command builds the commands run on behalf of requests. It is
+	// exec.Command unless replaced by tests.
+	command func(name string, arg ...string) *exec.Cmd
+}
+
+// NewProxyClient returns the client relaying requests to the API of other
+// machines. If cafile is not empty, the certificates of their API are
+// verified against the CA it holds. The credentials of requests are only
+// relayed over TLS connections whose certificates are verified. The client
+// has no certificate of its own, as it would grant every relayed request
+// the role of the machine, so only tokens authenticate relayed requests.
+func NewProxyClient(cafile string) (*http.Client, error) {
+	if cafile == "" {
+		return &http.Client{}, nil
+	}
+
+	ca, err := ioutil.ReadFile(cafile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", cafile)
+	}
+
+	trans := http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	return &http.Client{Transport: &trans}, nil
+}
+
+func wireUpNodeResources(mux *http.ServeMux, prefix string, cAPI client.API, node *Node) {
+	jr := journalsResource{cAPI, path.Join(prefix, "journals"), node}
+	mux.Handle(jr.basePath+"/", &jr)
+
+	xr := execResource{cAPI, path.Join(prefix, "exec"), node}
+	mux.Handle(xr.basePath+"/", &xr)
+}
+
+type journalsResource struct {
+	cAPI     client.API
+	basePath string
+	node     *Node
+}
+
+func (jr *journalsResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	name, ok := isItemPath(jr.basePath, req.URL.Path)
+	if !ok {
+		sendError(rw, http.StatusNotFound, nil)
+		return
+	}
+	if req.Method != "GET" {
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		return
+	}
+
+	lines, follow, reattach, err := parseJournalQuery(req)
+	if err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	u, err := jr.cAPI.Unit(name)
+	if err != nil {
+		log.Errorf("Failed fetching Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if u == nil {
+		sendError(rw, http.StatusNotFound, errors.New("unit does not exist"))
+		return
+	}
+
+	ju := job.Unit{Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options)}
+	if ju.IsGlobal() {
+		sendError(rw, http.StatusConflict, errors.New("journals of global units are unsupported"))
+		return
+	} else if reattach {
+		jr.stream(rw, req, name, u.MachineID, lines)
+		return
+	} else if u.MachineID == "" {
+		sendError(rw, http.StatusConflict, errors.New("unit is not scheduled to a machine"))
+		return
+	}
+
+	if u.MachineID != jr.node.Machine.State().ID {
+		jr.node.relay(rw, req, jr.cAPI, u.MachineID)
+		return
+	}
+
+	stop, release := clientGone(rw)
+	defer release()
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.WriteHeader(http.StatusOK)
+	if err := jr.readJournal(stop, newFlushWriter(rw), name, lines, follow); err != nil {
+		log.Errorf("Failed reading journal of Unit(%s): %v", name, err)
+	}
+}
+
+// readJournal copies the journal of the named Unit on the local machine to
+// out, starting with the given number of recent entries. It returns once
+// the journal is read, or if following it, once stop is closed.
+func (jr *journalsResource) readJournal(stop <-chan struct{}, out io.Writer, name string, lines int, follow bool) error {
+	args := []string{"--unit", name, "--no-pager", "-n", strconv.Itoa(lines)}
+	if follow {
+		args = append(args, "-f")
+	}
+	cmd := jr.node.newCommand("journalctl", args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if err := runCommand(cmd, stop); err != nil && !stopped(stop) {
+		return err
+	}
+	return nil
+}
+
+// parseJournalQuery returns the number of recent journal entries requested,
+// whether new entries are followed and whether the Unit is followed from
+// machine to machine, from the lines, follow and reattach query parameters
+// of the given request
+func parseJournalQuery(req *http.Request) (lines int, follow, reattach bool, err error) {
+	q := req.URL.Query()
+
+	lines = defaultJournalLines
+	if val := q.Get("lines"); val != "" {
+		lines, err = strconv.Atoi(val)
+		if err != nil || lines < 0 {
+			return 0, false, false, errors.New("lines must be a non-negative integer")
+		}
+	}
+
+	if val := q.Get("follow"); val != "" {
+		follow, err = strconv.ParseBool(val)
+		if err != nil {
+			return 0, false, false, errors.New("follow must be a boolean")
+		}
+	}
+
+	if val := q.Get("reattach"); val != "" {
+		reattach, err = strconv.ParseBool(val)
+		if err != nil {
+			return 0, false, false, errors.New("reattach must be a boolean")
+		} else if reattach && !follow {
+			return 0, false, false, errors.New("reattach requires follow")
+		}
+	}
+	return
+}
+
+type execResource struct {
+	cAPI     client.API
+	basePath string
+	node     *Node
+}
+
+func (xr *execResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	machID, ok := isItemPath(xr.basePath, req.URL.Path)
+	if !ok {
+		sendError(rw, http.StatusNotFound, nil)
+		return
+	}
+	if req.Method != "POST" {
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only POST supported against this resource"))
+		return
+	}
+
+	if machID != xr.node.Machine.State().ID {
+		xr.node.relay(rw, req, xr.cAPI, machID)
+		return
+	}
+
+	if !xr.node.AllowExec {
+		sendError(rw, http.StatusForbidden, errors.New("running commands is not allowed on this machine"))
+		return
+	}
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var ce schema.CommandExecution
+	if err := json.NewDecoder(req.Body).Decode(&ce); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if len(ce.Command) == 0 || ce.Command[0] == "" {
+		sendError(rw, http.StatusBadRequest, errors.New("command must not be empty"))
+		return
+	}
+
+	log.Infof("Running %q on behalf of %s", strings.Join(ce.Command, " "), requestUser(rw, req))
+
+	stop, release := clientGone(rw)
+	defer release()
+
+	cmd := xr.node.newCommand(ce.Command[0], ce.Command[1:]...)
+	out := client.NewCommandOutputWriter(newFlushWriter(rw))
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.WriteHeader(http.StatusOK)
+
+	status := 0
+	if err := runCommand(cmd, stop); err != nil {
+		status = exitStatus(err)
+		if status < 0 {
+			fmt.Fprintf(out, "%v\n", err)
+		}
+	}
+	out.Exit(status)
+}
+
+// exitStatus returns the exit status of a command that failed with the
+// given error, or -1 if it did not exit normally
+func exitStatus(err error) int {
+	if ee, ok := err.(*exec.ExitError); ok {
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Exited() {
+			return ws.ExitStatus()
+		}
+	}
+	return -1
+}
+
+func (n *Node) newCommand(name string, arg ...string) *exec.Cmd {
+	if n.command != nil {
+		return n.command(name, arg...)
+	}
+	return exec.Command(name, arg...)
+}
+
+// runCommand runs the given command, killing it if stop is closed before
+// it exits
+func runCommand(cmd *exec.Cmd, stop <-chan struct{}) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-stop:
+		cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// clientGone returns a channel closed once the client of the given response
+// goes away. The returned function must be called once done responding.
+func clientGone(rw http.ResponseWriter) (<-chan struct{}, func()) {
+	var notify <-chan bool
+	if cn, ok := rw.(http.CloseNotifier); ok {
+		notify = cn.CloseNotify()
+	}
+
+	gone := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-notify:
+			close(gone)
+		case <-done:
+		}
+	}()
+	return gone, func() { close(done) }
+}
+
+// stopped determines whether the given stop channel is closed
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// relay passes the given request on to the API advertised by the identified
+// machine and streams its response back.
+func (n *Node) relay(rw http.ResponseWriter, req *http.Request, cAPI client.API, machID string) {
+	stop, release := clientGone(rw)
+	defer release()
+
+	resp, rerr := n.forward(stop, req, req.URL.RequestURI(), cAPI, machID)
+	if rerr != nil {
+		sendError(rw, rerr.code, rerr.err)
+		return
+	}
+	defer resp.Body.Close()
+
+	for h, vals := range resp.Header {
+		if h == "Server" {
+			continue
+		}
+		rw.Header()[h] = vals
+	}
+	rw.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(newFlushWriter(rw), resp.Body); err != nil && !stopped(stop) {
+		log.Errorf("Failed relaying response of Machine(%s): %v", machID, err)
+	}
+}
+
+// relayError describes why a request could not be relayed to another
+// machine, along with the HTTP status code reported for it
+type relayError struct {
+	code int
+	err  error
+}
+
+func (re *relayError) Error() string {
+	return re.err.Error()
+}
+
+// forward makes the given request, for the given URI, against the API
+// advertised by the identified machine, passing on its body and credentials.
+// The request is cancelled if stop is closed before its response is read.
+// Requests already relayed once are refused, so that machines with
+// misconfigured URLs cannot relay requests back and forth, as are requests
+// whose credentials would be sent over an unverified connection. Client
+// certificates cannot be passed on, so requests authenticated with them
+// are refused rather than relayed without credentials.
+func (n *Node) forward(stop <-chan struct{}, req *http.Request, uri string, cAPI client.API, machID string) (*http.Response, *relayError) {
+	if by := req.Header.Get(proxiedHeader); by != "" {
+		return nil, &relayError{http.StatusBadGateway, fmt.Errorf("request relayed by Machine(%s) reached a machine other than Machine(%s)", by, machID)}
+	}
+
+	machines, err := cAPI.Machines()
+	if err != nil {
+		log.Errorf("Failed fetching Machines from Registry: %v", err)
+		return nil, &relayError{http.StatusInternalServerError, errors.New("unable to fetch machines")}
+	}
+	var target *machine.MachineState
+	for i := range machines {
+		if machines[i].ID == machID {
+			target = &machines[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, &relayError{http.StatusNotFound, errors.New("machine does not exist")}
+	} else if target.APIURL == "" {
+		return nil, &relayError{http.StatusBadGateway, errors.New("machine does not advertise its API")}
+	}
+
+	apiURL, err := url.Parse(target.APIURL)
+	if err != nil || (apiURL.Scheme != "http" && apiURL.Scheme != "https") || apiURL.Host == "" || apiURL.User != nil {
+		return nil, &relayError{http.StatusBadGateway, fmt.Errorf("invalid API URL %q of machine", target.APIURL)}
+	}
+	out, err := http.NewRequest(req.Method, strings.TrimSuffix(target.APIURL, "/")+uri, req.Body)
+	if err != nil {
+		return nil, &relayError{http.StatusBadGateway, fmt.Errorf("invalid API URL of machine: %v", err)}
+	}
+
+	if req.Header.Get("Authorization") == "" && req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+		return nil, &relayError{http.StatusBadGateway, errors.New("requests authenticated with a client certificate cannot be relayed to other machines, authenticate with a token instead")}
+	}
+
+	proxy := n.Proxy
+	if proxy == nil {
+		proxy = http.DefaultClient
+	}
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		if apiURL.Scheme != "https" || !verifiesTLS(proxy) {
+			return nil, &relayError{http.StatusBadGateway, errors.New("refusing to relay credentials to machine over an unverified connection")}
+		}
+		out.Header.Set("Authorization", auth)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		out.Header.Set("Content-Type", ct)
+	}
+	out.Header.Set(proxiedHeader, n.Machine.State().ID)
+
+	done := make(chan struct{})
+	go cancelOnStop(proxy, out, stop, done)
+	resp, err := proxy.Do(out)
+	if err != nil {
+		close(done)
+		log.Errorf("Failed relaying %s %s to Machine(%s): %v", req.Method, req.URL.Path, machID, err)
+		return nil, &relayError{http.StatusBadGateway, fmt.Errorf("unable to reach API of machine: %v", err)}
+	}
+	resp.Body = &doneBody{ReadCloser: resp.Body, done: done}
+	return resp, nil
+}
+
+// verifiesTLS determines whether the given client verifies the certificates
+// of the servers it makes requests to over TLS
+func verifiesTLS(c *http.Client) bool {
+	tr := c.Transport
+	if tr == nil {
+		tr = http.DefaultTransport
+	}
+	t, ok := tr.(*http.Transport)
+	return ok && (t.TLSClientConfig == nil || !t.TLSClientConfig.InsecureSkipVerify)
+}
+
+// cancelOnStop cancels the given request, made with the given client, if
+// stop is closed before done
+func cancelOnStop(c *http.Client, req *http.Request, stop, done <-chan struct{}) {
+	tr := c.Transport
+	if tr == nil {
+		tr = http.DefaultTransport
+	}
+	canceler, ok := tr.(interface {
+		CancelRequest(*http.Request)
+	})
+	if !ok {
+		return
+	}
+
+	select {
+	case <-stop:
+		canceler.CancelRequest(req)
+	case <-done:
+	}
+}
+
+// doneBody is the body of a relayed response, closing done once closed
+type doneBody struct {
+	io.ReadCloser
+	once sync.Once
+	done chan struct{}
+}
+
+func (b *doneBody) Close() error {
+	b.once.Do(func() { close(b.done) })
+	return b.ReadCloser.Close()
+}
+
+// flushWriter writes to an http.ResponseWriter, flushing every write so
+// that streamed output reaches clients as it is produced
+type flushWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func newFlushWriter(rw http.ResponseWriter) *flushWriter {
+	f, _ := rw.(http.Flusher)
+	return &flushWriter{rw, f}
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if fw.f != nil {
+		fw.f.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/schema"
+)
+
+func wireUpQuotasResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "quotas")
+	qr := quotasResource{cAPI, base}
+	mux.Handle(base, &qr)
+	mux.Handle(base+"/", &qr)
+}
+
+// quotasResource serves the Quotas of namespaces. Quotas are listed along
+// with their usage, and set or deleted by namespace.
+type quotasResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (qr *quotasResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if isCollectionPath(qr.basePath, req.URL.Path) {
+		switch req.Method {
+		case "GET":
+			qr.list(rw, req)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		}
+	} else if ns, ok := isItemPath(qr.basePath, req.URL.Path); ok {
+		if err := job.ValidateNamespace(ns); err != nil {
+			sendError(rw, http.StatusBadRequest, err)
+			return
+		}
+		switch req.Method {
+		case "PUT":
+			qr.set(rw, req, ns)
+		case "DELETE":
+			qr.destroy(rw, ns)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only PUT and DELETE supported against this resource"))
+		}
+	} else {
+		sendError(rw, http.StatusNotFound, nil)
+	}
+}
+
+// list responds with the Quotas of all namespaces, or only of those the
+// client is restricted to
+func (qr *quotasResource) list(rw http.ResponseWriter, req *http.Request) {
+	quotas, err := qr.cAPI.Quotas()
+	if err != nil {
+		log.Errorf("Failed fetching Quotas: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	if namespaces := requestNamespaces(rw); namespaces != nil {
+		var visible []*schema.Quota
+		for _, q := range quotas {
+			for _, ns := range namespaces {
+				if q.Namespace == ns {
+					visible = append(visible, q)
+					break
+				}
+			}
+		}
+		quotas = visible
+	}
+
+	page := schema.QuotaPage{Quotas: quotas}
+	sendResponse(rw, http.StatusOK, &page)
+}
+
+func (qr *quotasResource) set(rw http.ResponseWriter, req *http.Request, ns string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var q schema.Quota
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&q); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if q.Namespace == "" {
+		q.Namespace = ns
+	}
+	if q.Namespace != ns {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("namespace in URL %q differs from namespace in request body %q", ns, q.Namespace))
+		return
+	}
+	if q.Cores < 0 || q.Memory < 0 || q.Units < 0 {
+		sendError(rw, http.StatusBadRequest, errors.New("quota limits cannot be negative"))
+		return
+	}
+
+	if err := qr.cAPI.SetQuota(&q); err != nil {
+		log.Errorf("Failed setting Quota of namespace %s: %v", ns, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (qr *quotasResource) destroy(rw http.ResponseWriter, ns string) {
+	if err := qr.cAPI.DeleteQuota(ns); err != nil {
+		log.Errorf("Failed deleting Quota of namespace %s: %v", ns, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestQuotasResource(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		job.Job{Name: "team-a:web.service", Unit: newUnit(t, "[X-Fleet]\nMemoryReservation=512")},
+	})
+	fAPI := &client.RegistryClient{Registry: fr}
+	qr := &quotasResource{fAPI, "/quotas"}
+
+	for i, tt := range []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		quotas []registry.Quota
+	}{
+		{"PUT", "/quotas/team-a", `{"memory":1024,"units":5}`, http.StatusNoContent, []registry.Quota{registry.Quota{Namespace: "team-a", Memory: 1024, Units: 5}}},
+		{"PUT", "/quotas/team-a", `{"namespace":"team-b","units":1}`, http.StatusBadRequest, nil},
+		{"PUT", "/quotas/team-a", `{"units":-1}`, http.StatusBadRequest, nil},
+		{"PUT", "/quotas/Team_A", `{"units":1}`, http.StatusBadRequest, nil},
+		{"GET", "/quotas/team-a", "", http.StatusMethodNotAllowed, nil},
+		{"POST", "/quotas", "", http.StatusMethodNotAllowed, nil},
+		{"DELETE", "/quotas/team-a", "", http.StatusNoContent, []registry.Quota{}},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		qr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+
+		if tt.quotas == nil {
+			continue
+		}
+		quotas, _ := fr.Quotas()
+		if quotas == nil {
+			quotas = []registry.Quota{}
+		}
+		if !reflect.DeepEqual(quotas, tt.quotas) {
+			t.Errorf("case %d: unexpected quotas: got %v, want %v", i, quotas, tt.quotas)
+		}
+	}
+}
+
+func TestQuotasList(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		job.Job{Name: "team-a:web.service", Unit: newUnit(t, "[X-Fleet]\nMemoryReservation=512\nCPUUnits=50")},
+		job.Job{Name: "team-b:db.service", Unit: newUnit(t, "[Service]\nExecStart=/bin/db")},
+	})
+	fr.SetQuota(registry.Quota{Namespace: "team-a", Memory: 1024})
+	fr.SetQuota(registry.Quota{Namespace: "team-b", Units: 1})
+	qr := &quotasResource{&client.RegistryClient{Registry: fr}, "/quotas"}
+
+	for i, tt := range []struct {
+		namespaces []string
+		want       []*schema.Quota
+	}{
+		{nil, []*schema.Quota{
+			&schema.Quota{Namespace: "team-a", Memory: 1024, UsedCores: 50, UsedMemory: 512, UsedUnits: 1},
+			&schema.Quota{Namespace: "team-b", Units: 1, UsedUnits: 1},
+		}},
+		// restricted clients only see the quotas of their namespaces
+		{[]string{"team-b"}, []*schema.Quota{
+			&schema.Quota{Namespace: "team-b", Units: 1, UsedUnits: 1},
+		}},
+	} {
+		req, err := http.NewRequest("GET", "http://example.com/quotas", nil)
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+
+		rw := httptest.NewRecorder()
+		qr.ServeHTTP(&authResponseWriter{ResponseWriter: rw, namespaces: tt.namespaces}, req)
+		if rw.Code != http.StatusOK {
+			t.Fatalf("case %d: expected 200, got %d", i, rw.Code)
+		}
+
+		var page schema.QuotaPage
+		if err := json.Unmarshal(rw.Body.Bytes(), &page); err != nil {
+			t.Fatalf("case %d: failed decoding response: %v", i, err)
+		}
+		if !reflect.DeepEqual(page.Quotas, tt.want) {
+			t.Errorf("case %d: unexpected quotas: got %v, want %v", i, page.Quotas, tt.want)
+		}
+	}
+}
+
+func TestUnitsCreateQuota(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		job.Job{Name: "team-a:web@1.service", Unit: newUnit(t, "[X-Fleet]\nMemoryReservation=512")},
+	})
+	fr.SetQuota(registry.Quota{Namespace: "team-a", Memory: 1024})
+	ur := &unitsResource{&client.RegistryClient{Registry: fr}, "/units", nil}
+
+	for i, tt := range []struct {
+		name   string
+		memory string
+		code   int
+	}{
+		{"team-a:web@2.service", "512", http.StatusCreated},
+		{"team-a:web@3.service", "512", http.StatusForbidden},
+		// other namespaces are not limited
+		{"team-b:web@1.service", "512", http.StatusCreated},
+	} {
+		su := schema.Unit{
+			Name:         tt.name,
+			DesiredState: "launched",
+			Options: []*schema.UnitOption{
+				&schema.UnitOption{Section: "X-Fleet", Name: "MemoryReservation", Value: tt.memory},
+			},
+		}
+		enc, err := json.Marshal(su)
+		if err != nil {
+			t.Fatalf("case %d: unable to JSON-encode request: %v", i, err)
+		}
+		req, err := http.NewRequest("PUT", "http://example.com/units/"+tt.name, bytes.NewBuffer(enc))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.set(rw, req, tt.name)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+	}
+}
@@ -10,11 +10,17 @@ import (
 
 var unavailable = &unavailableHdlr{}
 
-func NewServer(listeners []net.Listener, hdlr http.Handler) *Server {
+// NewServer serves the given http.Handler on the given listeners. If secure
+// is not nil, requests on TCP listeners are served through the handler it
+// wraps the API in, e.g. to require clients to authenticate, while requests
+// on other listeners, like Unix domain sockets protected by file
+// permissions, are not.
+func NewServer(listeners []net.Listener, hdlr http.Handler, secure func(http.Handler) http.Handler) *Server {
 	return &Server{
 		listeners: listeners,
 		api:       hdlr,
 		cur:       unavailable,
+		secure:    secure,
 	}
 }
 
@@ -22,6 +28,7 @@ type Server struct {
 	listeners []net.Listener
 	api       http.Handler
 	cur       http.Handler
+	secure    func(http.Handler) http.Handler
 }
 
 func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -31,8 +38,12 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 func (s *Server) Serve() {
 	for i, _ := range s.listeners {
 		l := s.listeners[i]
+		var hdlr http.Handler = s
+		if s.secure != nil && l.Addr().Network() == "tcp" {
+			hdlr = s.secure(s)
+		}
 		go func() {
-			err := http.Serve(l, s)
+			err := http.Serve(l, hdlr)
 			if err != nil {
 				log.Errorf("Failed serving HTTP on listener: %v", l.Addr)
 			}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerSecuresTCPOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleet-api")
+	if err != nil {
+		t.Fatalf("Failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "fleet.sock")
+
+	tl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed listening on TCP: %v", err)
+	}
+	defer tl.Close()
+	ul, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("Failed listening on Unix domain socket: %v", err)
+	}
+	defer ul.Close()
+
+	hdlr := http.NotFoundHandler()
+	secure := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusUnauthorized)
+		})
+	}
+	// the API is left unavailable, which only clients not asked to
+	// authenticate are told
+	NewServer([]net.Listener{tl, ul}, hdlr, secure).Serve()
+
+	unix := &http.Client{Transport: &http.Transport{Dial: func(string, string) (net.Conn, error) {
+		return net.Dial("unix", sock)
+	}}}
+	for i, tt := range []struct {
+		client *http.Client
+		url    string
+		code   int
+	}{
+		{http.DefaultClient, "http://" + tl.Addr().String() + "/", http.StatusUnauthorized},
+		{unix, "http://fleet/", http.StatusServiceUnavailable},
+	} {
+		resp, err := tt.client.Get(tt.url)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, resp.StatusCode)
+		}
+	}
+}
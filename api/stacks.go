@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	stackNameMax    = 64
+	validStackChars = alphanumerical + "-_."
+)
+
+func wireUpStacksResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "stacks")
+	sr := stacksResource{cAPI, base}
+	mux.Handle(base, &sr)
+	mux.Handle(base+"/", &sr)
+}
+
+type stacksResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (sr *stacksResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if isCollectionPath(sr.basePath, req.URL.Path) {
+		switch req.Method {
+		case "GET":
+			sr.list(rw)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		}
+	} else if item, ok := isItemPath(sr.basePath, req.URL.Path); ok {
+		switch req.Method {
+		case "GET":
+			sr.get(rw, item)
+		case "DELETE":
+			sr.destroy(rw, item)
+		case "PUT":
+			sr.create(rw, req, item)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET, PUT and DELETE supported against this resource"))
+		}
+	} else {
+		sendError(rw, http.StatusNotFound, nil)
+	}
+}
+
+func (sr *stacksResource) list(rw http.ResponseWriter) {
+	stacks, err := sr.cAPI.Stacks()
+	if err != nil {
+		log.Errorf("Failed fetching Stacks: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	page := schema.StackPage{Stacks: stacks}
+	sendResponse(rw, http.StatusOK, &page)
+}
+
+func (sr *stacksResource) get(rw http.ResponseWriter, name string) {
+	s, err := sr.cAPI.Stack(name)
+	if err != nil {
+		log.Errorf("Failed fetching Stack(%s): %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if s == nil {
+		sendError(rw, http.StatusNotFound, errors.New("stack does not exist"))
+		return
+	}
+
+	sendResponse(rw, http.StatusOK, s)
+}
+
+func (sr *stacksResource) destroy(rw http.ResponseWriter, name string) {
+	s, err := sr.cAPI.Stack(name)
+	if err != nil {
+		log.Errorf("Failed fetching Stack(%s): %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if s == nil {
+		sendError(rw, http.StatusNotFound, errors.New("stack does not exist"))
+		return
+	}
+
+	if err := sr.cAPI.DestroyStack(name); err != nil {
+		log.Errorf("Failed destroying Stack(%s): %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (sr *stacksResource) create(rw http.ResponseWriter, req *http.Request, item string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var s schema.Stack
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&s); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if s.Name == "" {
+		s.Name = item
+	}
+	if item != s.Name {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("name in URL %q differs from stack name in request body %q", item, s.Name))
+		return
+	}
+	if err := ValidateStack(&s); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	stacks, err := sr.cAPI.Stacks()
+	if err != nil {
+		log.Errorf("Failed fetching Stacks: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	if err := StackOverlap(&s, stacks); err != nil {
+		sendError(rw, http.StatusConflict, err)
+		return
+	}
+
+	if err := sr.cAPI.CreateStack(&s); err != nil {
+		if err == registry.ErrStackExists {
+			sendError(rw, http.StatusConflict, err)
+			return
+		}
+		log.Errorf("Failed creating Stack(%s): %v", s.Name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+}
+
+// ValidateStack ensures that a given Stack is valid, i.e. that it has a
+// valid name and consists of at least one Unit, each a valid Unit name
+// listed once. Templates cannot be part of a Stack, only their instances.
+func ValidateStack(s *schema.Stack) error {
+	if s.Name == "" {
+		return errors.New("stack name cannot be empty")
+	}
+	if len(s.Name) > stackNameMax {
+		return fmt.Errorf("stack name exceeds maximum length (%d)", stackNameMax)
+	}
+	for _, char := range s.Name {
+		if !strings.ContainsRune(validStackChars, char) {
+			return fmt.Errorf("invalid character %q in stack name", char)
+		}
+	}
+
+	if len(s.Units) == 0 {
+		return errors.New("stack must consist of at least one unit")
+	}
+	seen := make(map[string]bool, len(s.Units))
+	for _, name := range s.Units {
+		if err := ValidateName(name); err != nil {
+			return fmt.Errorf("unit %q: %v", name, err)
+		}
+		if uni := unit.NewUnitNameInfo(name); uni != nil && uni.IsTemplate() {
+			return fmt.Errorf("unit %s is a template, only its instances can be part of a stack", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("unit %s listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// StackOverlap returns an error if any of the Units of the given Stack is
+// part of any of the given existing Stacks, as a Unit can only belong to a
+// single Stack
+func StackOverlap(s *schema.Stack, existing []*schema.Stack) error {
+	owner := make(map[string]string)
+	for _, es := range existing {
+		for _, name := range es.Units {
+			owner[name] = es.Name
+		}
+	}
+	for _, name := range s.Units {
+		if other, ok := owner[name]; ok {
+			return fmt.Errorf("unit %s is already part of stack %s", name, other)
+		}
+	}
+	return nil
+}
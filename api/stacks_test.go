@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestStacksResource(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fAPI := &client.RegistryClient{Registry: fr}
+	sr := &stacksResource{fAPI, "/stacks"}
+
+	for i, tt := range []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		stacks []string
+	}{
+		{"GET", "/stacks/web", "", http.StatusNotFound, nil},
+		{"PUT", "/stacks/web", `{"units":["web@1.service","db.service"]}`, http.StatusCreated, []string{"web"}},
+		{"PUT", "/stacks/web", `{"units":["cache.service"]}`, http.StatusConflict, []string{"web"}},
+		// Units belong to a single Stack
+		{"PUT", "/stacks/other", `{"units":["db.service"]}`, http.StatusConflict, []string{"web"}},
+		{"PUT", "/stacks/other", `{"name":"bogus","units":["cache.service"]}`, http.StatusBadRequest, []string{"web"}},
+		{"PUT", "/stacks/other", `{"units":[]}`, http.StatusBadRequest, []string{"web"}},
+		{"PUT", "/stacks/other", `{"units":["web@.service"]}`, http.StatusBadRequest, []string{"web"}},
+		{"PUT", "/stacks/other", `{"units":["a.service","a.service"]}`, http.StatusBadRequest, []string{"web"}},
+		{"PUT", "/stacks/b%40d", `{"units":["a.service"]}`, http.StatusBadRequest, []string{"web"}},
+		{"GET", "/stacks/web", "", http.StatusOK, []string{"web"}},
+		{"GET", "/stacks", "", http.StatusOK, []string{"web"}},
+		{"POST", "/stacks", "", http.StatusMethodNotAllowed, []string{"web"}},
+		{"DELETE", "/stacks/web", "", http.StatusNoContent, nil},
+		{"DELETE", "/stacks/web", "", http.StatusNotFound, nil},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		sr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+
+		stacks, _ := fr.Stacks()
+		var names []string
+		for _, s := range stacks {
+			names = append(names, s.Name)
+		}
+		if !reflect.DeepEqual(names, tt.stacks) {
+			t.Errorf("case %d: unexpected stacks: got %v, want %v", i, names, tt.stacks)
+		}
+	}
+}
+
+func TestValidateStack(t *testing.T) {
+	valid := &schema.Stack{Name: "web-1.prod", Units: []string{"web@1.service", "db.service"}}
+	if err := ValidateStack(valid); err != nil {
+		t.Errorf("Unexpected error validating stack: %v", err)
+	}
+
+	for i, s := range []*schema.Stack{
+		&schema.Stack{Units: []string{"db.service"}},
+		&schema.Stack{Name: "web/1", Units: []string{"db.service"}},
+		&schema.Stack{Name: "web"},
+		&schema.Stack{Name: "web", Units: []string{"db"}},
+	} {
+		if err := ValidateStack(s); err == nil {
+			t.Errorf("case %d: expected error validating stack", i)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	// Ways the API of a machine whose engine does not lead the cluster
+	// handles requests changing the cluster, see Node.Standby
+	StandbyOff      = "off"
+	StandbyProxy    = "proxy"
+	StandbyRedirect = "redirect"
+
+	// leaderHeader names the machine whose engine leads the cluster in
+	// responses to requests relayed or redirected by a standby API
+	leaderHeader = "X-Fleet-Leader"
+)
+
+// ValidateStandby returns an error if the given standby mode is unknown
+func ValidateStandby(mode string) error {
+	switch mode {
+	case StandbyOff, StandbyProxy, StandbyRedirect:
+		return nil
+	}
+	return fmt.Errorf("unknown standby mode %q: must be %s, %s or %s", mode, StandbyOff, StandbyProxy, StandbyRedirect)
+}
+
+// standbyMiddleware serves read-only requests locally, but only lets
+// requests changing the cluster through if the local engine leads the
+// cluster. Otherwise they are relayed or redirected to the API of the
+// machine whose engine does, so that changes are not made against a
+// Registry the local machine may be partitioned from, nor race with
+// changes made during leadership churn. Requests for the journals of Units
+// and commands run on machines act on machines rather than the cluster,
+// and are always passed on.
+type standbyMiddleware struct {
+	next   http.Handler
+	cAPI   client.API
+	node   *Node
+	bypass []string
+}
+
+func newStandbyMiddleware(next http.Handler, prefix string, cAPI client.API, node *Node) *standbyMiddleware {
+	return &standbyMiddleware{
+		next:   next,
+		cAPI:   cAPI,
+		node:   node,
+		bypass: []string{prefix + "/journals/", prefix + "/exec/"},
+	}
+}
+
+func (sm *standbyMiddleware) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !isMutatingMethod(req.Method) || sm.bypasses(req.URL.Path) {
+		sm.next.ServeHTTP(rw, req)
+		return
+	}
+
+	leader, err := leaderMachineID(sm.cAPI)
+	if err != nil {
+		log.Errorf("Failed determining lead engine: %v", err)
+		sendError(rw, http.StatusServiceUnavailable, errors.New("unable to determine lead engine"))
+		return
+	} else if leader == "" {
+		rw.Header().Set("Retry-After", "5")
+		sendError(rw, http.StatusServiceUnavailable, errors.New("no engine leads the cluster"))
+		return
+	}
+
+	if leader == sm.node.Machine.State().ID {
+		sm.next.ServeHTTP(rw, req)
+		return
+	}
+
+	rw.Header().Set(leaderHeader, leader)
+	if sm.node.Standby == StandbyRedirect {
+		sm.redirect(rw, req, leader)
+		return
+	}
+	log.V(1).Infof("Relaying %s %s to lead engine of Machine(%s)", req.Method, req.URL.Path, leader)
+	sm.node.relay(rw, req, sm.cAPI, leader)
+}
+
+// redirect sends the client to the same URI at the API advertised by the
+// identified machine
+func (sm *standbyMiddleware) redirect(rw http.ResponseWriter, req *http.Request, machID string) {
+	machines, err := sm.cAPI.Machines()
+	if err != nil {
+		log.Errorf("Failed fetching Machines from Registry: %v", err)
+		sendError(rw, http.StatusInternalServerError, errors.New("unable to fetch machines"))
+		return
+	}
+	for _, ms := range machines {
+		if ms.ID != machID {
+			continue
+		}
+		if ms.APIURL == "" {
+			break
+		}
+		rw.Header().Set("Location", strings.TrimSuffix(ms.APIURL, "/")+req.URL.RequestURI())
+		rw.WriteHeader(http.StatusTemporaryRedirect)
+		return
+	}
+	sendError(rw, http.StatusBadGateway, errors.New("lead engine's machine does not advertise its API"))
+}
+
+func (sm *standbyMiddleware) bypasses(p string) bool {
+	for _, prefix := range sm.bypass {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
+
+// leaderMachineID returns the ID of the machine whose engine leads the
+// cluster, or an empty string if none does. With the scheduling work split
+// into shards, the engine holding the first shard leads.
+func leaderMachineID(cAPI client.API) (string, error) {
+	statuses, err := cAPI.EngineStatuses()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	for _, es := range statuses {
+		// engines that went away leave their status behind until it
+		// expires
+		if expires, err := time.Parse(time.RFC3339, es.LeaseExpires); err == nil && expires.Before(now) {
+			continue
+		}
+		if len(es.Shards) == 0 {
+			return es.MachineID, nil
+		}
+		for _, shard := range es.Shards {
+			if shard == 0 {
+				return es.MachineID, nil
+			}
+		}
+	}
+	return "", nil
+}
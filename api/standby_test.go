@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestValidateStandby(t *testing.T) {
+	for _, mode := range []string{StandbyOff, StandbyProxy, StandbyRedirect} {
+		if err := ValidateStandby(mode); err != nil {
+			t.Errorf("unexpected error for %q: %v", mode, err)
+		}
+	}
+	if err := ValidateStandby("forward"); err == nil {
+		t.Errorf("expected error for unknown mode")
+	}
+}
+
+func TestStandbyMiddleware(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+
+	// requests reaching the lead engine's machine are counted
+	var relayed []string
+	leader := NewServeMux(fr, nil, &Node{Machine: &machine.FakeMachine{MachineState: machine.MachineState{ID: "YYY"}}, Standby: StandbyProxy})
+	lsrv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		relayed = append(relayed, req.Method+" "+req.URL.Path)
+		leader.ServeHTTP(rw, req)
+	}))
+	defer lsrv.Close()
+	fr.SetMachines([]machine.MachineState{{ID: "XXX"}, {ID: "YYY", APIURL: lsrv.URL}})
+
+	for i, tt := range []struct {
+		standby  string
+		leader   string
+		method   string
+		path     string
+		body     string
+		code     int
+		relayed  bool
+		location string
+	}{
+		// reads are always served locally
+		{StandbyProxy, "YYY", "GET", "/v1-alpha/units", "", http.StatusOK, false, ""},
+		// changes are relayed to the lead engine's machine
+		{StandbyProxy, "YYY", "PUT", "/v1-alpha/upgrade", `{"version":"0.9.0"}`, http.StatusNoContent, true, ""},
+		// or clients are redirected to it
+		{StandbyRedirect, "YYY", "PUT", "/v1-alpha/upgrade", `{"version":"0.9.0"}`, http.StatusTemporaryRedirect, false, lsrv.URL + "/v1-alpha/upgrade"},
+		// the lead engine's machine serves changes itself
+		{StandbyProxy, "XXX", "PUT", "/v1-alpha/upgrade", `{"version":"0.9.0"}`, http.StatusNoContent, false, ""},
+		// changes are refused while no engine leads
+		{StandbyProxy, "", "PUT", "/v1-alpha/upgrade", `{"version":"0.9.0"}`, http.StatusServiceUnavailable, false, ""},
+		// requests acting on machines are passed on
+		{StandbyProxy, "", "POST", "/v1-alpha/exec/XXX", `{"command":["true"]}`, http.StatusForbidden, false, ""},
+		// without standby, changes are served by any machine
+		{StandbyOff, "YYY", "PUT", "/v1-alpha/upgrade", `{"version":"0.9.0"}`, http.StatusNoContent, false, ""},
+	} {
+		relayed = nil
+		fr.RemoveEngineStatus("XXX")
+		fr.RemoveEngineStatus("YYY")
+		if tt.leader != "" {
+			fr.SaveEngineStatus(registry.EngineStatus{MachineID: tt.leader, LeaseExpires: time.Now().Add(time.Minute)}, time.Minute)
+		}
+		// a stale status of an engine that went away is ignored
+		if tt.leader != "YYY" {
+			fr.SaveEngineStatus(registry.EngineStatus{MachineID: "YYY", LeaseExpires: time.Now().Add(-time.Minute)}, time.Minute)
+		}
+
+		node := &Node{Machine: &machine.FakeMachine{MachineState: machine.MachineState{ID: "XXX"}}, Standby: tt.standby}
+		hdlr := NewServeMux(fr, nil, node)
+
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		hdlr.ServeHTTP(rw, req)
+
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+		if tt.relayed != (len(relayed) == 1) {
+			t.Errorf("case %d: unexpected requests relayed: %v", i, relayed)
+		}
+		if loc := rw.Header().Get("Location"); loc != tt.location {
+			t.Errorf("case %d: expected Location %q, got %q", i, tt.location, loc)
+		}
+		if (tt.relayed || tt.location != "") && rw.Header().Get(leaderHeader) != "YYY" {
+			t.Errorf("case %d: lead engine's machine not named", i)
+		}
+	}
+}
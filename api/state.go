@@ -42,43 +42,40 @@ func (sr *stateResource) list(rw http.ResponseWriter, req *http.Request) {
 		token = &def
 	}
 
-	var machineID, unitName string
-	for _, val := range req.URL.Query()["machineID"] {
-		machineID = val
-		break
-	}
-	for _, val := range req.URL.Query()["unitName"] {
-		unitName = val
-		break
+	filter, err := findFilter(req.URL, schema.UnitState{})
+	if err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
 	}
+	filter.Namespaces = requestNamespaces(rw)
 
-	page, err := getUnitStatePage(sr.cAPI, machineID, unitName, *token)
+	page, err := getUnitStatePage(sr.cAPI, filter, *token)
 	if err != nil {
 		log.Errorf("Failed fetching page of UnitStates: %v", err)
 		sendError(rw, http.StatusInternalServerError, nil)
 		return
 	}
 
-	sendResponse(rw, http.StatusOK, &page)
+	if len(filter.Fields) == 0 {
+		sendResponse(rw, http.StatusOK, &page)
+		return
+	}
+	sparse, err := sparsePage(page, "states", filter.Fields)
+	if err != nil {
+		log.Errorf("Failed selecting fields of page of UnitStates: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	sendResponse(rw, http.StatusOK, sparse)
 }
 
-func getUnitStatePage(cAPI client.API, machineID, unitName string, tok PageToken) (*schema.UnitStatePage, error) {
-	states, err := cAPI.UnitStates()
+func getUnitStatePage(cAPI client.API, filter client.Filter, tok PageToken) (*schema.UnitStatePage, error) {
+	states, err := cAPI.UnitStatesMatching(filter)
 	if err != nil {
 		return nil, err
 	}
-	var filtered []*schema.UnitState
-	for _, us := range states {
-		if machineID != "" && machineID != us.MachineID {
-			continue
-		}
-		if unitName != "" && unitName != us.Name {
-			continue
-		}
-		filtered = append(filtered, us)
-	}
 
-	items, next := extractUnitStatePageData(filtered, tok)
+	items, next := extractUnitStatePageData(states, tok)
 	page := schema.UnitStatePage{
 		States: items,
 	}
@@ -63,6 +63,21 @@ func TestUnitStateList(t *testing.T) {
 			"http://example.com/state?unitName=CCC&machineID=XXX",
 			[]*schema.UnitState{sus3},
 		},
+		{
+			// Query for a unit name glob should match all names
+			"http://example.com/state?unitName=[BC]*",
+			[]*schema.UnitState{sus2, sus3, sus4},
+		},
+		{
+			// Query for a systemd active state should be fine
+			"http://example.com/state?state=inactive",
+			[]*schema.UnitState{sus2, sus4},
+		},
+		{
+			// Query for specific fields should leave out all others
+			"http://example.com/state?state=active&fields=name,machineID",
+			[]*schema.UnitState{&schema.UnitState{Name: "AAA"}, &schema.UnitState{Name: "CCC", MachineID: "XXX"}},
+		},
 	} {
 		fr := registry.NewFakeRegistry()
 		fr.SetUnitStates([]unit.UnitState{us1, us2, us3, us4})
@@ -179,6 +194,30 @@ func TestUnitStateListBadNextPageToken(t *testing.T) {
 	}
 }
 
+func TestUnitStateListBadFilter(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fAPI := &client.RegistryClient{Registry: fr}
+	resource := &stateResource{fAPI, "/state"}
+
+	for i, query := range []string{
+		"unitName=[AAA",
+		"fields=name,options",
+		"state=active&state=failed",
+	} {
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "http://example.com/state?"+query, nil)
+		if err != nil {
+			t.Fatalf("case %d: Failed creating http.Request: %v", i, err)
+		}
+
+		resource.list(rw, req)
+
+		if err := assertErrorResponse(rw, http.StatusBadRequest); err != nil {
+			t.Errorf("case %d: %v", i, err)
+		}
+	}
+}
+
 func TestExtractUnitStatePage(t *testing.T) {
 	all := make([]*schema.UnitState, 103)
 	for i := 0; i < 103; i++ {
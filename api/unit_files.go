@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/unit"
+)
+
+func wireUpUnitFilesResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "unit-files")
+	ufr := unitFilesResource{cAPI, base}
+	mux.Handle(base+"/", &ufr)
+}
+
+// unitFilesResource serves unit files by the hash of their contents, which
+// never change and may therefore be cached by clients indefinitely
+type unitFilesResource struct {
+	cAPI     client.API
+	basePath string
+}
+
+func (ufr *unitFilesResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	hash, ok := isItemPath(ufr.basePath, req.URL.Path)
+	if !ok {
+		sendError(rw, http.StatusNotFound, nil)
+		return
+	}
+	if req.Method != "GET" {
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		return
+	}
+
+	if _, err := unit.ParseHash(hash); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	uf, err := ufr.cAPI.UnitFile(hash)
+	if err != nil {
+		log.Errorf("Failed fetching unit file %s from Registry: %v", hash, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	if uf == nil {
+		sendError(rw, http.StatusNotFound, errors.New("unit file does not exist"))
+		return
+	}
+
+	rw.Header().Set("Cache-Control", "max-age=31536000, immutable")
+	sendResponse(rw, http.StatusOK, uf)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestUnitFilesResource(t *testing.T) {
+	uf := newUnit(t, "[Service]\nExecStart=/bin/sleep 100")
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{{Name: "foo.service", Unit: uf}})
+	ufr := &unitFilesResource{&client.RegistryClient{Registry: fr}, "/unit-files"}
+
+	hash := uf.Hash().String()
+	for i, tt := range []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{"GET", "/unit-files/" + hash, http.StatusOK},
+		{"GET", "/unit-files/0000000000000000000000000000000000000000", http.StatusNotFound},
+		{"GET", "/unit-files/bogus", http.StatusBadRequest},
+		{"GET", "/unit-files/", http.StatusNotFound},
+		{"DELETE", "/unit-files/" + hash, http.StatusMethodNotAllowed},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com"+tt.path, nil)
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+
+		rw := httptest.NewRecorder()
+		ufr.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+
+		var got schema.UnitFile
+		if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+			t.Errorf("case %d: received unparseable body: %v", i, err)
+			continue
+		}
+		if got.Hash != hash || schema.MapSchemaUnitOptionsToUnitFile(got.Options).Hash() != uf.Hash() {
+			t.Errorf("case %d: unexpected unit file %#v", i, got)
+		}
+	}
+}
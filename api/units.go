@@ -9,15 +9,18 @@ import (
 	"strings"
 
 	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/engine"
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/log"
 	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
 	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
 )
 
-func wireUpUnitsResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+func wireUpUnitsResource(mux *http.ServeMux, prefix string, cAPI client.API, record client.AuditFunc) {
 	base := path.Join(prefix, "units")
-	ur := unitsResource{cAPI, base}
+	ur := unitsResource{cAPI, base, record}
 	mux.Handle(base, &ur)
 	mux.Handle(base+"/", &ur)
 }
@@ -25,9 +28,93 @@ func wireUpUnitsResource(mux *http.ServeMux, prefix string, cAPI client.API) {
 type unitsResource struct {
 	cAPI     client.API
 	basePath string
+	// record, if set, is passed every change made through the resource
+	record client.AuditFunc
+}
+
+// audited returns the API to make changes requested by the given request,
+// answered with the given response, with, recording them on behalf of the
+// requesting user
+func (ur *unitsResource) audited(rw http.ResponseWriter, req *http.Request) client.API {
+	if ur.record == nil {
+		return ur.cAPI
+	}
+	return client.NewAuditedAPI(ur.cAPI, requestUser(rw, req), ur.record)
 }
 
 func (ur *unitsResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if name, ok := isSubresourcePath(ur.basePath, req.URL.Path, "scale"); ok {
+		switch req.Method {
+		case "GET":
+			ur.getScale(rw, req, name)
+		case "PUT":
+			ur.scale(rw, req, name)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET and PUT supported against this resource"))
+		}
+		return
+	}
+
+	if name, ok := isSubresourcePath(ur.basePath, req.URL.Path, "labels"); ok {
+		switch req.Method {
+		case "PUT":
+			ur.label(rw, req, name)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only PUT supported against this resource"))
+		}
+		return
+	}
+
+	if name, ok := isSubresourcePath(ur.basePath, req.URL.Path, "plan"); ok {
+		switch req.Method {
+		case "POST":
+			ur.plan(rw, req, name)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only POST supported against this resource"))
+		}
+		return
+	}
+
+	if name, ok := isSubresourcePath(ur.basePath, req.URL.Path, "rejections"); ok {
+		switch req.Method {
+		case "GET":
+			ur.rejections(rw, req, name)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		}
+		return
+	}
+
+	if name, ok := isSubresourcePath(ur.basePath, req.URL.Path, "versions"); ok {
+		switch req.Method {
+		case "GET":
+			ur.versions(rw, req, name)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		}
+		return
+	}
+
+	if name, ok := isSubresourcePath(ur.basePath, req.URL.Path, "history"); ok {
+		switch req.Method {
+		case "GET":
+			ur.history(rw, req, name)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		}
+		return
+	}
+
+	if name, ok := isSubresourcePath(ur.basePath, req.URL.Path, "runs"); ok {
+		switch req.Method {
+		case "GET":
+			ur.runs(rw, req, name)
+		default:
+			sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET supported against this resource"))
+		}
+		return
+	}
+
 	if isCollectionPath(ur.basePath, req.URL.Path) {
 		switch req.Method {
 		case "GET":
@@ -75,6 +162,10 @@ func (ur *unitsResource) set(rw http.ResponseWriter, req *http.Request, item str
 		sendError(rw, http.StatusBadRequest, err)
 		return
 	}
+	if err := validateReferences(su.Name, su.Options, requestNamespaces(rw)); err != nil {
+		sendError(rw, http.StatusForbidden, err)
+		return
+	}
 
 	eu, err := ur.cAPI.Unit(su.Name)
 	if err != nil {
@@ -89,8 +180,19 @@ func (ur *unitsResource) set(rw http.ResponseWriter, req *http.Request, item str
 			sendError(rw, http.StatusConflict, err)
 		} else if err := ValidateOptions(su.Options); err != nil {
 			sendError(rw, http.StatusBadRequest, err)
+		} else if err := job.ValidateLabels(su.Labels); err != nil {
+			sendError(rw, http.StatusBadRequest, err)
 		} else {
-			ur.create(rw, su.Name, &su)
+			ur.create(rw, req, su.Name, &su)
+		}
+		return
+	}
+
+	if req.URL.Query().Get("replace") == "true" && len(su.Options) > 0 {
+		if err := ValidateOptions(su.Options); err != nil {
+			sendError(rw, http.StatusBadRequest, err)
+		} else {
+			ur.replace(rw, req, eu, &su)
 		}
 		return
 	}
@@ -101,7 +203,7 @@ func (ur *unitsResource) set(rw http.ResponseWriter, req *http.Request, item str
 		return
 	}
 
-	ur.update(rw, su.Name, su.DesiredState)
+	ur.update(rw, req, su.Name, su.DesiredState)
 }
 
 const (
@@ -159,6 +261,36 @@ func ValidateName(name string) error {
 	if strings.HasPrefix(name, "@") {
 		return errors.New(`unit name cannot start in "@"`)
 	}
+	if strings.Contains(name, job.NamespaceSeparator) {
+		ns, base := job.SplitNamespace(name)
+		if err := job.ValidateNamespace(ns); err != nil {
+			return fmt.Errorf("invalid namespace %q: %v", ns, err)
+		}
+		if ns == job.DefaultNamespace {
+			return fmt.Errorf("units of the %s namespace cannot be qualified with it", job.DefaultNamespace)
+		}
+		if strings.HasPrefix(base, "@") {
+			return errors.New(`unit name within namespace cannot start in "@"`)
+		}
+	}
+	return nil
+}
+
+// validateReferences ensures that the Units the given options of the named
+// Unit refer to, such as with MachineOf=, belong to the given namespaces a
+// client is restricted to, so that it cannot affect the scheduling of Units
+// of other namespaces. Clients not restricted to namespaces may refer to any
+// Unit.
+func validateReferences(name string, opts []*schema.UnitOption, namespaces []string) error {
+	if namespaces == nil || len(opts) == 0 {
+		return nil
+	}
+	j := job.NewJob(name, *schema.MapSchemaUnitOptionsToUnitFile(opts))
+	for _, ref := range j.References() {
+		if !job.InNamespace(ref, namespaces) {
+			return fmt.Errorf("unit %s referred to is outside the namespaces %v", ref, namespaces)
+		}
+	}
 	return nil
 }
 
@@ -180,6 +312,11 @@ func ValidateOptions(opts []*schema.UnitOption) error {
 			}
 		}
 	}
+	for _, replaced := range j.Replaces() {
+		if peers.Contains(replaced) {
+			return fmt.Errorf("unresolvable requirements: peer %q is also replaced", replaced)
+		}
+	}
 	hasPeers := peers.Length() != 0
 	hasConflicts := conflicts.Length() != 0
 	_, hasReqTarget := j.RequiredTarget()
@@ -187,6 +324,14 @@ func ValidateOptions(opts []*schema.UnitOption) error {
 		Unit: *uf,
 	}
 	isGlobal := u.IsGlobal()
+	reschedulesOnFailure := j.FailurePolicy() != nil
+	_, hasRescheduleDelay := j.RescheduleDelay()
+	spreads := j.SpreadConstraint() != nil
+	_, hasMaxSkew := j.Requirements()["MaxSkew"]
+	runtime := j.Runtime()
+	_, hasRuntimeImage := j.Requirements()["RuntimeImage"]
+	_, hasRuntimeArgs := j.Requirements()["RuntimeArgs"]
+	_, hasExecStart := uf.Contents["Service"]["ExecStart"]
 
 	switch {
 	case hasReqTarget && hasPeers:
@@ -199,13 +344,312 @@ func ValidateOptions(opts []*schema.UnitOption) error {
 		return errors.New("Global cannot be used with Peers")
 	case isGlobal && hasConflicts:
 		return errors.New("Global cannot be used with Conflicts")
+	case isGlobal && len(j.Replaces()) != 0:
+		return errors.New("Global cannot be used with Replaces")
+	case hasReqTarget && reschedulesOnFailure:
+		return errors.New("MachineID cannot be used with OnFailure")
+	case isGlobal && reschedulesOnFailure:
+		return errors.New("Global cannot be used with OnFailure")
+	case isGlobal && j.CronSchedule() != nil:
+		return errors.New("Global cannot be used with Schedule")
+	case isGlobal && j.IsBatch():
+		return errors.New("Global cannot be used with Batch")
+	case isGlobal && hasRescheduleDelay:
+		return errors.New("Global cannot be used with RescheduleDelay")
+	case len(j.ConflictDomains()) != 0 && !hasConflicts:
+		return errors.New("ConflictsWithMetadata cannot be used without Conflicts")
+	case hasReqTarget && spreads:
+		return errors.New("MachineID cannot be used with SpreadAcross")
+	case isGlobal && spreads:
+		return errors.New("Global cannot be used with SpreadAcross")
+	case hasMaxSkew && !spreads:
+		return errors.New("MaxSkew cannot be used without SpreadAcross")
+	case runtime != nil && runtime.Image == "":
+		return errors.New("Runtime cannot be used without RuntimeImage")
+	case runtime != nil && hasExecStart:
+		return errors.New("Runtime cannot be used with ExecStart")
+	case runtime == nil && (hasRuntimeImage || hasRuntimeArgs):
+		return errors.New("RuntimeImage and RuntimeArgs cannot be used without Runtime")
+	}
+
+	for _, val := range j.Requirements()["WorkloadWindow"] {
+		if _, err := job.ParseWorkloadWindow(val); err != nil {
+			return fmt.Errorf("invalid WorkloadWindow %q: %v", val, err)
+		}
 	}
 
 	return nil
 }
 
-func (ur *unitsResource) create(rw http.ResponseWriter, name string, u *schema.Unit) {
-	if err := ur.cAPI.CreateUnit(u); err != nil {
+// isSubresourcePath determines whether the given path identifies the given
+// subresource of a Unit, i.e. matches <base>/<unitName>/<sub>
+func isSubresourcePath(base, p, sub string) (name string, matched bool) {
+	matched, err := path.Match(path.Join(base, "*", sub), p)
+	if err != nil {
+		log.Errorf("Failed to determine if %q is a %s path: %v", p, sub, err)
+		return "", false
+	} else if !matched {
+		return
+	}
+
+	name = path.Base(path.Dir(p))
+	return
+}
+
+func (ur *unitsResource) getScale(rw http.ResponseWriter, req *http.Request, name string) {
+	s, err := ur.cAPI.UnitScale(name)
+	if err != nil {
+		log.Errorf("Failed fetching scale of Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if s == nil {
+		sendError(rw, http.StatusNotFound, errors.New("unit is not scaled"))
+		return
+	}
+
+	sendResponse(rw, http.StatusOK, s)
+}
+
+func (ur *unitsResource) scale(rw http.ResponseWriter, req *http.Request, name string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var s schema.Scale
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&s); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if s.Count < 0 {
+		sendError(rw, http.StatusBadRequest, errors.New("count cannot be negative"))
+		return
+	}
+	if uni := unit.NewUnitNameInfo(name); uni == nil || !uni.IsTemplate() {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unit %s is not a template", name))
+		return
+	}
+
+	u, err := ur.cAPI.Unit(name)
+	if err != nil {
+		log.Errorf("Failed fetching Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if u == nil {
+		sendError(rw, http.StatusNotFound, errors.New("unit does not exist"))
+		return
+	}
+
+	if err := ur.audited(rw, req).SetUnitScale(name, int(s.Count)); err != nil {
+		log.Errorf("Failed scaling Unit(%s): %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// label replaces the labels of the Unit of the given name with those in the
+// body of the request
+func (ur *unitsResource) label(rw http.ResponseWriter, req *http.Request, name string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var ul schema.UnitLabels
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&ul); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if err := job.ValidateLabels(ul.Labels); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	u, err := ur.cAPI.Unit(name)
+	if err != nil {
+		log.Errorf("Failed fetching Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if u == nil {
+		sendError(rw, http.StatusNotFound, errors.New("unit does not exist"))
+		return
+	}
+
+	if err := ur.audited(rw, req).SetUnitLabels(name, ul.Labels); err != nil {
+		log.Errorf("Failed labeling Unit(%s): %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// plan responds with where the engine would schedule the Unit in the body of
+// the request, without submitting it. If the body has no options, the Unit
+// of the given name already in the cluster is planned.
+func (ur *unitsResource) plan(rw http.ResponseWriter, req *http.Request, name string) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var su schema.Unit
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&su); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if su.Name == "" {
+		su.Name = name
+	}
+	if name != su.Name {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("name in URL %q differs from unit name in request body %q", name, su.Name))
+		return
+	}
+	if err := ValidateName(su.Name); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateReferences(su.Name, su.Options, requestNamespaces(rw)); err != nil {
+		sendError(rw, http.StatusForbidden, err)
+		return
+	}
+
+	strategy := req.URL.Query().Get("strategy")
+	if _, err := engine.NewScheduler(strategy); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(su.Options) == 0 {
+		eu, err := ur.cAPI.Unit(su.Name)
+		if err != nil {
+			log.Errorf("Failed fetching Unit(%s) from Registry: %v", su.Name, err)
+			sendError(rw, http.StatusInternalServerError, nil)
+			return
+		} else if eu == nil {
+			sendError(rw, http.StatusNotFound, errors.New("unit does not exist and options field empty"))
+			return
+		}
+		su.Options = eu.Options
+	} else if err := ValidateOptions(su.Options); err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := ur.cAPI.PlanUnit(&su, strategy)
+	if err != nil {
+		log.Errorf("Failed planning Unit(%s): %v", su.Name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	sendResponse(rw, http.StatusOK, p)
+}
+
+// rejections responds with why the engine was last unable to schedule the
+// Unit of the given name
+func (ur *unitsResource) rejections(rw http.ResponseWriter, req *http.Request, name string) {
+	u, err := ur.cAPI.Unit(name)
+	if err != nil {
+		log.Errorf("Failed fetching Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if u == nil {
+		sendError(rw, http.StatusNotFound, errors.New("unit does not exist"))
+		return
+	}
+
+	rej, err := ur.cAPI.UnitRejections(name)
+	if err != nil {
+		log.Errorf("Failed fetching rejections of Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	sendResponse(rw, http.StatusOK, rej)
+}
+
+// runs responds with the run history of the template Unit of the given name
+func (ur *unitsResource) runs(rw http.ResponseWriter, req *http.Request, name string) {
+	u, err := ur.cAPI.Unit(name)
+	if err != nil {
+		log.Errorf("Failed fetching Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if u == nil {
+		sendError(rw, http.StatusNotFound, errors.New("unit does not exist"))
+		return
+	}
+
+	runs, err := ur.cAPI.UnitRuns(name)
+	if err != nil {
+		log.Errorf("Failed fetching runs of Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	sendResponse(rw, http.StatusOK, schema.CronRunPage{Runs: runs})
+}
+
+// versions sends the recorded versions of the named Unit, which may no
+// longer exist
+func (ur *unitsResource) versions(rw http.ResponseWriter, req *http.Request, name string) {
+	versions, err := ur.cAPI.UnitVersions(name)
+	if err != nil {
+		log.Errorf("Failed fetching versions of Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	sendResponse(rw, http.StatusOK, schema.UnitVersionPage{Versions: versions})
+}
+
+// history sends the latest events concerning the named Unit, which may no
+// longer exist
+func (ur *unitsResource) history(rw http.ResponseWriter, req *http.Request, name string) {
+	events, err := ur.cAPI.UnitHistory(name)
+	if err != nil {
+		log.Errorf("Failed fetching history of Unit(%s) from Registry: %v", name, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+
+	sendResponse(rw, http.StatusOK, schema.EventPage{Events: events})
+}
+
+func (ur *unitsResource) create(rw http.ResponseWriter, req *http.Request, name string, u *schema.Unit) {
+	if len(u.MachineID) > 0 {
+		if (&job.Unit{Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options)}).IsGlobal() {
+			sendError(rw, http.StatusBadRequest, errors.New("global units cannot be created with a machineID"))
+			return
+		}
+		exists, err := ur.machineExists(u.MachineID)
+		if err != nil {
+			log.Errorf("Failed fetching Machines from Registry: %v", err)
+			sendError(rw, http.StatusInternalServerError, nil)
+			return
+		} else if !exists {
+			sendError(rw, http.StatusConflict, fmt.Errorf("unable to schedule unit to machine %s: machine does not exist", u.MachineID))
+			return
+		}
+	}
+
+	reason, err := ur.exceedsQuota(u, nil)
+	if err != nil {
+		log.Errorf("Failed fetching Quotas: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	} else if reason != "" {
+		sendError(rw, http.StatusForbidden, errors.New(reason))
+		return
+	}
+
+	if err := ur.audited(rw, req).CreateUnit(u); err != nil {
 		log.Errorf("Failed creating Unit(%s) in Registry: %v", u.Name, err)
 		sendError(rw, http.StatusInternalServerError, nil)
 		return
@@ -214,8 +658,84 @@ func (ur *unitsResource) create(rw http.ResponseWriter, name string, u *schema.U
 	rw.WriteHeader(http.StatusCreated)
 }
 
-func (ur *unitsResource) update(rw http.ResponseWriter, item, ds string) {
-	if err := ur.cAPI.SetUnitTargetState(item, ds); err != nil {
+// machineExists determines whether the identified machine is part of the
+// cluster
+func (ur *unitsResource) machineExists(machID string) (bool, error) {
+	machines, err := ur.cAPI.Machines()
+	if err != nil {
+		return false, err
+	}
+	for _, ms := range machines {
+		if ms.ID == machID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// replace replaces the unit file of the existing Unit eu with that of u,
+// unless they are the same, and sets its desired state if u has one
+func (ur *unitsResource) replace(rw http.ResponseWriter, req *http.Request, eu, u *schema.Unit) {
+	prev := schema.MapSchemaUnitOptionsToUnitFile(eu.Options)
+	next := schema.MapSchemaUnitOptionsToUnitFile(u.Options)
+	if prev.Hash() != next.Hash() {
+		if (&job.Unit{Unit: *prev}).IsGlobal() != (&job.Unit{Unit: *next}).IsGlobal() {
+			sendError(rw, http.StatusConflict, errors.New("unable to replace unit: whether a unit is global cannot change"))
+			return
+		}
+
+		reason, err := ur.exceedsQuota(u, eu)
+		if err != nil {
+			log.Errorf("Failed fetching Quotas: %v", err)
+			sendError(rw, http.StatusInternalServerError, nil)
+			return
+		} else if reason != "" {
+			sendError(rw, http.StatusForbidden, errors.New(reason))
+			return
+		}
+
+		if err := ur.audited(rw, req).ReplaceUnit(u); err != nil {
+			log.Errorf("Failed replacing Unit(%s) in Registry: %v", u.Name, err)
+			sendError(rw, http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	if len(u.DesiredState) > 0 && u.DesiredState != eu.DesiredState {
+		ur.update(rw, req, u.Name, u.DesiredState)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// exceedsQuota describes the limit of the Quota of the namespace of the
+// given Unit that creating it, or replacing the given previous Unit with
+// it, would exceed, if any
+func (ur *unitsResource) exceedsQuota(u, prev *schema.Unit) (string, error) {
+	quotas, err := ur.cAPI.Quotas()
+	if err != nil {
+		return "", err
+	}
+
+	ns, _ := job.SplitNamespace(u.Name)
+	for _, sq := range quotas {
+		if sq.Namespace != ns {
+			continue
+		}
+		q := schema.MapSchemaQuotaToQuota(sq)
+		qu := registry.QuotaUsage{Cores: int(sq.UsedCores), Memory: int(sq.UsedMemory), Units: int(sq.UsedUnits)}
+		if prev != nil {
+			qu.Remove(&job.Job{Name: prev.Name, Unit: *schema.MapSchemaUnitOptionsToUnitFile(prev.Options)})
+		}
+		qu.Add(&job.Job{Name: u.Name, Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options)})
+		return q.Exceeded(qu), nil
+	}
+	return "", nil
+}
+
+func (ur *unitsResource) update(rw http.ResponseWriter, req *http.Request, item, ds string) {
+	if err := ur.audited(rw, req).SetUnitTargetState(item, ds); err != nil {
 		log.Errorf("Failed setting target state of Unit(%s): %v", item, err)
 		sendError(rw, http.StatusInternalServerError, nil)
 		return
@@ -237,7 +757,7 @@ func (ur *unitsResource) destroy(rw http.ResponseWriter, req *http.Request, item
 		return
 	}
 
-	err = ur.cAPI.DestroyUnit(item)
+	err = ur.audited(rw, req).DestroyUnit(item)
 	if err != nil {
 		log.Errorf("Failed destroying Unit(%s): %v", item, err)
 		sendError(rw, http.StatusInternalServerError, nil)
@@ -275,18 +795,35 @@ func (ur *unitsResource) list(rw http.ResponseWriter, req *http.Request) {
 		token = &def
 	}
 
-	page, err := getUnitPage(ur.cAPI, *token)
+	filter, err := findFilter(req.URL, schema.Unit{})
+	if err != nil {
+		sendError(rw, http.StatusBadRequest, err)
+		return
+	}
+	filter.Namespaces = requestNamespaces(rw)
+
+	page, err := getUnitPage(ur.cAPI, filter, *token)
 	if err != nil {
 		log.Errorf("Failed fetching page of Units: %v", err)
 		sendError(rw, http.StatusInternalServerError, nil)
 		return
 	}
 
-	sendResponse(rw, http.StatusOK, page)
+	if len(filter.Fields) == 0 {
+		sendResponse(rw, http.StatusOK, page)
+		return
+	}
+	sparse, err := sparsePage(page, "units", filter.Fields)
+	if err != nil {
+		log.Errorf("Failed selecting fields of page of Units: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	sendResponse(rw, http.StatusOK, sparse)
 }
 
-func getUnitPage(cAPI client.API, tok PageToken) (*schema.UnitPage, error) {
-	units, err := cAPI.Units()
+func getUnitPage(cAPI client.API, filter client.Filter, tok PageToken) (*schema.UnitPage, error) {
+	units, err := cAPI.UnitsMatching(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -10,9 +10,11 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/coreos/fleet/client"
 	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/registry"
 	"github.com/coreos/fleet/schema"
 	"github.com/coreos/fleet/unit"
@@ -29,7 +31,7 @@ func newUnit(t *testing.T, str string) unit.UnitFile {
 func TestUnitsSubResourceNotFound(t *testing.T) {
 	fr := registry.NewFakeRegistry()
 	fAPI := &client.RegistryClient{fr}
-	ur := &unitsResource{fAPI, "/units"}
+	ur := &unitsResource{fAPI, "/units", nil}
 	rr := httptest.NewRecorder()
 
 	req, err := http.NewRequest("GET", "/units/foo/bar", nil)
@@ -52,7 +54,7 @@ func TestUnitsList(t *testing.T) {
 		{Name: "YYY.service"},
 	})
 	fAPI := &client.RegistryClient{fr}
-	resource := &unitsResource{fAPI, "/units"}
+	resource := &unitsResource{fAPI, "/units", nil}
 	rw := httptest.NewRecorder()
 	req, err := http.NewRequest("GET", "http://example.com/units", nil)
 	if err != nil {
@@ -86,10 +88,71 @@ func TestUnitsList(t *testing.T) {
 	}
 }
 
+func TestUnitsListFilter(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		{Name: "web@1.service", TargetState: job.JobStateLaunched},
+		{Name: "web@2.service", TargetState: job.JobStateLaunched},
+		{Name: "db.service", TargetState: job.JobStateLaunched},
+	})
+	fr.ScheduleUnit("web@1.service", "XXX")
+	fr.ScheduleUnit("db.service", "XXX")
+	fAPI := &client.RegistryClient{Registry: fr}
+	resource := &unitsResource{fAPI, "/units", nil}
+
+	for i, tt := range []struct {
+		query string
+		names []string
+	}{
+		{"", []string{"db.service", "web@1.service", "web@2.service"}},
+		{"unitName=web@*", []string{"web@1.service", "web@2.service"}},
+		{"machineID=XXX", []string{"db.service", "web@1.service"}},
+		{"machineID=XXX&unitName=web@*", []string{"web@1.service"}},
+		{"unitName=nope.service", nil},
+	} {
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "http://example.com/units?"+tt.query, nil)
+		if err != nil {
+			t.Fatalf("case %d: Failed creating http.Request: %v", i, err)
+		}
+
+		resource.list(rw, req)
+		if rw.Code != http.StatusOK {
+			t.Errorf("case %d: Expected 200, got %d", i, rw.Code)
+			continue
+		}
+
+		var page schema.UnitPage
+		if err := json.Unmarshal(rw.Body.Bytes(), &page); err != nil {
+			t.Fatalf("case %d: Received unparseable body: %v", i, err)
+		}
+		var names []string
+		for _, u := range page.Units {
+			names = append(names, u.Name)
+		}
+		if !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("case %d: expected units %v, got %v", i, tt.names, names)
+		}
+	}
+
+	// only the requested fields are sent
+	rw := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://example.com/units?unitName=db.service&fields=name,machineID", nil)
+	resource.list(rw, req)
+	var page map[string][]map[string]interface{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Received unparseable body: %v", err)
+	}
+	want := []map[string]interface{}{{"name": "db.service", "machineID": "XXX"}}
+	if !reflect.DeepEqual(page["units"], want) {
+		t.Errorf("Unexpected sparse units: got %v, want %v", page["units"], want)
+	}
+}
+
 func TestUnitsListBadNextPageToken(t *testing.T) {
 	fr := registry.NewFakeRegistry()
 	fAPI := &client.RegistryClient{fr}
-	resource := &unitsResource{fAPI, "/units"}
+	resource := &unitsResource{fAPI, "/units", nil}
 	rw := httptest.NewRecorder()
 	req, err := http.NewRequest("GET", "http://example.com/units?nextPageToken=EwBMLg==", nil)
 	if err != nil {
@@ -170,7 +233,7 @@ func TestUnitGet(t *testing.T) {
 		{Name: "YYY.service"},
 	})
 	fAPI := &client.RegistryClient{fr}
-	resource := &unitsResource{fAPI, "/units"}
+	resource := &unitsResource{fAPI, "/units", nil}
 
 	for i, tt := range tests {
 		rw := httptest.NewRecorder()
@@ -233,7 +296,7 @@ func TestUnitsDestroy(t *testing.T) {
 		}
 
 		fAPI := &client.RegistryClient{fr}
-		resource := &unitsResource{fAPI, "/units"}
+		resource := &unitsResource{fAPI, "/units", nil}
 		rw := httptest.NewRecorder()
 		resource.destroy(rw, req, tt.arg)
 
@@ -384,7 +447,7 @@ func TestUnitsSetDesiredState(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		fAPI := &client.RegistryClient{fr}
-		resource := &unitsResource{fAPI, "/units"}
+		resource := &unitsResource{fAPI, "/units", nil}
 		rw := httptest.NewRecorder()
 		resource.set(rw, req, tt.item)
 
@@ -439,6 +502,161 @@ func makeIDUO(name string) *schema.UnitOption {
 	}
 }
 
+func TestUnitsReplace(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		job.Job{Name: "foo.service", Unit: newUnit(t, "[Service]\nExecStart=/bin/foo"), TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+		job.Job{Name: "bar.service", Unit: newUnit(t, "[Service]\nExecStart=/bin/bar"), TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+	})
+	ur := &unitsResource{&client.RegistryClient{Registry: fr}, "/units", nil}
+
+	for i, tt := range []struct {
+		name     string
+		contents string
+		query    string
+		code     int
+		machine  string
+	}{
+		// the unit file is replaced in place
+		{"foo.service", "[Service]\nExecStart=/bin/foo --v2", "?replace=true", http.StatusNoContent, "XXX"},
+		// replacing the same unit file changes nothing
+		{"foo.service", "[Service]\nExecStart=/bin/foo --v2", "?replace=true", http.StatusNoContent, "XXX"},
+		// without replace, existing units are not changed
+		{"foo.service", "[Service]\nExecStart=/bin/foo --v3", "", http.StatusConflict, "XXX"},
+		// units cannot become global
+		{"foo.service", "[X-Fleet]\nGlobal=true", "?replace=true", http.StatusConflict, "XXX"},
+		// units updated by rescheduling are unscheduled
+		{"bar.service", "[Service]\nExecStart=/bin/bar --v2\n[X-Fleet]\nUpdatePolicy=reschedule", "?replace=true", http.StatusNoContent, ""},
+	} {
+		uf := newUnit(t, tt.contents)
+		su := schema.Unit{Name: tt.name, Options: schema.MapUnitFileToSchemaUnitOptions(&uf)}
+		enc, err := json.Marshal(su)
+		if err != nil {
+			t.Fatalf("case %d: unable to JSON-encode request: %v", i, err)
+		}
+		req, err := http.NewRequest("PUT", "http://example.com/units/"+tt.name+tt.query, bytes.NewBuffer(enc))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.set(rw, req, tt.name)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+
+		u, err := fr.Unit(tt.name)
+		if err != nil || u == nil {
+			t.Fatalf("case %d: failed fetching Unit: %v", i, err)
+		}
+		if replaced := u.Unit.Hash() == uf.Hash(); replaced != (tt.code == http.StatusNoContent) {
+			t.Errorf("case %d: unit file replaced %t, want %t", i, replaced, !replaced)
+		}
+		if u.TargetState != job.JobStateLaunched {
+			t.Errorf("case %d: expected target state to be kept, got %s", i, u.TargetState)
+		}
+		if su, _ := fr.ScheduledUnit(tt.name); su.TargetMachineID != tt.machine {
+			t.Errorf("case %d: scheduled to %q, want %q", i, su.TargetMachineID, tt.machine)
+		}
+	}
+
+	versions, err := fr.UnitVersions("bar.service")
+	if err != nil || len(versions) != 1 {
+		t.Errorf("Expected a version to be recorded for the replaced unit file, got %v: %v", versions, err)
+	}
+}
+
+func TestUnitsNamespaceReferences(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	ur := &unitsResource{&client.RegistryClient{Registry: fr}, "/units", nil}
+	namespaces := []string{"team-a"}
+
+	for i, tt := range []struct {
+		name       string
+		contents   string
+		namespaces []string
+		code       int
+	}{
+		// units of the client's namespaces may be referred to
+		{"team-a:web.service", "[X-Fleet]\nMachineOf=team-a:db.service\nConflicts=team-a:web*.service", namespaces, http.StatusCreated},
+		{"team-a:web@1.service", "[X-Fleet]\nFleetAfter=%p-cache.service", namespaces, http.StatusCreated},
+		// units of other namespaces, including the default one, may not
+		{"team-a:app.service", "[X-Fleet]\nMachineOf=db.service", namespaces, http.StatusForbidden},
+		{"team-a:app.service", "[X-Fleet]\nConflicts=team-b:*.service", namespaces, http.StatusForbidden},
+		{"team-a:app.service", "[X-Fleet]\nX-Conflicts=*", namespaces, http.StatusForbidden},
+		{"team-a:app.service", "[X-Fleet]\nReplaces=team-b:app.service", namespaces, http.StatusForbidden},
+		{"team-a:app.service", "[X-Fleet]\nFleetRequires=db.service", namespaces, http.StatusForbidden},
+		{"team-a:app.service", "[X-Fleet]\nFleetAfter=team-b:db.service", namespaces, http.StatusForbidden},
+		// unless the client is not restricted to namespaces
+		{"team-a:app.service", "[X-Fleet]\nMachineOf=db.service", nil, http.StatusCreated},
+	} {
+		uf := newUnit(t, tt.contents)
+		su := schema.Unit{Name: tt.name, DesiredState: "inactive", Options: schema.MapUnitFileToSchemaUnitOptions(&uf)}
+		enc, err := json.Marshal(su)
+		if err != nil {
+			t.Fatalf("case %d: unable to JSON-encode request: %v", i, err)
+		}
+		req, err := http.NewRequest("PUT", "http://example.com/units/"+tt.name, bytes.NewBuffer(enc))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.set(&authResponseWriter{ResponseWriter: rw, namespaces: tt.namespaces}, req, tt.name)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+	}
+}
+
+func TestUnitsCreateScheduled(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetMachines([]machine.MachineState{machine.MachineState{ID: "XXX"}})
+	ur := &unitsResource{&client.RegistryClient{Registry: fr}, "/units", nil}
+
+	for i, tt := range []struct {
+		name     string
+		contents string
+		machine  string
+		code     int
+	}{
+		// units are scheduled to the given machine right away
+		{"foo.service", "[Service]\nExecStart=/bin/foo", "XXX", http.StatusCreated},
+		// but only to machines in the cluster
+		{"bar.service", "[Service]\nExecStart=/bin/bar", "YYY", http.StatusConflict},
+		// global units are not scheduled
+		{"baz.service", "[X-Fleet]\nGlobal=true", "XXX", http.StatusBadRequest},
+	} {
+		uf := newUnit(t, tt.contents)
+		su := schema.Unit{Name: tt.name, DesiredState: "launched", MachineID: tt.machine, Options: schema.MapUnitFileToSchemaUnitOptions(&uf)}
+		enc, err := json.Marshal(su)
+		if err != nil {
+			t.Fatalf("case %d: unable to JSON-encode request: %v", i, err)
+		}
+		req, err := http.NewRequest("PUT", "http://example.com/units/"+tt.name, bytes.NewBuffer(enc))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.set(rw, req, tt.name)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+		}
+
+		var want string
+		if tt.code == http.StatusCreated {
+			want = tt.machine
+		}
+		if su, _ := fr.ScheduledUnit(tt.name); su != nil && su.TargetMachineID != want || su == nil && want != "" {
+			t.Errorf("case %d: unit not scheduled to %q: %v", i, want, su)
+		}
+	}
+}
+
 func TestValidateOptions(t *testing.T) {
 	testCases := []struct {
 		opts  []*schema.UnitOption
@@ -618,6 +836,285 @@ func TestValidateOptions(t *testing.T) {
 			},
 			false,
 		},
+		// ConflictsWithMetadata requires Conflicts
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "ConflictsWithMetadata",
+					Value:   "region",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				makeConflictUO("foo@*.service"),
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "ConflictsWithMetadata",
+					Value:   "region",
+				},
+			},
+			true,
+		},
+		// Replaces must not name a peer
+		{
+			[]*schema.UnitOption{
+				makePeerUO("foo.service"),
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Replaces",
+					Value:   "foo.service",
+				},
+			},
+			false,
+		},
+		// Replaces cannot be combined with Global
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Global",
+					Value:   "true",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Replaces",
+					Value:   "foo.service",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				makePeerUO("bar.service"),
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Replaces",
+					Value:   "foo.service",
+				},
+			},
+			true,
+		},
+		// OnFailure cannot be combined with Global or MachineID
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Global",
+					Value:   "true",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "OnFailure",
+					Value:   "reschedule",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "MachineID",
+					Value:   "abc123",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "OnFailure",
+					Value:   "reschedule",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "OnFailure",
+					Value:   "reschedule",
+				},
+			},
+			true,
+		},
+		// Schedule cannot be combined with Global
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Global",
+					Value:   "true",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Schedule",
+					Value:   "*/15 * * * *",
+				},
+			},
+			false,
+		},
+		// Batch cannot be combined with Global
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Global",
+					Value:   "true",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Batch",
+					Value:   "true",
+				},
+			},
+			false,
+		},
+		// RescheduleDelay cannot be combined with Global
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Global",
+					Value:   "true",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "RescheduleDelay",
+					Value:   "1m",
+				},
+			},
+			false,
+		},
+		// SpreadAcross cannot be combined with Global
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Global",
+					Value:   "true",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "SpreadAcross",
+					Value:   "zone",
+				},
+			},
+			false,
+		},
+		// MaxSkew requires SpreadAcross
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "MaxSkew",
+					Value:   "2",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "SpreadAcross",
+					Value:   "zone",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "MaxSkew",
+					Value:   "2",
+				},
+			},
+			true,
+		},
+		// Runtime requires RuntimeImage and replaces ExecStart
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Runtime",
+					Value:   "docker",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Runtime",
+					Value:   "docker",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "RuntimeImage",
+					Value:   "nginx",
+				},
+			},
+			true,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "Service",
+					Name:    "ExecStart",
+					Value:   "/bin/nginx",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "Runtime",
+					Value:   "docker",
+				},
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "RuntimeImage",
+					Value:   "nginx",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "RuntimeImage",
+					Value:   "nginx",
+				},
+			},
+			false,
+		},
+		// WorkloadWindow must be valid
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "WorkloadWindow",
+					Value:   "22:00-06:00 Europe/Berlin",
+				},
+			},
+			true,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "WorkloadWindow",
+					Value:   "22:00",
+				},
+			},
+			false,
+		},
+		{
+			[]*schema.UnitOption{
+				&schema.UnitOption{
+					Section: "X-Fleet",
+					Name:    "WorkloadWindow",
+					Value:   "22:00-06:00 Nowhere/Atlantis",
+				},
+			},
+			false,
+		},
 	}
 	for i, tt := range testCases {
 		err := ValidateOptions(tt.opts)
@@ -656,6 +1153,12 @@ func TestValidateName(t *testing.T) {
 		// cannot start in "@"
 		"@foo.service",
 		"@this.mount",
+		// must have a valid namespace, other than the default one
+		":foo.service",
+		"Team:foo.service",
+		"-team:foo.service",
+		"default:foo.service",
+		"team:@foo.service",
 	}
 	for _, name := range badTestCases {
 		if err := ValidateName(name); err == nil {
@@ -671,6 +1174,7 @@ func TestValidateName(t *testing.T) {
 		"yo.yo.service",
 		"hello@world.path",
 		"hello:world.service",
+		"team-a:foo@1.service",
 		"yes@no\\.service",
 		"foo-bar.mount",
 		"jalapano_chips.service",
@@ -683,3 +1187,388 @@ func TestValidateName(t *testing.T) {
 		}
 	}
 }
+
+func TestUnitsScale(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		{Name: "foo@.service"},
+		{Name: "bar.service"},
+	})
+	fAPI := &client.RegistryClient{Registry: fr}
+	ur := &unitsResource{fAPI, "/units", nil}
+
+	for i, tt := range []struct {
+		method string
+		name   string
+		body   string
+		code   int
+		scales map[string]int
+	}{
+		{"GET", "foo@.service", "", http.StatusNotFound, map[string]int{}},
+		{"PUT", "foo@.service", `{"count":3}`, http.StatusNoContent, map[string]int{"foo@.service": 3}},
+		{"GET", "foo@.service", "", http.StatusOK, map[string]int{"foo@.service": 3}},
+		{"PUT", "foo@.service", `{}`, http.StatusNoContent, map[string]int{"foo@.service": 0}},
+		{"PUT", "foo@.service", `{"count":-1}`, http.StatusBadRequest, map[string]int{"foo@.service": 0}},
+		{"PUT", "bar.service", `{"count":2}`, http.StatusBadRequest, map[string]int{"foo@.service": 0}},
+		{"PUT", "baz@.service", `{"count":2}`, http.StatusNotFound, map[string]int{"foo@.service": 0}},
+		{"POST", "foo@.service", "", http.StatusMethodNotAllowed, map[string]int{"foo@.service": 0}},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com/units/"+tt.name+"/scale", bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rw.Code)
+		}
+
+		scales, _ := fr.UnitScales()
+		if !reflect.DeepEqual(scales, tt.scales) {
+			t.Errorf("case %d: unexpected scales: got %v, want %v", i, scales, tt.scales)
+		}
+	}
+}
+
+func TestUnitsPlan(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetMachines([]machine.MachineState{
+		{ID: "XXX", Metadata: map[string]string{"region": "us"}},
+		{ID: "YYY", Metadata: map[string]string{"region": "eu"}},
+	})
+	fr.SetJobs([]job.Job{
+		{Name: "bar.service", Unit: newUnit(t, "[X-Fleet]\nMachineMetadata=region=us")},
+	})
+	fAPI := &client.RegistryClient{Registry: fr}
+	ur := &unitsResource{fAPI, "/units", nil}
+
+	for i, tt := range []struct {
+		method string
+		url    string
+		body   string
+		code   int
+		want   *schema.UnitPlacement
+	}{
+		{
+			"POST",
+			"http://example.com/units/foo.service/plan",
+			`{"options":[{"section":"X-Fleet","name":"MachineMetadata","value":"region=eu"}]}`,
+			http.StatusOK,
+			&schema.UnitPlacement{
+				MachineID: "YYY",
+				Rejections: []*schema.MachineRejection{{
+					MachineID:  "XXX",
+					Reason:     "local Machine metadata insufficient",
+					Shortfalls: []*schema.Shortfall{{Constraint: "metadata", Reason: "local Machine metadata insufficient"}},
+				}},
+			},
+		},
+		// the options of a Unit in the cluster are used if none are given
+		{
+			"POST",
+			"http://example.com/units/bar.service/plan?strategy=binpack",
+			`{}`,
+			http.StatusOK,
+			&schema.UnitPlacement{
+				MachineID: "XXX",
+				Rejections: []*schema.MachineRejection{{
+					MachineID:  "YYY",
+					Reason:     "local Machine metadata insufficient",
+					Shortfalls: []*schema.Shortfall{{Constraint: "metadata", Reason: "local Machine metadata insufficient"}},
+				}},
+			},
+		},
+		{"POST", "http://example.com/units/baz.service/plan", `{}`, http.StatusNotFound, nil},
+		{"POST", "http://example.com/units/bar.service/plan?strategy=bogus", `{}`, http.StatusBadRequest, nil},
+		{"GET", "http://example.com/units/bar.service/plan", "", http.StatusMethodNotAllowed, nil},
+	} {
+		req, err := http.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+
+		var got schema.UnitPlacement
+		if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+			t.Errorf("case %d: received unparseable body: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&got, tt.want) {
+			t.Errorf("case %d: got %#v, want %#v", i, got, *tt.want)
+		}
+	}
+}
+
+func TestUnitsRejections(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		{Name: "foo.service", Unit: newUnit(t, "[X-Fleet]\nMachineMetadata=region=eu")},
+		{Name: "bar.service", Unit: newUnit(t, "")},
+	})
+	fr.SaveUnitRejections("foo.service", registry.UnitRejections{
+		Time:     time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC),
+		Reason:   "no agents able to run job",
+		Machines: map[string]string{"YYY": "local Machine metadata insufficient", "XXX": "local Machine metadata insufficient; Machine(XXX) is cordoned"},
+		Shortfalls: map[string][]job.Shortfall{
+			"XXX": []job.Shortfall{
+				{Constraint: job.ConstraintMetadata, Reason: "local Machine metadata insufficient"},
+				{Constraint: job.ConstraintCordoned, Reason: "Machine(XXX) is cordoned"},
+			},
+		},
+	}, time.Minute)
+	fAPI := &client.RegistryClient{Registry: fr}
+	ur := &unitsResource{fAPI, "/units", nil}
+
+	for i, tt := range []struct {
+		method string
+		url    string
+		code   int
+		want   *schema.UnitRejections
+	}{
+		{
+			"GET",
+			"http://example.com/units/foo.service/rejections",
+			http.StatusOK,
+			&schema.UnitRejections{
+				Time:   "2014-10-01T12:00:00Z",
+				Reason: "no agents able to run job",
+				Rejections: []*schema.MachineRejection{
+					{
+						MachineID: "XXX",
+						Reason:    "local Machine metadata insufficient; Machine(XXX) is cordoned",
+						Shortfalls: []*schema.Shortfall{
+							{Constraint: "metadata", Reason: "local Machine metadata insufficient"},
+							{Constraint: "cordoned", Reason: "Machine(XXX) is cordoned"},
+						},
+					},
+					{MachineID: "YYY", Reason: "local Machine metadata insufficient"},
+				},
+			},
+		},
+		// Units without recorded problems have empty rejections
+		{"GET", "http://example.com/units/bar.service/rejections", http.StatusOK, &schema.UnitRejections{}},
+		{"GET", "http://example.com/units/baz.service/rejections", http.StatusNotFound, nil},
+		{"DELETE", "http://example.com/units/foo.service/rejections", http.StatusMethodNotAllowed, nil},
+	} {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+
+		rw := httptest.NewRecorder()
+		ur.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+
+		var got schema.UnitRejections
+		if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+			t.Errorf("case %d: received unparseable body: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&got, tt.want) {
+			t.Errorf("case %d: got %#v, want %#v", i, got, *tt.want)
+		}
+	}
+}
+
+func TestUnitsRuns(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		{Name: "backup@.service", Unit: newUnit(t, "[X-Fleet]\nSchedule=0 3 * * *")},
+	})
+	tick := time.Date(2014, 10, 1, 3, 0, 0, 0, time.UTC)
+	fr.SaveCronRun(registry.CronRun{Name: "backup@1412132400.service", Template: "backup@.service", Tick: tick, MachineID: "XXX", Finished: tick.Add(time.Minute), Result: registry.CronRunSucceeded})
+	fr.SaveCronRun(registry.CronRun{Name: "other@1412132400.service", Template: "other@.service", Tick: tick})
+	fAPI := &client.RegistryClient{Registry: fr}
+	ur := &unitsResource{fAPI, "/units", nil}
+
+	for i, tt := range []struct {
+		method string
+		url    string
+		code   int
+		want   *schema.CronRunPage
+	}{
+		{
+			"GET",
+			"http://example.com/units/backup@.service/runs",
+			http.StatusOK,
+			&schema.CronRunPage{Runs: []*schema.CronRun{
+				{Name: "backup@1412132400.service", Tick: "2014-10-01T03:00:00Z", MachineID: "XXX", Finished: "2014-10-01T03:01:00Z", Result: "succeeded"},
+			}},
+		},
+		{"GET", "http://example.com/units/other@.service/runs", http.StatusNotFound, nil},
+		{"PUT", "http://example.com/units/backup@.service/runs", http.StatusMethodNotAllowed, nil},
+	} {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+
+		rw := httptest.NewRecorder()
+		ur.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+
+		var got schema.CronRunPage
+		if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+			t.Errorf("case %d: received unparseable body: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&got, tt.want) {
+			t.Errorf("case %d: got %#v, want %#v", i, got, *tt.want)
+		}
+	}
+}
+
+func TestUnitsHistory(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	tm := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	fr.RecordEvent(registry.ClusterEvent{Time: tm, Type: registry.EventUnitScheduled, UnitName: "foo.service", MachineID: "XXX"})
+	fr.RecordEvent(registry.ClusterEvent{Time: tm, Type: registry.EventMachineLeft, MachineID: "YYY"})
+	fr.RecordEvent(registry.ClusterEvent{Time: tm, Type: registry.EventUnitStateChanged, UnitName: "foo.service", MachineID: "XXX", Reason: "activating/start -> failed/failed"})
+	fAPI := &client.RegistryClient{Registry: fr}
+	ur := &unitsResource{fAPI, "/units", nil}
+
+	for i, tt := range []struct {
+		method string
+		url    string
+		code   int
+		want   *schema.EventPage
+	}{
+		{
+			"GET",
+			"http://example.com/units/foo.service/history",
+			http.StatusOK,
+			&schema.EventPage{Events: []*schema.Event{
+				{Index: 1, Time: "2014-10-01T12:00:00Z", Type: registry.EventUnitScheduled, UnitName: "foo.service", MachineID: "XXX"},
+				{Index: 3, Time: "2014-10-01T12:00:00Z", Type: registry.EventUnitStateChanged, UnitName: "foo.service", MachineID: "XXX", Reason: "activating/start -> failed/failed"},
+			}},
+		},
+		{"GET", "http://example.com/units/bar.service/history", http.StatusOK, &schema.EventPage{}},
+		{"DELETE", "http://example.com/units/foo.service/history", http.StatusMethodNotAllowed, nil},
+	} {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+
+		rw := httptest.NewRecorder()
+		ur.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+
+		var got schema.EventPage
+		if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+			t.Errorf("case %d: received unparseable body: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&got, tt.want) {
+			t.Errorf("case %d: got %#v, want %#v", i, got, *tt.want)
+		}
+	}
+}
+
+func TestUnitsLabels(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fr.SetJobs([]job.Job{
+		{Name: "web.service"},
+		{Name: "db.service"},
+	})
+	fAPI := &client.RegistryClient{Registry: fr}
+	ur := &unitsResource{fAPI, "/units", nil}
+
+	for i, tt := range []struct {
+		method string
+		name   string
+		body   string
+		code   int
+		labels map[string]string
+	}{
+		{"PUT", "web.service", `{"labels":{"app":"web","env":"staging"}}`, http.StatusNoContent, map[string]string{"app": "web", "env": "staging"}},
+		{"PUT", "web.service", `{"labels":{"app":"web app"}}`, http.StatusBadRequest, map[string]string{"app": "web", "env": "staging"}},
+		{"PUT", "nope.service", `{"labels":{"app":"web"}}`, http.StatusNotFound, map[string]string{"app": "web", "env": "staging"}},
+		{"GET", "web.service", "", http.StatusMethodNotAllowed, map[string]string{"app": "web", "env": "staging"}},
+		{"PUT", "web.service", `{}`, http.StatusNoContent, nil},
+	} {
+		req, err := http.NewRequest(tt.method, "http://example.com/units/"+tt.name+"/labels", bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rw.Code)
+		}
+
+		u, _ := fr.Unit("web.service")
+		if !reflect.DeepEqual(u.Labels, tt.labels) {
+			t.Errorf("case %d: unexpected labels: got %v, want %v", i, u.Labels, tt.labels)
+		}
+	}
+
+	// Units are listed by selector
+	fr.SetUnitLabels("web.service", map[string]string{"app": "web"})
+	fr.SetUnitLabels("db.service", map[string]string{"app": "db"})
+	for i, tt := range []struct {
+		query string
+		code  int
+		names []string
+	}{
+		{"selector=app%3Dweb", http.StatusOK, []string{"web.service"}},
+		{"selector=app%21%3Dweb", http.StatusOK, []string{"db.service"}},
+		{"selector=app", http.StatusOK, []string{"db.service", "web.service"}},
+		{"selector=%3Dweb", http.StatusBadRequest, nil},
+	} {
+		rw := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://example.com/units?"+tt.query, nil)
+		ur.list(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d", i, tt.code, rw.Code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+
+		var page schema.UnitPage
+		if err := json.Unmarshal(rw.Body.Bytes(), &page); err != nil {
+			t.Fatalf("case %d: Received unparseable body: %v", i, err)
+		}
+		var names []string
+		for _, u := range page.Units {
+			names = append(names, u.Name)
+		}
+		if !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("case %d: expected units %v, got %v", i, tt.names, names)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-semver/semver"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/schema"
+)
+
+func wireUpUpgradeResource(mux *http.ServeMux, prefix string, cAPI client.API) {
+	base := path.Join(prefix, "upgrade")
+	ur := upgradeResource{cAPI}
+	mux.Handle(base, &ur)
+}
+
+// upgradeResource serves the upgrade of fleetd across the cluster and its
+// progress, and lets upgrades be started and cancelled
+type upgradeResource struct {
+	cAPI client.API
+}
+
+func (ur *upgradeResource) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+		ur.get(rw)
+	case "PUT":
+		ur.set(rw, req)
+	case "DELETE":
+		ur.cancel(rw)
+	default:
+		sendError(rw, http.StatusMethodNotAllowed, errors.New("only GET, PUT and DELETE supported against this resource"))
+	}
+}
+
+func (ur *upgradeResource) get(rw http.ResponseWriter) {
+	u, err := ur.cAPI.Upgrade()
+	if err != nil {
+		log.Errorf("Failed fetching upgrade: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	if u == nil {
+		sendError(rw, http.StatusNotFound, errors.New("no upgrade in progress"))
+		return
+	}
+	sendResponse(rw, http.StatusOK, u)
+}
+
+func (ur *upgradeResource) set(rw http.ResponseWriter, req *http.Request) {
+	if validateContentType(req) != nil {
+		sendError(rw, http.StatusNotAcceptable, errors.New("application/json is only supported Content-Type"))
+		return
+	}
+
+	var u schema.Upgrade
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&u); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("unable to decode body: %v", err))
+		return
+	}
+	if _, err := semver.NewVersion(u.Version); err != nil {
+		sendError(rw, http.StatusBadRequest, fmt.Errorf("invalid version %q: %v", u.Version, err))
+		return
+	}
+
+	if err := ur.cAPI.StartUpgrade(u.Version, u.DomainKey); err != nil {
+		log.Errorf("Failed starting upgrade to %s: %v", u.Version, err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (ur *upgradeResource) cancel(rw http.ResponseWriter) {
+	if err := ur.cAPI.CancelUpgrade(); err != nil {
+		log.Errorf("Failed cancelling upgrade: %v", err)
+		sendError(rw, http.StatusInternalServerError, nil)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestUpgradeResource(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	fAPI := &client.RegistryClient{Registry: fr}
+	ur := &upgradeResource{fAPI}
+
+	for i, tt := range []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{"GET", "", http.StatusNotFound},
+		{"PUT", `{"version":"latest"}`, http.StatusBadRequest},
+		{"PUT", `{`, http.StatusBadRequest},
+		{"PUT", `{"version":"0.9.0","domainKey":"az"}`, http.StatusNoContent},
+		{"GET", "", http.StatusOK},
+		{"POST", "", http.StatusMethodNotAllowed},
+		{"DELETE", "", http.StatusNoContent},
+		{"GET", "", http.StatusNotFound},
+	} {
+		if i == 4 {
+			fr.SaveUpgradeStatus(registry.UpgradeStatus{MachineID: "XXX", Version: "0.9.0", State: registry.UpgradeStateDone, Time: time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)})
+		}
+
+		req, err := http.NewRequest(tt.method, "http://example.com/upgrade", bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("case %d: failed creating http.Request: %v", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		ur.ServeHTTP(rw, req)
+		if rw.Code != tt.code {
+			t.Errorf("case %d: expected %d, got %d: %s", i, tt.code, rw.Code, rw.Body.String())
+			continue
+		}
+
+		if tt.method == "GET" && tt.code == http.StatusOK {
+			var got schema.Upgrade
+			if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+				t.Errorf("case %d: received unparseable body: %v", i, err)
+				continue
+			}
+			if got.Version != "0.9.0" || got.DomainKey != "az" || len(got.Machines) != 1 || got.Machines[0].State != "done" || got.Machines[0].Time != "2014-10-01T12:00:00Z" {
+				t.Errorf("case %d: unexpected upgrade %#v", i, got)
+			}
+		}
+	}
+}
@@ -1,18 +1,151 @@
 package client
 
 import (
+	"io"
+	"time"
+
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
 )
 
 type API interface {
 	Machines() ([]machine.MachineState, error)
+	SetMachineMetadata(machID, key, value string) error
+	DeleteMachineMetadata(machID, key string) error
+	CordonMachine(machID string, drain bool) error
+	UncordonMachine(machID string) error
+	TaintMachine(machID string, t machine.Taint) error
+	UntaintMachine(machID, key string) error
 
 	Unit(string) (*schema.Unit, error)
 	Units() ([]*schema.Unit, error)
 	UnitStates() ([]*schema.UnitState, error)
+	// UnitsMatching returns the Units the given Filter selects.
+	UnitsMatching(f Filter) ([]*schema.Unit, error)
+	// UnitStatesMatching returns the UnitStates the given Filter selects.
+	UnitStatesMatching(f Filter) ([]*schema.UnitState, error)
 
 	SetUnitTargetState(name, target string) error
+	// CreateUnit creates the given Unit. If it has a MachineID, the Unit
+	// is scheduled to that machine instead of waiting for the engine to
+	// schedule it, as when restoring a snapshot of the cluster.
 	CreateUnit(*schema.Unit) error
 	DestroyUnit(string) error
+	// ReplaceUnit replaces the unit file of the existing Unit of the same
+	// name with the one given, keeping its desired state and placement.
+	// Running instances pick up the new unit file according to its
+	// UpdatePolicy.
+	ReplaceUnit(*schema.Unit) error
+	// SetUnitLabels replaces the labels of the named Unit with the given
+	// ones, removing them all if none are given.
+	SetUnitLabels(name string, labels map[string]string) error
+
+	// SetUnitScale sets the number of instances the engine maintains of
+	// the named template Unit.
+	SetUnitScale(tmpl string, count int) error
+	// UnitScale returns the number of instances the engine maintains of
+	// the named template Unit, or nil if it has not been scaled.
+	UnitScale(tmpl string) (*schema.Scale, error)
+
+	// Events returns the cluster events recorded after the given index.
+	// If there are none, it waits up to the given amount of time for the
+	// next one to be recorded.
+	Events(since uint64, wait time.Duration) ([]*schema.Event, error)
+
+	// AuditLog returns the recorded changes made to the Units of the
+	// cluster, oldest first.
+	AuditLog() ([]*schema.AuditEntry, error)
+
+	// PlanUnit determines where the engine, using the given scheduling
+	// strategy, would schedule the given Unit, without submitting it.
+	PlanUnit(u *schema.Unit, strategy string) (*schema.UnitPlacement, error)
+
+	// UnitRejections returns why the engine was last unable to schedule
+	// the named Unit. It returns an empty UnitRejections if the engine
+	// has recorded no problems scheduling the Unit.
+	UnitRejections(name string) (*schema.UnitRejections, error)
+
+	// UnitRuns returns the run history of the named template Unit with a
+	// Schedule, oldest first.
+	UnitRuns(tmpl string) ([]*schema.CronRun, error)
+
+	// UnitVersions returns the recorded versions of the unit file of the
+	// named Unit, oldest first. Versions are kept after the Unit is
+	// destroyed.
+	UnitVersions(name string) ([]*schema.UnitVersion, error)
+
+	// UnitFile returns the unit file whose contents have the given hash,
+	// or nil if no such unit file exists.
+	UnitFile(hash string) (*schema.UnitFile, error)
+
+	// UnitHistory returns the latest cluster events concerning the named
+	// Unit, oldest first. They are kept after the Unit is destroyed.
+	UnitHistory(name string) ([]*schema.Event, error)
+
+	// UnitCompletions returns how the last runs of all batch Units that
+	// finished ended, in the order they finished.
+	UnitCompletions() ([]*schema.UnitCompletion, error)
+
+	// CreateStack records a Stack of Units the engine schedules together,
+	// failing if a Stack of the same name exists. The Units are not
+	// created.
+	CreateStack(*schema.Stack) error
+	// DestroyStack removes the named Stack, leaving its Units alone.
+	DestroyStack(name string) error
+	// Stack returns the named Stack, or nil if it does not exist.
+	Stack(name string) (*schema.Stack, error)
+	Stacks() ([]*schema.Stack, error)
+
+	// EngineStatuses returns the status of the engines holding the lease
+	// of the lead engine or of shards of the scheduling work.
+	EngineStatuses() ([]*schema.EngineStatus, error)
+	// RequestEngineStepDown asks the engine of the identified machine to
+	// release its leases and not to acquire any for the given duration.
+	RequestEngineStepDown(machID string, hold time.Duration) error
+
+	// Upgrade returns the current upgrade of fleetd across the cluster,
+	// along with the progress of each machine, or nil if there is none.
+	Upgrade() (*schema.Upgrade, error)
+	// StartUpgrade asks the agents to restart into the given version of
+	// fleetd, one failure domain, as given by the domainKey metadata of
+	// each machine, at a time. It replaces any current upgrade.
+	StartUpgrade(version, domainKey string) error
+	// CancelUpgrade cancels the current upgrade, if any.
+	CancelUpgrade() error
+
+	// ConfigValues returns the configuration values stored in the named
+	// namespace, sorted by key. Secret values are returned encrypted.
+	ConfigValues(namespace string) ([]*schema.ConfigValue, error)
+	// SetConfigValue stores the given value under its key in the named
+	// namespace. Secret values must be encrypted with the cluster key.
+	SetConfigValue(namespace string, cv *schema.ConfigValue) error
+	DeleteConfigValue(namespace, key string) error
+
+	// ClusterConfig returns the options of fleet.conf set for the whole
+	// cluster, along with the version of the configuration.
+	ClusterConfig() (*schema.ClusterConfig, error)
+	// SetClusterConfig replaces the options set for the whole cluster
+	// with the Values of the given ClusterConfig, provided its Version is
+	// still the current one, and returns the resulting ClusterConfig.
+	// Otherwise registry.ErrClusterConfigChanged is returned.
+	SetClusterConfig(cc *schema.ClusterConfig) (*schema.ClusterConfig, error)
+
+	// Quotas returns the Quotas of all namespaces, sorted by namespace,
+	// along with what the Units of each namespace count against them.
+	Quotas() ([]*schema.Quota, error)
+	// SetQuota replaces the Quota of its namespace.
+	SetQuota(q *schema.Quota) error
+	DeleteQuota(namespace string) error
+
+	// UnitJournal streams the journal of the named Unit from the machine
+	// it is scheduled to, starting with the given number of recent
+	// entries. If follow is set, new entries are streamed until the
+	// returned stream is closed, from whichever machine the Unit is
+	// rescheduled to.
+	UnitJournal(name string, lines int, follow bool) (io.ReadCloser, error)
+
+	// RunCommand runs the given command on the identified machine,
+	// copying its combined output to out as it is produced, and returns
+	// its exit status.
+	RunCommand(machID string, command []string, out io.Writer) (int, error)
 }
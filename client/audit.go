@@ -0,0 +1,105 @@
+package client
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+// AuditFunc records an AuditEntry
+type AuditFunc func(ae registry.AuditEntry)
+
+// NewAuditedAPI wraps the given API so that each change successfully made
+// through it to the Units of the cluster is passed to record, attributed to
+// the given user.
+func NewAuditedAPI(api API, user string, record AuditFunc) API {
+	return &auditedAPI{API: api, user: user, record: record}
+}
+
+type auditedAPI struct {
+	API
+	user   string
+	record AuditFunc
+}
+
+func (a *auditedAPI) CreateUnit(u *schema.Unit) error {
+	if err := a.API.CreateUnit(u); err != nil {
+		return err
+	}
+	a.audit(registry.AuditUnitCreated, u.Name, "", u.DesiredState)
+	return nil
+}
+
+func (a *auditedAPI) ReplaceUnit(u *schema.Unit) error {
+	var prev string
+	if eu, err := a.API.Unit(u.Name); err == nil && eu != nil {
+		prev = schema.MapSchemaUnitOptionsToUnitFile(eu.Options).Hash().Short()
+	}
+	if err := a.API.ReplaceUnit(u); err != nil {
+		return err
+	}
+	a.audit(registry.AuditUnitReplaced, u.Name, prev, schema.MapSchemaUnitOptionsToUnitFile(u.Options).Hash().Short())
+	return nil
+}
+
+func (a *auditedAPI) DestroyUnit(name string) error {
+	prev := a.desiredState(name)
+	if err := a.API.DestroyUnit(name); err != nil {
+		return err
+	}
+	a.audit(registry.AuditUnitDestroyed, name, prev, "")
+	return nil
+}
+
+func (a *auditedAPI) SetUnitTargetState(name, target string) error {
+	prev := a.desiredState(name)
+	if err := a.API.SetUnitTargetState(name, target); err != nil {
+		return err
+	}
+	a.audit(registry.AuditUnitTargetStateSet, name, prev, target)
+	return nil
+}
+
+func (a *auditedAPI) SetUnitScale(tmpl string, count int) error {
+	if err := a.API.SetUnitScale(tmpl, count); err != nil {
+		return err
+	}
+	a.audit(registry.AuditUnitScaled, tmpl, "", strconv.Itoa(count))
+	return nil
+}
+
+func (a *auditedAPI) SetUnitLabels(name string, labels map[string]string) error {
+	var prev string
+	if eu, err := a.API.Unit(name); err == nil && eu != nil {
+		prev = job.FormatLabels(eu.Labels)
+	}
+	if err := a.API.SetUnitLabels(name, labels); err != nil {
+		return err
+	}
+	a.audit(registry.AuditUnitLabeled, name, prev, job.FormatLabels(labels))
+	return nil
+}
+
+// desiredState returns the desired state of the named Unit before it is
+// changed, if it can be determined
+func (a *auditedAPI) desiredState(name string) string {
+	u, err := a.API.Unit(name)
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.DesiredState
+}
+
+func (a *auditedAPI) audit(action, name, prev, state string) {
+	a.record(registry.AuditEntry{
+		Time:      time.Now(),
+		User:      a.user,
+		Action:    action,
+		UnitName:  name,
+		PrevState: prev,
+		State:     state,
+	})
+}
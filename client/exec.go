@@ -0,0 +1,89 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The response of the API to running a command frames its output so that
+// the exit status of the command can follow it in the body. Every chunk of
+// output is preceded by a line holding "output" and the length of the chunk
+// in bytes, and the response ends with a line holding "exit" and the exit
+// status of the command.
+const (
+	outputFrame = "output"
+	exitFrame   = "exit"
+)
+
+// CommandOutputWriter frames the output of a command written to it for
+// the response of the API to running the command
+type CommandOutputWriter struct {
+	w io.Writer
+}
+
+func NewCommandOutputWriter(w io.Writer) *CommandOutputWriter {
+	return &CommandOutputWriter{w}
+}
+
+func (cw *CommandOutputWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	frame := make([]byte, 0, len(p)+16)
+	frame = append(frame, fmt.Sprintf("%s %d\n", outputFrame, len(p))...)
+	frame = append(frame, p...)
+	if _, err := cw.w.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Exit ends the response with the exit status of the command
+func (cw *CommandOutputWriter) Exit(status int) error {
+	_, err := fmt.Fprintf(cw.w, "%s %d\n", exitFrame, status)
+	return err
+}
+
+// ReadCommandOutput copies the output of a command framed by a
+// CommandOutputWriter to out, and returns the exit status of the command
+func ReadCommandOutput(r io.Reader, out io.Writer) (int, error) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			return 0, errors.New("exit status of command missing from response")
+		} else if err != nil {
+			return 0, err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return 0, fmt.Errorf("malformed command output frame %q", line)
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, fmt.Errorf("malformed command output frame %q", line)
+		}
+
+		switch fields[0] {
+		case exitFrame:
+			return n, nil
+		case outputFrame:
+			if n < 0 {
+				return 0, fmt.Errorf("malformed command output frame %q", line)
+			}
+			if _, err := io.CopyN(out, br, int64(n)); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+		default:
+			return 0, fmt.Errorf("malformed command output frame %q", line)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+)
+
+// A Filter selects the Units or UnitStates to list. Its zero value selects
+// everything.
+type Filter struct {
+	// MachineID selects the Units scheduled to, or the UnitStates
+	// published by, the identified machine
+	MachineID string
+	// Name selects the Units or UnitStates whose name matches the given
+	// glob pattern, as in path.Match
+	Name string
+	// State selects the Units in the given current state, or the
+	// UnitStates in the given systemd active state
+	State string
+	// Namespaces selects the Units or UnitStates belonging to any of the
+	// given namespaces
+	Namespaces []string
+	// Selector selects the Units whose labels match the given selector,
+	// as parsed by job.ParseSelector. UnitStates have no labels, so it is
+	// ignored for them.
+	Selector string
+	// Fields lists the JSON fields of each Unit or UnitState the caller
+	// uses. An API server may leave out all others, so they must not be
+	// relied on; other clients return them anyway.
+	Fields []string
+}
+
+// Validate determines whether the Filter's name pattern is well-formed
+func (f Filter) Validate() error {
+	if _, err := path.Match(f.Name, ""); err != nil {
+		return fmt.Errorf("invalid unit name pattern %q: %v", f.Name, err)
+	}
+	if _, err := job.ParseSelector(f.Selector); err != nil {
+		return err
+	}
+	return nil
+}
+
+// matchName matches unit names against a glob pattern. Patterns without
+// wildcards are compared as is, as the backslashes escaped unit names may
+// contain would otherwise be interpreted.
+func (f Filter) matchName(name string) bool {
+	if f.Name == "" {
+		return true
+	}
+	if !strings.ContainsAny(f.Name, "*?[") {
+		return f.Name == name
+	}
+	ok, _ := path.Match(f.Name, name)
+	return ok
+}
+
+func (f Filter) MatchUnit(u *schema.Unit) bool {
+	return (f.Namespaces == nil || job.InNamespace(u.Name, f.Namespaces)) &&
+		(f.MachineID == "" || f.MachineID == u.MachineID) &&
+		(f.State == "" || f.State == u.CurrentState) &&
+		f.matchName(u.Name) &&
+		f.matchLabels(u.Labels)
+}
+
+// matchLabels matches the labels of Units against the selector. Selectors
+// that do not parse match no Units.
+func (f Filter) matchLabels(labels map[string]string) bool {
+	if f.Selector == "" {
+		return true
+	}
+	sel, err := job.ParseSelector(f.Selector)
+	return err == nil && sel.Matches(labels)
+}
+
+func (f Filter) MatchUnitState(us *schema.UnitState) bool {
+	return (f.Namespaces == nil || job.InNamespace(us.Name, f.Namespaces)) &&
+		(f.MachineID == "" || f.MachineID == us.MachineID) &&
+		(f.State == "" || f.State == us.SystemdActiveState) &&
+		f.matchName(us.Name)
+}
+
+// FilterUnits returns the given Units the Filter selects, in order
+func FilterUnits(units []*schema.Unit, f Filter) []*schema.Unit {
+	var selected []*schema.Unit
+	for _, u := range units {
+		if f.MatchUnit(u) {
+			selected = append(selected, u)
+		}
+	}
+	return selected
+}
+
+// FilterUnitStates returns the given UnitStates the Filter selects, in
+// order
+func FilterUnitStates(states []*schema.UnitState, f Filter) []*schema.UnitState {
+	var selected []*schema.UnitState
+	for _, us := range states {
+		if f.MatchUnitState(us) {
+			selected = append(selected, us)
+		}
+	}
+	return selected
+}
@@ -1,13 +1,20 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coreos/fleet/Godeps/_workspace/src/code.google.com/p/google-api-go-client/googleapi"
 
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
 	"github.com/coreos/fleet/schema"
 )
 
@@ -26,12 +33,16 @@ func NewHTTPClient(c *http.Client, endpoint string) (API, error) {
 	ep.Path = path.Join(ep.Path, "v1-alpha") + "/"
 	svc.BasePath = ep.String()
 
-	return &HTTPClient{svc: svc}, nil
+	return &HTTPClient{svc: svc, hc: c}, nil
 }
 
 type HTTPClient struct {
 	svc *schema.Service
 
+	// hc makes the requests the schema.Service has no calls for, as
+	// their responses are streamed rather than JSON documents
+	hc *http.Client
+
 	//NOTE(bcwaldon): This is only necessary until the API interface
 	// is fully implemented by HTTPClient
 	API
@@ -58,9 +69,57 @@ func (c *HTTPClient) Machines() ([]machine.MachineState, error) {
 	return machines, nil
 }
 
+func (c *HTTPClient) SetMachineMetadata(machID, key, value string) error {
+	return c.svc.Machines.SetMetadata(machID, key, &schema.MetadataValue{Value: value}).Do()
+}
+
+func (c *HTTPClient) DeleteMachineMetadata(machID, key string) error {
+	return c.svc.Machines.DeleteMetadata(machID, key).Do()
+}
+
+func (c *HTTPClient) CordonMachine(machID string, drain bool) error {
+	return c.svc.Machines.Cordon(machID, &schema.Cordon{Drain: drain}).Do()
+}
+
+func (c *HTTPClient) UncordonMachine(machID string) error {
+	return c.svc.Machines.Uncordon(machID).Do()
+}
+
+func (c *HTTPClient) TaintMachine(machID string, t machine.Taint) error {
+	return c.svc.Machines.Taint(machID, t.Key, &schema.Taint{Key: t.Key, Value: t.Value, Effect: t.Effect}).Do()
+}
+
+func (c *HTTPClient) UntaintMachine(machID, key string) error {
+	return c.svc.Machines.Untaint(machID, key).Do()
+}
+
 func (c *HTTPClient) Units() ([]*schema.Unit, error) {
+	return c.UnitsMatching(Filter{})
+}
+
+func (c *HTTPClient) UnitsMatching(f Filter) ([]*schema.Unit, error) {
+	list := func() *schema.UnitsListCall {
+		call := c.svc.Units.List()
+		if f.MachineID != "" {
+			call.MachineID(f.MachineID)
+		}
+		if f.Name != "" {
+			call.UnitName(f.Name)
+		}
+		if f.State != "" {
+			call.State(f.State)
+		}
+		if f.Selector != "" {
+			call.Selector(f.Selector)
+		}
+		if len(f.Fields) > 0 {
+			call.Fields(strings.Join(f.Fields, ","))
+		}
+		return call
+	}
+
 	var units []*schema.Unit
-	call := c.svc.Units.List()
+	call := list()
 	for call != nil {
 		page, err := call.Do()
 		if err != nil {
@@ -70,12 +129,17 @@ func (c *HTTPClient) Units() ([]*schema.Unit, error) {
 		units = append(units, page.Units...)
 
 		if len(page.NextPageToken) > 0 {
-			call = c.svc.Units.List()
+			call = list()
 			call.NextPageToken(page.NextPageToken)
 		} else {
 			call = nil
 		}
 	}
+
+	// namespaces are selected here, as the API takes no query parameter for them
+	if f.Namespaces != nil {
+		units = FilterUnits(units, Filter{Namespaces: f.Namespaces})
+	}
 	return units, nil
 }
 
@@ -88,8 +152,29 @@ func (c *HTTPClient) Unit(name string) (*schema.Unit, error) {
 }
 
 func (c *HTTPClient) UnitStates() ([]*schema.UnitState, error) {
+	return c.UnitStatesMatching(Filter{})
+}
+
+func (c *HTTPClient) UnitStatesMatching(f Filter) ([]*schema.UnitState, error) {
+	list := func() *schema.UnitStateListCall {
+		call := c.svc.UnitState.List()
+		if f.MachineID != "" {
+			call.MachineID(f.MachineID)
+		}
+		if f.Name != "" {
+			call.UnitName(f.Name)
+		}
+		if f.State != "" {
+			call.State(f.State)
+		}
+		if len(f.Fields) > 0 {
+			call.Fields(strings.Join(f.Fields, ","))
+		}
+		return call
+	}
+
 	var states []*schema.UnitState
-	call := c.svc.UnitState.List()
+	call := list()
 	for call != nil {
 		page, err := call.Do()
 		if err != nil {
@@ -99,12 +184,17 @@ func (c *HTTPClient) UnitStates() ([]*schema.UnitState, error) {
 		states = append(states, page.States...)
 
 		if len(page.NextPageToken) > 0 {
-			call = c.svc.UnitState.List()
+			call = list()
 			call.NextPageToken(page.NextPageToken)
 		} else {
 			call = nil
 		}
 	}
+
+	// namespaces are selected here, as the API takes no query parameter for them
+	if f.Namespaces != nil {
+		states = FilterUnitStates(states, Filter{Namespaces: f.Namespaces})
+	}
 	return states, nil
 }
 
@@ -116,6 +206,10 @@ func (c *HTTPClient) CreateUnit(u *schema.Unit) error {
 	return c.svc.Units.Set(u.Name, u).Do()
 }
 
+func (c *HTTPClient) ReplaceUnit(u *schema.Unit) error {
+	return c.svc.Units.Set(u.Name, u).Replace(true).Do()
+}
+
 func (c *HTTPClient) SetUnitTargetState(name, target string) error {
 	u := schema.Unit{
 		Name:         name,
@@ -124,7 +218,242 @@ func (c *HTTPClient) SetUnitTargetState(name, target string) error {
 	return c.svc.Units.Set(name, &u).Do()
 }
 
+func (c *HTTPClient) SetUnitLabels(name string, labels map[string]string) error {
+	return c.svc.Units.SetLabels(name, &schema.UnitLabels{Labels: labels}).Do()
+}
+
+func (c *HTTPClient) SetUnitScale(tmpl string, count int) error {
+	return c.svc.Units.Scale(tmpl, &schema.Scale{Count: int64(count)}).Do()
+}
+
+func (c *HTTPClient) UnitScale(tmpl string) (*schema.Scale, error) {
+	s, err := c.svc.Units.GetScale(tmpl).Do()
+	if err != nil && !is404(err) {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *HTTPClient) PlanUnit(u *schema.Unit, strategy string) (*schema.UnitPlacement, error) {
+	call := c.svc.Units.Plan(u.Name, u)
+	if strategy != "" {
+		call.Strategy(strategy)
+	}
+	return call.Do()
+}
+
+func (c *HTTPClient) UnitRejections(name string) (*schema.UnitRejections, error) {
+	return c.svc.Units.Rejections(name).Do()
+}
+
+func (c *HTTPClient) UnitRuns(tmpl string) ([]*schema.CronRun, error) {
+	page, err := c.svc.Units.Runs(tmpl).Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Runs, nil
+}
+
+func (c *HTTPClient) UnitVersions(name string) ([]*schema.UnitVersion, error) {
+	page, err := c.svc.Units.Versions(name).Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Versions, nil
+}
+
+func (c *HTTPClient) UnitFile(hash string) (*schema.UnitFile, error) {
+	uf, err := c.svc.UnitFiles.Get(hash).Do()
+	if err != nil && !is404(err) {
+		return nil, err
+	}
+	return uf, nil
+}
+
+func (c *HTTPClient) UnitHistory(name string) ([]*schema.Event, error) {
+	page, err := c.svc.Units.History(name).Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Events, nil
+}
+
+func (c *HTTPClient) EngineStatuses() ([]*schema.EngineStatus, error) {
+	page, err := c.svc.Engine.Get().Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Engines, nil
+}
+
+func (c *HTTPClient) RequestEngineStepDown(machID string, hold time.Duration) error {
+	return c.svc.Engine.StepDown(&schema.StepDown{MachineID: machID, Hold: int64(hold / time.Second)}).Do()
+}
+
+func (c *HTTPClient) Upgrade() (*schema.Upgrade, error) {
+	u, err := c.svc.Upgrade.Get().Do()
+	if err != nil && !is404(err) {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (c *HTTPClient) StartUpgrade(version, domainKey string) error {
+	return c.svc.Upgrade.Set(&schema.Upgrade{Version: version, DomainKey: domainKey}).Do()
+}
+
+func (c *HTTPClient) CancelUpgrade() error {
+	return c.svc.Upgrade.Cancel().Do()
+}
+
+func (c *HTTPClient) UnitCompletions() ([]*schema.UnitCompletion, error) {
+	page, err := c.svc.Completions.List().Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Completions, nil
+}
+
+func (c *HTTPClient) CreateStack(s *schema.Stack) error {
+	return c.svc.Stacks.Create(s.Name, s).Do()
+}
+
+func (c *HTTPClient) DestroyStack(name string) error {
+	return c.svc.Stacks.Delete(name).Do()
+}
+
+func (c *HTTPClient) Stack(name string) (*schema.Stack, error) {
+	s, err := c.svc.Stacks.Get(name).Do()
+	if err != nil && !is404(err) {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *HTTPClient) Stacks() ([]*schema.Stack, error) {
+	page, err := c.svc.Stacks.List().Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Stacks, nil
+}
+
+func (c *HTTPClient) ConfigValues(namespace string) ([]*schema.ConfigValue, error) {
+	page, err := c.svc.Config.List(namespace).Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Values, nil
+}
+
+func (c *HTTPClient) SetConfigValue(namespace string, cv *schema.ConfigValue) error {
+	return c.svc.Config.Set(namespace, cv.Key, cv).Do()
+}
+
+func (c *HTTPClient) DeleteConfigValue(namespace, key string) error {
+	return c.svc.Config.Delete(namespace, key).Do()
+}
+
+func (c *HTTPClient) ClusterConfig() (*schema.ClusterConfig, error) {
+	return c.svc.ClusterConfig.Get().Do()
+}
+
+func (c *HTTPClient) SetClusterConfig(cc *schema.ClusterConfig) (*schema.ClusterConfig, error) {
+	updated, err := c.svc.ClusterConfig.Set(cc).Do()
+	if googerr, ok := err.(*googleapi.Error); ok && googerr.Code == http.StatusConflict {
+		err = registry.ErrClusterConfigChanged
+	}
+	return updated, err
+}
+
+func (c *HTTPClient) Quotas() ([]*schema.Quota, error) {
+	page, err := c.svc.Quotas.List().Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Quotas, nil
+}
+
+func (c *HTTPClient) SetQuota(q *schema.Quota) error {
+	return c.svc.Quotas.Set(q.Namespace, q).Do()
+}
+
+func (c *HTTPClient) DeleteQuota(namespace string) error {
+	return c.svc.Quotas.Delete(namespace).Do()
+}
+
 func is404(err error) bool {
 	googerr, ok := err.(*googleapi.Error)
 	return ok && googerr.Code == http.StatusNotFound
 }
+
+func (c *HTTPClient) Events(since uint64, wait time.Duration) ([]*schema.Event, error) {
+	call := c.svc.Events.List().Since(int64(since))
+	if secs := int64(wait / time.Second); secs > 0 {
+		call.Wait(secs)
+	}
+
+	page, err := call.Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Events, nil
+}
+
+func (c *HTTPClient) AuditLog() ([]*schema.AuditEntry, error) {
+	page, err := c.svc.Audit.List().Do()
+	if err != nil {
+		return nil, err
+	}
+	return page.Entries, nil
+}
+
+func (c *HTTPClient) UnitJournal(name string, lines int, follow bool) (io.ReadCloser, error) {
+	q := url.Values{}
+	q.Set("lines", strconv.Itoa(lines))
+	q.Set("follow", strconv.FormatBool(follow))
+	q.Set("reattach", strconv.FormatBool(follow))
+	req, err := http.NewRequest("GET", c.svc.BasePath+"journals/"+escapePath(name)+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := googleapi.CheckResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *HTTPClient) RunCommand(machID string, command []string, out io.Writer) (int, error) {
+	body, err := json.Marshal(schema.CommandExecution{Command: command})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", c.svc.BasePath+"exec/"+escapePath(machID), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return 0, err
+	}
+
+	return ReadCommandOutput(resp.Body, out)
+}
+
+// escapePath escapes the given unit name or machine ID for use as a single
+// segment of a request path
+func escapePath(s string) string {
+	return (&url.URL{Path: s}).RequestURI()
+}
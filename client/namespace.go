@@ -0,0 +1,212 @@
+package client
+
+import (
+	"io"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+)
+
+// NewNamespacedAPI wraps the given API so that it only acts on the Units of
+// the given namespace, which are named without it. The names of Units
+// passed to the wrapped API are qualified with the namespace, and those it
+// returns are stripped of it. Cluster-wide resources, such as events, are
+// left alone.
+func NewNamespacedAPI(api API, ns string) API {
+	return &namespacedAPI{API: api, ns: ns}
+}
+
+// QualifyOptions returns a copy of the given UnitOptions with the names of
+// the Units referred to by X-Fleet options, such as MachineOf=, qualified
+// with the given namespace, as they are submitted by a namespaced API
+func QualifyOptions(ns string, opts []*schema.UnitOption) []*schema.UnitOption {
+	qualified := make([]*schema.UnitOption, len(opts))
+	for i, opt := range opts {
+		if opt.Section == "X-Fleet" && job.IsReferenceOption(opt.Name) {
+			so := *opt
+			so.Value = job.QualifyReference(ns, so.Value)
+			opt = &so
+		}
+		qualified[i] = opt
+	}
+	return qualified
+}
+
+type namespacedAPI struct {
+	API
+	ns string
+}
+
+func (n *namespacedAPI) qualify(name string) string {
+	return job.QualifyName(n.ns, name)
+}
+
+// qualifyUnit returns a copy of the given Unit with its name, and the names
+// of the Units its options refer to, qualified with the namespace
+func (n *namespacedAPI) qualifyUnit(u *schema.Unit) *schema.Unit {
+	su := *u
+	su.Name = n.qualify(su.Name)
+	su.Options = QualifyOptions(n.ns, u.Options)
+	return &su
+}
+
+func (n *namespacedAPI) strip(name string) string {
+	if ns, base := job.SplitNamespace(name); ns == n.ns {
+		return base
+	}
+	return name
+}
+
+func (n *namespacedAPI) Unit(name string) (*schema.Unit, error) {
+	u, err := n.API.Unit(n.qualify(name))
+	if err != nil || u == nil {
+		return u, err
+	}
+	su := *u
+	su.Name = n.strip(su.Name)
+	return &su, nil
+}
+
+func (n *namespacedAPI) Units() ([]*schema.Unit, error) {
+	return n.UnitsMatching(Filter{})
+}
+
+func (n *namespacedAPI) UnitsMatching(f Filter) ([]*schema.Unit, error) {
+	units, err := n.API.UnitsMatching(n.filter(f))
+	if err != nil {
+		return nil, err
+	}
+	stripped := make([]*schema.Unit, len(units))
+	for i, u := range units {
+		su := *u
+		su.Name = n.strip(su.Name)
+		stripped[i] = &su
+	}
+	return stripped, nil
+}
+
+func (n *namespacedAPI) UnitStates() ([]*schema.UnitState, error) {
+	return n.UnitStatesMatching(Filter{})
+}
+
+func (n *namespacedAPI) UnitStatesMatching(f Filter) ([]*schema.UnitState, error) {
+	states, err := n.API.UnitStatesMatching(n.filter(f))
+	if err != nil {
+		return nil, err
+	}
+	stripped := make([]*schema.UnitState, len(states))
+	for i, us := range states {
+		sus := *us
+		sus.Name = n.strip(sus.Name)
+		stripped[i] = &sus
+	}
+	return stripped, nil
+}
+
+// filter restricts the given Filter to the namespace, qualifying its name
+// pattern
+func (n *namespacedAPI) filter(f Filter) Filter {
+	f.Namespaces = []string{n.ns}
+	if f.Name != "" {
+		f.Name = n.qualify(f.Name)
+	}
+	return f
+}
+
+func (n *namespacedAPI) SetUnitTargetState(name, target string) error {
+	return n.API.SetUnitTargetState(n.qualify(name), target)
+}
+
+func (n *namespacedAPI) CreateUnit(u *schema.Unit) error {
+	return n.API.CreateUnit(n.qualifyUnit(u))
+}
+
+func (n *namespacedAPI) ReplaceUnit(u *schema.Unit) error {
+	return n.API.ReplaceUnit(n.qualifyUnit(u))
+}
+
+func (n *namespacedAPI) DestroyUnit(name string) error {
+	return n.API.DestroyUnit(n.qualify(name))
+}
+
+func (n *namespacedAPI) SetUnitLabels(name string, labels map[string]string) error {
+	return n.API.SetUnitLabels(n.qualify(name), labels)
+}
+
+func (n *namespacedAPI) SetUnitScale(tmpl string, count int) error {
+	return n.API.SetUnitScale(n.qualify(tmpl), count)
+}
+
+func (n *namespacedAPI) UnitScale(tmpl string) (*schema.Scale, error) {
+	return n.API.UnitScale(n.qualify(tmpl))
+}
+
+func (n *namespacedAPI) PlanUnit(u *schema.Unit, strategy string) (*schema.UnitPlacement, error) {
+	up, err := n.API.PlanUnit(n.qualifyUnit(u), strategy)
+	if err != nil || up == nil {
+		return up, err
+	}
+	sup := *up
+	sup.Preempts = make([]string, len(up.Preempts))
+	for i, name := range up.Preempts {
+		sup.Preempts[i] = n.strip(name)
+	}
+	return &sup, nil
+}
+
+func (n *namespacedAPI) UnitRejections(name string) (*schema.UnitRejections, error) {
+	return n.API.UnitRejections(n.qualify(name))
+}
+
+func (n *namespacedAPI) UnitRuns(tmpl string) ([]*schema.CronRun, error) {
+	runs, err := n.API.UnitRuns(n.qualify(tmpl))
+	if err != nil {
+		return nil, err
+	}
+	stripped := make([]*schema.CronRun, len(runs))
+	for i, r := range runs {
+		sr := *r
+		sr.Name = n.strip(sr.Name)
+		stripped[i] = &sr
+	}
+	return stripped, nil
+}
+
+func (n *namespacedAPI) UnitVersions(name string) ([]*schema.UnitVersion, error) {
+	return n.API.UnitVersions(n.qualify(name))
+}
+
+func (n *namespacedAPI) UnitHistory(name string) ([]*schema.Event, error) {
+	events, err := n.API.UnitHistory(n.qualify(name))
+	if err != nil {
+		return nil, err
+	}
+	stripped := make([]*schema.Event, len(events))
+	for i, e := range events {
+		se := *e
+		se.UnitName = n.strip(se.UnitName)
+		stripped[i] = &se
+	}
+	return stripped, nil
+}
+
+func (n *namespacedAPI) UnitCompletions() ([]*schema.UnitCompletion, error) {
+	completions, err := n.API.UnitCompletions()
+	if err != nil {
+		return nil, err
+	}
+	var stripped []*schema.UnitCompletion
+	for _, c := range completions {
+		if !job.InNamespace(c.Name, []string{n.ns}) {
+			continue
+		}
+		sc := *c
+		sc.Name = n.strip(sc.Name)
+		stripped = append(stripped, &sc)
+	}
+	return stripped, nil
+}
+
+func (n *namespacedAPI) UnitJournal(name string, lines int, follow bool) (io.ReadCloser, error) {
+	return n.API.UnitJournal(n.qualify(name), lines, follow)
+}
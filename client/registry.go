@@ -1,15 +1,86 @@
 package client
 
 import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/engine"
 	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/registry"
 	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
 )
 
+// errNeedsAPI is returned by the methods of RegistryClient acting on the
+// machines themselves, which only their API can do
+var errNeedsAPI = errors.New("only supported through the fleet API")
+
 type RegistryClient struct {
 	registry.Registry
 }
 
+// Machines returns the state of all machines in the cluster. The
+// AllocatedResources of each machine are derived from the reservations of
+// the units currently scheduled to it, counted the same way the engine does.
+func (rc *RegistryClient) Machines() ([]machine.MachineState, error) {
+	machines, err := rc.Registry.Machines()
+	if err != nil {
+		return nil, err
+	}
+
+	rUnits, err := rc.Registry.Units()
+	if err != nil {
+		return nil, err
+	}
+
+	sUnits, err := rc.Registry.Schedule()
+	if err != nil {
+		return nil, err
+	}
+
+	sUnitMap := make(map[string]string, len(sUnits))
+	for _, sUnit := range sUnits {
+		sUnitMap[sUnit.Name] = sUnit.TargetMachineID
+	}
+
+	// Global Units only count against the machines able to run them
+	var globals []*job.Unit
+	for i := range rUnits {
+		if rUnits[i].IsGlobal() {
+			globals = append(globals, &rUnits[i])
+		}
+	}
+
+	agents := make(map[string]*agent.AgentState, len(machines))
+	for i := range machines {
+		as := agent.NewAgentState(&machines[i])
+		as.ScheduleGlobalUnits(globals)
+		agents[machines[i].ID] = as
+	}
+
+	for i := range rUnits {
+		ru := &rUnits[i]
+		if ru.IsGlobal() || ru.TargetState == job.JobStateInactive {
+			continue
+		}
+
+		if as, ok := agents[sUnitMap[ru.Name]]; ok {
+			as.Units[ru.Name] = ru
+		}
+	}
+
+	for i := range machines {
+		machines[i].AllocatedResources = agents[machines[i].ID].AllocatedResources()
+		machines[i].AllocatedExtendedResources = agents[machines[i].ID].AllocatedExtendedResources()
+	}
+
+	return machines, nil
+}
+
 func (rc *RegistryClient) Units() ([]*schema.Unit, error) {
 	rUnits, err := rc.Registry.Units()
 	if err != nil {
@@ -58,6 +129,7 @@ func (rc *RegistryClient) CreateUnit(u *schema.Unit) error {
 		Name:        u.Name,
 		Unit:        *schema.MapSchemaUnitOptionsToUnitFile(u.Options),
 		TargetState: job.JobStateInactive,
+		Labels:      u.Labels,
 	}
 
 	if len(u.DesiredState) > 0 {
@@ -69,7 +141,63 @@ func (rc *RegistryClient) CreateUnit(u *schema.Unit) error {
 		rUnit.TargetState = ts
 	}
 
-	return rc.Registry.CreateUnit(&rUnit)
+	// The signature is stored first so that agents verifying signatures
+	// never see the Unit without it
+	if len(u.Signature) > 0 {
+		if err := rc.Registry.SetUnitSignature(rUnit.Unit.Hash(), u.Signature); err != nil {
+			return err
+		}
+	}
+
+	if err := rc.Registry.CreateUnit(&rUnit); err != nil {
+		return err
+	}
+
+	if len(u.MachineID) > 0 && !rUnit.IsGlobal() {
+		if err := rc.Registry.ScheduleUnit(rUnit.Name, u.MachineID); err != nil {
+			return err
+		}
+	}
+
+	// The Unit is created regardless, so failing to keep its history
+	// is not worth failing the request over
+	if err := rc.Registry.RecordUnitVersion(rUnit.Name, rUnit.Unit.Hash()); err != nil {
+		log.Errorf("Failed recording version of Unit(%s): %v", rUnit.Name, err)
+	}
+	return nil
+}
+
+func (rc *RegistryClient) ReplaceUnit(u *schema.Unit) error {
+	rUnit := job.Unit{
+		Name: u.Name,
+		Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options),
+	}
+
+	if len(u.Signature) > 0 {
+		if err := rc.Registry.SetUnitSignature(rUnit.Unit.Hash(), u.Signature); err != nil {
+			return err
+		}
+	}
+
+	if err := rc.Registry.ReplaceUnit(&rUnit); err != nil {
+		return err
+	}
+
+	if err := rc.Registry.RecordUnitVersion(rUnit.Name, rUnit.Unit.Hash()); err != nil {
+		log.Errorf("Failed recording version of Unit(%s): %v", rUnit.Name, err)
+	}
+
+	// Units updated by rescheduling are unscheduled right away, so that
+	// the engine schedules them again with their new unit file. Agents
+	// restart global Units in place instead.
+	if rUnit.IsGlobal() || rUnit.UpdatePolicy() != job.UpdateReschedule {
+		return nil
+	}
+	sUnit, err := rc.Registry.ScheduledUnit(rUnit.Name)
+	if err != nil || sUnit == nil || sUnit.TargetMachineID == "" {
+		return err
+	}
+	return rc.Registry.UnscheduleUnit(rUnit.Name, sUnit.TargetMachineID)
 }
 
 func (rc *RegistryClient) UnitStates() ([]*schema.UnitState, error) {
@@ -86,6 +214,271 @@ func (rc *RegistryClient) UnitStates() ([]*schema.UnitState, error) {
 	return states, nil
 }
 
+func (rc *RegistryClient) UnitsMatching(f Filter) ([]*schema.Unit, error) {
+	units, err := rc.Units()
+	if err != nil {
+		return nil, err
+	}
+	return FilterUnits(units, f), nil
+}
+
+func (rc *RegistryClient) UnitStatesMatching(f Filter) ([]*schema.UnitState, error) {
+	states, err := rc.UnitStates()
+	if err != nil {
+		return nil, err
+	}
+	return FilterUnitStates(states, f), nil
+}
+
+func (rc *RegistryClient) Events(since uint64, wait time.Duration) ([]*schema.Event, error) {
+	var rEvents []registry.ClusterEvent
+	var err error
+	if wait > 0 {
+		stop := make(chan struct{})
+		timer := time.AfterFunc(wait, func() { close(stop) })
+		rEvents, err = rc.Registry.WaitForEvents(since, stop)
+		timer.Stop()
+	} else {
+		rEvents, err = rc.Registry.Events(since)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*schema.Event, len(rEvents))
+	for i := range rEvents {
+		events[i] = schema.MapClusterEventToSchemaEvent(&rEvents[i])
+	}
+
+	return events, nil
+}
+
+func (rc *RegistryClient) AuditLog() ([]*schema.AuditEntry, error) {
+	rEntries, err := rc.Registry.AuditLog()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*schema.AuditEntry, len(rEntries))
+	for i := range rEntries {
+		entries[i] = schema.MapAuditEntryToSchemaAuditEntry(&rEntries[i])
+	}
+
+	return entries, nil
+}
+
+func (rc *RegistryClient) PlanUnit(u *schema.Unit, strategy string) (*schema.UnitPlacement, error) {
+	rUnit := job.Unit{
+		Name: u.Name,
+		Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options),
+	}
+
+	p, err := engine.Plan(rc.Registry, strategy, &rUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.MapPlacementToSchemaUnitPlacement(p), nil
+}
+
+func (rc *RegistryClient) UnitRejections(name string) (*schema.UnitRejections, error) {
+	ur, err := rc.Registry.UnitRejections(name)
+	if err != nil {
+		return nil, err
+	}
+	if ur == nil {
+		return &schema.UnitRejections{}, nil
+	}
+	return schema.MapUnitRejectionsToSchemaUnitRejections(ur), nil
+}
+
+func (rc *RegistryClient) UnitRuns(tmpl string) ([]*schema.CronRun, error) {
+	runs, err := rc.Registry.CronRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []registry.CronRun
+	for _, run := range runs {
+		if run.Template == tmpl {
+			history = append(history, run)
+		}
+	}
+	return schema.MapCronRunsToSchemaCronRuns(history), nil
+}
+
+func (rc *RegistryClient) UnitCompletions() ([]*schema.UnitCompletion, error) {
+	completions, err := rc.Registry.UnitCompletions()
+	if err != nil {
+		return nil, err
+	}
+	return schema.MapUnitCompletionsToSchemaUnitCompletions(completions), nil
+}
+
+func (rc *RegistryClient) CreateStack(s *schema.Stack) error {
+	return rc.Registry.CreateStack(schema.MapSchemaStackToStack(s))
+}
+
+func (rc *RegistryClient) Stack(name string) (*schema.Stack, error) {
+	s, err := rc.Registry.Stack(name)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	return schema.MapStackToSchemaStack(s), nil
+}
+
+func (rc *RegistryClient) Stacks() ([]*schema.Stack, error) {
+	rStacks, err := rc.Registry.Stacks()
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := make([]*schema.Stack, len(rStacks))
+	for i := range rStacks {
+		stacks[i] = schema.MapStackToSchemaStack(&rStacks[i])
+	}
+	return stacks, nil
+}
+
+func (rc *RegistryClient) ConfigValues(namespace string) ([]*schema.ConfigValue, error) {
+	values, err := rc.Registry.ConfigValues(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return schema.MapConfigValuesToSchemaConfigValues(values), nil
+}
+
+func (rc *RegistryClient) SetConfigValue(namespace string, cv *schema.ConfigValue) error {
+	return rc.Registry.SetConfigValue(namespace, cv.Key, schema.MapSchemaConfigValueToConfigValue(cv))
+}
+
+func (rc *RegistryClient) Quotas() ([]*schema.Quota, error) {
+	rQuotas, err := rc.Registry.Quotas()
+	if err != nil {
+		return nil, err
+	}
+	units, err := rc.Registry.Units()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := registry.NamespaceUsage(units)
+	quotas := make([]*schema.Quota, len(rQuotas))
+	for i := range rQuotas {
+		quotas[i] = schema.MapQuotaToSchemaQuota(&rQuotas[i], usage[rQuotas[i].Namespace])
+	}
+	return quotas, nil
+}
+
+func (rc *RegistryClient) SetQuota(q *schema.Quota) error {
+	return rc.Registry.SetQuota(schema.MapSchemaQuotaToQuota(q))
+}
+
 func (rc *RegistryClient) SetUnitTargetState(name, target string) error {
 	return rc.Registry.SetUnitTargetState(name, job.JobState(target))
 }
+
+func (rc *RegistryClient) SetUnitLabels(name string, labels map[string]string) error {
+	return rc.Registry.SetUnitLabels(name, labels)
+}
+
+func (rc *RegistryClient) UnitScale(tmpl string) (*schema.Scale, error) {
+	scales, err := rc.Registry.UnitScales()
+	if err != nil {
+		return nil, err
+	}
+	count, ok := scales[tmpl]
+	if !ok {
+		return nil, nil
+	}
+	return &schema.Scale{Count: int64(count)}, nil
+}
+
+func (rc *RegistryClient) UnitVersions(name string) ([]*schema.UnitVersion, error) {
+	versions, err := rc.Registry.UnitVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	return schema.MapUnitVersionsToSchemaUnitVersions(versions), nil
+}
+
+func (rc *RegistryClient) UnitFile(hash string) (*schema.UnitFile, error) {
+	h, err := unit.ParseHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	uf, err := rc.Registry.UnitFile(h)
+	if err != nil || uf == nil {
+		return nil, err
+	}
+	return schema.MapUnitFileToSchemaUnitFile(uf), nil
+}
+
+func (rc *RegistryClient) UnitHistory(name string) ([]*schema.Event, error) {
+	rEvents, err := rc.Registry.UnitHistory(name)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*schema.Event, len(rEvents))
+	for i := range rEvents {
+		events[i] = schema.MapClusterEventToSchemaEvent(&rEvents[i])
+	}
+	return events, nil
+}
+
+func (rc *RegistryClient) EngineStatuses() ([]*schema.EngineStatus, error) {
+	statuses, err := rc.Registry.EngineStatuses()
+	if err != nil {
+		return nil, err
+	}
+	return schema.MapEngineStatusesToSchemaEngineStatuses(statuses), nil
+}
+
+func (rc *RegistryClient) Upgrade() (*schema.Upgrade, error) {
+	p, err := rc.Registry.UpgradePlan()
+	if err != nil || p == nil {
+		return nil, err
+	}
+	statuses, err := rc.Registry.UpgradeStatuses()
+	if err != nil {
+		return nil, err
+	}
+	return schema.MapUpgradeToSchemaUpgrade(p, statuses), nil
+}
+
+func (rc *RegistryClient) StartUpgrade(version, domainKey string) error {
+	return rc.Registry.SetUpgradePlan(registry.UpgradePlan{Version: version, DomainKey: domainKey, Created: time.Now()})
+}
+
+func (rc *RegistryClient) CancelUpgrade() error {
+	return rc.Registry.ClearUpgradePlan()
+}
+
+func (rc *RegistryClient) ClusterConfig() (*schema.ClusterConfig, error) {
+	cc, err := rc.Registry.ClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	return schema.MapClusterConfigToSchemaClusterConfig(cc), nil
+}
+
+func (rc *RegistryClient) SetClusterConfig(sc *schema.ClusterConfig) (*schema.ClusterConfig, error) {
+	values := sc.Values
+	if values == nil {
+		values = map[string]string{}
+	}
+	cc, err := rc.Registry.UpdateClusterConfig(int(sc.Version), values)
+	if err != nil {
+		return nil, err
+	}
+	return schema.MapClusterConfigToSchemaClusterConfig(cc), nil
+}
+
+func (rc *RegistryClient) UnitJournal(name string, lines int, follow bool) (io.ReadCloser, error) {
+	return nil, errNeedsAPI
+}
+
+func (rc *RegistryClient) RunCommand(machID string, command []string, out io.Writer) (int, error) {
+	return 0, errNeedsAPI
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Options of fleet.conf which may also be set for the whole cluster in the
+// registry, taking precedence over the values configured on each machine
+const (
+	ClusterEngineReconcileInterval = "engine_reconcile_interval"
+	ClusterEngineRescheduleDelay   = "engine_reschedule_delay"
+	ClusterSchedulingStrategy      = "scheduling_strategy"
+	ClusterCPUOvercommit           = "cpu_overcommit"
+	ClusterMemoryOvercommit        = "memory_overcommit"
+)
+
+var clusterOptions = map[string]func(string) error{
+	ClusterEngineReconcileInterval: validatePositive,
+	ClusterEngineRescheduleDelay:   validateNonNegative,
+	ClusterSchedulingStrategy:      validateNonEmpty,
+	ClusterCPUOvercommit:           validatePositive,
+	ClusterMemoryOvercommit:        validatePositive,
+}
+
+// ClusterOptions returns the names of the options which may be set for the
+// whole cluster, sorted
+func ClusterOptions() []string {
+	names := make([]string, 0, len(clusterOptions))
+	for name := range clusterOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateClusterOption returns an error if the named option cannot be set
+// for the whole cluster, or not to the given value. Scheduling strategies
+// are only known to the engine, which validates them itself.
+func ValidateClusterOption(name, value string) error {
+	validate, ok := clusterOptions[name]
+	if !ok {
+		return fmt.Errorf("unknown cluster option %q", name)
+	}
+	if err := validate(value); err != nil {
+		return fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return nil
+}
+
+// ClusterFloat returns the value of the named option in the given cluster
+// options, or def if it is not set or invalid
+func ClusterFloat(options map[string]string, name string, def float64) float64 {
+	val, ok := options[name]
+	if !ok || ValidateClusterOption(name, val) != nil {
+		return def
+	}
+	f, _ := strconv.ParseFloat(val, 64)
+	return f
+}
+
+func validatePositive(value string) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 {
+		return fmt.Errorf("%q is not a positive number", value)
+	}
+	return nil
+}
+
+func validateNonNegative(value string) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return fmt.Errorf("%q is not a non-negative number", value)
+	}
+	return nil
+}
+
+func validateNonEmpty(value string) error {
+	if value == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
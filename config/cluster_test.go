@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidateClusterOption(t *testing.T) {
+	for i, tt := range []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"engine_reconcile_interval", "5", true},
+		{"engine_reconcile_interval", "0", false},
+		{"engine_reschedule_delay", "0", true},
+		{"engine_reschedule_delay", "-1", false},
+		{"scheduling_strategy", "binpack", true},
+		{"scheduling_strategy", "", false},
+		{"cpu_overcommit", "1.5", true},
+		{"memory_overcommit", "lots", false},
+		{"etcd_servers", "http://127.0.0.1:2379", false},
+	} {
+		if err := ValidateClusterOption(tt.name, tt.value); (err == nil) != tt.valid {
+			t.Errorf("case %d: unexpected result for %s=%q: %v", i, tt.name, tt.value, err)
+		}
+	}
+}
+
+func TestClusterFloat(t *testing.T) {
+	options := map[string]string{"cpu_overcommit": "2", "memory_overcommit": "-1"}
+	if f := ClusterFloat(options, "cpu_overcommit", 1); f != 2 {
+		t.Errorf("Got cpu_overcommit %v, want 2", f)
+	}
+	// invalid and unset options fall back to the default
+	if f := ClusterFloat(options, "memory_overcommit", 1); f != 1 {
+		t.Errorf("Got memory_overcommit %v, want 1", f)
+	}
+	if f := ClusterFloat(options, "engine_reconcile_interval", 2); f != 2 {
+		t.Errorf("Got engine_reconcile_interval %v, want 2", f)
+	}
+}
@@ -5,19 +5,80 @@ import (
 )
 
 type Config struct {
-	EtcdServers             []string
-	EtcdKeyPrefix           string
-	EtcdKeyFile             string
-	EtcdCertFile            string
-	EtcdCAFile              string
-	EtcdRequestTimeout      float64
-	EngineReconcileInterval float64
-	PublicIP                string
-	Verbosity               int
-	RawMetadata             string
-	AgentTTL                string
-	VerifyUnits             bool
-	AuthorizedKeysFile      string
+	EtcdServers                 []string
+	EtcdKeyPrefix               string
+	EtcdKeyFile                 string
+	EtcdCertFile                string
+	EtcdCAFile                  string
+	EtcdRequestTimeout          float64
+	EtcdRequestTimeouts         string
+	EtcdRetryBackoff            string
+	EtcdRetryMaxBackoff         string
+	EtcdRetryJitter             float64
+	EtcdBreakerThreshold        int
+	EtcdBreakerCooldown         string
+	APICertFile                 string
+	APIKeyFile                  string
+	APICAFile                   string
+	APITokensFile               string
+	APIAdvertiseURL             string
+	APIAllowExec                bool
+	APIStandby                  string
+	ClusterKeyFile              string
+	RegistryKeyFiles            []string
+	TrustedKeysFile             string
+	AuditLogFile                string
+	AuditRegistry               bool
+	EngineReconcileInterval     float64
+	EngineFullReconcileInterval float64
+	EngineReconcileConcurrency  int
+	EngineShards                int
+	SchedulingStrategy          string
+	EvictOnMetadataChange       bool
+	EnginePlacementWebhook      string
+	EngineRescheduleDelay       float64
+	EngineRescheduleLimit       int
+	EngineRescheduleWindow      float64
+	EngineWebhookURLs           []string
+	EngineWebhookEvents         []string
+	EngineWebhookSecretFile     string
+	EngineGCInterval            float64
+	EngineGCGrace               float64
+	EngineGCDryRun              bool
+	FastFailureDetection        bool
+	RegistryCache               bool
+	UnitCacheDir                string
+	PublicIP                    string
+	PrivateIP                   string
+	IPPreference                string
+	Verbosity                   int
+	RawMetadata                 string
+	RawMetadataSources          string
+	SecurityMetadata            bool
+	Pool                        string
+	AgentTTL                    string
+	AgentHeartbeatInterval      string
+	ZombieCleanup               string
+	HandoffTimeout              float64
+	DiskPath                    string
+	CPUCapacity                 int
+	CPUReservableFraction       float64
+	MemoryPolicy                string
+	MemoryRefreshInterval       string
+	RawResources                string
+	ReservedMemory              int
+	ReservedCPUUnits            int
+	CPUOvercommit               float64
+	MemoryOvercommit            float64
+	MaxUnitsPerMachine          int
+	UsageAlertFactor            float64
+	UsageAlertPeriod            float64
+	UsageAlertFail              bool
+	MaxConcurrentStarts         int
+	MetricsListen               string
+	UpgradeBinaryPath           string
+	VerifyUnits                 bool
+	AuthorizedKeysFile          string
 }
 
 func (c *Config) Metadata() map[string]string {
@@ -37,3 +98,16 @@ func (c *Config) Metadata() map[string]string {
 
 	return meta
 }
+
+// MetadataSources returns the names of the cloud providers from which the
+// machine's metadata should be discovered.
+func (c *Config) MetadataSources() []string {
+	var sources []string
+	for _, name := range strings.Split(c.RawMetadataSources, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			sources = append(sources, name)
+		}
+	}
+	return sources
+}
@@ -30,3 +30,17 @@ func TestConfigMetadataNotSet(t *testing.T) {
 		t.Errorf("Parsed %d keys, expected 0", len(metadata))
 	}
 }
+
+func TestConfigMetadataSources(t *testing.T) {
+	cfg := Config{RawMetadataSources: "ec2, gce,,"}
+	sources := cfg.MetadataSources()
+
+	if len(sources) != 2 || sources[0] != "ec2" || sources[1] != "gce" {
+		t.Errorf("Unexpected metadata sources %v, expected [ec2 gce]", sources)
+	}
+
+	cfg = Config{}
+	if sources := cfg.MetadataSources(); len(sources) != 0 {
+		t.Errorf("Parsed %d metadata sources, expected 0", len(sources))
+	}
+}
@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	// number of finished runs kept in the run history of each template
+	cronHistoryLimit = 10
+
+	// how long ago the tick of a template without any run history may
+	// have been to still be run
+	cronCatchUp = time.Minute
+)
+
+// cronPlan holds the changes to the Registry running the scheduled template
+// Units, applied in the order of its fields
+type cronPlan struct {
+	save    []registry.CronRun
+	destroy []string
+	remove  []registry.CronRun
+	create  []job.Unit
+	clear   []string
+}
+
+// runCronJobs starts the runs of the template Units with a Schedule that
+// are due and keeps their run history. Runs are instances of their
+// template, scheduled like any other Unit by the remainder of the
+// reconciliation.
+func (e *Engine) runCronJobs() {
+	units, err := e.registry.Units()
+	if err != nil {
+		log.Errorf("Failed fetching Units from Registry: %v", err)
+		return
+	}
+	runs, err := e.registry.CronRuns()
+	if err != nil {
+		log.Errorf("Failed fetching run history from Registry: %v", err)
+		return
+	}
+	results, err := e.registry.CronRunResults()
+	if err != nil {
+		log.Errorf("Failed fetching results of runs from Registry: %v", err)
+		return
+	}
+
+	plan := planCronRuns(time.Now(), units, runs, results)
+
+	for _, run := range plan.save {
+		if err := e.registry.SaveCronRun(run); err != nil {
+			log.Errorf("Failed saving run %s of template Unit(%s): %v", run.Name, run.Template, err)
+		}
+	}
+	for _, name := range plan.destroy {
+		if err := e.registry.DestroyUnit(name); err != nil {
+			log.Errorf("Failed destroying Unit(%s): %v", name, err)
+			continue
+		}
+		log.Infof("Destroyed Unit(%s) as its run is over", name)
+	}
+	for _, run := range plan.remove {
+		if err := e.registry.RemoveCronRun(run.Template, run.Name); err != nil {
+			log.Errorf("Failed removing run %s of template Unit(%s): %v", run.Name, run.Template, err)
+		}
+	}
+	for _, u := range plan.create {
+		u := u
+		if err := e.registry.CreateUnit(&u); err != nil {
+			log.Errorf("Failed creating Unit(%s): %v", u.Name, err)
+			continue
+		}
+		log.Infof("Created Unit(%s) to run its template on schedule", u.Name)
+	}
+	for _, name := range plan.clear {
+		if err := e.registry.ClearCronRunResult(name); err != nil {
+			log.Errorf("Failed clearing result of run %s: %v", name, err)
+		}
+	}
+}
+
+// planCronRuns determines the changes running the scheduled template Units
+// at the given time. Runs are named after the Unix time of their tick and
+// created launched, with the unit file of their template. Reported results
+// finish runs, whose instances are then destroyed. Only the latest of any
+// ticks missed since the last run is run. The run history of templates
+// that no longer exist is dropped, leaving any of their runs going alone.
+func planCronRuns(now time.Time, units []job.Unit, runs []registry.CronRun, results []registry.CronRunResult) *cronPlan {
+	var plan cronPlan
+
+	exists := make(map[string]bool, len(units))
+	templates := make(map[string]*job.Unit)
+	for i := range units {
+		u := &units[i]
+		exists[u.Name] = true
+		if u.IsTemplate() && !u.IsGlobal() && u.CronSchedule() != nil {
+			templates[u.Name] = u
+		}
+	}
+
+	history := make(map[string][]registry.CronRun)
+	for _, run := range runs {
+		if _, ok := templates[run.Template]; !ok {
+			plan.remove = append(plan.remove, run)
+			continue
+		}
+		history[run.Template] = append(history[run.Template], run)
+	}
+
+	reported := make(map[string]registry.CronRunResult, len(results))
+	for _, res := range results {
+		reported[res.Name] = res
+		plan.clear = append(plan.clear, res.Name)
+	}
+
+	var names []string
+	for tmpl := range templates {
+		names = append(names, tmpl)
+	}
+	sort.Strings(names)
+
+	for _, tmpl := range names {
+		t := templates[tmpl]
+		runs := history[tmpl]
+
+		var live []int
+		for i := range runs {
+			run := &runs[i]
+			if run.Result != "" {
+				continue
+			}
+			if res, ok := reported[run.Name]; ok {
+				run.MachineID, run.Finished, run.Result = res.MachineID, res.Finished, res.Result
+			} else if !exists[run.Name] {
+				run.Finished, run.Result = now, registry.CronRunLost
+			} else {
+				live = append(live, i)
+				continue
+			}
+			plan.save = append(plan.save, *run)
+			if exists[run.Name] {
+				plan.destroy = append(plan.destroy, run.Name)
+			}
+		}
+
+		last := now.Add(-cronCatchUp)
+		if len(runs) > 0 {
+			last = runs[len(runs)-1].Tick
+		}
+		tick := dueTick(t.CronSchedule(), last, now)
+		if !tick.IsZero() {
+			uni := unit.NewUnitNameInfo(tmpl)
+			run := registry.CronRun{
+				Name:     instanceName(uni, int(tick.Unix())),
+				Template: tmpl,
+				Tick:     tick,
+			}
+
+			policy := t.ConcurrencyPolicy()
+			if policy == job.ConcurrencyForbid && len(live) > 0 {
+				log.V(1).Infof("Skipping run %s of template Unit(%s), an earlier run is still going", run.Name, tmpl)
+				run.Finished, run.Result = now, registry.CronRunSkipped
+				plan.save = append(plan.save, run)
+			} else {
+				if policy == job.ConcurrencyReplace {
+					for _, i := range live {
+						runs[i].Finished, runs[i].Result = now, registry.CronRunReplaced
+						plan.save = append(plan.save, runs[i])
+						plan.destroy = append(plan.destroy, runs[i].Name)
+					}
+				}
+				plan.save = append(plan.save, run)
+				if !exists[run.Name] {
+					plan.create = append(plan.create, job.Unit{
+						Name:        run.Name,
+						Unit:        t.Unit,
+						TargetState: job.JobStateLaunched,
+						Labels:      t.Labels,
+					})
+				}
+			}
+			runs = append(runs, run)
+		}
+
+		var finished []registry.CronRun
+		for _, run := range runs {
+			if run.Result != "" {
+				finished = append(finished, run)
+			}
+		}
+		if len(finished) > cronHistoryLimit {
+			plan.remove = append(plan.remove, finished[:len(finished)-cronHistoryLimit]...)
+		}
+	}
+
+	return &plan
+}
+
+// dueTick returns the latest tick of the given schedule after last and no
+// later than now, or the zero time if there is none
+func dueTick(cs *job.CronSchedule, last, now time.Time) time.Time {
+	var due time.Time
+	for next := cs.Next(last); !next.IsZero() && !next.After(now); next = cs.Next(next) {
+		due = next
+	}
+	return due
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestPlanCronRuns(t *testing.T) {
+	at := func(min int) time.Time {
+		return time.Date(2015, time.March, 2, 10, min, 0, 0, time.UTC)
+	}
+	name := func(min int) string {
+		return fmt.Sprintf("backup@%d.service", at(min).Unix())
+	}
+	tmpl := func(policy string) job.Unit {
+		return job.Unit{
+			Name: "backup@.service",
+			Unit: newTestUnit(t, fmt.Sprintf("[X-Fleet]\nSchedule=*/15 * * * *\nConcurrencyPolicy=%s", policy)),
+		}
+	}
+	live := func(min int) registry.CronRun {
+		return registry.CronRun{Name: name(min), Template: "backup@.service", Tick: at(min)}
+	}
+	instance := func(min int) job.Unit {
+		return job.Unit{Name: name(min), TargetState: job.JobStateLaunched}
+	}
+
+	for i, tt := range []struct {
+		now     time.Time
+		units   []job.Unit
+		runs    []registry.CronRun
+		results []registry.CronRunResult
+		save    map[string]string
+		destroy []string
+		create  []string
+	}{
+		// nothing is due between ticks
+		{
+			now:   at(20),
+			units: []job.Unit{tmpl("forbid")},
+		},
+		// without history, a tick within the last minute is run
+		{
+			now:    at(30).Add(30 * time.Second),
+			units:  []job.Unit{tmpl("forbid")},
+			save:   map[string]string{name(30): ""},
+			create: []string{name(30)},
+		},
+		// of ticks missed since the last run, only the latest is run
+		{
+			now:    at(50),
+			units:  []job.Unit{tmpl("forbid")},
+			runs:   []registry.CronRun{registry.CronRun{Name: name(0), Template: "backup@.service", Tick: at(0), Result: registry.CronRunSucceeded}},
+			save:   map[string]string{name(45): ""},
+			create: []string{name(45)},
+		},
+		// reported results finish runs, destroying their instances
+		{
+			now:     at(10),
+			units:   []job.Unit{tmpl("forbid"), instance(0)},
+			runs:    []registry.CronRun{live(0)},
+			results: []registry.CronRunResult{registry.CronRunResult{Name: name(0), MachineID: "XXX", Finished: at(5), Result: registry.CronRunFailed}},
+			save:    map[string]string{name(0): registry.CronRunFailed},
+			destroy: []string{name(0)},
+		},
+		// runs without instances are lost
+		{
+			now:   at(10),
+			units: []job.Unit{tmpl("forbid")},
+			runs:  []registry.CronRun{live(0)},
+			save:  map[string]string{name(0): registry.CronRunLost},
+		},
+		// overlapping runs are skipped if forbidden
+		{
+			now:   at(15),
+			units: []job.Unit{tmpl("forbid"), instance(0)},
+			runs:  []registry.CronRun{live(0)},
+			save:  map[string]string{name(15): registry.CronRunSkipped},
+		},
+		// or run alongside if allowed
+		{
+			now:    at(15),
+			units:  []job.Unit{tmpl("allow"), instance(0)},
+			runs:   []registry.CronRun{live(0)},
+			save:   map[string]string{name(15): ""},
+			create: []string{name(15)},
+		},
+		// or run in place of earlier runs
+		{
+			now:     at(15),
+			units:   []job.Unit{tmpl("replace"), instance(0)},
+			runs:    []registry.CronRun{live(0)},
+			save:    map[string]string{name(0): registry.CronRunReplaced, name(15): ""},
+			destroy: []string{name(0)},
+			create:  []string{name(15)},
+		},
+		// templates without a schedule are left alone
+		{
+			now:   at(15),
+			units: []job.Unit{job.Unit{Name: "backup@.service"}},
+		},
+	} {
+		plan := planCronRuns(tt.now, tt.units, tt.runs, tt.results)
+
+		save := make(map[string]string)
+		for _, run := range plan.save {
+			save[run.Name] = run.Result
+		}
+		if len(save) != 0 || len(tt.save) != 0 {
+			if !reflect.DeepEqual(save, tt.save) {
+				t.Errorf("case %d: saved %v, want %v", i, save, tt.save)
+			}
+		}
+		if !reflect.DeepEqual(plan.destroy, tt.destroy) {
+			t.Errorf("case %d: destroyed %v, want %v", i, plan.destroy, tt.destroy)
+		}
+
+		var create []string
+		for _, u := range plan.create {
+			create = append(create, u.Name)
+			if u.TargetState != job.JobStateLaunched || !reflect.DeepEqual(u.Unit, tt.units[0].Unit) {
+				t.Errorf("case %d: Unit(%s) created incorrectly: %#v", i, u.Name, u)
+			}
+		}
+		if !reflect.DeepEqual(create, tt.create) {
+			t.Errorf("case %d: created %v, want %v", i, create, tt.create)
+		}
+		if len(plan.clear) != len(tt.results) {
+			t.Errorf("case %d: cleared %v, want all results cleared", i, plan.clear)
+		}
+	}
+}
+
+func TestPlanCronRunsHistory(t *testing.T) {
+	units := []job.Unit{
+		job.Unit{Name: "backup@.service", Unit: newTestUnit(t, "[X-Fleet]\nSchedule=* * * * *")},
+	}
+	start := time.Date(2015, time.March, 2, 10, 0, 0, 0, time.UTC)
+
+	var runs []registry.CronRun
+	for n := 0; n < cronHistoryLimit+2; n++ {
+		tick := start.Add(time.Duration(n) * time.Minute)
+		runs = append(runs, registry.CronRun{Name: fmt.Sprintf("backup@%d.service", tick.Unix()), Template: "backup@.service", Tick: tick, Result: registry.CronRunSucceeded})
+	}
+	runs = append(runs, registry.CronRun{Name: "gone@1.service", Template: "gone@.service", Tick: start})
+
+	plan := planCronRuns(start.Add(30*time.Second), units, runs, nil)
+
+	want := []string{"gone@1.service", runs[0].Name, runs[1].Name}
+	var got []string
+	for _, run := range plan.remove {
+		got = append(got, run.Name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("removed %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,208 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+)
+
+// clusterSnapshot condenses the parts of the cluster state that scheduling
+// decisions depend on, so that consecutive states can be compared cheaply
+type clusterSnapshot struct {
+	// jobs holds a fingerprint of each Job, including until when its
+	// rescheduling is held back, and machines one of each machine,
+	// indexed by name and ID respectively
+	jobs     map[string]string
+	machines map[string]string
+
+	// scheduled holds the names of the Jobs holding resources on a
+	// machine
+	scheduled pkg.Set
+
+	// globals fingerprints the global Units, which hold resources on
+	// every machine, deps the Units against which dependencies are
+	// checked, and quotas the Quotas of namespaces
+	globals string
+	deps    string
+	quotas  string
+}
+
+func newClusterSnapshot(clust *clusterState) *clusterSnapshot {
+	snap := clusterSnapshot{
+		jobs:      make(map[string]string, len(clust.jobs)),
+		machines:  make(map[string]string, len(clust.machines)),
+		scheduled: pkg.NewUnsafeSet(),
+	}
+
+	for name, j := range clust.jobs {
+		var stack string
+		if s, ok := clust.stacks[name]; ok {
+			stack = fmt.Sprintf("%s%v", s.Name, s.Units)
+		}
+		var backoff int64
+		if until, ok := clust.backoff[name]; ok {
+			backoff = until.UnixNano()
+		}
+		snap.jobs[name] = fmt.Sprintf("%s|%s|%s|%v|%t|%s|%d", j.Unit.Hash(), j.TargetState, j.TargetMachineID, clust.failures[name], clust.completed.Contains(name), stack, backoff)
+		if j.Scheduled() && j.TargetState != job.JobStateInactive {
+			snap.scheduled.Add(name)
+		}
+	}
+
+	for id, ms := range clust.machines {
+		// unlike its fmt representation, the JSON encoding of a
+		// MachineState holds no pointers and orders its metadata.
+		// Heartbeats alone change nothing about scheduling.
+		m := *ms
+		m.LastHeartbeat = nil
+		b, _ := json.Marshal(m)
+		snap.machines[id] = fmt.Sprintf("%s|%t|%t|%v", b, m.Cordoned, m.Draining, m.Taints)
+	}
+
+	var globals []string
+	for name, u := range clust.gUnits {
+		globals = append(globals, fmt.Sprintf("%s|%s|%s", name, u.Unit.Hash(), u.TargetState))
+	}
+	sort.Strings(globals)
+	snap.globals = strings.Join(globals, ",")
+
+	launched, active := clust.launched.Values(), clust.active.Values()
+	sort.Strings(launched)
+	sort.Strings(active)
+	snap.deps = strings.Join(launched, ",") + "|" + strings.Join(active, ",")
+
+	var quotas []string
+	for ns, q := range clust.quotas {
+		quotas = append(quotas, fmt.Sprintf("%s|%d|%d|%d", ns, q.Cores, q.Memory, q.Units))
+	}
+	sort.Strings(quotas)
+	snap.quotas = strings.Join(quotas, ",")
+
+	return &snap
+}
+
+// dirtySet holds what changed in the cluster since the previous
+// reconciliation, limiting the placements the engine re-evaluates
+type dirtySet struct {
+	// jobs holds the names of the Jobs that changed, appeared or went
+	// away, and machines the IDs of the machines that did
+	jobs     pkg.Set
+	machines pkg.Set
+
+	// pending is set if room may have been freed or taken anywhere in
+	// the cluster, or dependencies or Quotas changed, so that all Jobs
+	// waiting to be scheduled are reconsidered
+	pending bool
+
+	// rejected holds why the Jobs that are not reconsidered could not be
+	// scheduled before, indexed by Job name
+	rejected map[string]registry.UnitRejections
+}
+
+// diff determines what changed between the snapshot and a later one
+func (snap *clusterSnapshot) diff(later *clusterSnapshot) *dirtySet {
+	d := dirtySet{
+		jobs:     pkg.NewUnsafeSet(),
+		machines: pkg.NewUnsafeSet(),
+	}
+
+	for _, name := range changedKeys(snap.jobs, later.jobs) {
+		d.jobs.Add(name)
+		if snap.scheduled.Contains(name) || later.scheduled.Contains(name) {
+			d.pending = true
+		}
+	}
+
+	if snap.globals != later.globals {
+		for id := range later.machines {
+			d.machines.Add(id)
+		}
+	}
+	for _, id := range changedKeys(snap.machines, later.machines) {
+		d.machines.Add(id)
+	}
+	if d.machines.Length() != 0 || snap.deps != later.deps || snap.quotas != later.quotas {
+		d.pending = true
+	}
+
+	return &d
+}
+
+// changedKeys returns the keys whose values differ between the given maps,
+// including those present in only one of them
+func changedKeys(prev, cur map[string]string) []string {
+	var keys []string
+	for k, v := range cur {
+		if pv, ok := prev[k]; !ok || pv != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range prev {
+		if _, ok := cur[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// affects determines whether the placement of the given scheduled Job must
+// be re-evaluated. Jobs are moved off draining machines once room frees up
+// elsewhere, and workload windows open and close on their own.
+func (d *dirtySet) affects(j *job.Job, draining bool) bool {
+	return d.jobs.Contains(j.Name) || d.machines.Contains(j.TargetMachineID) ||
+		(draining && d.pending) || j.WorkloadWindow() != nil
+}
+
+// reconsiders determines whether the engine attempts to schedule the given
+// Job waiting to be scheduled again
+func (d *dirtySet) reconsiders(j *job.Job) bool {
+	return d.pending || d.jobs.Contains(j.Name) || j.WorkloadWindow() != nil
+}
+
+// unscheduled records that the named Job was unscheduled during the current
+// reconciliation, freeing room for any Job waiting to be scheduled
+func (d *dirtySet) unscheduled(name string) {
+	d.jobs.Add(name)
+	d.pending = true
+}
+
+// keepRejection carries over why the named Job could not be scheduled
+// before, as it is not reconsidered
+func (d *dirtySet) keepRejection(clust *clusterState, name string) {
+	rej, ok := d.rejected[name]
+	if !ok {
+		return
+	}
+	if rej.Shortfalls != nil {
+		clust.rejectShortfalls(name, rej.Reason, rej.Shortfalls)
+	} else {
+		clust.reject(name, rej.Reason, rej.Machines)
+	}
+}
+
+// reconsidersStack determines whether the engine attempts to schedule the
+// given Stack again, which it does if it reconsiders any of its Jobs
+func (d *dirtySet) reconsidersStack(clust *clusterState, s *registry.Stack) bool {
+	for _, name := range s.Units {
+		if j, ok := clust.jobs[name]; ok && d.reconsiders(j) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconsidersGroup determines whether the engine attempts to schedule the
+// named peer group again, which it does if it reconsiders any of its Jobs
+func (d *dirtySet) reconsidersGroup(clust *clusterState, names []string) bool {
+	for _, name := range names {
+		if j, ok := clust.jobs[name]; ok && d.reconsiders(j) {
+			return true
+		}
+	}
+	return false
+}
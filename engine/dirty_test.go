@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+)
+
+func newTestDirtyClusterState(t *testing.T) *clusterState {
+	jsLaunched := job.JobStateLaunched
+	return newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "running.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "pending.service", TargetState: job.JobStateLaunched, Unit: newTestUnit(t, "[X-Fleet]\nMachineMetadata=gpu=true")},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "running.service", State: &jsLaunched, TargetMachineID: "XXX"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX"},
+			machine.MachineState{ID: "YYY"},
+		},
+	)
+}
+
+func TestClusterSnapshotDiff(t *testing.T) {
+	prev := newClusterSnapshot(newTestDirtyClusterState(t))
+
+	tests := []struct {
+		change   func(*clusterState)
+		jobs     []string
+		machines []string
+		pending  bool
+	}{
+		// nothing changed
+		{
+			change: func(*clusterState) {},
+		},
+		// a new Job waiting to be scheduled takes no room
+		{
+			change: func(clust *clusterState) {
+				clust.jobs["new.service"] = &job.Job{Name: "new.service", TargetState: job.JobStateLaunched}
+			},
+			jobs: []string{"new.service"},
+		},
+		// a scheduled Job stopping frees room
+		{
+			change: func(clust *clusterState) {
+				clust.jobs["running.service"].TargetState = job.JobStateInactive
+			},
+			jobs:    []string{"running.service"},
+			pending: true,
+		},
+		// failures only concern their Job
+		{
+			change: func(clust *clusterState) {
+				clust.failures["pending.service"] = map[string]string{"XXX": "exit-code"}
+			},
+			jobs: []string{"pending.service"},
+		},
+		{
+			change: func(clust *clusterState) {
+				clust.machines["YYY"].Metadata = map[string]string{"gpu": "true"}
+			},
+			machines: []string{"YYY"},
+			pending:  true,
+		},
+		{
+			change: func(clust *clusterState) {
+				delete(clust.machines, "XXX")
+			},
+			machines: []string{"XXX"},
+			pending:  true,
+		},
+		// heartbeats alone change nothing about scheduling
+		{
+			change: func(clust *clusterState) {
+				now := time.Now()
+				clust.machines["XXX"].LastHeartbeat = &now
+			},
+		},
+		// global Units take room on all machines
+		{
+			change: func(clust *clusterState) {
+				clust.gUnits["global.service"] = &job.Unit{Name: "global.service", TargetState: job.JobStateLaunched}
+			},
+			machines: []string{"XXX", "YYY"},
+			pending:  true,
+		},
+		// dependencies may now be met
+		{
+			change: func(clust *clusterState) {
+				clust.active.Add("running.service")
+			},
+			pending: true,
+		},
+		// and so may Quotas
+		{
+			change: func(clust *clusterState) {
+				clust.setQuotas([]registry.Quota{{Namespace: "team-a", Units: 2}})
+			},
+			pending: true,
+		},
+	}
+
+	for i, tt := range tests {
+		clust := newTestDirtyClusterState(t)
+		tt.change(clust)
+		d := prev.diff(newClusterSnapshot(clust))
+
+		if got := sortedValues(d.jobs); !reflect.DeepEqual(got, tt.jobs) {
+			t.Errorf("case %d: unexpected dirty Jobs: got %v, want %v", i, got, tt.jobs)
+		}
+		if got := sortedValues(d.machines); !reflect.DeepEqual(got, tt.machines) {
+			t.Errorf("case %d: unexpected dirty machines: got %v, want %v", i, got, tt.machines)
+		}
+		if d.pending != tt.pending {
+			t.Errorf("case %d: unexpected pending: got %t, want %t", i, d.pending, tt.pending)
+		}
+	}
+}
+
+func sortedValues(s pkg.Set) []string {
+	vals := s.Values()
+	if len(vals) == 0 {
+		return nil
+	}
+	sort.Strings(vals)
+	return vals
+}
+
+func TestCalculateClusterTasksIncremental(t *testing.T) {
+	clust := newTestDirtyClusterState(t)
+	clust.jobs["new.service"] = &job.Job{Name: "new.service", TargetState: job.JobStateLaunched}
+	// the machine running.service is scheduled to went away unnoticed
+	clust.jobs["running.service"].TargetMachineID = "ZZZ"
+
+	rej := registry.UnitRejections{Reason: "no machine with metadata gpu=true"}
+	clust.dirty = &dirtySet{
+		jobs:     pkg.NewUnsafeSet("new.service"),
+		machines: pkg.NewUnsafeSet(),
+		rejected: map[string]registry.UnitRejections{"pending.service": rej},
+	}
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	var tasks []*task
+	for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+		tasks = append(tasks, tsk)
+	}
+
+	// only the changed Job is considered, leaving running.service alone
+	want := []*task{
+		&task{
+			Type:      taskTypeAttemptScheduleUnit,
+			Reason:    "target state launched and unit not scheduled",
+			JobName:   "new.service",
+			MachineID: "XXX",
+		},
+	}
+	if !reflect.DeepEqual(want, tasks) {
+		t.Errorf("task mismatch\nexpected %v\n got %v", want, tasks)
+	}
+	wantRejected := map[string]registry.UnitRejections{"pending.service": rej}
+	if !reflect.DeepEqual(wantRejected, clust.rejected) {
+		t.Errorf("Unexpected rejections: got %v, want %v", clust.rejected, wantRejected)
+	}
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+// displace records that the named Job was unscheduled from the given
+// machine because the machine went away, so that it is placed along with
+// the other Jobs of the machine rather than greedily
+func (cs *clusterState) displace(jobName, machID string) {
+	if cs.displaced == nil {
+		cs.displaced = make(map[string]string)
+	}
+	cs.displaced[jobName] = machID
+}
+
+// relax records why the constraints of the named Job had to be relaxed to
+// place it, or forgets earlier relaxations if reason is empty
+func (cs *clusterState) relax(jobName, reason string) {
+	if reason == "" {
+		delete(cs.relaxed, jobName)
+		return
+	}
+	if cs.relaxed == nil {
+		cs.relaxed = make(map[string]string)
+	}
+	cs.relaxed[jobName] = reason
+}
+
+// placementOrder returns the Jobs of the cluster in the order they are
+// placed: by descending priority, and among Jobs of equal priority those
+// displaced from lost machines first, the largest reservations first, so
+// that the Jobs of a lost machine are fitted into the remaining capacity as
+// a batch before smaller Jobs fragment it
+func placementOrder(clust *clusterState) []*job.Job {
+	jobs := jobsByPriority(clust)
+	sort.Stable(displacedFirst{jobs, clust.displaced})
+	return jobs
+}
+
+type displacedFirst struct {
+	jobs      []*job.Job
+	displaced map[string]string
+}
+
+func (df displacedFirst) Len() int      { return len(df.jobs) }
+func (df displacedFirst) Swap(i, j int) { df.jobs[i], df.jobs[j] = df.jobs[j], df.jobs[i] }
+
+func (df displacedFirst) Less(i, j int) bool {
+	ji, jj := df.jobs[i], df.jobs[j]
+	if pi, pj := ji.Priority(), jj.Priority(); pi != pj {
+		return pi > pj
+	}
+	_, di := df.displaced[ji.Name]
+	_, dj := df.displaced[jj.Name]
+	if di != dj {
+		return di
+	}
+	if !di {
+		return false
+	}
+	ri, rj := ji.Resources(), jj.Resources()
+	if ri.Memory != rj.Memory {
+		return ri.Memory > rj.Memory
+	}
+	return ri.Cores > rj.Cores
+}
+
+// candidateAgents returns the subset of the given agents a Scheduler may
+// place the Job on, preserving their order. Jobs displaced from a lost
+// machine are dispersed across the machines able to run them, see
+// disperseAgents, relaxing their SpreadAcross constraint rather than
+// staying unscheduled if only machines without its metadata are left.
+func candidateAgents(clust *clusterState, agents []*agent.AgentState, j *job.Job) []*agent.AgentState {
+	if _, ok := clust.displaced[j.Name]; !ok {
+		return clust.placeableAgents(ableAgents(agents, j))
+	}
+
+	var able []*agent.AgentState
+	for _, as := range agents {
+		if ok, _ := as.AbleToRun(j); ok && !hasDomainConflict(agents, as, j) {
+			able = append(able, as)
+		}
+	}
+
+	var relaxed []string
+	spread, _ := spreadAgents(agents, able, j)
+	if sc := j.SpreadConstraint(); sc != nil && len(spread) == 0 && len(able) > 0 {
+		spread = able
+		relaxed = append(relaxed, fmt.Sprintf("no machine able to run it has %s metadata to spread across", sc.Key))
+	}
+
+	dispersed, peers := clust.disperseAgents(clust.placeableAgents(spread), j)
+	if peers > 0 {
+		relaxed = append(relaxed, fmt.Sprintf("every machine able to run it already runs %d other units of %s", peers, job.SpreadGroup(j.Name)))
+	}
+	clust.relax(j.Name, strings.Join(relaxed, "; "))
+	return dispersed
+}
+
+// disperseAgents returns the subset of the given agents running the fewest
+// other Units of the displaced Job's spread group, and among those the
+// fewest Units displaced from the same machine as the Job, preserving their
+// order. It also returns how many Units of the group the kept agents run,
+// which is only positive if the implicit anti-affinity of the group could
+// not be honored.
+func (cs *clusterState) disperseAgents(agents []*agent.AgentState, j *job.Job) ([]*agent.AgentState, int) {
+	group := job.SpreadGroup(j.Name)
+	from := cs.displaced[j.Name]
+
+	peers := make([]int, len(agents))
+	displaced := make([]int, len(agents))
+	best := -1
+	for i, as := range agents {
+		for name := range as.Units {
+			if name == j.Name {
+				continue
+			}
+			if job.SpreadGroup(name) == group {
+				peers[i]++
+			}
+			if m, ok := cs.displaced[name]; ok && m == from {
+				displaced[i]++
+			}
+		}
+		if best < 0 || peers[i] < peers[best] || (peers[i] == peers[best] && displaced[i] < displaced[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, 0
+	}
+
+	var kept []*agent.AgentState
+	for i, as := range agents {
+		if peers[i] == peers[best] && displaced[i] == displaced[best] {
+			kept = append(kept, as)
+		}
+	}
+	return kept, peers[best]
+}
+
+// recordRelaxations records the Jobs displaced from lost machines that
+// were scheduled during the last reconciliation only by relaxing their
+// constraints, along with how
+func (e *Engine) recordRelaxations(clust *clusterState) {
+	for name, reason := range clust.relaxed {
+		j, ok := clust.jobs[name]
+		if !ok || !j.Scheduled() {
+			continue
+		}
+		log.Infof("Relaxed constraints of Unit(%s) to reschedule it to Machine(%s): %s", name, j.TargetMachineID, reason)
+		metricPlacementsRelaxed.Inc()
+		e.recordEvent(registry.ClusterEvent{Type: registry.EventUnitPlacementRelaxed, UnitName: name, MachineID: j.TargetMachineID, Reason: reason})
+	}
+}
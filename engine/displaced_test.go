@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+)
+
+// reconcileLostMachine lets a Reconciler placing Units with the given
+// strategy move the Units of the lost machine ZZZ, returning the cluster
+// state afterwards
+func reconcileLostMachine(t *testing.T, strategy string, units []job.Unit, sUnits []job.ScheduledUnit, machines []machine.MachineState) *clusterState {
+	sched, err := NewScheduler(strategy)
+	if err != nil {
+		t.Fatalf("Failed creating Scheduler: %v", err)
+	}
+	clust := newClusterState(units, sUnits, machines)
+	r := NewReconciler(sched, false)
+	for range r.calculateClusterTasks(clust, make(chan struct{})) {
+	}
+	return clust
+}
+
+func TestDisplacedUnitsDispersed(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	capacity := resource.ResourceTuple{Cores: 800, Memory: 16384}
+	var units []job.Unit
+	var sUnits []job.ScheduledUnit
+	for _, name := range []string{"web@1.service", "web@2.service", "api.service", "db.service"} {
+		units = append(units, job.Unit{Name: name, TargetState: jsLaunched, Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512")})
+		sUnits = append(sUnits, job.ScheduledUnit{Name: name, State: &jsLaunched, TargetMachineID: "ZZZ"})
+	}
+	units = append(units, job.Unit{Name: "web@3.service", TargetState: jsLaunched, Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512")})
+	sUnits = append(sUnits, job.ScheduledUnit{Name: "web@3.service", State: &jsLaunched, TargetMachineID: "XXX"})
+
+	// binpack alone would stack all Units onto the fuller machine XXX
+	clust := reconcileLostMachine(t, "binpack", units, sUnits, []machine.MachineState{
+		machine.MachineState{ID: "XXX", TotalResources: capacity, ReservedResources: &resource.ResourceTuple{}},
+		machine.MachineState{ID: "YYY", TotalResources: capacity, ReservedResources: &resource.ResourceTuple{}},
+	})
+
+	placed := make(map[string][]string)
+	for _, name := range []string{"web@1.service", "web@2.service", "api.service", "db.service"} {
+		j := clust.jobs[name]
+		if !j.Scheduled() {
+			t.Fatalf("%s not rescheduled", name)
+		}
+		placed[j.TargetMachineID] = append(placed[j.TargetMachineID], name)
+	}
+	if len(placed["XXX"]) != 2 || len(placed["YYY"]) != 2 {
+		t.Errorf("Units of lost machine not dispersed: %v", placed)
+	}
+
+	// one instance of web@ had to join web@3 on XXX
+	if !reflect.DeepEqual(clust.relaxed, map[string]string{"web@2.service": "every machine able to run it already runs 1 other units of web@.service"}) {
+		t.Errorf("Unexpected relaxations: %v", clust.relaxed)
+	}
+}
+
+func TestDisplacedUnitsSpreadRelaxed(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	clust := reconcileLostMachine(t, "",
+		[]job.Unit{
+			job.Unit{Name: "db@1.service", TargetState: jsLaunched, Unit: newTestUnit(t, "[X-Fleet]\nSpreadAcross=zone")},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "db@1.service", State: &jsLaunched, TargetMachineID: "ZZZ"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX"},
+		},
+	)
+
+	if j := clust.jobs["db@1.service"]; j.TargetMachineID != "XXX" {
+		t.Errorf("db@1.service scheduled to %q, want XXX", j.TargetMachineID)
+	}
+	if !reflect.DeepEqual(clust.relaxed, map[string]string{"db@1.service": "no machine able to run it has zone metadata to spread across"}) {
+		t.Errorf("Unexpected relaxations: %v", clust.relaxed)
+	}
+}
+
+func TestPlacementOrder(t *testing.T) {
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "a.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=128")},
+			job.Unit{Name: "b.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=256")},
+			job.Unit{Name: "c.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024")},
+			job.Unit{Name: "d.service", Unit: newTestUnit(t, "[X-Fleet]\nPriority=10")},
+		},
+		nil, nil,
+	)
+	clust.displace("b.service", "ZZZ")
+	clust.displace("c.service", "ZZZ")
+
+	var got []string
+	for _, j := range placementOrder(clust) {
+		got = append(got, j.Name)
+	}
+	want := []string{"d.service", "c.service", "b.service", "a.service"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected placement order: got %v, want %v", got, want)
+	}
+}
@@ -2,8 +2,10 @@ package engine
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/coreos/fleet/agent"
 	"github.com/coreos/fleet/log"
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/pkg"
@@ -26,12 +28,76 @@ type Engine struct {
 	rStream   pkg.EventStream
 	machine   machine.Machine
 
-	lease   registry.Lease
+	// leases holds the leases of the shards of the scheduling work held
+	// by the local engine, indexed by shard, out of shards in total
+	leases  map[int]registry.Lease
+	shards  int
 	trigger chan struct{}
+
+	// machines holds the IDs of the machines seen during the last
+	// reconciliation while leading the cluster, if any
+	machines pkg.Set
+
+	// lost holds since when the machines Jobs are scheduled to have been
+	// missing, indexed by machine ID
+	lost map[string]time.Time
+
+	// waiting holds since when the Jobs declaring a ScheduleDeadline have
+	// been waiting to be scheduled, and running since when those declaring
+	// a TTL have been active, indexed by Job name
+	waiting map[string]time.Time
+	running map[string]time.Time
+
+	// flaps holds back the rescheduling of Jobs moved between machines
+	// too often, or is nil if they are always rescheduled
+	flaps *flapDamper
+
+	// gc removes the keys Units and machines that no longer exist leave
+	// behind in the Registry, or is nil if they are left alone
+	gc *garbageCollector
+
+	// notifier is notified of the ClusterEvents the engine records, or is
+	// nil if no webhooks are notified
+	notifier *WebhookNotifier
+
+	// rejections holds the reasons Units could not be scheduled last
+	// saved in the Registry, indexed by Unit name
+	rejections map[string]registry.UnitRejections
+
+	// snapshot condenses the cluster state left by the last
+	// reconciliation, against which the next one determines what
+	// changed. It is nil if the next reconciliation must re-evaluate all
+	// placements.
+	snapshot *clusterSnapshot
+
+	// fullIval is how often all placements are re-evaluated regardless
+	// of what changed, and lastFull when they last were. Zero disables
+	// incremental reconciliation.
+	fullIval time.Duration
+	lastFull time.Time
+
+	// reconciles counts the reconciliations carried out since the local
+	// engine acquired its first lease, or zero if it holds none
+	reconciles int
+
+	// tuning holds the settings passed to Tune since the last
+	// reconciliation, if any
+	tuneMutex sync.Mutex
+	tuning    *Tuning
 }
 
-func New(reg *registry.EtcdRegistry, rStream pkg.EventStream, mach machine.Machine) *Engine {
-	rec := NewReconciler()
+// New creates an Engine scheduling Units with the given Scheduler. Between
+// full reconciliations, which happen at least every fullIval, the Engine
+// only re-evaluates the placements affected by changes in the cluster.
+// Scheduling decisions are persisted by up to concurrency workers at once.
+// Units of machines that went away are moved after rescheduleDelay, unless
+// they declare their own RescheduleDelay.
+// The scheduling work is split by Unit into the given number of shards,
+// which the engines of the cluster share.
+func New(reg *registry.EtcdRegistry, rStream pkg.EventStream, mach machine.Machine, sched Scheduler, evictOnMetadataChange bool, rescheduleDelay, fullIval time.Duration, concurrency, shards int) *Engine {
+	rec := NewReconciler(sched, evictOnMetadataChange)
+	rec.concurrency = concurrency
+	rec.rescheduleDelay = rescheduleDelay
 	return &Engine{
 		rec:       rec,
 		registry:  reg,
@@ -40,27 +106,55 @@ func New(reg *registry.EtcdRegistry, rStream pkg.EventStream, mach machine.Machi
 		rStream:   rStream,
 		machine:   mach,
 		trigger:   make(chan struct{}),
+		fullIval:  fullIval,
+		shards:    shards,
 	}
 }
 
 func (e *Engine) Run(ival time.Duration, stop chan bool) {
 	leaseTTL := ival * 5
+	if e.notifier != nil {
+		go e.notifier.Run(stop)
+	}
 	machID := e.machine.State().ID
 
+	var rec pkg.PeriodicReconciler
 	reconcile := func() {
+		if next := e.applyTuning(ival); next != ival {
+			ival = next
+			leaseTTL = ival * 5
+			rec.SetInterval(ival)
+		}
+
 		if !ensureEngineVersionMatch(e.cRegistry, engineVersion) {
 			return
 		}
 
-		if e.lease == nil {
-			e.lease = acquireLeadership(e.lRegistry, machID, engineVersion, leaseTTL)
-		} else {
-			e.lease = renewLeadership(e.lease, leaseTTL)
+		// Placements of Units moving between shards are re-evaluated. An
+		// engine asked to step down gives up all its leases instead.
+		renewed := time.Now()
+		if e.stepDownRequested(machID) {
+			if len(e.leases) > 0 {
+				log.Infof("Engine stepping down as requested, releasing %d leases", len(e.leases))
+				e.releaseLeases()
+			}
+		} else if e.updateLeases(machID, leaseTTL) {
+			e.snapshot = nil
 		}
 
-		if e.lease == nil {
+		if len(e.leases) == 0 {
+			e.clearStatus(machID)
+			metricLeader.Set(0)
+			e.machines = nil
+			e.lost = nil
+			e.waiting = nil
+			e.running = nil
+			e.flaps.forget()
+			e.gc.forget()
+			e.snapshot = nil
 			return
 		}
+		metricLeader.Set(1)
 
 		// abort is closed when reconciliation must stop prematurely, either
 		// by a local timeout or the fleet server shutting down
@@ -84,6 +178,8 @@ func (e *Engine) Run(ival time.Duration, stop chan bool) {
 		e.rec.Reconcile(e, abort)
 		close(monitor)
 		elapsed := time.Now().Sub(start)
+		metricReconcileDuration.Observe(elapsed.Seconds())
+		e.publishStatus(machID, renewed.Add(leaseTTL), start, elapsed, leaseTTL)
 
 		msg := fmt.Sprintf("Engine completed reconciliation in %s", elapsed)
 		if elapsed > ival {
@@ -93,18 +189,13 @@ func (e *Engine) Run(ival time.Duration, stop chan bool) {
 		}
 	}
 
-	rec := pkg.NewPeriodicReconciler(ival, reconcile, e.rStream)
+	rec = pkg.NewPeriodicReconciler(ival, reconcile, e.rStream)
 	rec.Run(stop)
 }
 
 func (e *Engine) Purge() {
-	if e.lease == nil {
-		return
-	}
-	err := e.lease.Release()
-	if err != nil {
-		log.Errorf("Failed to release lease: %v", err)
-	}
+	e.releaseLeases()
+	e.clearStatus(e.machine.State().ID)
 }
 
 func ensureEngineVersionMatch(cReg registry.ClusterRegistry, expect int) bool {
@@ -129,8 +220,8 @@ func ensureEngineVersionMatch(cReg registry.ClusterRegistry, expect int) bool {
 	return true
 }
 
-func acquireLeadership(lReg registry.LeaseRegistry, machID string, ver int, ttl time.Duration) registry.Lease {
-	existing, err := lReg.GetLease(engineLeaseName)
+func acquireLeadership(lReg registry.LeaseRegistry, name, machID string, ver int, ttl time.Duration) registry.Lease {
+	existing, err := lReg.GetLease(name)
 	if err != nil {
 		log.Errorf("Unable to determine current lessee: %v", err)
 		return nil
@@ -138,7 +229,7 @@ func acquireLeadership(lReg registry.LeaseRegistry, machID string, ver int, ttl
 
 	var l registry.Lease
 	if existing == nil {
-		l, err = lReg.AcquireLease(engineLeaseName, machID, ver, ttl)
+		l, err = lReg.AcquireLease(name, machID, ver, ttl)
 		if err != nil {
 			log.Errorf("Engine leadership acquisition failed: %v", err)
 			return nil
@@ -147,6 +238,7 @@ func acquireLeadership(lReg registry.LeaseRegistry, machID string, ver int, ttl
 			return nil
 		}
 		log.Infof("Engine leadership acquired")
+		metricLeaseAcquisitions.Inc("acquire")
 		return l
 	}
 
@@ -156,7 +248,7 @@ func acquireLeadership(lReg registry.LeaseRegistry, machID string, ver int, ttl
 	}
 
 	rem := existing.TimeRemaining()
-	l, err = lReg.StealLease(engineLeaseName, machID, ver, ttl+rem, existing.Index())
+	l, err = lReg.StealLease(name, machID, ver, ttl+rem, existing.Index())
 	if err != nil {
 		log.Errorf("Engine leadership steal failed: %v", err)
 		return nil
@@ -166,6 +258,7 @@ func acquireLeadership(lReg registry.LeaseRegistry, machID string, ver int, ttl
 	}
 
 	log.Infof("Stole engine leadership from Machine(%s)", existing.MachineID())
+	metricLeaseAcquisitions.Inc("steal")
 
 	if rem > 0 {
 		log.Infof("Waiting %v for previous lease to expire before continuing reconciliation", rem)
@@ -191,25 +284,117 @@ func (e *Engine) Trigger() {
 }
 
 func (e *Engine) clusterState() (*clusterState, error) {
-	units, err := e.registry.Units()
+	return loadClusterState(e.registry)
+}
+
+// loadClusterState gathers the state of the cluster the engine reconciles
+// from the given Registry
+func loadClusterState(reg registry.Registry) (*clusterState, error) {
+	units, err := reg.Units()
 	if err != nil {
 		log.Errorf("Failed fetching Units from Registry: %v", err)
 		return nil, err
 	}
 
-	sUnits, err := e.registry.Schedule()
+	sUnits, err := reg.Schedule()
 	if err != nil {
 		log.Errorf("Failed fetching schedule from Registry: %v", err)
 		return nil, err
 	}
 
-	machines, err := e.registry.Machines()
+	machines, err := reg.Machines()
 	if err != nil {
 		log.Errorf("Failed fetching Machines from Registry: %v", err)
 		return nil, err
 	}
 
-	return newClusterState(units, sUnits, machines), nil
+	failures, err := reg.UnitFailures()
+	if err != nil {
+		log.Errorf("Failed fetching Unit failures from Registry: %v", err)
+		return nil, err
+	}
+
+	states, err := reg.UnitStates()
+	if err != nil {
+		log.Errorf("Failed fetching Unit states from Registry: %v", err)
+		return nil, err
+	}
+
+	stacks, err := reg.Stacks()
+	if err != nil {
+		log.Errorf("Failed fetching Stacks from Registry: %v", err)
+		return nil, err
+	}
+
+	completions, err := reg.UnitCompletions()
+	if err != nil {
+		log.Errorf("Failed fetching Unit completions from Registry: %v", err)
+		return nil, err
+	}
+
+	quotas, err := reg.Quotas()
+	if err != nil {
+		log.Errorf("Failed fetching Quotas from Registry: %v", err)
+		return nil, err
+	}
+
+	clust := newClusterState(units, sUnits, machines)
+	clust.failures = failures
+	clust.setStacks(stacks)
+	clust.setQuotas(quotas)
+	clust.setCompletions(completions)
+	clust.launched, clust.active = agent.DependencyState(units, states)
+	return clust, nil
+}
+
+// dirtySince determines what changed in the given cluster state since the
+// last reconciliation, or returns nil if all placements are to be
+// re-evaluated: after gaining leadership, after a failed or interrupted
+// reconciliation, and at least every fullIval. State changes are observed
+// by comparing the cluster state loaded from the Registry against the one
+// left by the last reconciliation, which covers all changes regardless of
+// the watches triggering the reconciliation.
+func (e *Engine) dirtySince(clust *clusterState) *dirtySet {
+	now := time.Now()
+	if e.snapshot == nil || e.fullIval <= 0 || now.Sub(e.lastFull) >= e.fullIval {
+		e.lastFull = now
+		metricReconciles.Inc("full")
+		return nil
+	}
+
+	metricReconciles.Inc("incremental")
+	d := e.snapshot.diff(newClusterSnapshot(clust))
+	d.rejected = e.rejections
+	return d
+}
+
+// trackMachines records the machines that joined or left the cluster since
+// the previous reconciliation
+func (e *Engine) trackMachines(clust *clusterState) {
+	current := pkg.NewUnsafeSet()
+	for machID := range clust.machines {
+		current.Add(machID)
+	}
+
+	if e.machines != nil {
+		for _, machID := range current.Sub(e.machines).Values() {
+			e.recordEvent(registry.ClusterEvent{Type: registry.EventMachineJoined, MachineID: machID})
+		}
+		for _, machID := range e.machines.Sub(current).Values() {
+			e.recordEvent(registry.ClusterEvent{Type: registry.EventMachineLeft, MachineID: machID})
+		}
+	}
+
+	e.machines = current
+}
+
+// recordEvent adds the given ClusterEvent to the cluster's event log and
+// notifies the webhooks of it
+func (e *Engine) recordEvent(ev registry.ClusterEvent) {
+	e.notifier.Notify(ev)
+	if err := e.registry.RecordEvent(ev); err != nil {
+		log.Errorf("Failed recording %s event: %v", ev.Type, err)
+	}
 }
 
 func (e *Engine) unscheduleUnit(name, machID string) (err error) {
@@ -1,9 +1,11 @@
 package engine
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/registry"
 )
 
@@ -97,10 +99,57 @@ func TestAcquireLeadership(t *testing.T) {
 			lReg.SetLease(engineLeaseName, tt.exist.machID, tt.exist.ver, time.Millisecond)
 		}
 
-		got := acquireLeadership(lReg, tt.local.machID, tt.local.ver, time.Millisecond)
+		got := acquireLeadership(lReg, engineLeaseName, tt.local.machID, tt.local.ver, time.Millisecond)
 
 		if tt.wantAcquire != (got != nil) {
 			t.Errorf("case %d: wantAcquire=%t but got %#v", i, tt.wantAcquire, got)
 		}
 	}
 }
+
+func TestTrackMachines(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	e := &Engine{registry: reg}
+
+	newClust := func(ids ...string) *clusterState {
+		var machines []machine.MachineState
+		for _, id := range ids {
+			machines = append(machines, machine.MachineState{ID: id})
+		}
+		return newClusterState(nil, nil, machines)
+	}
+
+	// the first reconciliation only establishes a baseline
+	e.trackMachines(newClust("XXX", "YYY"))
+	e.trackMachines(newClust("YYY", "ZZZ"))
+
+	events, _ := reg.Events(0)
+	want := []registry.ClusterEvent{
+		registry.ClusterEvent{Index: 1, Type: registry.EventMachineJoined, MachineID: "ZZZ"},
+		registry.ClusterEvent{Index: 2, Type: registry.EventMachineLeft, MachineID: "XXX"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Unexpected events:\ngot\n%#v\nwant\n%#v", events, want)
+	}
+}
+
+func TestTaskEvent(t *testing.T) {
+	for i, tt := range []struct {
+		task *task
+		want string
+	}{
+		{&task{Type: taskTypeAttemptScheduleUnit, Reason: "target state launched and unit not scheduled"}, registry.EventUnitScheduled},
+		{&task{Type: taskTypeUnscheduleUnit, Reason: "target state inactive"}, registry.EventUnitUnscheduled},
+		{&task{Type: taskTypeUnscheduleUnit, Reason: "preempted by higher-priority Unit(high.service)"}, registry.EventUnitPreempted},
+		{&task{Type: taskTypeUnscheduleUnit, Reason: "unit failed on target Machine(XXX): failed 4 times within 5m0s"}, registry.EventUnitFailed},
+		{&task{Type: taskTypeUnscheduleUnit, Reason: "target Machine(XXX) went away"}, registry.EventUnitRescheduled},
+	} {
+		tt.task.JobName = "foo.service"
+		tt.task.MachineID = "XXX"
+
+		ev := taskEvent(tt.task)
+		if ev.Type != tt.want || ev.UnitName != "foo.service" || ev.MachineID != "XXX" || ev.Reason != tt.task.Reason {
+			t.Errorf("case %d: unexpected event %#v", i, ev)
+		}
+	}
+}
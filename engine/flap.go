@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+const (
+	// flapBackoffInitial is how long the rescheduling of a Job is first
+	// held back once it exceeded the reschedule limit, doubling each time
+	// it exceeds the limit again up to flapBackoffMax
+	flapBackoffInitial = time.Minute
+	flapBackoffMax     = time.Hour
+)
+
+// flapDamper holds back the rescheduling of Jobs moved between machines
+// more than limit times within window, e.g. because they crash on every
+// machine they land on, rather than moving them around the cluster
+// endlessly. Like lost machines, moves are only known to the engine that
+// carried them out.
+type flapDamper struct {
+	limit  int
+	window time.Duration
+
+	// flaps holds how often Jobs were moved lately, indexed by Job name
+	flaps map[string]*flapState
+}
+
+// flapState records how often a Job was moved between machines lately
+type flapState struct {
+	// moves holds when the Job was moved within the window
+	moves []time.Time
+
+	// backoffs counts how often the rescheduling of the Job was held back
+	// since it last settled, and until when it currently is, if at all
+	backoffs int
+	until    time.Time
+}
+
+// SetFlapDamping holds back the rescheduling of Jobs moved between machines
+// more than limit times within window. A limit of zero disables damping.
+func (e *Engine) SetFlapDamping(limit int, window time.Duration) {
+	if limit <= 0 {
+		e.flaps = nil
+		return
+	}
+	e.flaps = &flapDamper{limit: limit, window: window}
+}
+
+// track forgets the moves older than the window, along with Jobs that
+// settled or went away, and records in the cluster state until when the
+// rescheduling of each Job is held back. As this is part of the snapshot
+// of the cluster state, Jobs whose backoff ended are reconsidered for
+// scheduling.
+func (d *flapDamper) track(clust *clusterState, now time.Time) {
+	clust.flaps = d
+	clust.backoff = make(map[string]time.Time)
+	if d == nil {
+		return
+	}
+
+	for name, fs := range d.flaps {
+		if _, ok := clust.jobs[name]; !ok {
+			delete(d.flaps, name)
+			continue
+		}
+
+		var moves []time.Time
+		for _, t := range fs.moves {
+			if now.Sub(t) < d.window {
+				moves = append(moves, t)
+			}
+		}
+		fs.moves = moves
+
+		if now.Before(fs.until) {
+			clust.backoff[name] = fs.until
+			continue
+		}
+		fs.until = time.Time{}
+		if len(fs.moves) == 0 {
+			delete(d.flaps, name)
+		}
+	}
+}
+
+// moved records that the named Job was moved off its machine. If it was
+// moved too often, its rescheduling is held back, which is returned along
+// with why.
+func (d *flapDamper) moved(name string, now time.Time) (until time.Time, reason string) {
+	if d == nil {
+		return
+	}
+	if d.flaps == nil {
+		d.flaps = make(map[string]*flapState)
+	}
+
+	fs, ok := d.flaps[name]
+	if !ok {
+		fs = &flapState{}
+		d.flaps[name] = fs
+	}
+	fs.moves = append(fs.moves, now)
+	if len(fs.moves) <= d.limit {
+		return
+	}
+
+	fs.backoffs++
+	delay := backoffDelay(fs.backoffs)
+	fs.until = now.Add(delay)
+	return fs.until, fmt.Sprintf("rescheduled %d times within %s, backing off for %s", len(fs.moves), d.window, delay)
+}
+
+// forget drops all moves, e.g. once another engine may have moved Jobs
+func (d *flapDamper) forget() {
+	if d != nil {
+		d.flaps = nil
+	}
+}
+
+// backoffDelay returns how long the rescheduling of a Job is held back the
+// given time in a row
+func backoffDelay(backoffs int) time.Duration {
+	delay := flapBackoffInitial
+	for i := 1; i < backoffs && delay < flapBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > flapBackoffMax {
+		delay = flapBackoffMax
+	}
+	return delay
+}
+
+// recordBackoffs records the Jobs whose rescheduling was held back during
+// the last reconciliation, along with why
+func (e *Engine) recordBackoffs(backedOff map[string]string) {
+	for name, reason := range backedOff {
+		log.Infof("Holding back rescheduling of Unit(%s): %s", name, reason)
+		metricUnitsBackedOff.Inc()
+		e.recordEvent(registry.ClusterEvent{Type: registry.EventUnitBackoff, UnitName: name, Reason: reason})
+	}
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	for backoffs, want := range map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		3:  4 * time.Minute,
+		7:  time.Hour,
+		20: time.Hour,
+	} {
+		if got := backoffDelay(backoffs); got != want {
+			t.Errorf("backoff %d: got delay %v, want %v", backoffs, got, want)
+		}
+	}
+}
+
+func TestFlapDamperMoved(t *testing.T) {
+	d := &flapDamper{limit: 2, window: 10 * time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if until, _ := d.moved("foo.service", now); !until.IsZero() {
+			t.Fatalf("move %d: backed off within limit", i+1)
+		}
+	}
+	until, reason := d.moved("foo.service", now)
+	if want := now.Add(time.Minute); !until.Equal(want) {
+		t.Fatalf("got backoff until %v, want %v", until, want)
+	}
+	if want := "rescheduled 3 times within 10m0s, backing off for 1m0s"; reason != want {
+		t.Errorf("got reason %q, want %q", reason, want)
+	}
+
+	// moving again before the moves expire doubles the backoff
+	until, _ = d.moved("foo.service", now)
+	if want := now.Add(2 * time.Minute); !until.Equal(want) {
+		t.Errorf("got backoff until %v, want %v", until, want)
+	}
+
+	var nilDamper *flapDamper
+	if until, _ := nilDamper.moved("foo.service", now); !until.IsZero() {
+		t.Errorf("disabled damper backed off")
+	}
+}
+
+func TestFlapDamperTrack(t *testing.T) {
+	units := []job.Unit{
+		{Name: "waiting.service", TargetState: job.JobStateLaunched},
+		{Name: "released.service", TargetState: job.JobStateLaunched},
+		{Name: "settled.service", TargetState: job.JobStateLaunched},
+	}
+	clust := newClusterState(units, nil, []machine.MachineState{{ID: "XXX"}})
+
+	now := time.Now()
+	d := &flapDamper{
+		limit:  2,
+		window: 10 * time.Minute,
+		flaps: map[string]*flapState{
+			"waiting.service":  {moves: []time.Time{now.Add(-time.Minute)}, backoffs: 1, until: now.Add(time.Minute)},
+			"released.service": {moves: []time.Time{now.Add(-time.Hour), now.Add(-time.Minute)}, backoffs: 1, until: now.Add(-time.Second)},
+			"settled.service":  {moves: []time.Time{now.Add(-time.Hour)}},
+			"gone.service":     {moves: []time.Time{now}},
+		},
+	}
+	d.track(clust, now)
+
+	if want := map[string]time.Time{"waiting.service": now.Add(time.Minute)}; !reflect.DeepEqual(want, clust.backoff) {
+		t.Errorf("unexpected backoffs: got %v, want %v", clust.backoff, want)
+	}
+	if _, ok := d.flaps["settled.service"]; ok {
+		t.Errorf("settled Job not forgotten")
+	}
+	if _, ok := d.flaps["gone.service"]; ok {
+		t.Errorf("destroyed Job not forgotten")
+	}
+	fs, ok := d.flaps["released.service"]
+	if !ok || len(fs.moves) != 1 || !fs.until.IsZero() || fs.backoffs != 1 {
+		t.Errorf("unexpected state of released Job: %#v", fs)
+	}
+
+	// the released Job is reconsidered once its backoff is no longer
+	// part of the cluster state
+	prev := newClusterState(units, nil, []machine.MachineState{{ID: "XXX"}})
+	prev.backoff = map[string]time.Time{"waiting.service": now.Add(time.Minute), "released.service": now.Add(-time.Second)}
+	if dirty := newClusterSnapshot(prev).diff(newClusterSnapshot(clust)); !reflect.DeepEqual(sortedValues(dirty.jobs), []string{"released.service"}) {
+		t.Errorf("unexpected dirty Jobs %v", dirty.jobs.Values())
+	}
+}
+
+func TestReconcileBacksOffFlappingJob(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		{Name: "flapping.service", TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+	})
+	units, _ := reg.Units()
+	sUnits, _ := reg.Schedule()
+	clust := newClusterState(units, sUnits, []machine.MachineState{{ID: "XXX"}, {ID: "YYY"}})
+	clust.failures = map[string]map[string]string{"flapping.service": {"XXX": "exited"}}
+
+	d := &flapDamper{limit: 1, window: time.Hour, flaps: map[string]*flapState{
+		"flapping.service": {moves: []time.Time{time.Now()}},
+	}}
+	d.track(clust, time.Now())
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	var tasks []*task
+	for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+		tasks = append(tasks, tsk)
+	}
+	if len(tasks) != 1 || tasks[0].Type != taskTypeUnscheduleUnit {
+		t.Fatalf("expected the Job to be unscheduled only, got %v", tasks)
+	}
+	if _, ok := clust.backoff["flapping.service"]; !ok {
+		t.Errorf("rescheduling of Job not held back")
+	}
+	if rej := clust.rejected["flapping.service"]; rej.BackoffUntil == nil {
+		t.Errorf("backoff not recorded in rejections: %#v", rej)
+	}
+	if _, ok := clust.backedOff["flapping.service"]; !ok {
+		t.Errorf("backoff not reported")
+	}
+}
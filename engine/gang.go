@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/resource"
+)
+
+// peerGroups indexes the Jobs linked to each other by MachineOf, which must
+// all run on the same machine. Global Units required as peers run on every
+// machine able to run them, so they do not link Jobs.
+type peerGroups struct {
+	// links holds, for each Job, the names of the Jobs it requires as
+	// peers and of those requiring it as a peer
+	links map[string][]string
+}
+
+func newPeerGroups(clust *clusterState) *peerGroups {
+	links := make(map[string][]string)
+	for _, j := range clust.jobs {
+		for _, peer := range j.Peers() {
+			if _, ok := clust.gUnits[peer]; ok || peer == j.Name {
+				continue
+			}
+			links[j.Name] = append(links[j.Name], peer)
+			links[peer] = append(links[peer], j.Name)
+		}
+	}
+	return &peerGroups{links: links}
+}
+
+// group returns the sorted names of the Jobs linked to the named Job,
+// directly or through other Jobs, including the Job itself. It returns nil
+// if the Job is linked to none.
+func (pg *peerGroups) group(name string) []string {
+	if len(pg.links[name]) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, linked := range pg.links[cur] {
+			if !seen[linked] {
+				seen[linked] = true
+				queue = append(queue, linked)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// groupRejection describes why the pending Jobs of a peer group could not
+// be scheduled
+type groupRejection struct {
+	reason string
+	// machines holds the reason each machine was unable to run the group
+	machines map[string]string
+}
+
+// pendingGroupJobs returns the Jobs of the named peer group waiting to be
+// scheduled, ordered so that each Job follows the peers it requires, along
+// with the machine the Jobs of the group already scheduled run on, if any.
+// If the pending Jobs cannot be scheduled, e.g. because a peer they require
+// does not exist, the reason is returned.
+func pendingGroupJobs(clust *clusterState, names []string) ([]*job.Job, string, string) {
+	var anchor string
+	var pending []*job.Job
+	isPending := make(map[string]bool)
+	for _, name := range names {
+		j, ok := clust.jobs[name]
+		if !ok {
+			continue
+		}
+		if j.Scheduled() {
+			if anchor == "" {
+				anchor = j.TargetMachineID
+			}
+			continue
+		}
+		if j.TargetState == job.JobStateInactive || clust.completed.Contains(name) || !clust.schedules(name) {
+			continue
+		}
+		pending = append(pending, j)
+		isPending[name] = true
+	}
+	sort.Sort(prioritizedJobs(pending))
+
+	for _, j := range pending {
+		for _, peer := range j.Peers() {
+			if _, ok := clust.gUnits[peer]; ok || isPending[peer] {
+				continue
+			}
+			if p, ok := clust.jobs[peer]; !ok {
+				return pending, anchor, fmt.Sprintf("required peer Unit(%s) of Unit(%s) does not exist", peer, j.Name)
+			} else if !p.Scheduled() {
+				return pending, anchor, fmt.Sprintf("required peer Unit(%s) of Unit(%s) is not scheduled", peer, j.Name)
+			}
+		}
+	}
+
+	// Each Job is placed once all pending peers it requires are
+	var ordered []*job.Job
+	placed := make(map[string]bool)
+	for len(ordered) < len(pending) {
+		progress := false
+		for _, j := range pending {
+			if placed[j.Name] || !peersPlaced(j, isPending, placed) {
+				continue
+			}
+			ordered = append(ordered, j)
+			placed[j.Name] = true
+			progress = true
+		}
+		if !progress {
+			var cycle []string
+			for _, j := range pending {
+				if !placed[j.Name] {
+					cycle = append(cycle, j.Name)
+				}
+			}
+			sort.Strings(cycle)
+			return pending, anchor, fmt.Sprintf("Units %s require each other as peers", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, anchor, ""
+}
+
+// peersPlaced determines whether all pending peers the given Job requires
+// have been placed
+func peersPlaced(j *job.Job, pending, placed map[string]bool) bool {
+	for _, peer := range j.Peers() {
+		if pending[peer] && !placed[peer] {
+			return false
+		}
+	}
+	return true
+}
+
+// scheduleGroup places the given pending Jobs of a peer group, ordered by
+// pendingGroupJobs, together on one machine: the anchor if Jobs of the group
+// already run there, or else the machine the Scheduler decides in favor of
+// for the first Job among those with room for the combined reservations of
+// all of them. If no machine is able to run all of them, the clusterState is
+// left as it was and the reason is returned. Peer groups never preempt other
+// Jobs.
+func scheduleGroup(clust *clusterState, sched Scheduler, pending []*job.Job, anchor string) *groupRejection {
+	names := make([]string, len(pending))
+	var reqs []resource.ResourceTuple
+	var exts []resource.Counts
+	for i, j := range pending {
+		names[i] = j.Name
+		if reason := j.UnmetDependency(clust.launched, clust.active); reason != "" {
+			return &groupRejection{reason: fmt.Sprintf("Unit(%s) of peer group not schedulable yet: %s", j.Name, reason)}
+		}
+		reqs = append(reqs, j.Resources())
+		exts = append(exts, j.ResourceRequests())
+	}
+	req, ext := resource.Sum(reqs...), resource.SumCounts(exts...)
+	group := strings.Join(names, ", ")
+
+	sorted := sortedAgentsByID(clust)
+	agents := make(map[string]*agent.AgentState, len(sorted))
+	for _, as := range sorted {
+		agents[as.MState.ID] = as
+	}
+	var candidates []string
+	if anchor != "" {
+		if _, ok := agents[anchor]; ok {
+			candidates = append(candidates, anchor)
+		}
+	} else {
+		for _, as := range clust.placeableAgents(sorted) {
+			candidates = append(candidates, as.MState.ID)
+		}
+	}
+
+	// Machines without room for the whole group are ruled out up front,
+	// so that no Job of the group is placed where the others do not fit
+	machines := make(map[string]string)
+	var fitting []string
+	for _, id := range candidates {
+		if able, reason := agents[id].Fits(req, ext); !able {
+			machines[id] = fmt.Sprintf("unable to fit peer group: %s", reason)
+			continue
+		}
+		fitting = append(fitting, id)
+	}
+
+	var placed []string
+	rollback := func() {
+		for _, name := range placed {
+			clust.unschedule(name)
+		}
+		placed = nil
+	}
+
+	for len(fitting) > 0 {
+		restricted := *clust
+		restricted.placeable = pkg.NewUnsafeSet(fitting...)
+
+		var target, failed string
+		for _, j := range pending {
+			if reason := clust.exceedsQuota(j); reason != "" {
+				rollback()
+				return &groupRejection{reason: fmt.Sprintf("Unit(%s) of peer group not schedulable: %s", j.Name, reason)}
+			}
+
+			dec, err := sched.Decide(&restricted, j)
+			if err != nil {
+				failed = fmt.Sprintf("Unit(%s) cannot run alongside its peers: %v", j.Name, err)
+				break
+			}
+			if target == "" {
+				target = dec.machineID
+				restricted.placeable = pkg.NewUnsafeSet(target)
+			}
+			clust.schedule(j.Name, target)
+			placed = append(placed, j.Name)
+		}
+		if failed == "" {
+			return nil
+		}
+		rollback()
+
+		// None of the remaining machines is able to run the first Job
+		if target == "" {
+			for id, reason := range rejections(clust, pending[0]) {
+				if _, ok := machines[id]; !ok {
+					machines[id] = reason
+				}
+			}
+			break
+		}
+
+		machines[target] = failed
+		var rest []string
+		for _, id := range fitting {
+			if id != target {
+				rest = append(rest, id)
+			}
+		}
+		fitting = rest
+	}
+
+	reason := fmt.Sprintf("no agents able to run peer group %s together", group)
+	if anchor != "" {
+		reason = fmt.Sprintf("Machine(%s) running peers is unable to run peer group %s", anchor, group)
+	}
+	return &groupRejection{reason: reason, machines: machines}
+}
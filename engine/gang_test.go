@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+)
+
+func TestCalculateClusterTasksPeerGroups(t *testing.T) {
+	small := machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 768}}
+	large := machine.MachineState{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}}
+
+	for i, tt := range []struct {
+		units    []job.Unit
+		sUnits   []job.ScheduledUnit
+		machines []machine.MachineState
+		// want holds the machine each Unit is scheduled to
+		want   map[string]string
+		reason string
+	}{
+		// only YYY has room for both Units, although app.service
+		// alone would be placed on XXX
+		{
+			units: []job.Unit{
+				{Name: "app.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=db.service\nMemoryReservation=512"), TargetState: job.JobStateLaunched},
+				{Name: "db.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512"), TargetState: job.JobStateLaunched},
+			},
+			machines: []machine.MachineState{small, large},
+			want:     map[string]string{"app.service": "YYY", "db.service": "YYY"},
+		},
+		// no machine has room for both
+		{
+			units: []job.Unit{
+				{Name: "app.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=db.service\nMemoryReservation=768"), TargetState: job.JobStateLaunched},
+				{Name: "db.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512"), TargetState: job.JobStateLaunched},
+			},
+			machines: []machine.MachineState{small, large},
+			want:     map[string]string{},
+			reason:   "no agents able to run peer group db.service, app.service together",
+		},
+		// Units join peers already scheduled
+		{
+			units: []job.Unit{
+				{Name: "app.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=db.service"), TargetState: job.JobStateLaunched},
+				{Name: "db.service", TargetState: job.JobStateLaunched},
+			},
+			sUnits:   []job.ScheduledUnit{{Name: "db.service", TargetMachineID: "XXX"}},
+			machines: []machine.MachineState{small, large},
+			want:     map[string]string{"app.service": "XXX"},
+		},
+		// peers chain, in either direction
+		{
+			units: []job.Unit{
+				{Name: "a.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=b.service"), TargetState: job.JobStateLaunched},
+				{Name: "b.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=c.service"), TargetState: job.JobStateLaunched},
+				{Name: "c.service", TargetState: job.JobStateLaunched},
+				{Name: "d.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=c.service"), TargetState: job.JobStateLaunched},
+			},
+			machines: []machine.MachineState{small, large},
+			want:     map[string]string{"a.service": "XXX", "b.service": "XXX", "c.service": "XXX", "d.service": "XXX"},
+		},
+		// the group waits for peers that do not exist
+		{
+			units: []job.Unit{
+				{Name: "app.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=db.service"), TargetState: job.JobStateLaunched},
+			},
+			machines: []machine.MachineState{small, large},
+			want:     map[string]string{},
+			reason:   "required peer Unit(db.service) of Unit(app.service) does not exist",
+		},
+		// and for inactive ones
+		{
+			units: []job.Unit{
+				{Name: "app.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=db.service"), TargetState: job.JobStateLaunched},
+				{Name: "db.service", TargetState: job.JobStateInactive},
+			},
+			machines: []machine.MachineState{small, large},
+			want:     map[string]string{},
+			reason:   "required peer Unit(db.service) of Unit(app.service) is not scheduled",
+		},
+		// Units requiring each other can never be placed
+		{
+			units: []job.Unit{
+				{Name: "a.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=b.service"), TargetState: job.JobStateLaunched},
+				{Name: "b.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=a.service"), TargetState: job.JobStateLaunched},
+			},
+			machines: []machine.MachineState{small, large},
+			want:     map[string]string{},
+			reason:   "Units a.service, b.service require each other as peers",
+		},
+	} {
+		clust := newClusterState(tt.units, tt.sUnits, tt.machines)
+		r := NewReconciler(&leastLoadedScheduler{}, false)
+
+		scheduled := make(map[string]string)
+		for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+			if tsk.Type == taskTypeAttemptScheduleUnit {
+				scheduled[tsk.JobName] = tsk.MachineID
+			}
+		}
+
+		if !reflect.DeepEqual(tt.want, scheduled) {
+			t.Errorf("case %d: scheduled %v, want %v", i, scheduled, tt.want)
+		}
+		if tt.reason == "" {
+			if len(clust.rejected) != 0 {
+				t.Errorf("case %d: unexpected rejections %v", i, clust.rejected)
+			}
+			continue
+		}
+		if got := clust.rejected["app.service"].Reason + clust.rejected["a.service"].Reason; got != tt.reason {
+			t.Errorf("case %d: rejected with %q, want %q", i, got, tt.reason)
+		}
+	}
+}
+
+func TestScheduleGroupMachineReasons(t *testing.T) {
+	units := []job.Unit{
+		{Name: "app.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineOf=db.service\nMemoryReservation=512"), TargetState: job.JobStateLaunched},
+		{Name: "db.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512\nConflicts=other.service"), TargetState: job.JobStateLaunched},
+		{Name: "other.service", TargetState: job.JobStateLaunched},
+	}
+	sUnits := []job.ScheduledUnit{{Name: "other.service", TargetMachineID: "YYY"}}
+	machines := []machine.MachineState{
+		{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 768}},
+		{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}},
+	}
+	clust := newClusterState(units, sUnits, machines)
+
+	pending, anchor, reason := pendingGroupJobs(clust, []string{"app.service", "db.service"})
+	if reason != "" || anchor != "" {
+		t.Fatalf("Unexpected anchor %q or reason %q", anchor, reason)
+	}
+	if len(pending) != 2 || pending[0].Name != "db.service" {
+		t.Fatalf("Expected db.service to be placed first, got %v", pending)
+	}
+
+	rej := scheduleGroup(clust, &leastLoadedScheduler{}, pending, anchor)
+	if rej == nil {
+		t.Fatalf("Expected peer group to be rejected")
+	}
+	want := map[string]string{
+		"XXX": "unable to fit peer group: insufficient memory: requested 1024MB, available 512MB",
+		"YYY": "found conflict with locally-scheduled Unit(other.service)",
+	}
+	if !reflect.DeepEqual(want, rej.machines) {
+		t.Errorf("Unexpected machine reasons: got %v, want %v", rej.machines, want)
+	}
+	for _, name := range []string{"app.service", "db.service"} {
+		if clust.jobs[name].Scheduled() {
+			t.Errorf("Unit(%s) left scheduled after rejection", name)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+// garbageCollector removes the data Units and machines that no longer exist
+// leave behind in the Registry, which nothing expires, so that the Registry
+// does not keep growing as Units and machines come and go. Rather than
+// relying on TTLs, keys found orphaned are tombstoned, and only removed
+// once they were still found orphaned, and unchanged, grace later. Like
+// lost machines, tombstones are only known to the engine that found them.
+type garbageCollector struct {
+	ival   time.Duration
+	grace  time.Duration
+	dryRun bool
+
+	// last is when the Registry was last scanned
+	last time.Time
+
+	// tombstones holds the orphaned keys found so far, indexed by key
+	tombstones map[string]tombstone
+}
+
+// tombstone records since when a key has been found orphaned, as of which
+// modification
+type tombstone struct {
+	index uint64
+	since time.Time
+}
+
+// SetGarbageCollection makes the Engine scan the Registry for data left
+// behind by Units and machines that no longer exist every ival, removing
+// what was found orphaned for at least grace. In a dry run, what would be
+// removed is only logged. An ival of zero disables garbage collection.
+func (e *Engine) SetGarbageCollection(ival, grace time.Duration, dryRun bool) {
+	if ival <= 0 {
+		e.gc = nil
+		return
+	}
+	e.gc = &garbageCollector{ival: ival, grace: grace, dryRun: dryRun}
+}
+
+// collectGarbage scans the Registry for orphaned keys if it was not
+// scanned within the interval of garbage collection, and removes those
+// found orphaned for long enough
+func (e *Engine) collectGarbage(now time.Time) {
+	gc := e.gc
+	if gc == nil || now.Sub(gc.last) < gc.ival {
+		return
+	}
+	gc.last = now
+
+	orphans, err := e.registry.Orphans()
+	if err != nil {
+		log.Errorf("Failed scanning Registry for orphaned keys: %v", err)
+		return
+	}
+
+	tombstones := make(map[string]tombstone, len(orphans))
+	var found, removed int
+	for _, o := range orphans {
+		found += o.Keys
+		ts, ok := gc.tombstones[o.Key]
+		if !ok || ts.index != o.Index {
+			ts = tombstone{index: o.Index, since: now}
+		}
+		tombstones[o.Key] = ts
+
+		if wait := ts.since.Add(gc.grace).Sub(now); wait > 0 {
+			log.V(1).Infof("Leaving orphaned %s key %s (%s) for another %s", o.Kind, o.Key, o.Reason, wait)
+			continue
+		}
+		if gc.dryRun {
+			log.Infof("Would remove orphaned %s key %s (%s)", o.Kind, o.Key, o.Reason)
+			continue
+		}
+		if err := e.registry.RemoveOrphan(o); err != nil {
+			if err == registry.ErrOrphanChanged {
+				log.V(1).Infof("Orphaned %s key %s changed, leaving it for now", o.Kind, o.Key)
+			} else {
+				log.Errorf("Failed removing orphaned %s key %s: %v", o.Kind, o.Key, err)
+			}
+			continue
+		}
+		log.Infof("Removed orphaned %s key %s (%s)", o.Kind, o.Key, o.Reason)
+		delete(tombstones, o.Key)
+		removed += o.Keys
+		metricOrphansRemoved.Add(float64(o.Keys), o.Kind)
+	}
+	gc.tombstones = tombstones
+
+	if found > 0 {
+		log.Infof("Engine found %d orphaned keys in the Registry, removed %d", found, removed)
+	}
+}
+
+// forget drops the tombstones, as another engine may be removing the keys
+// in the meantime
+func (gc *garbageCollector) forget() {
+	if gc != nil {
+		gc.tombstones = nil
+		gc.last = time.Time{}
+	}
+}
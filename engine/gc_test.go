@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	newRegistry := func() *registry.FakeRegistry {
+		reg := registry.NewFakeRegistry()
+		reg.SetMachines([]machine.MachineState{{ID: "XXX"}})
+		reg.CreateUnit(&job.Unit{Name: "web.service", Unit: newTestUnit(t, "[Service]\nExecStart=/bin/web")})
+		reg.CreateUnit(&job.Unit{Name: "old.service", Unit: newTestUnit(t, "[Service]\nExecStart=/bin/old")})
+		reg.DestroyUnit("old.service")
+		reg.SetUnitStates([]unit.UnitState{
+			{UnitName: "web.service", MachineID: "XXX"},
+			{UnitName: "web.service", MachineID: "YYY"},
+			{UnitName: "old.service", MachineID: "XXX"},
+		})
+		return reg
+	}
+	remaining := func(reg *registry.FakeRegistry) (keys []string) {
+		orphans, _ := reg.Orphans()
+		for _, o := range orphans {
+			keys = append(keys, o.Key)
+		}
+		return
+	}
+
+	reg := newRegistry()
+	orphaned := remaining(reg)
+	if len(orphaned) != 3 {
+		t.Fatalf("Expected 3 orphans, got %v", orphaned)
+	}
+
+	e := &Engine{registry: reg}
+	e.SetGarbageCollection(time.Hour, 90*time.Minute, false)
+	start := time.Now()
+
+	// orphans are tombstoned first, and left alone within the grace
+	e.collectGarbage(start)
+	if got := remaining(reg); !reflect.DeepEqual(got, orphaned) {
+		t.Errorf("Orphans removed within grace: %v left", got)
+	}
+	if len(e.gc.tombstones) != 3 {
+		t.Errorf("Expected 3 tombstones, got %v", e.gc.tombstones)
+	}
+
+	// the Registry is not scanned again within the interval
+	reg.SetUnitStates([]unit.UnitState{{UnitName: "web.service", MachineID: "XXX"}})
+	e.collectGarbage(start.Add(30 * time.Minute))
+	if len(e.gc.tombstones) != 3 {
+		t.Errorf("Registry scanned within interval")
+	}
+
+	// orphans no longer found are forgotten, the others removed
+	// once found orphaned for the whole grace
+	e.collectGarbage(start.Add(time.Hour))
+	if got := remaining(reg); len(got) != 1 || len(e.gc.tombstones) != 1 {
+		t.Errorf("Unexpected orphans %v, tombstones %v", got, e.gc.tombstones)
+	}
+	e.collectGarbage(start.Add(2 * time.Hour))
+	if got := remaining(reg); len(got) != 0 || len(e.gc.tombstones) != 0 {
+		t.Errorf("Unexpected orphans %v, tombstones %v", got, e.gc.tombstones)
+	}
+	if u, _ := reg.Unit("web.service"); u == nil {
+		t.Errorf("Unit file of existing unit removed")
+	}
+
+	// a dry run removes nothing
+	reg = newRegistry()
+	e = &Engine{registry: reg}
+	e.SetGarbageCollection(time.Hour, 0, true)
+	e.collectGarbage(start)
+	if got := remaining(reg); !reflect.DeepEqual(got, orphaned) {
+		t.Errorf("Orphans removed in a dry run: %v left", got)
+	}
+
+	// garbage collection may be disabled
+	e.SetGarbageCollection(0, 0, false)
+	e.collectGarbage(start)
+	if e.gc != nil {
+		t.Errorf("Garbage collection not disabled")
+	}
+}
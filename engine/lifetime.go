@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+// trackLifetimes records since when each Job declaring a ScheduleDeadline
+// has been waiting to be scheduled, and since when each Job declaring a TTL
+// has been active, forgetting the Jobs that no longer are. Like lost
+// machines, Jobs are only known to be waiting or active from when the local
+// engine first found them so.
+func (e *Engine) trackLifetimes(clust *clusterState, now time.Time) {
+	waiting := make(map[string]time.Time)
+	running := make(map[string]time.Time)
+	for _, j := range clust.jobs {
+		if !clust.schedules(j.Name) {
+			continue
+		}
+
+		if _, ok := j.ScheduleDeadline(); ok && !j.Scheduled() && j.TargetState != job.JobStateInactive && !clust.completed.Contains(j.Name) {
+			since, ok := e.waiting[j.Name]
+			if !ok {
+				since = now
+			}
+			waiting[j.Name] = since
+		}
+
+		if _, ok := j.TTL(); ok && j.Scheduled() && clust.active.Contains(j.Name) {
+			since, ok := e.running[j.Name]
+			if !ok {
+				since = now
+			}
+			running[j.Name] = since
+		}
+	}
+	e.waiting, e.running = waiting, running
+}
+
+// overdueJobs returns the names of the Jobs that have waited to be scheduled
+// for longer than their ScheduleDeadline, and of those that have been active
+// for longer than their TTL, given since when they have been so
+func overdueJobs(clust *clusterState, waiting, running map[string]time.Time, now time.Time) (missed, expired []string) {
+	for name, since := range waiting {
+		j, ok := clust.jobs[name]
+		if !ok {
+			continue
+		}
+		if deadline, ok := j.ScheduleDeadline(); ok && now.Sub(since) >= deadline {
+			missed = append(missed, name)
+		}
+	}
+	for name, since := range running {
+		j, ok := clust.jobs[name]
+		if !ok {
+			continue
+		}
+		if ttl, ok := j.TTL(); ok && now.Sub(since) >= ttl {
+			expired = append(expired, name)
+		}
+	}
+	return
+}
+
+// enforceLifetimes gives up on the Jobs that missed their ScheduleDeadline,
+// setting their target state to inactive so they are no longer considered
+// for scheduling until started again, and destroys the Jobs that outlived
+// their TTL. The cluster state is updated accordingly.
+func (e *Engine) enforceLifetimes(clust *clusterState, now time.Time) {
+	missed, expired := overdueJobs(clust, e.waiting, e.running, now)
+
+	for _, name := range missed {
+		deadline, _ := clust.jobs[name].ScheduleDeadline()
+		reason := fmt.Sprintf("not scheduled within ScheduleDeadline of %s", deadline)
+		if rej, ok := e.rejections[name]; ok {
+			reason = fmt.Sprintf("%s: %s", reason, rej.Reason)
+		}
+
+		if err := e.registry.SetUnitTargetState(name, job.JobStateInactive); err != nil {
+			log.Errorf("Failed giving up on scheduling Unit(%s): %v", name, err)
+			continue
+		}
+		log.Infof("Gave up on scheduling Unit(%s): %s", name, reason)
+		metricScheduleDeadlinesMissed.Inc()
+		e.recordEvent(registry.ClusterEvent{Type: registry.EventUnitScheduleFailed, UnitName: name, Reason: reason})
+
+		clust.jobs[name].TargetState = job.JobStateInactive
+		clust.launched.Remove(name)
+		delete(e.waiting, name)
+	}
+
+	for _, name := range expired {
+		j := clust.jobs[name]
+		ttl, _ := j.TTL()
+		reason := fmt.Sprintf("active for longer than TTL of %s", ttl)
+
+		if err := e.registry.DestroyUnit(name); err != nil {
+			log.Errorf("Failed destroying expired Unit(%s): %v", name, err)
+			continue
+		}
+		log.Infof("Destroyed Unit(%s): %s", name, reason)
+		metricUnitsExpired.Inc()
+		e.recordEvent(registry.ClusterEvent{Type: registry.EventUnitExpired, UnitName: name, MachineID: j.TargetMachineID, Reason: reason})
+
+		delete(clust.jobs, name)
+		clust.launched.Remove(name)
+		delete(e.running, name)
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestTrackLifetimes(t *testing.T) {
+	units := []job.Unit{
+		{Name: "pending.service", Unit: newTestUnit(t, "[X-Fleet]\nScheduleDeadline=10m"), TargetState: job.JobStateLaunched},
+		{Name: "stopped.service", Unit: newTestUnit(t, "[X-Fleet]\nScheduleDeadline=10m"), TargetState: job.JobStateInactive},
+		{Name: "nodeadline.service", TargetState: job.JobStateLaunched},
+		{Name: "running.service", Unit: newTestUnit(t, "[X-Fleet]\nScheduleDeadline=10m\nTTL=1h"), TargetState: job.JobStateLaunched},
+		{Name: "starting.service", Unit: newTestUnit(t, "[X-Fleet]\nTTL=1h"), TargetState: job.JobStateLaunched},
+	}
+	sUnits := []job.ScheduledUnit{
+		{Name: "running.service", TargetMachineID: "XXX"},
+		{Name: "starting.service", TargetMachineID: "XXX"},
+	}
+	clust := newClusterState(units, sUnits, []machine.MachineState{{ID: "XXX"}})
+	clust.active.Add("running.service")
+
+	then := time.Date(2015, time.March, 1, 12, 0, 0, 0, time.UTC)
+	now := then.Add(time.Minute)
+	e := &Engine{
+		waiting: map[string]time.Time{
+			"pending.service": then,
+			// Jobs that got scheduled are forgotten
+			"running.service": then,
+		},
+		running: map[string]time.Time{
+			// as are those no longer active
+			"starting.service": then,
+		},
+	}
+	e.trackLifetimes(clust, now)
+
+	if want := map[string]time.Time{"pending.service": then}; !reflect.DeepEqual(want, e.waiting) {
+		t.Errorf("unexpected waiting Jobs: got %v, want %v", e.waiting, want)
+	}
+	if want := map[string]time.Time{"running.service": now}; !reflect.DeepEqual(want, e.running) {
+		t.Errorf("unexpected running Jobs: got %v, want %v", e.running, want)
+	}
+}
+
+func TestOverdueJobs(t *testing.T) {
+	units := []job.Unit{
+		{Name: "a.service", Unit: newTestUnit(t, "[X-Fleet]\nScheduleDeadline=10m"), TargetState: job.JobStateLaunched},
+		{Name: "b.service", Unit: newTestUnit(t, "[X-Fleet]\nScheduleDeadline=1h"), TargetState: job.JobStateLaunched},
+		{Name: "c.service", Unit: newTestUnit(t, "[X-Fleet]\nTTL=10m"), TargetState: job.JobStateLaunched},
+		{Name: "d.service", Unit: newTestUnit(t, "[X-Fleet]\nTTL=1h"), TargetState: job.JobStateLaunched},
+	}
+	clust := newClusterState(units, nil, nil)
+
+	now := time.Now()
+	since := now.Add(-10 * time.Minute)
+	missed, expired := overdueJobs(clust,
+		map[string]time.Time{"a.service": since, "b.service": since, "gone.service": since},
+		map[string]time.Time{"c.service": since, "d.service": since},
+		now,
+	)
+	if want := []string{"a.service"}; !reflect.DeepEqual(want, missed) {
+		t.Errorf("unexpected Jobs past their ScheduleDeadline: got %v, want %v", missed, want)
+	}
+	if want := []string{"c.service"}; !reflect.DeepEqual(want, expired) {
+		t.Errorf("unexpected Jobs past their TTL: got %v, want %v", expired, want)
+	}
+}
+
+func TestEnforceLifetimes(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		{Name: "pending.service", Unit: newTestUnit(t, "[X-Fleet]\nScheduleDeadline=10m"), TargetState: job.JobStateLaunched},
+		{Name: "running.service", Unit: newTestUnit(t, "[X-Fleet]\nTTL=1h"), TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+	})
+	units, _ := reg.Units()
+	sUnits, _ := reg.Schedule()
+	clust := newClusterState(units, sUnits, []machine.MachineState{{ID: "XXX"}})
+	clust.active.Add("running.service")
+
+	now := time.Now()
+	e := &Engine{
+		registry:   reg,
+		waiting:    map[string]time.Time{"pending.service": now.Add(-time.Hour)},
+		running:    map[string]time.Time{"running.service": now.Add(-time.Hour)},
+		rejections: map[string]registry.UnitRejections{"pending.service": {Reason: "insufficient memory"}},
+	}
+	e.enforceLifetimes(clust, now)
+
+	units, _ = reg.Units()
+	if len(units) != 1 || units[0].Name != "pending.service" || units[0].TargetState != job.JobStateInactive {
+		t.Fatalf("Unexpected Units left: %v", units)
+	}
+	if j := clust.jobs["pending.service"]; j.TargetState != job.JobStateInactive {
+		t.Errorf("Target state of Job(pending.service) in cluster state not updated")
+	}
+	if _, ok := clust.jobs["running.service"]; ok {
+		t.Errorf("Job(running.service) left in cluster state")
+	}
+
+	events, _ := reg.Events(0)
+	var got []string
+	for _, ev := range events {
+		got = append(got, ev.Type+": "+ev.Reason)
+	}
+	sort.Strings(got)
+	want := []string{
+		"UnitExpired: active for longer than TTL of 1h0m0s",
+		"UnitScheduleFailed: not scheduled within ScheduleDeadline of 10m0s: insufficient memory",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Unexpected events: got %v, want %v", got, want)
+	}
+}
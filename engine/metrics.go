@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"github.com/coreos/fleet/metrics"
+)
+
+var (
+	metricLeader = metrics.NewGauge(
+		"fleet_engine_leader",
+		"Whether the local engine currently holds the engine leadership lease (1) or not (0).",
+	)
+	metricShards = metrics.NewGauge(
+		"fleet_engine_shards",
+		"Number of shards of the scheduling work whose leases the local engine holds.",
+	)
+	metricLeaseAcquisitions = metrics.NewCounter(
+		"fleet_engine_lease_acquisitions_total",
+		"Number of times the local engine acquired the engine leadership lease, by method (acquire or steal).",
+		"method",
+	)
+	metricReconciles = metrics.NewCounter(
+		"fleet_engine_reconciliations_total",
+		"Number of reconciliations carried out by the lead engine, by kind (full or incremental).",
+		"kind",
+	)
+	metricReconcileDuration = metrics.NewHistogram(
+		"fleet_engine_reconcile_duration_seconds",
+		"Time taken by the lead engine to reconcile the cluster schedule.",
+		metrics.DefaultBuckets,
+	)
+	metricTasks = metrics.NewCounter(
+		"fleet_engine_scheduling_decisions_total",
+		"Number of scheduling decisions carried out by the engine, by task type.",
+		"type",
+	)
+	metricFailedPlacements = metrics.NewCounter(
+		"fleet_engine_failed_placements_total",
+		"Number of times the engine found no machine able to run a unit.",
+	)
+	metricScheduleDeadlinesMissed = metrics.NewCounter(
+		"fleet_engine_schedule_deadlines_missed_total",
+		"Number of units the engine gave up on scheduling because they waited longer than their ScheduleDeadline.",
+	)
+	metricUnitsExpired = metrics.NewCounter(
+		"fleet_engine_units_expired_total",
+		"Number of units the engine destroyed because they were active longer than their TTL.",
+	)
+	metricUnitsBackedOff = metrics.NewCounter(
+		"fleet_engine_units_backed_off_total",
+		"Number of times the engine held back the rescheduling of a unit because it was rescheduled too often.",
+	)
+	metricPlacementsRelaxed = metrics.NewCounter(
+		"fleet_engine_placements_relaxed_total",
+		"Number of units displaced from lost machines the engine rescheduled only by relaxing their placement constraints.",
+	)
+	metricOrphansRemoved = metrics.NewCounter(
+		"fleet_engine_orphaned_keys_removed_total",
+		"Number of keys left behind by units and machines that no longer exist the engine removed from etcd, by kind (unit-state, schedule, unit-file or machine).",
+		"kind",
+	)
+	metricNotifications = metrics.NewCounter(
+		"fleet_engine_notifications_total",
+		"Number of notifications of cluster events to webhooks, by result (delivered, failed or dropped).",
+		"result",
+	)
+)
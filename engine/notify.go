@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+const (
+	// time allowed for a notification webhook to respond
+	notifyWebhookTimeout = 5 * time.Second
+
+	// notifyAttempts is how often the delivery of a notification to a
+	// webhook is attempted, waiting notifyRetryBackoff after the first
+	// failed attempt and doubling the wait after each further one
+	notifyAttempts     = 5
+	notifyRetryBackoff = time.Second
+
+	// notifyQueueLength is how many notifications may wait for delivery
+	// before further ones are dropped
+	notifyQueueLength = 256
+
+	// NotifySignatureHeader carries the hex-encoded HMAC-SHA256 of the
+	// body of a notification, keyed with the configured secret
+	NotifySignatureHeader = "X-Fleet-Signature"
+)
+
+// DefaultNotifyEvents are the types of ClusterEvents POSTed to webhooks
+// unless configured otherwise
+var DefaultNotifyEvents = []string{
+	registry.EventUnitFailed,
+	registry.EventUnitRescheduled,
+	registry.EventUnitPreempted,
+	registry.EventMachineLeft,
+}
+
+// Notification is POSTed as JSON to webhooks for each ClusterEvent recorded
+// by the engine whose type they are notified of
+type Notification struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	UnitName  string    `json:"unitName,omitempty"`
+	MachineID string    `json:"machineID,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// NewWebhookNotifier returns a WebhookNotifier POSTing the ClusterEvents of
+// the given types to each of the given URLs, signed with the given secret
+// if it is not empty. Without any types, DefaultNotifyEvents are POSTed.
+func NewWebhookNotifier(urls []string, events []string, secret []byte) (*WebhookNotifier, error) {
+	if len(events) == 0 {
+		events = DefaultNotifyEvents
+	}
+	types := make(map[string]bool, len(events))
+	for _, ev := range events {
+		if !registry.IsEventType(ev) {
+			return nil, fmt.Errorf("unknown event type %q", ev)
+		}
+		types[ev] = true
+	}
+
+	return &WebhookNotifier{
+		urls:    urls,
+		events:  types,
+		secret:  secret,
+		client:  &http.Client{Timeout: notifyWebhookTimeout},
+		queue:   make(chan Notification, notifyQueueLength),
+		backoff: notifyRetryBackoff,
+	}, nil
+}
+
+// WebhookNotifier POSTs the ClusterEvents recorded by the engine to
+// webhooks, e.g. to route unit failures to an alerting system. Delivery is
+// asynchronous and retried, so slow webhooks do not hold back scheduling.
+type WebhookNotifier struct {
+	urls    []string
+	events  map[string]bool
+	secret  []byte
+	client  *http.Client
+	queue   chan Notification
+	backoff time.Duration
+}
+
+// SetNotifier makes the engine notify the given WebhookNotifier of the
+// ClusterEvents it records. A nil WebhookNotifier disables notifications.
+func (e *Engine) SetNotifier(n *WebhookNotifier) {
+	e.notifier = n
+}
+
+// Notify queues the delivery of the given ClusterEvent if it is of a type
+// the webhooks are notified of. The ClusterEvent is dropped if the queue
+// is full.
+func (n *WebhookNotifier) Notify(ev registry.ClusterEvent) {
+	if n == nil || !n.events[ev.Type] {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	select {
+	case n.queue <- Notification{Type: ev.Type, Time: ev.Time, UnitName: ev.UnitName, MachineID: ev.MachineID, Reason: ev.Reason}:
+	default:
+		log.Errorf("Notification queue full, dropping %s event", ev.Type)
+		metricNotifications.Inc("dropped")
+	}
+}
+
+// Run delivers queued notifications until the stop channel is closed
+func (n *WebhookNotifier) Run(stop chan bool) {
+	for {
+		select {
+		case <-stop:
+			log.V(1).Info("Halting WebhookNotifier")
+			return
+		case note := <-n.queue:
+			body, err := json.Marshal(note)
+			if err != nil {
+				log.Errorf("Failed encoding %s notification: %v", note.Type, err)
+				continue
+			}
+			for _, url := range n.urls {
+				n.deliver(url, body, stop)
+			}
+		}
+	}
+}
+
+// deliver POSTs the given notification body to the given URL, retrying
+// with backoff until it succeeds, the attempts are exhausted or the stop
+// channel is closed
+func (n *WebhookNotifier) deliver(url string, body []byte, stop chan bool) {
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		err := n.post(url, body)
+		if err == nil {
+			metricNotifications.Inc("delivered")
+			return
+		}
+		if attempt == notifyAttempts {
+			log.Errorf("Giving up notifying %s after %d attempts: %v", url, attempt, err)
+			metricNotifications.Inc("failed")
+			return
+		}
+		log.V(1).Infof("Failed notifying %s, retrying in %v: %v", url, backoff, err)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(url string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		req.Header.Set(NotifySignatureHeader, SignNotification(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+// SignNotification returns the signature of the given notification body
+// sent in the NotifySignatureHeader, against which webhooks can verify that
+// the notification was sent by fleet
+func SignNotification(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReadNotifySecret reads the secret notifications are signed with from the
+// given file, ignoring surrounding whitespace
+func ReadNotifySecret(file string) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	secret := strings.TrimSpace(string(b))
+	if secret == "" {
+		return nil, fmt.Errorf("%s is empty", file)
+	}
+	return []byte(secret), nil
+}
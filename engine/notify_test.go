@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/registry"
+)
+
+func TestNewWebhookNotifier(t *testing.T) {
+	n, err := NewWebhookNotifier([]string{"http://example.com"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, typ := range DefaultNotifyEvents {
+		if !n.events[typ] {
+			t.Errorf("Default event type %s not notified", typ)
+		}
+	}
+
+	if _, err := NewWebhookNotifier([]string{"http://example.com"}, []string{"UnitExploded"}, nil); err == nil {
+		t.Errorf("Expected error for unknown event type")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	secret := []byte("sekrit")
+
+	var mu sync.Mutex
+	var attempts int
+	var got []Notification
+	received := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// the first attempt fails, to be retried
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if sig := r.Header.Get(NotifySignatureHeader); sig != SignNotification(secret, body) {
+			t.Errorf("Unexpected signature %q", sig)
+		}
+		var note Notification
+		if err := json.Unmarshal(body, &note); err != nil {
+			t.Errorf("Unable to decode notification: %v", err)
+		}
+		got = append(got, note)
+		received <- struct{}{}
+	}))
+	defer srv.Close()
+
+	n, err := NewWebhookNotifier([]string{srv.URL}, []string{registry.EventUnitFailed, registry.EventMachineLeft}, secret)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n.backoff = time.Millisecond
+	stop := make(chan bool)
+	defer close(stop)
+	go n.Run(stop)
+
+	at := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	n.Notify(registry.ClusterEvent{Time: at, Type: registry.EventUnitScheduled, UnitName: "foo.service", MachineID: "XXX"})
+	n.Notify(registry.ClusterEvent{Time: at, Type: registry.EventUnitFailed, UnitName: "foo.service", MachineID: "XXX", Reason: "exited"})
+	n.Notify(registry.ClusterEvent{Time: at, Type: registry.EventMachineLeft, MachineID: "XXX"})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for notifications")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []Notification{
+		{Type: registry.EventUnitFailed, Time: at, UnitName: "foo.service", MachineID: "XXX", Reason: "exited"},
+		{Type: registry.EventMachineLeft, Time: at, MachineID: "XXX"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Unexpected notifications:\ngot  %#v\nwant %#v", got, want)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestNilWebhookNotifier(t *testing.T) {
+	var n *WebhookNotifier
+	n.Notify(registry.ClusterEvent{Type: registry.EventUnitFailed})
+}
@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/pkg"
+)
+
+const (
+	// time allowed for a placement webhook to respond
+	placementWebhookTimeout = 5 * time.Second
+)
+
+// A PlacementPlugin implements a site-specific placement policy, such as
+// licensing zones or rack diversity, by filtering and scoring the machines
+// able to run a Job before the scheduling strategy picks one of them.
+type PlacementPlugin interface {
+	// Name identifies the plugin in logs and scheduling failures
+	Name() string
+
+	// Score returns a score for each of the given candidates that may run
+	// the Job, indexed by machine ID. Candidates missing from the result
+	// must not run the Job. The scheduling strategy picks among the
+	// candidates with the highest total score of all plugins. If Score
+	// fails, the Job is not scheduled.
+	Score(j *job.Job, candidates []*agent.AgentState) (map[string]int, error)
+}
+
+var (
+	pluginsMutex sync.Mutex
+	plugins      []PlacementPlugin
+)
+
+// RegisterPlacementPlugin makes the given PlacementPlugin available to
+// engines started afterwards. Sites building their own fleetd register
+// their plugins from an init function.
+func RegisterPlacementPlugin(p PlacementPlugin) {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+	plugins = append(plugins, p)
+}
+
+// PlacementPlugins returns the registered PlacementPlugins, in the order
+// they were registered
+func PlacementPlugins() []PlacementPlugin {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+	return append([]PlacementPlugin(nil), plugins...)
+}
+
+// placementError is returned by a pluginScheduler when its plugins leave
+// no machine to run a Job on. Such Jobs do not preempt other Units, which
+// would bypass the plugins.
+type placementError struct {
+	reason string
+}
+
+func (pe *placementError) Error() string {
+	return pe.reason
+}
+
+func isPlacementError(err error) bool {
+	_, ok := err.(*placementError)
+	return ok
+}
+
+// WithPlacementPlugins returns a Scheduler consulting the registered
+// PlacementPlugins, followed by the given ones, before leaving the decision
+// to the given Scheduler, or the given Scheduler itself if there are no
+// plugins to consult
+func WithPlacementPlugins(sched Scheduler, extra ...PlacementPlugin) Scheduler {
+	ps := append(PlacementPlugins(), extra...)
+	if len(ps) == 0 {
+		return sched
+	}
+	return &pluginScheduler{sched, ps}
+}
+
+type pluginScheduler struct {
+	Scheduler
+	plugins []PlacementPlugin
+}
+
+func (ps *pluginScheduler) Decide(clust *clusterState, j *job.Job) (*decision, error) {
+	candidates := candidateAgents(clust, sortedAgentsByID(clust), j)
+	if len(candidates) == 0 {
+		return ps.Scheduler.Decide(clust, j)
+	}
+
+	scores := make(map[string]int, len(candidates))
+	for _, p := range ps.plugins {
+		pScores, err := p.Score(j, candidates)
+		if err != nil {
+			return nil, &placementError{fmt.Sprintf("placement plugin %s failed: %v", p.Name(), err)}
+		}
+
+		var kept []*agent.AgentState
+		for _, as := range candidates {
+			if score, ok := pScores[as.MState.ID]; ok {
+				scores[as.MState.ID] += score
+				kept = append(kept, as)
+			}
+		}
+		if len(kept) == 0 {
+			return nil, &placementError{fmt.Sprintf("placement plugin %s rejected all agents able to run job", p.Name())}
+		}
+		candidates = kept
+	}
+
+	best := scores[candidates[0].MState.ID]
+	for _, as := range candidates {
+		if scores[as.MState.ID] > best {
+			best = scores[as.MState.ID]
+		}
+	}
+
+	// The scheduling strategy decides among the best candidates only
+	restricted := *clust
+	restricted.placeable = pkg.NewUnsafeSet()
+	for _, as := range candidates {
+		if scores[as.MState.ID] == best {
+			restricted.placeable.Add(as.MState.ID)
+		}
+	}
+	return ps.Scheduler.Decide(&restricted, j)
+}
+
+// NewWebhookPlacementPlugin returns a PlacementPlugin delegating to the
+// given URL. For each Job to schedule, a placementRequest is POSTed to it as
+// JSON, to which it must respond with a placementResponse.
+func NewWebhookPlacementPlugin(url string) PlacementPlugin {
+	return &webhookPlugin{
+		url:    url,
+		client: &http.Client{Timeout: placementWebhookTimeout},
+	}
+}
+
+type webhookPlugin struct {
+	url    string
+	client *http.Client
+}
+
+type placementRequest struct {
+	Unit         string               `json:"unit"`
+	Requirements map[string][]string  `json:"requirements"`
+	Machines     []placementCandidate `json:"machines"`
+}
+
+type placementCandidate struct {
+	ID       string            `json:"id"`
+	PublicIP string            `json:"publicIP"`
+	Metadata map[string]string `json:"metadata"`
+	Units    []string          `json:"units"`
+}
+
+type placementResponse struct {
+	Scores map[string]int `json:"scores"`
+}
+
+func (wp *webhookPlugin) Name() string {
+	return wp.url
+}
+
+func (wp *webhookPlugin) Score(j *job.Job, candidates []*agent.AgentState) (map[string]int, error) {
+	preq := placementRequest{
+		Unit:         j.Name,
+		Requirements: j.Requirements(),
+		Machines:     make([]placementCandidate, len(candidates)),
+	}
+	for i, as := range candidates {
+		units := make([]string, 0, len(as.Units))
+		for name := range as.Units {
+			units = append(units, name)
+		}
+		sort.Strings(units)
+		preq.Machines[i] = placementCandidate{
+			ID:       as.MState.ID,
+			PublicIP: as.MState.PublicIP,
+			Metadata: as.MState.Metadata,
+			Units:    units,
+		}
+	}
+
+	body, err := json.Marshal(preq)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := wp.client.Post(wp.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	var presp placementResponse
+	if err := json.NewDecoder(resp.Body).Decode(&presp); err != nil {
+		return nil, fmt.Errorf("unable to decode response: %v", err)
+	}
+	return presp.Scores, nil
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+)
+
+type testPlacementPlugin struct {
+	scores map[string]int
+	err    error
+}
+
+func (tp *testPlacementPlugin) Name() string {
+	return "test"
+}
+
+func (tp *testPlacementPlugin) Score(j *job.Job, candidates []*agent.AgentState) (map[string]int, error) {
+	return tp.scores, tp.err
+}
+
+func TestPluginSchedulerDecide(t *testing.T) {
+	machines := []machine.MachineState{
+		machine.MachineState{ID: "XXX"},
+		machine.MachineState{ID: "YYY"},
+		machine.MachineState{ID: "ZZZ"},
+	}
+
+	for i, tt := range []struct {
+		plugins   []PlacementPlugin
+		machineID string
+		placeErr  bool
+	}{
+		// omitted machines are filtered out
+		{
+			plugins:   []PlacementPlugin{&testPlacementPlugin{scores: map[string]int{"ZZZ": 0}}},
+			machineID: "ZZZ",
+		},
+		// the strategy decides among the best scores
+		{
+			plugins:   []PlacementPlugin{&testPlacementPlugin{scores: map[string]int{"XXX": 1, "YYY": 5, "ZZZ": 5}}},
+			machineID: "YYY",
+		},
+		// scores of all plugins add up
+		{
+			plugins: []PlacementPlugin{
+				&testPlacementPlugin{scores: map[string]int{"XXX": 1, "YYY": 5, "ZZZ": 5}},
+				&testPlacementPlugin{scores: map[string]int{"XXX": 10, "ZZZ": 2}},
+			},
+			machineID: "XXX",
+		},
+		// unknown machines are ignored
+		{
+			plugins:  []PlacementPlugin{&testPlacementPlugin{scores: map[string]int{"AAA": 1}}},
+			placeErr: true,
+		},
+		{
+			plugins:  []PlacementPlugin{&testPlacementPlugin{err: errors.New("unavailable")}},
+			placeErr: true,
+		},
+	} {
+		sched := &pluginScheduler{&leastLoadedScheduler{}, tt.plugins}
+		clust := newClusterState([]job.Unit{}, []job.ScheduledUnit{}, machines)
+		dec, err := sched.Decide(clust, &job.Job{Name: "foo.service"})
+		if tt.placeErr {
+			if !isPlacementError(err) {
+				t.Errorf("case %d: expected placementError, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if dec.machineID != tt.machineID {
+			t.Errorf("case %d: expected machine %s, got %s", i, tt.machineID, dec.machineID)
+		}
+	}
+}
+
+func TestWithPlacementPlugins(t *testing.T) {
+	sched := &leastLoadedScheduler{}
+	if got := WithPlacementPlugins(sched); got != Scheduler(sched) {
+		t.Errorf("Expected Scheduler to be returned as is without plugins, got %#v", got)
+	}
+	if _, ok := WithPlacementPlugins(sched, &testPlacementPlugin{}).(*pluginScheduler); !ok {
+		t.Errorf("Expected pluginScheduler")
+	}
+}
+
+func TestWebhookPlacementPlugin(t *testing.T) {
+	var got placementRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			t.Errorf("Failed decoding request: %v", err)
+		}
+		rw.Write([]byte(`{"scores":{"XXX":3}}`))
+	}))
+	defer ts.Close()
+
+	as := agent.NewAgentState(&machine.MachineState{ID: "XXX", PublicIP: "10.0.0.1", Metadata: map[string]string{"rack": "a"}})
+	as.Units["db.service"] = &job.Unit{Name: "db.service"}
+	j := &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineMetadata=rack=a")}
+
+	scores, err := NewWebhookPlacementPlugin(ts.URL).Score(j, []*agent.AgentState{as})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(scores, map[string]int{"XXX": 3}) {
+		t.Errorf("Unexpected scores %v", scores)
+	}
+
+	want := placementRequest{
+		Unit:         "foo.service",
+		Requirements: map[string][]string{"MachineMetadata": []string{"rack=a"}},
+		Machines: []placementCandidate{
+			placementCandidate{ID: "XXX", PublicIP: "10.0.0.1", Metadata: map[string]string{"rack": "a"}, Units: []string{"db.service"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected request:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if _, err := NewWebhookPlacementPlugin(failing.URL).Score(j, []*agent.AgentState{as}); err == nil {
+		t.Errorf("Expected error from failing webhook")
+	}
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+)
+
+// Placement describes where the engine would schedule a Unit
+type Placement struct {
+	// MachineID is the machine the Unit would be scheduled to, if any
+	MachineID string
+	// Preempts lists the Units that would be unscheduled from the machine
+	// to make room for the Unit
+	Preempts []string
+	// Reason explains why the Unit would not be scheduled to a machine
+	Reason string
+	// Rejections holds the reason each machine unable to run the Unit is
+	// unable to, indexed by machine ID
+	Rejections map[string]string
+	// Shortfalls holds each constraint of the Unit that each machine unable
+	// to run it fails to meet, indexed by machine ID
+	Shortfalls map[string][]job.Shortfall
+}
+
+// Plan determines where the engine, placing Units with the given scheduling
+// strategy, would schedule the given Unit in the current state of the
+// cluster, without changing it. A Unit of the same name already in the
+// cluster is disregarded.
+func Plan(reg registry.Registry, strategy string, u *job.Unit) (*Placement, error) {
+	sched, err := NewScheduler(strategy)
+	if err != nil {
+		return nil, err
+	}
+	sched = WithPlacementPlugins(sched)
+
+	clust, err := loadClusterState(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(clust.jobs, u.Name)
+	delete(clust.gUnits, u.Name)
+
+	return plan(clust, sched, u), nil
+}
+
+func plan(clust *clusterState, sched Scheduler, u *job.Unit) *Placement {
+	if u.IsTemplate() {
+		return &Placement{Reason: "template units cannot be scheduled, only their instances"}
+	}
+
+	j := &job.Job{
+		Name:        u.Name,
+		Unit:        u.Unit,
+		TargetState: job.JobStateLaunched,
+	}
+	sf := machineShortfalls(clust, j)
+	p := Placement{Rejections: shortfallReasons(sf), Shortfalls: sf}
+
+	if u.IsGlobal() {
+		p.Reason = "global units run on every machine able to run them"
+		return &p
+	}
+
+	if reason := j.UnmetDependency(clust.launched, clust.active); reason != "" {
+		p.Reason = reason
+		return &p
+	}
+
+	if dec, err := sched.Decide(clust, j); err == nil {
+		p.MachineID = dec.machineID
+	} else if pre := preempt(clust, j); pre != nil && !isPlacementError(err) {
+		p.MachineID = pre.machineID
+		p.Preempts = pre.victims
+	} else {
+		p.Reason = err.Error()
+	}
+
+	return &p
+}
+
+// rejections returns the reason each agent of the cluster unable to run
+// the Job is unable to, indexed by machine ID
+func rejections(clust *clusterState, j *job.Job) map[string]string {
+	return shortfallReasons(machineShortfalls(clust, j))
+}
+
+// machineShortfalls returns each constraint of the Job that each agent of
+// the cluster unable to run it fails to meet, indexed by machine ID
+func machineShortfalls(clust *clusterState, j *job.Job) map[string][]job.Shortfall {
+	var all []*agent.AgentState
+	for _, as := range clust.agents() {
+		all = append(all, as)
+	}
+
+	shortfalls := make(map[string][]job.Shortfall)
+	var able []*agent.AgentState
+	for _, as := range all {
+		if sf := as.Shortfalls(j); len(sf) != 0 {
+			shortfalls[as.MState.ID] = sf
+		} else if hasDomainConflict(all, as, j) {
+			shortfalls[as.MState.ID] = []job.Shortfall{{Constraint: job.ConstraintConflictDomain, Reason: "conflicts with a Unit on a machine in the same conflict domain"}}
+		} else {
+			able = append(able, as)
+		}
+	}
+
+	_, unspread := spreadAgents(all, able, j)
+	for id, reason := range unspread {
+		shortfalls[id] = []job.Shortfall{{Constraint: job.ConstraintSpread, Reason: reason}}
+	}
+	return shortfalls
+}
+
+// shortfallReasons combines the Shortfalls of each machine into the reason
+// it is unable to run a Job, indexed by machine ID
+func shortfallReasons(shortfalls map[string][]job.Shortfall) map[string]string {
+	reasons := make(map[string]string, len(shortfalls))
+	for id, sf := range shortfalls {
+		reasons[id] = job.ShortfallReason(sf)
+	}
+	return reasons
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
+)
+
+func TestPlan(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX", Metadata: map[string]string{"region": "us"}, TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}},
+		machine.MachineState{ID: "YYY", Metadata: map[string]string{"region": "eu"}, TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}},
+	})
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "big.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=768"), TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+	})
+
+	for i, tt := range []struct {
+		name     string
+		contents string
+		want     Placement
+	}{
+		{
+			"foo.service",
+			"[X-Fleet]\nMachineMetadata=region=eu",
+			Placement{
+				MachineID:  "YYY",
+				Rejections: map[string]string{"XXX": "local Machine metadata insufficient"},
+			},
+		},
+		{
+			"foo.service",
+			"[X-Fleet]\nMemoryReservation=512",
+			Placement{MachineID: "YYY", Rejections: map[string]string{"XXX": "insufficient memory: requested 512MB, available 256MB"}},
+		},
+		{
+			"foo.service",
+			"[X-Fleet]\nMemoryReservation=512\nMachineMetadata=region=us\nPriority=10",
+			Placement{
+				MachineID: "XXX",
+				Preempts:  []string{"big.service"},
+				Rejections: map[string]string{
+					"XXX": "insufficient memory: requested 512MB, available 256MB",
+					"YYY": "local Machine metadata insufficient",
+				},
+			},
+		},
+		{
+			"foo.service",
+			"[X-Fleet]\nMemoryReservation=2048",
+			Placement{
+				Reason: "no agents able to run job",
+				Rejections: map[string]string{
+					"XXX": "insufficient memory: requested 2048MB, available 256MB",
+					"YYY": "insufficient memory: requested 2048MB, available 1024MB",
+				},
+			},
+		},
+		// all constraints a machine fails to meet are reported
+		{
+			"foo.service",
+			"[X-Fleet]\nMemoryReservation=2048\nMachineMetadata=region=eu",
+			Placement{
+				Reason: "no agents able to run job",
+				Rejections: map[string]string{
+					"XXX": "local Machine metadata insufficient; insufficient memory: requested 2048MB, available 256MB",
+					"YYY": "insufficient memory: requested 2048MB, available 1024MB",
+				},
+			},
+		},
+		// the Unit is planned as if it were not yet in the cluster
+		{
+			"big.service",
+			"[X-Fleet]\nMemoryReservation=1024\nMachineID=XXX",
+			Placement{MachineID: "XXX", Rejections: map[string]string{"YYY": `agent ID "YYY" does not match required "XXX"`}},
+		},
+		{
+			"foo@.service",
+			"",
+			Placement{Reason: "template units cannot be scheduled, only their instances"},
+		},
+	} {
+		u := &job.Unit{Name: tt.name, Unit: newTestUnit(t, tt.contents)}
+		got, err := Plan(reg, "", u)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(shortfallReasons(got.Shortfalls), tt.want.Rejections) && len(tt.want.Rejections) != 0 {
+			t.Errorf("case %d: shortfalls %v do not match rejections", i, got.Shortfalls)
+		}
+		got.Shortfalls = nil
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("case %d: got %#v, want %#v", i, *got, tt.want)
+		}
+	}
+
+	if _, err := Plan(reg, "bogus", &job.Unit{Name: "foo.service"}); err == nil {
+		t.Errorf("Expected error planning with unknown strategy")
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+)
+
+// preemption describes the Units that must be unscheduled from a machine
+// to make room for a higher-priority Job
+type preemption struct {
+	machineID string
+	victims   []string
+}
+
+// jobsByPriority returns the Jobs of the clusterState sorted descending by
+// priority, then ascending by name
+func jobsByPriority(clust *clusterState) []*job.Job {
+	jobs := make([]*job.Job, 0, len(clust.jobs))
+	for _, j := range clust.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Sort(prioritizedJobs(jobs))
+	return jobs
+}
+
+type prioritizedJobs []*job.Job
+
+func (pj prioritizedJobs) Len() int      { return len(pj) }
+func (pj prioritizedJobs) Swap(i, j int) { pj[i], pj[j] = pj[j], pj[i] }
+
+func (pj prioritizedJobs) Less(i, j int) bool {
+	pi, pk := pj[i].Priority(), pj[j].Priority()
+	return pi > pk || (pi == pk && pj[i].Name < pj[j].Name)
+}
+
+// evictionOrder sorts Jobs ascending by priority, then by name
+type evictionOrder []*job.Job
+
+func (eo evictionOrder) Len() int      { return len(eo) }
+func (eo evictionOrder) Swap(i, j int) { eo[i], eo[j] = eo[j], eo[i] }
+
+func (eo evictionOrder) Less(i, j int) bool {
+	pi, pk := eo[i].Priority(), eo[j].Priority()
+	return pi < pk || (pi == pk && eo[i].Name < eo[j].Name)
+}
+
+// preempt attempts to find a machine on which the given Job could run if
+// some lower-priority Units were unscheduled from it. The machine requiring
+// the fewest evictions is chosen. Only non-global Units with a strictly
+// lower priority than the Job are considered for eviction, lowest priority
+// first. If no such machine exists, nil is returned.
+func preempt(clust *clusterState, j *job.Job) *preemption {
+	prio := j.Priority()
+
+	var best *preemption
+	for _, as := range sortedAgentsByID(clust) {
+		victims := preemptionCandidates(clust, as, prio)
+		if len(victims) == 0 {
+			continue
+		}
+
+		var evicted []string
+		for _, v := range victims {
+			delete(as.Units, v)
+			evicted = append(evicted, v)
+
+			if able, _ := as.AbleToRun(j); able {
+				if best == nil || len(evicted) < len(best.victims) {
+					best = &preemption{machineID: as.MState.ID, victims: evicted}
+				}
+				break
+			}
+		}
+	}
+
+	return best
+}
+
+// preemptionCandidates returns the names of the Units scheduled to the agent
+// that may be evicted in favor of a Job of the given priority, in the order
+// in which they should be evicted
+func preemptionCandidates(clust *clusterState, as *agent.AgentState, prio int) []string {
+	var candidates []*job.Job
+	for name := range as.Units {
+		cj, ok := clust.jobs[name]
+		if !ok {
+			// global Units cannot be unscheduled
+			continue
+		}
+		if cj.Priority() < prio {
+			candidates = append(candidates, cj)
+		}
+	}
+
+	sort.Sort(evictionOrder(candidates))
+
+	names := make([]string, len(candidates))
+	for i, cj := range candidates {
+		names[i] = cj.Name
+	}
+	return names
+}
+
+func sortedAgentsByID(clust *clusterState) []*agent.AgentState {
+	agents := clust.agents()
+
+	ids := make([]string, 0, len(agents))
+	for id := range agents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	sorted := make([]*agent.AgentState, len(ids))
+	for i, id := range ids {
+		sorted[i] = agents[id]
+	}
+	return sorted
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+)
+
+func TestJobsByPriority(t *testing.T) {
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "b.service", Unit: newTestUnit(t, "[X-Fleet]\nPriority=5")},
+			job.Unit{Name: "a.service", Unit: newTestUnit(t, "[X-Fleet]\nPriority=5")},
+			job.Unit{Name: "c.service", Unit: newTestUnit(t, "")},
+			job.Unit{Name: "d.service", Unit: newTestUnit(t, "[X-Fleet]\nPriority=10")},
+		},
+		[]job.ScheduledUnit{},
+		[]machine.MachineState{},
+	)
+
+	var got []string
+	for _, j := range jobsByPriority(clust) {
+		got = append(got, j.Name)
+	}
+
+	want := []string{"d.service", "a.service", "b.service", "c.service"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestPreempt(t *testing.T) {
+	mem := func(mb string) string {
+		return "[X-Fleet]\nMemoryReservation=" + mb
+	}
+
+	clust := newClusterState(
+		[]job.Unit{
+			// XXX: two small low-priority units
+			job.Unit{Name: "x1.service", Unit: newTestUnit(t, mem("512")+"\nPriority=1"), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "x2.service", Unit: newTestUnit(t, mem("512")), TargetState: job.JobStateLaunched},
+			// YYY: one large low-priority unit
+			job.Unit{Name: "y1.service", Unit: newTestUnit(t, mem("1024")), TargetState: job.JobStateLaunched},
+			// ZZZ: one large unit of equal priority, which cannot be preempted
+			job.Unit{Name: "z1.service", Unit: newTestUnit(t, mem("1024")+"\nPriority=10"), TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "x1.service", TargetMachineID: "XXX"},
+			job.ScheduledUnit{Name: "x2.service", TargetMachineID: "XXX"},
+			job.ScheduledUnit{Name: "y1.service", TargetMachineID: "YYY"},
+			job.ScheduledUnit{Name: "z1.service", TargetMachineID: "ZZZ"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}},
+			machine.MachineState{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}},
+			machine.MachineState{ID: "ZZZ", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}},
+		},
+	)
+
+	for i, tt := range []struct {
+		contents string
+		want     *preemption
+	}{
+		// fewest evictions wins
+		{mem("1024") + "\nPriority=10", &preemption{machineID: "YYY", victims: []string{"y1.service"}}},
+		// lowest priority is evicted first
+		{mem("512") + "\nPriority=10", &preemption{machineID: "XXX", victims: []string{"x2.service"}}},
+		// nothing to preempt for a Job of default priority
+		{mem("1024"), nil},
+		// nothing to preempt if no machine could ever fit the Job
+		{mem("4096") + "\nPriority=10", nil},
+	} {
+		j := &job.Job{Name: "new.service", Unit: newTestUnit(t, tt.contents)}
+		got := preempt(clust, j)
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("case %d: expected %#v, got %#v", i, tt.want, got)
+		}
+	}
+}
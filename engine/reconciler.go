@@ -1,15 +1,31 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
 )
 
 const (
 	taskTypeUnscheduleUnit      = "UnscheduleUnit"
 	taskTypeAttemptScheduleUnit = "AttemptScheduleUnit"
+
+	// prefix of the reason of tasks unscheduling preempted Units
+	taskReasonPreempted = "preempted by higher-priority"
+	taskReasonFailed    = "unit failed on target"
+	taskReasonLost      = "went away"
+
+	// number of tasks queued for each worker before deciding further
+	// tasks blocks
+	taskQueueLength = 64
 )
 
 type task struct {
@@ -23,30 +39,78 @@ func (t *task) String() string {
 	return fmt.Sprintf("{Type: %s, JobName: %s, MachineID: %s, Reason: %q}", t.Type, t.JobName, t.MachineID, t.Reason)
 }
 
-func NewReconciler() *Reconciler {
+// NewReconciler creates a Reconciler placing Jobs with the given Scheduler.
+// If evictOnMetadataChange is set, Jobs are unscheduled from machines whose
+// metadata no longer satisfies their MachineMetadata requirements. Jobs of
+// machines that went away are moved right away unless they declare a
+// RescheduleDelay.
+func NewReconciler(sched Scheduler, evictOnMetadataChange bool) *Reconciler {
 	return &Reconciler{
-		sched: &leastLoadedScheduler{},
+		sched:                 sched,
+		evictOnMetadataChange: evictOnMetadataChange,
+		concurrency:           1,
 	}
 }
 
 type Reconciler struct {
-	sched Scheduler
+	sched                 Scheduler
+	evictOnMetadataChange bool
+
+	// concurrency is the number of tasks carried out at once
+	concurrency int
+
+	// rescheduleDelay is how long Jobs declaring no RescheduleDelay are
+	// left scheduled to a machine that went away
+	rescheduleDelay time.Duration
 }
 
 func (r *Reconciler) Reconcile(e *Engine, stop chan struct{}) {
 	log.V(1).Infof("Polling Registry for actionable work")
 
+	if e.holdsClusterDuties() {
+		e.scaleTemplates()
+		e.runCronJobs()
+		e.collectGarbage(time.Now())
+	} else {
+		e.gc.forget()
+	}
+
 	clust, err := e.clusterState()
 	if err != nil {
 		log.Errorf("Failed getting current cluster state: %v", err)
 		return
 	}
+	clust.shards = e.shardSet()
 
-	for t := range r.calculateClusterTasks(clust, stop) {
-		err = doTask(t, e)
-		if err != nil {
-			log.Errorf("Failed resolving task: task=%s err=%v", t, err)
-		}
+	if e.holdsClusterDuties() {
+		e.trackMachines(clust)
+	} else {
+		e.machines = nil
+	}
+	now := time.Now()
+	e.trackLostMachines(clust, now)
+	e.trackLifetimes(clust, now)
+	e.enforceLifetimes(clust, now)
+	e.flaps.track(clust, now)
+	clust.dirty = e.dirtySince(clust)
+
+	// The next reconciliation only builds on this one if all of its
+	// tasks were carried out
+	e.snapshot = nil
+	ok := doTasks(r.calculateClusterTasks(clust, stop), e, r.concurrency)
+
+	// An interrupted reconciliation has not considered all Jobs
+	select {
+	case <-stop:
+		return
+	default:
+	}
+
+	e.recordBackoffs(clust.backedOff)
+	e.recordRelaxations(clust)
+	e.saveRejections(clust.rejected)
+	if ok {
+		e.snapshot = newClusterSnapshot(clust)
 	}
 }
 
@@ -70,10 +134,24 @@ func (r *Reconciler) calculateClusterTasks(clust *clusterState, stopchan chan st
 		agents := clust.agents()
 
 		for _, j := range clust.jobs {
-			if !j.Scheduled() {
+			if !j.Scheduled() || !clust.schedules(j.Name) {
 				continue
 			}
 
+			// Jobs of machines that went away are re-evaluated
+			// until they are moved
+			if clust.dirty != nil {
+				as, ok := agents[j.TargetMachineID]
+				_, lost := clust.lost[j.TargetMachineID]
+				if !lost && !clust.dirty.affects(j, ok && as.MState.Draining) {
+					continue
+				}
+			}
+
+			// moved is set if the Job is moved because it failed
+			// or its machine went away, rather than on request, and
+			// lost if its machine went away
+			var moved, lost bool
 			decide := func() (unschedule bool, reason string) {
 				if j.TargetState == job.JobStateInactive {
 					unschedule = true
@@ -81,18 +159,54 @@ func (r *Reconciler) calculateClusterTasks(clust *clusterState, stopchan chan st
 					return
 				}
 
+				if clust.completed.Contains(j.Name) {
+					unschedule = true
+					reason = "batch unit completed"
+					return
+				}
+
 				as, ok := agents[j.TargetMachineID]
 				if !ok {
+					// Machines that went away briefly keep their
+					// Jobs
+					if wait := clust.rescheduleWait(j, r.rescheduleDelay, time.Now()); wait > 0 {
+						log.V(1).Infof("Leaving Job(%s) on lost Machine(%s) for another %s", j.Name, j.TargetMachineID, wait)
+						return
+					}
+					unschedule, moved, lost = true, true, true
+					reason = fmt.Sprintf("target Machine(%s) %s", j.TargetMachineID, taskReasonLost)
+					return
+				}
+
+				// Jobs that failed persistently are moved elsewhere
+				if freason, ok := as.Failures[j.Name]; ok {
+					unschedule, moved = true, true
+					reason = fmt.Sprintf("%s Machine(%s): %s", taskReasonFailed, j.TargetMachineID, freason)
+					return
+				}
+
+				// Jobs are only moved off a draining machine once
+				// another machine is able to take them
+				if as.MState.Draining {
+					if _, err := r.sched.Decide(clust, j); err != nil {
+						log.V(1).Infof("Leaving Job(%s) on draining Machine(%s): %v", j.Name, j.TargetMachineID, err)
+						return
+					}
 					unschedule = true
-					reason = fmt.Sprintf("target Machine(%s) went away", j.TargetMachineID)
+					reason = fmt.Sprintf("target Machine(%s) is draining", j.TargetMachineID)
 					return
 				}
 
-				var able bool
-				if able, reason = as.AbleToRun(j); !able {
+				// Jobs stay on machines whose metadata, including
+				// their pool, has changed under them unless eviction
+				// is enabled
+				sf := as.Shortfalls(j)
+				if !r.evictOnMetadataChange {
+					sf = ignoreShortfalls(sf, job.ConstraintMetadata, job.ConstraintPool)
+				}
+				if len(sf) != 0 {
 					unschedule = true
 					reason = fmt.Sprintf("target Machine(%s) unable to run unit", j.TargetMachineID)
-					return
 				}
 
 				return
@@ -107,18 +221,118 @@ func (r *Reconciler) calculateClusterTasks(clust *clusterState, stopchan chan st
 				return
 			}
 
+			if lost {
+				clust.displace(j.Name, j.TargetMachineID)
+			}
 			clust.unschedule(j.Name)
+			if moved {
+				clust.moved(j.Name, time.Now())
+			}
 		}
 
-		for _, j := range clust.jobs {
-			if j.Scheduled() || j.TargetState == job.JobStateInactive {
+		// Stacks are placed as a whole once reaching the first of
+		// their pending Jobs, and so are Jobs linked by MachineOf,
+		// onto a single machine
+		placedStacks := make(map[string]bool)
+		placedGroups := make(map[string]bool)
+		groups := newPeerGroups(clust)
+
+		// Higher-priority Jobs are placed first so they are not
+		// crowded out by lower-priority Jobs in the same pass, and the
+		// Jobs of lost machines before other Jobs of their priority
+		for _, j := range placementOrder(clust) {
+			if j.Scheduled() || j.TargetState == job.JobStateInactive || clust.completed.Contains(j.Name) || !clust.schedules(j.Name) {
+				continue
+			}
+
+			// Jobs moved too often wait out their backoff, and so do
+			// the Stacks and peer groups they belong to
+			if until, ok := clust.backoff[j.Name]; ok {
+				log.V(1).Infof("Not rescheduling Job(%s) before %s", j.Name, until)
+				clust.backOff(j.Name, until)
+				if s, ok := clust.stacks[j.Name]; ok {
+					placedStacks[s.Name] = true
+				}
+				if names := groups.group(j.Name); names != nil {
+					placedGroups[names[0]] = true
+				}
+				continue
+			}
+
+			if s, ok := clust.stacks[j.Name]; ok {
+				if placedStacks[s.Name] {
+					continue
+				}
+				placedStacks[s.Name] = true
+				if clust.dirty != nil && !clust.dirty.reconsidersStack(clust, s) {
+					for _, name := range s.Units {
+						clust.dirty.keepRejection(clust, name)
+					}
+					continue
+				}
+				if !r.scheduleStack(clust, s, send) {
+					return
+				}
+				continue
+			}
+
+			if names := groups.group(j.Name); names != nil {
+				if placedGroups[names[0]] {
+					continue
+				}
+				placedGroups[names[0]] = true
+				if clust.dirty != nil && !clust.dirty.reconsidersGroup(clust, names) {
+					for _, name := range names {
+						clust.dirty.keepRejection(clust, name)
+					}
+					continue
+				}
+				if !r.schedulePeerGroup(clust, names, send) {
+					return
+				}
+				continue
+			}
+
+			if clust.dirty != nil && !clust.dirty.reconsiders(j) {
+				clust.dirty.keepRejection(clust, j.Name)
+				continue
+			}
+
+			if reason := j.UnmetDependency(clust.launched, clust.active); reason != "" {
+				log.V(1).Infof("Not scheduling Job(%s) yet: %s", j.Name, reason)
+				clust.reject(j.Name, reason, nil)
+				continue
+			}
+
+			if reason := clust.exceedsQuota(j); reason != "" {
+				log.V(1).Infof("Not scheduling Job(%s): %s", j.Name, reason)
+				clust.reject(j.Name, reason, nil)
 				continue
 			}
 
 			dec, err := r.sched.Decide(clust, j)
 			if err != nil {
-				log.V(1).Infof("Unable to schedule Job(%s): %v", j.Name, err)
-				continue
+				var pre *preemption
+				if !isPlacementError(err) {
+					pre = preempt(clust, j)
+				}
+				if pre == nil {
+					log.V(1).Infof("Unable to schedule Job(%s): %v", j.Name, err)
+					metricFailedPlacements.Inc()
+					clust.rejectShortfalls(j.Name, err.Error(), machineShortfalls(clust, j))
+					continue
+				}
+
+				for _, victim := range pre.victims {
+					log.Infof("Preempting Job(%s) on Machine(%s) in favor of Job(%s)", victim, pre.machineID, j.Name)
+					reason := fmt.Sprintf("%s Unit(%s)", taskReasonPreempted, j.Name)
+					if !send(taskTypeUnscheduleUnit, reason, victim, pre.machineID) {
+						return
+					}
+					clust.unschedule(victim)
+				}
+
+				dec = &decision{machineID: pre.machineID}
 			}
 
 			reason := fmt.Sprintf("target state %s and unit not scheduled", j.TargetState)
@@ -127,25 +341,217 @@ func (r *Reconciler) calculateClusterTasks(clust *clusterState, stopchan chan st
 			}
 
 			clust.schedule(j.Name, dec.machineID)
+
+			// Jobs replaced by this Job must make way for it and
+			// are rescheduled elsewhere
+			for _, rName := range j.Replaces() {
+				rj, ok := clust.jobs[rName]
+				if !ok || !rj.Scheduled() || rj.TargetMachineID != dec.machineID {
+					continue
+				}
+
+				reason := fmt.Sprintf("replaced by Unit(%s)", j.Name)
+				if !send(taskTypeUnscheduleUnit, reason, rName, dec.machineID) {
+					return
+				}
+				clust.unschedule(rName)
+			}
 		}
 	}()
 
 	return
 }
 
+// scheduleStack schedules all pending Jobs of the given Stack or, if they
+// cannot all be placed, records why none of them are scheduled. It returns
+// false if sending a task was interrupted.
+func (r *Reconciler) scheduleStack(clust *clusterState, s *registry.Stack, send func(typ, reason, jName, machID string) bool) bool {
+	pending, reason := pendingStackJobs(clust, s)
+	if reason != "" {
+		log.V(1).Infof("Not scheduling Stack(%s) yet: %s", s.Name, reason)
+		for _, name := range s.Units {
+			if j, ok := clust.jobs[name]; ok && !j.Scheduled() && j.TargetState != job.JobStateInactive {
+				clust.reject(name, reason, nil)
+			}
+		}
+		return true
+	}
+
+	if rej := scheduleStack(clust, r.sched, s, pending); rej != nil {
+		log.V(1).Infof("Unable to schedule Stack(%s): %s", s.Name, rej.reason)
+		metricFailedPlacements.Inc()
+		for _, j := range pending {
+			var machines map[string]string
+			if j.Name == rej.jobName {
+				machines = rej.machines
+			}
+			clust.reject(j.Name, rej.reason, machines)
+		}
+		return true
+	}
+
+	for _, j := range pending {
+		reason := fmt.Sprintf("target state %s and unit not scheduled, scheduled with stack %s", j.TargetState, s.Name)
+		if !send(taskTypeAttemptScheduleUnit, reason, j.Name, j.TargetMachineID) {
+			return false
+		}
+	}
+	return true
+}
+
+// schedulePeerGroup schedules all pending Jobs of the named peer group to
+// one machine or, if no machine is able to run all of them, records why none
+// of them are scheduled. It returns false if sending a task was interrupted.
+func (r *Reconciler) schedulePeerGroup(clust *clusterState, names []string, send func(typ, reason, jName, machID string) bool) bool {
+	pending, anchor, reason := pendingGroupJobs(clust, names)
+	if len(pending) == 0 {
+		return true
+	}
+	if reason != "" {
+		log.V(1).Infof("Not scheduling peer group %v yet: %s", names, reason)
+		for _, j := range pending {
+			clust.reject(j.Name, reason, nil)
+		}
+		return true
+	}
+
+	if rej := scheduleGroup(clust, r.sched, pending, anchor); rej != nil {
+		log.V(1).Infof("Unable to schedule peer group %v: %s", names, rej.reason)
+		metricFailedPlacements.Inc()
+		for _, j := range pending {
+			clust.reject(j.Name, rej.reason, rej.machines)
+		}
+		return true
+	}
+
+	for _, j := range pending {
+		reason := fmt.Sprintf("target state %s and unit not scheduled, scheduled with its peers", j.TargetState)
+		if !send(taskTypeAttemptScheduleUnit, reason, j.Name, j.TargetMachineID) {
+			return false
+		}
+	}
+	return true
+}
+
+// doTasks carries out the given tasks with up to concurrency workers, so
+// that slow Registry writes do not hold up the tasks queued behind them.
+// The tasks of each Unit are carried out by the same worker, in the order
+// they were decided. Unscheduling tasks decided before a scheduling task
+// are carried out before it, as the Unit scheduled may rely on the room
+// they free, e.g. when preempting other Units. It returns whether all
+// tasks were carried out.
+func doTasks(tasks chan *task, e *Engine, concurrency int) bool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg, inflight sync.WaitGroup
+	var failed int32
+	queues := make([]chan *task, concurrency)
+	for i := range queues {
+		queues[i] = make(chan *task, taskQueueLength)
+		wg.Add(1)
+		go func(queue chan *task) {
+			defer wg.Done()
+			for t := range queue {
+				if err := doTask(t, e); err != nil {
+					log.Errorf("Failed resolving task: task=%s err=%v", t, err)
+					atomic.StoreInt32(&failed, 1)
+				}
+				inflight.Done()
+			}
+		}(queues[i])
+	}
+
+	// unscheduling is set while unscheduling tasks may be in flight
+	var unscheduling bool
+	for t := range tasks {
+		switch t.Type {
+		case taskTypeUnscheduleUnit:
+			unscheduling = true
+		case taskTypeAttemptScheduleUnit:
+			if unscheduling {
+				inflight.Wait()
+				unscheduling = false
+			}
+		}
+		inflight.Add(1)
+		queues[taskWorker(t.JobName, concurrency)] <- t
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	return atomic.LoadInt32(&failed) == 0
+}
+
+// taskWorker returns which of the given number of workers carries out the
+// tasks of the named Unit
+func taskWorker(name string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % uint32(workers))
+}
+
 func doTask(t *task, e *Engine) (err error) {
 	switch t.Type {
 	case taskTypeUnscheduleUnit:
 		err = e.unscheduleUnit(t.JobName, t.MachineID)
 	case taskTypeAttemptScheduleUnit:
-		e.attemptScheduleUnit(t.JobName, t.MachineID)
+		if !e.attemptScheduleUnit(t.JobName, t.MachineID) {
+			err = errors.New("scheduling decision not persisted")
+		}
 	default:
 		err = fmt.Errorf("unrecognized task type %q", t.Type)
 	}
 
 	if err == nil {
 		log.Infof("EngineReconciler completed task: %s", t)
+		metricTasks.Inc(t.Type)
+		e.recordEvent(taskEvent(t))
 	}
 
 	return
 }
+
+// taskEvent returns the ClusterEvent describing a completed task
+func taskEvent(t *task) registry.ClusterEvent {
+	ev := registry.ClusterEvent{
+		Type:      registry.EventUnitScheduled,
+		UnitName:  t.JobName,
+		MachineID: t.MachineID,
+		Reason:    t.Reason,
+	}
+	if t.Type == taskTypeUnscheduleUnit {
+		ev.Type = registry.EventUnitUnscheduled
+		switch {
+		case strings.HasPrefix(t.Reason, taskReasonPreempted):
+			ev.Type = registry.EventUnitPreempted
+		case strings.HasPrefix(t.Reason, taskReasonFailed):
+			ev.Type = registry.EventUnitFailed
+		case strings.HasSuffix(t.Reason, taskReasonLost):
+			ev.Type = registry.EventUnitRescheduled
+		}
+	}
+	return ev
+}
+
+// ignoreShortfalls returns the given Shortfalls but those of the given
+// constraints
+func ignoreShortfalls(sf []job.Shortfall, constraints ...string) []job.Shortfall {
+	var kept []job.Shortfall
+	for _, s := range sf {
+		ignored := false
+		for _, c := range constraints {
+			if s.Constraint == c {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
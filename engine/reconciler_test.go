@@ -1,11 +1,18 @@
 package engine
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
 )
 
 func TestCalculateClusterTasks(t *testing.T) {
@@ -140,10 +147,210 @@ func TestCalculateClusterTasks(t *testing.T) {
 				},
 			},
 		},
+
+		// preempt lower-priority Jobs if no machine has room
+		{
+			clust: newClusterState(
+				[]job.Unit{
+					job.Unit{
+						Name:        "high.service",
+						Unit:        newTestUnit(t, "[X-Fleet]\nPriority=10\nMemoryReservation=1024"),
+						TargetState: job.JobStateLaunched,
+					},
+					job.Unit{
+						Name:        "low.service",
+						Unit:        newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024"),
+						TargetState: job.JobStateLaunched,
+					},
+				},
+				[]job.ScheduledUnit{
+					job.ScheduledUnit{
+						Name:            "low.service",
+						State:           &jsLaunched,
+						TargetMachineID: "XXX",
+					},
+				},
+				[]machine.MachineState{
+					machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1280}},
+				},
+			),
+			tasks: []*task{
+				&task{
+					Type:      taskTypeUnscheduleUnit,
+					Reason:    "preempted by higher-priority Unit(high.service)",
+					JobName:   "low.service",
+					MachineID: "XXX",
+				},
+				&task{
+					Type:      taskTypeAttemptScheduleUnit,
+					Reason:    "target state launched and unit not scheduled",
+					JobName:   "high.service",
+					MachineID: "XXX",
+				},
+			},
+		},
+
+		// move Jobs off a draining machine
+		{
+			clust: newClusterState(
+				[]job.Unit{
+					job.Unit{
+						Name:        "foo.service",
+						TargetState: job.JobStateLaunched,
+					},
+				},
+				[]job.ScheduledUnit{
+					job.ScheduledUnit{
+						Name:            "foo.service",
+						State:           &jsLaunched,
+						TargetMachineID: "XXX",
+					},
+				},
+				[]machine.MachineState{
+					machine.MachineState{ID: "XXX", Cordoned: true, Draining: true},
+					machine.MachineState{ID: "YYY"},
+				},
+			),
+			tasks: []*task{
+				&task{
+					Type:      taskTypeUnscheduleUnit,
+					Reason:    "target Machine(XXX) is draining",
+					JobName:   "foo.service",
+					MachineID: "XXX",
+				},
+				&task{
+					Type:      taskTypeAttemptScheduleUnit,
+					Reason:    "target state launched and unit not scheduled",
+					JobName:   "foo.service",
+					MachineID: "YYY",
+				},
+			},
+		},
+
+		// leave Jobs on a draining machine if nowhere else can take them
+		{
+			clust: newClusterState(
+				[]job.Unit{
+					job.Unit{
+						Name:        "foo.service",
+						Unit:        newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024"),
+						TargetState: job.JobStateLaunched,
+					},
+				},
+				[]job.ScheduledUnit{
+					job.ScheduledUnit{
+						Name:            "foo.service",
+						State:           &jsLaunched,
+						TargetMachineID: "XXX",
+					},
+				},
+				[]machine.MachineState{
+					machine.MachineState{ID: "XXX", Cordoned: true, Draining: true},
+					machine.MachineState{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 512}},
+					machine.MachineState{ID: "ZZZ", Cordoned: true},
+				},
+			),
+			tasks: []*task{},
+		},
+
+		// move Jobs replaced by a newly-scheduled Job elsewhere
+		{
+			clust: newClusterState(
+				[]job.Unit{
+					job.Unit{
+						Name:        "new.service",
+						Unit:        newTestUnit(t, "[X-Fleet]\nReplaces=old.service\nMachineID=XXX"),
+						TargetState: job.JobStateLaunched,
+					},
+					job.Unit{
+						Name:        "old.service",
+						TargetState: job.JobStateLaunched,
+					},
+				},
+				[]job.ScheduledUnit{
+					job.ScheduledUnit{
+						Name:            "old.service",
+						State:           &jsLaunched,
+						TargetMachineID: "XXX",
+					},
+				},
+				[]machine.MachineState{
+					machine.MachineState{ID: "XXX"},
+					machine.MachineState{ID: "YYY"},
+				},
+			),
+			tasks: []*task{
+				&task{
+					Type:      taskTypeAttemptScheduleUnit,
+					Reason:    "target state launched and unit not scheduled",
+					JobName:   "new.service",
+					MachineID: "XXX",
+				},
+				&task{
+					Type:      taskTypeUnscheduleUnit,
+					Reason:    "replaced by Unit(new.service)",
+					JobName:   "old.service",
+					MachineID: "XXX",
+				},
+				&task{
+					Type:      taskTypeAttemptScheduleUnit,
+					Reason:    "target state launched and unit not scheduled",
+					JobName:   "old.service",
+					MachineID: "YYY",
+				},
+			},
+		},
+
+		// replaced Jobs may not remain alongside their replacement
+		{
+			clust: newClusterState(
+				[]job.Unit{
+					job.Unit{
+						Name:        "new.service",
+						Unit:        newTestUnit(t, "[X-Fleet]\nReplaces=old.service"),
+						TargetState: job.JobStateLaunched,
+					},
+					job.Unit{
+						Name:        "old.service",
+						TargetState: job.JobStateLaunched,
+					},
+				},
+				[]job.ScheduledUnit{
+					job.ScheduledUnit{
+						Name:            "new.service",
+						State:           &jsLaunched,
+						TargetMachineID: "XXX",
+					},
+					job.ScheduledUnit{
+						Name:            "old.service",
+						State:           &jsLaunched,
+						TargetMachineID: "XXX",
+					},
+				},
+				[]machine.MachineState{
+					machine.MachineState{ID: "XXX"},
+					machine.MachineState{ID: "YYY"},
+				},
+			),
+			tasks: []*task{
+				&task{
+					Type:      taskTypeUnscheduleUnit,
+					Reason:    "target Machine(XXX) unable to run unit",
+					JobName:   "old.service",
+					MachineID: "XXX",
+				},
+				&task{
+					Type:      taskTypeAttemptScheduleUnit,
+					Reason:    "target state launched and unit not scheduled",
+					JobName:   "old.service",
+					MachineID: "YYY",
+				},
+			},
+		},
 	}
 
 	for i, tt := range tests {
-		r := NewReconciler()
+		r := NewReconciler(&leastLoadedScheduler{}, true)
 		tasks := make([]*task, 0)
 		for tsk := range r.calculateClusterTasks(tt.clust, make(chan struct{})) {
 			tasks = append(tasks, tsk)
@@ -154,3 +361,375 @@ func TestCalculateClusterTasks(t *testing.T) {
 		}
 	}
 }
+
+func TestCalculateClusterTasksMetadataChange(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	newClust := func(contents string) *clusterState {
+		return newClusterState(
+			[]job.Unit{
+				job.Unit{
+					Name:        "foo.service",
+					Unit:        newTestUnit(t, contents),
+					TargetState: job.JobStateLaunched,
+				},
+			},
+			[]job.ScheduledUnit{
+				job.ScheduledUnit{
+					Name:            "foo.service",
+					State:           &jsLaunched,
+					TargetMachineID: "XXX",
+				},
+			},
+			[]machine.MachineState{
+				machine.MachineState{ID: "XXX", Metadata: map[string]string{"region": "us-west"}},
+				machine.MachineState{ID: "YYY", Metadata: map[string]string{"region": "us-east"}},
+			},
+		)
+	}
+
+	unschedule := []*task{
+		&task{
+			Type:      taskTypeUnscheduleUnit,
+			Reason:    "target Machine(XXX) unable to run unit",
+			JobName:   "foo.service",
+			MachineID: "XXX",
+		},
+		&task{
+			Type:      taskTypeAttemptScheduleUnit,
+			Reason:    "target state launched and unit not scheduled",
+			JobName:   "foo.service",
+			MachineID: "YYY",
+		},
+	}
+
+	for i, tt := range []struct {
+		contents string
+		evict    bool
+		tasks    []*task
+	}{
+		{
+			contents: "[X-Fleet]\nMachineMetadata=region=us-east",
+			evict:    false,
+			tasks:    []*task{},
+		},
+		// other constraints are still enforced without eviction
+		{
+			contents: "[X-Fleet]\nMachineMetadata=region=us-east\nMachineID=YYY",
+			evict:    false,
+			tasks:    unschedule,
+		},
+		{
+			contents: "[X-Fleet]\nMachineMetadata=region=us-east",
+			evict:    true,
+			tasks:    unschedule,
+		},
+	} {
+		r := NewReconciler(&leastLoadedScheduler{}, tt.evict)
+		tasks := make([]*task, 0)
+		for tsk := range r.calculateClusterTasks(newClust(tt.contents), make(chan struct{})) {
+			tasks = append(tasks, tsk)
+		}
+
+		if !reflect.DeepEqual(tt.tasks, tasks) {
+			t.Errorf("case %d: task mismatch\nexpected %v\n got %v", i, tt.tasks, tasks)
+		}
+	}
+}
+
+func TestCalculateClusterTasksUnitFailure(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{
+				Name:        "foo.service",
+				TargetState: job.JobStateLaunched,
+			},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{
+				Name:            "foo.service",
+				State:           &jsLaunched,
+				TargetMachineID: "XXX",
+			},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX"},
+			machine.MachineState{ID: "YYY"},
+		},
+	)
+	clust.failures = map[string]map[string]string{
+		"foo.service": map[string]string{"XXX": "failed 4 times within 5m0s"},
+	}
+
+	want := []*task{
+		&task{
+			Type:      taskTypeUnscheduleUnit,
+			Reason:    "unit failed on target Machine(XXX): failed 4 times within 5m0s",
+			JobName:   "foo.service",
+			MachineID: "XXX",
+		},
+		// the machine the unit failed on is avoided
+		&task{
+			Type:      taskTypeAttemptScheduleUnit,
+			Reason:    "target state launched and unit not scheduled",
+			JobName:   "foo.service",
+			MachineID: "YYY",
+		},
+	}
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	tasks := make([]*task, 0)
+	for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+		tasks = append(tasks, tsk)
+	}
+
+	if !reflect.DeepEqual(want, tasks) {
+		t.Errorf("task mismatch\nexpected %v\n got %v", want, tasks)
+	}
+}
+
+func TestCalculateClusterTasksBatchCompleted(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	batch := newTestUnit(t, "[X-Fleet]\nBatch=true")
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "done.service", TargetState: job.JobStateLaunched, Unit: batch},
+			job.Unit{Name: "moved.service", TargetState: job.JobStateLaunched, Unit: batch},
+			job.Unit{Name: "failed.service", TargetState: job.JobStateLaunched, Unit: batch},
+			// only batch units stay completed
+			job.Unit{Name: "web.service", TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "done.service", State: &jsLaunched, TargetMachineID: "XXX"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX"},
+		},
+	)
+	clust.setCompletions([]registry.UnitCompletion{
+		registry.UnitCompletion{Name: "done.service", Result: "success"},
+		registry.UnitCompletion{Name: "moved.service", Result: "success"},
+		registry.UnitCompletion{Name: "failed.service", Result: "exit-code", ExitCode: 1},
+		registry.UnitCompletion{Name: "web.service", Result: "success"},
+	})
+
+	want := []*task{
+		&task{
+			Type:      taskTypeUnscheduleUnit,
+			Reason:    "batch unit completed",
+			JobName:   "done.service",
+			MachineID: "XXX",
+		},
+		&task{
+			Type:      taskTypeAttemptScheduleUnit,
+			Reason:    "target state launched and unit not scheduled",
+			JobName:   "failed.service",
+			MachineID: "XXX",
+		},
+		&task{
+			Type:      taskTypeAttemptScheduleUnit,
+			Reason:    "target state launched and unit not scheduled",
+			JobName:   "web.service",
+			MachineID: "XXX",
+		},
+	}
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	tasks := make([]*task, 0)
+	for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+		tasks = append(tasks, tsk)
+	}
+
+	if !reflect.DeepEqual(want, tasks) {
+		t.Errorf("task mismatch\nexpected %v\n got %v", want, tasks)
+	}
+}
+
+func TestCalculateClusterTasksUnitLimit(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "a.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "b.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "c.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "d.service", TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "a.service", State: &jsLaunched, TargetMachineID: "XXX"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX", MaxUnits: 2},
+			machine.MachineState{ID: "YYY", Metadata: map[string]string{"max-units-per-machine": "1"}},
+		},
+	)
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	scheduled := make(map[string]string)
+	for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+		if tsk.Type == taskTypeAttemptScheduleUnit {
+			scheduled[tsk.JobName] = tsk.MachineID
+		}
+	}
+
+	// each machine takes one more unit, leaving no room for the last one
+	want := map[string]string{"b.service": "YYY", "c.service": "XXX"}
+	if !reflect.DeepEqual(want, scheduled) {
+		t.Errorf("scheduled %v, want %v", scheduled, want)
+	}
+}
+
+func TestCalculateClusterTasksQuota(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "team-a:a.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=256"), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "team-a:b.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=256"), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "team-a:c.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512"), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "team-b:d.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512"), TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "team-a:a.service", State: &jsLaunched, TargetMachineID: "XXX"},
+		},
+		[]machine.MachineState{machine.MachineState{ID: "XXX"}},
+	)
+	clust.setQuotas([]registry.Quota{registry.Quota{Namespace: "team-a", Memory: 768}})
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	var scheduled []string
+	for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+		if tsk.Type == taskTypeAttemptScheduleUnit {
+			scheduled = append(scheduled, tsk.JobName)
+		}
+	}
+	sort.Strings(scheduled)
+
+	// the namespace without a quota is not limited
+	want := []string{"team-a:b.service", "team-b:d.service"}
+	if !reflect.DeepEqual(want, scheduled) {
+		t.Errorf("scheduled %v, want %v", scheduled, want)
+	}
+	wantReason := "memory quota of namespace team-a exceeded: 1024MB of 768MB"
+	if got := clust.rejected["team-a:c.service"].Reason; got != wantReason {
+		t.Errorf("team-a:c.service rejected with %q, want %q", got, wantReason)
+	}
+}
+
+func TestCalculateClusterTasksDependencies(t *testing.T) {
+	for i, tt := range []struct {
+		active []string
+		want   []string
+	}{
+		// nothing is active yet, so only the database is scheduled
+		{nil, []string{"db.service"}},
+		// once the database is active, the web server follows
+		{[]string{"db.service"}, []string{"db.service", "web.service"}},
+	} {
+		units := []job.Unit{
+			job.Unit{Name: "db.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "web.service", Unit: newTestUnit(t, "[X-Fleet]\nFleetRequires=db.service"), TargetState: job.JobStateLaunched},
+		}
+		clust := newClusterState(units, nil, []machine.MachineState{machine.MachineState{ID: "XXX"}})
+		clust.launched = pkg.NewUnsafeSet("db.service", "web.service")
+		clust.active = pkg.NewUnsafeSet(tt.active...)
+
+		r := NewReconciler(&leastLoadedScheduler{}, false)
+		var scheduled []string
+		for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+			if tsk.Type == taskTypeAttemptScheduleUnit {
+				scheduled = append(scheduled, tsk.JobName)
+			}
+		}
+		sort.Strings(scheduled)
+
+		if !reflect.DeepEqual(tt.want, scheduled) {
+			t.Errorf("case %d: scheduled %v, want %v", i, scheduled, tt.want)
+		}
+	}
+}
+
+// orderRecordingRegistry records the order in which Units are scheduled
+// and unscheduled, unscheduling them slowly
+type orderRecordingRegistry struct {
+	registry.Registry
+	sync.Mutex
+	order []string
+}
+
+func (r *orderRecordingRegistry) UnscheduleUnit(name, machID string) error {
+	time.Sleep(10 * time.Millisecond)
+	r.Lock()
+	defer r.Unlock()
+	r.order = append(r.order, "unschedule "+name)
+	return nil
+}
+
+func (r *orderRecordingRegistry) ScheduleUnit(name, machID string) error {
+	r.Lock()
+	defer r.Unlock()
+	r.order = append(r.order, "schedule "+name)
+	return nil
+}
+
+func TestDoTasksUnschedulesFirst(t *testing.T) {
+	reg := &orderRecordingRegistry{Registry: registry.NewFakeRegistry()}
+	e := &Engine{registry: reg}
+
+	// a preemptor only fits once its victims are gone, whichever worker
+	// carries out their tasks
+	tasks := make(chan *task, 3)
+	tasks <- &task{Type: taskTypeUnscheduleUnit, JobName: "a.service", MachineID: "XXX"}
+	tasks <- &task{Type: taskTypeUnscheduleUnit, JobName: "c.service", MachineID: "XXX"}
+	tasks <- &task{Type: taskTypeAttemptScheduleUnit, JobName: "b.service", MachineID: "XXX"}
+	close(tasks)
+	if !doTasks(tasks, e, 4) {
+		t.Fatalf("Expected all tasks to be carried out")
+	}
+
+	if len(reg.order) != 3 || reg.order[2] != "schedule b.service" {
+		t.Errorf("Preemptor scheduled before its victims were unscheduled: %v", reg.order)
+	}
+}
+
+func TestDoTasks(t *testing.T) {
+	fr := registry.NewFakeRegistry()
+	var jobs []job.Job
+	for i := 0; i < 20; i++ {
+		jobs = append(jobs, job.Job{Name: fmt.Sprintf("%02d.service", i), TargetMachineID: "XXX"})
+	}
+	fr.SetJobs(jobs)
+	e := &Engine{registry: fr}
+
+	tasks := make(chan *task)
+	go func() {
+		defer close(tasks)
+		for _, j := range jobs {
+			// the tasks of each Unit must be carried out in order
+			tasks <- &task{Type: taskTypeUnscheduleUnit, JobName: j.Name, MachineID: "XXX"}
+			tasks <- &task{Type: taskTypeAttemptScheduleUnit, JobName: j.Name, MachineID: "YYY"}
+		}
+	}()
+	if !doTasks(tasks, e, 4) {
+		t.Errorf("Expected all tasks to be carried out")
+	}
+
+	sUnits, err := fr.Schedule()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sUnits) != len(jobs) {
+		t.Fatalf("Expected %d scheduled Units, got %d", len(jobs), len(sUnits))
+	}
+	for _, su := range sUnits {
+		if su.TargetMachineID != "YYY" {
+			t.Errorf("Unit %s scheduled to %q, expected YYY", su.Name, su.TargetMachineID)
+		}
+	}
+
+	tasks = make(chan *task, 2)
+	tasks <- &task{Type: taskTypeAttemptScheduleUnit, JobName: "00.service", MachineID: "XXX"}
+	tasks <- &task{Type: "Bogus", JobName: "01.service"}
+	close(tasks)
+	if doTasks(tasks, e, 4) {
+		t.Errorf("Expected failed task to be reported")
+	}
+}
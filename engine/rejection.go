@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+const (
+	// rejectionTTL is how long the reasons a Unit could not be scheduled
+	// are kept in the Registry unless confirmed by a later reconciliation
+	rejectionTTL = 10 * time.Minute
+)
+
+// saveRejections persists why Units could not be scheduled during the last
+// reconciliation. Records are only written when the reasons change or
+// before they expire, and removed once their Unit is no longer rejected.
+func (e *Engine) saveRejections(rejected map[string]registry.UnitRejections) {
+	if e.rejections == nil {
+		e.rejections = make(map[string]registry.UnitRejections)
+	}
+
+	now := time.Now()
+	for name, rej := range rejected {
+		prev, ok := e.rejections[name]
+		if ok && prev.Reason == rej.Reason && reflect.DeepEqual(prev.Machines, rej.Machines) && now.Sub(prev.Time) < rejectionTTL/2 {
+			continue
+		}
+
+		rej.Time = now
+		if err := e.registry.SaveUnitRejections(name, rej, rejectionTTL); err != nil {
+			log.Errorf("Failed saving scheduling rejections of Unit(%s): %v", name, err)
+			continue
+		}
+		e.rejections[name] = rej
+	}
+
+	for name := range e.rejections {
+		if _, ok := rejected[name]; ok {
+			continue
+		}
+		if err := e.registry.ClearUnitRejections(name); err != nil {
+			log.Errorf("Failed clearing scheduling rejections of Unit(%s): %v", name, err)
+			continue
+		}
+		delete(e.rejections, name)
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestCalculateClusterTasksRejections(t *testing.T) {
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineMetadata=region=eu"), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "bar.service", Unit: newTestUnit(t, "[X-Fleet]\nFleetRequires=baz.service"), TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX", Metadata: map[string]string{"region": "us"}},
+		},
+	)
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	for _ = range r.calculateClusterTasks(clust, make(chan struct{})) {
+	}
+
+	want := map[string]registry.UnitRejections{
+		"foo.service": registry.UnitRejections{
+			Reason:   "no agents able to run job",
+			Machines: map[string]string{"XXX": "local Machine metadata insufficient"},
+			Shortfalls: map[string][]job.Shortfall{
+				"XXX": []job.Shortfall{{Constraint: job.ConstraintMetadata, Reason: "local Machine metadata insufficient"}},
+			},
+		},
+		"bar.service": registry.UnitRejections{
+			Reason: clust.rejected["bar.service"].Reason,
+		},
+	}
+	if !reflect.DeepEqual(clust.rejected, want) {
+		t.Errorf("Unexpected rejections:\ngot\n%#v\nwant\n%#v", clust.rejected, want)
+	}
+	if clust.rejected["bar.service"].Reason == "" {
+		t.Errorf("Expected unmet dependency to be recorded")
+	}
+}
+
+type countingRejectionRegistry struct {
+	*registry.FakeRegistry
+	saves int
+}
+
+func (r *countingRejectionRegistry) SaveUnitRejections(name string, rej registry.UnitRejections, ttl time.Duration) error {
+	r.saves++
+	return r.FakeRegistry.SaveUnitRejections(name, rej, ttl)
+}
+
+func TestSaveRejections(t *testing.T) {
+	reg := &countingRejectionRegistry{FakeRegistry: registry.NewFakeRegistry()}
+	e := &Engine{registry: reg}
+
+	rejected := map[string]registry.UnitRejections{
+		"foo.service": registry.UnitRejections{Reason: "no agents able to run job", Machines: map[string]string{"XXX": "local Machine metadata insufficient"}},
+	}
+	e.saveRejections(rejected)
+	e.saveRejections(rejected)
+	if reg.saves != 1 {
+		t.Errorf("Expected unchanged rejections to be saved once, saved %d times", reg.saves)
+	}
+
+	rej, _ := reg.UnitRejections("foo.service")
+	if rej == nil || rej.Reason != "no agents able to run job" || rej.Time.IsZero() {
+		t.Fatalf("Unexpected saved rejections: %#v", rej)
+	}
+
+	rejected["foo.service"] = registry.UnitRejections{Reason: "no agents able to run job", Machines: map[string]string{"XXX": "insufficient memory: requested 512MB, available 256MB"}}
+	e.saveRejections(rejected)
+	if reg.saves != 2 {
+		t.Errorf("Expected changed rejections to be saved, saved %d times", reg.saves)
+	}
+
+	// once the Unit is scheduled, its record is removed
+	e.saveRejections(nil)
+	if rej, _ := reg.UnitRejections("foo.service"); rej != nil {
+		t.Errorf("Expected rejections to be cleared, got %#v", rej)
+	}
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/job"
+)
+
+// trackLostMachines records since when each machine that Jobs are scheduled
+// to has been missing from the cluster, forgetting the machines that came
+// back or no longer have any Jobs. Machines are only known to be missing
+// from when the local engine first found them so.
+func (e *Engine) trackLostMachines(clust *clusterState, now time.Time) {
+	lost := make(map[string]time.Time)
+	for _, j := range clust.jobs {
+		if !j.Scheduled() {
+			continue
+		}
+		if _, ok := clust.machines[j.TargetMachineID]; ok {
+			continue
+		}
+		since, ok := e.lost[j.TargetMachineID]
+		if !ok {
+			since = now
+		}
+		lost[j.TargetMachineID] = since
+	}
+	e.lost = lost
+	clust.lost = lost
+}
+
+// rescheduleWait returns how much longer the given Job, whose machine went
+// away, is left scheduled to that machine in case it comes back, given the
+// delay of Jobs declaring no RescheduleDelay. The Job is moved once the
+// result is not positive.
+func (cs *clusterState) rescheduleWait(j *job.Job, def time.Duration, now time.Time) time.Duration {
+	delay, ok := j.RescheduleDelay()
+	if !ok {
+		delay = def
+	}
+	since, ok := cs.lost[j.TargetMachineID]
+	if !ok {
+		since = now
+	}
+	return since.Add(delay).Sub(now)
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+)
+
+func TestTrackLostMachines(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "bar.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "baz.service", TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "foo.service", State: &jsLaunched, TargetMachineID: "XXX"},
+			job.ScheduledUnit{Name: "bar.service", State: &jsLaunched, TargetMachineID: "YYY"},
+			job.ScheduledUnit{Name: "baz.service", State: &jsLaunched, TargetMachineID: "ZZZ"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "ZZZ"},
+		},
+	)
+
+	then := time.Date(2015, time.March, 1, 12, 0, 0, 0, time.UTC)
+	now := then.Add(time.Minute)
+	e := &Engine{lost: map[string]time.Time{
+		"XXX": then,
+		// machines that came back are forgotten
+		"ZZZ": then,
+		// as are those without any Jobs
+		"AAA": then,
+	}}
+	e.trackLostMachines(clust, now)
+
+	want := map[string]time.Time{"XXX": then, "YYY": now}
+	if !reflect.DeepEqual(want, e.lost) {
+		t.Errorf("unexpected lost machines: got %v, want %v", e.lost, want)
+	}
+	if !reflect.DeepEqual(want, clust.lost) {
+		t.Errorf("unexpected lost machines of cluster state: got %v, want %v", clust.lost, want)
+	}
+}
+
+func TestCalculateClusterTasksRescheduleDelay(t *testing.T) {
+	jsLaunched := job.JobStateLaunched
+	now := time.Now()
+
+	tests := []struct {
+		contents string
+		def      time.Duration
+		lost     time.Time
+		moved    bool
+	}{
+		// moved right away by default
+		{"", 0, now, true},
+		// left in place within the delay
+		{"[X-Fleet]\nRescheduleDelay=5m", 0, now.Add(-time.Minute), false},
+		{"", 5 * time.Minute, now.Add(-time.Minute), false},
+		// and moved once it passed
+		{"[X-Fleet]\nRescheduleDelay=5m", 0, now.Add(-10 * time.Minute), true},
+		{"", 5 * time.Minute, now.Add(-10 * time.Minute), true},
+		// the delay of the Job takes precedence
+		{"[X-Fleet]\nRescheduleDelay=0", 5 * time.Minute, now, true},
+		{"[X-Fleet]\nRescheduleDelay=1h", time.Minute, now.Add(-10 * time.Minute), false},
+	}
+
+	for i, tt := range tests {
+		clust := newClusterState(
+			[]job.Unit{
+				job.Unit{Name: "foo.service", TargetState: job.JobStateLaunched, Unit: newTestUnit(t, tt.contents)},
+			},
+			[]job.ScheduledUnit{
+				job.ScheduledUnit{Name: "foo.service", State: &jsLaunched, TargetMachineID: "ZZZ"},
+			},
+			[]machine.MachineState{
+				machine.MachineState{ID: "XXX"},
+			},
+		)
+		clust.lost = map[string]time.Time{"ZZZ": tt.lost}
+		// Jobs of lost machines are re-evaluated by incremental
+		// reconciliations too
+		clust.dirty = newClusterSnapshot(clust).diff(newClusterSnapshot(clust))
+
+		r := NewReconciler(&leastLoadedScheduler{}, false)
+		r.rescheduleDelay = tt.def
+		var tasks []*task
+		for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+			tasks = append(tasks, tsk)
+		}
+
+		if moved := len(tasks) != 0; moved != tt.moved {
+			t.Errorf("case %d: expected moved=%t, got tasks %v", i, tt.moved, tasks)
+			continue
+		}
+		if tt.moved && tasks[0].Type != taskTypeUnscheduleUnit {
+			t.Errorf("case %d: expected Job to be unscheduled first, got tasks %v", i, tasks)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/unit"
+)
+
+// scaleTemplates creates and destroys instances of the scaled template
+// Units so each has as many as requested. They are scheduled like any
+// other Unit by the remainder of the reconciliation.
+func (e *Engine) scaleTemplates() {
+	scales, err := e.registry.UnitScales()
+	if err != nil {
+		log.Errorf("Failed fetching template scales from Registry: %v", err)
+		return
+	} else if len(scales) == 0 {
+		return
+	}
+
+	units, err := e.registry.Units()
+	if err != nil {
+		log.Errorf("Failed fetching Units from Registry: %v", err)
+		return
+	}
+
+	create, destroy := scaleInstances(units, scales)
+	for _, u := range create {
+		u := u
+		if err := e.registry.CreateUnit(&u); err != nil {
+			log.Errorf("Failed creating Unit(%s): %v", u.Name, err)
+			continue
+		}
+		log.Infof("Created Unit(%s) to scale up its template", u.Name)
+	}
+	for _, name := range destroy {
+		if err := e.registry.DestroyUnit(name); err != nil {
+			log.Errorf("Failed destroying Unit(%s): %v", name, err)
+			continue
+		}
+		log.Infof("Destroyed Unit(%s) to scale down its template", name)
+	}
+}
+
+// scaleInstances determines the instances to create and destroy so each
+// template Unit in scales has instances numbered 1 through its count.
+// Instances are created launched, with the unit file and labels of their
+// template.
+// Instances with names that are not numbers are left alone.
+func scaleInstances(units []job.Unit, scales map[string]int) (create []job.Unit, destroy []string) {
+	templates := make(map[string]*job.Unit)
+	instances := make(map[string]map[int]bool)
+	for i := range units {
+		u := &units[i]
+		uni := unit.NewUnitNameInfo(u.Name)
+		if uni == nil {
+			continue
+		}
+		if uni.IsTemplate() {
+			templates[u.Name] = u
+			continue
+		}
+		if _, ok := scales[uni.Template]; !ok || !uni.IsInstance() {
+			continue
+		}
+		if n, ok := instanceNumber(uni.Instance); ok {
+			if instances[uni.Template] == nil {
+				instances[uni.Template] = make(map[int]bool)
+			}
+			instances[uni.Template][n] = true
+		}
+	}
+
+	var names []string
+	for tmpl := range scales {
+		names = append(names, tmpl)
+	}
+	sort.Strings(names)
+
+	for _, tmpl := range names {
+		count := scales[tmpl]
+		uni := unit.NewUnitNameInfo(tmpl)
+		if uni == nil || !uni.IsTemplate() {
+			log.Errorf("Unable to scale Unit(%s), not a template", tmpl)
+			continue
+		}
+
+		t, ok := templates[tmpl]
+		if !ok {
+			log.V(1).Infof("Unable to scale template Unit(%s), it does not exist", tmpl)
+			continue
+		}
+		if t.CronSchedule() != nil {
+			log.Errorf("Unable to scale template Unit(%s), its instances are run on schedule", tmpl)
+			continue
+		}
+
+		var existing []int
+		for n := range instances[tmpl] {
+			existing = append(existing, n)
+		}
+		sort.Ints(existing)
+
+		for n := 1; n <= count; n++ {
+			if !instances[tmpl][n] {
+				create = append(create, job.Unit{
+					Name:        instanceName(uni, n),
+					Unit:        t.Unit,
+					TargetState: job.JobStateLaunched,
+					Labels:      t.Labels,
+				})
+			}
+		}
+		for _, n := range existing {
+			if n > count {
+				destroy = append(destroy, instanceName(uni, n))
+			}
+		}
+	}
+
+	return
+}
+
+// instanceNumber parses the name of an instance created by scaling,
+// i.e. a positive number without leading zeros
+func instanceNumber(instance string) (int, bool) {
+	n, err := strconv.Atoi(instance)
+	if err != nil || n < 1 || strconv.Itoa(n) != instance {
+		return 0, false
+	}
+	return n, true
+}
+
+func instanceName(tmpl *unit.UnitNameInfo, n int) string {
+	return fmt.Sprintf("%s@%d%s", tmpl.Prefix, n, tmpl.FullName[len(tmpl.Name):])
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestScaleInstances(t *testing.T) {
+	uf := unit.UnitFile{}
+	labels := map[string]string{"app": "foo"}
+	tmpl := job.Unit{Name: "foo@.service", Unit: uf, Labels: labels}
+
+	for i, tt := range []struct {
+		units   []job.Unit
+		scales  map[string]int
+		create  []string
+		destroy []string
+	}{
+		// missing instances are created
+		{
+			[]job.Unit{tmpl, job.Unit{Name: "foo@2.service"}},
+			map[string]int{"foo@.service": 3},
+			[]string{"foo@1.service", "foo@3.service"},
+			nil,
+		},
+		// surplus instances are destroyed, others left alone
+		{
+			[]job.Unit{tmpl, job.Unit{Name: "foo@1.service"}, job.Unit{Name: "foo@2.service"}, job.Unit{Name: "foo@10.service"}, job.Unit{Name: "foo@web.service"}, job.Unit{Name: "foo@03.service"}},
+			map[string]int{"foo@.service": 1},
+			nil,
+			[]string{"foo@2.service", "foo@10.service"},
+		},
+		// templates that do not exist are not scaled
+		{
+			[]job.Unit{job.Unit{Name: "bar@1.service"}, job.Unit{Name: "bar@2.service"}},
+			map[string]int{"bar@.service": 1},
+			nil,
+			nil,
+		},
+		// neither are Units that are not templates
+		{
+			[]job.Unit{job.Unit{Name: "bar.service"}},
+			map[string]int{"bar.service": 2},
+			nil,
+			nil,
+		},
+	} {
+		create, destroy := scaleInstances(tt.units, tt.scales)
+
+		var names []string
+		for _, u := range create {
+			names = append(names, u.Name)
+			if u.TargetState != job.JobStateLaunched || !reflect.DeepEqual(u.Unit, uf) || !reflect.DeepEqual(u.Labels, labels) {
+				t.Errorf("case %d: Unit(%s) created incorrectly: %#v", i, u.Name, u)
+			}
+		}
+		if !reflect.DeepEqual(names, tt.create) {
+			t.Errorf("case %d: created %v, want %v", i, names, tt.create)
+		}
+		if !reflect.DeepEqual(destroy, tt.destroy) {
+			t.Errorf("case %d: destroyed %v, want %v", i, destroy, tt.destroy)
+		}
+	}
+}
+
+func TestScaleTemplates(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "foo@.service"},
+		job.Job{Name: "foo@1.service", TargetState: job.JobStateLaunched},
+		job.Job{Name: "foo@2.service", TargetState: job.JobStateLaunched},
+		job.Job{Name: "foo@3.service", TargetState: job.JobStateLaunched},
+	})
+	e := &Engine{registry: reg}
+
+	reg.SetUnitScale("foo@.service", 2)
+	e.scaleTemplates()
+	assertUnits(t, reg, []string{"foo@.service", "foo@1.service", "foo@2.service"})
+
+	reg.SetUnitScale("foo@.service", 4)
+	e.scaleTemplates()
+	assertUnits(t, reg, []string{"foo@.service", "foo@1.service", "foo@2.service", "foo@3.service", "foo@4.service"})
+}
+
+func assertUnits(t *testing.T, reg registry.Registry, want []string) {
+	units, err := reg.Units()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, u := range units {
+		names = append(names, u.Name)
+	}
+	sort.Strings(names)
+	sort.Strings(want)
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Unexpected Units: got %v, want %v", names, want)
+	}
+}
@@ -2,10 +2,29 @@ package engine
 
 import (
 	"fmt"
+	"math/rand"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/coreos/fleet/agent"
 	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+)
+
+const (
+	// SchedulingStrategyLeastLoaded places a Job on the eligible machine
+	// running the fewest units
+	SchedulingStrategyLeastLoaded = "least-loaded"
+	// SchedulingStrategyBinpack places a Job on the eligible machine with
+	// the least free resources
+	SchedulingStrategyBinpack = "binpack"
+	// SchedulingStrategySpread places a Job on the eligible machine with
+	// the most free resources
+	SchedulingStrategySpread = "spread"
+	// SchedulingStrategyRandom places a Job on a random eligible machine
+	SchedulingStrategyRandom = "random"
 )
 
 type decision struct {
@@ -16,37 +35,135 @@ type Scheduler interface {
 	Decide(*clusterState, *job.Job) (*decision, error)
 }
 
-type leastLoadedScheduler struct{}
-
-func (lls *leastLoadedScheduler) Decide(clust *clusterState, j *job.Job) (*decision, error) {
-	agents := lls.sortedAgents(clust)
+// NewScheduler returns a Scheduler implementing the named strategy. An empty
+// strategy selects the least-loaded scheduler.
+func NewScheduler(strategy string) (Scheduler, error) {
+	switch strategy {
+	case "", SchedulingStrategyLeastLoaded:
+		return &leastLoadedScheduler{}, nil
+	case SchedulingStrategyBinpack:
+		return &resourceScheduler{spread: false}, nil
+	case SchedulingStrategySpread:
+		return &resourceScheduler{spread: true}, nil
+	case SchedulingStrategyRandom:
+		return newRandomScheduler(), nil
+	}
+	return nil, fmt.Errorf("unrecognized scheduling strategy %q", strategy)
+}
 
-	if len(agents) == 0 {
-		return nil, fmt.Errorf("zero agents available")
+// ableAgents returns the subset of the given agents able to run the Job,
+// preserving their order. Besides each agent's own checks, an agent is only
+// able to run the Job if doing so would not place it in the same conflict
+// domain as a conflicting Unit on any other agent, nor spread the Job's
+// group more unevenly than its SpreadConstraint allows.
+func ableAgents(agents []*agent.AgentState, j *job.Job) []*agent.AgentState {
+	var able []*agent.AgentState
+	for _, as := range agents {
+		if ok, _ := as.AbleToRun(j); ok && !hasDomainConflict(agents, as, j) {
+			able = append(able, as)
+		}
 	}
+	spread, _ := spreadAgents(agents, able, j)
+	return spread
+}
 
-	var target *agent.AgentState
+// hasDomainConflict determines whether scheduling the Job to the target
+// agent would violate a conflict domain with a Unit on any other agent
+func hasDomainConflict(agents []*agent.AgentState, target *agent.AgentState, j *job.Job) bool {
 	for _, as := range agents {
-		if able, _ := as.AbleToRun(j); !able {
+		if as.MState.ID == target.MState.ID {
 			continue
 		}
 
-		as := as
-		target = as
-		break
+		if found, conflict := as.HasDomainConflict(target.MState, j); found {
+			log.V(1).Infof("Job(%s) conflicts with Unit(%s) on Machine(%s) in the same domain as Machine(%s)", j.Name, conflict, as.MState.ID, target.MState.ID)
+			return true
+		}
+	}
+	return false
+}
+
+// preferredAgents returns the subset of the given agents matching the most
+// of the Job's preferred metadata, preserving their order
+func preferredAgents(agents []*agent.AgentState, j *job.Job) []*agent.AgentState {
+	preferred := j.PreferredTargetMetadata()
+	if len(preferred) == 0 {
+		return agents
 	}
 
-	if target == nil {
+	best := -1
+	var matched []*agent.AgentState
+	for _, as := range agents {
+		score := machine.MetadataScore(as.MState, preferred)
+		if score > best {
+			best = score
+			matched = nil
+		}
+		if score == best {
+			matched = append(matched, as)
+		}
+	}
+	return matched
+}
+
+// cheapestAgents returns the subset of the given agents on the cheapest
+// machines, preserving their order. Jobs avoiding preemptible machines
+// instead get the agents on machines not marked preemptible, if any.
+func cheapestAgents(agents []*agent.AgentState, j *job.Job) []*agent.AgentState {
+	if j.AvoidsPreemptibleMachines() {
+		var durable []*agent.AgentState
+		for _, as := range agents {
+			if !as.MState.Preemptible() {
+				durable = append(durable, as)
+			}
+		}
+		if len(durable) == 0 {
+			return agents
+		}
+		return durable
+	}
+
+	var cheapest []*agent.AgentState
+	for _, as := range agents {
+		if len(cheapest) > 0 {
+			best := cheapest[0].MState.Cost()
+			if cost := as.MState.Cost(); cost > best {
+				continue
+			} else if cost < best {
+				cheapest = nil
+			}
+		}
+		cheapest = append(cheapest, as)
+	}
+	return cheapest
+}
+
+// firstAbleAgent decides in favor of the first of the given agents able to
+// run the Job, among the placeable ones best matching its preferred metadata
+// and then on the cheapest machines
+func firstAbleAgent(clust *clusterState, agents []*agent.AgentState, j *job.Job) (*decision, error) {
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("zero agents available")
+	}
+
+	able := cheapestAgents(preferredAgents(candidateAgents(clust, agents, j), j), j)
+	if len(able) == 0 {
 		return nil, fmt.Errorf("no agents able to run job")
 	}
 
 	dec := decision{
-		machineID: target.MState.ID,
+		machineID: able[0].MState.ID,
 	}
 
 	return &dec, nil
 }
 
+type leastLoadedScheduler struct{}
+
+func (lls *leastLoadedScheduler) Decide(clust *clusterState, j *job.Job) (*decision, error) {
+	return firstAbleAgent(clust, lls.sortedAgents(clust), j)
+}
+
 // sortedAgents returns a list of AgentState objects sorted ascending
 // by the number of scheduled units
 func (lls *leastLoadedScheduler) sortedAgents(clust *clusterState) []*agent.AgentState {
@@ -71,3 +188,105 @@ func (sas sortableAgentStates) Less(i, j int) bool {
 	njUnits := len(sas[j].Units)
 	return niUnits < njUnits || (niUnits == njUnits && sas[i].MState.ID < sas[j].MState.ID)
 }
+
+// resourceScheduler places Jobs based on the free resources of each
+// machine. By default it bin-packs, preferring the machine with the least
+// free memory (then CPU, then disk) that can still run the Job; with spread set it
+// prefers the machine with the most. Machines that do not publish their
+// capacity are considered last.
+type resourceScheduler struct {
+	spread bool
+}
+
+func (rs *resourceScheduler) Decide(clust *clusterState, j *job.Job) (*decision, error) {
+	return firstAbleAgent(clust, rs.sortedAgents(clust), j)
+}
+
+func (rs *resourceScheduler) sortedAgents(clust *clusterState) []*agent.AgentState {
+	agents := clust.agents()
+
+	sas := freeResourceAgentStates{spread: rs.spread}
+	for _, as := range agents {
+		sas.agents = append(sas.agents, as)
+	}
+	sort.Sort(sas)
+
+	return sas.agents
+}
+
+type freeResourceAgentStates struct {
+	agents []*agent.AgentState
+	spread bool
+}
+
+func (fas freeResourceAgentStates) Len() int { return len(fas.agents) }
+func (fas freeResourceAgentStates) Swap(i, j int) {
+	fas.agents[i], fas.agents[j] = fas.agents[j], fas.agents[i]
+}
+
+func (fas freeResourceAgentStates) Less(i, j int) bool {
+	ai, aj := fas.agents[i], fas.agents[j]
+
+	iKnown := !ai.MState.TotalResources.Empty()
+	jKnown := !aj.MState.TotalResources.Empty()
+	if iKnown != jKnown {
+		return iKnown
+	}
+
+	if iKnown {
+		fi, fj := ai.FreeResources(), aj.FreeResources()
+		if fi.Memory != fj.Memory {
+			return (fi.Memory < fj.Memory) != fas.spread
+		}
+		if fi.Cores != fj.Cores {
+			return (fi.Cores < fj.Cores) != fas.spread
+		}
+		if fi.Disk != fj.Disk {
+			return (fi.Disk < fj.Disk) != fas.spread
+		}
+	}
+
+	return ai.MState.ID < aj.MState.ID
+}
+
+// randomScheduler places Jobs on a randomly-chosen eligible machine among
+// the cheapest of those best matching the Job's preferred metadata
+type randomScheduler struct {
+	// mutex guards rand, which is not safe for concurrent use
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func newRandomScheduler() *randomScheduler {
+	return &randomScheduler{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// intn returns a random number in [0, n)
+func (rs *randomScheduler) intn(n int) int {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	return rs.rand.Intn(n)
+}
+
+func (rs *randomScheduler) Decide(clust *clusterState, j *job.Job) (*decision, error) {
+	agents := clust.agents()
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("zero agents available")
+	}
+
+	var all []*agent.AgentState
+	for _, as := range agents {
+		all = append(all, as)
+	}
+
+	able := cheapestAgents(preferredAgents(candidateAgents(clust, all, j), j), j)
+	if len(able) == 0 {
+		return nil, fmt.Errorf("no agents able to run job")
+	}
+
+	dec := decision{
+		machineID: able[rs.intn(len(able))].MState.ID,
+	}
+
+	return &dec, nil
+}
@@ -8,8 +8,18 @@ import (
 	"github.com/coreos/fleet/agent"
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/unit"
 )
 
+func newTestUnit(t *testing.T, contents string) unit.UnitFile {
+	u, err := unit.NewUnitFile(contents)
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit from %q: %v", contents, err)
+	}
+	return *u
+}
+
 func TestSchedulerDecisions(t *testing.T) {
 	tests := []struct {
 		clust *clusterState
@@ -31,6 +41,36 @@ func TestSchedulerDecisions(t *testing.T) {
 				machineID: "XXX",
 			},
 		},
+
+		// skip machines without enough free resources
+		{
+			clust: newClusterState(
+				[]job.Unit{
+					job.Unit{Name: "bar.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024"), TargetState: job.JobStateLaunched},
+				},
+				[]job.ScheduledUnit{
+					job.ScheduledUnit{Name: "bar.service", TargetMachineID: "XXX"},
+				},
+				[]machine.MachineState{
+					machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048}},
+					machine.MachineState{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 1024}},
+					machine.MachineState{ID: "ZZZ", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048}},
+				},
+			),
+			job: &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024")},
+			dec: &decision{
+				machineID: "ZZZ",
+			},
+		},
+
+		// no machine has enough free resources
+		{
+			clust: newClusterState([]job.Unit{}, []job.ScheduledUnit{}, []machine.MachineState{
+				machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 512}},
+			}),
+			job: &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024")},
+			dec: nil,
+		},
 	}
 
 	for i, tt := range tests {
@@ -51,6 +91,258 @@ func TestSchedulerDecisions(t *testing.T) {
 	}
 }
 
+func TestSchedulerPreferences(t *testing.T) {
+	clust := newClusterState(
+		[]job.Unit{},
+		[]job.ScheduledUnit{},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX", Metadata: map[string]string{"disk": "hdd", "zone": "a"}},
+			machine.MachineState{ID: "YYY", Metadata: map[string]string{"disk": "ssd", "zone": "b"}},
+			machine.MachineState{ID: "ZZZ", Metadata: map[string]string{"disk": "ssd", "zone": "a"}},
+		},
+	)
+
+	for i, tt := range []struct {
+		contents string
+		want     string
+	}{
+		// no preferences, least-loaded by ID
+		{"", "XXX"},
+		// single preference, ties broken in the usual order
+		{"[X-Fleet]\nPreferredMachineMetadata=disk=ssd", "YYY"},
+		// more matching preferences win
+		{"[X-Fleet]\nPreferredMachineMetadata=disk=ssd\nPreferredMachineMetadata=zone=a", "ZZZ"},
+		// unmatched preferences fall back to the usual order
+		{"[X-Fleet]\nPreferredMachineMetadata=disk=tape", "XXX"},
+		// requirements still apply
+		{"[X-Fleet]\nMachineMetadata=zone=b\nPreferredMachineMetadata=zone=a", "YYY"},
+	} {
+		j := &job.Job{Name: "foo.service", Unit: newTestUnit(t, tt.contents)}
+		sched := &leastLoadedScheduler{}
+		dec, err := sched.Decide(clust, j)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if dec.machineID != tt.want {
+			t.Errorf("case %d: expected Machine(%s), got Machine(%s)", i, tt.want, dec.machineID)
+		}
+	}
+}
+
+func TestSchedulerCosts(t *testing.T) {
+	clust := newClusterState(
+		[]job.Unit{},
+		[]job.ScheduledUnit{},
+		[]machine.MachineState{
+			machine.MachineState{ID: "XXX", Metadata: map[string]string{"cost": "3", "zone": "a"}},
+			machine.MachineState{ID: "YYY", Metadata: map[string]string{"cost": "1", "preemptible": "true", "zone": "b"}},
+			machine.MachineState{ID: "ZZZ", Metadata: map[string]string{"cost": "1", "preemptible": "true", "zone": "a"}},
+		},
+	)
+
+	for i, tt := range []struct {
+		contents string
+		want     string
+	}{
+		// cheapest machines win, ties broken in the usual order
+		{"", "YYY"},
+		// preferred metadata weighs more than cost
+		{"[X-Fleet]\nPreferredMachineMetadata=zone=a", "ZZZ"},
+		// requirements still apply
+		{"[X-Fleet]\nMachineMetadata=zone=a", "ZZZ"},
+		// preemptible machines are avoided regardless of their cost
+		{"[X-Fleet]\nAvoidPreemptibleMachines=true", "XXX"},
+		// but used if no other machine is eligible
+		{"[X-Fleet]\nAvoidPreemptibleMachines=true\nMachineMetadata=zone=b", "YYY"},
+	} {
+		j := &job.Job{Name: "foo.service", Unit: newTestUnit(t, tt.contents)}
+		for _, sched := range []Scheduler{&leastLoadedScheduler{}, &resourceScheduler{}} {
+			dec, err := sched.Decide(clust, j)
+			if err != nil {
+				t.Errorf("case %d: unexpected error: %v", i, err)
+				continue
+			}
+			if dec.machineID != tt.want {
+				t.Errorf("case %d: %T expected Machine(%s), got Machine(%s)", i, sched, tt.want, dec.machineID)
+			}
+		}
+	}
+}
+
+func TestSchedulerDomainConflicts(t *testing.T) {
+	web := "[X-Fleet]\nConflicts=web@*.service\nConflictsWithMetadata=region"
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "web@1.service", Unit: newTestUnit(t, web), TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "web@1.service", TargetMachineID: "AAA"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "AAA", Metadata: map[string]string{"region": "us-east"}},
+			machine.MachineState{ID: "BBB", Metadata: map[string]string{"region": "us-east"}},
+			machine.MachineState{ID: "CCC", Metadata: map[string]string{"region": "us-west"}},
+		},
+	)
+
+	sched := &leastLoadedScheduler{}
+	dec, err := sched.Decide(clust, &job.Job{Name: "web@2.service", Unit: newTestUnit(t, web)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dec.machineID != "CCC" {
+		t.Errorf("Expected Machine(CCC), got Machine(%s)", dec.machineID)
+	}
+
+	dec, err = sched.Decide(clust, &job.Job{Name: "db.service"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dec.machineID != "BBB" {
+		t.Errorf("Expected Machine(BBB), got Machine(%s)", dec.machineID)
+	}
+}
+
+func TestSchedulerPorts(t *testing.T) {
+	web := "[X-Fleet]\nPorts=8080"
+	clust := newClusterState(
+		[]job.Unit{
+			job.Unit{Name: "web.service", Unit: newTestUnit(t, web), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "db.service", TargetState: job.JobStateLaunched},
+			job.Unit{Name: "cache.service", TargetState: job.JobStateLaunched},
+		},
+		[]job.ScheduledUnit{
+			job.ScheduledUnit{Name: "web.service", TargetMachineID: "AAA"},
+			job.ScheduledUnit{Name: "db.service", TargetMachineID: "BBB"},
+			job.ScheduledUnit{Name: "cache.service", TargetMachineID: "BBB"},
+		},
+		[]machine.MachineState{
+			machine.MachineState{ID: "AAA"},
+			machine.MachineState{ID: "BBB"},
+		},
+	)
+
+	// the least-loaded machine already binds the port
+	sched := &leastLoadedScheduler{}
+	dec, err := sched.Decide(clust, &job.Job{Name: "proxy.service", Unit: newTestUnit(t, web)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dec.machineID != "BBB" {
+		t.Errorf("Expected Machine(BBB), got Machine(%s)", dec.machineID)
+	}
+}
+
+func TestNewScheduler(t *testing.T) {
+	for i, tt := range []struct {
+		strategy string
+		want     Scheduler
+	}{
+		{"", &leastLoadedScheduler{}},
+		{"least-loaded", &leastLoadedScheduler{}},
+		{"binpack", &resourceScheduler{spread: false}},
+		{"spread", &resourceScheduler{spread: true}},
+		{"random", &randomScheduler{}},
+		{"fastest", nil},
+	} {
+		got, err := NewScheduler(tt.strategy)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("case %d: expected error for strategy %q", i, tt.strategy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		// random schedulers each hold their own seeded source
+		if rs, ok := got.(*randomScheduler); ok {
+			if rs.rand == nil {
+				t.Errorf("case %d: random scheduler has no source", i)
+			}
+			rs.rand = nil
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("case %d: expected %#v, got %#v", i, tt.want, got)
+		}
+	}
+}
+
+func TestResourceSchedulerDecisions(t *testing.T) {
+	newClust := func() *clusterState {
+		return newClusterState(
+			[]job.Unit{
+				job.Unit{Name: "bar.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024"), TargetState: job.JobStateLaunched},
+			},
+			[]job.ScheduledUnit{
+				job.ScheduledUnit{Name: "bar.service", TargetMachineID: "XXX"},
+			},
+			[]machine.MachineState{
+				// 768MB free
+				machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048}},
+				// 512MB free
+				machine.MachineState{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 768}},
+				// 1792MB free
+				machine.MachineState{ID: "ZZZ", TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048}},
+				// unknown capacity
+				machine.MachineState{ID: "AAA"},
+			},
+		)
+	}
+
+	for i, tt := range []struct {
+		spread bool
+		job    *job.Job
+		want   string
+	}{
+		// binpack onto the fullest machine that fits
+		{false, &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=128")}, "YYY"},
+		{false, &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=600")}, "XXX"},
+		{false, &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=1024")}, "ZZZ"},
+		// machines of unknown capacity are used last
+		{false, &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=4096")}, "AAA"},
+		// spread onto the emptiest machine
+		{true, &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=128")}, "ZZZ"},
+		{true, &job.Job{Name: "foo.service", Unit: unit.UnitFile{}}, "ZZZ"},
+	} {
+		sched := &resourceScheduler{spread: tt.spread}
+		dec, err := sched.Decide(newClust(), tt.job)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if dec.machineID != tt.want {
+			t.Errorf("case %d: expected Machine(%s), got Machine(%s)", i, tt.want, dec.machineID)
+		}
+	}
+}
+
+func TestRandomSchedulerDecisions(t *testing.T) {
+	clust := newClusterState([]job.Unit{}, []job.ScheduledUnit{}, []machine.MachineState{
+		machine.MachineState{ID: "XXX", Metadata: map[string]string{"ping": "pong"}},
+		machine.MachineState{ID: "YYY"},
+	})
+
+	sched := newRandomScheduler()
+	j := &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineMetadata=ping=pong")}
+	for i := 0; i < 10; i++ {
+		dec, err := sched.Decide(clust, j)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if dec.machineID != "XXX" {
+			t.Fatalf("Expected Machine(XXX), got Machine(%s)", dec.machineID)
+		}
+	}
+
+	j = &job.Job{Name: "foo.service", Unit: newTestUnit(t, "[X-Fleet]\nMachineMetadata=ping=ping")}
+	if _, err := sched.Decide(clust, j); err == nil {
+		t.Errorf("Expected error when no agents are able to run job")
+	}
+}
+
 func TestAgentStateSorting(t *testing.T) {
 	tests := []struct {
 		in  []*agent.AgentState
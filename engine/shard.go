@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+// shardSet describes the shards of the scheduling work the local engine
+// holds the leases of
+type shardSet struct {
+	total int
+	held  map[int]bool
+}
+
+// owns determines whether the named Job belongs to one of the held shards.
+// The Jobs of a Stack belong to the shard of the Stack, as they are
+// scheduled as a whole.
+func (ss *shardSet) owns(clust *clusterState, name string) bool {
+	key := name
+	if s, ok := clust.stacks[name]; ok {
+		key = s.Name
+	}
+	return ss.held[shardOf(key, ss.total)]
+}
+
+// schedules determines whether the local engine schedules the named Job
+func (cs *clusterState) schedules(name string) bool {
+	return cs.shards == nil || cs.shards.owns(cs, name)
+}
+
+// shardOf returns which of the given number of shards the given key falls
+// into
+func shardOf(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// shardLeaseName returns the name of the lease held by the engine scheduling
+// the given shard. A single shard is held under the lease of the lead
+// engine, as before sharding.
+func shardLeaseName(shard, shards int) string {
+	if shards <= 1 {
+		return engineLeaseName
+	}
+	return fmt.Sprintf("engine-shard-%d-of-%d", shard, shards)
+}
+
+// fairShare returns how many of the given number of shards each of the given
+// number of engines holds at most
+func fairShare(shards, engines int) int {
+	if engines < 1 {
+		engines = 1
+	}
+	return (shards + engines - 1) / engines
+}
+
+// updateLeases renews the shard leases held by the local engine and acquires
+// or releases leases until it holds its fair share of the shards, given
+// that every machine runs an engine. Free shards are tried starting from
+// one derived from the machine ID, so that engines do not all contend for
+// the same shards. It returns whether the shards held changed.
+func (e *Engine) updateLeases(machID string, ttl time.Duration) (changed bool) {
+	if e.leases == nil {
+		e.leases = make(map[int]registry.Lease)
+	}
+	defer func() {
+		metricShards.Set(float64(len(e.leases)))
+	}()
+
+	for shard, l := range e.leases {
+		if renewLeadership(l, ttl) == nil {
+			delete(e.leases, shard)
+			changed = true
+		}
+	}
+
+	shards := e.shards
+	if shards < 1 {
+		shards = 1
+	}
+	share := 1
+	if shards > 1 {
+		machines, err := e.registry.Machines()
+		if err != nil {
+			log.Errorf("Failed fetching Machines from Registry: %v", err)
+			return
+		}
+		share = fairShare(shards, len(machines))
+
+		// An engine configured with a single shard schedules all Units
+		if l, err := e.lRegistry.GetLease(engineLeaseName); err != nil || l != nil {
+			if l != nil {
+				log.Warningf("Engine of Machine(%s) schedules all units, unable to schedule %d shards", l.MachineID(), shards)
+			}
+			return
+		}
+	}
+
+	held := e.heldShards()
+	for len(held) > share {
+		shard := held[len(held)-1]
+		if err := e.leases[shard].Release(); err != nil {
+			log.Errorf("Failed releasing lease of shard %d: %v", shard, err)
+		}
+		log.Infof("Released shard %d to the other engines", shard)
+		delete(e.leases, shard)
+		held = held[:len(held)-1]
+		changed = true
+	}
+
+	start := shardOf(machID, shards)
+	for i := 0; i < shards && len(e.leases) < share; i++ {
+		shard := (start + i) % shards
+		if _, ok := e.leases[shard]; ok {
+			continue
+		}
+		if l := acquireLeadership(e.lRegistry, shardLeaseName(shard, shards), machID, engineVersion, ttl); l != nil {
+			e.leases[shard] = l
+			changed = true
+		}
+	}
+	return
+}
+
+// heldShards returns the shards the local engine holds the leases of, in
+// order
+func (e *Engine) heldShards() []int {
+	held := make([]int, 0, len(e.leases))
+	for shard := range e.leases {
+		held = append(held, shard)
+	}
+	sort.Ints(held)
+	return held
+}
+
+// shardSet returns the shards the local engine schedules the Units of, or
+// nil if it schedules all of them
+func (e *Engine) shardSet() *shardSet {
+	if e.shards <= 1 {
+		return nil
+	}
+	ss := shardSet{total: e.shards, held: make(map[int]bool, len(e.leases))}
+	for shard := range e.leases {
+		ss.held[shard] = true
+	}
+	return &ss
+}
+
+// holdsClusterDuties determines whether the local engine takes care of the
+// work concerning the whole cluster, like scaling template Units, which
+// falls to the holder of the first shard
+func (e *Engine) holdsClusterDuties() bool {
+	_, ok := e.leases[0]
+	return ok
+}
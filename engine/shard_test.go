@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestFairShare(t *testing.T) {
+	for i, tt := range []struct {
+		shards, engines, want int
+	}{
+		{1, 1, 1},
+		{4, 1, 4},
+		{4, 2, 2},
+		{4, 3, 2},
+		{4, 8, 1},
+		{4, 0, 4},
+	} {
+		if got := fairShare(tt.shards, tt.engines); got != tt.want {
+			t.Errorf("case %d: got %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestShardLeaseName(t *testing.T) {
+	if got := shardLeaseName(0, 1); got != engineLeaseName {
+		t.Errorf("Single shard held under lease %q, want %q", got, engineLeaseName)
+	}
+	if got := shardLeaseName(2, 4); got != "engine-shard-2-of-4" {
+		t.Errorf("Unexpected lease name %q", got)
+	}
+}
+
+func TestUpdateLeases(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX"},
+		machine.MachineState{ID: "YYY"},
+	})
+	lReg := registry.NewFakeLeaseRegistry()
+	e := &Engine{registry: reg, lRegistry: lReg, shards: 4}
+
+	// each of two engines holds half of the shards
+	if !e.updateLeases("XXX", time.Minute) {
+		t.Errorf("Expected shards held to change")
+	}
+	if len(e.leases) != 2 {
+		t.Fatalf("Expected 2 shards held, got %v", e.heldShards())
+	}
+	other := &Engine{registry: reg, lRegistry: lReg, shards: 4}
+	other.updateLeases("YYY", time.Minute)
+	if len(other.leases) != 2 {
+		t.Fatalf("Expected other engine to hold the 2 remaining shards, got %v", other.heldShards())
+	}
+	for shard := range e.leases {
+		if _, ok := other.leases[shard]; ok {
+			t.Errorf("Shard %d held by both engines", shard)
+		}
+	}
+	if e.updateLeases("XXX", time.Minute) {
+		t.Errorf("Expected shards held to stay the same")
+	}
+
+	// shards are released to machines joining the cluster
+	reg.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX"},
+		machine.MachineState{ID: "YYY"},
+		machine.MachineState{ID: "ZZZ"},
+		machine.MachineState{ID: "ZZZ2"},
+	})
+	e.updateLeases("XXX", time.Minute)
+	if len(e.leases) != 1 {
+		t.Errorf("Expected 1 shard held, got %v", e.heldShards())
+	}
+}
+
+func TestUpdateLeasesSingleLeader(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{machine.MachineState{ID: "XXX"}})
+	lReg := registry.NewFakeLeaseRegistry()
+	lReg.SetLease(engineLeaseName, "YYY", engineVersion, time.Minute)
+
+	// no shards are taken while an engine schedules all units
+	e := &Engine{registry: reg, lRegistry: lReg, shards: 4}
+	e.updateLeases("XXX", time.Minute)
+	if len(e.leases) != 0 {
+		t.Errorf("Expected no shards held, got %v", e.heldShards())
+	}
+}
+
+func TestCalculateClusterTasksSharded(t *testing.T) {
+	var units []job.Unit
+	for _, name := range []string{"a.service", "b.service", "c.service", "d.service", "db.service", "web.service"} {
+		units = append(units, job.Unit{Name: name, TargetState: job.JobStateLaunched})
+	}
+	clust := newClusterState(units, nil, []machine.MachineState{machine.MachineState{ID: "XXX"}})
+	clust.setStacks([]registry.Stack{registry.Stack{Name: "shop", Units: []string{"db.service", "web.service"}}})
+	clust.shards = &shardSet{total: 2, held: map[int]bool{shardOf("shop", 2): true}}
+
+	r := NewReconciler(&leastLoadedScheduler{}, false)
+	var got []string
+	for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+		got = append(got, tsk.JobName)
+	}
+
+	var want []string
+	for _, u := range units {
+		key := u.Name
+		if s, ok := clust.stacks[u.Name]; ok {
+			key = s.Name
+		}
+		if shardOf(key, 2) == shardOf("shop", 2) {
+			want = append(want, u.Name)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected Units scheduled: got %v, want %v", got, want)
+	}
+}
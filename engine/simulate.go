@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/coreos/fleet/registry"
+)
+
+const (
+	// simulationRounds is how many reconciliations Simulate carries out
+	// at most before giving up on the placements settling
+	simulationRounds = 100
+)
+
+// Simulate reconciles the cluster described by the given Registry as the
+// engine would, placing Units with the given scheduling strategy, until no
+// further tasks are decided. Scaled templates get their instances first.
+// All decisions are recorded in the Registry, which is meant to describe a
+// synthetic cluster, e.g. a FakeRegistry, to try out scheduling without
+// touching a real one. It returns why the Units left unscheduled could not
+// be scheduled, indexed by Unit name.
+func Simulate(reg registry.Registry, strategy string) (map[string]registry.UnitRejections, error) {
+	sched, err := NewScheduler(strategy)
+	if err != nil {
+		return nil, err
+	}
+	return SimulateScheduler(reg, WithPlacementPlugins(sched))
+}
+
+// SimulateScheduler is like Simulate, but places Units with the given
+// Scheduler, e.g. one consulting additional PlacementPlugins.
+func SimulateScheduler(reg registry.Registry, sched Scheduler) (map[string]registry.UnitRejections, error) {
+	r := NewReconciler(sched, false)
+
+	scales, err := reg.UnitScales()
+	if err != nil {
+		return nil, err
+	}
+	units, err := reg.Units()
+	if err != nil {
+		return nil, err
+	}
+	create, _ := scaleInstances(units, scales)
+	for _, u := range create {
+		u := u
+		if err := reg.CreateUnit(&u); err != nil {
+			return nil, fmt.Errorf("failed creating Unit(%s): %v", u.Name, err)
+		}
+	}
+
+	for i := 0; i < simulationRounds; i++ {
+		clust, err := loadClusterState(reg)
+		if err != nil {
+			return nil, err
+		}
+
+		// All tasks are decided before any is carried out, as the
+		// reconciliation is based on the state loaded above
+		var tasks []*task
+		for t := range r.calculateClusterTasks(clust, make(chan struct{})) {
+			tasks = append(tasks, t)
+		}
+		if len(tasks) == 0 {
+			return clust.rejected, nil
+		}
+
+		for _, t := range tasks {
+			switch t.Type {
+			case taskTypeUnscheduleUnit:
+				err = reg.UnscheduleUnit(t.JobName, t.MachineID)
+			case taskTypeAttemptScheduleUnit:
+				err = reg.ScheduleUnit(t.JobName, t.MachineID)
+			default:
+				err = fmt.Errorf("unrecognized task type %q", t.Type)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed resolving task %s: %v", t, err)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("placements did not settle after %d reconciliations", simulationRounds)
+}
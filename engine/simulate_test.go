@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
+)
+
+func TestSimulate(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 2048}},
+		machine.MachineState{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 2048}},
+	})
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "db.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=768"), TargetState: job.JobStateLaunched},
+		job.Job{Name: "huge.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=4096"), TargetState: job.JobStateLaunched},
+		job.Job{Name: "web@.service", Unit: newTestUnit(t, "[X-Fleet]\nMemoryReservation=512\nConflicts=web@*.service"), TargetState: job.JobStateInactive},
+	})
+	reg.SetUnitScale("web@.service", 2)
+
+	rejected, err := Simulate(reg, "")
+	if err != nil {
+		t.Fatalf("Simulation failed: %v", err)
+	}
+
+	sUnits, err := reg.Schedule()
+	if err != nil {
+		t.Fatalf("Failed fetching schedule: %v", err)
+	}
+	placed := make(map[string]string)
+	for _, su := range sUnits {
+		placed[su.Name] = su.TargetMachineID
+	}
+
+	if placed["db.service"] == "" {
+		t.Errorf("db.service not scheduled")
+	}
+	if placed["web@1.service"] == "" || placed["web@2.service"] == "" || placed["web@1.service"] == placed["web@2.service"] {
+		t.Errorf("Instances of web@.service not scheduled to different machines: %v", placed)
+	}
+	if placed["huge.service"] != "" {
+		t.Errorf("huge.service scheduled to %s despite lacking memory", placed["huge.service"])
+	}
+	if _, ok := rejected["huge.service"]; !ok || len(rejected) != 1 {
+		t.Errorf("Expected only huge.service to be rejected, got %v", rejected)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+)
+
+// spreadAgents returns the subset of the given able agents to which the Job
+// may be scheduled without exceeding the max skew of its SpreadConstraint,
+// preserving their order, along with the reason each other able agent was
+// left out, indexed by machine ID. The Units of the Job's spread group are
+// counted on all of the given agents, but only the values of the able ones
+// bound the skew.
+func spreadAgents(all, able []*agent.AgentState, j *job.Job) ([]*agent.AgentState, map[string]string) {
+	sc := j.SpreadConstraint()
+	if sc == nil {
+		return able, nil
+	}
+
+	group := job.SpreadGroup(j.Name)
+	counts := make(map[string]int)
+	for _, as := range all {
+		val := as.MState.Metadata[sc.Key]
+		if val == "" {
+			continue
+		}
+		for name := range as.Units {
+			if name != j.Name && job.SpreadGroup(name) == group {
+				counts[val]++
+			}
+		}
+	}
+
+	min := -1
+	for _, as := range able {
+		if val := as.MState.Metadata[sc.Key]; val != "" && (min < 0 || counts[val] < min) {
+			min = counts[val]
+		}
+	}
+
+	var kept []*agent.AgentState
+	rejected := make(map[string]string)
+	for _, as := range able {
+		val := as.MState.Metadata[sc.Key]
+		switch {
+		case val == "":
+			rejected[as.MState.ID] = fmt.Sprintf("Machine(%s) has no %s metadata to spread across", as.MState.ID, sc.Key)
+		case counts[val]+1-min > sc.MaxSkew:
+			rejected[as.MState.ID] = fmt.Sprintf("%s=%s already runs %d instances of %s, exceeding max skew %d", sc.Key, val, counts[val], group, sc.MaxSkew)
+		default:
+			kept = append(kept, as)
+		}
+	}
+	return kept, rejected
+}
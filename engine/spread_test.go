@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+)
+
+func TestSpreadAgents(t *testing.T) {
+	machines := []machine.MachineState{
+		machine.MachineState{ID: "A1", Metadata: map[string]string{"zone": "a"}},
+		machine.MachineState{ID: "A2", Metadata: map[string]string{"zone": "a"}},
+		machine.MachineState{ID: "B1", Metadata: map[string]string{"zone": "b"}},
+		machine.MachineState{ID: "C1", Metadata: map[string]string{"zone": "c", "disk": "hdd"}},
+		machine.MachineState{ID: "XXX"},
+	}
+
+	for i, tt := range []struct {
+		contents  string
+		scheduled map[string]string
+		want      []string
+	}{
+		// no constraint
+		{
+			"",
+			map[string]string{"web@1.service": "A1"},
+			[]string{"A1", "A2", "B1", "C1", "XXX"},
+		},
+		// machines without the key are left out
+		{
+			"SpreadAcross=zone",
+			nil,
+			[]string{"A1", "A2", "B1", "C1"},
+		},
+		{
+			"SpreadAcross=zone",
+			map[string]string{"web@1.service": "A1"},
+			[]string{"B1", "C1"},
+		},
+		// both machines of a zone count towards it
+		{
+			"SpreadAcross=zone",
+			map[string]string{"web@1.service": "A1", "web@2.service": "B1", "web@3.service": "C1", "web@4.service": "A2"},
+			[]string{"B1", "C1"},
+		},
+		{
+			"SpreadAcross=zone\nMaxSkew=2",
+			map[string]string{"web@1.service": "A1"},
+			[]string{"A1", "A2", "B1", "C1"},
+		},
+		// units of other groups are not counted
+		{
+			"SpreadAcross=zone",
+			map[string]string{"db@1.service": "A1", "web.service": "B1"},
+			[]string{"A1", "A2", "B1", "C1"},
+		},
+		// only the zones of machines able to run the unit bound the skew
+		{
+			"SpreadAcross=zone\nMachineMetadata=zone=a\nMachineMetadata=zone=b",
+			map[string]string{"web@1.service": "A1", "web@2.service": "B1", "web@3.service": "A2"},
+			[]string{"B1"},
+		},
+	} {
+		var units []job.Unit
+		var sUnits []job.ScheduledUnit
+		for name, machID := range tt.scheduled {
+			units = append(units, job.Unit{Name: name, TargetState: job.JobStateLaunched})
+			sUnits = append(sUnits, job.ScheduledUnit{Name: name, TargetMachineID: machID})
+		}
+		clust := newClusterState(units, sUnits, machines)
+
+		j := &job.Job{Name: "web@5.service", Unit: newTestUnit(t, "[X-Fleet]\n"+tt.contents)}
+		var got []string
+		for _, as := range ableAgents(sortedAgentsByID(clust), j) {
+			got = append(got, as.MState.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: able agents %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestSpreadRejections(t *testing.T) {
+	clust := newClusterState(
+		[]job.Unit{job.Unit{Name: "web@1.service", TargetState: job.JobStateLaunched}},
+		[]job.ScheduledUnit{job.ScheduledUnit{Name: "web@1.service", TargetMachineID: "A1"}},
+		[]machine.MachineState{
+			machine.MachineState{ID: "A1", Metadata: map[string]string{"zone": "a"}},
+			machine.MachineState{ID: "B1", Metadata: map[string]string{"zone": "b"}},
+			machine.MachineState{ID: "XXX"},
+		},
+	)
+
+	j := &job.Job{Name: "web@2.service", Unit: newTestUnit(t, "[X-Fleet]\nSpreadAcross=zone")}
+	want := map[string]string{
+		"A1":  "zone=a already runs 1 instances of web@.service, exceeding max skew 1",
+		"XXX": "Machine(XXX) has no zone metadata to spread across",
+	}
+	if got := rejections(clust, j); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected rejections:\ngot\n%v\nwant\n%v", got, want)
+	}
+}
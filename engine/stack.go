@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+)
+
+// stackRejection describes why the pending Jobs of a Stack could not be
+// scheduled
+type stackRejection struct {
+	// reason applies to all pending Jobs of the Stack
+	reason string
+	// jobName is the Job that could not be placed, if any, and machines
+	// the reason each machine was unable to run it
+	jobName  string
+	machines map[string]string
+}
+
+// pendingStackJobs returns the Jobs of the given Stack waiting to be
+// scheduled, i.e. those neither scheduled nor inactive, in the order they are
+// placed. Global Units of the Stack are never pending. If any Unit of the
+// Stack does not exist, e.g. because the Stack is still being submitted, no
+// Jobs are pending and the reason is returned.
+func pendingStackJobs(clust *clusterState, s *registry.Stack) ([]*job.Job, string) {
+	var pending []*job.Job
+	for _, name := range s.Units {
+		if _, ok := clust.gUnits[name]; ok {
+			continue
+		}
+		j, ok := clust.jobs[name]
+		if !ok {
+			return nil, fmt.Sprintf("Unit(%s) of stack %s does not exist", name, s.Name)
+		}
+		if j.Scheduled() || j.TargetState == job.JobStateInactive || clust.completed.Contains(name) {
+			continue
+		}
+		pending = append(pending, j)
+	}
+	sort.Sort(prioritizedJobs(pending))
+	return pending, ""
+}
+
+// scheduleStack places the given pending Jobs of a Stack one after the
+// other, accounting for those already placed, and schedules them in the
+// clusterState if all of them could be placed. Otherwise the clusterState is
+// left as it was and the reason the Jobs could not be scheduled is returned.
+// Jobs of a Stack never preempt other Jobs.
+func scheduleStack(clust *clusterState, sched Scheduler, s *registry.Stack, pending []*job.Job) *stackRejection {
+	var placed []string
+	rollback := func() {
+		for _, name := range placed {
+			clust.unschedule(name)
+		}
+	}
+
+	for _, j := range pending {
+		if reason := j.UnmetDependency(clust.launched, clust.active); reason != "" {
+			rollback()
+			return &stackRejection{
+				reason:  fmt.Sprintf("Unit(%s) of stack %s not schedulable yet: %s", j.Name, s.Name, reason),
+				jobName: j.Name,
+			}
+		}
+
+		if reason := clust.exceedsQuota(j); reason != "" {
+			rollback()
+			return &stackRejection{
+				reason:  fmt.Sprintf("Unit(%s) of stack %s not schedulable: %s", j.Name, s.Name, reason),
+				jobName: j.Name,
+			}
+		}
+
+		dec, err := sched.Decide(clust, j)
+		if err != nil {
+			rej := &stackRejection{
+				reason:   fmt.Sprintf("Unit(%s) of stack %s cannot be scheduled alongside the others: %v", j.Name, s.Name, err),
+				jobName:  j.Name,
+				machines: rejections(clust, j),
+			}
+			rollback()
+			return rej
+		}
+
+		clust.schedule(j.Name, dec.machineID)
+		placed = append(placed, j.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestCalculateClusterTasksStacks(t *testing.T) {
+	web := "[X-Fleet]\nPorts=8080"
+	for i, tt := range []struct {
+		machines []string
+		stack    []string
+		want     []string
+		reason   string
+	}{
+		// both Units bind the same port, so they need two machines
+		{[]string{"XXX", "YYY"}, []string{"web@1.service", "web@2.service"}, []string{"web@1.service", "web@2.service"}, ""},
+		// placing only one of them is not good enough
+		{[]string{"XXX"}, []string{"web@1.service", "web@2.service"}, nil, "Unit(web@2.service) of stack web cannot be scheduled alongside the others: no agents able to run job"},
+		// nothing is scheduled until all Units of the Stack exist
+		{[]string{"XXX", "YYY"}, []string{"web@1.service", "web@2.service", "web@3.service"}, nil, "Unit(web@3.service) of stack web does not exist"},
+	} {
+		units := []job.Unit{
+			job.Unit{Name: "web@1.service", Unit: newTestUnit(t, web), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "web@2.service", Unit: newTestUnit(t, web), TargetState: job.JobStateLaunched},
+			job.Unit{Name: "other.service", TargetState: job.JobStateLaunched},
+		}
+		var machines []machine.MachineState
+		for _, id := range tt.machines {
+			machines = append(machines, machine.MachineState{ID: id})
+		}
+		clust := newClusterState(units, nil, machines)
+		clust.setStacks([]registry.Stack{registry.Stack{Name: "web", Units: tt.stack}})
+
+		r := NewReconciler(&leastLoadedScheduler{}, false)
+		var scheduled []string
+		for tsk := range r.calculateClusterTasks(clust, make(chan struct{})) {
+			if tsk.Type == taskTypeAttemptScheduleUnit && tsk.JobName != "other.service" {
+				scheduled = append(scheduled, tsk.JobName)
+			}
+		}
+		sort.Strings(scheduled)
+
+		if !reflect.DeepEqual(tt.want, scheduled) {
+			t.Errorf("case %d: scheduled %v, want %v", i, scheduled, tt.want)
+		}
+		if tt.reason == "" {
+			if len(clust.rejected) != 0 {
+				t.Errorf("case %d: unexpected rejections %v", i, clust.rejected)
+			}
+			continue
+		}
+		for _, name := range []string{"web@1.service", "web@2.service"} {
+			if got := clust.rejected[name].Reason; got != tt.reason {
+				t.Errorf("case %d: Unit(%s) rejected with %q, want %q", i, name, got, tt.reason)
+			}
+			if clust.jobs[name].Scheduled() {
+				t.Errorf("case %d: Unit(%s) unexpectedly scheduled", i, name)
+			}
+		}
+	}
+}
@@ -1,15 +1,79 @@
 package engine
 
 import (
+	"sort"
+	"time"
+
 	"github.com/coreos/fleet/agent"
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/registry"
 )
 
 type clusterState struct {
 	jobs     map[string]*job.Job
 	gUnits   map[string]*job.Unit
 	machines map[string]*machine.MachineState
+
+	// failures holds the reasons of unexpired failure reports, indexed
+	// by Unit name and then by machine ID
+	failures map[string]map[string]string
+
+	// launched and active hold the names of the Units with target state
+	// launched and of those reported active, respectively, against which
+	// dependencies between Units are checked
+	launched pkg.Set
+	active   pkg.Set
+
+	// rejected holds why Jobs could not be scheduled during the current
+	// reconciliation, indexed by Job name
+	rejected map[string]registry.UnitRejections
+
+	// stacks holds the Stack each Unit belongs to, indexed by Unit name
+	stacks map[string]*registry.Stack
+
+	// completed holds the names of the batch Units that have completed
+	// successfully, which are never scheduled again
+	completed pkg.Set
+
+	// dirty holds what changed since the previous reconciliation, or nil
+	// if all placements are re-evaluated
+	dirty *dirtySet
+
+	// shards holds the shards of the Jobs the local engine schedules, or
+	// nil if it schedules all Jobs
+	shards *shardSet
+
+	// lost holds since when the machines that went away while Jobs are
+	// still scheduled to them have been missing, indexed by machine ID
+	lost map[string]time.Time
+
+	// quotas holds the Quotas of namespaces, and usage what the Units
+	// of each namespace scheduled so far count against its Quota, both
+	// indexed by namespace
+	quotas map[string]registry.Quota
+	usage  map[string]registry.QuotaUsage
+
+	// flaps records the Jobs moved off their machines, holding back the
+	// rescheduling of those moved too often. backoff holds until when
+	// it is held back, and backedOff why it was held back during the
+	// current reconciliation, both indexed by Job name.
+	flaps     *flapDamper
+	backoff   map[string]time.Time
+	backedOff map[string]string
+
+	// placeable holds the IDs of the machines a Scheduler may decide in
+	// favor of, or nil if it may decide in favor of any. The Units of all
+	// machines still count towards conflicts and spreading.
+	placeable pkg.Set
+
+	// displaced holds the machine each Job unscheduled during the current
+	// reconciliation because its machine went away was scheduled to, and
+	// relaxed why the constraints of such Jobs had to be relaxed to place
+	// them, both indexed by Job name
+	displaced map[string]string
+	relaxed   map[string]string
 }
 
 func newClusterState(units []job.Unit, sUnits []job.ScheduledUnit, machines []machine.MachineState) *clusterState {
@@ -48,10 +112,31 @@ func newClusterState(units []job.Unit, sUnits []job.ScheduledUnit, machines []ma
 	}
 
 	return &clusterState{
-		jobs:     jMap,
-		gUnits:   guMap,
-		machines: mMap,
+		jobs:      jMap,
+		gUnits:    guMap,
+		machines:  mMap,
+		failures:  make(map[string]map[string]string),
+		launched:  pkg.NewUnsafeSet(),
+		active:    pkg.NewUnsafeSet(),
+		completed: pkg.NewUnsafeSet(),
+		relaxed:   make(map[string]string),
+	}
+}
+
+// placeableAgents returns the subset of the given agents a Scheduler may
+// decide in favor of, preserving their order
+func (cs *clusterState) placeableAgents(agents []*agent.AgentState) []*agent.AgentState {
+	if cs.placeable == nil {
+		return agents
+	}
+
+	var placeable []*agent.AgentState
+	for _, as := range agents {
+		if cs.placeable.Contains(as.MState.ID) {
+			placeable = append(placeable, as)
+		}
 	}
+	return placeable
 }
 
 func (cs *clusterState) agents() map[string]*agent.AgentState {
@@ -61,6 +146,35 @@ func (cs *clusterState) agents() map[string]*agent.AgentState {
 		agents[ms.ID] = agent.NewAgentState(ms)
 	}
 
+	for name, reports := range cs.failures {
+		for machID, reason := range reports {
+			as, ok := agents[machID]
+			if !ok {
+				continue
+			}
+			if as.Failures == nil {
+				as.Failures = make(map[string]string)
+			}
+			as.Failures[name] = reason
+		}
+	}
+
+	// Global Units are accounted for first, in the same order as the
+	// agents themselves do, so that only those each agent will actually
+	// run count against its resources
+	gNames := make([]string, 0, len(cs.gUnits))
+	for name := range cs.gUnits {
+		gNames = append(gNames, name)
+	}
+	sort.Strings(gNames)
+	gUnits := make([]*job.Unit, len(gNames))
+	for i, name := range gNames {
+		gUnits[i] = cs.gUnits[name]
+	}
+	for _, a := range agents {
+		a.ScheduleGlobalUnits(gUnits)
+	}
+
 	for _, j := range cs.jobs {
 		j := j
 		if !j.Scheduled() || j.TargetState == job.JobStateInactive {
@@ -76,16 +190,80 @@ func (cs *clusterState) agents() map[string]*agent.AgentState {
 		}
 	}
 
-	for _, gu := range cs.gUnits {
-		gu := gu
-		for _, a := range agents {
-			if machine.HasMetadata(a.MState, gu.RequiredTargetMetadata()) {
-				a.Units[gu.Name] = gu
+	return agents
+}
+
+// setStacks records the Stacks the Units of the cluster belong to. A Unit
+// listed in more than one Stack belongs to the first.
+func (cs *clusterState) setStacks(stacks []registry.Stack) {
+	cs.stacks = make(map[string]*registry.Stack)
+	for i := range stacks {
+		s := &stacks[i]
+		for _, name := range s.Units {
+			if _, ok := cs.stacks[name]; !ok {
+				cs.stacks[name] = s
 			}
 		}
 	}
+}
 
-	return agents
+// setCompletions records the batch Units among the given completions that
+// completed successfully
+func (cs *clusterState) setCompletions(completions []registry.UnitCompletion) {
+	for i := range completions {
+		uc := &completions[i]
+		if j, ok := cs.jobs[uc.Name]; ok && j.IsBatch() && uc.Succeeded() {
+			cs.completed.Add(uc.Name)
+		}
+	}
+}
+
+// setQuotas records the given Quotas and what the scheduled Jobs and the
+// global Units of each namespace count against them
+func (cs *clusterState) setQuotas(quotas []registry.Quota) {
+	cs.quotas = make(map[string]registry.Quota, len(quotas))
+	for _, q := range quotas {
+		cs.quotas[q.Namespace] = q
+	}
+
+	cs.usage = make(map[string]registry.QuotaUsage)
+	for _, j := range cs.jobs {
+		if j.Scheduled() {
+			cs.countUsage(j, true)
+		}
+	}
+	for _, u := range cs.gUnits {
+		cs.countUsage(&job.Job{Name: u.Name, Unit: u.Unit}, true)
+	}
+}
+
+// countUsage adds the given Job to, or removes it from, the usage of its
+// namespace
+func (cs *clusterState) countUsage(j *job.Job, add bool) {
+	if cs.usage == nil {
+		return
+	}
+	ns, _ := job.SplitNamespace(j.Name)
+	qu := cs.usage[ns]
+	if add {
+		qu.Add(j)
+	} else {
+		qu.Remove(j)
+	}
+	cs.usage[ns] = qu
+}
+
+// exceedsQuota describes the limit of the Quota of the namespace of the
+// given Job that scheduling it would exceed, if any
+func (cs *clusterState) exceedsQuota(j *job.Job) string {
+	ns, _ := job.SplitNamespace(j.Name)
+	q, ok := cs.quotas[ns]
+	if !ok {
+		return ""
+	}
+	qu := cs.usage[ns]
+	qu.Add(j)
+	return q.Exceeded(qu)
 }
 
 func (cs *clusterState) schedule(jobName, targetMachineID string) {
@@ -93,13 +271,66 @@ func (cs *clusterState) schedule(jobName, targetMachineID string) {
 	if j == nil {
 		return
 	}
+	if !j.Scheduled() {
+		cs.countUsage(j, true)
+	}
 	j.TargetMachineID = targetMachineID
 }
 
+// reject records why the named Job could not be scheduled, along with the
+// reason each machine was unable to run it, if known
+func (cs *clusterState) reject(jobName, reason string, machines map[string]string) {
+	if cs.rejected == nil {
+		cs.rejected = make(map[string]registry.UnitRejections)
+	}
+	cs.rejected[jobName] = registry.UnitRejections{Reason: reason, Machines: machines}
+}
+
+// rejectShortfalls records why the named Job could not be scheduled, along
+// with each constraint of it that each machine failed to meet
+func (cs *clusterState) rejectShortfalls(jobName, reason string, shortfalls map[string][]job.Shortfall) {
+	cs.reject(jobName, reason, shortfallReasons(shortfalls))
+	rej := cs.rejected[jobName]
+	rej.Shortfalls = shortfalls
+	cs.rejected[jobName] = rej
+}
+
+// backOff records that the named Job is not scheduled as its rescheduling
+// is held back until the given time
+func (cs *clusterState) backOff(jobName string, until time.Time) {
+	cs.reject(jobName, "unit was rescheduled too often", nil)
+	rej := cs.rejected[jobName]
+	rej.BackoffUntil = &until
+	cs.rejected[jobName] = rej
+}
+
+// moved records that the named Job was moved off its machine, holding back
+// its rescheduling if it was moved too often
+func (cs *clusterState) moved(jobName string, now time.Time) {
+	until, reason := cs.flaps.moved(jobName, now)
+	if until.IsZero() {
+		return
+	}
+	if cs.backoff == nil {
+		cs.backoff = make(map[string]time.Time)
+	}
+	if cs.backedOff == nil {
+		cs.backedOff = make(map[string]string)
+	}
+	cs.backoff[jobName] = until
+	cs.backedOff[jobName] = reason
+}
+
 func (cs *clusterState) unschedule(jobName string) {
 	j := cs.jobs[jobName]
 	if j == nil {
 		return
 	}
+	if j.Scheduled() {
+		cs.countUsage(j, false)
+	}
 	j.TargetMachineID = ""
+	if cs.dirty != nil {
+		cs.dirty.unscheduled(jobName)
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/coreos/fleet/agent"
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/unit"
 )
 
@@ -331,3 +332,31 @@ func TestClusterStateAgents(t *testing.T) {
 		}
 	}
 }
+
+func TestClusterStateAgentsGlobalResources(t *testing.T) {
+	units := []job.Unit{
+		job.Unit{
+			Name:        "a.service",
+			Unit:        newTestUnit(t, "[X-Fleet]\nGlobal=true\nMemoryReservation=512"),
+			TargetState: job.JobStateLaunched,
+		},
+		job.Unit{
+			Name:        "b.service",
+			Unit:        newTestUnit(t, "[X-Fleet]\nGlobal=true\nMemoryReservation=512"),
+			TargetState: job.JobStateLaunched,
+		},
+	}
+	machines := []machine.MachineState{
+		machine.MachineState{ID: "XXX", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 1024}},
+		machine.MachineState{ID: "YYY", TotalResources: resource.ResourceTuple{Cores: 100, Memory: 2048}},
+	}
+	agents := newClusterState(units, []job.ScheduledUnit{}, machines).agents()
+
+	// XXX only has 768MB to spare, so b.service is skipped there
+	if _, ok := agents["XXX"].Units["a.service"]; !ok || len(agents["XXX"].Units) != 1 {
+		t.Errorf("Expected only a.service on XXX, got %v", agents["XXX"].Units)
+	}
+	if len(agents["YYY"].Units) != 2 {
+		t.Errorf("Expected both global Units on YYY, got %v", agents["YYY"].Units)
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+// stepDownRequested determines whether the engine of the given machine was
+// asked to give up its leases. Errors are taken as no request, so that an
+// unreachable request does not stop the cluster from being scheduled.
+func (e *Engine) stepDownRequested(machID string) bool {
+	requested, err := e.registry.EngineStepDownRequested(machID)
+	if err != nil {
+		log.Errorf("Failed checking for engine step-down request: %v", err)
+		return false
+	}
+	return requested
+}
+
+// releaseLeases gives up all leases held by the local engine, so that the
+// engines of other machines take over its work
+func (e *Engine) releaseLeases() {
+	for shard, l := range e.leases {
+		if err := l.Release(); err != nil {
+			log.Errorf("Failed to release lease: %v", err)
+		}
+		delete(e.leases, shard)
+	}
+	metricShards.Set(0)
+}
+
+// publishStatus publishes the EngineStatus of the local engine after a
+// reconciliation, which started at the given time and took elapsed, with
+// leases expiring at the given time
+func (e *Engine) publishStatus(machID string, expires, start time.Time, elapsed, ttl time.Duration) {
+	e.reconciles++
+	es := registry.EngineStatus{
+		MachineID:             machID,
+		Version:               engineVersion,
+		LeaseExpires:          expires,
+		Reconciles:            e.reconciles,
+		LastReconcile:         start,
+		LastReconcileDuration: elapsed,
+	}
+	if e.shards > 1 {
+		es.Shards = e.heldShards()
+	}
+	if err := e.registry.SaveEngineStatus(es, ttl); err != nil {
+		log.Errorf("Failed publishing engine status: %v", err)
+	}
+}
+
+// clearStatus removes the EngineStatus published by the local engine, if
+// any, once it no longer holds any lease
+func (e *Engine) clearStatus(machID string) {
+	if e.reconciles == 0 {
+		return
+	}
+	e.reconciles = 0
+	if err := e.registry.RemoveEngineStatus(machID); err != nil {
+		log.Errorf("Failed removing engine status: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestEngineStatus(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX"},
+		machine.MachineState{ID: "YYY"},
+	})
+	lReg := registry.NewFakeLeaseRegistry()
+	e := &Engine{registry: reg, lRegistry: lReg, shards: 4}
+	e.updateLeases("XXX", time.Minute)
+
+	start := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	e.publishStatus("XXX", start.Add(time.Minute), start, time.Second, time.Minute)
+	e.publishStatus("XXX", start.Add(2*time.Minute), start.Add(time.Minute), 2*time.Second, time.Minute)
+
+	want := []registry.EngineStatus{
+		registry.EngineStatus{
+			MachineID:             "XXX",
+			Version:               engineVersion,
+			Shards:                e.heldShards(),
+			LeaseExpires:          start.Add(2 * time.Minute),
+			Reconciles:            2,
+			LastReconcile:         start.Add(time.Minute),
+			LastReconcileDuration: 2 * time.Second,
+		},
+	}
+	if got, _ := reg.EngineStatuses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected statuses:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	// stepping down gives up all leases and the status
+	e.releaseLeases()
+	if len(e.leases) != 0 {
+		t.Errorf("Expected no shards held, got %v", e.heldShards())
+	}
+	for shard := 0; shard < 4; shard++ {
+		if l, _ := lReg.GetLease(shardLeaseName(shard, 4)); l != nil {
+			t.Errorf("Lease of shard %d still held by %s", shard, l.MachineID())
+		}
+	}
+	e.clearStatus("XXX")
+	if got, _ := reg.EngineStatuses(); len(got) != 0 {
+		t.Errorf("Unexpected statuses after stepping down: %#v", got)
+	}
+	if e.reconciles != 0 {
+		t.Errorf("Expected reconciliations to be counted from zero again, got %d", e.reconciles)
+	}
+}
+
+func TestStepDownRequested(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	e := &Engine{registry: reg}
+	if e.stepDownRequested("XXX") {
+		t.Errorf("Unexpected step-down request")
+	}
+	reg.RequestEngineStepDown("XXX", time.Minute)
+	if !e.stepDownRequested("XXX") {
+		t.Errorf("Expected step-down request")
+	}
+	if e.stepDownRequested("YYY") {
+		t.Errorf("Step-down request applies to other machine")
+	}
+}
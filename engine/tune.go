@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/log"
+)
+
+// Tuning holds the settings of an Engine that may change while it runs,
+// e.g. as the configuration of the cluster changes
+type Tuning struct {
+	// ReconcileInterval is how often the Engine reconciles at least
+	ReconcileInterval time.Duration
+	// Scheduler decides where Units are placed
+	Scheduler Scheduler
+	// RescheduleDelay is how long Units declaring no RescheduleDelay are
+	// left scheduled to a machine that went away
+	RescheduleDelay time.Duration
+}
+
+// Tune replaces the settings of the running Engine. They take effect as
+// of its next reconciliation.
+func (e *Engine) Tune(t Tuning) {
+	e.tuneMutex.Lock()
+	defer e.tuneMutex.Unlock()
+	e.tuning = &t
+}
+
+// takeTuning returns the settings passed to Tune since it was last called,
+// or nil if there are none
+func (e *Engine) takeTuning() *Tuning {
+	e.tuneMutex.Lock()
+	defer e.tuneMutex.Unlock()
+	t := e.tuning
+	e.tuning = nil
+	return t
+}
+
+// applyTuning applies the settings passed to Tune since the last
+// reconciliation, if any, returning the reconcile interval to use from now on
+func (e *Engine) applyTuning(ival time.Duration) time.Duration {
+	t := e.takeTuning()
+	if t == nil {
+		return ival
+	}
+
+	if t.ReconcileInterval != ival {
+		log.Infof("Engine reconcile interval changed from %v to %v", ival, t.ReconcileInterval)
+	}
+	if t.RescheduleDelay != e.rec.rescheduleDelay {
+		log.Infof("Engine reschedule delay changed from %v to %v", e.rec.rescheduleDelay, t.RescheduleDelay)
+	}
+	e.rec.sched = t.Scheduler
+	e.rec.rescheduleDelay = t.RescheduleDelay
+	return t.ReconcileInterval
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyTuning(t *testing.T) {
+	e := &Engine{rec: NewReconciler(&leastLoadedScheduler{}, false)}
+	e.rec.rescheduleDelay = time.Minute
+
+	// nothing changes until tuned
+	if ival := e.applyTuning(2 * time.Second); ival != 2*time.Second {
+		t.Errorf("Untuned Engine changed interval to %v", ival)
+	}
+
+	sched := newRandomScheduler()
+	e.Tune(Tuning{ReconcileInterval: 5 * time.Second, Scheduler: sched, RescheduleDelay: 0})
+	if ival := e.applyTuning(2 * time.Second); ival != 5*time.Second {
+		t.Errorf("Tuned Engine has interval %v, want 5s", ival)
+	}
+	if e.rec.sched != sched {
+		t.Errorf("Tuned Engine kept Scheduler %T", e.rec.sched)
+	}
+	if e.rec.rescheduleDelay != 0 {
+		t.Errorf("Tuned Engine has reschedule delay %v, want 0", e.rec.rescheduleDelay)
+	}
+
+	// tuning is applied once
+	if ival := e.applyTuning(5 * time.Second); ival != 5*time.Second {
+		t.Errorf("Tuning applied again, changing interval to %v", ival)
+	}
+}
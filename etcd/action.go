@@ -19,6 +19,7 @@ type Action interface {
 type Delete struct {
 	Key           string
 	Recursive     bool
+	Dir           bool
 	PreviousValue string
 	PreviousIndex uint64
 }
@@ -32,6 +33,9 @@ func (del *Delete) HTTPRequest() (*http.Request, error) {
 
 	params := endpoint.Query()
 	params.Add("recursive", strconv.FormatBool(del.Recursive))
+	if del.Dir {
+		params.Add("dir", "true")
+	}
 	if del.PreviousValue != "" {
 		params.Add("prevValue", del.PreviousValue)
 	}
@@ -79,6 +83,40 @@ func (c *Create) HTTPRequest() (*http.Request, error) {
 	return req, nil
 }
 
+// CreateInOrder creates a node with a unique, increasing key in the
+// directory identified by Dir
+type CreateInOrder struct {
+	Dir   string
+	Value string
+	TTL   time.Duration
+}
+
+func (c *CreateInOrder) String() string {
+	return fmt.Sprintf("{CreateInOrder %s}", c.Dir)
+}
+
+func (c *CreateInOrder) HTTPRequest() (*http.Request, error) {
+	endpoint := v2URL(c.Dir)
+
+	form := url.Values{}
+	form.Set("value", c.Value)
+
+	ttl := int64(c.TTL.Seconds())
+	if ttl > 0 {
+		form.Set("ttl", strconv.FormatInt(ttl, 10))
+	}
+
+	body := strings.NewReader(form.Encode())
+
+	req, err := http.NewRequest("POST", endpoint.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
+	return req, nil
+}
+
 type Update struct {
 	Key   string
 	Value string
@@ -59,6 +59,25 @@ func TestCreateHTTPRequest(t *testing.T) {
 	driveActionTestCases(t, tests)
 }
 
+func TestCreateInOrderHTTPRequest(t *testing.T) {
+	tests := []actionTestCase{
+		{
+			&CreateInOrder{Dir: "/foo"},
+			"POST",
+			"/v2/keys/foo",
+			"value=",
+		},
+		{
+			&CreateInOrder{Dir: "/foo", TTL: 5 * time.Minute, Value: "bar"},
+			"POST",
+			"/v2/keys/foo",
+			"ttl=300&value=bar",
+		},
+	}
+
+	driveActionTestCases(t, tests)
+}
+
 func TestUpdateHTTPRequest(t *testing.T) {
 	tests := []actionTestCase{
 		{
@@ -184,6 +203,12 @@ func TestDeleteHTTPRequest(t *testing.T) {
 			"/v2/keys/foo?prevIndex=12&prevValue=bar&recursive=false",
 			"",
 		},
+		{
+			&Delete{Key: "/foo", Dir: true},
+			"DELETE",
+			"/v2/keys/foo?dir=true&recursive=false",
+			"",
+		},
 	}
 
 	driveActionTestCases(t, tests)
@@ -103,6 +103,16 @@ type client struct {
 	endpoints     []url.URL
 	transport     transport
 	actionTimeout time.Duration
+
+	policy  RetryPolicy
+	breaker *breaker
+}
+
+// SetRetryPolicy changes how the client retries Actions and when it stops
+// attempting them altogether
+func (c *client) SetRetryPolicy(p RetryPolicy) {
+	c.policy = p
+	c.breaker = newBreaker(p.BreakerThreshold, p.BreakerCooldown)
 }
 
 // a requestFunc must never return a nil *http.Response and a nil error together
@@ -158,8 +168,9 @@ func (c *client) requestHTTP(req *http.Request, cancel <-chan struct{}) (resp *h
 // Attempt to get a usable Result for the provided Action.
 // - this call will block until the provided channel is closed
 // - requests are attempted against all configured endpoints
-// - exponential backoff is used before reattempting resolution
-//   of the given Action against the set of endpoints
+// - exponential backoff, following the RetryPolicy of the client, is
+//   used before reattempting resolution of the given Action against the
+//   set of endpoints
 // - up to 10 redirects are followed per endpoint per attempt
 // If the provided channel is closed before a Result can be
 // retrieved, a nil object is returned.
@@ -184,8 +195,7 @@ func (c *client) resolve(act Action, rf requestFunc, cancel <-chan struct{}) (*R
 	}
 
 	backoff := func(fn func() (*Result, error)) (res *Result, err error) {
-		sleep := 100 * time.Millisecond
-		for {
+		for retry := 1; ; retry++ {
 			res, err = fn()
 			if res != nil || err != nil {
 				break
@@ -197,18 +207,15 @@ func (c *client) resolve(act Action, rf requestFunc, cancel <-chan struct{}) (*R
 			default:
 			}
 
+			sleep := c.policy.backoff(retry)
 			log.Errorf("Unable to get result for %v, retrying in %v", act, sleep)
+			metricRequestRetries.Inc(actionName(act))
 
 			select {
 			case <-cancel:
 				return nil, errors.New("cancelled")
 			case <-time.After(sleep):
 			}
-
-			sleep = sleep * 2
-			if sleep > time.Second {
-				sleep = time.Second
-			}
 		}
 		return
 	}
@@ -217,13 +224,22 @@ func (c *client) resolve(act Action, rf requestFunc, cancel <-chan struct{}) (*R
 }
 
 // Make any necessary HTTP requests to resolve the given Action, returning
-// a Result if one can be acquired. This function call will wait 10s before
-// aborting any in-flight requests and returning an error.
+// a Result if one can be acquired. This function call will wait for the
+// action timeout of the client, or that of the RetryPolicy for this kind
+// of Action, before aborting any in-flight requests and returning an error.
+// While the circuit breaker of the RetryPolicy is open, ErrCircuitOpen is
+// returned right away.
 func (c *client) Do(act Action) (*Result, error) {
 	type re struct {
 		res *Result
 		err error
 	}
+	start := time.Now()
+	if !c.breaker.allow() {
+		observeRequest(act, start, ErrCircuitOpen)
+		return nil, ErrCircuitOpen
+	}
+
 	cancel := make(chan struct{})
 	result := make(chan re)
 
@@ -233,10 +249,17 @@ func (c *client) Do(act Action) (*Result, error) {
 	}()
 
 	select {
-	case <-time.After(c.actionTimeout):
+	case <-time.After(c.policy.timeout(act, c.actionTimeout)):
 		close(cancel)
-		return nil, errors.New("timeout reached")
+		err := errors.New("timeout reached")
+		observeRequest(act, start, err)
+		c.breaker.record(false)
+		return nil, err
 	case r := <-result:
+		observeRequest(act, start, r.err)
+		// Error responses from etcd itself mean it is available
+		_, ok := r.err.(Error)
+		c.breaker.record(r.err == nil || ok)
 		return r.res, r.err
 	}
 }
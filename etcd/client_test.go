@@ -586,7 +586,7 @@ func (n *nilNilTransport) CancelRequest(req *http.Request) {}
 
 // Ensure that any request that somehow returns (nil, nil) propagates an actual error
 func TestNilNilRequestHTTP(t *testing.T) {
-	c := &client{[]url.URL{}, &nilNilTransport{}, time.Second}
+	c := &client{endpoints: []url.URL{}, transport: &nilNilTransport{}, actionTimeout: time.Second}
 	cancel := make(chan struct{})
 	resp, body, err := c.requestHTTP(nil, cancel)
 	if err == nil {
@@ -620,7 +620,7 @@ func (r *respAndErrTransport) CancelRequest(req *http.Request) {}
 
 // Ensure that the body of a response is closed even when an error is returned
 func TestRespAndErrRequestHTTP(t *testing.T) {
-	c := &client{[]url.URL{}, &respAndErrTransport{}, time.Second}
+	c := &client{endpoints: []url.URL{}, transport: &respAndErrTransport{}, actionTimeout: time.Second}
 	cancel := make(chan struct{})
 	resp, body, err := c.requestHTTP(nil, cancel)
 	if err == nil {
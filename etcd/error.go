@@ -8,7 +8,9 @@ import (
 
 const (
 	ErrorKeyNotFound       = 100
+	ErrorTestFailed        = 101
 	ErrorNodeExist         = 105
+	ErrorDirNotEmpty       = 108
 	ErrorEventIndexCleared = 401
 )
 
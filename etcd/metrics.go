@@ -0,0 +1,50 @@
+package etcd
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/metrics"
+)
+
+var (
+	metricRequestDuration = metrics.NewHistogram(
+		"fleet_etcd_request_duration_seconds",
+		"Time taken to resolve an action against etcd, by action.",
+		metrics.DefaultBuckets,
+		"action",
+	)
+	metricRequestErrors = metrics.NewCounter(
+		"fleet_etcd_request_errors_total",
+		"Number of actions that could not be resolved against etcd, by action. Error responses from etcd itself, such as a missing key, are not counted.",
+		"action",
+	)
+)
+
+// actionName returns the label under which metrics of the given Action are
+// reported
+func actionName(act Action) string {
+	switch act.(type) {
+	case *Get:
+		return "get"
+	case *Set:
+		return "set"
+	case *Create, *CreateInOrder:
+		return "create"
+	case *Update:
+		return "update"
+	case *Delete:
+		return "delete"
+	case *Watch:
+		return "watch"
+	}
+	return "unknown"
+}
+
+// observeRequest records the duration and outcome of an Action
+func observeRequest(act Action, start time.Time, err error) {
+	name := actionName(act)
+	metricRequestDuration.ObserveSince(start, name)
+	if _, ok := err.(Error); err != nil && !ok {
+		metricRequestErrors.Inc(name)
+	}
+}
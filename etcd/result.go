@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -12,6 +13,10 @@ type Result struct {
 	Node     *Node  `json:"node"`
 	PrevNode *Node  `json:"prevNode"`
 	Raw      []byte `json:"-"`
+
+	// Index is the etcd index at the time the response was sent, as
+	// reported in the X-Etcd-Index header. It is zero if unknown.
+	Index uint64 `json:"-"`
 }
 
 func (r *Result) String() string {
@@ -24,6 +29,7 @@ type Node struct {
 	Value         string `json:"value"`
 	TTL           int    `json:"ttl"`
 	Nodes         Nodes  `json:"nodes"`
+	Dir           bool   `json:"dir,omitempty"`
 	ModifiedIndex uint64 `json:"modifiedIndex"`
 	CreatedIndex  uint64 `json:"createdIndex"`
 }
@@ -48,5 +54,8 @@ func unmarshalSuccessfulResponse(resp *http.Response, body []byte) (*Result, err
 	}
 
 	res.Raw = body
+	if idx := resp.Header.Get("X-Etcd-Index"); idx != "" {
+		res.Index, _ = strconv.ParseUint(idx, 10, 64)
+	}
 	return &res, nil
 }
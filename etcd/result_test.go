@@ -51,6 +51,16 @@ func TestUnmarshalSuccessfulResponseNoNodes(t *testing.T) {
 			false,
 		},
 
+		// Index header
+		{
+			http.Response{
+				Header: http.Header{"X-Etcd-Index": {"123"}},
+				Body:   ioutil.NopCloser(strings.NewReader(`{"action":"get", "node": {"key": "/foo", "value": "bar", "modifiedIndex": 12, "createdIndex": 10}}`)),
+			},
+			&Result{Action: "get", Node: &Node{Key: "/foo", Value: "bar", ModifiedIndex: 12, CreatedIndex: 10}, Index: 123},
+			false,
+		},
+
 		// Garbage in body
 		{
 			http.Response{
@@ -90,6 +100,10 @@ func TestUnmarshalSuccessfulResponseNoNodes(t *testing.T) {
 		if !reflect.DeepEqual(res.Node, tt.res.Node) {
 			t.Errorf("case %d: Node=%v, expected %v", i, res.Node, tt.res.Node)
 		}
+
+		if res.Index != tt.res.Index {
+			t.Errorf("case %d: Index=%d, expected %d", i, res.Index, tt.res.Index)
+		}
 	}
 }
 
@@ -0,0 +1,245 @@
+package etcd
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/metrics"
+)
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = time.Second
+
+	breakerClosed   = 0
+	breakerOpen     = 1
+	breakerHalfOpen = 2
+)
+
+var (
+	// ErrCircuitOpen is returned for Actions not attempted because too
+	// many Actions failed in a row recently
+	ErrCircuitOpen = errors.New("circuit breaker open, etcd deemed unavailable")
+
+	metricRequestRetries = metrics.NewCounter(
+		"fleet_etcd_request_retries_total",
+		"Number of times an action was retried after failing against all etcd endpoints, by action.",
+		"action",
+	)
+	metricBreakerState = metrics.NewGauge(
+		"fleet_etcd_circuit_breaker_state",
+		"State of the circuit breaker guarding etcd: 0 when closed, 1 when open, 2 when half-open.",
+	)
+	metricBreakerTrips = metrics.NewCounter(
+		"fleet_etcd_circuit_breaker_trips_total",
+		"Number of times the circuit breaker guarding etcd opened.",
+	)
+)
+
+// A RetryPolicy configures how a Client retries Actions that could not be
+// resolved against any endpoint, how long it allows each Action, and when
+// it stops attempting Actions altogether. The zero value retries with the
+// default backoff and never opens the circuit breaker.
+type RetryPolicy struct {
+	// InitialBackoff is how long to wait before retrying an Action the
+	// first time. Each further wait doubles, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Jitter is the fraction, between 0 and 1, of each wait that is
+	// randomized, so that clients do not retry in lockstep
+	Jitter float64
+
+	// Timeouts overrides the action timeout of the Client for the kinds
+	// of Actions given: get, set, create, update or delete
+	Timeouts map[string]time.Duration
+
+	// BreakerThreshold is the number of Actions in a row that must fail
+	// for the circuit breaker to open, failing further Actions right away
+	// with ErrCircuitOpen. Zero disables the circuit breaker.
+	BreakerThreshold int
+
+	// BreakerCooldown is how long the circuit breaker stays open before
+	// letting a trial Action through. If it succeeds, the circuit breaker
+	// closes again; otherwise it stays open for another cooldown.
+	BreakerCooldown time.Duration
+}
+
+// Validate ensures the RetryPolicy is usable
+func (p RetryPolicy) Validate() error {
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return errors.New("backoff must not be negative")
+	}
+	if p.MaxBackoff > 0 && p.MaxBackoff < p.InitialBackoff {
+		return fmt.Errorf("maximum backoff %s is shorter than initial backoff %s", p.MaxBackoff, p.InitialBackoff)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("jitter %v must be between 0 and 1", p.Jitter)
+	}
+	for name, d := range p.Timeouts {
+		if d <= 0 {
+			return fmt.Errorf("timeout of %s actions must be positive", name)
+		}
+	}
+	if p.BreakerThreshold < 0 {
+		return errors.New("circuit breaker threshold must not be negative")
+	}
+	if p.BreakerThreshold > 0 && p.BreakerCooldown <= 0 {
+		return errors.New("circuit breaker cooldown must be positive")
+	}
+	return nil
+}
+
+// backoff returns how long to wait before the given retry of an Action,
+// counting from 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	initial, max := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	if max < initial {
+		max = initial
+	}
+
+	d := initial
+	for i := 1; i < retry && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	if p.Jitter > 0 {
+		spread := time.Duration(float64(d) * p.Jitter)
+		d = d - spread + time.Duration(rand.Int63n(int64(2*spread)+1))
+	}
+	return d
+}
+
+// timeout returns how long the given Action is allowed, falling back to the
+// given default
+func (p RetryPolicy) timeout(act Action, def time.Duration) time.Duration {
+	if d, ok := p.Timeouts[actionName(act)]; ok {
+		return d
+	}
+	return def
+}
+
+// ParseTimeouts parses per-action timeouts given as a comma-separated list
+// like "get=1s,set=2s", as used in RetryPolicy
+func ParseTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("timeout %q must be of the form ACTION=DURATION", field)
+		}
+		name := strings.TrimSpace(parts[0])
+		switch name {
+		case "get", "set", "create", "update", "delete":
+		default:
+			return nil, fmt.Errorf("unknown action %q, must be get, set, create, update or delete", name)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout of %s actions: %v", name, err)
+		}
+		timeouts[name] = d
+	}
+	return timeouts, nil
+}
+
+// breaker is a circuit breaker failing Actions right away once too many
+// Actions failed in a row, sparing an unavailable etcd and its clients
+// from requests that would time out anyway
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	// trial is set while the Action let through by a half-open breaker
+	// has yet to complete
+	trial bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold > 0 {
+		metricBreakerState.Set(breakerClosed)
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow determines whether an Action may be attempted
+func (b *breaker) allow() bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.trial = true
+		return true
+	case breakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+// record accounts for the outcome of an attempted Action
+func (b *breaker) record(ok bool) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.trial = false
+	if ok {
+		b.failures = 0
+		if b.state != breakerClosed {
+			log.Infof("etcd available again, closing circuit breaker")
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		log.Warningf("%d etcd actions failed in a row, opening circuit breaker for %s", b.failures, b.cooldown)
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+		metricBreakerTrips.Inc()
+	}
+}
+
+func (b *breaker) setState(state int) {
+	b.state = state
+	metricBreakerState.Set(float64(state))
+}
@@ -0,0 +1,120 @@
+package etcd
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	var zero RetryPolicy
+	for retry, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if got := zero.backoff(retry + 1); got != want {
+			t.Errorf("retry %d: got backoff %v, want %v", retry+1, got, want)
+		}
+	}
+
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(10); got < 2*time.Second || got > 6*time.Second {
+			t.Fatalf("backoff %v outside of jittered range", got)
+		}
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	for i, tt := range []struct {
+		p  RetryPolicy
+		ok bool
+	}{
+		{RetryPolicy{}, true},
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Jitter: 0.2, BreakerThreshold: 5, BreakerCooldown: time.Second}, true},
+		{RetryPolicy{InitialBackoff: 2 * time.Second, MaxBackoff: time.Second}, false},
+		{RetryPolicy{Jitter: 1.5}, false},
+		{RetryPolicy{BreakerThreshold: 5}, false},
+		{RetryPolicy{Timeouts: map[string]time.Duration{"get": 0}}, false},
+	} {
+		if err := tt.p.Validate(); (err == nil) != tt.ok {
+			t.Errorf("case %d: unexpected result %v", i, err)
+		}
+	}
+}
+
+func TestParseTimeouts(t *testing.T) {
+	got, err := ParseTimeouts("get=500ms, set=2s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]time.Duration{"get": 500 * time.Millisecond, "set": 2 * time.Second}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, s := range []string{"get", "watch=1s", "get=soon"} {
+		if _, err := ParseTimeouts(s); err == nil {
+			t.Errorf("Expected error parsing %q", s)
+		}
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.record(false)
+	if !b.allow() {
+		t.Fatalf("breaker open after a single failure")
+	}
+	b.record(true)
+	b.record(false)
+	if !b.allow() {
+		t.Fatalf("success did not reset failures")
+	}
+	b.record(false)
+	if b.allow() {
+		t.Fatalf("breaker closed after two failures in a row")
+	}
+
+	// a single trial is let through once the cooldown is over
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatalf("breaker still open after cooldown")
+	}
+	if b.allow() {
+		t.Fatalf("breaker let through a second trial")
+	}
+	b.record(false)
+	if b.allow() {
+		t.Fatalf("breaker closed after failed trial")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatalf("breaker still open after cooldown")
+	}
+	b.record(true)
+	if !b.allow() || !b.allow() {
+		t.Fatalf("breaker open after successful trial")
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	c := &client{
+		endpoints:     []url.URL{url.URL{Scheme: "http", Host: "192.0.2.1:4001", Path: "/"}},
+		transport:     &nilNilTransport{},
+		actionTimeout: 10 * time.Millisecond,
+	}
+	c.SetRetryPolicy(RetryPolicy{BreakerThreshold: 2, BreakerCooldown: time.Hour})
+
+	act := &Get{Key: "/foo"}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Do(act); err == nil || err == ErrCircuitOpen {
+			t.Fatalf("attempt %d: expected timeout, got %v", i, err)
+		}
+	}
+	if _, err := c.Do(act); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+}
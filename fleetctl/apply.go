@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/fleet/api"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+var (
+	applyFlags = struct {
+		Dir    string
+		Prune  bool
+		DryRun bool
+	}{}
+
+	cmdApply = &Command{
+		Name:    "apply",
+		Summary: "Make the units of the cluster match a directory of unit files",
+		Usage:   "-f DIR [--prune] [--dry-run]",
+		Description: `Compare the units of the cluster with a directory describing the units it
+should run, print the changes needed to get there, and make them. Running apply
+again with the same directory changes nothing.
+
+Every unit file in the directory is submitted and started, except template
+units, which are only submitted. Units whose unit file differs from the one in
+the cluster are destroyed and submitted again. Files whose names are not unit
+names are ignored, besides YAML files (ending in .yaml or .yml), which are
+either stack files as read by "fleetctl submit-stack", or scale manifests
+setting the number of instances of template units as "fleetctl scale" does:
+
+	scale:
+	  web@.service: 3
+
+Unit files listed by a stack file are only submitted as part of the stack.
+Instances of an updated template keep running their previous unit file until
+replaced, for example by "fleetctl rolling-update".
+
+With --prune, units and stacks of the cluster not described by the directory
+are destroyed, apart from the instances the engine maintains of the templates
+scaled by the directory. With --dry-run, the changes are only printed.
+
+Show what applying a directory would change:
+	fleetctl apply --dry-run -f units/
+
+Make the cluster run exactly the units of a directory:
+	fleetctl apply --prune -f units/`,
+		Run: runApply,
+	}
+)
+
+func init() {
+	cmdApply.Flags.StringVar(&applyFlags.Dir, "file", "", "Directory of unit files, stack files and scale manifests to apply.")
+	cmdApply.Flags.StringVar(&applyFlags.Dir, "f", "", "Shorthand for --file")
+	cmdApply.Flags.BoolVar(&applyFlags.Prune, "prune", false, "Destroy units and stacks not described by the directory.")
+	cmdApply.Flags.BoolVar(&applyFlags.DryRun, "dry-run", false, "Only print the changes that would be made.")
+}
+
+// applyManifest is the desired state of the cluster described by a
+// directory
+type applyManifest struct {
+	units  map[string]*unit.UnitFile
+	stacks map[string]*schema.Stack
+	scales map[string]int
+}
+
+// applyChange is a single step of bringing the cluster to the desired state
+type applyChange struct {
+	desc string
+	do   func() error
+}
+
+func runApply(args []string) (exit int) {
+	if len(args) != 0 || applyFlags.Dir == "" {
+		stderr("One directory must be provided with -f.")
+		return 1
+	}
+
+	m, err := readApplyDir(applyFlags.Dir)
+	if err != nil {
+		stderr("Error reading %s: %v", applyFlags.Dir, err)
+		return 1
+	}
+
+	changes, err := planApply(m, applyFlags.Prune)
+	if err != nil {
+		stderr("Unable to apply %s: %v", applyFlags.Dir, err)
+		return 1
+	}
+
+	defer out.Flush()
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes.")
+		return
+	}
+	for _, c := range changes {
+		fmt.Fprintln(out, c.desc)
+	}
+	if applyFlags.DryRun {
+		return
+	}
+	out.Flush()
+
+	for _, c := range changes {
+		if err := c.do(); err != nil {
+			stderr("Error applying change %q: %v", c.desc, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(out, "Applied %d changes.\n", len(changes))
+	return
+}
+
+// readApplyDir reads the desired state of the cluster from the unit files,
+// stack files and scale manifests in the given directory
+func readApplyDir(dir string) (*applyManifest, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := applyManifest{
+		units:  make(map[string]*unit.UnitFile),
+		stacks: make(map[string]*schema.Stack),
+		scales: make(map[string]int),
+	}
+	// stackUnits holds the unit files of all stacks, indexed by unit
+	// name, and stacked the paths of the unit files listed by stack files
+	stackUnits := make(map[string]*unit.UnitFile)
+	stacked := make(map[string]bool)
+	var unitFiles []string
+
+	for _, fi := range entries {
+		name := fi.Name()
+		if fi.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		file := filepath.Join(dir, name)
+
+		switch filepath.Ext(name) {
+		case ".yaml", ".yml":
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if isScaleManifest(b) {
+				scales, err := parseScaleManifest(bytes.NewReader(b))
+				if err != nil {
+					return nil, fmt.Errorf("failed parsing scale manifest %s: %v", name, err)
+				}
+				for tmpl, count := range scales {
+					if _, ok := m.scales[tmpl]; ok {
+						return nil, fmt.Errorf("template unit %s is scaled more than once", tmpl)
+					}
+					m.scales[tmpl] = count
+				}
+				continue
+			}
+
+			sf, err := parseStackFile(bytes.NewReader(b))
+			if err != nil {
+				return nil, fmt.Errorf("failed parsing stack file %s: %v", name, err)
+			}
+			if sf.Name == "" {
+				sf.Name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if _, ok := m.stacks[sf.Name]; ok {
+				return nil, fmt.Errorf("stack %s is described more than once", sf.Name)
+			}
+			files, err := stackUnitFiles(sf, dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading units of stack %s: %v", sf.Name, err)
+			}
+
+			s := schema.Stack{Name: sf.Name}
+			for _, su := range files {
+				s.Units = append(s.Units, su.name)
+				stackUnits[su.name] = su.uf
+			}
+			if err := api.ValidateStack(&s); err != nil {
+				return nil, fmt.Errorf("invalid stack %s: %v", sf.Name, err)
+			}
+			m.stacks[s.Name] = &s
+
+			for _, su := range sf.Units {
+				f := su.File
+				if !filepath.IsAbs(f) {
+					f = filepath.Join(dir, f)
+				}
+				stacked[filepath.Clean(f)] = true
+			}
+		default:
+			if isUnitFileName(name) {
+				unitFiles = append(unitFiles, file)
+			}
+		}
+	}
+
+	for _, file := range unitFiles {
+		if stacked[filepath.Clean(file)] {
+			continue
+		}
+		name := filepath.Base(file)
+		if err := api.ValidateName(name); err != nil {
+			return nil, fmt.Errorf("invalid unit file %s: %v", name, err)
+		}
+		uf, err := getUnitFromFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting unit from file %s: %v", name, err)
+		}
+		m.units[name] = uf
+	}
+
+	var stacks []*schema.Stack
+	for _, name := range sortedStackNames(m.stacks) {
+		s := m.stacks[name]
+		if err := api.StackOverlap(s, stacks); err != nil {
+			return nil, fmt.Errorf("invalid stack %s: %v", s.Name, err)
+		}
+		stacks = append(stacks, s)
+	}
+	for name, uf := range stackUnits {
+		if _, ok := m.units[name]; ok {
+			return nil, fmt.Errorf("unit %s is described by both a unit file and a stack", name)
+		}
+		m.units[name] = uf
+	}
+
+	for tmpl := range m.scales {
+		if uni := unit.NewUnitNameInfo(tmpl); uni == nil || !uni.IsTemplate() {
+			return nil, fmt.Errorf("unit %s is not a template unit, so cannot be scaled", tmpl)
+		}
+	}
+
+	return &m, nil
+}
+
+// isUnitFileName determines whether the name of a file ends in the suffix
+// of a unit type
+func isUnitFileName(name string) bool {
+	ext := filepath.Ext(name)
+	return ext != "" && api.ValidateName("unit"+ext) == nil
+}
+
+// applyState returns the desired state of the named unit once applied, as
+// template units cannot be started
+func applyState(name string) job.JobState {
+	if uni := unit.NewUnitNameInfo(name); uni != nil && uni.IsTemplate() {
+		return job.JobStateInactive
+	}
+	return job.JobStateLaunched
+}
+
+// planApply determines the changes bringing the cluster to the state
+// described by the given manifest, in the order they are to be made:
+// stacks are recorded before their units are submitted, so that the engine
+// holds off scheduling them until all of them are, after any stack they may
+// share units with is destroyed. Units are destroyed last.
+func planApply(m *applyManifest, prune bool) ([]applyChange, error) {
+	units, err := cAPI.Units()
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieving units: %v", err)
+	}
+	stacks, err := cAPI.Stacks()
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieving stacks: %v", err)
+	}
+
+	current := make(map[string]*schema.Unit, len(units))
+	for _, u := range units {
+		current[u.Name] = u
+	}
+	currentStacks := make(map[string]*schema.Stack, len(stacks))
+	for _, s := range stacks {
+		currentStacks[s.Name] = s
+	}
+
+	var changes []applyChange
+
+	// Stacks left in place must not share units with those described
+	// by the manifest
+	var kept []*schema.Stack
+	for _, s := range stacks {
+		if _, ok := m.stacks[s.Name]; ok {
+			continue
+		}
+		if !prune {
+			kept = append(kept, s)
+			continue
+		}
+		name := s.Name
+		changes = append(changes, applyChange{fmt.Sprintf("destroy stack %s", name), func() error {
+			return cAPI.DestroyStack(name)
+		}})
+	}
+	for _, name := range sortedStackNames(m.stacks) {
+		s := m.stacks[name]
+		if err := api.StackOverlap(s, kept); err != nil {
+			return nil, fmt.Errorf("unable to create stack %s: %v", name, err)
+		}
+
+		cs, ok := currentStacks[name]
+		if ok && sameUnits(cs.Units, s.Units) {
+			continue
+		}
+
+		desc := fmt.Sprintf("create stack %s", name)
+		if ok {
+			desc = fmt.Sprintf("update stack %s", name)
+		}
+		changes = append(changes, applyChange{desc, func() error {
+			if ok {
+				if err := cAPI.DestroyStack(s.Name); err != nil {
+					return err
+				}
+			}
+			return cAPI.CreateStack(s)
+		}})
+	}
+
+	var names []string
+	for name := range m.units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		name, uf, state := name, m.units[name], applyState(name)
+		u, ok := current[name]
+		switch {
+		case !ok:
+			changes = append(changes, applyChange{fmt.Sprintf("create unit %s", name), func() error {
+				_, err := createUnitWithState(name, uf, state)
+				return err
+			}})
+		case schema.MapSchemaUnitOptionsToUnitFile(u.Options).Hash() != uf.Hash():
+			changes = append(changes, applyChange{fmt.Sprintf("update unit %s", name), func() error {
+				return replaceUnit(name, uf, state)
+			}})
+		case job.JobState(u.DesiredState) != state:
+			changes = append(changes, applyChange{fmt.Sprintf("set unit %s to %s", name, state), func() error {
+				return cAPI.SetUnitTargetState(name, string(state))
+			}})
+		}
+	}
+
+	var templates []string
+	for tmpl := range m.scales {
+		if _, ok := m.units[tmpl]; !ok {
+			if _, ok := current[tmpl]; !ok {
+				return nil, fmt.Errorf("unable to scale %s: template unit not found", tmpl)
+			}
+		}
+		templates = append(templates, tmpl)
+	}
+	sort.Strings(templates)
+	for _, tmpl := range templates {
+		tmpl, count := tmpl, m.scales[tmpl]
+		s, err := cAPI.UnitScale(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("failed retrieving scale of unit %s: %v", tmpl, err)
+		}
+		if s != nil && int(s.Count) == count {
+			continue
+		}
+		changes = append(changes, applyChange{fmt.Sprintf("scale unit %s to %d instances", tmpl, count), func() error {
+			return cAPI.SetUnitScale(tmpl, count)
+		}})
+	}
+
+	if prune {
+		var destroyed []string
+		for _, u := range units {
+			if _, ok := m.units[u.Name]; ok {
+				continue
+			}
+			if uni := unit.NewUnitNameInfo(u.Name); uni != nil && uni.IsInstance() {
+				if _, ok := m.scales[uni.Template]; ok {
+					continue
+				}
+			}
+			destroyed = append(destroyed, u.Name)
+		}
+		sort.Strings(destroyed)
+		for _, name := range destroyed {
+			name := name
+			changes = append(changes, applyChange{fmt.Sprintf("destroy unit %s", name), func() error {
+				return cAPI.DestroyUnit(name)
+			}})
+		}
+	}
+
+	return changes, nil
+}
+
+func sortedStackNames(stacks map[string]*schema.Stack) []string {
+	var names []string
+	for name := range stacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sameUnits determines whether two lists of unit names hold the same units,
+// in any order
+func sameUnits(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isScaleManifest determines whether a YAML document is a scale manifest
+// rather than a stack file, by its first key
+func isScaleManifest(b []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := stripYAMLComment(scanner.Text())
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		key, _, ok := splitYAMLPair(line)
+		return ok && key == "scale"
+	}
+	return false
+}
+
+// parseScaleManifest parses a scale manifest, a YAML document holding a
+// map of template units to the number of their instances to run, using
+// the same subset of YAML as stack files
+func parseScaleManifest(r io.Reader) (map[string]int, error) {
+	scales := make(map[string]int)
+	var inScale bool
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := stripYAMLComment(scanner.Text())
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		key, val, ok := splitYAMLPair(strings.TrimSpace(line))
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		if indent == 0 {
+			if key != "scale" {
+				return nil, fmt.Errorf("line %d: unknown key %q", n, key)
+			}
+			if val != "" {
+				return nil, fmt.Errorf("line %d: scale must be a map", n)
+			}
+			inScale = true
+			continue
+		}
+		if !inScale {
+			return nil, fmt.Errorf("line %d: unexpected indentation", n)
+		}
+
+		name := unitNameMangle(unquoteYAML(key))
+		if _, ok := scales[name]; ok {
+			return nil, fmt.Errorf("line %d: unit %s is scaled more than once", n, name)
+		}
+		count, err := strconv.Atoi(val)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("line %d: count must be a non-negative integer", n)
+		}
+		scales[name] = count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return scales, nil
+}
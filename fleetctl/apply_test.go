@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestParseScaleManifest(t *testing.T) {
+	contents := `# instances per template
+scale:
+  web@.service: 3
+  'worker@': 0
+`
+	if !isScaleManifest([]byte(contents)) {
+		t.Fatal("Expected scale manifest to be recognized")
+	}
+	if isScaleManifest([]byte("name: web\nunits:\n  - web.service\n")) {
+		t.Error("Expected stack file not to be taken for a scale manifest")
+	}
+
+	got, err := parseScaleManifest(strings.NewReader(contents))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]int{"web@.service": 3, "worker@.service": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected scales: got %v, want %v", got, want)
+	}
+
+	for i, bad := range []string{
+		"scale: 3",
+		"units:\n  web@.service: 3",
+		"  web@.service: 3",
+		"scale:\n  web@.service: -1",
+		"scale:\n  web@.service: many",
+		"scale:\n  web@.service: 1\n  web@: 2",
+	} {
+		if _, err := parseScaleManifest(strings.NewReader(bad)); err == nil {
+			t.Errorf("case %d: expected error parsing %q", i, bad)
+		}
+	}
+}
+
+func TestRunApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleetctl-apply")
+	if err != nil {
+		t.Fatalf("Failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatalf("Failed writing %s: %v", name, err)
+		}
+	}
+	write("README.md", "not a unit\n")
+	write("hello.service", "[Service]\nExecStart=/bin/hello\n")
+	write("worker@.service", "[Service]\nExecStart=/bin/worker %i\n")
+	write("scale.yaml", "scale:\n  worker@.service: 2\n")
+	write("shop.yaml", "units:\n  - file: web@.service\n    count: 2\n  - db.service\n")
+	write("web@.service", "[Service]\nExecStart=/bin/web\n")
+	write("db.service", "[Service]\nExecStart=/bin/db\n")
+
+	reg := registry.NewFakeRegistry()
+	cAPI = &client.RegistryClient{Registry: reg}
+	if _, err := createUnitWithState("hello.service", newUnitFile(t, "[Service]\nExecStart=/bin/old\n"), job.JobStateLaunched); err != nil {
+		t.Fatalf("Failed creating unit: %v", err)
+	}
+	if _, err := createUnitWithState("stale.service", newUnitFile(t, "[Service]\nExecStart=/bin/stale\n"), job.JobStateLaunched); err != nil {
+		t.Fatalf("Failed creating unit: %v", err)
+	}
+	// instances of scaled templates are left to the engine
+	if _, err := createUnitWithState("worker@1.service", newUnitFile(t, "[Service]\nExecStart=/bin/worker 1\n"), job.JobStateLaunched); err != nil {
+		t.Fatalf("Failed creating unit: %v", err)
+	}
+
+	applyFlags.Dir, applyFlags.Prune, applyFlags.DryRun = dir, true, true
+	defer func() { applyFlags.Dir, applyFlags.Prune, applyFlags.DryRun = "", false, false }()
+
+	want := []string{
+		"create stack shop",
+		"create unit db.service",
+		"update unit hello.service",
+		"create unit web@1.service",
+		"create unit web@2.service",
+		"create unit worker@.service",
+		"scale unit worker@.service to 2 instances",
+		"destroy unit stale.service",
+	}
+	if got := runWithOutput(t, runApply); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if u, _ := cAPI.Unit("stale.service"); u == nil {
+		t.Fatal("Dry run unexpectedly destroyed unit")
+	}
+
+	applyFlags.DryRun = false
+	if got := runWithOutput(t, runApply); len(got) != len(want)+1 || got[len(got)-1] != "Applied 8 changes." {
+		t.Errorf("Unexpected output applying changes: %v", got)
+	}
+
+	units, err := cAPI.Units()
+	if err != nil {
+		t.Fatalf("Failed retrieving units: %v", err)
+	}
+	var names []string
+	for _, u := range units {
+		names = append(names, u.Name)
+		wantState := job.JobStateLaunched
+		if u.Name == "worker@.service" {
+			wantState = job.JobStateInactive
+		}
+		if u.DesiredState != string(wantState) {
+			t.Errorf("Unit %s has desired state %s, want %s", u.Name, u.DesiredState, wantState)
+		}
+	}
+	sort.Strings(names)
+	wantNames := []string{"db.service", "hello.service", "web@1.service", "web@2.service", "worker@.service", "worker@1.service"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("Unexpected units: got %v, want %v", names, wantNames)
+	}
+
+	u, _ := cAPI.Unit("hello.service")
+	if got := schema.MapSchemaUnitOptionsToUnitFile(u.Options).Hash(); got != newUnitFile(t, "[Service]\nExecStart=/bin/hello\n").Hash() {
+		t.Errorf("Unit hello.service was not updated")
+	}
+	s, err := cAPI.Stack("shop")
+	if err != nil || s == nil {
+		t.Fatalf("Failed retrieving stack: %v", err)
+	}
+	if !sameUnits(s.Units, []string{"web@1.service", "web@2.service", "db.service"}) {
+		t.Errorf("Unexpected units of stack: %v", s.Units)
+	}
+	if scales, _ := reg.UnitScales(); scales["worker@.service"] != 2 {
+		t.Errorf("Unexpected scales: %v", scales)
+	}
+
+	want = []string{"No changes."}
+	if got := runWithOutput(t, runApply); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected output applying again: %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coreos/fleet/schema"
+)
+
+var cmdAudit = &Command{
+	Name:    "audit",
+	Summary: "Print the recent changes made to the units of the cluster",
+	Usage:   "[--no-legend] [-l|--full]",
+	Run:     runAudit,
+	Description: `Prints the audit log of the cluster: who created, destroyed, started,
+stopped or scaled which units, and when. Changes made through the API are
+only recorded if fleetd is configured with audit_registry=true; changes made
+by fleetctl directly against etcd are always recorded.`,
+}
+
+func init() {
+	cmdAudit.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+	cmdAudit.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+	cmdAudit.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runAudit(args []string) (exit int) {
+	entries, err := cAPI.AuditLog()
+	if err != nil {
+		stderr("Error retrieving audit log: %v", err)
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "TIME\tUSER\tACTION\tUNIT\tPREVIOUS\tSTATE")
+	}
+	for _, ae := range entries {
+		fmt.Fprintln(out, formatAuditEntry(ae, sharedFlags.Full))
+	}
+	out.Flush()
+	return
+}
+
+func formatAuditEntry(ae *schema.AuditEntry, full bool) string {
+	user := ae.User
+	if !full && len(user) > 32 {
+		user = user[:29] + "..."
+	}
+	return strings.Join([]string{
+		ae.Time,
+		user,
+		ae.Action,
+		ae.UnitName,
+		dashIfEmpty(ae.PreviousState),
+		dashIfEmpty(ae.State),
+	}, "\t")
+}
+
+// localUser identifies the user running fleetctl in the audit log, as
+// user@host, noting the remote address of SSH sessions
+func localUser() string {
+	user := os.Getenv("SUDO_USER")
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	if user == "" {
+		user = "unknown"
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		user = fmt.Sprintf("%s@%s", user, host)
+	}
+
+	if conn := strings.Fields(os.Getenv("SSH_CONNECTION")); len(conn) > 0 {
+		user = fmt.Sprintf("%s (ssh from %s)", user, conn[0])
+	}
+	return user
+}
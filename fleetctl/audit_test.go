@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunAudit(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	at := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	reg.RecordAudit(registry.AuditEntry{Time: at, User: "cert:alice", Action: registry.AuditUnitCreated, UnitName: "foo.service", State: "launched"})
+	reg.RecordAudit(registry.AuditEntry{Time: at, User: "bob@host", Action: registry.AuditUnitTargetStateSet, UnitName: "foo.service", PrevState: "launched", State: "inactive"})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	sharedFlags.NoLegend = true
+	defer func() { sharedFlags.NoLegend = false }()
+
+	want := []string{
+		"2014-10-01T12:00:00Z\tcert:alice\tcreate\tfoo.service\t-\tlaunched",
+		"2014-10-01T12:00:00Z\tbob@host\tset-target-state\tfoo.service\tlaunched\tinactive",
+	}
+	lines := runWithOutput(t, runAudit)
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %d: %q", len(want), len(lines), lines)
+	}
+	for i := range lines {
+		// ignore the padding between columns
+		got := strings.Join(strings.FieldsFunc(lines[i], func(r rune) bool { return r == '\t' }), "\t")
+		if got != want[i] {
+			t.Errorf("Line %d is %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestLocalUser(t *testing.T) {
+	defer func(sudo, user, ssh string) {
+		os.Setenv("SUDO_USER", sudo)
+		os.Setenv("USER", user)
+		os.Setenv("SSH_CONNECTION", ssh)
+	}(os.Getenv("SUDO_USER"), os.Getenv("USER"), os.Getenv("SSH_CONNECTION"))
+
+	os.Setenv("SUDO_USER", "alice")
+	os.Setenv("USER", "root")
+	os.Setenv("SSH_CONNECTION", "10.0.0.1 51234 10.0.0.2 22")
+
+	user := localUser()
+	if !strings.HasPrefix(user, "alice@") || !strings.HasSuffix(user, " (ssh from 10.0.0.1)") {
+		t.Errorf("Unexpected user %q", user)
+	}
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	// version of the snapshots written by backup
+	snapshotVersion = 1
+
+	// values of snapshotMachine.Cordon
+	snapshotCordon = "cordon"
+	snapshotDrain  = "drain"
+
+	// ways restore handles conflicts with the cluster
+	restoreConflictFail    = "fail"
+	restoreConflictSkip    = "skip"
+	restoreConflictReplace = "replace"
+)
+
+var (
+	restoreFlags = struct {
+		Conflict string
+	}{}
+
+	cmdBackup = &Command{
+		Name:    "backup",
+		Summary: "Write a snapshot of the cluster to stdout",
+		Usage:   "",
+		Description: `Capture everything fleet stores about the cluster that is not published by
+the machines themselves, as JSON: all units along with their unit files,
+desired states and the machines they are scheduled to, the instance counts of
+scaled templates, stacks, the configuration values of the namespaces units
+use with FleetEnvironment=, quotas, and the metadata, cordons and taints of
+the machines.
+
+Take a snapshot of the cluster:
+	fleetctl backup > snapshot.json
+
+Secret configuration values are saved encrypted, and can only be restored to
+a cluster using the same cluster key. Use restore to recreate the cluster
+from the snapshot.`,
+		Run: runBackup,
+	}
+
+	cmdRestore = &Command{
+		Name:    "restore",
+		Summary: "Recreate the cluster from a snapshot taken with backup",
+		Usage:   "[--conflict=fail|skip|replace] SNAPSHOT",
+		Description: `Restore everything captured by backup that is missing from the cluster, e.g.
+after losing the data of etcd. Units are scheduled to the machines they were
+scheduled to when the snapshot was taken if those machines are part of the
+cluster, and left to the engine otherwise. Machines that are not part of the
+cluster are skipped. Use - to read the snapshot from stdin.
+
+Parts of the snapshot that differ from what is in the cluster, e.g. a unit
+whose unit file was changed after the snapshot was taken, are conflicts.
+With --conflict=fail, the default, restore lists all conflicts and changes
+nothing if there are any. With skip, conflicting parts are left as they are
+in the cluster, and with replace, they are overwritten with the snapshot.
+Cordons are only added, and never cause conflicts.
+
+Restore a snapshot to a new cluster:
+	fleetctl restore snapshot.json`,
+		Run: runRestore,
+	}
+)
+
+func init() {
+	cmdRestore.Flags.StringVar(&restoreFlags.Conflict, "conflict", restoreConflictFail, "How to handle parts of the snapshot differing from the cluster: fail, skip or replace.")
+}
+
+// snapshot is what backup captures of the cluster
+type snapshot struct {
+	Version  int                              `json:"version"`
+	Created  string                           `json:"created"`
+	Units    []*schema.Unit                   `json:"units,omitempty"`
+	Scales   map[string]int64                 `json:"scales,omitempty"`
+	Stacks   []*schema.Stack                  `json:"stacks,omitempty"`
+	Config   map[string][]*schema.ConfigValue `json:"config,omitempty"`
+	Quotas   []*schema.Quota                  `json:"quotas,omitempty"`
+	Machines []*snapshotMachine               `json:"machines,omitempty"`
+}
+
+// snapshotMachine is what backup captures of a machine
+type snapshotMachine struct {
+	ID       string            `json:"id"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Cordon   string            `json:"cordon,omitempty"`
+	Taints   []*schema.Taint   `json:"taints,omitempty"`
+}
+
+func runBackup(args []string) (exit int) {
+	if len(args) != 0 {
+		stderr("backup takes no arguments.")
+		return 1
+	}
+
+	snap, err := takeSnapshot(time.Now())
+	if err != nil {
+		stderr("Error taking snapshot: %v", err)
+		return 1
+	}
+
+	enc, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		stderr("Error encoding snapshot: %v", err)
+		return 1
+	}
+	fmt.Println(string(enc))
+	return
+}
+
+func takeSnapshot(now time.Time) (*snapshot, error) {
+	snap := snapshot{
+		Version: snapshotVersion,
+		Created: now.UTC().Format(time.RFC3339),
+		Scales:  make(map[string]int64),
+		Config:  make(map[string][]*schema.ConfigValue),
+	}
+
+	units, err := cAPI.Units()
+	if err != nil {
+		return nil, fmt.Errorf("retrieving units: %v", err)
+	}
+	namespaces := make(map[string]bool)
+	for _, u := range units {
+		su := *u
+		su.CurrentState = ""
+		snap.Units = append(snap.Units, &su)
+
+		if uni := unit.NewUnitNameInfo(u.Name); uni != nil && uni.IsTemplate() {
+			scale, err := cAPI.UnitScale(u.Name)
+			if err != nil {
+				return nil, fmt.Errorf("retrieving scale of unit %s: %v", u.Name, err)
+			}
+			if scale != nil {
+				snap.Scales[u.Name] = scale.Count
+			}
+		}
+
+		j := job.NewJob(u.Name, *schema.MapSchemaUnitOptionsToUnitFile(u.Options))
+		for _, ns := range j.FleetEnvironment() {
+			namespaces[ns] = true
+		}
+	}
+
+	for ns := range namespaces {
+		values, err := cAPI.ConfigValues(ns)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving configuration values of namespace %s: %v", ns, err)
+		}
+		if len(values) > 0 {
+			snap.Config[ns] = values
+		}
+	}
+
+	if snap.Stacks, err = cAPI.Stacks(); err != nil {
+		return nil, fmt.Errorf("retrieving stacks: %v", err)
+	}
+
+	quotas, err := cAPI.Quotas()
+	if err != nil {
+		return nil, fmt.Errorf("retrieving quotas: %v", err)
+	}
+	for _, q := range quotas {
+		snap.Quotas = append(snap.Quotas, &schema.Quota{Namespace: q.Namespace, Cores: q.Cores, Memory: q.Memory, Units: q.Units})
+	}
+
+	machines, err := cAPI.Machines()
+	if err != nil {
+		return nil, fmt.Errorf("retrieving machines: %v", err)
+	}
+	for _, ms := range machines {
+		snap.Machines = append(snap.Machines, snapshotOfMachine(ms, now))
+	}
+
+	return &snap, nil
+}
+
+// snapshotOfMachine captures the given machine. Machines drained only for
+// their maintenance window are not recorded as drained, since the window
+// is part of their metadata.
+func snapshotOfMachine(ms machine.MachineState, now time.Time) *snapshotMachine {
+	sm := snapshotMachine{ID: ms.ID, Metadata: ms.Metadata}
+	if mw := ms.MaintenanceWindow(); mw == nil || !mw.Drains(now) {
+		switch {
+		case ms.Draining:
+			sm.Cordon = snapshotDrain
+		case ms.Cordoned:
+			sm.Cordon = snapshotCordon
+		}
+	}
+	for _, t := range ms.Taints {
+		sm.Taints = append(sm.Taints, &schema.Taint{Key: t.Key, Value: t.Value, Effect: t.Effect})
+	}
+	return &sm
+}
+
+// restoreAction is a change restore makes to the cluster. It conflicts
+// with the cluster if it overwrites something.
+type restoreAction struct {
+	desc     string
+	conflict bool
+	do       func() error
+}
+
+func runRestore(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One snapshot must be provided.")
+		return 1
+	}
+	switch restoreFlags.Conflict {
+	case restoreConflictFail, restoreConflictSkip, restoreConflictReplace:
+	default:
+		stderr("Invalid --conflict %q, must be %s, %s or %s.", restoreFlags.Conflict, restoreConflictFail, restoreConflictSkip, restoreConflictReplace)
+		return 1
+	}
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		stderr("Error reading snapshot %s: %v", args[0], err)
+		return 1
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		stderr("Error decoding snapshot %s: %v", args[0], err)
+		return 1
+	}
+	if snap.Version != snapshotVersion {
+		stderr("Unsupported snapshot version %d, expected %d.", snap.Version, snapshotVersion)
+		return 1
+	}
+
+	actions, err := planRestore(&snap)
+	if err != nil {
+		stderr("Error comparing snapshot with the cluster: %v", err)
+		return 1
+	}
+
+	if restoreFlags.Conflict == restoreConflictFail {
+		for _, a := range actions {
+			if a.conflict {
+				stderr("Conflict: %s", a.desc)
+				exit = 1
+			}
+		}
+		if exit != 0 {
+			stderr("Nothing restored, use --conflict=skip or --conflict=replace to restore regardless.")
+			return
+		}
+	}
+
+	var restored, skipped int
+	for _, a := range actions {
+		if a.conflict && restoreFlags.Conflict == restoreConflictSkip {
+			stdout("Skipping conflict: %s", a.desc)
+			skipped++
+			continue
+		}
+		if err := a.do(); err != nil {
+			stderr("Error restoring snapshot: %s: %v", a.desc, err)
+			return 1
+		}
+		restored++
+	}
+
+	stdout("Restored %d changes from snapshot taken %s, skipped %d conflicts", restored, snap.Created, skipped)
+	return
+}
+
+// planRestore determines the changes restoring the given snapshot makes to
+// the cluster, in the order they are to be made: configuration values,
+// quotas and machines first, so that units are scheduled with them in
+// place, then stacks, units and the scales of templates.
+func planRestore(snap *snapshot) ([]restoreAction, error) {
+	var actions []restoreAction
+
+	var namespaces []string
+	for ns := range snap.Config {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		current, err := cAPI.ConfigValues(ns)
+		if err != nil {
+			return nil, err
+		}
+		for _, cv := range snap.Config[ns] {
+			ns, cv := ns, cv
+			var ecv *schema.ConfigValue
+			for _, c := range current {
+				if c.Key == cv.Key {
+					ecv = c
+				}
+			}
+			if ecv != nil && reflect.DeepEqual(ecv, cv) {
+				continue
+			}
+			actions = append(actions, restoreAction{
+				desc:     fmt.Sprintf("configuration value %s of namespace %s", cv.Key, ns),
+				conflict: ecv != nil,
+				do:       func() error { return cAPI.SetConfigValue(ns, cv) },
+			})
+		}
+	}
+
+	quotas, err := cAPI.Quotas()
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range snap.Quotas {
+		q := q
+		var eq *schema.Quota
+		for _, c := range quotas {
+			if c.Namespace == q.Namespace {
+				eq = c
+			}
+		}
+		if eq != nil && eq.Cores == q.Cores && eq.Memory == q.Memory && eq.Units == q.Units {
+			continue
+		}
+		actions = append(actions, restoreAction{
+			desc:     fmt.Sprintf("quota of namespace %s", q.Namespace),
+			conflict: eq != nil,
+			do:       func() error { return cAPI.SetQuota(q) },
+		})
+	}
+
+	machines, err := cAPI.Machines()
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]machine.MachineState, len(machines))
+	for _, ms := range machines {
+		present[ms.ID] = ms
+	}
+	for _, sm := range snap.Machines {
+		ms, ok := present[sm.ID]
+		if !ok {
+			stdout("Machine %s is not part of the cluster, skipping it", sm.ID)
+			continue
+		}
+		actions = append(actions, planMachineRestore(sm, ms)...)
+	}
+
+	stacks, err := cAPI.Stacks()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snap.Stacks {
+		s := s
+		var es *schema.Stack
+		for _, c := range stacks {
+			if c.Name == s.Name {
+				es = c
+			}
+		}
+		if es != nil && reflect.DeepEqual(es.Units, s.Units) {
+			continue
+		}
+		actions = append(actions, restoreAction{
+			desc:     fmt.Sprintf("stack %s", s.Name),
+			conflict: es != nil,
+			do: func() error {
+				if es != nil {
+					if err := cAPI.DestroyStack(s.Name); err != nil {
+						return err
+					}
+				}
+				return cAPI.CreateStack(s)
+			},
+		})
+	}
+
+	units, err := cAPI.Units()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*schema.Unit, len(units))
+	for _, u := range units {
+		current[u.Name] = u
+	}
+	for _, u := range snap.Units {
+		if a := planUnitRestore(u, current[u.Name], present); a != nil {
+			actions = append(actions, *a)
+		}
+	}
+
+	var templates []string
+	for tmpl := range snap.Scales {
+		templates = append(templates, tmpl)
+	}
+	sort.Strings(templates)
+	for _, tmpl := range templates {
+		tmpl, count := tmpl, snap.Scales[tmpl]
+		scale, err := cAPI.UnitScale(tmpl)
+		if err != nil {
+			return nil, err
+		}
+		if scale != nil && scale.Count == count {
+			continue
+		}
+		actions = append(actions, restoreAction{
+			desc:     fmt.Sprintf("scale of unit %s", tmpl),
+			conflict: scale != nil,
+			do:       func() error { return cAPI.SetUnitScale(tmpl, int(count)) },
+		})
+	}
+
+	return actions, nil
+}
+
+// planMachineRestore determines the changes restoring the given snapshot of
+// a machine makes to it
+func planMachineRestore(sm *snapshotMachine, ms machine.MachineState) []restoreAction {
+	var keys []string
+	for key := range sm.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var actions []restoreAction
+	for _, key := range keys {
+		key, val := key, sm.Metadata[key]
+		cur, ok := ms.Metadata[key]
+		if ok && cur == val {
+			continue
+		}
+		actions = append(actions, restoreAction{
+			desc:     fmt.Sprintf("metadata %s of machine %s", key, sm.ID),
+			conflict: ok,
+			do:       func() error { return cAPI.SetMachineMetadata(sm.ID, key, val) },
+		})
+	}
+
+	if (sm.Cordon == snapshotCordon && !ms.Cordoned) || (sm.Cordon == snapshotDrain && !ms.Draining) {
+		drain := sm.Cordon == snapshotDrain
+		actions = append(actions, restoreAction{
+			desc: fmt.Sprintf("%s of machine %s", sm.Cordon, sm.ID),
+			do:   func() error { return cAPI.CordonMachine(sm.ID, drain) },
+		})
+	}
+
+	for _, st := range sm.Taints {
+		t := machine.Taint{Key: st.Key, Value: st.Value, Effect: st.Effect}
+		var et *machine.Taint
+		for i := range ms.Taints {
+			if ms.Taints[i].Key == t.Key {
+				et = &ms.Taints[i]
+			}
+		}
+		if et != nil && *et == t {
+			continue
+		}
+		actions = append(actions, restoreAction{
+			desc:     fmt.Sprintf("taint %s of machine %s", t.Key, sm.ID),
+			conflict: et != nil,
+			do:       func() error { return cAPI.TaintMachine(sm.ID, t) },
+		})
+	}
+	return actions
+}
+
+// planUnitRestore determines the change restoring the given snapshot of a
+// Unit makes to the existing Unit eu, if any. The Unit is only scheduled to
+// the machine it was scheduled to if that machine is present.
+func planUnitRestore(u, eu *schema.Unit, present map[string]machine.MachineState) *restoreAction {
+	if eu == nil {
+		su := *u
+		if _, ok := present[su.MachineID]; !ok || suToGlobal(su) {
+			su.MachineID = ""
+		}
+		return &restoreAction{
+			desc: fmt.Sprintf("unit %s", u.Name),
+			do:   func() error { return cAPI.CreateUnit(&su) },
+		}
+	}
+
+	sameFile := schema.MapSchemaUnitOptionsToUnitFile(u.Options).Hash() == schema.MapSchemaUnitOptionsToUnitFile(eu.Options).Hash()
+	if sameFile && u.DesiredState == eu.DesiredState {
+		return nil
+	}
+	return &restoreAction{
+		desc:     fmt.Sprintf("unit %s", u.Name),
+		conflict: true,
+		do: func() error {
+			if !sameFile {
+				if err := cAPI.ReplaceUnit(u); err != nil {
+					return err
+				}
+			}
+			if len(u.DesiredState) == 0 {
+				return nil
+			}
+			return cAPI.SetUnitTargetState(u.Name, u.DesiredState)
+		},
+	}
+}
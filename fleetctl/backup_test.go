@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestBackupRestore(t *testing.T) {
+	src := registry.NewFakeRegistry()
+	src.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX", Metadata: map[string]string{"region": "us-west"}},
+	})
+	src.CordonMachine("XXX", false)
+	src.TaintMachine("XXX", machine.Taint{Key: "dedicated", Value: "db", Effect: machine.TaintEffectNoSchedule})
+	src.SetJobs([]job.Job{
+		job.Job{Name: "foo.service", Unit: *newUnitFile(t, "[Service]\nExecStart=/bin/foo\n[X-Fleet]\nFleetEnvironment=myapp"), TargetState: job.JobStateLaunched, TargetMachineID: "XXX"},
+		job.Job{Name: "web@.service", Unit: *newUnitFile(t, "[Service]\nExecStart=/bin/web"), TargetState: job.JobStateInactive},
+	})
+	src.SetUnitScale("web@.service", 2)
+	src.CreateStack(&registry.Stack{Name: "app", Units: []string{"foo.service"}})
+	src.SetConfigValue("myapp", "DB_URL", registry.ConfigValue{Value: "db:5432"})
+	src.SetQuota(registry.Quota{Namespace: "team-a", Units: 10})
+	cAPI = &client.RegistryClient{Registry: src}
+
+	snap, err := takeSnapshot(time.Now())
+	if err != nil {
+		t.Fatalf("Failed taking snapshot: %v", err)
+	}
+	enc, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("Failed encoding snapshot: %v", err)
+	}
+	f, err := ioutil.TempFile("", "fleetctl-snapshot")
+	if err != nil {
+		t.Fatalf("Failed creating snapshot file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(enc)
+	f.Close()
+
+	dst := registry.NewFakeRegistry()
+	dst.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX"},
+		machine.MachineState{ID: "YYY"},
+	})
+	cAPI = &client.RegistryClient{Registry: dst}
+
+	restoreFlags.Conflict = restoreConflictFail
+	if exit := runRestore([]string{f.Name()}); exit != 0 {
+		t.Fatalf("Restoring to an empty cluster failed with exit %d", exit)
+	}
+
+	if u, _ := dst.Unit("foo.service"); u == nil || u.TargetState != job.JobStateLaunched {
+		t.Errorf("Unit foo.service not restored: %v", u)
+	}
+	if su, _ := dst.ScheduledUnit("foo.service"); su == nil || su.TargetMachineID != "XXX" {
+		t.Errorf("Unit foo.service not scheduled to its machine: %v", su)
+	}
+	if scales, _ := dst.UnitScales(); scales["web@.service"] != 2 {
+		t.Errorf("Scale of web@.service not restored: %v", scales)
+	}
+	if s, _ := dst.Stack("app"); s == nil || !reflect.DeepEqual(s.Units, []string{"foo.service"}) {
+		t.Errorf("Stack app not restored: %v", s)
+	}
+	if values, _ := dst.ConfigValues("myapp"); values["DB_URL"].Value != "db:5432" {
+		t.Errorf("Configuration values not restored: %v", values)
+	}
+	if quotas, _ := dst.Quotas(); !reflect.DeepEqual(quotas, []registry.Quota{registry.Quota{Namespace: "team-a", Units: 10}}) {
+		t.Errorf("Quotas not restored: %v", quotas)
+	}
+	if metadata, _ := dst.MachineMetadata("XXX"); metadata["region"] != "us-west" {
+		t.Errorf("Metadata of machine XXX not restored: %v", metadata)
+	}
+	machines, _ := dst.Machines()
+	if !machines[0].Cordoned || len(machines[0].Taints) != 1 {
+		t.Errorf("Cordon and taints of machine XXX not restored: %#v", machines[0])
+	}
+
+	// units changed after the snapshot was taken conflict with it
+	changed := newUnitFile(t, "[Service]\nExecStart=/bin/foo --v2")
+	dst.ReplaceUnit(&job.Unit{Name: "foo.service", Unit: *changed})
+
+	if exit := runRestore([]string{f.Name()}); exit != 1 {
+		t.Errorf("Expected restoring conflicting snapshot to fail, got exit %d", exit)
+	}
+
+	restoreFlags.Conflict = restoreConflictSkip
+	if exit := runRestore([]string{f.Name()}); exit != 0 {
+		t.Errorf("Expected restore skipping conflicts to succeed, got exit %d", exit)
+	}
+	if u, _ := dst.Unit("foo.service"); u.Unit.Hash() != changed.Hash() {
+		t.Errorf("Conflicting unit replaced despite --conflict=skip")
+	}
+
+	restoreFlags.Conflict = restoreConflictReplace
+	if exit := runRestore([]string{f.Name()}); exit != 0 {
+		t.Errorf("Expected restore replacing conflicts to succeed, got exit %d", exit)
+	}
+	if u, _ := dst.Unit("foo.service"); u.Unit.Hash() == changed.Hash() {
+		t.Errorf("Conflicting unit not replaced with --conflict=replace")
+	}
+	restoreFlags.Conflict = restoreConflictFail
+}
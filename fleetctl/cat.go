@@ -7,16 +7,24 @@ import (
 )
 
 var (
-	cmdCatUnit = &Command{
+	flagCatVersion int
+	cmdCatUnit     = &Command{
 		Name:    "cat",
 		Summary: "Output the contents of a submitted unit",
-		Usage:   "UNIT",
+		Usage:   "[--version=N] UNIT",
 		Description: `Outputs the unit file that is currently loaded in the cluster. Useful to verify
-the correct version of a unit is running.`,
+the correct version of a unit is running.
+
+Output an earlier version of the unit file, as listed by "fleetctl history":
+	fleetctl cat --version=2 foo.service`,
 		Run: runCatUnit,
 	}
 )
 
+func init() {
+	cmdCatUnit.Flags.IntVar(&flagCatVersion, "version", 0, "Output the given version of the unit file rather than the current one.")
+}
+
 func runCatUnit(args []string) (exit int) {
 	if len(args) != 1 {
 		stderr("One unit file must be provided")
@@ -24,6 +32,19 @@ func runCatUnit(args []string) (exit int) {
 	}
 
 	name := unitNameMangle(args[0])
+	if flagCatVersion != 0 {
+		uv, err := findUnitVersion(name, flagCatVersion)
+		if err != nil {
+			stderr("Error retrieving version %d of Unit %s: %v", flagCatVersion, name, err)
+			return 1
+		} else if len(uv.Options) == 0 {
+			stderr("Unit file of version %d of Unit %s is no longer stored.", flagCatVersion, name)
+			return 1
+		}
+		fmt.Print(schema.MapSchemaUnitOptionsToUnitFile(uv.Options).String())
+		return
+	}
+
 	u, err := cAPI.Unit(name)
 	if err != nil {
 		stderr("Error retrieving Unit %s: %v", name, err)
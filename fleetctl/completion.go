@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreos/fleet/unit"
+)
+
+var (
+	cmdCompletion = &Command{
+		Name:    "completion",
+		Summary: "Generate shell completion for fleetctl",
+		Usage:   "bash|zsh|fish",
+		Description: `Print a script making the given shell complete the commands of fleetctl, along
+with the names of units, templates, stacks, machine IDs and metadata keys,
+which the script retrieves from the cluster while completing.
+
+Enable completion in the current bash session:
+	source <(fleetctl completion bash)
+
+Enable completion for all fish sessions:
+	fleetctl completion fish > ~/.config/fish/completions/fleetctl.fish
+
+The script runs fleetctl to retrieve what it completes, so the cluster it
+reaches must be configured in the environment, e.g. with FLEETCTL_ENDPOINT.`,
+		Run: runCompletion,
+	}
+
+	flagCompletionList string
+
+	// completionArgs holds what each argument of a command completes to,
+	// by position. The last kind applies to all further arguments.
+	completionArgs = map[string][]string{
+		"cat":                  {"units"},
+		"destroy":              {"units"},
+		"history":              {"units"},
+		"journal":              {"units"},
+		"label":                {"units"},
+		"load":                 {"units"},
+		"restart":              {"units"},
+		"rollback":             {"units"},
+		"schedule":             {"units"},
+		"start":                {"units"},
+		"status":               {"units"},
+		"stop":                 {"units"},
+		"unload":               {"units"},
+		"wait":                 {"units"},
+		"why":                  {"units"},
+		"list-runs":            {"templates"},
+		"rolling-update":       {"templates"},
+		"scale":                {"templates"},
+		"destroy-stack":        {"stacks"},
+		"cordon":               {"machines"},
+		"drain":                {"machines"},
+		"ssh":                  {"machines"},
+		"taint":                {"machines"},
+		"uncordon":             {"machines"},
+		"set-machine-metadata": {"machines", "metadata-keys"},
+		"help":                 {"commands"},
+	}
+)
+
+func init() {
+	cmdCompletion.Flags.StringVar(&flagCompletionList, "list", "", "Print the candidates of the given kind, one per line, instead of a script: units, templates, stacks, machines, metadata-keys or commands. Used by the scripts.")
+}
+
+func runCompletion(args []string) (exit int) {
+	if flagCompletionList != "" {
+		candidates, err := completionCandidates(flagCompletionList)
+		if err != nil {
+			stderr("Error listing %s: %v", flagCompletionList, err)
+			return 1
+		}
+		for _, c := range candidates {
+			stdout("%s", c)
+		}
+		return
+	}
+
+	if len(args) != 1 {
+		stderr("One shell must be provided: bash, zsh or fish.")
+		return 1
+	}
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = "autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion()
+	case "fish":
+		script = fishCompletion()
+	default:
+		stderr("Unsupported shell %q, must be bash, zsh or fish.", args[0])
+		return 1
+	}
+	fmt.Print(script)
+	return
+}
+
+// completionCandidates returns what the given kind of argument completes to
+func completionCandidates(kind string) ([]string, error) {
+	var candidates []string
+	switch kind {
+	case "commands":
+		for _, c := range commands {
+			candidates = append(candidates, c.Name)
+		}
+	case "units", "templates":
+		units, err := cAPI.Units()
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range units {
+			if kind == "templates" {
+				if uni := unit.NewUnitNameInfo(u.Name); uni == nil || !uni.IsTemplate() {
+					continue
+				}
+			}
+			candidates = append(candidates, u.Name)
+		}
+	case "stacks":
+		stacks, err := cAPI.Stacks()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stacks {
+			candidates = append(candidates, s.Name)
+		}
+	case "machines":
+		machines, err := cAPI.Machines()
+		if err != nil {
+			return nil, err
+		}
+		for _, ms := range machines {
+			candidates = append(candidates, ms.ID)
+		}
+	case "metadata-keys":
+		machines, err := cAPI.Machines()
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, ms := range machines {
+			for key := range ms.Metadata {
+				if !seen[key] {
+					seen[key] = true
+					candidates = append(candidates, key+"=")
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown kind, must be units, templates, stacks, machines, metadata-keys or commands")
+	}
+	sort.Strings(candidates)
+	return candidates, nil
+}
+
+// completedCommands returns the names of the commands whose arguments
+// complete to the same kinds, indexed by those kinds joined by spaces
+func completedCommands() map[string][]string {
+	byKinds := make(map[string][]string)
+	for name, kinds := range completionArgs {
+		key := strings.Join(kinds, " ")
+		byKinds[key] = append(byKinds[key], name)
+	}
+	for _, names := range byKinds {
+		sort.Strings(names)
+	}
+	return byKinds
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func bashCompletion() string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.Name)
+	}
+
+	var cases bytes.Buffer
+	byKinds := completedCommands()
+	for _, kinds := range sortedKeys(byKinds) {
+		fmt.Fprintf(&cases, "\t%s)\n\t\tkinds=(%s)\n\t\t;;\n", strings.Join(byKinds[kinds], "|"), kinds)
+	}
+
+	return fmt.Sprintf(`# bash completion for fleetctl, generated by "fleetctl completion bash"
+
+_fleetctl() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local cmd="" argn=0 i kinds kind
+
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case "${COMP_WORDS[i]}" in
+		-*) ;;
+		*)
+			if [[ -z "$cmd" ]]; then
+				cmd="${COMP_WORDS[i]}"
+			else
+				argn=$((argn + 1))
+			fi
+			;;
+		esac
+	done
+
+	if [[ -z "$cmd" ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+
+	case "$cmd" in
+%s	*)
+		return
+		;;
+	esac
+
+	if ((argn < ${#kinds[@]})); then
+		kind="${kinds[argn]}"
+	else
+		kind="${kinds[${#kinds[@]}-1]}"
+	fi
+	if [[ "$kind" == metadata-keys ]]; then
+		compopt -o nospace 2>/dev/null
+	fi
+	COMPREPLY=($(compgen -W "$(fleetctl completion --list="$kind" 2>/dev/null)" -- "$cur"))
+}
+
+complete -o default -F _fleetctl fleetctl
+`, strings.Join(names, " "), cases.String())
+}
+
+func fishCompletion() string {
+	var buf bytes.Buffer
+	buf.WriteString(`# fish completion for fleetctl, generated by "fleetctl completion fish"
+
+function __fleetctl_complete
+	# the tokens before the cursor, flags aside, are fleetctl, its command
+	# and the arguments given so far
+	set -l n (count (commandline -opc | string match -v -- '-*'))
+	set -l n (math $n - 1)
+	set -l kind $argv[-1]
+	if test $n -le (count $argv)
+		set kind $argv[$n]
+	end
+	fleetctl completion --list=$kind 2>/dev/null
+end
+
+`)
+	for _, c := range commands {
+		fmt.Fprintf(&buf, "complete -c fleetctl -f -n __fish_use_subcommand -a %s -d %s\n", c.Name, fishQuote(c.Summary))
+	}
+	byKinds := completedCommands()
+	for _, kinds := range sortedKeys(byKinds) {
+		fmt.Fprintf(&buf, "complete -c fleetctl -f -n '__fish_seen_subcommand_from %s' -a '(__fleetctl_complete %s)'\n", strings.Join(byKinds[kinds], " "), kinds)
+	}
+	return buf.String()
+}
+
+// fishQuote quotes the given string for fish
+func fishQuote(s string) string {
+	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+}
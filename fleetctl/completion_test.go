@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestCompletionCandidates(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "web@.service"},
+		job.Job{Name: "web@1.service"},
+		job.Job{Name: "db.service"},
+	})
+	reg.SetMachines([]machine.MachineState{
+		newMachineState("c31e44e1-f858-436e-933e-59c642517860", "1.2.3.4", map[string]string{"role": "web", "region": "eu"}),
+		newMachineState("595989bb-cbb7-49ce-8726-722d6e157b4e", "5.6.7.8", map[string]string{"role": "db"}),
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for kind, want := range map[string][]string{
+		"units":         {"db.service", "web@.service", "web@1.service"},
+		"templates":     {"web@.service"},
+		"machines":      {"595989bb-cbb7-49ce-8726-722d6e157b4e", "c31e44e1-f858-436e-933e-59c642517860"},
+		"metadata-keys": {"region=", "role="},
+	} {
+		got, err := completionCandidates(kind)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", kind, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: got %v, want %v", kind, got, want)
+		}
+	}
+
+	if _, err := completionCandidates("files"); err == nil {
+		t.Errorf("Expected error listing unknown kind")
+	}
+}
+
+func TestCompletionArgsCommands(t *testing.T) {
+	known := make(map[string]bool)
+	for _, c := range commands {
+		known[c.Name] = true
+	}
+	for name := range completionArgs {
+		if !known[name] {
+			t.Errorf("Completion defined for unknown command %s", name)
+		}
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	bash := bashCompletion()
+	if !strings.Contains(bash, "complete -o default -F _fleetctl fleetctl") {
+		t.Errorf("bash script does not register completion")
+	}
+	if !strings.Contains(bash, "set-machine-metadata)\n\t\tkinds=(machines metadata-keys)") {
+		t.Errorf("bash script does not complete set-machine-metadata")
+	}
+
+	fish := fishCompletion()
+	if !strings.Contains(fish, "__fish_seen_subcommand_from destroy-stack' -a '(__fleetctl_complete stacks)'") {
+		t.Errorf("fish script does not complete destroy-stack")
+	}
+
+	if exit := runCompletion([]string{"tcsh"}); exit != 1 {
+		t.Errorf("Expected exit 1 for unsupported shell, got %d", exit)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/fleet/api"
+	"github.com/coreos/fleet/config"
+	"github.com/coreos/fleet/registry"
+)
+
+var cmdConfig = &Command{
+	Name:    "config",
+	Summary: "Manage the options set for the whole cluster",
+	Usage:   "get | set KEY=VALUE...",
+	Description: `Options set for the whole cluster take precedence over those in the fleet.conf
+of each machine. Engines and agents apply changes to them within a few seconds,
+without being restarted.
+
+List the options set for the cluster:
+	fleetctl config get
+
+Schedule units with the binpack strategy and overcommit CPU by half:
+	fleetctl config set scheduling_strategy=binpack cpu_overcommit=1.5
+
+Fall back to the fleet.conf of each machine by leaving an option empty:
+	fleetctl config set cpu_overcommit=
+
+The options which may be set are ` + strings.Join(config.ClusterOptions(), ", ") + `.`,
+	Run: runConfig,
+}
+
+func runConfig(args []string) (exit int) {
+	if len(args) == 0 {
+		stderr("Unknown config command, must be get or set.")
+		return 1
+	}
+	switch args[0] {
+	case "get":
+		return configGet(args[1:])
+	case "set":
+		return configSet(args[1:])
+	default:
+		stderr("Unknown config command, must be get or set.")
+		return 1
+	}
+}
+
+func configGet(args []string) (exit int) {
+	if len(args) != 0 {
+		stderr("config get takes no arguments.")
+		return 1
+	}
+	cc, err := cAPI.ClusterConfig()
+	if err != nil {
+		stderr("Error retrieving cluster configuration: %v", err)
+		return 1
+	}
+
+	fmt.Fprintf(out, "VERSION\t%d\n", cc.Version)
+	for _, name := range config.ClusterOptions() {
+		if val, ok := cc.Values[name]; ok {
+			fmt.Fprintf(out, "%s\t%s\n", name, val)
+		}
+	}
+	out.Flush()
+	return
+}
+
+func configSet(args []string) (exit int) {
+	if len(args) == 0 {
+		stderr("At least one KEY=VALUE pair must be provided.")
+		return 1
+	}
+
+	cc, err := cAPI.ClusterConfig()
+	if err != nil {
+		stderr("Error retrieving cluster configuration: %v", err)
+		return 1
+	}
+	if cc.Values == nil {
+		cc.Values = map[string]string{}
+	}
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			stderr("Invalid option %q, must be KEY=VALUE.", arg)
+			return 1
+		}
+		if parts[1] == "" {
+			delete(cc.Values, parts[0])
+		} else {
+			cc.Values[parts[0]] = parts[1]
+		}
+	}
+	if err := api.ValidateClusterConfig(cc.Values); err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
+	updated, err := cAPI.SetClusterConfig(cc)
+	if err == registry.ErrClusterConfigChanged {
+		stderr("The cluster configuration changed concurrently, please retry.")
+		return 1
+	} else if err != nil {
+		stderr("Error setting cluster configuration: %v", err)
+		return 1
+	}
+
+	stdout("Set cluster configuration version %d", updated.Version)
+	return
+}
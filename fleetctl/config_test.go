@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunConfigSet(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.UpdateClusterConfig(0, map[string]string{"cpu_overcommit": "1.5"})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args    []string
+		exit    int
+		version int
+		want    map[string]string
+	}{
+		// unknown command and missing values
+		{[]string{"put", "cpu_overcommit=2"}, 1, 1, map[string]string{"cpu_overcommit": "1.5"}},
+		{[]string{"set"}, 1, 1, map[string]string{"cpu_overcommit": "1.5"}},
+		{[]string{"set", "cpu_overcommit"}, 1, 1, map[string]string{"cpu_overcommit": "1.5"}},
+		// invalid options and values
+		{[]string{"set", "etcd_servers=http://127.0.0.1:2379"}, 1, 1, map[string]string{"cpu_overcommit": "1.5"}},
+		{[]string{"set", "scheduling_strategy=fastest"}, 1, 1, map[string]string{"cpu_overcommit": "1.5"}},
+		{[]string{"set", "memory_overcommit=0"}, 1, 1, map[string]string{"cpu_overcommit": "1.5"}},
+		{[]string{"set", "scheduling_strategy=binpack", "cpu_overcommit="}, 0, 2, map[string]string{"scheduling_strategy": "binpack"}},
+	} {
+		if exit := runConfig(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		got, _ := reg.ClusterConfig()
+		if got.Version != tt.version || !reflect.DeepEqual(got.Values, tt.want) {
+			t.Errorf("case %d: unexpected config: got version %d %v, want version %d %v", i, got.Version, got.Values, tt.version, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+var (
+	cmdCordonMachine = &Command{
+		Name:    "cordon",
+		Summary: "Stop scheduling new units to a machine",
+		Usage:   "MACHINE",
+		Description: `Mark a machine as unschedulable. Units already running on the machine keep
+running there, but the engine will not schedule any further units to it.
+Global units are unaffected.
+
+Cordon a machine before maintenance:
+	fleetctl cordon 2444264c
+
+MACHINE may be any unique prefix of a machine ID.`,
+		Run: runCordonMachine,
+	}
+	cmdDrainMachine = &Command{
+		Name:    "drain",
+		Summary: "Move all units off a machine",
+		Usage:   "MACHINE",
+		Description: `Cordon a machine and reschedule the units running on it elsewhere in the
+cluster. A unit that cannot be scheduled to any other machine is left where
+it is. Global units are unaffected.
+
+Drain a machine before taking it down:
+	fleetctl drain 2444264c
+
+Use list-units to follow the progress of the drain.`,
+		Run: runDrainMachine,
+	}
+	cmdUncordonMachine = &Command{
+		Name:    "uncordon",
+		Summary: "Allow units to be scheduled to a machine again",
+		Usage:   "MACHINE",
+		Description: `Revert a previous cordon or drain of a machine. Units that were moved away
+while draining are not moved back.`,
+		Run: runUncordonMachine,
+	}
+)
+
+func runCordonMachine(args []string) (exit int) {
+	return cordonMachine(args, false)
+}
+
+func runDrainMachine(args []string) (exit int) {
+	return cordonMachine(args, true)
+}
+
+func cordonMachine(args []string, drain bool) (exit int) {
+	if len(args) != 1 {
+		stderr("One machine must be provided.")
+		return 1
+	}
+
+	ms, err := findMachine(args[0])
+	if err != nil {
+		stderr("Unable to find machine %s: %v", args[0], err)
+		return 1
+	}
+
+	if err := cAPI.CordonMachine(ms.ID, drain); err != nil {
+		stderr("Error cordoning machine %s: %v", ms.ID, err)
+		return 1
+	}
+
+	if drain {
+		stdout("Draining machine %s", ms.ID)
+	} else {
+		stdout("Cordoned machine %s", ms.ID)
+	}
+	return
+}
+
+func runUncordonMachine(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One machine must be provided.")
+		return 1
+	}
+
+	ms, err := findMachine(args[0])
+	if err != nil {
+		stderr("Unable to find machine %s: %v", args[0], err)
+		return 1
+	}
+
+	if err := cAPI.UncordonMachine(ms.ID); err != nil {
+		stderr("Error uncordoning machine %s: %v", ms.ID, err)
+		return 1
+	}
+
+	stdout("Uncordoned machine %s", ms.ID)
+	return
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunCordonMachine(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		newMachineState("c31e44e1-f858-436e-933e-59c642517860", "1.2.3.4", nil),
+		newMachineState("c31e5555-cbb7-49ce-8726-722d6e157b4e", "5.6.7.8", nil),
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		run      func([]string) int
+		args     []string
+		exit     int
+		cordoned bool
+		draining bool
+	}{
+		// ambiguous machine prefix
+		{runCordonMachine, []string{"c31e"}, 1, false, false},
+		{runCordonMachine, []string{}, 1, false, false},
+		{runCordonMachine, []string{"c31e44"}, 0, true, false},
+		{runDrainMachine, []string{"c31e44"}, 0, true, true},
+		{runUncordonMachine, []string{"c31e44", "c31e55"}, 1, true, true},
+		{runUncordonMachine, []string{"c31e44"}, 0, false, false},
+	} {
+		if exit := tt.run(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		machines, _ := reg.Machines()
+		ms := machines[0]
+		if ms.Cordoned != tt.cordoned || ms.Draining != tt.draining {
+			t.Errorf("case %d: unexpected state: cordoned=%t draining=%t", i, ms.Cordoned, ms.Draining)
+		}
+	}
+}
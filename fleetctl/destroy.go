@@ -3,18 +3,38 @@ package main
 var cmdDestroyUnit = &Command{
 	Name:    "destroy",
 	Summary: "Destroy one or more units in the cluster",
-	Usage:   "UNIT...",
+	Usage:   "[-l SELECTOR] UNIT...",
 	Description: `Completely remove one or more running or submitted units from the cluster.
 
 Instructs systemd on the host machine to stop the unit, deferring to systemd
 completely for any custom stop directives (i.e. ExecStop option in the unit
 file).
 
-Destroyed units are impossible to start unless re-submitted.`,
+Destroyed units are impossible to start unless re-submitted.
+
+Destroy all instances of a template unit, quoting the pattern so that it is
+matched against the names of the units in the cluster:
+	fleetctl destroy 'web@*'
+
+Destroy all units labeled env=staging:
+	fleetctl destroy -l env=staging`,
 	Run: runDestroyUnits,
 }
 
+func init() {
+	addSelectorFlag(cmdDestroyUnit)
+}
+
 func runDestroyUnits(args []string) (exit int) {
+	args, err := expandUnitGlobs(args)
+	if err == nil {
+		args, err = selectUnits(args)
+	}
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	for _, v := range args {
 		name := unitNameMangle(v)
 		err := cAPI.DestroyUnit(name)
@@ -0,0 +1,53 @@
+package main
+
+var cmdDestroyStack = &Command{
+	Name:    "destroy-stack",
+	Summary: "Destroy a stack and all of its units",
+	Usage:   "STACK",
+	Description: `Completely remove all units of a stack from the cluster, then the stack
+itself, as if each unit was destroyed with the destroy command.
+
+Destroy a stack:
+	fleetctl destroy-stack web`,
+	Run: runDestroyStack,
+}
+
+func runDestroyStack(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One stack must be provided.")
+		return 1
+	}
+
+	name := args[0]
+	s, err := cAPI.Stack(name)
+	if err != nil {
+		stderr("Error retrieving stack %s: %v", name, err)
+		return 1
+	} else if s == nil {
+		stderr("Stack %s does not exist.", name)
+		return 1
+	}
+
+	for _, uName := range s.Units {
+		u, err := cAPI.Unit(uName)
+		if err != nil {
+			stderr("Error retrieving Unit(%s) from Registry: %v", uName, err)
+			return 1
+		} else if u == nil {
+			continue
+		}
+		if err := cAPI.DestroyUnit(uName); err != nil {
+			stderr("Error destroying unit %s: %v", uName, err)
+			return 1
+		}
+		stdout("Destroyed %s", uName)
+	}
+
+	if err := cAPI.DestroyStack(name); err != nil {
+		stderr("Error destroying stack %s: %v", name, err)
+		return 1
+	}
+
+	stdout("Destroyed stack %s", name)
+	return
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/schema"
+)
+
+const (
+	// lines of context shown around each change
+	diffContext = 3
+)
+
+var (
+	diffFlags = struct {
+		Brief bool
+	}{}
+
+	cmdDiffUnits = &Command{
+		Name:    "diff",
+		Summary: "Compare local unit files with the units in the cluster",
+		Usage:   "[--brief] UNIT_FILE...",
+		Description: `Compare the given unit files with the units of the same names in the cluster,
+printing the differences in the unified format. Unit files are compared as
+fleet stores them, so differences in comments or whitespace alone are not
+reported.
+
+Exits with 0 if all units are the same, 1 if any of them differ or are not in
+the cluster yet, and 2 if comparing them failed. This allows CI to detect
+units that were changed in the cluster, or locally without being submitted,
+before submitting them fails:
+	fleetctl diff --brief units/*.service
+
+With --brief, only the names of the units that differ are printed.`,
+		Run: runDiffUnits,
+	}
+)
+
+func init() {
+	cmdDiffUnits.Flags.BoolVar(&diffFlags.Brief, "brief", false, "Only print which units differ.")
+}
+
+func runDiffUnits(args []string) (exit int) {
+	if len(args) == 0 {
+		stderr("One or more unit files must be provided.")
+		return 2
+	}
+
+	for _, arg := range args {
+		name := unitNameMangle(arg)
+		local, err := getUnitFromFile(arg)
+		if err != nil {
+			stderr("Error reading unit file %s: %v", arg, err)
+			return 2
+		}
+
+		u, err := cAPI.Unit(name)
+		if err != nil {
+			stderr("Error retrieving Unit %s: %v", name, err)
+			return 2
+		}
+		if u == nil {
+			stdout("Unit %s is not in the cluster", name)
+			exit = 1
+			continue
+		}
+
+		remote := schema.MapSchemaUnitOptionsToUnitFile(u.Options)
+		if remote.Hash() == local.Hash() {
+			continue
+		}
+		exit = 1
+
+		if diffFlags.Brief {
+			stdout("Unit %s differs from %s", name, arg)
+			continue
+		}
+		fmt.Print(pkg.UnifiedDiff(unitLines(remote.String()), unitLines(local.String()), "cluster/"+name, arg, diffContext))
+	}
+	return
+}
+
+// unitLines splits the contents of a unit file into its lines
+func unitLines(contents string) []string {
+	return strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+}
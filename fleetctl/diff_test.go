@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestRunDiffUnits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleetctl-diff")
+	if err != nil {
+		t.Fatalf("Failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"same.service":    "[Service]\nExecStart=/bin/true\n",
+		"comment.service": "# only a comment differs\n[Service]\nExecStart=/bin/true\n",
+		"changed.service": "[Service]\nExecStart=/bin/false\n",
+		"new.service":     "[Service]\nExecStart=/bin/true\n",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatalf("Failed writing unit file: %v", err)
+		}
+	}
+
+	uf, err := unit.NewUnitFile("[Service]\nExecStart=/bin/true\n")
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit file: %v", err)
+	}
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "same.service", Unit: *uf},
+		job.Job{Name: "comment.service", Unit: *uf},
+		job.Job{Name: "changed.service", Unit: *uf},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	diffFlags.Brief = true
+	defer func() { diffFlags.Brief = false }()
+	for i, tt := range []struct {
+		files []string
+		exit  int
+	}{
+		{nil, 2},
+		{[]string{"same.service", "comment.service"}, 0},
+		{[]string{"same.service", "changed.service"}, 1},
+		{[]string{"new.service"}, 1},
+		{[]string{"missing.service"}, 2},
+	} {
+		var args []string
+		for _, f := range tt.files {
+			args = append(args, filepath.Join(dir, f))
+		}
+		if exit := runDiffUnits(args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	cmdClusterStatus = &Command{
+		Name:    "cluster-status",
+		Summary: "Show which engines lead the cluster",
+		Usage:   "[-l|--full] [--no-legend]",
+		Description: `Lists the engines holding the lease of the lead engine, or of shards of the
+scheduling work, as last published by each of them: the machine running the
+engine, whether it leads or which shards it holds, when its leases expire
+unless renewed, how many reconciliations it carried out since acquiring
+them, and when the last one started and how long it took.
+
+Exits with a non-zero status if no engine holds a lease.`,
+		Run: runClusterStatus,
+	}
+	cmdEngine = &Command{
+		Name:    "engine",
+		Summary: "Manage the engines leading the cluster",
+		Usage:   "step-down [--hold=DURATION] [MACHINE]",
+		Description: `Ask the engine of a machine to release its leases, so that the engines of
+other machines take over scheduling the cluster, e.g. before draining a
+control node. The engine does not acquire any lease during --hold, 1m by
+default, and steps down within one engine reconcile interval.
+
+MACHINE may be any unique prefix of a machine ID. It may be omitted if a
+single engine holds all leases.
+
+Step down the current lead engine:
+	fleetctl engine step-down`,
+		Run: runEngine,
+	}
+
+	engineStepDownFlagset = flag.NewFlagSet("step-down", flag.ContinueOnError)
+	engineStepDownHold    time.Duration
+)
+
+func init() {
+	cmdClusterStatus.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+	cmdClusterStatus.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+	cmdClusterStatus.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+
+	engineStepDownFlagset.DurationVar(&engineStepDownHold, "hold", time.Minute, "Time during which the engine does not acquire any lease.")
+}
+
+func runClusterStatus(args []string) (exit int) {
+	statuses, err := cAPI.EngineStatuses()
+	if err != nil {
+		stderr("Error retrieving engine status: %v", err)
+		return 1
+	}
+	if len(statuses) == 0 {
+		stderr("No engine currently holds a lease.")
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "MACHINE\tROLE\tVERSION\tLEASE EXPIRES\tRECONCILES\tLAST RECONCILE\tDURATION")
+	}
+	for _, es := range statuses {
+		fmt.Fprintln(out, strings.Join([]string{
+			machineIDLegend(machine.MachineState{ID: es.MachineID}, sharedFlags.Full),
+			engineRole(es),
+			fmt.Sprintf("%d", es.Version),
+			es.LeaseExpires,
+			fmt.Sprintf("%d", es.Reconciles),
+			es.LastReconcile,
+			time.Duration(es.LastReconcileDuration * float64(time.Second)).String(),
+		}, "\t"))
+	}
+	out.Flush()
+	return
+}
+
+// engineRole describes the work held by an engine
+func engineRole(es *schema.EngineStatus) string {
+	if len(es.Shards) == 0 {
+		return "leader"
+	}
+	shards := make([]string, len(es.Shards))
+	for i, shard := range es.Shards {
+		shards[i] = fmt.Sprintf("%d", shard)
+	}
+	return "shards " + strings.Join(shards, ",")
+}
+
+func runEngine(args []string) (exit int) {
+	if len(args) == 0 || args[0] != "step-down" {
+		stderr("Unknown engine command, must be step-down.")
+		return 1
+	}
+	if err := engineStepDownFlagset.Parse(args[1:]); err != nil {
+		return 1
+	}
+	return engineStepDown(engineStepDownFlagset.Args())
+}
+
+func engineStepDown(args []string) (exit int) {
+	if len(args) > 1 {
+		stderr("At most one machine may be provided.")
+		return 1
+	}
+	if engineStepDownHold <= 0 {
+		stderr("The hold must be positive.")
+		return 1
+	}
+
+	var machID string
+	if len(args) == 1 {
+		ms, err := findMachine(args[0])
+		if err != nil {
+			stderr("Unable to find machine %s: %v", args[0], err)
+			return 1
+		}
+		machID = ms.ID
+	} else {
+		statuses, err := cAPI.EngineStatuses()
+		if err != nil {
+			stderr("Error retrieving engine status: %v", err)
+			return 1
+		}
+		if len(statuses) != 1 {
+			stderr("%d engines hold leases, a machine must be provided.", len(statuses))
+			return 1
+		}
+		machID = statuses[0].MachineID
+	}
+
+	if err := cAPI.RequestEngineStepDown(machID, engineStepDownHold); err != nil {
+		stderr("Error requesting engine of machine %s to step down: %v", machID, err)
+		return 1
+	}
+
+	stdout("Requested engine of machine %s to step down for %s", machID, engineStepDownHold)
+	return
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestEngineStepDown(t *testing.T) {
+	defer func() {
+		engineStepDownHold = time.Minute
+	}()
+
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		machine.MachineState{ID: "XXX"},
+		machine.MachineState{ID: "YYY"},
+	})
+	reg.SaveEngineStatus(registry.EngineStatus{MachineID: "XXX", Shards: []int{0}}, time.Minute)
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args []string
+		exit int
+	}{
+		{nil, 1},
+		{[]string{"resign"}, 1},
+		{[]string{"step-down", "--hold=0"}, 1},
+		{[]string{"step-down", "XXX", "YYY"}, 1},
+		{[]string{"step-down", "ZZZ"}, 1},
+		{[]string{"step-down", "YYY"}, 0},
+	} {
+		engineStepDownHold = time.Minute
+		if exit := runEngine(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+	if requested, _ := reg.EngineStepDownRequested("YYY"); !requested {
+		t.Errorf("Expected step-down of engine of YYY to be requested")
+	}
+
+	// the only engine holding leases steps down by default
+	if exit := runEngine([]string{"step-down"}); exit != 0 {
+		t.Fatalf("Unexpected exit %d", exit)
+	}
+	if requested, _ := reg.EngineStepDownRequested("XXX"); !requested {
+		t.Errorf("Expected step-down of engine of XXX to be requested")
+	}
+
+	reg.SaveEngineStatus(registry.EngineStatus{MachineID: "YYY", Shards: []int{1}}, time.Minute)
+	if exit := runEngine([]string{"step-down"}); exit != 1 {
+		t.Errorf("Expected a machine to be required with several engines, got exit %d", exit)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	flagEventsSince  uint64
+	flagEventsFollow bool
+	cmdEvents        = &Command{
+		Name:    "events",
+		Summary: "Print the recent events of the cluster",
+		Usage:   "[--since=N] [-f|--follow] [--no-legend] [-l|--full]",
+		Run:     runEvents,
+		Description: `Prints the events recorded by the cluster within the last hour, such as
+scheduling decisions, preemptions, unit state changes and machines joining or
+leaving the cluster.
+
+Print only the events after the one with index 1024:
+	fleetctl events --since 1024
+
+Keep printing new events as they are recorded:
+	fleetctl events --follow`,
+	}
+)
+
+func init() {
+	cmdEvents.Flags.Uint64Var(&flagEventsSince, "since", 0, "Only print events with a greater index")
+	cmdEvents.Flags.BoolVar(&flagEventsFollow, "follow", false, "Continuously print new events as they are recorded")
+	cmdEvents.Flags.BoolVar(&flagEventsFollow, "f", false, "Shorthand for --follow")
+	cmdEvents.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+	cmdEvents.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+	cmdEvents.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runEvents(args []string) (exit int) {
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "INDEX\tTIME\tTYPE\tUNIT\tMACHINE\tREASON")
+	}
+
+	since := flagEventsSince
+	wait := time.Duration(0)
+	for {
+		events, err := cAPI.Events(since, wait)
+		if err != nil {
+			stderr("Error retrieving events: %v", err)
+			return 1
+		}
+
+		for _, ev := range events {
+			fmt.Fprintln(out, formatEvent(ev, sharedFlags.Full))
+			since = uint64(ev.Index)
+		}
+		out.Flush()
+
+		if !flagEventsFollow {
+			return
+		}
+
+		// Long-poll for new events, leaving the request enough time to
+		// complete within the request timeout
+		wait = followWait()
+		if wait == 0 && len(events) == 0 {
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// followWait returns how long a single request for new events may wait
+func followWait() time.Duration {
+	timeout := time.Duration(globalFlags.RequestTimeout*1000) * time.Millisecond
+	return timeout / 2 / time.Second * time.Second
+}
+
+func formatEvent(ev *schema.Event, full bool) string {
+	fields := []string{
+		fmt.Sprintf("%d", ev.Index),
+		ev.Time,
+		ev.Type,
+		dashIfEmpty(ev.UnitName),
+		"-",
+		dashIfEmpty(ev.Reason),
+	}
+	if ev.MachineID != "" {
+		fields[4] = machineIDLegend(machine.MachineState{ID: ev.MachineID}, full)
+	}
+	return strings.Join(fields, "\t")
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunEvents(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	at := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	reg.RecordEvent(registry.ClusterEvent{Time: at, Type: registry.EventUnitScheduled, UnitName: "foo.service", MachineID: "c31e44e1-f858-436e-933e-59c642517860", Reason: "target state launched and unit not scheduled"})
+	reg.RecordEvent(registry.ClusterEvent{Time: at, Type: registry.EventMachineJoined, MachineID: "595989bb-cbb7-49ce-8726-722d6e157b4e"})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	sharedFlags.NoLegend = true
+	defer func() { sharedFlags.NoLegend = false }()
+
+	for i, tt := range []struct {
+		since uint64
+		want  []string
+	}{
+		{
+			0,
+			[]string{
+				"1\t2014-10-01T12:00:00Z\tUnitScheduled\tfoo.service\tc31e44e1...\ttarget state launched and unit not scheduled",
+				"2\t2014-10-01T12:00:00Z\tMachineJoined\t-\t595989bb...\t-",
+			},
+		},
+		{
+			1,
+			[]string{
+				"2\t2014-10-01T12:00:00Z\tMachineJoined\t-\t595989bb...\t-",
+			},
+		},
+	} {
+		flagEventsSince = tt.since
+		lines := runWithOutput(t, runEvents)
+		if len(lines) != len(tt.want) {
+			t.Errorf("case %d: expected %d lines, got %d: %q", i, len(tt.want), len(lines), lines)
+			continue
+		}
+		for j := range lines {
+			// ignore the padding between columns
+			got := strings.Join(strings.FieldsFunc(lines[j], func(r rune) bool { return r == '\t' }), "\t")
+			if got != tt.want[j] {
+				t.Errorf("case %d: line %d is %q, want %q", i, j, got, tt.want[j])
+			}
+		}
+	}
+	flagEventsSince = 0
+}
+
+func TestFollowWait(t *testing.T) {
+	defer func(timeout float64) { globalFlags.RequestTimeout = timeout }(globalFlags.RequestTimeout)
+
+	for _, tt := range []struct {
+		timeout float64
+		want    time.Duration
+	}{
+		{3.0, time.Second},
+		{10.0, 5 * time.Second},
+		{1.0, 0},
+	} {
+		globalFlags.RequestTimeout = tt.timeout
+		if got := followWait(); got != tt.want {
+			t.Errorf("request timeout %v: got wait %v, want %v", tt.timeout, got, tt.want)
+		}
+	}
+}
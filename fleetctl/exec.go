@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/unit"
+)
+
+var (
+	flagAllInstances bool
+	cmdExec          = &Command{
+		Name:    "exec",
+		Summary: "Run a command on the machine running a unit",
+		Usage:   "[--all-instances] UNIT -- COMMAND...",
+		Description: `Run a one-off command on the machine a unit is scheduled to, through the fleet
+API, and print its output as it is produced. The command is run by the fleet
+server of that machine, which must allow it with api_allow_exec. Requires
+--experimental-api. Interactive commands are not supported.
+
+For global units, the command is run on each machine running the unit. Given a
+template unit and --all-instances, the command is run on the machine of each
+of its instances in turn, and each line of output is prefixed with the name of
+the instance it was produced for.
+
+fleetctl exits with the exit status of the command, or 1 if it failed on any
+of several machines.
+
+Show the processes of a unit:
+	fleetctl --experimental-api exec foo.service -- systemctl status foo.service
+
+Check the disk usage next to each instance of a template:
+	fleetctl --experimental-api exec --all-instances web@.service -- df -h /var/lib/web`,
+		Run: runExec,
+	}
+)
+
+func init() {
+	cmdExec.Flags.BoolVar(&flagAllInstances, "all-instances", false, "Run the command for each instance of the given template unit.")
+}
+
+// execTarget is a machine to run a command on for a unit
+type execTarget struct {
+	unit   string
+	machID string
+}
+
+func runExec(args []string) (exit int) {
+	if len(args) == 0 {
+		stderr("One unit must be provided.")
+		return 1
+	}
+	name := unitNameMangle(args[0])
+	command := pkg.TrimToDashes(args[1:])
+	if len(command) == 0 {
+		stderr("A command must be provided.")
+		return 1
+	}
+
+	targets, err := execTargets(name, flagAllInstances)
+	if err != nil {
+		stderr("Unable to proceed: %v", err)
+		return 1
+	}
+
+	for _, tgt := range targets {
+		var out io.Writer = os.Stdout
+		var pw *prefixWriter
+		if len(targets) > 1 {
+			pw = newPrefixWriter(os.Stdout, tgt.unit+": ")
+			out = pw
+		}
+		status, err := cAPI.RunCommand(tgt.machID, command, out)
+		if pw != nil {
+			pw.Flush()
+		}
+		if err != nil {
+			stderr("Failed running command for %s on %s: %v", tgt.unit, machineLegend(tgt.machID), err)
+			exit = 1
+			continue
+		}
+		if len(targets) == 1 {
+			return status
+		}
+		if status != 0 {
+			stderr("Command exited with status %d for %s on %s", status, tgt.unit, machineLegend(tgt.machID))
+			exit = 1
+		}
+	}
+	return
+}
+
+// execTargets resolves the named unit to the machines to run a command on:
+// the machine the unit is scheduled to, each machine running it if it is
+// global, or, if allInstances is set and the unit is a template, those of
+// each of its scheduled instances, ordered by instance
+func execTargets(name string, allInstances bool) ([]execTarget, error) {
+	uni := unit.NewUnitNameInfo(name)
+	isTemplate := uni != nil && uni.IsTemplate()
+	if allInstances && !isTemplate {
+		return nil, fmt.Errorf("--all-instances requires a template unit, %s is not one", name)
+	} else if !allInstances && isTemplate {
+		return nil, fmt.Errorf("%s is a template unit, use --all-instances to run the command for each of its instances", name)
+	}
+
+	if !allInstances {
+		return execUnitTargets(name)
+	}
+
+	all, err := cAPI.Units()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving list of units from repository: %v", err)
+	}
+	var instances []rollingInstance
+	for _, u := range all {
+		if iuni := unit.NewUnitNameInfo(u.Name); iuni != nil && iuni.Template == name && iuni.IsInstance() {
+			instances = append(instances, rollingInstance{name: u.Name})
+		}
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("template unit %s has no instances", name)
+	}
+	sort.Sort(rollingInstancesByName(instances))
+
+	var targets []execTarget
+	for _, ri := range instances {
+		tgts, err := execUnitTargets(ri.name)
+		if err != nil {
+			stderr("Skipping %s: %v", ri.name, err)
+			continue
+		}
+		targets = append(targets, tgts...)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no instance of %s is scheduled to a machine", name)
+	}
+	return targets, nil
+}
+
+// execUnitTargets returns the machines the named unit runs on
+func execUnitTargets(name string) ([]execTarget, error) {
+	u, err := cAPI.Unit(name)
+	if err != nil {
+		return nil, err
+	} else if u == nil {
+		return nil, fmt.Errorf("unit %s does not exist", name)
+	}
+
+	if !suToGlobal(*u) {
+		if u.MachineID == "" {
+			return nil, fmt.Errorf("unit %s is not scheduled to a machine", name)
+		}
+		return []execTarget{{name, u.MachineID}}, nil
+	}
+
+	states, err := cAPI.UnitStates()
+	if err != nil {
+		return nil, err
+	}
+	var targets []execTarget
+	for _, us := range states {
+		if us.Name == name {
+			targets = append(targets, execTarget{name, us.MachineID})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("global unit %s is not running on any machine", name)
+	}
+	return targets, nil
+}
+
+// prefixWriter writes each line written to it to the underlying writer
+// with the given prefix. Partial lines are held back until they are
+// completed or the writer is flushed.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(w io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{w: w, prefix: prefix}
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buf.Write(p)
+	for {
+		i := bytes.IndexByte(pw.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := pw.buf.Next(i + 1)
+		if _, err := fmt.Fprintf(pw.w, "%s%s", pw.prefix, line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes any partial line held back, terminating it
+func (pw *prefixWriter) Flush() error {
+	if pw.buf.Len() == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(pw.w, "%s%s\n", pw.prefix, pw.buf.Bytes())
+	pw.buf.Reset()
+	return err
+}
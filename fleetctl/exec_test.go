@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func newFakeRegistryForExec() *registry.FakeRegistry {
+	uf, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/hello")
+	guf, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/hello\n[X-Fleet]\nGlobal=true")
+	reg := registry.NewFakeRegistry()
+	var jobs []job.Job
+	for _, name := range []string{"web@.service", "web@10.service", "web@2.service", "web@3.service", "hello.service", "idle.service"} {
+		j := job.NewJob(name, *uf)
+		j.TargetState = job.JobStateLaunched
+		jobs = append(jobs, *j)
+	}
+	jobs = append(jobs, *job.NewJob("global.service", *guf))
+	reg.SetJobs(jobs)
+	reg.ScheduleUnit("web@10.service", "XXX")
+	reg.ScheduleUnit("web@2.service", "YYY")
+	reg.ScheduleUnit("hello.service", "XXX")
+	reg.SetUnitStates([]unit.UnitState{
+		{UnitName: "global.service", MachineID: "XXX"},
+		{UnitName: "global.service", MachineID: "YYY"},
+		{UnitName: "hello.service", MachineID: "XXX"},
+	})
+	return reg
+}
+
+func TestExecTargets(t *testing.T) {
+	cAPI = &client.RegistryClient{Registry: newFakeRegistryForExec()}
+
+	for i, tt := range []struct {
+		name         string
+		allInstances bool
+		targets      []execTarget
+		err          bool
+	}{
+		{"hello.service", false, []execTarget{{"hello.service", "XXX"}}, false},
+		{"global.service", false, []execTarget{{"global.service", "XXX"}, {"global.service", "YYY"}}, false},
+		{"web@.service", true, []execTarget{{"web@2.service", "YYY"}, {"web@10.service", "XXX"}}, false},
+		{"web@.service", false, nil, true},
+		{"hello.service", true, nil, true},
+		{"idle.service", false, nil, true},
+		{"missing.service", false, nil, true},
+		{"other@.service", true, nil, true},
+	} {
+		targets, err := execTargets(tt.name, tt.allInstances)
+		if (err != nil) != tt.err {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if len(targets) != len(tt.targets) {
+			t.Errorf("case %d: expected targets %v, got %v", i, tt.targets, targets)
+			continue
+		}
+		for j := range targets {
+			if targets[j] != tt.targets[j] {
+				t.Errorf("case %d: expected targets %v, got %v", i, tt.targets, targets)
+				break
+			}
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw := newPrefixWriter(&buf, "web@1.service: ")
+	pw.Write([]byte("first line\nsec"))
+	pw.Write([]byte("ond line\n"))
+	pw.Write([]byte("unterminated"))
+	pw.Flush()
+
+	want := "web@1.service: first line\nweb@1.service: second line\nweb@1.service: unterminated\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output %q, want %q", got, want)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"io"
@@ -9,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"text/tabwriter"
@@ -59,11 +62,18 @@ var (
 		EtcdKeyFile           string
 		EtcdCertFile          string
 		EtcdCAFile            string
+		RegistryKeyFiles      string
+		Namespace             string
 		UseAPI                bool
+		CAFile                string
+		CertFile              string
+		KeyFile               string
+		Token                 string
 		KnownHostsFile        string
 		StrictHostKeyChecking bool
 		Tunnel                string
 		RequestTimeout        float64
+		SigningKeyFile        string
 	}{}
 
 	// flags used by multiple commands
@@ -74,6 +84,9 @@ var (
 		NoBlock       bool
 		BlockAttempts int
 		Fields        string
+		Output        string
+		Selector      string
+		Labels        string
 	}{}
 
 	// used to cache MachineStates
@@ -92,11 +105,18 @@ func init() {
 	globalFlagset.StringVar(&globalFlags.EtcdKeyFile, "etcd-keyfile", "", "etcd key file authentication")
 	globalFlagset.StringVar(&globalFlags.EtcdCertFile, "etcd-certfile", "", "etcd cert file authentication")
 	globalFlagset.StringVar(&globalFlags.EtcdCAFile, "etcd-cafile", "", "etcd CA file authentication")
+	globalFlagset.StringVar(&globalFlags.RegistryKeyFiles, "registry-key-files", "", "Comma-separated files holding the keys fleet data is encrypted with in etcd, as configured for fleetd. Not used with --experimental-api.")
+	globalFlagset.StringVar(&globalFlags.Namespace, "namespace", job.DefaultNamespace, "Namespace of the units to act on. Unit names are given and shown without it.")
 	globalFlagset.BoolVar(&globalFlags.UseAPI, "experimental-api", false, "Use the experimental HTTP API. This flag will be removed when the API is no longer considered experimental.")
+	globalFlagset.StringVar(&globalFlags.CAFile, "cafile", "", "CA file used to verify the certificate of the fleet API. Only used with --experimental-api.")
+	globalFlagset.StringVar(&globalFlags.CertFile, "cert-file", "", "Certificate file used to authenticate to the fleet API. Only used with --experimental-api.")
+	globalFlagset.StringVar(&globalFlags.KeyFile, "key-file", "", "Key file used to authenticate to the fleet API. Only used with --experimental-api.")
+	globalFlagset.StringVar(&globalFlags.Token, "token", "", "Token used to authenticate to the fleet API. Only used with --experimental-api.")
 	globalFlagset.StringVar(&globalFlags.KnownHostsFile, "known-hosts-file", ssh.DefaultKnownHostsFile, "File used to store remote machine fingerprints. Ignored if strict host key checking is disabled.")
 	globalFlagset.BoolVar(&globalFlags.StrictHostKeyChecking, "strict-host-key-checking", true, "Verify host keys presented by remote machines before initiating SSH connections.")
 	globalFlagset.StringVar(&globalFlags.Tunnel, "tunnel", "", "Establish an SSH tunnel through the provided address for communication with fleet and etcd.")
 	globalFlagset.Float64Var(&globalFlags.RequestTimeout, "request-timeout", 3.0, "Amount of time in seconds to allow a single request before considering it failed.")
+	globalFlagset.StringVar(&globalFlags.SigningKeyFile, "signing-key-file", "", "ECDSA P-256 private key file used to sign the unit files of submitted units.")
 }
 
 type Command struct {
@@ -114,22 +134,62 @@ func init() {
 	out = new(tabwriter.Writer)
 	out.Init(os.Stdout, 0, 8, 1, '\t', 0)
 	commands = []*Command{
+		cmdApply,
+		cmdAudit,
+		cmdBackup,
 		cmdCatUnit,
+		cmdClusterStatus,
+		cmdCompletion,
+		cmdConfig,
+		cmdCordonMachine,
+		cmdDestroyStack,
 		cmdDestroyUnit,
+		cmdDiffUnits,
+		cmdDrainMachine,
+		cmdEngine,
+		cmdEvents,
+		cmdExec,
 		cmdHelp,
+		cmdHistory,
 		cmdJournal,
+		cmdLabelUnit,
+		cmdListConfig,
+		cmdListJobs,
 		cmdListMachines,
+		cmdListRuns,
+		cmdListStacks,
 		cmdListUnitFiles,
 		cmdListUnits,
 		cmdLoadUnits,
+		cmdMaintenance,
+		cmdQuota,
+		cmdScaleUnit,
+		cmdScheduleUnit,
+		cmdSetConfig,
+		cmdSetMachineMetadata,
+		cmdSetQuota,
+		cmdSimulate,
+		cmdRestartUnit,
+		cmdRestore,
+		cmdRollback,
+		cmdRollingUpdate,
 		cmdSSH,
 		cmdStartUnit,
 		cmdStatusUnits,
 		cmdStopUnit,
+		cmdSubmitStack,
 		cmdSubmitUnit,
+		cmdTaintMachine,
+		cmdTopMachines,
+		cmdTopUnits,
+		cmdUncordonMachine,
 		cmdUnloadUnit,
+		cmdUpgradeAgents,
+		cmdValidateUnit,
 		cmdVerifyUnit,
 		cmdVersion,
+		cmdWaitUnits,
+		cmdWhyUnit,
 	}
 }
 
@@ -222,13 +282,22 @@ func main() {
 		os.Exit(2)
 	}
 
-	if cmd.Name != "help" && cmd.Name != "version" {
+	// Completion scripts are generated without reaching the cluster
+	if cmd.Name != "help" && cmd.Name != "version" && !(cmd.Name == "completion" && flagCompletionList == "") {
 		var err error
 		cAPI, err = getClient()
 		if err != nil {
 			stderr("Unable to initialize client: %v", err)
 			os.Exit(1)
 		}
+
+		if globalFlags.Namespace != job.DefaultNamespace {
+			if err := job.ValidateNamespace(globalFlags.Namespace); err != nil {
+				stderr("Invalid namespace %q: %v", globalFlags.Namespace, err)
+				os.Exit(1)
+			}
+			cAPI = client.NewNamespacedAPI(cAPI, globalFlags.Namespace)
+		}
 	}
 
 	os.Exit(cmd.Run(cmd.Flags.Args()))
@@ -289,26 +358,85 @@ func getHTTPClient() (client.API, error) {
 		}
 	}
 
+	tlsConfig, err := getAPITLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	trans := pkg.LoggingHTTPTransport{
 		http.Transport{
-			Dial: dialFunc,
+			Dial:            dialFunc,
+			TLSClientConfig: tlsConfig,
 		},
 	}
 
 	hc := http.Client{
 		Transport: &trans,
 	}
+	if globalFlags.Token != "" {
+		hc.Transport = &tokenTransport{token: globalFlags.Token, next: &trans}
+	}
+
+	scheme := "http"
+	if globalFlags.CAFile != "" || globalFlags.CertFile != "" {
+		scheme = "https"
+	}
 
 	endpoint := globalFlags.Endpoint
 	if dialDomainSocket {
 		endpoint = "http://domain-sock/"
 	} else if !strings.HasPrefix(endpoint, "http") {
-		endpoint = fmt.Sprintf("http://%s", endpoint)
+		endpoint = fmt.Sprintf("%s://%s", scheme, endpoint)
 	}
 
 	return client.NewHTTPClient(&hc, endpoint)
 }
 
+// getAPITLSConfig builds the TLS configuration used to connect to the fleet
+// API from the CLI flags. The certificate of the API is verified against the
+// given CA, if any, or the system's root CAs otherwise.
+func getAPITLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+
+	if globalFlags.CertFile != "" || globalFlags.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(globalFlags.CertFile, globalFlags.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if globalFlags.CAFile != "" {
+		ca, err := ioutil.ReadFile(globalFlags.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", globalFlags.CAFile)
+		}
+	}
+
+	return cfg, nil
+}
+
+// tokenTransport authenticates each request to the fleet API with a token
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (tt *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the given request
+	areq := *req
+	areq.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		areq.Header[k] = v
+	}
+	areq.Header.Set("Authorization", "Bearer "+tt.token)
+	return tt.next.RoundTrip(&areq)
+}
+
 func getRegistryClient() (client.API, error) {
 	var dial func(string, string) (net.Conn, error)
 	tun := getTunnelFlag()
@@ -345,12 +473,26 @@ func getRegistryClient() (client.API, error) {
 	}
 
 	reg := registry.NewEtcdRegistry(eClient, globalFlags.EtcdKeyPrefix)
+	if globalFlags.RegistryKeyFiles != "" {
+		kr, err := registry.ReadKeyring(strings.Split(globalFlags.RegistryKeyFiles, ","))
+		if err != nil {
+			return nil, err
+		}
+		reg.SetKeyring(kr)
+	}
 
 	if msg, ok := checkVersion(reg); !ok {
 		stderr(msg)
 	}
 
-	return &client.RegistryClient{reg}, nil
+	// Changes made directly against the registry are recorded in its
+	// audit log, as the API does when configured to
+	record := func(ae registry.AuditEntry) {
+		if err := reg.RecordAudit(ae); err != nil {
+			stderr("Failed recording change in audit log: %v", err)
+		}
+	}
+	return client.NewAuditedAPI(&client.RegistryClient{Registry: reg}, localUser(), record), nil
 }
 
 // getChecker creates and returns a HostKeyChecker, or nil if any error is encountered
@@ -426,13 +568,98 @@ func findUnits(args []string) (sus []schema.Unit, err error) {
 	return filtered, nil
 }
 
+// expandUnitGlobs replaces the arguments that are glob patterns matching
+// unit names, such as web@*, with the names of the units in the cluster they
+// match, in order. Other arguments, including paths to local unit files, are
+// returned as they are. A pattern matching no unit is an error.
+func expandUnitGlobs(args []string) ([]string, error) {
+	var units []*schema.Unit
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if path.Base(arg) != arg || !strings.ContainsAny(arg, "*?[") {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		if units == nil {
+			var err error
+			if units, err = cAPI.Units(); err != nil {
+				return nil, err
+			}
+		}
+
+		pattern := unitNameMangle(arg)
+		var matched []string
+		for _, u := range units {
+			ok, err := path.Match(pattern, u.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %v", arg, err)
+			}
+			if ok && !seen[u.Name] {
+				matched = append(matched, u.Name)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no units match %s", pattern)
+		}
+		sort.Strings(matched)
+		for _, name := range matched {
+			seen[name] = true
+		}
+		expanded = append(expanded, matched...)
+	}
+	return expanded, nil
+}
+
 func createUnit(name string, uf *unit.UnitFile) (*schema.Unit, error) {
+	return createUnitWithState(name, uf, "")
+}
+
+// createUnitWithState creates a Unit with the given desired state, which
+// defaults to inactive if empty
+func createUnitWithState(name string, uf *unit.UnitFile, state job.JobState) (*schema.Unit, error) {
+	return createLabeledUnit(name, uf, state, nil)
+}
+
+// createLabeledUnit creates a Unit with the given desired state and labels,
+// along with those given with --label, which take precedence
+func createLabeledUnit(name string, uf *unit.UnitFile, state job.JobState, labels map[string]string) (*schema.Unit, error) {
+	u, err := newSchemaUnit(name, uf, state)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := submitLabels()
+	if err != nil {
+		return nil, err
+	}
+	if len(labels)+len(extra) > 0 {
+		u.Labels = make(map[string]string, len(labels)+len(extra))
+		for _, l := range []map[string]string{labels, extra} {
+			for k, v := range l {
+				u.Labels[k] = v
+			}
+		}
+	}
+	err = cAPI.CreateUnit(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating unit %s: %v", name, err)
+	}
+
+	log.V(1).Infof("Created Unit(%s) in Registry", name)
+	return u, nil
+}
+
+// newSchemaUnit validates the given unit file and returns the Unit to submit
+// it as, signed if a signing key was given
+func newSchemaUnit(name string, uf *unit.UnitFile, state job.JobState) (*schema.Unit, error) {
 	if uf == nil {
 		return nil, fmt.Errorf("nil unit provided")
 	}
 	u := schema.Unit{
-		Name:    name,
-		Options: schema.MapUnitFileToSchemaUnitOptions(uf),
+		Name:         name,
+		Options:      schema.MapUnitFileToSchemaUnitOptions(uf),
+		DesiredState: string(state),
 	}
 	// TODO(jonboulle): this dependency on the API package is awkward, and
 	// redundant with the check in api.unitsResource.set, but it is a
@@ -448,12 +675,21 @@ func createUnit(name string, uf *unit.UnitFile) (*schema.Unit, error) {
 	if err := j.ValidateRequirements(); err != nil {
 		log.Warningf("Unit %s: %v", name, err)
 	}
-	err := cAPI.CreateUnit(&u)
-	if err != nil {
-		return nil, fmt.Errorf("failed creating unit %s: %v", name, err)
+	if globalFlags.SigningKeyFile != "" {
+		key, err := unit.ReadSigningKey(globalFlags.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign unit %s: %v", name, err)
+		}
+		signed := uf
+		if globalFlags.Namespace != job.DefaultNamespace {
+			// sign the unit file as the namespaced API stores it, with
+			// the names of the units it refers to qualified
+			signed = schema.MapSchemaUnitOptionsToUnitFile(client.QualifyOptions(globalFlags.Namespace, u.Options))
+		}
+		if u.Signature, err = signed.Sign(key); err != nil {
+			return nil, fmt.Errorf("unable to sign unit %s: %v", name, err)
+		}
 	}
-
-	log.V(1).Infof("Created Unit(%s) in Registry", name)
 	return &u, nil
 }
 
@@ -468,6 +704,9 @@ func createUnit(name string, uf *unit.UnitFile) (*schema.Unit, error) {
 // subsequent Jobs are not acted on). An error is also returned if none of the
 // above conditions match a given Job.
 func lazyCreateUnits(args []string) error {
+	if _, err := submitLabels(); err != nil {
+		return err
+	}
 	for _, arg := range args {
 		// TODO(jonboulle): this loop is getting too unwieldy; factor it out
 
@@ -512,6 +751,7 @@ func lazyCreateUnits(args []string) error {
 
 		// Finally, if we could not find a template unit in the Registry, check the local disk for one instead
 		var uf *unit.UnitFile
+		var labels map[string]string
 		if tmpl == nil {
 			file := path.Join(path.Dir(arg), uni.Template)
 			if _, err := os.Stat(file); os.IsNotExist(err) {
@@ -524,11 +764,13 @@ func lazyCreateUnits(args []string) error {
 		} else {
 			warnOnDifferentLocalUnit(arg, tmpl)
 			uf = schema.MapSchemaUnitOptionsToUnitFile(tmpl.Options)
+			labels = tmpl.Labels
 		}
 
 		// If we found a template unit, create a near-identical instance unit in
-		// the Registry - same unit file as the template, but different name
-		u, err = createUnit(name, uf)
+		// the Registry - same unit file and labels as the template, but
+		// different name
+		u, err = createLabeledUnit(name, uf, "", labels)
 		if err != nil {
 			return err
 		}
@@ -680,6 +922,35 @@ func machineState(machID string) (*machine.MachineState, error) {
 	return nil, nil
 }
 
+// findMachine returns the state of the single machine whose ID starts
+// with the given string
+func findMachine(lookup string) (*machine.MachineState, error) {
+	states, err := cAPI.Machines()
+	if err != nil {
+		return nil, err
+	}
+
+	var match *machine.MachineState
+	for i := range states {
+		machState := states[i]
+		if !strings.HasPrefix(machState.ID, lookup) {
+			continue
+		}
+
+		if match != nil {
+			return nil, fmt.Errorf("found more than one machine")
+		}
+
+		match = &machState
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("machine does not exist")
+	}
+
+	return match, nil
+}
+
 // cachedMachineState makes a best-effort to retrieve the MachineState of the given machine ID.
 // It memoizes MachineState information for the life of a fleetctl invocation.
 // Any error encountered retrieving the list of machines is ignored.
@@ -709,6 +980,12 @@ func unitNameMangle(baseName string) string {
 	return name
 }
 
+// systemdUnitName returns the name systemd knows the named unit of the
+// --namespace by on the machines it runs on
+func systemdUnitName(name string) string {
+	return job.QualifyName(globalFlags.Namespace, name)
+}
+
 // suToGlobal returns whether or not a schema.Unit refers to a global unit
 func suToGlobal(su schema.Unit) bool {
 	u := job.Unit{
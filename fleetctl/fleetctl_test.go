@@ -1,9 +1,18 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
 
 	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/registry"
 	"github.com/coreos/fleet/unit"
@@ -111,6 +120,40 @@ func newUnitFile(t *testing.T, contents string) *unit.UnitFile {
 	return uf
 }
 
+func TestExpandUnitGlobs(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "web@2.service"},
+		job.Job{Name: "web@1.service"},
+		job.Job{Name: "web@.service"},
+		job.Job{Name: "db.service"},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args []string
+		want []string
+		err  bool
+	}{
+		{[]string{"db", "web@1.service"}, []string{"db", "web@1.service"}, false},
+		{[]string{"web@?"}, []string{"web@1.service", "web@2.service"}, false},
+		{[]string{"web@*", "*"}, []string{"web@.service", "web@1.service", "web@2.service", "db.service"}, false},
+		// paths are left to the shell
+		{[]string{"units/web@*"}, []string{"units/web@*"}, false},
+		{[]string{"api@*"}, nil, true},
+		{[]string{"web@["}, nil, true},
+	} {
+		got, err := expandUnitGlobs(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("case %d: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
 func TestCreateUnitFails(t *testing.T) {
 	type fakeAPI struct {
 		client.API
@@ -189,3 +232,118 @@ Conflicts=bar`),
 		}
 	}
 }
+
+// writeSigningKey writes a new signing key to a temporary file, returning
+// the key and the name of the file
+func writeSigningKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed generating key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("Failed marshaling key: %v", err)
+	}
+	f, err := ioutil.TempFile("", "fleetctl-signing-key")
+	if err != nil {
+		t.Fatalf("Failed creating temporary file: %v", err)
+	}
+	pem.Encode(f, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	f.Close()
+	return priv, f.Name()
+}
+
+func TestCreateUnitSigned(t *testing.T) {
+	priv, keyFile := writeSigningKey(t)
+	defer os.Remove(keyFile)
+
+	reg := registry.NewFakeRegistry()
+	cAPI = &client.RegistryClient{Registry: reg}
+	globalFlags.SigningKeyFile = keyFile
+	defer func() { globalFlags.SigningKeyFile = "" }()
+
+	uf := newUnitFile(t, "[Service]\nExecStart=/bin/hello\n")
+	if _, err := createUnit("hello.service", uf); err != nil {
+		t.Fatalf("Unexpected error creating unit: %v", err)
+	}
+	sig, _ := reg.UnitSignature(uf.Hash())
+	if err := uf.VerifySignature([]*ecdsa.PublicKey{&priv.PublicKey}, sig); err != nil {
+		t.Errorf("Unit file not signed: %v", err)
+	}
+}
+
+func TestNamespacedUnits(t *testing.T) {
+	uf := newUnitFile(t, "[Service]\nExecStart=/bin/hello")
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		*job.NewJob("web.service", *uf),
+		*job.NewJob("team-b:web.service", *uf),
+	})
+	cAPI = client.NewNamespacedAPI(&client.RegistryClient{Registry: reg}, "team-a")
+	defer func() { cAPI = nil }()
+
+	if _, err := createUnit("web.service", uf); err != nil {
+		t.Fatalf("Unexpected error creating unit: %v", err)
+	}
+	if u, _ := reg.Unit("team-a:web.service"); u == nil {
+		t.Fatal("Expected unit created in namespace")
+	}
+
+	units, err := cAPI.Units()
+	if err != nil {
+		t.Fatalf("Unexpected error listing units: %v", err)
+	}
+	if len(units) != 1 || units[0].Name != "web.service" {
+		t.Errorf("Expected only web.service of the namespace, got %v", units)
+	}
+
+	if err := cAPI.DestroyUnit("web.service"); err != nil {
+		t.Fatalf("Unexpected error destroying unit: %v", err)
+	}
+	if u, _ := reg.Unit("web.service"); u == nil {
+		t.Error("Expected unit of the default namespace left alone")
+	}
+	if u, _ := reg.Unit("team-a:web.service"); u != nil {
+		t.Error("Expected unit of the namespace destroyed")
+	}
+}
+
+func TestNamespacedUnitReferences(t *testing.T) {
+	priv, keyFile := writeSigningKey(t)
+	defer os.Remove(keyFile)
+
+	reg := registry.NewFakeRegistry()
+	cAPI = client.NewNamespacedAPI(&client.RegistryClient{Registry: reg}, "team-a")
+	globalFlags.Namespace = "team-a"
+	globalFlags.SigningKeyFile = keyFile
+	defer func() {
+		cAPI = nil
+		globalFlags.Namespace = job.DefaultNamespace
+		globalFlags.SigningKeyFile = ""
+	}()
+
+	uf := newUnitFile(t, `[Service]
+ExecStart=/bin/app
+[X-Fleet]
+MachineOf=db.service
+Conflicts=app@*.service
+FleetAfter=%p-cache.service`)
+	if _, err := createUnit("app@1.service", uf); err != nil {
+		t.Fatalf("Unexpected error creating unit: %v", err)
+	}
+	u, _ := reg.Unit("team-a:app@1.service")
+	if u == nil {
+		t.Fatal("Expected unit created in namespace")
+	}
+
+	j := job.NewJob(u.Name, u.Unit)
+	want := []string{"team-a:db.service", "team-a:app@*.service", "team-a:app-cache.service"}
+	if got := j.References(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected references %v, got %v", want, got)
+	}
+
+	sig, _ := reg.UnitSignature(u.Unit.Hash())
+	if err := u.Unit.VerifySignature([]*ecdsa.PublicKey{&priv.PublicKey}, sig); err != nil {
+		t.Errorf("Stored unit file not signed: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/coreos/fleet/schema"
+)
+
+var cmdHistory = &Command{
+	Name:    "history",
+	Summary: "List the versions of the unit file of a unit",
+	Usage:   "[--no-legend] [-l|--full] UNIT",
+	Description: `Lists the versions of the unit file a unit was submitted with, oldest first:
+the number of each version, the hash of its unit file and when it was
+submitted. The version the unit currently runs is marked as current. Versions
+are kept after the unit is destroyed, up to the latest 20.
+
+Print a version with "fleetctl cat --version=N" and submit it again with
+"fleetctl rollback --to=N".
+
+List the versions of a unit:
+	fleetctl history foo.service`,
+	Run: runHistory,
+}
+
+func init() {
+	cmdHistory.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+	cmdHistory.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+	cmdHistory.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runHistory(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One unit must be provided.")
+		return 1
+	}
+
+	name := unitNameMangle(args[0])
+	versions, err := cAPI.UnitVersions(name)
+	if err != nil {
+		stderr("Error retrieving versions of Unit(%s): %v", name, err)
+		return 1
+	} else if len(versions) == 0 {
+		stderr("No versions of unit %s recorded.", name)
+		return 1
+	}
+
+	current, err := currentUnitHash(name)
+	if err != nil {
+		stderr("Error retrieving Unit(%s): %v", name, err)
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "VERSION\tHASH\tCREATED\tCURRENT")
+	}
+	for _, uv := range versions {
+		hash := uv.Hash
+		if !sharedFlags.Full && len(hash) > 7 {
+			hash = hash[:7]
+		}
+		cur := "-"
+		if uv.Hash == current {
+			cur = "current"
+		}
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\n", uv.Version, hash, uv.Created, cur)
+	}
+	out.Flush()
+	return
+}
+
+// currentUnitHash returns the hash of the unit file of the named unit, or
+// an empty string if it does not exist
+func currentUnitHash(name string) (string, error) {
+	u, err := cAPI.Unit(name)
+	if err != nil || u == nil {
+		return "", err
+	}
+	return schema.MapSchemaUnitOptionsToUnitFile(u.Options).Hash().String(), nil
+}
+
+// findUnitVersion returns the given version of the named unit, or an error
+// if it is not recorded
+func findUnitVersion(name string, version int) (*schema.UnitVersion, error) {
+	versions, err := cAPI.UnitVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, uv := range versions {
+		if int(uv.Version) == version {
+			return uv, nil
+		}
+	}
+	return nil, fmt.Errorf("version %d of unit %s is not recorded", version, name)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/coreos/fleet/job"
 )
@@ -9,10 +11,11 @@ import (
 var (
 	flagLines  int
 	flagFollow bool
+	flagViaAPI bool
 	cmdJournal = &Command{
 		Name:    "journal",
 		Summary: "Print the journal of a unit in the cluster to stdout",
-		Usage:   "[--lines=N] [-f|--follow] <unit>",
+		Usage:   "[--lines=N] [-f|--follow] [--via-api] <unit>",
 		Run:     runJournal,
 		Description: `Outputs the journal of a unit by connecting to the machine that the unit occupies.
 
@@ -22,6 +25,11 @@ Read the last 10 lines:
 Read the last 100 lines:
 	fleetctl journal --lines 100 foo.service
 
+Follow the journal through the API of the cluster rather than over SSH, on
+whichever machine the unit is rescheduled to, which requires the machines to
+advertise their API with api_advertise_url:
+	fleetctl --experimental-api journal --via-api -f foo.service
+
 This command does not work with global units.`,
 	}
 )
@@ -30,6 +38,7 @@ func init() {
 	cmdJournal.Flags.IntVar(&flagLines, "lines", 10, "Number of recent log lines to return")
 	cmdJournal.Flags.BoolVar(&flagFollow, "follow", false, "Continuously print new entries as they are appended to the journal.")
 	cmdJournal.Flags.BoolVar(&flagFollow, "f", false, "Shorthand for --follow")
+	cmdJournal.Flags.BoolVar(&flagViaAPI, "via-api", false, "Read the journal through the fleet API rather than over SSH. Requires --experimental-api.")
 }
 
 func runJournal(args []string) (exit int) {
@@ -54,7 +63,21 @@ func runJournal(args []string) (exit int) {
 		return 1
 	}
 
-	command := fmt.Sprintf("journalctl --unit %s --no-pager -n %d", name, flagLines)
+	if flagViaAPI {
+		journal, err := cAPI.UnitJournal(name, flagLines, flagFollow)
+		if err != nil {
+			stderr("Error retrieving journal of unit %s: %v", name, err)
+			return 1
+		}
+		defer journal.Close()
+		if _, err := io.Copy(os.Stdout, journal); err != nil {
+			stderr("Error reading journal of unit %s: %v", name, err)
+			return 1
+		}
+		return
+	}
+
+	command := fmt.Sprintf("journalctl --unit %s --no-pager -n %d", systemdUnitName(name), flagLines)
 	if flagFollow {
 		command += " -f"
 	}
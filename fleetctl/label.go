@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+)
+
+var cmdLabelUnit = &Command{
+	Name:    "label",
+	Summary: "Set or remove labels of one or more units in the cluster",
+	Usage:   "[-l SELECTOR] UNIT... KEY=VALUE...",
+	Description: `Labels are arbitrary key/value pairs attached to units, by which related units
+are selected with the --selector (-l) flag of start, stop, load, unload, destroy
+and list-unit-files, rather than by their names. Units may also be labeled as
+they are submitted, with the --label flag of submit, load and start.
+
+Keys and values are up to 63 letters, digits, "-", "_", "." and "/". Setting a
+label replaces any earlier value of it; leaving its value empty removes it.
+Without KEY=VALUE pairs, the labels of the units are printed.
+
+Label a unit with the app it belongs to and its environment:
+	fleetctl label web@1.service app=web env=staging
+
+Remove the env label of all instances of a template unit:
+	fleetctl label 'web@*' env=
+
+Promote the staging units of an app to production:
+	fleetctl label -l app=web,env=staging env=production
+
+A selector is a comma-separated list of requirements units must all meet:
+key=value, key!=value, key (the label is set) or !key (the label is not set).`,
+	Run: runLabelUnit,
+}
+
+func init() {
+	addSelectorFlag(cmdLabelUnit)
+}
+
+// addSelectorFlag registers the --selector flag, and its -l shorthand, with
+// the given Command
+func addSelectorFlag(cmd *Command) {
+	usage := "Also act on the units whose labels match the given selector, e.g. app=web,env!=staging."
+	cmd.Flags.StringVar(&sharedFlags.Selector, "selector", "", usage)
+	cmd.Flags.StringVar(&sharedFlags.Selector, "l", "", "Shorthand for --selector")
+}
+
+// addLabelFlag registers the --label flag with the given Command, which
+// labels the units it submits
+func addLabelFlag(cmd *Command) {
+	cmd.Flags.StringVar(&sharedFlags.Labels, "label", "", "Label the units submitted with the given comma-separated KEY=VALUE pairs. Units already in the cluster are left alone.")
+}
+
+func runLabelUnit(args []string) (exit int) {
+	var names []string
+	set := make(map[string]string)
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			names = append(names, arg)
+			continue
+		}
+		if kv[1] != "" {
+			if err := job.ValidateLabel(kv[0], kv[1]); err != nil {
+				stderr("Invalid label %s: %v", arg, err)
+				return 1
+			}
+		}
+		set[kv[0]] = kv[1]
+	}
+
+	names, err := expandUnitGlobs(names)
+	if err == nil {
+		names, err = selectUnits(names)
+	}
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+	if len(names) == 0 {
+		stderr("At least one unit must be provided, by name or selector.")
+		return 1
+	}
+
+	units := make([]*schema.Unit, 0, len(names))
+	for _, name := range names {
+		name = unitNameMangle(name)
+		u, err := cAPI.Unit(name)
+		if err != nil {
+			stderr("Error retrieving unit %s: %v", name, err)
+			return 1
+		} else if u == nil {
+			stderr("Unit %s does not exist.", name)
+			return 1
+		}
+		units = append(units, u)
+	}
+
+	if len(set) == 0 {
+		for _, u := range units {
+			fmt.Fprintf(out, "%s\t%s\n", u.Name, job.FormatLabels(u.Labels))
+		}
+		out.Flush()
+		return
+	}
+
+	for _, u := range units {
+		labels := make(map[string]string, len(u.Labels)+len(set))
+		for k, v := range u.Labels {
+			labels[k] = v
+		}
+		for k, v := range set {
+			if v == "" {
+				delete(labels, k)
+			} else {
+				labels[k] = v
+			}
+		}
+		if err := cAPI.SetUnitLabels(u.Name, labels); err != nil {
+			stderr("Error labeling unit %s: %v", u.Name, err)
+			return 1
+		}
+		stdout("Labeled %s %s", u.Name, job.FormatLabels(labels))
+	}
+	return
+}
+
+// selectUnits returns the given names of units followed by the names of
+// the other units in the cluster whose labels match the selector given
+// with --selector, if any, in order. A selector matching no unit is an
+// error.
+func selectUnits(names []string) ([]string, error) {
+	if sharedFlags.Selector == "" {
+		return names, nil
+	}
+	sel, err := job.ParseSelector(sharedFlags.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("selector %q selects all units", sharedFlags.Selector)
+	}
+
+	units, err := cAPI.Units()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[unitNameMangle(name)] = true
+	}
+	var matched []string
+	for _, u := range units {
+		if sel.Matches(u.Labels) && !seen[u.Name] {
+			matched = append(matched, u.Name)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no units match selector %s", sharedFlags.Selector)
+	}
+	sort.Strings(matched)
+	return append(names, matched...), nil
+}
+
+// submitLabels returns the labels given with --label to label the units
+// submitted with
+func submitLabels() (map[string]string, error) {
+	labels, err := job.ParseLabels(sharedFlags.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --label: %v", err)
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestRunLabelUnit(t *testing.T) {
+	uf := newUnitFile(t, "[Service]\nExecStart=/bin/hello")
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		*job.NewJob("web@1.service", *uf),
+		*job.NewJob("web@2.service", *uf),
+		*job.NewJob("db.service", *uf),
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+	defer func() { sharedFlags.Selector = "" }()
+
+	for i, tt := range []struct {
+		selector string
+		args     []string
+		exit     int
+		want     map[string]map[string]string
+	}{
+		// units must be given and exist, labels be valid
+		{"", []string{"app=web"}, 1, nil},
+		{"", []string{"nope.service", "app=web"}, 1, nil},
+		{"", []string{"db.service", "app=my db"}, 1, nil},
+		{"app=web", []string{"env=staging"}, 1, nil},
+		{"", []string{"web@*", "app=web", "env=staging"}, 0, map[string]map[string]string{
+			"web@1.service": {"app": "web", "env": "staging"},
+			"web@2.service": {"app": "web", "env": "staging"},
+		}},
+		{"", []string{"db.service", "app=db"}, 0, map[string]map[string]string{
+			"web@1.service": {"app": "web", "env": "staging"},
+			"web@2.service": {"app": "web", "env": "staging"},
+			"db.service":    {"app": "db"},
+		}},
+		// labels are replaced and removed by selector
+		{"app=web", []string{"env=production"}, 0, map[string]map[string]string{
+			"web@1.service": {"app": "web", "env": "production"},
+			"web@2.service": {"app": "web", "env": "production"},
+			"db.service":    {"app": "db"},
+		}},
+		{"env", []string{"web@1.service", "env="}, 0, map[string]map[string]string{
+			"web@1.service": {"app": "web"},
+			"web@2.service": {"app": "web"},
+			"db.service":    {"app": "db"},
+		}},
+		// printing labels changes nothing
+		{"", []string{"db.service"}, 0, map[string]map[string]string{
+			"web@1.service": {"app": "web"},
+			"web@2.service": {"app": "web"},
+			"db.service":    {"app": "db"},
+		}},
+	} {
+		sharedFlags.Selector = tt.selector
+		if exit := runLabelUnit(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		got := make(map[string]map[string]string)
+		units, _ := reg.Units()
+		for _, u := range units {
+			if u.Labels != nil {
+				got[u.Name] = u.Labels
+			}
+		}
+		want := tt.want
+		if want == nil {
+			want = map[string]map[string]string{}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("case %d: unexpected labels: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSelectUnits(t *testing.T) {
+	uf := newUnitFile(t, "[Service]\nExecStart=/bin/hello")
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		*job.NewJob("web@1.service", *uf),
+		*job.NewJob("web@2.service", *uf),
+		*job.NewJob("db.service", *uf),
+	})
+	reg.SetUnitLabels("web@1.service", map[string]string{"app": "web", "env": "staging"})
+	reg.SetUnitLabels("web@2.service", map[string]string{"app": "web", "env": "production"})
+	reg.SetUnitLabels("db.service", map[string]string{"app": "db", "env": "staging"})
+	cAPI = &client.RegistryClient{Registry: reg}
+	defer func() { sharedFlags.Selector = "" }()
+
+	for i, tt := range []struct {
+		selector string
+		args     []string
+		want     []string
+		err      bool
+	}{
+		{"", []string{"foo.service"}, []string{"foo.service"}, false},
+		{"app=web", nil, []string{"web@1.service", "web@2.service"}, false},
+		{"app=web,env=staging", nil, []string{"web@1.service"}, false},
+		{"env=staging", []string{"db.service"}, []string{"db.service", "web@1.service"}, false},
+		{"app!=web", []string{"foo.service"}, []string{"foo.service", "db.service"}, false},
+		{"app=api", nil, nil, true},
+		{"=web", nil, nil, true},
+		{" ", nil, nil, true},
+	} {
+		sharedFlags.Selector = tt.selector
+		got, err := selectUnits(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: selected %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestCreateUnitsLabeled(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	cAPI = &client.RegistryClient{Registry: reg}
+	defer func() { sharedFlags.Labels = "" }()
+
+	tmpl := newUnitFile(t, "[Service]\nExecStart=/bin/web %i")
+	su := schema.Unit{
+		Name:    "web@.service",
+		Options: schema.MapUnitFileToSchemaUnitOptions(tmpl),
+		Labels:  map[string]string{"app": "web", "env": "staging"},
+	}
+	if err := cAPI.CreateUnit(&su); err != nil {
+		t.Fatalf("Unexpected error creating template: %v", err)
+	}
+
+	sharedFlags.Labels = "app"
+	if err := lazyCreateUnits([]string{"web@1.service"}); err == nil {
+		t.Errorf("Expected error creating units with invalid labels")
+	}
+
+	// instances are labeled like their template, unless told otherwise
+	sharedFlags.Labels = "env=production,tier=frontend"
+	if err := lazyCreateUnits([]string{"web@1.service"}); err != nil {
+		t.Fatalf("Unexpected error creating units: %v", err)
+	}
+	u, _ := reg.Unit("web@1.service")
+	if want := map[string]string{"app": "web", "env": "production", "tier": "frontend"}; u == nil || !reflect.DeepEqual(u.Labels, want) {
+		t.Errorf("Unexpected unit %#v, want labels %v", u, want)
+	}
+}
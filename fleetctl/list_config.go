@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/coreos/fleet/job"
+)
+
+var cmdListConfig = &Command{
+	Name:    "list-config",
+	Summary: "List the configuration values of a namespace",
+	Usage:   "[--no-legend] NAMESPACE",
+	Description: `Lists the configuration values stored in a namespace with set-config. The
+values of secrets are not shown.`,
+	Run: runListConfig,
+}
+
+func init() {
+	cmdListConfig.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runListConfig(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One namespace must be provided.")
+		return 1
+	}
+	if err := job.ValidateConfigNamespace(args[0]); err != nil {
+		stderr("Invalid namespace %q: %v", args[0], err)
+		return 1
+	}
+
+	values, err := cAPI.ConfigValues(args[0])
+	if err != nil {
+		stderr("Error retrieving configuration values: %v", err)
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "KEY\tVALUE")
+	}
+	for _, cv := range values {
+		val := cv.Value
+		if cv.Secret {
+			val = "(secret)"
+		}
+		fmt.Fprintf(out, "%s\t%s\n", cv.Key, val)
+	}
+	out.Flush()
+	return
+}
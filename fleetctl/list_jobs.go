@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+)
+
+const (
+	jobStatePending   = "pending"
+	jobStateRunning   = "running"
+	jobStateSucceeded = "succeeded"
+	jobStateFailed    = "failed"
+)
+
+var (
+	flagJobsCompleted bool
+
+	cmdListJobs = &Command{
+		Name:    "list-jobs",
+		Summary: "List batch units and how their runs ended",
+		Usage:   "[--completed] [--no-legend]",
+		Description: `Lists the units with Batch=true, which run to completion once, with their
+state: pending until they start, running, then succeeded or failed. Finished
+jobs show the exit code of their main process and how long they ran, as far
+as systemd recorded them, and the machine they ran on.
+
+Succeeded jobs are neither restarted nor rescheduled. Destroy and submit a job
+again to rerun it.
+
+List only the jobs that finished:
+	fleetctl list-jobs --completed`,
+		Run: runListJobs,
+	}
+)
+
+func init() {
+	cmdListJobs.Flags.BoolVar(&flagJobsCompleted, "completed", false, "List only jobs that finished")
+	cmdListJobs.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runListJobs(args []string) (exit int) {
+	units, err := cAPI.Units()
+	if err != nil {
+		stderr("Error retrieving list of units from repository: %v", err)
+		return 1
+	}
+	states, err := cAPI.UnitStates()
+	if err != nil {
+		stderr("Error retrieving list of units from repository: %v", err)
+		return 1
+	}
+	completions, err := cAPI.UnitCompletions()
+	if err != nil {
+		stderr("Error retrieving completions of units: %v", err)
+		return 1
+	}
+
+	completed := make(map[string]*schema.UnitCompletion, len(completions))
+	for _, uc := range completions {
+		completed[uc.Name] = uc
+	}
+	active := make(map[string]*schema.UnitState, len(states))
+	for _, us := range states {
+		active[us.Name] = us
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "JOB\tMACHINE\tSTATE\tEXIT\tDURATION")
+	}
+	for _, u := range units {
+		if !suToBatch(*u) {
+			continue
+		}
+
+		uc, ok := completed[u.Name]
+		if !ok {
+			if flagJobsCompleted {
+				continue
+			}
+			state, machine := jobStatePending, "-"
+			if us := active[u.Name]; us != nil {
+				machine = machineLegend(us.MachineID)
+				if us.SystemdActiveState == "active" || us.SystemdActiveState == "activating" {
+					state = jobStateRunning
+				}
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\t-\t-\n", u.Name, machine, state)
+			continue
+		}
+
+		state := jobStateFailed
+		if uc.Result == "success" {
+			state = jobStateSucceeded
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%d\t%s\n", u.Name, machineLegend(uc.MachineID), state, uc.ExitCode, completionDuration(uc))
+	}
+	out.Flush()
+	return
+}
+
+// suToBatch returns whether or not a schema.Unit refers to a batch unit
+func suToBatch(su schema.Unit) bool {
+	u := job.Unit{
+		Unit: *schema.MapSchemaUnitOptionsToUnitFile(su.Options),
+	}
+	return u.IsBatch()
+}
+
+// completionDuration formats how long a finished job ran, or "-" if unknown
+func completionDuration(uc *schema.UnitCompletion) string {
+	started, err := time.Parse(time.RFC3339, uc.Started)
+	if err != nil {
+		return "-"
+	}
+	finished, err := time.Parse(time.RFC3339, uc.Finished)
+	if err != nil || finished.Before(started) {
+		return "-"
+	}
+	return finished.Sub(started).String()
+}
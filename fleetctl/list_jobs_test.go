@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/coreos/fleet/schema"
+)
+
+func TestCompletionDuration(t *testing.T) {
+	for i, tt := range []struct {
+		uc   schema.UnitCompletion
+		want string
+	}{
+		{schema.UnitCompletion{Started: "2014-10-01T12:00:00Z", Finished: "2014-10-01T13:30:05Z"}, "1h30m5s"},
+		// the start of units that are not services is not recorded
+		{schema.UnitCompletion{Finished: "2014-10-01T13:30:05Z"}, "-"},
+		{schema.UnitCompletion{Started: "2014-10-01T13:00:00Z", Finished: "2014-10-01T12:00:00Z"}, "-"},
+	} {
+		if got := completionDuration(&tt.uc); got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestSuToBatch(t *testing.T) {
+	batch := schema.Unit{Options: []*schema.UnitOption{
+		&schema.UnitOption{Section: "X-Fleet", Name: "Batch", Value: "true"},
+	}}
+	if !suToBatch(batch) {
+		t.Errorf("Expected unit with Batch=true to be a batch unit")
+	}
+	if suToBatch(schema.Unit{}) {
+		t.Errorf("Expected unit without Batch to not be a batch unit")
+	}
+}
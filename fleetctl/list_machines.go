@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/coreos/fleet/machine"
 )
@@ -14,17 +15,44 @@ const (
 
 var (
 	listMachinesFieldsFlag string
+	flagShowLastHeartbeat  bool
+	listMachinesWatchFlag  bool
 	cmdListMachines        = &Command{
 		Name:    "list-machines",
 		Summary: "Enumerate the current hosts in the cluster",
-		Usage:   "[-l|--full] [--no-legend]",
+		Usage:   "[-l|--full] [--no-legend] [--fields] [--show-last-heartbeat] [--output=table|json|yaml] [-w|--watch]",
 		Description: `Lists all active machines within the cluster. Previously active machines will not appear in this list.
 
 For easily parsable output, you can remove the column headers:
 	fleetctl list-machines --no-legend
 
 Output the list without truncation:
-	fleetctl list-machines --full`,
+	fleetctl list-machines --full
+
+Show the CPU units, memory and disk reserved on each machine out of its total:
+	fleetctl list-machines --fields=machine,cpu,memory,disk
+
+Show the extended resources, like GPUs, requested on each machine out of those
+it offers:
+	fleetctl list-machines --fields=machine,resources
+
+Show all addresses of each machine, IPv4 and IPv6, most preferred first:
+	fleetctl list-machines --fields=machine,addresses
+
+Show which machines are cordoned or draining:
+	fleetctl list-machines --fields=machine,ip,state
+
+Show the taints of each machine:
+	fleetctl list-machines --fields=machine,taints
+
+Show how long ago each machine last renewed its presence:
+	fleetctl list-machines --show-last-heartbeat
+
+Print all fields of each machine as YAML:
+	fleetctl list-machines --output=yaml
+
+Keep the list up to date as machines join and leave, and units are scheduled:
+	fleetctl list-machines --watch --fields=machine,cpu,memory`,
 		Run: runListMachines,
 	}
 
@@ -38,12 +66,80 @@ Output the list without truncation:
 			}
 			return ms.PublicIP
 		},
+		"addresses": func(ms *machine.MachineState, full bool) string {
+			addrs := ms.AllAddresses()
+			if len(addrs) == 0 {
+				return "-"
+			}
+			var ips []string
+			for _, a := range addrs {
+				ip := a.IP
+				if a.Scope == machine.AddressScopePrivate {
+					ip += "(private)"
+				}
+				ips = append(ips, ip)
+			}
+			return strings.Join(ips, ",")
+		},
 		"metadata": func(ms *machine.MachineState, full bool) string {
 			if len(ms.Metadata) == 0 {
 				return "-"
 			}
 			return formatMetadata(ms.Metadata)
 		},
+		"state": func(ms *machine.MachineState, full bool) string {
+			switch {
+			case ms.Draining:
+				return "draining"
+			case ms.Cordoned:
+				return "cordoned"
+			}
+			return "-"
+		},
+		"taints": func(ms *machine.MachineState, full bool) string {
+			if len(ms.Taints) == 0 {
+				return "-"
+			}
+			var taints []string
+			for _, t := range ms.Taints {
+				taints = append(taints, t.String())
+			}
+			return strings.Join(taints, ",")
+		},
+		"cpu": func(ms *machine.MachineState, full bool) string {
+			if ms.TotalResources.Cores == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%d/%d", ms.AllocatedResources.Cores, ms.TotalResources.Cores)
+		},
+		"memory": func(ms *machine.MachineState, full bool) string {
+			if ms.TotalResources.Memory == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%dMB/%dMB", ms.AllocatedResources.Memory, ms.TotalResources.Memory)
+		},
+		"disk": func(ms *machine.MachineState, full bool) string {
+			if ms.TotalResources.Disk == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%dMB/%dMB", ms.AllocatedResources.Disk, ms.TotalResources.Disk)
+		},
+		"resources": func(ms *machine.MachineState, full bool) string {
+			if len(ms.ExtendedResources) == 0 {
+				return "-"
+			}
+			var pairs []string
+			for _, name := range ms.ExtendedResources.Names() {
+				pairs = append(pairs, fmt.Sprintf("%s:%d/%d", name, ms.AllocatedExtendedResources[name], ms.ExtendedResources[name]))
+			}
+			return strings.Join(pairs, ",")
+		},
+		"heartbeat": func(ms *machine.MachineState, full bool) string {
+			if ms.LastHeartbeat == nil {
+				return "-"
+			}
+			return heartbeatAge(*ms.LastHeartbeat, time.Now())
+		},
 	}
 )
 
@@ -53,10 +149,20 @@ func init() {
 	cmdListMachines.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
 	cmdListMachines.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
 	cmdListMachines.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+	addOutputFlag(cmdListMachines)
 	cmdListMachines.Flags.StringVar(&listMachinesFieldsFlag, "fields", defaultListMachinesFields, fmt.Sprintf("Columns to print for each Machine. Valid fields are %q", strings.Join(machineToFieldKeys(listMachinesFields), ",")))
+	cmdListMachines.Flags.BoolVar(&flagShowLastHeartbeat, "show-last-heartbeat", false, "Add a column showing how long ago each Machine last sent a heartbeat")
+	cmdListMachines.Flags.BoolVar(&listMachinesWatchFlag, "watch", false, "Print the list again each time events are recorded in the cluster")
+	cmdListMachines.Flags.BoolVar(&listMachinesWatchFlag, "w", false, "Shorthand for --watch")
 }
 
 func runListMachines(args []string) (exit int) {
+	structured, err := structuredOutput()
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	if listMachinesFieldsFlag == "" {
 		stderr("Must define output format")
 		return 1
@@ -69,13 +175,39 @@ func runListMachines(args []string) (exit int) {
 			return 1
 		}
 	}
+	if flagShowLastHeartbeat && !strings.Contains(","+listMachinesFieldsFlag+",", ",heartbeat,") {
+		cols = append(cols, "heartbeat")
+	}
+	if structured && listMachinesWatchFlag {
+		stderr("--watch cannot be used with --output.")
+		return 1
+	}
 
+	if listMachinesWatchFlag {
+		return watchList(func() int { return printMachines(cols, structured) })
+	}
+	return printMachines(cols, structured)
+}
+
+func printMachines(cols []string, structured bool) (exit int) {
 	machines, err := cAPI.Machines()
 	if err != nil {
 		stderr("Error retrieving list of active machines: %v", err)
 		return 1
 	}
 
+	if structured {
+		items := make([]machineOutput, len(machines))
+		for i := range machines {
+			items[i] = newMachineOutput(&machines[i])
+		}
+		if err := printStructured(items); err != nil {
+			stderr("Error printing machines: %v", err)
+			return 1
+		}
+		return
+	}
+
 	if !sharedFlags.NoLegend {
 		fmt.Fprintln(out, strings.ToUpper(strings.Join(cols, "\t")))
 	}
@@ -93,6 +225,16 @@ func runListMachines(args []string) (exit int) {
 	return
 }
 
+// heartbeatAge formats how long before now a heartbeat was sent, in whole
+// seconds
+func heartbeatAge(beat, now time.Time) string {
+	age := now.Sub(beat)
+	if age < 0 {
+		age = 0
+	}
+	return fmt.Sprintf("%s ago", age/time.Second*time.Second)
+}
+
 func formatMetadata(metadata map[string]string) string {
 	pairs := make([]string, len(metadata))
 	idx := 0
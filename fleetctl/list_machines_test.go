@@ -2,9 +2,11 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
 )
 
 func newTestRegistryForListMachines() registry.Registry {
@@ -47,6 +49,73 @@ func TestListMachinesFieldsToStrings(t *testing.T) {
 
 	val = listMachinesFields["metadata"](ms, false)
 	assertEqual(t, "metadata", "foo=bar,ping=pong", val)
+
+	val = listMachinesFields["addresses"](ms, false)
+	assertEqual(t, "addresses", "192.0.2.1", val)
+
+	ms.Addresses = []machine.Address{
+		{IP: "192.0.2.1", Scope: machine.AddressScopePublic},
+		{IP: "2001:db8::1", Scope: machine.AddressScopePublic},
+		{IP: "10.0.0.1", Scope: machine.AddressScopePrivate},
+	}
+	val = listMachinesFields["addresses"](ms, false)
+	assertEqual(t, "addresses", "192.0.2.1,2001:db8::1,10.0.0.1(private)", val)
+
+	ms.TotalResources = resource.ResourceTuple{Cores: 400, Memory: 2048, Disk: 10240}
+	ms.AllocatedResources = resource.ResourceTuple{Cores: 150, Memory: 512, Disk: 4096}
+
+	val = listMachinesFields["cpu"](ms, false)
+	assertEqual(t, "cpu", "150/400", val)
+
+	val = listMachinesFields["memory"](ms, false)
+	assertEqual(t, "memory", "512MB/2048MB", val)
+
+	val = listMachinesFields["disk"](ms, false)
+	assertEqual(t, "disk", "4096MB/10240MB", val)
+
+	val = listMachinesFields["resources"](ms, false)
+	assertEqual(t, "resources", "-", val)
+
+	ms.ExtendedResources = resource.Counts{"gpu": 2, "fpga": 1}
+	ms.AllocatedExtendedResources = resource.Counts{"gpu": 1}
+	val = listMachinesFields["resources"](ms, false)
+	assertEqual(t, "resources", "fpga:0/1,gpu:1/2", val)
+
+	val = listMachinesFields["state"](ms, false)
+	assertEqual(t, "state", "-", val)
+
+	ms.Cordoned = true
+	val = listMachinesFields["state"](ms, false)
+	assertEqual(t, "state", "cordoned", val)
+
+	ms.Draining = true
+	val = listMachinesFields["state"](ms, false)
+	assertEqual(t, "state", "draining", val)
+
+	beat := time.Now().Add(-time.Hour)
+	ms.LastHeartbeat = &beat
+	val = listMachinesFields["heartbeat"](ms, false)
+	assertEqual(t, "heartbeat", "1h0m0s ago", val)
+}
+
+func TestHeartbeatAge(t *testing.T) {
+	now := time.Date(2015, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		beat time.Time
+		want string
+	}{
+		{now, "0s ago"},
+		{now.Add(-4500 * time.Millisecond), "4s ago"},
+		{now.Add(-90 * time.Second), "1m30s ago"},
+		// clocks of machines may be slightly ahead
+		{now.Add(time.Second), "0s ago"},
+	}
+
+	for i, tt := range tests {
+		if got := heartbeatAge(tt.beat, now); got != tt.want {
+			t.Errorf("case %d: expected %q, got %q", i, tt.want, got)
+		}
+	}
 }
 
 func TestListMachinesFieldsEmpty(t *testing.T) {
@@ -62,7 +131,7 @@ func TestListMachinesFieldsEmpty(t *testing.T) {
 		Version:  ver,
 	}
 
-	for _, tt := range []string{"ip", "metadata"} {
+	for _, tt := range []string{"ip", "metadata", "state", "cpu", "memory", "disk", "heartbeat"} {
 		f := listMachinesFields[tt](ms, false)
 		assertEqual(t, tt, "-", f)
 	}
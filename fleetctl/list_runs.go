@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+)
+
+var cmdListRuns = &Command{
+	Name:    "list-runs",
+	Summary: "List the run history of a scheduled template unit",
+	Usage:   "[--no-legend] TEMPLATE",
+	Description: `Lists the recent runs the engine started of a template unit with a Schedule,
+oldest first: the tick of the schedule each run is for, the machine it ran on
+and its result, which is empty while the run is going.
+
+List the runs of a nightly backup:
+	fleetctl list-runs backup@.service`,
+	Run: runListRuns,
+}
+
+func init() {
+	cmdListRuns.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runListRuns(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One template unit must be provided.")
+		return 1
+	}
+
+	name := unitNameMangle(args[0])
+	u, err := cAPI.Unit(name)
+	if err != nil {
+		stderr("Error retrieving Unit(%s): %v", name, err)
+		return 1
+	} else if u == nil {
+		stderr("Unit %s does not exist.", name)
+		return 1
+	}
+
+	runs, err := cAPI.UnitRuns(name)
+	if err != nil {
+		stderr("Error retrieving runs of Unit(%s): %v", name, err)
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "RUN\tTICK\tMACHINE\tRESULT")
+	}
+	for _, run := range runs {
+		machine := "-"
+		if run.MachineID != "" {
+			machine = machineLegend(run.MachineID)
+		}
+		result := run.Result
+		if result == "" {
+			result = "running"
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", run.Name, run.Tick, machine, result)
+	}
+	out.Flush()
+	return
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var cmdListStacks = &Command{
+	Name:        "list-stacks",
+	Summary:     "List the stacks of units submitted together",
+	Usage:       "[--no-legend]",
+	Description: `Lists the stacks submitted with submit-stack, along with their units.`,
+	Run:         runListStacks,
+}
+
+func init() {
+	cmdListStacks.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runListStacks(args []string) (exit int) {
+	stacks, err := cAPI.Stacks()
+	if err != nil {
+		stderr("Error retrieving stacks: %v", err)
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "STACK\tUNITS")
+	}
+	for _, s := range stacks {
+		fmt.Fprintf(out, "%s\t%s\n", s.Name, strings.Join(s.Units, ","))
+	}
+	out.Flush()
+	return
+}
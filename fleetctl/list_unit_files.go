@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
 )
@@ -29,11 +31,14 @@ func mapTargetField(u schema.Unit, full bool) string {
 var (
 	listUnitFilesFieldsFlag string
 	cmdListUnitFiles        = &Command{
-		Name:        "list-unit-files",
-		Summary:     "List the units that exist in the cluster.",
-		Usage:       "[--fields]",
-		Description: `Lists all unit files that exist in the cluster (whether or not they are loaded onto a machine).`,
-		Run:         runListUnitFiles,
+		Name:    "list-unit-files",
+		Summary: "List the units that exist in the cluster.",
+		Usage:   "[--fields] [--selector=SELECTOR] [--output=table|json|yaml]",
+		Description: `Lists all unit files that exist in the cluster (whether or not they are loaded onto a machine).
+
+List the units labeled app=web, along with their labels:
+	fleetctl list-unit-files --selector app=web --fields unit,dstate,state,labels`,
+		Run: runListUnitFiles,
 	}
 	listUnitFilesFields = map[string]unitToField{
 		"unit": func(u schema.Unit, full bool) string {
@@ -63,6 +68,12 @@ var (
 			}
 			return uf.Hash().String()
 		},
+		"labels": func(u schema.Unit, full bool) string {
+			if len(u.Labels) == 0 {
+				return "-"
+			}
+			return job.FormatLabels(u.Labels)
+		},
 		"desc": func(u schema.Unit, full bool) string {
 			uf := schema.MapSchemaUnitOptionsToUnitFile(u.Options)
 			d := uf.Description()
@@ -79,10 +90,18 @@ type unitToField func(u schema.Unit, full bool) string
 func init() {
 	cmdListUnitFiles.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
 	cmdListUnitFiles.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+	addOutputFlag(cmdListUnitFiles)
+	cmdListUnitFiles.Flags.StringVar(&sharedFlags.Selector, "selector", "", "List only the units whose labels match the given selector, e.g. app=web,env!=staging.")
 	cmdListUnitFiles.Flags.StringVar(&listUnitFilesFieldsFlag, "fields", defaultListUnitFilesFields, fmt.Sprintf("Columns to print for each Unit file. Valid fields are %q", strings.Join(unitToFieldKeys(listUnitFilesFields), ",")))
 }
 
 func runListUnitFiles(args []string) (exit int) {
+	structured, err := structuredOutput()
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	if listUnitFilesFieldsFlag == "" {
 		stderr("Must define output format")
 		return 1
@@ -99,12 +118,29 @@ func runListUnitFiles(args []string) (exit int) {
 		}
 	}
 
-	units, err := cAPI.Units()
+	filter := client.Filter{Selector: sharedFlags.Selector}
+	if err := filter.Validate(); err != nil {
+		stderr("%v", err)
+		return 1
+	}
+	units, err := cAPI.UnitsMatching(filter)
 	if err != nil {
 		stderr("Error retrieving list of units from repository: %v", err)
 		return 1
 	}
 
+	if structured {
+		items := make([]unitFileOutput, len(units))
+		for i, u := range units {
+			items[i] = newUnitFileOutput(u)
+		}
+		if err := printStructured(items); err != nil {
+			stderr("Error printing unit files: %v", err)
+			return 1
+		}
+		return
+	}
+
 	if !sharedFlags.NoLegend {
 		fmt.Fprintln(out, strings.ToUpper(strings.Join(cols, "\t")))
 	}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/coreos/fleet/client"
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
 )
@@ -14,12 +15,18 @@ const (
 )
 
 var (
-	listUnitsFieldsFlag string
-	cmdListUnits        = &Command{
+	listUnitsFieldsFlag  string
+	listUnitsMachineFlag string
+	listUnitsStateFlag   string
+	listUnitsWatchFlag   bool
+	cmdListUnits         = &Command{
 		Name:    "list-units",
 		Summary: "List the current state of units in the cluster",
-		Usage:   "[--no-legend] [-l|--full] [--fields]",
+		Usage:   "[--no-legend] [-l|--full] [--fields] [--machine=MACHINE] [--state=STATE] [--output=table|json|yaml] [-w|--watch] [PATTERN]",
 		Description: `Lists the state of all units in the cluster loaded onto a machine.
+With a PATTERN, only units whose name matches the glob pattern are listed.
+The units listed are filtered by the fleet API, if used, rather than by
+fleetctl, which keeps listing large clusters fast.
 
 For easily parsable output, you can remove the column headers:
 	fleetctl list-units --no-legend
@@ -28,7 +35,16 @@ Output the list without ellipses:
 	fleetctl list-units --full
 
 Or, choose the columns to display:
-	fleetctl list-units --fields=unit,machine`,
+	fleetctl list-units --fields=unit,machine
+
+List the failed instances of web@.service:
+	fleetctl list-units --state=failed 'web@*'
+
+Print all fields of each unit state as JSON:
+	fleetctl list-units --output=json
+
+Keep the list up to date as units are scheduled and change state:
+	fleetctl list-units --watch`,
 		Run: runListUnits,
 	}
 
@@ -67,6 +83,18 @@ Or, choose the columns to display:
 			}
 			return machineFullLegend(*ms, full)
 		},
+		"health": func(us *schema.UnitState, full bool) string {
+			if us == nil || us.Health == "" {
+				return "-"
+			}
+			return us.Health
+		},
+		"reason": func(us *schema.UnitState, full bool) string {
+			if us == nil || us.Reason == "" {
+				return "-"
+			}
+			return us.Reason
+		},
 		"hash": func(us *schema.UnitState, full bool) string {
 			if us == nil || us.Hash == "" {
 				return "-"
@@ -77,6 +105,18 @@ Or, choose the columns to display:
 			return us.Hash
 		},
 	}
+
+	// JSON field of a UnitState each column is printed from
+	listUnitsSchemaFields = map[string]string{
+		"unit":    "name",
+		"load":    "systemdLoadState",
+		"active":  "systemdActiveState",
+		"sub":     "systemdSubState",
+		"machine": "machineID",
+		"health":  "health",
+		"reason":  "reason",
+		"hash":    "hash",
+	}
 )
 
 type usToField func(us *schema.UnitState, full bool) string
@@ -85,10 +125,21 @@ func init() {
 	cmdListUnits.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
 	cmdListUnits.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
 	cmdListUnits.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+	addOutputFlag(cmdListUnits)
+	cmdListUnits.Flags.StringVar(&listUnitsMachineFlag, "machine", "", "List only the units of the given machine")
+	cmdListUnits.Flags.StringVar(&listUnitsStateFlag, "state", "", "List only the units in the given systemd active state, e.g. failed")
+	cmdListUnits.Flags.BoolVar(&listUnitsWatchFlag, "watch", false, "Print the list again each time events are recorded in the cluster")
+	cmdListUnits.Flags.BoolVar(&listUnitsWatchFlag, "w", false, "Shorthand for --watch")
 	cmdListUnits.Flags.StringVar(&listUnitsFieldsFlag, "fields", defaultListUnitsFields, fmt.Sprintf("Columns to print for each Unit. Valid fields are %q", strings.Join(usToFieldKeys(listUnitsFields), ",")))
 }
 
 func runListUnits(args []string) (exit int) {
+	structured, err := structuredOutput()
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	if listUnitsFieldsFlag == "" {
 		stderr("Must define output format")
 		return 1
@@ -102,12 +153,65 @@ func runListUnits(args []string) (exit int) {
 		}
 	}
 
-	states, err := cAPI.UnitStates()
+	if len(args) > 1 {
+		stderr("At most one unit name pattern may be provided.")
+		return 1
+	}
+	if structured && listUnitsWatchFlag {
+		stderr("--watch cannot be used with --output.")
+		return 1
+	}
+
+	filter := client.Filter{State: listUnitsStateFlag}
+	if len(args) == 1 {
+		filter.Name = args[0]
+	}
+	if err := filter.Validate(); err != nil {
+		stderr("%v", err)
+		return 1
+	}
+	if listUnitsMachineFlag != "" {
+		filter.MachineID, err = findMachineID(listUnitsMachineFlag)
+		if err != nil {
+			stderr("Unable to find machine %s: %v", listUnitsMachineFlag, err)
+			return 1
+		}
+	}
+	if !structured {
+		for _, c := range cols {
+			filter.Fields = append(filter.Fields, listUnitsSchemaFields[c])
+		}
+	}
+
+	if listUnitsWatchFlag {
+		return watchList(func() int {
+			// machines may have joined since the list was last printed
+			machineStates = nil
+			return printUnitStates(filter, cols, structured)
+		})
+	}
+	return printUnitStates(filter, cols, structured)
+}
+
+func printUnitStates(filter client.Filter, cols []string, structured bool) (exit int) {
+	states, err := cAPI.UnitStatesMatching(filter)
 	if err != nil {
 		stderr("Error retrieving list of units from repository: %v", err)
 		return 1
 	}
 
+	if structured {
+		items := make([]unitStateOutput, len(states))
+		for i, us := range states {
+			items[i] = newUnitStateOutput(us)
+		}
+		if err := printStructured(items); err != nil {
+			stderr("Error printing units: %v", err)
+			return 1
+		}
+		return
+	}
+
 	if !sharedFlags.NoLegend {
 		fmt.Fprintln(out, strings.ToUpper(strings.Join(cols, "\t")))
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/coreos/fleet/job"
@@ -68,3 +69,32 @@ func TestListUnitsFieldsToStrings(t *testing.T) {
 	assertEqual(t, "hash", uh, fuh)
 	assertEqual(t, "hash", uh[:7], suh)
 }
+
+func TestListUnitsSchemaFields(t *testing.T) {
+	us := &schema.UnitState{
+		Name:               "sleep",
+		Hash:               "a0f275d46bc6ee0eca06be7c339913c07d99c0c7",
+		MachineID:          "some-id",
+		SystemdLoadState:   "loaded",
+		SystemdActiveState: "failed",
+		SystemdSubState:    "failed",
+		Health:             "unhealthy",
+		Reason:             "no reason",
+	}
+	enc, _ := json.Marshal(us)
+	var all map[string]json.RawMessage
+	json.Unmarshal(enc, &all)
+
+	// each column prints the same from a UnitState holding its field only
+	for k, field := range usToFieldKeys(listUnitsFields) {
+		sf, ok := listUnitsSchemaFields[field]
+		if !ok {
+			t.Errorf("case %d: no JSON field for column %q", k, field)
+			continue
+		}
+		enc, _ := json.Marshal(map[string]json.RawMessage{sf: all[sf]})
+		var sparse schema.UnitState
+		json.Unmarshal(enc, &sparse)
+		assertEqual(t, field, listUnitsFields[field](us, true), listUnitsFields[field](&sparse, true))
+	}
+}
@@ -10,7 +10,7 @@ var (
 	cmdLoadUnits = &Command{
 		Name:    "load",
 		Summary: "Schedule one or more units in the cluster, first submitting them if necessary.",
-		Usage:   "[--no-block|--block-attempts=N] UNIT...",
+		Usage:   "[--no-block|--block-attempts=N] [-l SELECTOR] [--label KEY=VALUE,...] UNIT...",
 		Description: `Load one or many units in the cluster into systemd, but do not start.
 
 Select units to load by glob matching for units in the current working directory 
@@ -20,7 +20,10 @@ For units which are not global, load operations are performed synchronously,
 which means fleetctl will block until it detects that the unit(s) have
 transitioned to a loaded state. This behaviour can be configured with the
 respective --block-attempts and --no-block options. Load operations on global
-units are always non-blocking.`,
+units are always non-blocking.
+
+Load all previously submitted units labeled app=web:
+	fleetctl load -l app=web`,
 		Run: runLoadUnits,
 	}
 )
@@ -29,9 +32,17 @@ func init() {
 	cmdLoadUnits.Flags.BoolVar(&sharedFlags.Sign, "sign", false, "DEPRECATED - this option cannot be used")
 	cmdLoadUnits.Flags.IntVar(&sharedFlags.BlockAttempts, "block-attempts", 0, "Wait until the jobs are loaded, performing up to N attempts before giving up. A value of 0 indicates no limit. Does not apply to global units.")
 	cmdLoadUnits.Flags.BoolVar(&sharedFlags.NoBlock, "no-block", false, "Do not wait until the jobs have been loaded before exiting. Always the case for global units.")
+	addSelectorFlag(cmdLoadUnits)
+	addLabelFlag(cmdLoadUnits)
 }
 
 func runLoadUnits(args []string) (exit int) {
+	args, err := selectUnits(args)
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	if err := lazyCreateUnits(args); err != nil {
 		stderr("Error creating units: %v", err)
 		return 1
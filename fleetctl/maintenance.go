@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+)
+
+var (
+	cmdMaintenance = &Command{
+		Name:    "maintenance",
+		Summary: "Schedule maintenance windows of machines",
+		Usage:   "[--no-legend] | schedule [--lead=DURATION] MACHINE START END | cancel MACHINE",
+		Description: `Declare a period during which a machine is expected to be unavailable. Ahead of
+the window, the engine moves the units of the machine elsewhere in the cluster
+like drain does, and schedules no new units to it until the window ends. The
+machine then accepts units again without being uncordoned; units moved away
+are not moved back.
+
+START and END are times in RFC 3339, or "now" for START. END may also be a
+duration after START. Units are moved off the machine --lead before START,
+15m by default. A unit that cannot be scheduled to any other machine is left
+where it is. Global units are unaffected.
+
+Take a machine down for two hours tonight:
+	fleetctl maintenance schedule 2444264c 2015-03-01T02:00:00Z 2h
+
+Without a command, the maintenance windows of all machines are listed.
+
+The window is stored in the maintenance-window and maintenance-lead metadata
+of the machine, which may also be configured in fleet.conf or changed with
+set-machine-metadata. MACHINE may be any unique prefix of a machine ID.`,
+		Run: runMaintenance,
+	}
+
+	maintenanceScheduleFlagset = flag.NewFlagSet("schedule", flag.ContinueOnError)
+	maintenanceScheduleLead    time.Duration
+)
+
+func init() {
+	cmdMaintenance.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+
+	maintenanceScheduleFlagset.DurationVar(&maintenanceScheduleLead, "lead", machine.DefaultMaintenanceLead, "How long before the window units are moved off the machine.")
+}
+
+func runMaintenance(args []string) (exit int) {
+	if len(args) == 0 {
+		return listMaintenance()
+	}
+
+	switch args[0] {
+	case "schedule":
+		if err := maintenanceScheduleFlagset.Parse(args[1:]); err != nil {
+			return 1
+		}
+		return scheduleMaintenance(maintenanceScheduleFlagset.Args())
+	case "cancel":
+		return cancelMaintenance(args[1:])
+	}
+	stderr("Unknown maintenance command, must be schedule or cancel.")
+	return 1
+}
+
+func listMaintenance() (exit int) {
+	machines, err := cAPI.Machines()
+	if err != nil {
+		stderr("Error retrieving list of active machines: %v", err)
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "MACHINE\tSTART\tEND\tLEAD\tSTATE")
+	}
+	now := time.Now()
+	for _, ms := range machines {
+		mw := ms.MaintenanceWindow()
+		if mw == nil {
+			continue
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n", machineIDLegend(ms, sharedFlags.Full),
+			mw.Start.Format(time.RFC3339), mw.End.Format(time.RFC3339), mw.Lead, maintenanceState(*mw, now))
+	}
+	out.Flush()
+	return
+}
+
+// maintenanceState describes what a maintenance window does to its machine
+// at the given time
+func maintenanceState(mw machine.MaintenanceWindow, now time.Time) string {
+	switch {
+	case mw.Active(now):
+		return "active"
+	case mw.Drains(now):
+		return "draining"
+	case now.Before(mw.Start):
+		return "scheduled"
+	}
+	return "ended"
+}
+
+func scheduleMaintenance(args []string) (exit int) {
+	if len(args) != 3 {
+		stderr("One machine and the start and end of the window must be provided.")
+		return 1
+	}
+	if maintenanceScheduleLead < 0 {
+		stderr("The lead must not be negative.")
+		return 1
+	}
+
+	mw, err := parseMaintenanceTimes(args[1], args[2], time.Now())
+	if err != nil {
+		stderr("Invalid maintenance window: %v", err)
+		return 1
+	}
+
+	ms, err := findMachine(args[0])
+	if err != nil {
+		stderr("Unable to find machine %s: %v", args[0], err)
+		return 1
+	}
+
+	if err := cAPI.SetMachineMetadata(ms.ID, machine.MetadataMaintenanceLead, maintenanceScheduleLead.String()); err != nil {
+		stderr("Error scheduling maintenance of machine %s: %v", ms.ID, err)
+		return 1
+	}
+	if err := cAPI.SetMachineMetadata(ms.ID, machine.MetadataMaintenanceWindow, mw.String()); err != nil {
+		stderr("Error scheduling maintenance of machine %s: %v", ms.ID, err)
+		return 1
+	}
+
+	stdout("Scheduled maintenance of machine %s from %s until %s", ms.ID, mw.Start.Format(time.RFC3339), mw.End.Format(time.RFC3339))
+	return
+}
+
+// parseMaintenanceTimes parses the start and end of a maintenance window as
+// given to maintenance schedule
+func parseMaintenanceTimes(start, end string, now time.Time) (machine.MaintenanceWindow, error) {
+	var mw machine.MaintenanceWindow
+	if start == "now" {
+		mw.Start = now.UTC().Truncate(time.Second)
+	} else {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return mw, fmt.Errorf("invalid start: %v", err)
+		}
+		mw.Start = t
+	}
+
+	if d, err := time.ParseDuration(end); err == nil {
+		mw.End = mw.Start.Add(d)
+	} else if mw.End, err = time.Parse(time.RFC3339, end); err != nil {
+		return mw, fmt.Errorf("invalid end: %v", err)
+	}
+
+	if !mw.End.After(mw.Start) {
+		return mw, fmt.Errorf("window must end after it starts")
+	}
+	if !mw.End.After(now) {
+		return mw, fmt.Errorf("window ended already")
+	}
+	return mw, nil
+}
+
+func cancelMaintenance(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One machine must be provided.")
+		return 1
+	}
+
+	ms, err := findMachine(args[0])
+	if err != nil {
+		stderr("Unable to find machine %s: %v", args[0], err)
+		return 1
+	}
+
+	for _, key := range []string{machine.MetadataMaintenanceWindow, machine.MetadataMaintenanceLead} {
+		if err := cAPI.DeleteMachineMetadata(ms.ID, key); err != nil {
+			stderr("Error cancelling maintenance of machine %s: %v", ms.ID, err)
+			return 1
+		}
+	}
+
+	stdout("Cancelled maintenance of machine %s", ms.ID)
+	return
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestParseMaintenanceTimes(t *testing.T) {
+	now := time.Date(2015, time.March, 1, 1, 0, 0, 500, time.UTC)
+	start := time.Date(2015, time.March, 1, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		start, end string
+		ok         bool
+		window     string
+	}{
+		{"2015-03-01T02:00:00Z", "2015-03-01T04:00:00Z", true, "2015-03-01T02:00:00Z/2015-03-01T04:00:00Z"},
+		{"2015-03-01T02:00:00Z", "2h", true, "2015-03-01T02:00:00Z/2015-03-01T04:00:00Z"},
+		{"now", "30m", true, "2015-03-01T01:00:00Z/2015-03-01T01:30:00Z"},
+		{"tonight", "2h", false, ""},
+		{"2015-03-01T02:00:00Z", "later", false, ""},
+		{"2015-03-01T02:00:00Z", "-1h", false, ""},
+		// windows that already ended are refused
+		{"2015-02-28T02:00:00Z", "2h", false, ""},
+	}
+
+	for i, tt := range tests {
+		mw, err := parseMaintenanceTimes(tt.start, tt.end, now)
+		if (err == nil) != tt.ok {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if tt.ok && mw.String() != tt.window {
+			t.Errorf("case %d: got window %s, want %s", i, mw, tt.window)
+		}
+	}
+
+	mw, _ := parseMaintenanceTimes("2015-03-01T02:00:00Z", "2h", now)
+	for _, tt := range []struct {
+		now   time.Time
+		state string
+	}{
+		{start.Add(-time.Hour), "scheduled"},
+		{start.Add(-time.Minute), "draining"},
+		{start, "active"},
+		{start.Add(2 * time.Hour), "ended"},
+	} {
+		mw.Lead = machine.DefaultMaintenanceLead
+		if state := maintenanceState(mw, tt.now); state != tt.state {
+			t.Errorf("at %v: got state %s, want %s", tt.now, state, tt.state)
+		}
+	}
+}
+
+func TestRunMaintenance(t *testing.T) {
+	machID := "c31e44e1-f858-436e-933e-59c642517860"
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		newMachineState(machID, "1.2.3.4", nil),
+		newMachineState("c31e5555-cbb7-49ce-8726-722d6e157b4e", "5.6.7.8", nil),
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args []string
+		exit int
+		want map[string]string
+	}{
+		{[]string{"reboot", "c31e44"}, 1, map[string]string{}},
+		// ambiguous machine prefix
+		{[]string{"schedule", "c31e", "2030-03-01T02:00:00Z", "2h"}, 1, map[string]string{}},
+		{[]string{"schedule", "c31e44", "2030-03-01T02:00:00Z"}, 1, map[string]string{}},
+		{[]string{"schedule", "--lead=-1m", "c31e44", "2030-03-01T02:00:00Z", "2h"}, 1, map[string]string{}},
+		{[]string{"schedule", "--lead=1h", "c31e44", "2030-03-01T02:00:00Z", "2h"}, 0, map[string]string{
+			machine.MetadataMaintenanceWindow: "2030-03-01T02:00:00Z/2030-03-01T04:00:00Z",
+			machine.MetadataMaintenanceLead:   "1h0m0s",
+		}},
+		{[]string{"cancel", "c31e44"}, 0, map[string]string{}},
+	} {
+		maintenanceScheduleLead = machine.DefaultMaintenanceLead
+		if exit := runMaintenance(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		got, _ := reg.MachineMetadata(machID)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: unexpected metadata: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/schema"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// addOutputFlag registers the --output flag with the given Command
+func addOutputFlag(cmd *Command) {
+	cmd.Flags.StringVar(&sharedFlags.Output, "output", outputTable, fmt.Sprintf("Output format, one of %q. The json and yaml formats include all fields, regardless of --fields and --full.", strings.Join([]string{outputTable, outputJSON, outputYAML}, ",")))
+}
+
+// structuredOutput reports whether the requested output format is a
+// structured one, returning an error if the format is not known
+func structuredOutput() (bool, error) {
+	switch sharedFlags.Output {
+	case "", outputTable:
+		return false, nil
+	case outputJSON, outputYAML:
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid output format %q", sharedFlags.Output)
+}
+
+// printStructured writes v to out in the requested structured format
+func printStructured(v interface{}) error {
+	var err error
+	if sharedFlags.Output == outputYAML {
+		err = writeYAML(out, v)
+	} else {
+		var encoded []byte
+		encoded, err = json.MarshalIndent(v, "", "  ")
+		if err == nil {
+			_, err = fmt.Fprintf(out, "%s\n", encoded)
+		}
+	}
+	out.Flush()
+	return err
+}
+
+type resourcesOutput struct {
+	CPUUnits int             `json:"cpuUnits"`
+	Memory   int             `json:"memory"`
+	Disk     int             `json:"disk"`
+	Extended resource.Counts `json:"extended,omitempty"`
+}
+
+func newResourcesOutput(rt resource.ResourceTuple, ext resource.Counts) resourcesOutput {
+	return resourcesOutput{CPUUnits: rt.Cores, Memory: rt.Memory, Disk: rt.Disk, Extended: ext}
+}
+
+type machineOutput struct {
+	ID          string            `json:"id"`
+	PublicIP    string            `json:"primaryIP"`
+	Addresses   []addressOutput   `json:"addresses"`
+	Metadata    map[string]string `json:"metadata"`
+	Version     string            `json:"version"`
+	Cordoned    bool              `json:"cordoned"`
+	Draining    bool              `json:"draining"`
+	Taints      []string          `json:"taints"`
+	MaxUnits    int               `json:"maxUnits"`
+	Heartbeat   *time.Time        `json:"lastHeartbeat,omitempty"`
+	Total       resourcesOutput   `json:"totalResources"`
+	Reserved    resourcesOutput   `json:"reservedResources"`
+	Allocatable resourcesOutput   `json:"allocatableResources"`
+	Allocated   resourcesOutput   `json:"allocatedResources"`
+}
+
+type addressOutput struct {
+	IP    string `json:"ip"`
+	Scope string `json:"scope"`
+}
+
+func newMachineOutput(ms *machine.MachineState) machineOutput {
+	metadata := ms.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	taints := []string{}
+	for _, t := range ms.Taints {
+		taints = append(taints, t.String())
+	}
+	addrs := []addressOutput{}
+	for _, a := range ms.AllAddresses() {
+		addrs = append(addrs, addressOutput{IP: a.IP, Scope: a.Scope})
+	}
+	return machineOutput{
+		ID:          ms.ID,
+		PublicIP:    ms.PublicIP,
+		Addresses:   addrs,
+		Metadata:    metadata,
+		Version:     ms.Version,
+		Cordoned:    ms.Cordoned,
+		Draining:    ms.Draining,
+		Taints:      taints,
+		MaxUnits:    ms.UnitLimit(),
+		Heartbeat:   ms.LastHeartbeat,
+		Total:       newResourcesOutput(ms.TotalResources, ms.ExtendedResources),
+		Reserved:    newResourcesOutput(ms.Reserved(), nil),
+		Allocatable: newResourcesOutput(ms.AllocatableResources(), ms.ExtendedResources),
+		Allocated:   newResourcesOutput(ms.AllocatedResources, ms.AllocatedExtendedResources),
+	}
+}
+
+type unitStateOutput struct {
+	Name         string `json:"name"`
+	Hash         string `json:"hash"`
+	MachineID    string `json:"machineID"`
+	LoadState    string `json:"systemdLoadState"`
+	ActiveState  string `json:"systemdActiveState"`
+	SubState     string `json:"systemdSubState"`
+	Reason       string `json:"reason"`
+	Health       string `json:"health"`
+	UsedCPUUnits int64  `json:"usedCPUUnits"`
+	UsedMemory   int64  `json:"usedMemory"`
+}
+
+func newUnitStateOutput(us *schema.UnitState) unitStateOutput {
+	return unitStateOutput{
+		Name:         us.Name,
+		Hash:         us.Hash,
+		MachineID:    us.MachineID,
+		LoadState:    us.SystemdLoadState,
+		ActiveState:  us.SystemdActiveState,
+		SubState:     us.SystemdSubState,
+		Reason:       us.Reason,
+		Health:       us.Health,
+		UsedCPUUnits: us.UsedCPUUnits,
+		UsedMemory:   us.UsedMemory,
+	}
+}
+
+type unitOptionOutput struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+}
+
+type unitFileOutput struct {
+	Name            string             `json:"name"`
+	Hash            string             `json:"hash"`
+	Description     string             `json:"description"`
+	Global          bool               `json:"global"`
+	DesiredState    string             `json:"desiredState"`
+	CurrentState    string             `json:"currentState"`
+	TargetMachineID string             `json:"targetMachineID"`
+	Labels          map[string]string  `json:"labels,omitempty"`
+	Reservations    resourcesOutput    `json:"reservations"`
+	Options         []unitOptionOutput `json:"options"`
+}
+
+func newUnitFileOutput(u *schema.Unit) unitFileOutput {
+	uf := schema.MapSchemaUnitOptionsToUnitFile(u.Options)
+	ju := job.Unit{Name: u.Name, Unit: *uf}
+
+	opts := make([]unitOptionOutput, len(u.Options))
+	for i, opt := range u.Options {
+		opts[i] = unitOptionOutput{Section: opt.Section, Name: opt.Name, Value: opt.Value}
+	}
+
+	return unitFileOutput{
+		Name:            u.Name,
+		Hash:            uf.Hash().String(),
+		Description:     uf.Description(),
+		Global:          ju.IsGlobal(),
+		DesiredState:    u.DesiredState,
+		CurrentState:    u.CurrentState,
+		TargetMachineID: u.MachineID,
+		Labels:          u.Labels,
+		Reservations:    newResourcesOutput(ju.Resources(), ju.ResourceRequests()),
+		Options:         opts,
+	}
+}
+
+// writeYAML writes v, in the form it would be encoded to JSON, to w as a
+// YAML document
+func writeYAML(w io.Writer, v interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeYAMLNode(&buf, generic, 0, false)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// writeYAMLNode writes a decoded JSON value at the given indentation. If
+// inline is set, the first line of the value continues the current line,
+// e.g. after a list item's "- ".
+func writeYAMLNode(buf *bytes.Buffer, v interface{}, indent int, inline bool) {
+	pad := strings.Repeat(" ", indent)
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			buf.WriteString("{}\n")
+			return
+		}
+
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for i, k := range keys {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString(yamlScalar(k))
+			buf.WriteString(":")
+			writeYAMLValue(buf, val[k], indent+2)
+		}
+	case []interface{}:
+		if len(val) == 0 {
+			buf.WriteString("[]\n")
+			return
+		}
+
+		for i, item := range val {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			// maps start on the same line as their list indicator
+			if m, ok := item.(map[string]interface{}); ok && len(m) > 0 {
+				buf.WriteString("- ")
+				writeYAMLNode(buf, m, indent+2, true)
+				continue
+			}
+			buf.WriteString("-")
+			writeYAMLValue(buf, item, indent+2)
+		}
+	default:
+		buf.WriteString(yamlScalar(val))
+		buf.WriteString("\n")
+	}
+}
+
+// writeYAMLValue writes a value following a map key or list indicator
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAMLNode(buf, val, indent, false)
+	case []interface{}:
+		if len(val) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAMLNode(buf, val, indent, false)
+	default:
+		buf.WriteString(" ")
+		writeYAMLNode(buf, val, indent, true)
+	}
+}
+
+// yamlScalar formats a decoded JSON scalar, quoting strings YAML would
+// otherwise read as something else
+func yamlScalar(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case json.Number:
+		return val.String()
+	case string:
+		if yamlNeedsQuotes(val) {
+			return strconv.Quote(val)
+		}
+		return val
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return true
+		}
+	}
+
+	return strings.Contains(s, ": ") || strings.Contains(s, " #")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+)
+
+func TestWriteYAML(t *testing.T) {
+	v := []interface{}{
+		map[string]interface{}{
+			"name":     "foo.service",
+			"global":   false,
+			"metadata": map[string]string{"region": "us-west", "ssd": "true"},
+			"options":  []string{},
+			"ports":    []int{80, 443},
+			"reason":   "",
+		},
+		"plain",
+	}
+
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, v); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := `- global: false
+  metadata:
+    region: us-west
+    ssd: "true"
+  name: foo.service
+  options: []
+  ports:
+    - 80
+    - 443
+  reason: ""
+- plain
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected YAML:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestYAMLNeedsQuotes(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want bool
+	}{
+		{"foo.service", false},
+		{"1.2.3.4", false},
+		{"a b", false},
+		{"", true},
+		{" padded", true},
+		{"yes", true},
+		{"Null", true},
+		{"12", true},
+		{"1e3", true},
+		{"-foo", true},
+		{"*", true},
+		{"key: value", true},
+		{"foo #bar", true},
+		{"line\nbreak", true},
+	} {
+		if got := yamlNeedsQuotes(tt.s); got != tt.want {
+			t.Errorf("yamlNeedsQuotes(%q) = %t, want %t", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStructuredOutput(t *testing.T) {
+	defer func() { sharedFlags.Output = outputTable }()
+
+	for _, tt := range []struct {
+		output     string
+		structured bool
+		fail       bool
+	}{
+		{outputTable, false, false},
+		{"", false, false},
+		{outputJSON, true, false},
+		{outputYAML, true, false},
+		{"xml", false, true},
+	} {
+		sharedFlags.Output = tt.output
+		structured, err := structuredOutput()
+		if (err != nil) != tt.fail || structured != tt.structured {
+			t.Errorf("output %q: got structured=%t err=%v", tt.output, structured, err)
+		}
+	}
+}
+
+func TestListMachinesJSON(t *testing.T) {
+	cAPI = &client.RegistryClient{Registry: newFakeRegistryForTop(t)}
+	sharedFlags.Output = outputJSON
+	defer func() { sharedFlags.Output = outputTable }()
+
+	lines := runWithOutput(t, runListMachines)
+
+	var machines []machineOutput
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &machines); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(machines) != 2 {
+		t.Fatalf("Expected 2 machines, got %d", len(machines))
+	}
+
+	m := machines[0]
+	if m.ID != "c31e44e1-f858-436e-933e-59c642517860" || m.PublicIP != "1.2.3.4" {
+		t.Errorf("Unexpected machine: %#v", m)
+	}
+	if m.Total.CPUUnits != 400 || m.Allocated.CPUUnits != 100 || m.Allocated.Memory != 512 {
+		t.Errorf("Unexpected resources: total %#v, allocated %#v", m.Total, m.Allocated)
+	}
+}
+
+func TestListUnitsYAML(t *testing.T) {
+	cAPI = &client.RegistryClient{Registry: newFakeRegistryForTop(t)}
+	sharedFlags.Output = outputYAML
+	defer func() { sharedFlags.Output = outputTable }()
+
+	lines := runWithOutput(t, runListUnits)
+
+	var items int
+	for _, l := range lines {
+		if strings.HasPrefix(l, "- ") {
+			items++
+		}
+	}
+	if items != 2 {
+		t.Fatalf("Expected a list item per unit state, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	found := false
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "usedCPUUnits: 150" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Output misses usage of bar.service:\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	cmdQuota = &Command{
+		Name:    "quota",
+		Summary: "Show the quotas of namespaces along with their usage",
+		Usage:   "[--no-legend] [NAMESPACE...]",
+		Description: `Lists the quotas set with set-quota, showing what the units of each namespace
+use of them as USED/LIMIT. Namespaces without a limit on a resource show "-" as
+their limit.
+
+Show the quota of the namespace of a team:
+	fleetctl quota team-a`,
+		Run: runQuota,
+	}
+
+	cmdSetQuota = &Command{
+		Name:    "set-quota",
+		Summary: "Limit what the units of a namespace may reserve",
+		Usage:   "NAMESPACE LIMIT=VALUE...",
+		Description: `Set the limits of the quota of a namespace. The limits are cpu, in CPU units
+like CPUUnits= of units, memory, in MB like MemoryReservation=, and units,
+the number of units. The API refuses to submit units beyond the quota of
+their namespace, and the engine refuses to schedule them.
+
+Let a team run up to 20 units reserving up to 4 cores and 8GB of memory:
+	fleetctl set-quota team-a cpu=400 memory=8192 units=20
+
+Limits not given are left as they are. Remove a limit by setting it to 0; a
+quota without limits is removed.`,
+		Run: runSetQuota,
+	}
+)
+
+func init() {
+	cmdQuota.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+func runQuota(args []string) (exit int) {
+	quotas, err := cAPI.Quotas()
+	if err != nil {
+		stderr("Error retrieving quotas: %v", err)
+		return 1
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "NAMESPACE\tCPU\tMEMORY\tUNITS")
+	}
+	shown := pkg.NewUnsafeSet(args...)
+	for _, q := range quotas {
+		if len(args) > 0 && !shown.Contains(q.Namespace) {
+			continue
+		}
+		fmt.Fprintf(out, "%s\t%d/%s\t%dMB/%s\t%d/%s\n", q.Namespace,
+			q.UsedCores, quotaLimit(q.Cores, ""),
+			q.UsedMemory, quotaLimit(q.Memory, "MB"),
+			q.UsedUnits, quotaLimit(q.Units, ""))
+	}
+	out.Flush()
+	return
+}
+
+func quotaLimit(limit int64, unit string) string {
+	if limit == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%s", limit, unit)
+}
+
+func runSetQuota(args []string) (exit int) {
+	if len(args) < 2 {
+		stderr("One namespace and at least one LIMIT=VALUE pair must be provided.")
+		return 1
+	}
+
+	ns := args[0]
+	if err := job.ValidateNamespace(ns); err != nil {
+		stderr("Invalid namespace %q: %v", ns, err)
+		return 1
+	}
+
+	quotas, err := cAPI.Quotas()
+	if err != nil {
+		stderr("Error retrieving quotas: %v", err)
+		return 1
+	}
+	q := &schema.Quota{Namespace: ns}
+	for _, eq := range quotas {
+		if eq.Namespace == ns {
+			q = &schema.Quota{Namespace: ns, Cores: eq.Cores, Memory: eq.Memory, Units: eq.Units}
+		}
+	}
+
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			stderr("Invalid limit %q, expected LIMIT=VALUE", arg)
+			return 1
+		}
+		val, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || val < 0 {
+			stderr("Invalid value of limit %s: %q", parts[0], parts[1])
+			return 1
+		}
+		switch parts[0] {
+		case "cpu":
+			q.Cores = val
+		case "memory":
+			q.Memory = val
+		case "units":
+			q.Units = val
+		default:
+			stderr("Unknown limit %q, expected cpu, memory or units", parts[0])
+			return 1
+		}
+	}
+
+	if q.Cores == 0 && q.Memory == 0 && q.Units == 0 {
+		if err := cAPI.DeleteQuota(ns); err != nil {
+			stderr("Error removing quota of namespace %s: %v", ns, err)
+			return 1
+		}
+		stdout("Removed quota of namespace %s", ns)
+		return
+	}
+
+	if err := cAPI.SetQuota(q); err != nil {
+		stderr("Error setting quota of namespace %s: %v", ns, err)
+		return 1
+	}
+	stdout("Updated quota of namespace %s", ns)
+	return
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestRunSetQuota(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetQuota(registry.Quota{Namespace: "team-a", Units: 10})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args []string
+		exit int
+		want []registry.Quota
+	}{
+		// missing, invalid and unknown limits
+		{[]string{"team-a"}, 1, []registry.Quota{registry.Quota{Namespace: "team-a", Units: 10}}},
+		{[]string{"team-a", "cpu"}, 1, []registry.Quota{registry.Quota{Namespace: "team-a", Units: 10}}},
+		{[]string{"team-a", "cpu=-1"}, 1, []registry.Quota{registry.Quota{Namespace: "team-a", Units: 10}}},
+		{[]string{"team-a", "disk=100"}, 1, []registry.Quota{registry.Quota{Namespace: "team-a", Units: 10}}},
+		{[]string{"Team_A", "cpu=100"}, 1, []registry.Quota{registry.Quota{Namespace: "team-a", Units: 10}}},
+		// limits not given are kept
+		{[]string{"team-a", "cpu=400", "memory=8192"}, 0, []registry.Quota{registry.Quota{Namespace: "team-a", Cores: 400, Memory: 8192, Units: 10}}},
+		{[]string{"team-b", "units=1"}, 0, []registry.Quota{registry.Quota{Namespace: "team-a", Cores: 400, Memory: 8192, Units: 10}, registry.Quota{Namespace: "team-b", Units: 1}}},
+		// a quota without limits is removed
+		{[]string{"team-b", "units=0"}, 0, []registry.Quota{registry.Quota{Namespace: "team-a", Cores: 400, Memory: 8192, Units: 10}}},
+	} {
+		if exit := runSetQuota(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		got, _ := reg.Quotas()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: unexpected quotas: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestRunQuota(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	uf, err := unit.NewUnitFile("[X-Fleet]\nCPUUnits=50\nMemoryReservation=256")
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit file: %v", err)
+	}
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "team-a:web@1.service", Unit: *uf},
+		job.Job{Name: "team-a:web@2.service", Unit: *uf},
+	})
+	reg.SetQuota(registry.Quota{Namespace: "team-a", Cores: 400, Units: 10})
+	reg.SetQuota(registry.Quota{Namespace: "team-b", Memory: 1024})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	want := []string{
+		"NAMESPACE CPU MEMORY UNITS",
+		"team-a 100/400 512MB/- 2/10",
+		"team-b 0/- 0MB/1024MB 0/-",
+	}
+	if got := quotaOutput(t, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected output: got %q, want %q", got, want)
+	}
+
+	want = want[:2]
+	if got := quotaOutput(t, []string{"team-a"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected output: got %q, want %q", got, want)
+	}
+}
+
+// quotaOutput returns the lines printed by the quota command with the given
+// arguments, their columns separated by single spaces
+func quotaOutput(t *testing.T, args []string) []string {
+	lines := runWithOutput(t, func([]string) int { return runQuota(args) })
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return lines
+}
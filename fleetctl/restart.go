@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+var (
+	cmdRestartUnit = &Command{
+		Name:    "restart",
+		Summary: "Restart one or more units in the cluster",
+		Usage:   "[--in-place|--reschedule] [--rolling] [--timeout=DURATION] [--via-api] UNIT...",
+		Description: `Restart one or many launched units. Passing a template unit restarts all of
+its instances.
+
+--in-place, the default, restarts each unit with systemctl on the machine it
+is running on, over SSH or, with --via-api, through the fleet API. Units stay
+on their machines. Global units are restarted on every machine running them.
+
+--reschedule unloads each unit, waits until it is no longer scheduled, then
+launches it again so the engine schedules it anew, taking the resources
+currently available in the cluster into account. The unit may move to another
+machine. Global units cannot be rescheduled.
+
+With --rolling, units are restarted one at a time, and each must become active
+before the next one is restarted. Units with a health check must also become
+healthy. If a unit does not become ready within the timeout, the restart
+stops.
+
+Restart a unit where it runs:
+	fleetctl restart foo.service
+
+Restart all instances of web@.service one at a time, placing each anew:
+	fleetctl restart --reschedule --rolling web@.service`,
+		Run: runRestartUnit,
+	}
+
+	restartFlags = struct {
+		InPlace    bool
+		Reschedule bool
+		Rolling    bool
+		Timeout    time.Duration
+	}{}
+
+	// interval at which the state of restarted units is checked
+	restartPollInterval = time.Second
+)
+
+func init() {
+	cmdRestartUnit.Flags.BoolVar(&restartFlags.InPlace, "in-place", false, "Restart units with systemctl on the machine they run on. This is the default.")
+	cmdRestartUnit.Flags.BoolVar(&restartFlags.Reschedule, "reschedule", false, "Unload units and launch them again, letting the engine schedule them anew.")
+	cmdRestartUnit.Flags.BoolVar(&restartFlags.Rolling, "rolling", false, "Restart units one at a time, waiting for each to become ready.")
+	cmdRestartUnit.Flags.DurationVar(&restartFlags.Timeout, "timeout", 5*time.Minute, "Time to wait for each unit to be unscheduled or, with --rolling, to become ready.")
+	cmdRestartUnit.Flags.BoolVar(&flagViaAPI, "via-api", false, "Run systemctl through the fleet API rather than over SSH. Requires --experimental-api.")
+}
+
+func runRestartUnit(args []string) (exit int) {
+	if len(args) == 0 {
+		stderr("One or more units must be provided.")
+		return 1
+	}
+	if restartFlags.InPlace && restartFlags.Reschedule {
+		stderr("--in-place and --reschedule cannot be used together.")
+		return 1
+	}
+
+	units, err := restartTargets(args)
+	if err != nil {
+		stderr("Unable to proceed: %v", err)
+		return 1
+	}
+
+	for _, u := range units {
+		if restartFlags.Reschedule {
+			err = rescheduleUnit(u)
+		} else {
+			err = restartUnitInPlace(u)
+		}
+		if err != nil {
+			stderr("Error restarting unit %s: %v", u.Name, err)
+			return 1
+		}
+
+		if !restartFlags.Rolling {
+			stdout("Triggered unit %s restart", u.Name)
+			continue
+		}
+		if err := waitForRestart(u); err != nil {
+			stderr("Error restarting unit %s: %v", u.Name, err)
+			return 1
+		}
+		stdout("Restarted unit %s", u.Name)
+	}
+	return
+}
+
+// restartTargets resolves the given unit names, expanding templates to
+// their instances, to the launched units to restart
+func restartTargets(args []string) ([]*schema.Unit, error) {
+	all, err := cAPI.Units()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving list of units from repository: %v", err)
+	}
+	byName := make(map[string]*schema.Unit, len(all))
+	for _, u := range all {
+		byName[u.Name] = u
+	}
+
+	var units []*schema.Unit
+	seen := make(map[string]bool)
+	add := func(u *schema.Unit) error {
+		if job.JobState(u.DesiredState) != job.JobStateLaunched {
+			return fmt.Errorf("unit %s is not launched", u.Name)
+		}
+		if restartFlags.Reschedule && suToGlobal(*u) {
+			return fmt.Errorf("global unit %s cannot be rescheduled", u.Name)
+		}
+		if !seen[u.Name] {
+			seen[u.Name] = true
+			units = append(units, u)
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		name := unitNameMangle(arg)
+		if uni := unit.NewUnitNameInfo(name); uni != nil && uni.IsTemplate() {
+			var instances []rollingInstance
+			for _, u := range all {
+				if iuni := unit.NewUnitNameInfo(u.Name); iuni != nil && iuni.Template == name && iuni.IsInstance() {
+					instances = append(instances, rollingInstance{name: u.Name})
+				}
+			}
+			if len(instances) == 0 {
+				return nil, fmt.Errorf("template unit %s has no instances", name)
+			}
+			sort.Sort(rollingInstancesByName(instances))
+			for _, ri := range instances {
+				if err := add(byName[ri.name]); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		u, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unit %s does not exist", name)
+		}
+		if err := add(u); err != nil {
+			return nil, err
+		}
+	}
+	return units, nil
+}
+
+// restartUnitInPlace runs systemctl restart on each machine the unit runs on
+func restartUnitInPlace(u *schema.Unit) error {
+	var machines []string
+	if suToGlobal(*u) {
+		states, err := cAPI.UnitStates()
+		if err != nil {
+			return err
+		}
+		for _, us := range states {
+			if us.Name == u.Name {
+				machines = append(machines, us.MachineID)
+			}
+		}
+	} else if u.MachineID != "" {
+		machines = append(machines, u.MachineID)
+	}
+	if len(machines) == 0 {
+		return fmt.Errorf("unit is not running on any machine")
+	}
+
+	for _, machID := range machines {
+		var exit int
+		if flagViaAPI {
+			var err error
+			exit, err = cAPI.RunCommand(machID, []string{"systemctl", "restart", systemdUnitName(u.Name)}, os.Stdout)
+			if err != nil {
+				return fmt.Errorf("failed running systemctl through the API: %v", err)
+			}
+		} else {
+			exit = runCommand(fmt.Sprintf("systemctl restart %s", systemdUnitName(u.Name)), machID)
+		}
+		if exit != 0 {
+			return fmt.Errorf("systemctl exited with status %d on machine %s", exit, machID)
+		}
+	}
+	return nil
+}
+
+// rescheduleUnit unloads the unit, waits until the engine unschedules it
+// and launches it again. The unit is launched again even if it was not
+// unscheduled in time, so that it is not left stopped.
+func rescheduleUnit(u *schema.Unit) error {
+	if err := cAPI.SetUnitTargetState(u.Name, string(job.JobStateInactive)); err != nil {
+		return err
+	}
+
+	werr := waitForUnschedule(u.Name)
+	if err := cAPI.SetUnitTargetState(u.Name, u.DesiredState); err != nil {
+		return err
+	}
+	return werr
+}
+
+// waitForUnschedule waits until the named unit is no longer scheduled to
+// any machine
+func waitForUnschedule(name string) error {
+	deadline := time.Now().Add(restartFlags.Timeout)
+	for {
+		u, err := cAPI.Unit(name)
+		if err != nil {
+			return err
+		}
+		if u == nil || u.MachineID == "" {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("timed out waiting for unit to be unscheduled from machine %s", u.MachineID)
+		}
+		time.Sleep(restartPollInterval)
+	}
+}
+
+// waitForRestart waits until the unit reports being active on every machine
+// it runs on and, if it has a health check, being healthy
+func waitForRestart(u *schema.Unit) error {
+	ju := job.Unit{Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options)}
+	checked := ju.HealthCheck() != nil
+
+	deadline := time.Now().Add(restartFlags.Timeout)
+	for {
+		states, err := cAPI.UnitStates()
+		if err != nil {
+			return err
+		}
+
+		var own []*schema.UnitState
+		for _, us := range states {
+			if us.Name == u.Name {
+				own = append(own, us)
+			}
+		}
+		if len(own) > 0 && unitReached(own, waitForHealthy, checked) {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("timed out waiting for unit to become ready")
+		}
+		time.Sleep(restartPollInterval)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func newFakeRegistryForRestart() *registry.FakeRegistry {
+	uf, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/hello")
+	reg := registry.NewFakeRegistry()
+	var jobs []job.Job
+	for _, name := range []string{"web@.service", "web@10.service", "web@2.service", "hello.service", "stopped.service"} {
+		j := job.NewJob(name, *uf)
+		if name != "web@.service" && name != "stopped.service" {
+			j.TargetState = job.JobStateLaunched
+		}
+		jobs = append(jobs, *j)
+	}
+	reg.SetJobs(jobs)
+	return reg
+}
+
+func TestRestartTargets(t *testing.T) {
+	cAPI = &client.RegistryClient{Registry: newFakeRegistryForRestart()}
+
+	for i, tt := range []struct {
+		args  []string
+		names []string
+		err   bool
+	}{
+		{[]string{"hello"}, []string{"hello.service"}, false},
+		{[]string{"web@.service", "hello.service", "web@2.service"}, []string{"web@2.service", "web@10.service", "hello.service"}, false},
+		{[]string{"stopped.service"}, nil, true},
+		{[]string{"missing.service"}, nil, true},
+		{[]string{"other@.service"}, nil, true},
+	} {
+		units, err := restartTargets(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		var names []string
+		for _, u := range units {
+			names = append(names, u.Name)
+		}
+		if len(names) != len(tt.names) {
+			t.Errorf("case %d: expected units %v, got %v", i, tt.names, names)
+			continue
+		}
+		for j := range names {
+			if names[j] != tt.names[j] {
+				t.Errorf("case %d: expected units %v, got %v", i, tt.names, names)
+				break
+			}
+		}
+	}
+}
+
+func TestRunRestartUnitReschedule(t *testing.T) {
+	restartPollInterval = time.Millisecond
+	defer func() {
+		restartPollInterval = time.Second
+		restartFlags.Reschedule = false
+		restartFlags.Rolling = false
+		restartFlags.Timeout = 5 * time.Minute
+	}()
+
+	reg := newFakeRegistryForRestart()
+	reg.SetUnitStates([]unit.UnitState{
+		unit.UnitState{UnitName: "web@2.service", ActiveState: "active", MachineID: "XXX"},
+		unit.UnitState{UnitName: "web@10.service", ActiveState: "activating", MachineID: "XXX"},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	restartFlags.Reschedule = true
+	restartFlags.Timeout = 10 * time.Millisecond
+	if exit := runRestartUnit([]string{"hello.service"}); exit != 0 {
+		t.Errorf("Expected exit 0, got %d", exit)
+	}
+
+	// web@10.service never becomes active
+	restartFlags.Rolling = true
+	if exit := runRestartUnit([]string{"web@.service"}); exit != 1 {
+		t.Errorf("Expected exit 1, got %d", exit)
+	}
+
+	// units are launched again, even when they did not become ready
+	for _, name := range []string{"hello.service", "web@2.service", "web@10.service"} {
+		u, _ := reg.Unit(name)
+		if u.TargetState != job.JobStateLaunched {
+			t.Errorf("Expected unit %s to be launched, got %s", name, u.TargetState)
+		}
+	}
+
+	// a unit that stays scheduled times out
+	reg.ScheduleUnit("hello.service", "XXX")
+	restartFlags.Rolling = false
+	if exit := runRestartUnit([]string{"hello.service"}); exit != 1 {
+		t.Errorf("Expected exit 1, got %d", exit)
+	}
+	if u, _ := reg.Unit("hello.service"); u.TargetState != job.JobStateLaunched {
+		t.Errorf("Expected unit hello.service to be launched, got %s", u.TargetState)
+	}
+}
+
+func TestRunRestartUnitFlags(t *testing.T) {
+	defer func() {
+		restartFlags.InPlace = false
+		restartFlags.Reschedule = false
+	}()
+	cAPI = &client.RegistryClient{Registry: newFakeRegistryForRestart()}
+
+	if exit := runRestartUnit(nil); exit != 1 {
+		t.Errorf("Expected exit 1 without units, got %d", exit)
+	}
+
+	restartFlags.InPlace = true
+	restartFlags.Reschedule = true
+	if exit := runRestartUnit([]string{"hello.service"}); exit != 1 {
+		t.Errorf("Expected exit 1 with both strategies, got %d", exit)
+	}
+
+	// hello.service is not scheduled to any machine
+	restartFlags.Reschedule = false
+	if exit := runRestartUnit([]string{"hello.service"}); exit != 1 {
+		t.Errorf("Expected exit 1 for unscheduled unit, got %d", exit)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	flagRollbackTo int
+	cmdRollback    = &Command{
+		Name:    "rollback",
+		Summary: "Submit a unit again with an earlier version of its unit file",
+		Usage:   "[--to=N] UNIT",
+		Description: `Replaces a unit with an earlier version of its unit file, as listed by
+"fleetctl history". The unit is destroyed and submitted again with the unit
+file of that version, keeping its desired state, so that it is rescheduled and
+restarted if it was running. A destroyed unit is submitted again as inactive.
+
+The rollback is recorded as a new version of the unit. Without --to, the unit
+is rolled back to the latest version other than its current one.
+
+Roll a unit back to the version before its current one:
+	fleetctl rollback foo.service
+
+Roll a unit back to its second version:
+	fleetctl rollback --to=2 foo.service`,
+		Run: runRollback,
+	}
+)
+
+func init() {
+	cmdRollback.Flags.IntVar(&flagRollbackTo, "to", 0, "Version to roll the unit back to. Defaults to the latest version other than the current one.")
+}
+
+func runRollback(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One unit must be provided.")
+		return 1
+	}
+	name := unitNameMangle(args[0])
+
+	u, err := cAPI.Unit(name)
+	if err != nil {
+		stderr("Error retrieving Unit(%s): %v", name, err)
+		return 1
+	}
+	var current string
+	state := job.JobStateInactive
+	if u != nil {
+		current = schema.MapSchemaUnitOptionsToUnitFile(u.Options).Hash().String()
+		state = job.JobState(u.DesiredState)
+	}
+
+	uv, err := rollbackVersion(name, current, flagRollbackTo)
+	if err != nil {
+		stderr("Unable to roll back unit %s: %v", name, err)
+		return 1
+	}
+	if uv.Hash == current {
+		stdout("Unit %s already runs version %d.", name, uv.Version)
+		return
+	}
+	if len(uv.Options) == 0 {
+		stderr("Unit file of version %d of unit %s is no longer stored.", uv.Version, name)
+		return 1
+	}
+
+	if err := replaceUnit(name, schema.MapSchemaUnitOptionsToUnitFile(uv.Options), state); err != nil {
+		stderr("Error rolling back unit %s: %v", name, err)
+		return 1
+	}
+	stdout("Rolled back unit %s to version %d.", name, uv.Version)
+	return
+}
+
+// rollbackVersion returns the given version of the named unit, or if it is
+// 0, the latest version whose unit file differs from the one with the given
+// current hash
+func rollbackVersion(name, current string, version int) (*schema.UnitVersion, error) {
+	if version != 0 {
+		return findUnitVersion(name, version)
+	}
+
+	versions, err := cAPI.UnitVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Hash != current {
+			return versions[i], nil
+		}
+	}
+	return nil, errors.New("no earlier version recorded")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+func TestRunRollback(t *testing.T) {
+	cAPI = &client.RegistryClient{Registry: registry.NewFakeRegistry()}
+	v1 := newUnitFile(t, "[Service]\nExecStart=/bin/v1\n")
+	v2 := newUnitFile(t, "[Service]\nExecStart=/bin/v2\n")
+
+	if _, err := createUnitWithState("foo.service", v1, job.JobStateLaunched); err != nil {
+		t.Fatalf("Failed creating unit: %v", err)
+	}
+	if err := replaceUnit("foo.service", v2, job.JobStateLaunched); err != nil {
+		t.Fatalf("Failed replacing unit: %v", err)
+	}
+
+	assertCurrent := func(want string) {
+		u, err := cAPI.Unit("foo.service")
+		if err != nil || u == nil {
+			t.Fatalf("Failed retrieving unit: %v", err)
+		}
+		if got := schema.MapSchemaUnitOptionsToUnitFile(u.Options).Hash().String(); got != want {
+			t.Errorf("Expected unit file %s, got %s", want, got)
+		}
+		if u.DesiredState != string(job.JobStateLaunched) {
+			t.Errorf("Expected desired state to be kept, got %s", u.DesiredState)
+		}
+	}
+
+	// without a version, the unit is rolled back to the previous one
+	flagRollbackTo = 0
+	if exit := runRollback([]string{"foo.service"}); exit != 0 {
+		t.Fatalf("Unexpected exit %d", exit)
+	}
+	assertCurrent(v1.Hash().String())
+
+	flagRollbackTo = 2
+	defer func() { flagRollbackTo = 0 }()
+	if exit := runRollback([]string{"foo.service"}); exit != 0 {
+		t.Fatalf("Unexpected exit %d", exit)
+	}
+	assertCurrent(v2.Hash().String())
+
+	versions, err := cAPI.UnitVersions("foo.service")
+	if err != nil {
+		t.Fatalf("Failed retrieving versions: %v", err)
+	}
+	var hashes []string
+	for _, uv := range versions {
+		hashes = append(hashes, uv.Hash)
+	}
+	want := []string{v1.Hash().String(), v2.Hash().String(), v1.Hash().String(), v2.Hash().String()}
+	if len(hashes) != len(want) {
+		t.Fatalf("Expected %d versions, got %v", len(want), hashes)
+	}
+	for i := range want {
+		if hashes[i] != want[i] {
+			t.Errorf("Version %d is %s, want %s", i+1, hashes[i], want[i])
+		}
+	}
+
+	flagRollbackTo = 9
+	if exit := runRollback([]string{"foo.service"}); exit == 0 {
+		t.Error("Expected rolling back to an unknown version to fail")
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	// active state systemd reports for running units
+	unitActiveStateActive = "active"
+	// health published for units passing their health check
+	unitHealthHealthy = "healthy"
+)
+
+var (
+	cmdRollingUpdate = &Command{
+		Name:    "rolling-update",
+		Summary: "Replace the instances of a template unit with a new version",
+		Usage:   "[--max-unavailable=N] [--max-surge=N] [--timeout=DURATION] [--rollback] TEMPLATE",
+		Description: `Replace a template unit in the cluster with the given unit file, then replace
+each of its instances with one running the new unit file. Instances are
+destroyed and recreated under the same name, a few at a time, and each batch
+must become active before the next one is replaced. Instances with a health
+check must also become healthy.
+
+--max-unavailable is the number of instances replaced at a time. With
+--max-surge, that many additional instances of the new version are started
+before any existing instance is replaced, allowing larger batches without
+losing capacity. They are numbered after the existing instances and destroyed
+once the update completes. Templates scaled with "fleetctl scale" should be
+updated with --max-surge=0, as the engine destroys instances beyond the scale.
+
+If a batch does not become ready within the timeout, the update stops. With
+--rollback, the template and all instances already replaced are then restored
+to their previous unit files.
+
+Update all instances of web@.service, two at a time:
+	fleetctl rolling-update --max-unavailable=2 web@.service`,
+		Run: runRollingUpdate,
+	}
+
+	rollingUpdateFlags = struct {
+		MaxUnavailable int
+		MaxSurge       int
+		Timeout        time.Duration
+		Rollback       bool
+	}{}
+
+	// interval at which the state of replaced instances is checked
+	rollingUpdatePollInterval = time.Second
+)
+
+func init() {
+	cmdRollingUpdate.Flags.IntVar(&rollingUpdateFlags.MaxUnavailable, "max-unavailable", 1, "Number of instances replaced at a time.")
+	cmdRollingUpdate.Flags.IntVar(&rollingUpdateFlags.MaxSurge, "max-surge", 0, "Number of additional instances started for the duration of the update.")
+	cmdRollingUpdate.Flags.DurationVar(&rollingUpdateFlags.Timeout, "timeout", 5*time.Minute, "Time to wait for each batch of instances to become ready.")
+	cmdRollingUpdate.Flags.BoolVar(&rollingUpdateFlags.Rollback, "rollback", false, "Restore the previous unit files if the update stalls.")
+}
+
+// rollingInstance is an instance of the updated template along with the
+// unit file and desired state it had before the update
+type rollingInstance struct {
+	name  string
+	old   *unit.UnitFile
+	state job.JobState
+}
+
+func runRollingUpdate(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One template unit file must be provided.")
+		return 1
+	}
+
+	maxUnavailable, maxSurge := rollingUpdateFlags.MaxUnavailable, rollingUpdateFlags.MaxSurge
+	if maxUnavailable < 0 || maxSurge < 0 || maxUnavailable+maxSurge == 0 {
+		stderr("--max-unavailable and --max-surge must not be negative, and at least one must be positive.")
+		return 1
+	}
+
+	name := unitNameMangle(args[0])
+	tmplInfo := unit.NewUnitNameInfo(name)
+	if tmplInfo == nil || !tmplInfo.IsTemplate() {
+		stderr("Unit %s is not a template unit.", name)
+		return 1
+	}
+
+	uf, err := getUnitFromFile(args[0])
+	if err != nil {
+		stderr("Error reading unit file %s: %v", args[0], err)
+		return 1
+	}
+
+	units, err := cAPI.Units()
+	if err != nil {
+		stderr("Error retrieving list of units from repository: %v", err)
+		return 1
+	}
+
+	var oldTmpl *unit.UnitFile
+	var outdated []rollingInstance
+	highest := 0
+	for _, u := range units {
+		uni := unit.NewUnitNameInfo(u.Name)
+		if uni == nil || uni.Template != name {
+			continue
+		}
+
+		suf := schema.MapSchemaUnitOptionsToUnitFile(u.Options)
+		if !uni.IsInstance() {
+			oldTmpl = suf
+			continue
+		}
+
+		if n, ok := instanceNumber(uni.Instance); ok && n > highest {
+			highest = n
+		}
+		if suf.Hash() != uf.Hash() {
+			outdated = append(outdated, rollingInstance{name: u.Name, old: suf, state: job.JobState(u.DesiredState)})
+		}
+	}
+	sort.Sort(rollingInstancesByName(outdated))
+
+	if oldTmpl == nil || oldTmpl.Hash() != uf.Hash() {
+		if err := replaceUnit(name, uf, job.JobStateInactive); err != nil {
+			stderr("Error replacing template unit %s: %v", name, err)
+			return 1
+		}
+		stdout("Replaced template unit %s", name)
+	}
+
+	if len(outdated) == 0 {
+		stdout("All instances of %s are up to date", name)
+		return
+	}
+
+	if maxSurge > len(outdated) {
+		maxSurge = len(outdated)
+	}
+
+	var surge []string
+	for i := 1; i <= maxSurge; i++ {
+		surge = append(surge, fmt.Sprintf("%s@%d%s", tmplInfo.Prefix, highest+i, path.Ext(name)))
+	}
+
+	updated, err := rollInstances(outdated, surge, uf, maxUnavailable+maxSurge)
+	for _, sname := range surge {
+		if derr := cAPI.DestroyUnit(sname); derr != nil {
+			stderr("Error destroying surge unit %s: %v", sname, derr)
+		}
+	}
+	if err == nil {
+		stdout("Updated %d instances of %s", len(outdated), name)
+		return
+	}
+
+	stderr("Error updating %s: %v", name, err)
+	if !rollingUpdateFlags.Rollback {
+		return 1
+	}
+
+	stderr("Rolling back %d instances of %s", len(updated), name)
+	if oldTmpl != nil {
+		if err := replaceUnit(name, oldTmpl, job.JobStateInactive); err != nil {
+			stderr("Error restoring template unit %s: %v", name, err)
+			return 1
+		}
+	}
+	for _, ri := range updated {
+		if err := replaceUnit(ri.name, ri.old, ri.state); err != nil {
+			stderr("Error restoring unit %s: %v", ri.name, err)
+		}
+	}
+	return 1
+}
+
+// rollInstances starts the surge instances, then replaces the outdated
+// instances with the given unit file in batches of the given size. It
+// returns the instances that were replaced before an error occurred, if
+// any.
+func rollInstances(outdated []rollingInstance, surge []string, uf *unit.UnitFile, batch int) (updated []rollingInstance, err error) {
+	if len(surge) > 0 {
+		for _, sname := range surge {
+			if err = replaceUnit(sname, uf, job.JobStateLaunched); err != nil {
+				return
+			}
+			stdout("Started surge unit %s", sname)
+		}
+		if err = waitForInstances(surge, uf); err != nil {
+			return
+		}
+	}
+
+	for len(outdated) > 0 {
+		n := batch
+		if n > len(outdated) {
+			n = len(outdated)
+		}
+
+		var waiting []string
+		for _, ri := range outdated[:n] {
+			if err = replaceUnit(ri.name, uf, ri.state); err != nil {
+				return
+			}
+			updated = append(updated, ri)
+			stdout("Replaced unit %s", ri.name)
+			if ri.state == job.JobStateLaunched {
+				waiting = append(waiting, ri.name)
+			}
+		}
+
+		if err = waitForInstances(waiting, uf); err != nil {
+			return
+		}
+		outdated = outdated[n:]
+	}
+
+	return
+}
+
+// replaceUnit destroys the named unit, if it exists, and creates it anew
+// from the given unit file with the given desired state
+func replaceUnit(name string, uf *unit.UnitFile, state job.JobState) error {
+	u, err := cAPI.Unit(name)
+	if err != nil {
+		return err
+	}
+	if u != nil {
+		if err := cAPI.DestroyUnit(name); err != nil {
+			return err
+		}
+	}
+
+	if _, err := createUnit(name, uf); err != nil {
+		return err
+	}
+	if state == "" || state == job.JobStateInactive {
+		return nil
+	}
+	return cAPI.SetUnitTargetState(name, string(state))
+}
+
+// waitForInstances waits until each of the named units reports running the
+// given unit file and, if it has a health check, being healthy. An error is
+// returned if that does not happen within the configured timeout.
+func waitForInstances(names []string, uf *unit.UnitFile) error {
+	hash := uf.Hash().String()
+	ju := job.Unit{Unit: *uf}
+	checked := ju.HealthCheck() != nil
+
+	deadline := time.Now().Add(rollingUpdateFlags.Timeout)
+	for {
+		states, err := cAPI.UnitStates()
+		if err != nil {
+			return err
+		}
+
+		ready := make(map[string]bool)
+		for _, us := range states {
+			if us.Hash == hash && us.SystemdActiveState == unitActiveStateActive && (!checked || us.Health == unitHealthHealthy) {
+				ready[us.Name] = true
+			}
+		}
+
+		var pending []string
+		for _, name := range names {
+			if !ready[name] {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("timed out waiting for units %v to become ready", pending)
+		}
+		time.Sleep(rollingUpdatePollInterval)
+	}
+}
+
+// instanceNumber parses the name of a numbered instance, i.e. a positive
+// number without leading zeros
+func instanceNumber(instance string) (int, bool) {
+	n, err := strconv.Atoi(instance)
+	if err != nil || n < 1 || strconv.Itoa(n) != instance {
+		return 0, false
+	}
+	return n, true
+}
+
+// rollingInstancesByName orders numbered instances by their number, ahead
+// of all others
+type rollingInstancesByName []rollingInstance
+
+func (ri rollingInstancesByName) Len() int      { return len(ri) }
+func (ri rollingInstancesByName) Swap(i, j int) { ri[i], ri[j] = ri[j], ri[i] }
+
+func (ri rollingInstancesByName) Less(i, j int) bool {
+	ni, iok := instanceNumber(unit.NewUnitNameInfo(ri[i].name).Instance)
+	nj, jok := instanceNumber(unit.NewUnitNameInfo(ri[j].name).Instance)
+	if iok && jok {
+		return ni < nj
+	} else if iok != jok {
+		return iok
+	}
+	return ri[i].name < ri[j].name
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func newFakeRegistryForRollingUpdate(t *testing.T, oldUF *unit.UnitFile) *registry.FakeRegistry {
+	reg := registry.NewFakeRegistry()
+	var jobs []job.Job
+	for _, name := range []string{"web@.service", "web@1.service", "web@2.service", "web@10.service", "other.service"} {
+		j := job.NewJob(name, *oldUF)
+		if name != "web@.service" {
+			j.TargetState = job.JobStateLaunched
+		}
+		jobs = append(jobs, *j)
+	}
+	reg.SetJobs(jobs)
+	return reg
+}
+
+func writeTemplate(t *testing.T, contents string) (string, *unit.UnitFile) {
+	dir, err := ioutil.TempDir("", "fleetctl-rolling-update")
+	if err != nil {
+		t.Fatalf("Failed creating tempdir: %v", err)
+	}
+	file := path.Join(dir, "web@.service")
+	if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed writing unit file: %v", err)
+	}
+	uf, err := unit.NewUnitFile(contents)
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit file: %v", err)
+	}
+	return file, uf
+}
+
+func assertUnitHashes(t *testing.T, reg registry.Registry, want map[string]unit.Hash) {
+	units, err := reg.Units()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := make(map[string]unit.Hash)
+	for _, u := range units {
+		got[u.Name] = u.Unit.Hash()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected units: got %v, want %v", got, want)
+	}
+}
+
+func TestRunRollingUpdate(t *testing.T) {
+	rollingUpdatePollInterval = time.Millisecond
+	defer func() {
+		rollingUpdatePollInterval = time.Second
+		rollingUpdateFlags.MaxSurge = 0
+	}()
+
+	oldUF, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/old")
+	file, newUF := writeTemplate(t, "[Service]\nExecStart=/bin/new")
+	defer os.RemoveAll(path.Dir(file))
+
+	reg := newFakeRegistryForRollingUpdate(t, oldUF)
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	// all instances, including the surge instance, report running the
+	// new unit file
+	var states []unit.UnitState
+	for _, name := range []string{"web@1.service", "web@2.service", "web@10.service", "web@11.service"} {
+		states = append(states, unit.UnitState{UnitName: name, UnitHash: newUF.Hash().String(), ActiveState: "active", MachineID: "XXX"})
+	}
+	reg.SetUnitStates(states)
+
+	rollingUpdateFlags.MaxUnavailable = 1
+	rollingUpdateFlags.MaxSurge = 1
+	rollingUpdateFlags.Timeout = time.Second
+	if exit := runRollingUpdate([]string{file}); exit != 0 {
+		t.Fatalf("Unexpected exit %d", exit)
+	}
+
+	assertUnitHashes(t, reg, map[string]unit.Hash{
+		"web@.service":   newUF.Hash(),
+		"web@1.service":  newUF.Hash(),
+		"web@2.service":  newUF.Hash(),
+		"web@10.service": newUF.Hash(),
+		"other.service":  oldUF.Hash(),
+	})
+
+	units, _ := reg.Units()
+	for _, u := range units {
+		if u.Name != "web@.service" && u.TargetState != job.JobStateLaunched {
+			t.Errorf("Unit(%s) has target state %s after update", u.Name, u.TargetState)
+		}
+	}
+}
+
+func TestRunRollingUpdateRollback(t *testing.T) {
+	rollingUpdatePollInterval = time.Millisecond
+	defer func() {
+		rollingUpdatePollInterval = time.Second
+		rollingUpdateFlags.Rollback = false
+	}()
+
+	oldUF, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/old")
+	file, newUF := writeTemplate(t, "[Service]\nExecStart=/bin/new")
+	defer os.RemoveAll(path.Dir(file))
+
+	reg := newFakeRegistryForRollingUpdate(t, oldUF)
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	// only the first instance comes up with the new unit file
+	reg.SetUnitStates([]unit.UnitState{
+		unit.UnitState{UnitName: "web@1.service", UnitHash: newUF.Hash().String(), ActiveState: "active", MachineID: "XXX"},
+		unit.UnitState{UnitName: "web@2.service", UnitHash: newUF.Hash().String(), ActiveState: "failed", MachineID: "XXX"},
+	})
+
+	rollingUpdateFlags.MaxUnavailable = 1
+	rollingUpdateFlags.Timeout = 10 * time.Millisecond
+	rollingUpdateFlags.Rollback = true
+	if exit := runRollingUpdate([]string{file}); exit != 1 {
+		t.Fatalf("Expected exit 1, got %d", exit)
+	}
+
+	assertUnitHashes(t, reg, map[string]unit.Hash{
+		"web@.service":   oldUF.Hash(),
+		"web@1.service":  oldUF.Hash(),
+		"web@2.service":  oldUF.Hash(),
+		"web@10.service": oldUF.Hash(),
+		"other.service":  oldUF.Hash(),
+	})
+}
+
+func TestRollingUpdateInvalidArgs(t *testing.T) {
+	defer func() {
+		rollingUpdateFlags.MaxUnavailable = 1
+	}()
+
+	rollingUpdateFlags.MaxUnavailable = 0
+	if exit := runRollingUpdate([]string{"web@.service"}); exit != 1 {
+		t.Errorf("Expected exit 1 without any instances allowed to be replaced, got %d", exit)
+	}
+
+	rollingUpdateFlags.MaxUnavailable = 1
+	for _, args := range [][]string{nil, []string{"web.service"}, []string{"web@1.service"}} {
+		if exit := runRollingUpdate(args); exit != 1 {
+			t.Errorf("args %v: expected exit 1, got %d", args, exit)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/coreos/fleet/unit"
+)
+
+var cmdScaleUnit = &Command{
+	Name:    "scale",
+	Summary: "Run a number of instances of a template unit",
+	Usage:   "TEMPLATE COUNT",
+	Description: `Set the number of instances of a template unit the cluster should run. The
+engine creates and starts instances numbered 1 through COUNT from the template,
+and destroys any instances numbered above COUNT. The instances are scheduled
+like any other unit, so the Conflicts, metadata and resource requirements of
+the template apply to each of them.
+
+If the template has not been submitted yet, it is read from the filesystem.
+
+Run five instances of a web server:
+	fleetctl scale web@.service 5
+
+Destroy all instances created by scaling:
+	fleetctl scale web@.service 0
+
+Instances created by hand with names that are not numbers are left alone.`,
+	Run: runScaleUnit,
+}
+
+func runScaleUnit(args []string) (exit int) {
+	if len(args) != 2 {
+		stderr("One template unit and an instance count must be provided.")
+		return 1
+	}
+
+	name := unitNameMangle(args[0])
+	if uni := unit.NewUnitNameInfo(name); uni == nil || !uni.IsTemplate() {
+		stderr("Unit %s is not a template unit.", name)
+		return 1
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count < 0 {
+		stderr("Invalid instance count %q.", args[1])
+		return 1
+	}
+
+	if err := lazyCreateUnits(args[:1]); err != nil {
+		stderr("Error creating units: %v", err)
+		return 1
+	}
+
+	if err := cAPI.SetUnitScale(name, count); err != nil {
+		stderr("Error scaling unit %s: %v", name, err)
+		return 1
+	}
+
+	stdout("Scaling %s to %d instances", name, count)
+	return
+}
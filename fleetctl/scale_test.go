@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunScaleUnit(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "foo@.service"},
+		job.Job{Name: "bar.service"},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args   []string
+		exit   int
+		scales map[string]int
+	}{
+		{[]string{"foo@.service"}, 1, map[string]int{}},
+		{[]string{"bar.service", "2"}, 1, map[string]int{}},
+		{[]string{"foo@.service", "-1"}, 1, map[string]int{}},
+		{[]string{"foo@.service", "many"}, 1, map[string]int{}},
+		// neither in the registry nor on disk
+		{[]string{"baz@.service", "2"}, 1, map[string]int{}},
+		{[]string{"foo@.service", "3"}, 0, map[string]int{"foo@.service": 3}},
+		{[]string{"foo@", "0"}, 0, map[string]int{"foo@.service": 0}},
+	} {
+		if exit := runScaleUnit(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		scales, _ := reg.UnitScales()
+		if !reflect.DeepEqual(scales, tt.scales) {
+			t.Errorf("case %d: unexpected scales: got %v, want %v", i, scales, tt.scales)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	flagScheduleDryRun   bool
+	flagScheduleStrategy string
+	cmdScheduleUnit      = &Command{
+		Name:    "schedule",
+		Summary: "Show where the engine would schedule a unit",
+		Usage:   "--dry-run [--strategy=STRATEGY] UNIT",
+		Description: `Ask the engine which machine it would schedule a unit to right now, or why
+no machine qualifies, without submitting the unit. Each machine unable to run
+the unit is listed along with the reason, e.g. unmet metadata requirements,
+conflicts or insufficient resources.
+
+UNIT is read from the local filesystem if such a unit file exists, otherwise
+the unit already submitted under that name is used.
+
+The engine's decision depends on its scheduling strategy, which is not known
+to fleetctl; pass the scheduling_strategy fleet is configured with using
+--strategy. It defaults to least-loaded.
+
+Find out why a unit stays inactive:
+	fleetctl schedule --dry-run hello.service
+
+Only --dry-run is supported; use load or start to schedule units.`,
+		Run: runScheduleUnit,
+	}
+)
+
+func init() {
+	cmdScheduleUnit.Flags.BoolVar(&flagScheduleDryRun, "dry-run", false, "Only show where the unit would be scheduled")
+	cmdScheduleUnit.Flags.StringVar(&flagScheduleStrategy, "strategy", "", "Scheduling strategy of the engine: least-loaded, binpack, spread or random")
+	cmdScheduleUnit.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+	cmdScheduleUnit.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+}
+
+func runScheduleUnit(args []string) (exit int) {
+	if !flagScheduleDryRun {
+		stderr("Only --dry-run is supported; use load or start to schedule units.")
+		return 1
+	}
+	if len(args) != 1 {
+		stderr("One unit must be provided.")
+		return 1
+	}
+
+	name := unitNameMangle(args[0])
+	su := schema.Unit{Name: name}
+	if _, err := os.Stat(args[0]); !os.IsNotExist(err) {
+		uf, err := getUnitFromFile(args[0])
+		if err != nil {
+			stderr("Error reading unit file %s: %v", args[0], err)
+			return 1
+		}
+		for _, err := range validateUnit(name, uf) {
+			stderr("WARNING: %s: %v", name, err)
+		}
+		su.Options = schema.MapUnitFileToSchemaUnitOptions(uf)
+	} else {
+		u, err := cAPI.Unit(name)
+		if err != nil {
+			stderr("Error retrieving Unit(%s): %v", name, err)
+			return 1
+		} else if u == nil {
+			stderr("Unable to find Unit(%s) in Registry or on filesystem", name)
+			return 1
+		}
+		su.Options = u.Options
+	}
+
+	p, err := cAPI.PlanUnit(&su, flagScheduleStrategy)
+	if err != nil {
+		stderr("Error planning Unit(%s): %v", name, err)
+		return 1
+	}
+
+	if p.MachineID != "" {
+		stdout("Unit %s would be scheduled to %s.", name, machineLegend(p.MachineID))
+		for _, victim := range p.Preempts {
+			stdout("Unit %s would be preempted to make room for it.", victim)
+		}
+	} else {
+		stdout("Unit %s would not be scheduled: %s.", name, p.Reason)
+		if !suToGlobal(su) {
+			exit = 1
+		}
+	}
+
+	printMachineRejections(p.Rejections)
+	return
+}
+
+// machineLegend describes the machine of the given ID as in list-machines
+func machineLegend(machID string) string {
+	if ms := cachedMachineState(machID); ms != nil {
+		return machineFullLegend(*ms, sharedFlags.Full)
+	}
+	return machID
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestRunScheduleUnit(t *testing.T) {
+	newUnit := func(contents string) unit.UnitFile {
+		uf, err := unit.NewUnitFile(contents)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing unit: %v", err)
+		}
+		return *uf
+	}
+
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		{ID: "c31e44e1-f858-436e-933e-59c642517860", Metadata: map[string]string{"region": "us"}},
+	})
+	reg.SetJobs([]job.Job{
+		{Name: "us.service", Unit: newUnit("[X-Fleet]\nMachineMetadata=region=us")},
+		{Name: "eu.service", Unit: newUnit("[X-Fleet]\nMachineMetadata=region=eu")},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+	machineStates = nil
+	defer func() { machineStates = nil }()
+
+	for i, tt := range []struct {
+		dryRun bool
+		args   []string
+		exit   int
+	}{
+		{true, []string{"us.service"}, 0},
+		{true, []string{"eu.service"}, 1},
+		{true, []string{"missing.service"}, 1},
+		{true, nil, 1},
+		{false, []string{"us.service"}, 1},
+	} {
+		flagScheduleDryRun = tt.dryRun
+		if exit := runScheduleUnit(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+	flagScheduleDryRun = false
+}
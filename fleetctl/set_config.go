@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	setConfigFlags = struct {
+		Secret         bool
+		ClusterKeyFile string
+	}{}
+
+	cmdSetConfig = &Command{
+		Name:    "set-config",
+		Summary: "Change the configuration values a namespace passes to units",
+		Usage:   "[--secret --cluster-key-file=FILE] NAMESPACE KEY=VALUE...",
+		Description: `Store configuration values in a namespace of the registry. Units declaring
+the namespace with FleetEnvironment= get the values as environment variables,
+and are restarted when they change unless they set FleetEnvironmentRestart=false.
+
+Set the database of the production instances of an application:
+	fleetctl set-config myapp/prod DB_URL=postgres://db:5432/myapp
+
+Remove a value set earlier by leaving it empty:
+	fleetctl set-config myapp/prod DB_URL=
+
+With --secret, the values are encrypted with the cluster key read from FILE
+before they leave fleetctl. Agents decrypt them with the same key, read from
+their cluster_key_file.
+	fleetctl set-config --secret --cluster-key-file=cluster.key myapp/prod DB_PASSWORD=hunter2`,
+		Run: runSetConfig,
+	}
+)
+
+func init() {
+	cmdSetConfig.Flags.BoolVar(&setConfigFlags.Secret, "secret", false, "Encrypt the values with the cluster key.")
+	cmdSetConfig.Flags.StringVar(&setConfigFlags.ClusterKeyFile, "cluster-key-file", "", "File holding the hex-encoded cluster key to encrypt secret values with.")
+}
+
+func runSetConfig(args []string) (exit int) {
+	if len(args) < 2 {
+		stderr("One namespace and at least one KEY=VALUE pair must be provided.")
+		return 1
+	}
+
+	ns := args[0]
+	if err := job.ValidateConfigNamespace(ns); err != nil {
+		stderr("Invalid namespace %q: %v", ns, err)
+		return 1
+	}
+
+	var key []byte
+	if setConfigFlags.Secret {
+		if setConfigFlags.ClusterKeyFile == "" {
+			stderr("Secret values require the cluster key, provide it with --cluster-key-file.")
+			return 1
+		}
+		var err error
+		key, err = registry.ReadClusterKey(setConfigFlags.ClusterKeyFile)
+		if err != nil {
+			stderr("Unable to read cluster key: %v", err)
+			return 1
+		}
+	}
+
+	pairs := make(map[string]string, len(args)-1)
+	var keys []string
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			stderr("Invalid value %q, expected KEY=VALUE", arg)
+			return 1
+		}
+		if err := job.ValidateConfigKey(parts[0]); err != nil {
+			stderr("Invalid key %q: %v", parts[0], err)
+			return 1
+		}
+		if _, ok := pairs[parts[0]]; !ok {
+			keys = append(keys, parts[0])
+		}
+		pairs[parts[0]] = parts[1]
+	}
+
+	for _, k := range keys {
+		val := pairs[k]
+		if val == "" {
+			if err := cAPI.DeleteConfigValue(ns, k); err != nil {
+				stderr("Error removing %s of namespace %s: %v", k, ns, err)
+				return 1
+			}
+			continue
+		}
+
+		cv := registry.ConfigValue{Value: val}
+		if key != nil {
+			var err error
+			cv, err = registry.EncryptConfigValue(key, val)
+			if err != nil {
+				stderr("Error encrypting %s: %v", k, err)
+				return 1
+			}
+		}
+		if err := cAPI.SetConfigValue(ns, &schema.ConfigValue{Key: k, Value: cv.Value, Secret: cv.Secret}); err != nil {
+			stderr("Error setting %s of namespace %s: %v", k, ns, err)
+			return 1
+		}
+	}
+
+	stdout("Updated namespace %s", ns)
+	return
+}
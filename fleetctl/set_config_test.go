@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunSetConfig(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetConfigValue("myapp/prod", "LOG_LEVEL", registry.ConfigValue{Value: "info"})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args []string
+		exit int
+		want map[string]registry.ConfigValue
+	}{
+		// missing values
+		{[]string{"myapp/prod"}, 1, map[string]registry.ConfigValue{"LOG_LEVEL": registry.ConfigValue{Value: "info"}}},
+		{[]string{"myapp/prod", "DB_URL"}, 1, map[string]registry.ConfigValue{"LOG_LEVEL": registry.ConfigValue{Value: "info"}}},
+		// invalid namespace and key
+		{[]string{"myapp//prod", "DB_URL=db:5432"}, 1, map[string]registry.ConfigValue{"LOG_LEVEL": registry.ConfigValue{Value: "info"}}},
+		{[]string{"myapp/prod", "DB-URL=db:5432"}, 1, map[string]registry.ConfigValue{"LOG_LEVEL": registry.ConfigValue{Value: "info"}}},
+		{[]string{"myapp/prod", "DB_URL=db:5432", "LOG_LEVEL="}, 0, map[string]registry.ConfigValue{"DB_URL": registry.ConfigValue{Value: "db:5432"}}},
+	} {
+		if exit := runSetConfig(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		got, _ := reg.ConfigValues("myapp/prod")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: unexpected values: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestRunSetConfigSecret(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	f, err := ioutil.TempFile("", "fleetctl-cluster-key")
+	if err != nil {
+		t.Fatalf("Failed creating temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("0707070707070707070707070707070707070707070707070707070707070707\n"); err != nil {
+		t.Fatalf("Failed writing cluster key: %v", err)
+	}
+	f.Close()
+
+	setConfigFlags.Secret = true
+	defer func() { setConfigFlags.Secret, setConfigFlags.ClusterKeyFile = false, "" }()
+	if exit := runSetConfig([]string{"myapp", "TOKEN=hunter2"}); exit != 1 {
+		t.Errorf("Expected exit 1 without cluster key, got %d", exit)
+	}
+
+	setConfigFlags.ClusterKeyFile = f.Name()
+	if exit := runSetConfig([]string{"myapp", "TOKEN=hunter2"}); exit != 0 {
+		t.Fatalf("Expected exit 0, got %d", exit)
+	}
+	values, _ := reg.ConfigValues("myapp")
+	cv := values["TOKEN"]
+	if !cv.Secret || cv.Value == "hunter2" {
+		t.Fatalf("Value not encrypted: %#v", cv)
+	}
+	key, _ := registry.ReadClusterKey(f.Name())
+	if plain, err := cv.Plaintext(key); err != nil || plain != "hunter2" {
+		t.Errorf("Unexpected plaintext %q (err=%v)", plain, err)
+	}
+
+	want := []string{"KEY\tVALUE", "TOKEN\t(secret)"}
+	if got := runWithOutput(t, func(args []string) int { return runListConfig([]string{"myapp"}) }); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected list-config output: got %q, want %q", got, want)
+	}
+}
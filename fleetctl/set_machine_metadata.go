@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+)
+
+var cmdSetMachineMetadata = &Command{
+	Name:    "set-machine-metadata",
+	Summary: "Change the metadata of a machine in the cluster",
+	Usage:   "MACHINE KEY=VALUE...",
+	Description: `Set metadata of a machine at runtime, without restarting fleet on it. Values set
+this way take precedence over the metadata the machine was configured with
+and persist until removed.
+
+Set the region and role of a machine:
+	fleetctl set-machine-metadata 2444264c region=us-west role=db
+
+Remove a value set earlier by leaving it empty:
+	fleetctl set-machine-metadata 2444264c role=
+
+MACHINE may be any unique prefix of a machine ID. The engine takes the new
+metadata into account for all future scheduling decisions.`,
+	Run: runSetMachineMetadata,
+}
+
+func runSetMachineMetadata(args []string) (exit int) {
+	if len(args) < 2 {
+		stderr("One machine and at least one KEY=VALUE pair must be provided.")
+		return 1
+	}
+
+	ms, err := findMachine(args[0])
+	if err != nil {
+		stderr("Unable to find machine %s: %v", args[0], err)
+		return 1
+	}
+
+	pairs := make(map[string]string, len(args)-1)
+	var keys []string
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			stderr("Invalid metadata %q, expected KEY=VALUE", arg)
+			return 1
+		}
+		if _, ok := pairs[parts[0]]; !ok {
+			keys = append(keys, parts[0])
+		}
+		pairs[parts[0]] = parts[1]
+	}
+
+	for _, key := range keys {
+		val := pairs[key]
+		if val == "" {
+			err = cAPI.DeleteMachineMetadata(ms.ID, key)
+		} else {
+			err = cAPI.SetMachineMetadata(ms.ID, key, val)
+		}
+		if err != nil {
+			stderr("Error setting metadata %s of machine %s: %v", key, ms.ID, err)
+			return 1
+		}
+	}
+
+	stdout("Updated metadata of machine %s", ms.ID)
+	return
+}
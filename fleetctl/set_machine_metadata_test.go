@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunSetMachineMetadata(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		newMachineState("c31e44e1-f858-436e-933e-59c642517860", "1.2.3.4", nil),
+		newMachineState("c31e5555-cbb7-49ce-8726-722d6e157b4e", "5.6.7.8", nil),
+	})
+	reg.SetMachineMetadata("c31e44e1-f858-436e-933e-59c642517860", "role", "web")
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args []string
+		exit int
+		want map[string]string
+	}{
+		// ambiguous machine prefix
+		{[]string{"c31e", "region=us-west"}, 1, map[string]string{"role": "web"}},
+		// missing metadata
+		{[]string{"c31e44"}, 1, map[string]string{"role": "web"}},
+		{[]string{"c31e44", "region"}, 1, map[string]string{"role": "web"}},
+		{[]string{"c31e44", "region=us-west", "role="}, 0, map[string]string{"region": "us-west"}},
+	} {
+		if exit := runSetMachineMetadata(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		got, _ := reg.MachineMetadata("c31e44e1-f858-436e-933e-59c642517860")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: unexpected metadata: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
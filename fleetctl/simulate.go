@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/engine"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	simulateFlags = struct {
+		Machines string
+		Units    string
+		Strategy string
+	}{}
+
+	cmdSimulate = &Command{
+		Name:    "simulate",
+		Summary: "Show where the engine would schedule units in a synthetic cluster",
+		Usage:   "--machines=FILE --units=DIR [--strategy=STRATEGY] [-l|--full] [--no-legend]",
+		Description: `Run the scheduling of the engine offline, against the machines described by
+FILE and the units of DIR, and print where each unit ends up along with what
+the units of each machine reserve of what it can allocate. Nothing is read
+from or written to the cluster, so new reservations, constraints or
+strategies can be tried out safely.
+
+FILE holds machines in the format printed by list-machines --output=json, so
+the machines of the cluster may be simulated, or changed first:
+	fleetctl list-machines --output=json > machines.json
+	fleetctl simulate --machines=machines.json --units=units/
+
+DIR is read like apply does: unit files are started, except template units,
+stack files group units and scale manifests create instances of templates.
+Exits with a non-zero status if any unit could not be scheduled.`,
+		Run: runSimulate,
+	}
+)
+
+func init() {
+	cmdSimulate.Flags.StringVar(&simulateFlags.Machines, "machines", "", "File describing the machines of the simulated cluster.")
+	cmdSimulate.Flags.StringVar(&simulateFlags.Units, "units", "", "Directory of the units to schedule.")
+	cmdSimulate.Flags.StringVar(&simulateFlags.Strategy, "strategy", "", "Scheduling strategy of the engine: least-loaded, binpack, spread or random")
+	cmdSimulate.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+	cmdSimulate.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+	cmdSimulate.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+}
+
+// simulatedMachine describes a machine of a simulated cluster, as printed by
+// list-machines --output=json
+type simulatedMachine struct {
+	ID          string            `json:"id"`
+	PublicIP    string            `json:"primaryIP"`
+	Metadata    map[string]string `json:"metadata"`
+	Cordoned    bool              `json:"cordoned"`
+	Draining    bool              `json:"draining"`
+	Taints      []string          `json:"taints"`
+	MaxUnits    int               `json:"maxUnits"`
+	Total       resourcesOutput   `json:"totalResources"`
+	Reserved    *resourcesOutput  `json:"reservedResources"`
+	Allocatable *resourcesOutput  `json:"allocatableResources"`
+}
+
+// machineState returns the MachineState of the simulated machine. The
+// overcommit factors of the machine are derived from its allocatable
+// resources, if given.
+func (sm *simulatedMachine) machineState() (machine.MachineState, error) {
+	if sm.ID == "" {
+		return machine.MachineState{}, fmt.Errorf("machine without id")
+	}
+	ms := machine.MachineState{
+		ID:                sm.ID,
+		PublicIP:          sm.PublicIP,
+		Metadata:          sm.Metadata,
+		Cordoned:          sm.Cordoned || sm.Draining,
+		Draining:          sm.Draining,
+		MaxUnits:          sm.MaxUnits,
+		TotalResources:    resource.ResourceTuple{Cores: sm.Total.CPUUnits, Memory: sm.Total.Memory, Disk: sm.Total.Disk},
+		ExtendedResources: sm.Total.Extended,
+	}
+	if sm.Reserved != nil {
+		ms.ReservedResources = &resource.ResourceTuple{Cores: sm.Reserved.CPUUnits, Memory: sm.Reserved.Memory, Disk: sm.Reserved.Disk}
+	}
+	for _, s := range sm.Taints {
+		t, err := machine.ParseTaint(s)
+		if err != nil {
+			return ms, fmt.Errorf("machine %s: %v", sm.ID, err)
+		}
+		ms.Taints = append(ms.Taints, t)
+	}
+
+	if sm.Allocatable != nil {
+		free := resource.Sub(ms.TotalResources, ms.Reserved())
+		if free.Cores > 0 && sm.Allocatable.CPUUnits > 0 {
+			ms.CPUOvercommit = float64(sm.Allocatable.CPUUnits) / float64(free.Cores)
+		}
+		if free.Memory > 0 && sm.Allocatable.Memory > 0 {
+			ms.MemoryOvercommit = float64(sm.Allocatable.Memory) / float64(free.Memory)
+		}
+	}
+	return ms, nil
+}
+
+func runSimulate(args []string) (exit int) {
+	if simulateFlags.Machines == "" || simulateFlags.Units == "" {
+		stderr("Both --machines and --units must be provided.")
+		return 1
+	}
+
+	machines, err := readSimulatedMachines(simulateFlags.Machines)
+	if err != nil {
+		stderr("Error reading machines from %s: %v", simulateFlags.Machines, err)
+		return 1
+	}
+	m, err := readApplyDir(simulateFlags.Units)
+	if err != nil {
+		stderr("Error reading units from %s: %v", simulateFlags.Units, err)
+		return 1
+	}
+
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines(machines)
+	for name, uf := range m.units {
+		if err := reg.CreateUnit(&job.Unit{Name: name, Unit: *uf, TargetState: applyState(name)}); err != nil {
+			stderr("Error creating unit %s: %v", name, err)
+			return 1
+		}
+	}
+	for _, s := range m.stacks {
+		if err := reg.CreateStack(schema.MapSchemaStackToStack(s)); err != nil {
+			stderr("Error creating stack %s: %v", s.Name, err)
+			return 1
+		}
+	}
+	for tmpl, count := range m.scales {
+		reg.SetUnitScale(tmpl, count)
+	}
+
+	rejected, err := engine.Simulate(reg, simulateFlags.Strategy)
+	if err != nil {
+		stderr("Error simulating scheduling: %v", err)
+		return 1
+	}
+
+	exit = printSimulation(&client.RegistryClient{Registry: reg}, rejected)
+	out.Flush()
+	return
+}
+
+// readSimulatedMachines reads the machines of a simulated cluster from the
+// given file
+func readSimulatedMachines(file string) ([]machine.MachineState, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var sms []simulatedMachine
+	if err := json.Unmarshal(b, &sms); err != nil {
+		return nil, err
+	}
+
+	var machines []machine.MachineState
+	seen := make(map[string]bool)
+	for i := range sms {
+		ms, err := sms[i].machineState()
+		if err != nil {
+			return nil, err
+		}
+		if seen[ms.ID] {
+			return nil, fmt.Errorf("machine %s is described more than once", ms.ID)
+		}
+		seen[ms.ID] = true
+		machines = append(machines, ms)
+	}
+	return machines, nil
+}
+
+// printSimulation prints where the engine scheduled the units of the
+// simulated cluster, and the reservations on each machine. It returns 1 if
+// any unit was left unscheduled.
+func printSimulation(sim *client.RegistryClient, rejected map[string]registry.UnitRejections) (exit int) {
+	units, err := sim.Units()
+	if err != nil {
+		stderr("Error retrieving simulated units: %v", err)
+		return 1
+	}
+	machines, err := sim.Machines()
+	if err != nil {
+		stderr("Error retrieving simulated machines: %v", err)
+		return 1
+	}
+	states := make(map[string]machine.MachineState, len(machines))
+	for _, ms := range machines {
+		states[ms.ID] = ms
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "UNIT\tMACHINE\tREASON")
+	}
+	counts := make(map[string]int)
+	for _, u := range units {
+		if suToGlobal(*u) || job.JobState(u.DesiredState) == job.JobStateInactive {
+			continue
+		}
+		if u.MachineID != "" {
+			counts[u.MachineID]++
+			fmt.Fprintf(out, "%s\t%s\t-\n", u.Name, machineIDLegend(states[u.MachineID], sharedFlags.Full))
+			continue
+		}
+		reason := "not scheduled"
+		if rej, ok := rejected[u.Name]; ok {
+			reason = rej.Reason
+		}
+		fmt.Fprintf(out, "%s\t-\t%s\n", u.Name, reason)
+		exit = 1
+	}
+
+	fmt.Fprintln(out)
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "MACHINE\tUNITS\tCPU(RESERVED/ALLOCATABLE)\tMEMORY(RESERVED/ALLOCATABLE)")
+	}
+	sort.Sort(machinesByID(machines))
+	for _, ms := range machines {
+		alloc := ms.AllocatableResources()
+		fmt.Fprintf(out, "%s\t%d\t%s\t%s\n", machineIDLegend(ms, sharedFlags.Full), counts[ms.ID],
+			utilization(ms.AllocatedResources.Cores, alloc.Cores, ""),
+			utilization(ms.AllocatedResources.Memory, alloc.Memory, "MB"))
+	}
+	return
+}
+
+// utilization formats how much of a resource is reserved
+func utilization(reserved, allocatable int, unit string) string {
+	if allocatable <= 0 {
+		return fmt.Sprintf("%d%s/-", reserved, unit)
+	}
+	return fmt.Sprintf("%d%s/%d%s (%d%%)", reserved, unit, allocatable, unit, reserved*100/allocatable)
+}
+
+type machinesByID []machine.MachineState
+
+func (m machinesByID) Len() int           { return len(m) }
+func (m machinesByID) Less(i, j int) bool { return m[i].ID < m[j].ID }
+func (m machinesByID) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
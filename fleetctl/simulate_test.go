@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/fleet/resource"
+)
+
+func TestSimulatedMachineState(t *testing.T) {
+	sm := simulatedMachine{
+		ID:          "XXX",
+		Taints:      []string{"dedicated=db:NoSchedule"},
+		Total:       resourcesOutput{CPUUnits: 400, Memory: 4096},
+		Reserved:    &resourcesOutput{CPUUnits: 100, Memory: 1024},
+		Allocatable: &resourcesOutput{CPUUnits: 600, Memory: 3072},
+	}
+	ms, err := sm.machineState()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ms.AllocatableResources() != (resource.ResourceTuple{Cores: 600, Memory: 3072}) {
+		t.Errorf("Unexpected allocatable resources %v", ms.AllocatableResources())
+	}
+	if len(ms.Taints) != 1 || ms.Taints[0].Key != "dedicated" {
+		t.Errorf("Unexpected taints %v", ms.Taints)
+	}
+
+	sm.Taints = []string{"dedicated"}
+	if _, err := sm.machineState(); err == nil {
+		t.Errorf("Expected error for invalid taint")
+	}
+	if _, err := (&simulatedMachine{}).machineState(); err == nil {
+		t.Errorf("Expected error for machine without id")
+	}
+}
+
+func TestRunSimulate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleetctl-simulate")
+	if err != nil {
+		t.Fatalf("Failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	machines := filepath.Join(dir, "machines.json")
+	units := filepath.Join(dir, "units")
+	os.Mkdir(units, 0755)
+	ioutil.WriteFile(machines, []byte(`[
+		{"id": "XXX", "totalResources": {"cpuUnits": 400, "memory": 2048}, "reservedResources": {"cpuUnits": 0, "memory": 0}},
+		{"id": "YYY", "totalResources": {"cpuUnits": 400, "memory": 2048}, "reservedResources": {"cpuUnits": 0, "memory": 0}}
+	]`), 0644)
+	ioutil.WriteFile(filepath.Join(units, "web@.service"), []byte("[Service]\nExecStart=/bin/web\n[X-Fleet]\nMemoryReservation=1024\n"), 0644)
+	ioutil.WriteFile(filepath.Join(units, "scale.yaml"), []byte("scale:\n  web@.service: 4\n"), 0644)
+
+	defer func() { simulateFlags.Machines, simulateFlags.Units = "", "" }()
+	for i, tt := range []struct {
+		machines, units string
+		exit            int
+	}{
+		{"", units, 1},
+		{filepath.Join(dir, "missing.json"), units, 1},
+		// four instances fill both machines
+		{machines, units, 0},
+	} {
+		simulateFlags.Machines, simulateFlags.Units = tt.machines, tt.units
+		if exit := runSimulate(nil); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+
+	// a fifth does not fit
+	ioutil.WriteFile(filepath.Join(units, "scale.yaml"), []byte("scale:\n  web@.service: 5\n"), 0644)
+	if exit := runSimulate(nil); exit != 1 {
+		t.Errorf("Expected exit 1 for units left unscheduled, got %d", exit)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/coreos/fleet/log"
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/pkg"
 	"github.com/coreos/fleet/ssh"
@@ -20,7 +21,7 @@ var (
 	cmdSSH                 = &Command{
 		Name:    "ssh",
 		Summary: "Open interactive shell on a machine in the cluster",
-		Usage:   "[-A|--forward-agent] [--machine|--unit] {MACHINE|UNIT}",
+		Usage:   "[-A|--forward-agent] [--via-api] [--machine|--unit] {MACHINE|UNIT} [COMMAND]",
 		Description: `Open an interactive shell on a specific machine in the cluster or on the machine 
 where the specified unit is located.
 
@@ -37,6 +38,11 @@ cluster member as a bastion host:
 Open a shell on a machine and forward the authentication agent connection:
 	fleetctl ssh --forward-agent 2444264c-eac2-4eff-a490-32d5e5e4af24
 
+Run a command on the machine running a unit through the API of the cluster
+rather than over SSH, which requires the machine to allow it with
+api_allow_exec. Interactive shells are not supported this way:
+	fleetctl --experimental-api ssh --via-api foo.service systemctl status foo.service
+
 
 Tip: Create an alias for --tunnel.
 	- Add "alias fleetctl=fleetctl --tunnel 10.10.10.10" to your bash profile.
@@ -52,6 +58,7 @@ func init() {
 	cmdSSH.Flags.StringVar(&flagUnit, "unit", "", "Open SSH connection to machine running provided unit.")
 	cmdSSH.Flags.BoolVar(&flagSSHAgentForwarding, "forward-agent", false, "Forward local ssh-agent to target machine.")
 	cmdSSH.Flags.BoolVar(&flagSSHAgentForwarding, "A", false, "Shorthand for --forward-agent")
+	cmdSSH.Flags.BoolVar(&flagViaAPI, "via-api", false, "Run the command through the fleet API rather than over SSH. Requires --experimental-api.")
 }
 
 func runSSH(args []string) (exit int) {
@@ -60,16 +67,20 @@ func runSSH(args []string) (exit int) {
 		return 1
 	}
 
+	if flagViaAPI {
+		return runCommandViaAPI(args)
+	}
+
 	var err error
-	var addr string
+	var addrs []string
 
 	switch {
 	case flagMachine != "":
-		addr, _, err = findAddressInMachineList(flagMachine)
+		addrs, _, err = findAddressInMachineList(flagMachine)
 	case flagUnit != "":
-		addr, _, err = findAddressInRunningUnits(flagUnit)
+		addrs, _, err = findAddressInRunningUnits(flagUnit)
 	default:
-		addr, err = globalMachineLookup(args)
+		addrs, err = globalMachineLookup(args)
 		// trim machine/unit name from args
 		if len(args) > 0 {
 			args = args[1:]
@@ -81,19 +92,14 @@ func runSSH(args []string) (exit int) {
 		return 1
 	}
 
-	if addr == "" {
+	if len(addrs) == 0 {
 		stderr("Could not determine address of machine.")
 		return 1
 	}
 
 	args = pkg.TrimToDashes(args)
 
-	var sshClient *ssh.SSHForwardingClient
-	if tun := getTunnelFlag(); tun != "" {
-		sshClient, err = ssh.NewTunnelledSSHClient("core", tun, addr, getChecker(), flagSSHAgentForwarding)
-	} else {
-		sshClient, err = ssh.NewSSHClient("core", addr, getChecker(), flagSSHAgentForwarding)
-	}
+	sshClient, err := newSSHClient(addrs, flagSSHAgentForwarding)
 	if err != nil {
 		stderr("Failed building SSH client: %v", err)
 		return 1
@@ -116,72 +122,167 @@ func runSSH(args []string) (exit int) {
 	return
 }
 
-func globalMachineLookup(args []string) (string, error) {
+// runCommandViaAPI runs the command given after the machine or unit, if any,
+// on the machine through the fleet API
+func runCommandViaAPI(args []string) (exit int) {
+	var machID string
+	var err error
+	switch {
+	case flagMachine != "":
+		machID, err = findMachineID(flagMachine)
+	case flagUnit != "":
+		machID, err = findMachineIDOfUnit(flagUnit)
+	case len(args) == 0:
+		err = errors.New("one machine or unit must be provided")
+	default:
+		machID, err = globalMachineIDLookup(args[0])
+		args = args[1:]
+	}
+	if err != nil {
+		stderr("Unable to proceed: %v", err)
+		return 1
+	}
+
+	args = pkg.TrimToDashes(args)
 	if len(args) == 0 {
-		return "", errors.New("one machine or unit must be provided")
+		stderr("A command must be provided, interactive shells are not supported through the API.")
+		return 1
 	}
 
-	lookup := args[0]
+	exit, err = cAPI.RunCommand(machID, args, os.Stdout)
+	if err != nil {
+		stderr("Failed running command through the API: %v", err)
+		return 1
+	}
+	return
+}
 
-	machineAddr, machineOk, _ := findAddressInMachineList(lookup)
-	unitAddr, unitOk, _ := findAddressInRunningUnits(lookup)
+func globalMachineIDLookup(lookup string) (string, error) {
+	machID, machErr := findMachineID(lookup)
+	unitMachID, unitErr := findMachineIDOfUnit(lookup)
 
 	switch {
-	case machineOk && unitOk:
+	case machErr == nil && unitErr == nil:
 		return "", fmt.Errorf("ambiguous argument, both machine and unit found for `%s`.\nPlease use flag `-m` or `-u` to refine the search", lookup)
-	case machineOk:
-		return machineAddr, nil
-	case unitOk:
-		return unitAddr, nil
+	case machErr == nil:
+		return machID, nil
+	case unitErr == nil:
+		return unitMachID, nil
 	}
 
 	return "", fmt.Errorf("could not find matching unit or machine")
 }
 
-func findAddressInMachineList(lookup string) (string, bool, error) {
-	states, err := cAPI.Machines()
+func findMachineID(lookup string) (string, error) {
+	match, err := findMachine(lookup)
 	if err != nil {
-		return "", false, err
+		return "", err
 	}
+	return match.ID, nil
+}
 
-	var match *machine.MachineState
-	for i := range states {
-		machState := states[i]
-		if !strings.HasPrefix(machState.ID, lookup) {
-			continue
-		}
+func findMachineIDOfUnit(name string) (string, error) {
+	name = unitNameMangle(name)
+	u, err := cAPI.Unit(name)
+	if err != nil {
+		return "", err
+	} else if u == nil {
+		return "", fmt.Errorf("unit does not exist")
+	} else if suToGlobal(*u) {
+		return "", fmt.Errorf("global units unsupported")
+	} else if u.MachineID == "" {
+		return "", fmt.Errorf("unit is not scheduled to a machine")
+	}
+	return u.MachineID, nil
+}
 
-		if match != nil {
-			return "", false, fmt.Errorf("found more than one machine")
-		}
+func globalMachineLookup(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, errors.New("one machine or unit must be provided")
+	}
+
+	lookup := args[0]
+
+	machineAddrs, machineOk, _ := findAddressInMachineList(lookup)
+	unitAddrs, unitOk, _ := findAddressInRunningUnits(lookup)
 
-		match = &machState
+	switch {
+	case machineOk && unitOk:
+		return nil, fmt.Errorf("ambiguous argument, both machine and unit found for `%s`.\nPlease use flag `-m` or `-u` to refine the search", lookup)
+	case machineOk:
+		return machineAddrs, nil
+	case unitOk:
+		return unitAddrs, nil
 	}
 
-	if match == nil {
-		return "", false, fmt.Errorf("machine does not exist")
+	return nil, fmt.Errorf("could not find matching unit or machine")
+}
+
+// findAddressInMachineList returns the addresses of the matching machine,
+// most preferred first
+func findAddressInMachineList(lookup string) ([]string, bool, error) {
+	match, err := findMachine(lookup)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return match.PublicIP, true, nil
+	return machineAddresses(match), true, nil
 }
 
-func findAddressInRunningUnits(name string) (string, bool, error) {
+// findAddressInRunningUnits returns the addresses of the machine the named
+// unit is scheduled to, most preferred first
+func findAddressInRunningUnits(name string) ([]string, bool, error) {
 	name = unitNameMangle(name)
 	u, err := cAPI.Unit(name)
 	if err != nil {
-		return "", false, err
+		return nil, false, err
 	} else if u == nil {
-		return "", false, fmt.Errorf("unit does not exist")
+		return nil, false, fmt.Errorf("unit does not exist")
 	} else if suToGlobal(*u) {
-		return "", false, fmt.Errorf("global units unsupported")
+		return nil, false, fmt.Errorf("global units unsupported")
 	}
 
 	m := cachedMachineState(u.MachineID)
-	if m != nil && m.PublicIP != "" {
-		return m.PublicIP, true, nil
+	if addrs := machineAddresses(m); len(addrs) > 0 {
+		return addrs, true, nil
 	}
 
-	return "", false, nil
+	return nil, false, nil
+}
+
+// machineAddresses returns the IP addresses of the given machine, most
+// preferred first
+func machineAddresses(ms *machine.MachineState) []string {
+	if ms == nil {
+		return nil
+	}
+	var addrs []string
+	for _, a := range ms.AllAddresses() {
+		addrs = append(addrs, a.IP)
+	}
+	return addrs
+}
+
+// newSSHClient connects over SSH, through the tunnel if any, to the first
+// reachable of the given addresses of a machine, trying them in order. The
+// error of the last address tried is returned if none is reachable. A host
+// key mismatch is returned right away.
+func newSSHClient(addrs []string, agentForwarding bool) (sshClient *ssh.SSHForwardingClient, err error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("no address of machine known")
+	}
+	for _, addr := range addrs {
+		if tun := getTunnelFlag(); tun != "" {
+			sshClient, err = ssh.NewTunnelledSSHClient("core", tun, addr, getChecker(), agentForwarding)
+		} else {
+			sshClient, err = ssh.NewSSHClient("core", addr, getChecker(), agentForwarding)
+		}
+		if err == nil || strings.Contains(err.Error(), ssh.ErrUnmatchKey.Error()) {
+			return
+		}
+		log.V(1).Infof("Unable to reach %s over SSH: %v", addr, err)
+	}
+	return
 }
 
 // runCommand will attempt to run a command on a given machine. It will attempt
@@ -198,7 +299,7 @@ func runCommand(cmd string, machID string) (retcode int) {
 		if err != nil || ms == nil {
 			stderr("Error getting machine IP: %v", err)
 		} else {
-			err, retcode = runRemoteCommand(cmd, ms.PublicIP)
+			err, retcode = runRemoteCommand(cmd, machineAddresses(ms))
 			if err != nil {
 				stderr("Error running remote command: %v", err)
 			}
@@ -228,15 +329,11 @@ func runLocalCommand(cmd string) (error, int) {
 	return nil, 0
 }
 
-// runRemoteCommand runs the given command over SSH on the given IP, and returns
-// any error encountered and the exit status of the command
-func runRemoteCommand(cmd string, addr string) (err error, exit int) {
-	var sshClient *ssh.SSHForwardingClient
-	if tun := getTunnelFlag(); tun != "" {
-		sshClient, err = ssh.NewTunnelledSSHClient("core", tun, addr, getChecker(), false)
-	} else {
-		sshClient, err = ssh.NewSSHClient("core", addr, getChecker(), false)
-	}
+// runRemoteCommand runs the given command over SSH on the first reachable of
+// the given addresses of a machine, and returns any error encountered and the
+// exit status of the command
+func runRemoteCommand(cmd string, addrs []string) (err error, exit int) {
+	sshClient, err := newSSHClient(addrs, false)
 	if err != nil {
 		return err, -1
 	}
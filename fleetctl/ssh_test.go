@@ -1,6 +1,7 @@
 package main
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/coreos/fleet/client"
@@ -77,9 +78,9 @@ func TestSshUnknownMachine(t *testing.T) {
 func TestSshFindMachine(t *testing.T) {
 	cAPI = newFakeRegistryForSsh()
 
-	ip, _, _ := findAddressInMachineList("c31e44e1-f858-436e-933e-59c642517860")
-	if ip != "1.2.3.4" {
-		t.Errorf("Expected to return the host 1.2.3.4, but it was %s", ip)
+	ips, _, _ := findAddressInMachineList("c31e44e1-f858-436e-933e-59c642517860")
+	if !reflect.DeepEqual(ips, []string{"1.2.3.4"}) {
+		t.Errorf("Expected to return the host 1.2.3.4, but it was %v", ips)
 	}
 }
 
@@ -95,9 +96,9 @@ func TestSshFindMachineByUnknownUnitName(t *testing.T) {
 func TestSshFindMachineByUnitName(t *testing.T) {
 	cAPI = newFakeRegistryForSsh()
 
-	ip, _, _ := findAddressInRunningUnits("j1")
-	if ip != "1.2.3.4" {
-		t.Errorf("Expected to return the host 1.2.3.4, but it was %s", ip)
+	ips, _, _ := findAddressInRunningUnits("j1")
+	if !reflect.DeepEqual(ips, []string{"1.2.3.4"}) {
+		t.Errorf("Expected to return the host 1.2.3.4, but it was %v", ips)
 	}
 }
 
@@ -113,26 +114,26 @@ func TestGlobalLookupByUnknownArgument(t *testing.T) {
 func TestGlobalLookupByMachineID(t *testing.T) {
 	cAPI = newFakeRegistryForSsh()
 
-	ip, err := globalMachineLookup([]string{"c31e44e1-f858-436e-933e-59c642517860"})
+	ips, err := globalMachineLookup([]string{"c31e44e1-f858-436e-933e-59c642517860"})
 	if err != nil {
 		t.Fatal("Expected to not find any error")
 	}
 
-	if ip != "1.2.3.4" {
-		t.Errorf("Expected to return the host 1.2.3.4, but it was %s", ip)
+	if !reflect.DeepEqual(ips, []string{"1.2.3.4"}) {
+		t.Errorf("Expected to return the host 1.2.3.4, but it was %v", ips)
 	}
 }
 
 func TestGlobalLookupByUnitName(t *testing.T) {
 	cAPI = newFakeRegistryForSsh()
 
-	ip, err := globalMachineLookup([]string{"j1"})
+	ips, err := globalMachineLookup([]string{"j1"})
 	if err != nil {
 		t.Fatal("Expected to not find any error")
 	}
 
-	if ip != "1.2.3.4" {
-		t.Errorf("Expected to return the host 1.2.3.4, but it was %s", ip)
+	if !reflect.DeepEqual(ips, []string{"1.2.3.4"}) {
+		t.Errorf("Expected to return the host 1.2.3.4, but it was %v", ips)
 	}
 }
 
@@ -144,3 +145,27 @@ func TestGlobalLookupWithAmbiguousArgument(t *testing.T) {
 		t.Fatal("Expected to find an error with an ambiguous argument")
 	}
 }
+
+func TestSshFindMachineID(t *testing.T) {
+	cAPI = newFakeRegistryForSsh()
+
+	for i, tt := range []struct {
+		lookup string
+		machID string
+		ok     bool
+	}{
+		{"c31e44e1", "c31e44e1-f858-436e-933e-59c642517860", true},
+		{"j2.service", "595989bb-cbb7-49ce-8726-722d6e157b4e", true},
+		// both a machine and a unit
+		{"hello.service", "", false},
+		{"asdf", "", false},
+	} {
+		machID, err := globalMachineIDLookup(tt.lookup)
+		if (err == nil) != tt.ok {
+			t.Errorf("case %d: unexpected error %v", i, err)
+		}
+		if machID != tt.machID {
+			t.Errorf("case %d: expected machine %q, got %q", i, tt.machID, machID)
+		}
+	}
+}
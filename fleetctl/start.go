@@ -10,7 +10,7 @@ var (
 	cmdStartUnit = &Command{
 		Name:    "start",
 		Summary: "Instruct systemd to start one or more units in the cluster, first submitting and loading if necessary.",
-		Usage:   "[--no-block|--block-attempts=N] UNIT...",
+		Usage:   "[--no-block|--block-attempts=N] [-l SELECTOR] [--label KEY=VALUE,...] UNIT...",
 		Description: `Start one or many units on the cluster. Select units to start by glob matching
 for units in the current working directory or matching names of previously
 submitted units.
@@ -27,6 +27,15 @@ Start a single unit:
 Start an entire directory of units with glob matching:
 	fleetctl start myservice/*
 
+Start all previously submitted instances of a template unit:
+	fleetctl start 'web@*'
+
+Start all previously submitted units labeled app=web:
+	fleetctl start -l app=web
+
+Submit and start a unit labeled as belonging to the web app:
+	fleetctl start --label app=web,env=staging web.service
+
 You may filter suitable hosts based on metadata provided by the machine.
 Machine metadata is located in the fleet configuration file.`,
 		Run: runStartUnit,
@@ -37,9 +46,20 @@ func init() {
 	cmdStartUnit.Flags.BoolVar(&sharedFlags.Sign, "sign", false, "DEPRECATED - this option cannot be used")
 	cmdStartUnit.Flags.IntVar(&sharedFlags.BlockAttempts, "block-attempts", 0, "Wait until the units are launched, performing up to N attempts before giving up. A value of 0 indicates no limit. Does not apply to global units.")
 	cmdStartUnit.Flags.BoolVar(&sharedFlags.NoBlock, "no-block", false, "Do not wait until the units have launched before exiting. Always the case for global units.")
+	addSelectorFlag(cmdStartUnit)
+	addLabelFlag(cmdStartUnit)
 }
 
 func runStartUnit(args []string) (exit int) {
+	args, err := expandUnitGlobs(args)
+	if err == nil {
+		args, err = selectUnits(args)
+	}
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	if err := lazyCreateUnits(args); err != nil {
 		stderr("Error creating units: %v", err)
 		return 1
@@ -10,7 +10,7 @@ import (
 var cmdStatusUnits = &Command{
 	Name:    "status",
 	Summary: "Output the status of one or more units in the cluster",
-	Usage:   "UNIT...",
+	Usage:   "[--output=table|json|yaml] [--history [--no-legend] [-l|--full]] UNIT...",
 	Description: `Output the status of one or more units currently running in the cluster.
 Supports glob matching of units in the current working directory or matches
 previously started units.
@@ -21,11 +21,54 @@ Show status of a single unit:
 Show status of an entire directory with glob matching:
 fleetctl status myservice/*
 
-This command does not work with global units.`,
+Print the state fleet knows about a unit as JSON, without connecting to the
+machine running it:
+	fleetctl status --output=json foo.service
+
+Print the latest events of a unit, such as where it was scheduled and how its
+state changed, oldest first. Up to 50 events are kept per unit, even after the
+unit is destroyed:
+	fleetctl status --history foo.service
+
+When machines refused to run a unit, e.g. because it does not fit on them or
+they are cordoned, the reasons they last gave are printed instead. Otherwise,
+except with --history, this command does not work with global units.
+
+A unit rescheduled too often, e.g. because it crashes on every machine it lands
+on, is reported in backoff until the engine tries to schedule it again.`,
 	Run: runStatusUnits,
 }
 
+var flagStatusHistory bool
+
+func init() {
+	addOutputFlag(cmdStatusUnits)
+	cmdStatusUnits.Flags.BoolVar(&flagStatusHistory, "history", false, "Print the latest events of the units instead of their status")
+	cmdStatusUnits.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output. Only used with --history.")
+	cmdStatusUnits.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+	cmdStatusUnits.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers). Only used with --history.")
+}
+
+type statusOutput struct {
+	Name         string            `json:"name"`
+	DesiredState string            `json:"desiredState"`
+	CurrentState string            `json:"currentState"`
+	MachineID    string            `json:"machineID"`
+	BackoffUntil string            `json:"backoffUntil,omitempty"`
+	States       []unitStateOutput `json:"states"`
+}
+
 func runStatusUnits(args []string) (exit int) {
+	structured, err := structuredOutput()
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
+	if flagStatusHistory {
+		return printStatusHistory(args, structured)
+	}
+
 	units, err := cAPI.Units()
 	if err != nil {
 		stderr("Error retrieving unit: %v", err)
@@ -45,29 +88,162 @@ func runStatusUnits(args []string) (exit int) {
 		name := unitNameMangle(arg)
 		names[i] = name
 
-		u, ok := uMap[name]
-		if !ok {
+		if _, ok := uMap[name]; !ok {
 			stderr("Unit %s does not exist.", name)
 			return 1
-		} else if suToGlobal(*u) {
-			stderr("Unable to determine status of global unit %s.", name)
-			return 1
-		} else if job.JobState(u.CurrentState) == job.JobStateInactive {
-			stderr("Unit %s does not appear to be loaded.", name)
-			return 1
 		}
 	}
 
+	if structured {
+		return printStatusStructured(names, uMap)
+	}
+
+	states, err := cAPI.UnitStates()
+	if err != nil {
+		stderr("Error retrieving unit states: %v", err)
+		return 1
+	}
+
 	for i, name := range names {
+		u := uMap[name]
+		refused := refusedUnitStates(name, states)
+
 		// This extra newline is here to match systemctl status output
 		if i != 0 {
 			fmt.Printf("\n")
 		}
 
-		cmd := fmt.Sprintf("systemctl status -l %s", name)
-		if exit := runCommand(cmd, uMap[name].MachineID); exit != 0 {
+		if !suToGlobal(*u) && u.MachineID == "" {
+			rej, err := cAPI.UnitRejections(name)
+			if err != nil {
+				stderr("Error retrieving scheduling problems of unit %s: %v", name, err)
+				return 1
+			}
+			if rej.BackoffUntil != "" {
+				stdout("Unit %s is in backoff until %s: %s.", name, rej.BackoffUntil, rej.Reason)
+				exit = 1
+				continue
+			}
+		}
+
+		if suToGlobal(*u) || job.JobState(u.CurrentState) == job.JobStateInactive {
+			if len(refused) == 0 {
+				if suToGlobal(*u) {
+					stderr("Unable to determine status of global unit %s.", name)
+				} else {
+					stderr("Unit %s does not appear to be loaded.", name)
+				}
+				return 1
+			}
+			printRefusals(name, refused)
+			exit = 1
+			continue
+		}
+
+		for _, us := range refused {
+			if us.MachineID == u.MachineID {
+				printRefusals(name, []*schema.UnitState{us})
+				return 1
+			}
+		}
+
+		cmd := fmt.Sprintf("systemctl status -l %s", systemdUnitName(name))
+		if exit := runCommand(cmd, u.MachineID); exit != 0 {
 			break
 		}
 	}
 	return
 }
+
+// refusedUnitStates returns the states published for the named Unit by
+// machines that refused to run it, which record why
+func refusedUnitStates(name string, states []*schema.UnitState) []*schema.UnitState {
+	var refused []*schema.UnitState
+	for _, us := range states {
+		if us.Name == name && us.SystemdLoadState == "not-loaded" && us.Reason != "" {
+			refused = append(refused, us)
+		}
+	}
+	return refused
+}
+
+// printRefusals prints why machines last refused to run the named Unit
+func printRefusals(name string, refused []*schema.UnitState) {
+	stdout("Unit %s is not loaded:", name)
+	for _, us := range refused {
+		stdout("\t%s", us.Reason)
+	}
+}
+
+// printStatusStructured prints the Units with the given names, along with
+// the states published for them, from the state recorded in the registry
+func printStatusStructured(names []string, uMap map[string]*schema.Unit) (exit int) {
+	states, err := cAPI.UnitStates()
+	if err != nil {
+		stderr("Error retrieving unit states: %v", err)
+		return 1
+	}
+
+	items := make([]statusOutput, len(names))
+	for i, name := range names {
+		u := uMap[name]
+		items[i] = statusOutput{
+			Name:         u.Name,
+			DesiredState: u.DesiredState,
+			CurrentState: u.CurrentState,
+			MachineID:    u.MachineID,
+			States:       []unitStateOutput{},
+		}
+		for _, us := range states {
+			if us.Name == name {
+				items[i].States = append(items[i].States, newUnitStateOutput(us))
+			}
+		}
+		if !suToGlobal(*u) && u.MachineID == "" {
+			rej, err := cAPI.UnitRejections(name)
+			if err != nil {
+				stderr("Error retrieving scheduling problems of unit %s: %v", name, err)
+				return 1
+			}
+			items[i].BackoffUntil = rej.BackoffUntil
+		}
+	}
+
+	if err := printStructured(items); err != nil {
+		stderr("Error printing unit status: %v", err)
+		return 1
+	}
+	return
+}
+
+// printStatusHistory prints the latest events of the named Units, which
+// need not exist anymore
+func printStatusHistory(args []string, structured bool) (exit int) {
+	events := []*schema.Event{}
+	for _, arg := range args {
+		name := unitNameMangle(arg)
+		history, err := cAPI.UnitHistory(name)
+		if err != nil {
+			stderr("Error retrieving history of unit %s: %v", name, err)
+			return 1
+		}
+		events = append(events, history...)
+	}
+
+	if structured {
+		if err := printStructured(events); err != nil {
+			stderr("Error printing unit history: %v", err)
+			return 1
+		}
+		return
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "INDEX\tTIME\tTYPE\tUNIT\tMACHINE\tREASON")
+	}
+	for _, ev := range events {
+		fmt.Fprintln(out, formatEvent(ev, sharedFlags.Full))
+	}
+	out.Flush()
+	return
+}
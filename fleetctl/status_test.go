@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestRefusedUnitStates(t *testing.T) {
+	states := []*schema.UnitState{
+		{Name: "foo.service", MachineID: "XXX", SystemdLoadState: "loaded", SystemdSubState: "running"},
+		{Name: "foo.service", MachineID: "YYY", SystemdLoadState: "not-loaded", SystemdSubState: "rejected", Reason: "unit rejected by Machine(YYY): machine is cordoned"},
+		{Name: "foo.service", MachineID: "ZZZ", SystemdLoadState: "not-loaded"},
+		{Name: "bar.service", MachineID: "YYY", SystemdLoadState: "not-loaded", SystemdSubState: "skipped", Reason: "global unit skipped on Machine(YYY): insufficient memory"},
+	}
+
+	refused := refusedUnitStates("foo.service", states)
+	if len(refused) != 1 || refused[0].MachineID != "YYY" {
+		t.Errorf("Unexpected refused states: %v", refused)
+	}
+	if refused := refusedUnitStates("baz.service", states); len(refused) != 0 {
+		t.Errorf("Unexpected refused states: %v", refused)
+	}
+}
+
+func TestRunStatusUnitsRefused(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		{Name: "rejected.service", TargetMachineID: "XXX", TargetState: job.JobStateLaunched},
+		{Name: "skipped.service", TargetState: job.JobStateLaunched, Unit: *newUnitFile(t, "[X-Fleet]\nGlobal=true")},
+		{Name: "global.service", TargetState: job.JobStateLaunched, Unit: *newUnitFile(t, "[X-Fleet]\nGlobal=true")},
+	})
+	reg.SetUnitStates([]unit.UnitState{
+		{UnitName: "rejected.service", MachineID: "XXX", LoadState: "not-loaded", ActiveState: "inactive", SubState: "rejected", Reason: "unit rejected by Machine(XXX): insufficient memory"},
+		{UnitName: "skipped.service", MachineID: "XXX", LoadState: "not-loaded", ActiveState: "inactive", SubState: "skipped", Reason: "global unit skipped on Machine(XXX): insufficient memory"},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		args []string
+		exit int
+	}{
+		{[]string{"rejected.service"}, 1},
+		{[]string{"skipped.service"}, 1},
+		{[]string{"global.service"}, 1},
+		{[]string{"missing.service"}, 1},
+	} {
+		if exit := runStatusUnits(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+}
+
+func TestRunStatusUnitsBackoff(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		{Name: "flapping.service", TargetState: job.JobStateLaunched},
+	})
+	until := time.Date(2015, time.March, 1, 12, 0, 0, 0, time.UTC)
+	reg.SaveUnitRejections("flapping.service", registry.UnitRejections{
+		Time:         until.Add(-time.Minute),
+		Reason:       "unit was rescheduled too often",
+		BackoffUntil: &until,
+	}, time.Minute)
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	if exit := runStatusUnits([]string{"flapping.service"}); exit != 1 {
+		t.Errorf("expected exit 1, got %d", exit)
+	}
+
+	sharedFlags.Output = outputJSON
+	defer func() { sharedFlags.Output = outputTable }()
+	lines := runWithOutput(t, func([]string) int { return runStatusUnits([]string{"flapping.service"}) })
+	if want := `"backoffUntil": "2015-03-01T12:00:00Z",`; !strings.Contains(strings.Join(lines, "\n"), want) {
+		t.Errorf("Output lacks %s:\n%s", want, strings.Join(lines, "\n"))
+	}
+}
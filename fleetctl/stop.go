@@ -10,7 +10,7 @@ import (
 var cmdStopUnit = &Command{
 	Name:    "stop",
 	Summary: "Instruct systemd to stop one or more units in the cluster.",
-	Usage:   "[--no-block|--block-attempts=N] UNIT...",
+	Usage:   "[--no-block|--block-attempts=N] [-l SELECTOR] UNIT...",
 	Description: `Stop one or more units from running in the cluster, but allow them to be
 started again in the future.
 
@@ -28,16 +28,32 @@ Stop a single unit:
 	fleetctl stop foo.service
 
 Stop an entire directory of units with glob matching, without waiting:
-	fleetctl --no-block stop myservice/*`,
+	fleetctl --no-block stop myservice/*
+
+Stop all instances of a template unit:
+	fleetctl stop 'web@*'
+
+Stop all units labeled app=web and env=staging:
+	fleetctl stop -l app=web,env=staging`,
 	Run: runStopUnit,
 }
 
 func init() {
 	cmdStopUnit.Flags.IntVar(&sharedFlags.BlockAttempts, "block-attempts", 0, "Wait until the units are stopped, performing up to N attempts before giving up. A value of 0 indicates no limit. Does not apply to global units.")
 	cmdStopUnit.Flags.BoolVar(&sharedFlags.NoBlock, "no-block", false, "Do not wait until the units have stopped before exiting. Always the case for global units.")
+	addSelectorFlag(cmdStopUnit)
 }
 
 func runStopUnit(args []string) (exit int) {
+	args, err := expandUnitGlobs(args)
+	if err == nil {
+		args, err = selectUnits(args)
+	}
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	units, err := findUnits(args)
 	if err != nil {
 		stderr("%v", err)
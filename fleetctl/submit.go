@@ -1,30 +1,100 @@
 package main
 
-var cmdSubmitUnit = &Command{
-	Name:    "submit",
-	Summary: "Upload one or more units to the cluster without starting them",
-	Usage:   "UNIT...",
-	Description: `Upload one or more units to the cluster without starting them. Useful
+import (
+	"fmt"
+	"os"
+
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	submitFlags = struct {
+		Replace bool
+	}{}
+
+	cmdSubmitUnit = &Command{
+		Name:    "submit",
+		Summary: "Upload one or more units to the cluster without starting them",
+		Usage:   "[--replace] [--label KEY=VALUE,...] UNIT...",
+		Description: `Upload one or more units to the cluster without starting them. Useful
 for validating units before they are started.
 
 This operation is idempotent; if a named unit already exists in the cluster, it will not be resubmitted.
+With --replace, units whose local unit file differs from the one in the cluster
+are updated in place instead, keeping their desired state. Running units pick
+up the new unit file according to its UpdatePolicy: they are restarted on their
+machine (restart, the default), scheduled again (reschedule), or left running
+the earlier unit file until they are next loaded (manual).
 
 Submit a single unit:
 	fleetctl submit foo.service
 
 Submit a directory of units with glob matching:
-	fleetctl submit myservice/*`,
-	Run: runSubmitUnits,
-}
+	fleetctl submit myservice/*
+
+Update a unit with its changed unit file:
+	fleetctl submit --replace foo.service
+
+Submit units labeled as belonging to the web app, to select them by later:
+	fleetctl submit --label app=web,env=staging web@.service web-lb.service`,
+		Run: runSubmitUnits,
+	}
+)
 
 func init() {
 	cmdSubmitUnit.Flags.BoolVar(&sharedFlags.Sign, "sign", false, "DEPRECATED - this option cannot be used")
+	cmdSubmitUnit.Flags.BoolVar(&submitFlags.Replace, "replace", false, "Update units whose local unit file differs from the one in the cluster.")
+	addLabelFlag(cmdSubmitUnit)
 }
 
 func runSubmitUnits(args []string) (exit int) {
+	if submitFlags.Replace {
+		if err := replaceUnitFiles(args); err != nil {
+			stderr("Error replacing units: %v", err)
+			return 1
+		}
+	}
 	if err := lazyCreateUnits(args); err != nil {
 		stderr("Error creating units: %v", err)
 		exit = 1
 	}
 	return
 }
+
+// replaceUnitFiles replaces the unit file of each unit in the cluster that
+// differs from the local unit file of the same name. Units not in the
+// cluster yet, or without a local unit file, are left alone.
+func replaceUnitFiles(args []string) error {
+	for _, arg := range args {
+		if _, err := os.Stat(arg); os.IsNotExist(err) {
+			continue
+		}
+		name := unitNameMangle(arg)
+
+		eu, err := cAPI.Unit(name)
+		if err != nil {
+			return fmt.Errorf("error retrieving Unit(%s) from Registry: %v", name, err)
+		}
+		if eu == nil {
+			continue
+		}
+
+		uf, err := getUnitFromFile(arg)
+		if err != nil {
+			return fmt.Errorf("failed getting Unit(%s) from file: %v", name, err)
+		}
+		if schema.MapSchemaUnitOptionsToUnitFile(eu.Options).Hash() == uf.Hash() {
+			continue
+		}
+
+		u, err := newSchemaUnit(name, uf, "")
+		if err != nil {
+			return err
+		}
+		if err := cAPI.ReplaceUnit(u); err != nil {
+			return fmt.Errorf("failed replacing unit %s: %v", name, err)
+		}
+		stdout("Replaced unit file of %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/fleet/api"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+var cmdSubmitStack = &Command{
+	Name:    "submit-stack",
+	Summary: "Submit and start a stack of units scheduled together",
+	Usage:   "STACK_FILE",
+	Description: `Submit and start all units of a stack, described by a stack file. The engine
+schedules the units of a stack all at once: as long as any of them cannot be
+placed, given the resources, conflicts and other requirements of all of them,
+none of them are scheduled.
+
+A stack file names the stack and lists its unit files, relative to the stack
+file. Template units are given the number of instances to create, numbered 1
+through COUNT:
+
+	name: web
+	units:
+	  - file: web@.service
+	    count: 3
+	  - file: db.service
+
+Without a name, the stack is named after the stack file. None of the units of
+the stack may exist yet. If submitting any unit fails, the units submitted so
+far are destroyed again.
+
+Submit and start a stack:
+	fleetctl submit-stack web.yaml`,
+	Run: runSubmitStack,
+}
+
+// stackFile describes a Stack to submit
+type stackFile struct {
+	Name  string
+	Units []stackFileUnit
+}
+
+type stackFileUnit struct {
+	File string
+	// Count is the number of instances of a template unit, or zero if
+	// not given
+	Count int
+}
+
+func runSubmitStack(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One stack file must be provided.")
+		return 1
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		stderr("Error reading stack file: %v", err)
+		return 1
+	}
+	sf, err := parseStackFile(f)
+	f.Close()
+	if err != nil {
+		stderr("Error parsing stack file %s: %v", args[0], err)
+		return 1
+	}
+	if sf.Name == "" {
+		base := filepath.Base(args[0])
+		sf.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	files, err := stackUnitFiles(sf, filepath.Dir(args[0]))
+	if err != nil {
+		stderr("Error reading units of stack %s: %v", sf.Name, err)
+		return 1
+	}
+
+	s := schema.Stack{Name: sf.Name}
+	for _, su := range files {
+		s.Units = append(s.Units, su.name)
+	}
+	if err := api.ValidateStack(&s); err != nil {
+		stderr("Invalid stack %s: %v", sf.Name, err)
+		return 1
+	}
+
+	for _, name := range s.Units {
+		u, err := cAPI.Unit(name)
+		if err != nil {
+			stderr("Error retrieving Unit(%s) from Registry: %v", name, err)
+			return 1
+		} else if u != nil {
+			stderr("Unit %s already exists, units of a stack must be new.", name)
+			return 1
+		}
+	}
+
+	stacks, err := cAPI.Stacks()
+	if err != nil {
+		stderr("Error retrieving stacks: %v", err)
+		return 1
+	}
+	if err := api.StackOverlap(&s, stacks); err != nil {
+		stderr("Error creating stack %s: %v", sf.Name, err)
+		return 1
+	}
+
+	// The Stack is recorded first, so that the engine holds off
+	// scheduling any of its units until all of them are submitted
+	if err := cAPI.CreateStack(&s); err != nil {
+		stderr("Error creating stack %s: %v", sf.Name, err)
+		return 1
+	}
+
+	for i, su := range files {
+		if _, err := createUnitWithState(su.name, su.uf, job.JobStateLaunched); err != nil {
+			stderr("Error creating units of stack %s: %v", sf.Name, err)
+			for _, created := range files[:i] {
+				cAPI.DestroyUnit(created.name)
+			}
+			cAPI.DestroyStack(sf.Name)
+			return 1
+		}
+	}
+
+	stdout("Submitted stack %s: %s", sf.Name, strings.Join(s.Units, " "))
+	return
+}
+
+type stackUnitFile struct {
+	name string
+	uf   *unit.UnitFile
+}
+
+// stackUnitFiles reads the unit files of the given stack file, resolved
+// relative to the given directory, instantiating template units
+func stackUnitFiles(sf *stackFile, dir string) ([]stackUnitFile, error) {
+	var files []stackUnitFile
+	for _, su := range sf.Units {
+		file := su.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		uf, err := getUnitFromFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting unit from file %s: %v", su.File, err)
+		}
+
+		name := unitNameMangle(path.Base(su.File))
+		uni := unit.NewUnitNameInfo(name)
+		if uni == nil || !uni.IsTemplate() {
+			if su.Count > 1 {
+				return nil, fmt.Errorf("unit %s is not a template, so only one can be run", name)
+			}
+			files = append(files, stackUnitFile{name, uf})
+			continue
+		}
+
+		if su.Count < 1 {
+			return nil, fmt.Errorf("template unit %s needs a count of instances", name)
+		}
+		suffix := name[len(uni.Name):]
+		for n := 1; n <= su.Count; n++ {
+			files = append(files, stackUnitFile{fmt.Sprintf("%s@%d%s", uni.Prefix, n, suffix), uf})
+		}
+	}
+	return files, nil
+}
+
+// parseStackFile parses a stack file, a YAML document holding the name of
+// the stack and its list of units. Only the subset of YAML used by stack
+// files is supported: plain or quoted scalars, and a list of units given
+// either as maps with file and count keys or as plain file names.
+func parseStackFile(r io.Reader) (*stackFile, error) {
+	var sf stackFile
+	var inUnits bool
+	var cur *stackFileUnit
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := stripYAMLComment(scanner.Text())
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		line = strings.TrimSpace(line)
+
+		if indent == 0 && !strings.HasPrefix(line, "-") {
+			key, val, ok := splitYAMLPair(line)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key: value", n)
+			}
+			inUnits, cur = false, nil
+			switch key {
+			case "name":
+				sf.Name = val
+			case "units":
+				if val != "" {
+					return nil, fmt.Errorf("line %d: units must be a list", n)
+				}
+				inUnits = true
+			default:
+				return nil, fmt.Errorf("line %d: unknown key %q", n, key)
+			}
+			continue
+		}
+
+		if !inUnits {
+			return nil, fmt.Errorf("line %d: unexpected indentation", n)
+		}
+
+		if strings.HasPrefix(line, "-") {
+			sf.Units = append(sf.Units, stackFileUnit{})
+			cur = &sf.Units[len(sf.Units)-1]
+			line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+			if _, _, ok := splitYAMLPair(line); !ok {
+				// a plain file name
+				cur.File = unquoteYAML(line)
+				continue
+			}
+		} else if cur == nil {
+			return nil, fmt.Errorf("line %d: expected a list item", n)
+		}
+
+		key, val, _ := splitYAMLPair(line)
+		switch key {
+		case "file":
+			cur.File = val
+		case "count":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("line %d: count must be a positive integer", n)
+			}
+			cur.Count = count
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", n, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, su := range sf.Units {
+		if su.File == "" {
+			return nil, fmt.Errorf("unit %d has no file", i+1)
+		}
+	}
+	return &sf, nil
+}
+
+// splitYAMLPair splits a "key: value" line, unquoting the value
+func splitYAMLPair(line string) (key, val string, ok bool) {
+	i := strings.Index(line, ":")
+	if i < 1 || (i+1 < len(line) && line[i+1] != ' ') {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), unquoteYAML(strings.TrimSpace(line[i+1:])), true
+}
+
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// stripYAMLComment removes a trailing comment from a line, ignoring quoting
+// for simplicity as stack files hold no values containing " #"
+func stripYAMLComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if i := strings.Index(line, " #"); i != -1 {
+		line = line[:i]
+	}
+	return strings.TrimRight(line, " \t")
+}
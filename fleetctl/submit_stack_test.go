@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestParseStackFile(t *testing.T) {
+	contents := `# the web stack
+---
+name: "web"
+units:
+  - file: web@.service
+    count: 3 # one per zone
+  - db.service
+  - file: 'cache.service'
+`
+	got, err := parseStackFile(strings.NewReader(contents))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &stackFile{
+		Name: "web",
+		Units: []stackFileUnit{
+			{File: "web@.service", Count: 3},
+			{File: "db.service"},
+			{File: "cache.service"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected stack file: got %#v, want %#v", got, want)
+	}
+
+	for i, bad := range []string{
+		"name web",
+		"units: web.service",
+		"size: 3",
+		"  - web.service",
+		"units:\n  - file: web@.service\n    count: 0",
+		"units:\n  - count: 2",
+		"units:\n  - file: web.service\n    owner: ops",
+	} {
+		if _, err := parseStackFile(strings.NewReader(bad)); err == nil {
+			t.Errorf("case %d: expected error parsing %q", i, bad)
+		}
+	}
+}
+
+func TestRunSubmitAndDestroyStack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleetctl-stack")
+	if err != nil {
+		t.Fatalf("Failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+			t.Fatalf("Failed writing %s: %v", name, err)
+		}
+		return file
+	}
+	write("web@.service", "[Service]\nExecStart=/bin/web")
+	write("db.service", "[Service]\nExecStart=/bin/db")
+	stack := write("web.yaml", "units:\n  - file: web@.service\n    count: 2\n  - db.service\n")
+
+	reg := registry.NewFakeRegistry()
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	if exit := runSubmitStack([]string{stack}); exit != 0 {
+		t.Fatalf("Unexpected exit %d submitting stack", exit)
+	}
+
+	s, _ := reg.Stack("web")
+	want := []string{"web@1.service", "web@2.service", "db.service"}
+	if s == nil || !reflect.DeepEqual(s.Units, want) {
+		t.Fatalf("Unexpected stack %#v", s)
+	}
+	for _, name := range want {
+		u, _ := reg.Unit(name)
+		if u == nil || u.TargetState != job.JobStateLaunched {
+			t.Errorf("Expected Unit(%s) to be launched, got %#v", name, u)
+		}
+	}
+
+	// units of a stack must be new
+	if exit := runSubmitStack([]string{stack}); exit != 1 {
+		t.Errorf("Expected exit 1 resubmitting stack, got %d", exit)
+	}
+
+	if exit := runDestroyStack([]string{"web"}); exit != 0 {
+		t.Fatalf("Unexpected exit %d destroying stack", exit)
+	}
+	if s, _ := reg.Stack("web"); s != nil {
+		t.Errorf("Expected stack to be destroyed, got %#v", s)
+	}
+	if units, _ := reg.Units(); len(units) != 0 {
+		t.Errorf("Expected units to be destroyed, got %v", units)
+	}
+
+	if exit := runDestroyStack([]string{"web"}); exit != 1 {
+		t.Errorf("Expected exit 1 destroying missing stack, got %d", exit)
+	}
+}
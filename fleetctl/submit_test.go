@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestRunSubmitUnitsReplace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleetctl-submit")
+	if err != nil {
+		t.Fatalf("Failed creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "hello.service")
+	if err := ioutil.WriteFile(file, []byte("[Service]\nExecStart=/bin/hello --v2\n"), 0644); err != nil {
+		t.Fatalf("Failed writing unit file: %v", err)
+	}
+
+	uf, err := unit.NewUnitFile("[Service]\nExecStart=/bin/hello\n")
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit file: %v", err)
+	}
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		job.Job{Name: "hello.service", Unit: *uf, TargetState: job.JobStateLaunched},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	// without --replace, the unit in the cluster is kept
+	if exit := runSubmitUnits([]string{file}); exit != 0 {
+		t.Fatalf("Unexpected exit %d submitting unit", exit)
+	}
+	if u, _ := reg.Unit("hello.service"); u.Unit.Hash() != uf.Hash() {
+		t.Errorf("Expected unit file to be kept")
+	}
+
+	submitFlags.Replace = true
+	defer func() { submitFlags.Replace = false }()
+	if exit := runSubmitUnits([]string{file}); exit != 0 {
+		t.Fatalf("Unexpected exit %d replacing unit", exit)
+	}
+	local, err := getUnitFromFile(file)
+	if err != nil {
+		t.Fatalf("Unexpected error reading unit file: %v", err)
+	}
+	u, _ := reg.Unit("hello.service")
+	if u.Unit.Hash() != local.Hash() {
+		t.Errorf("Expected unit file to be replaced")
+	}
+	if u.TargetState != job.JobStateLaunched {
+		t.Errorf("Expected target state to be kept, got %s", u.TargetState)
+	}
+}
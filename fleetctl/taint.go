@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/coreos/fleet/machine"
+)
+
+var cmdTaintMachine = &Command{
+	Name:    "taint",
+	Summary: "Keep units not tolerating a taint off a machine",
+	Usage:   "MACHINE KEY[=VALUE]:NoSchedule...",
+	Description: `Set taints on a machine. The engine does not schedule units to a tainted machine
+unless they tolerate each of its taints with a matching Tolerates= option in
+their [X-Fleet] section. Units already scheduled to the machine stay there.
+Taints persist until removed, and replace earlier taints with the same key.
+
+Reserve a machine for database units:
+	fleetctl taint 2444264c dedicated=db:NoSchedule
+
+Remove a taint by appending a dash to its key:
+	fleetctl taint 2444264c dedicated-
+
+MACHINE may be any unique prefix of a machine ID. Use list-machines with
+--fields=machine,taints to show the taints of all machines.`,
+	Run: runTaintMachine,
+}
+
+func runTaintMachine(args []string) (exit int) {
+	if len(args) < 2 {
+		stderr("One machine and at least one taint must be provided.")
+		return 1
+	}
+
+	ms, err := findMachine(args[0])
+	if err != nil {
+		stderr("Unable to find machine %s: %v", args[0], err)
+		return 1
+	}
+
+	var taints []machine.Taint
+	var removed []string
+	for _, arg := range args[1:] {
+		if strings.HasSuffix(arg, "-") {
+			removed = append(removed, strings.TrimSuffix(arg, "-"))
+			continue
+		}
+		t, err := machine.ParseTaint(arg)
+		if err != nil {
+			stderr("Invalid taint: %v", err)
+			return 1
+		}
+		taints = append(taints, t)
+	}
+
+	for _, t := range taints {
+		if err := cAPI.TaintMachine(ms.ID, t); err != nil {
+			stderr("Error tainting machine %s with %s: %v", ms.ID, t, err)
+			return 1
+		}
+	}
+	for _, key := range removed {
+		if err := cAPI.UntaintMachine(ms.ID, key); err != nil {
+			stderr("Error removing taint %s of machine %s: %v", key, ms.ID, err)
+			return 1
+		}
+	}
+
+	stdout("Updated taints of machine %s", ms.ID)
+	return
+}
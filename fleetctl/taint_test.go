@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunTaintMachine(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		newMachineState("c31e44e1-f858-436e-933e-59c642517860", "1.2.3.4", nil),
+		newMachineState("c31e5555-cbb7-49ce-8726-722d6e157b4e", "5.6.7.8", nil),
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	dedicated := machine.Taint{Key: "dedicated", Value: "db", Effect: machine.TaintEffectNoSchedule}
+	maintenance := machine.Taint{Key: "maintenance", Effect: machine.TaintEffectNoSchedule}
+	for i, tt := range []struct {
+		args []string
+		exit int
+		want []machine.Taint
+	}{
+		// ambiguous machine prefix
+		{[]string{"c31e", "maintenance:NoSchedule"}, 1, nil},
+		{[]string{"c31e44"}, 1, nil},
+		{[]string{"c31e44", "dedicated=db"}, 1, nil},
+		{[]string{"c31e44", "dedicated=db:NoSchedule", "maintenance:NoSchedule"}, 0, []machine.Taint{dedicated, maintenance}},
+		{[]string{"c31e44", "maintenance-"}, 0, []machine.Taint{dedicated}},
+	} {
+		if exit := runTaintMachine(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+
+		machines, _ := reg.Machines()
+		if !reflect.DeepEqual(machines[0].Taints, tt.want) {
+			t.Errorf("case %d: unexpected taints: got %v, want %v", i, machines[0].Taints, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	cmdTopMachines = &Command{
+		Name:    "top-machines",
+		Summary: "Show the resources used on each machine in the cluster",
+		Usage:   "[-l|--full] [--no-legend]",
+		Description: `Lists the CPU and memory actually used by the units on each machine, next to
+the amount reserved by those units and the total the machine has. CPU is
+measured in hundredths of a core, memory in MB. Machines are listed by CPU
+usage, busiest first.`,
+		Run: runTopMachines,
+	}
+	cmdTopUnits = &Command{
+		Name:    "top-units",
+		Summary: "Show the resources used by each unit in the cluster",
+		Usage:   "[-l|--full] [--no-legend]",
+		Description: `Lists the CPU and memory actually used by each unit running in the cluster,
+next to the amount it has reserved. CPU is measured in hundredths of a core,
+memory in MB. Units are listed by CPU usage, busiest first.`,
+		Run: runTopUnits,
+	}
+)
+
+func init() {
+	for _, cmd := range []*Command{cmdTopMachines, cmdTopUnits} {
+		cmd.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+		cmd.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+		cmd.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+	}
+}
+
+// usageRow is a line of top-machines or top-units output
+type usageRow struct {
+	fields   []string
+	used     resource.ResourceTuple
+	reserved resource.ResourceTuple
+}
+
+type usageRowsByCPU []usageRow
+
+func (r usageRowsByCPU) Len() int           { return len(r) }
+func (r usageRowsByCPU) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r usageRowsByCPU) Less(i, j int) bool { return r[i].used.Cores > r[j].used.Cores }
+
+func runTopMachines(args []string) (exit int) {
+	machines, err := cAPI.Machines()
+	if err != nil {
+		stderr("Error retrieving list of active machines: %v", err)
+		return 1
+	}
+
+	states, err := cAPI.UnitStates()
+	if err != nil {
+		stderr("Error retrieving list of units from repository: %v", err)
+		return 1
+	}
+
+	used := make(map[string]resource.ResourceTuple)
+	for _, us := range states {
+		used[us.MachineID] = resource.Sum(used[us.MachineID], unitStateUsage(us))
+	}
+
+	var rows []usageRow
+	for _, ms := range machines {
+		row := usageRow{
+			fields:   []string{machineIDLegend(ms, sharedFlags.Full)},
+			used:     used[ms.ID],
+			reserved: ms.AllocatedResources,
+		}
+		row.fields = append(row.fields,
+			fmt.Sprintf("%d/%d/%d", row.used.Cores, row.reserved.Cores, ms.TotalResources.Cores),
+			fmt.Sprintf("%dMB/%dMB/%dMB", row.used.Memory, row.reserved.Memory, ms.TotalResources.Memory),
+		)
+		rows = append(rows, row)
+	}
+
+	printUsageRows([]string{"MACHINE", "CPU(USED/RESERVED/TOTAL)", "MEMORY(USED/RESERVED/TOTAL)"}, rows)
+	return
+}
+
+func runTopUnits(args []string) (exit int) {
+	units, err := cAPI.Units()
+	if err != nil {
+		stderr("Error retrieving list of units from repository: %v", err)
+		return 1
+	}
+
+	states, err := cAPI.UnitStates()
+	if err != nil {
+		stderr("Error retrieving list of units from repository: %v", err)
+		return 1
+	}
+
+	reserved := make(map[string]resource.ResourceTuple)
+	for _, u := range units {
+		ju := job.Unit{Name: u.Name, Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options)}
+		reserved[u.Name] = ju.Resources()
+	}
+
+	var rows []usageRow
+	for _, us := range states {
+		row := usageRow{
+			used:     unitStateUsage(us),
+			reserved: reserved[us.Name],
+		}
+		row.fields = []string{
+			us.Name,
+			listUnitsFields["machine"](us, sharedFlags.Full),
+			fmt.Sprintf("%d/%d", row.used.Cores, row.reserved.Cores),
+			fmt.Sprintf("%dMB/%dMB", row.used.Memory, row.reserved.Memory),
+		}
+		rows = append(rows, row)
+	}
+
+	printUsageRows([]string{"UNIT", "MACHINE", "CPU(USED/RESERVED)", "MEMORY(USED/RESERVED)"}, rows)
+	return
+}
+
+func printUsageRows(legend []string, rows []usageRow) {
+	sort.Stable(usageRowsByCPU(rows))
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, strings.Join(legend, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(out, strings.Join(row.fields, "\t"))
+	}
+	out.Flush()
+}
+
+// unitStateUsage returns the resources a unit was last reported to use
+func unitStateUsage(us *schema.UnitState) resource.ResourceTuple {
+	return resource.ResourceTuple{Cores: int(us.UsedCPUUnits), Memory: int(us.UsedMemory)}
+}
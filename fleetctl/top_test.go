@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/unit"
+)
+
+func newFakeRegistryForTop(t *testing.T) registry.Registry {
+	reg := registry.NewFakeRegistry()
+
+	var machines []machine.MachineState
+	for _, id := range []string{"c31e44e1-f858-436e-933e-59c642517860", "595989bb-cbb7-49ce-8726-722d6e157b4e"} {
+		ms := newMachineState(id, "1.2.3.4", nil)
+		ms.TotalResources = resource.ResourceTuple{Cores: 400, Memory: 4096}
+		machines = append(machines, ms)
+	}
+	reg.SetMachines(machines)
+
+	uf, err := unit.NewUnitFile("[X-Fleet]\nCPUUnits=100\nMemoryReservation=512")
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit file: %v", err)
+	}
+	foo := job.NewJob("foo.service", *uf)
+	foo.TargetMachineID = machines[0].ID
+	foo.TargetState = job.JobStateLaunched
+	bar := job.NewJob("bar.service", unit.UnitFile{})
+	bar.TargetMachineID = machines[1].ID
+	bar.TargetState = job.JobStateLaunched
+	reg.SetJobs([]job.Job{*foo, *bar})
+
+	reg.SetUnitStates([]unit.UnitState{
+		unit.UnitState{UnitName: "foo.service", MachineID: machines[0].ID, UsedCPUUnits: 20, UsedMemory: 300},
+		unit.UnitState{UnitName: "bar.service", MachineID: machines[1].ID, UsedCPUUnits: 150, UsedMemory: 64},
+	})
+
+	return reg
+}
+
+func runWithOutput(t *testing.T, run func([]string) int) []string {
+	var buf bytes.Buffer
+	out.Init(&buf, 0, 8, 1, '\t', 0)
+	defer out.Init(os.Stdout, 0, 8, 1, '\t', 0)
+
+	if exit := run(nil); exit != 0 {
+		t.Fatalf("Unexpected exit %d", exit)
+	}
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestRunTopMachines(t *testing.T) {
+	cAPI = &client.RegistryClient{Registry: newFakeRegistryForTop(t)}
+	sharedFlags.NoLegend = true
+	defer func() { sharedFlags.NoLegend = false }()
+
+	lines := runWithOutput(t, runTopMachines)
+	want := []string{
+		"595989bb...\t150/0/400\t64MB/0MB/4096MB",
+		"c31e44e1...\t20/100/400\t300MB/512MB/4096MB",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRunTopUnits(t *testing.T) {
+	cAPI = &client.RegistryClient{Registry: newFakeRegistryForTop(t)}
+	machineStates = nil
+	sharedFlags.NoLegend = true
+	defer func() { sharedFlags.NoLegend = false }()
+
+	lines := runWithOutput(t, runTopUnits)
+	want := []string{
+		"bar.service\t595989bb.../1.2.3.4\t150/0\t64MB/0MB",
+		"foo.service\tc31e44e1.../1.2.3.4\t20/100\t300MB/512MB",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
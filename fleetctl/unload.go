@@ -11,7 +11,7 @@ var (
 	cmdUnloadUnit = &Command{
 		Name:    "unload",
 		Summary: "Unschedule one or more units in the cluster.",
-		Usage:   "UNIT...",
+		Usage:   "[-l SELECTOR] UNIT...",
 		Run:     runUnloadUnit,
 	}
 )
@@ -19,9 +19,16 @@ var (
 func init() {
 	cmdUnloadUnit.Flags.IntVar(&sharedFlags.BlockAttempts, "block-attempts", 0, "Wait until the units are inactive, performing up to N attempts before giving up. A value of 0 indicates no limit.")
 	cmdUnloadUnit.Flags.BoolVar(&sharedFlags.NoBlock, "no-block", false, "Do not wait until the units have become inactive before exiting.")
+	addSelectorFlag(cmdUnloadUnit)
 }
 
 func runUnloadUnit(args []string) (exit int) {
+	args, err := selectUnits(args)
+	if err != nil {
+		stderr("%v", err)
+		return 1
+	}
+
 	units, err := findUnits(args)
 	if err != nil {
 		stderr("%v", err)
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-semver/semver"
+
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/schema"
+)
+
+var (
+	cmdUpgradeAgents = &Command{
+		Name:    "upgrade-agents",
+		Summary: "Upgrade fleetd across the cluster",
+		Usage:   "--version=VERSION [--domain-key=KEY] | --status [--no-legend] [--full] | --cancel",
+		Description: `Restart the fleetd of every machine into the given version, one failure domain
+at a time. Each machine in turn stops accepting new units, restarts into the
+binary configured with upgrade_binary_path in fleet.conf and accepts units
+again once the new fleetd is up. Units keep running throughout.
+
+The failure domain of a machine is the value of its --domain-key metadata.
+Domains upgrade in the order of their names, each once all machines of the
+domains before it run the new version. Without --domain-key, machines upgrade
+one at a time.
+
+Upgrade the cluster one availability zone at a time:
+	fleetctl upgrade-agents --version=0.9.0 --domain-key=az
+
+Follow the progress of the upgrade:
+	fleetctl upgrade-agents --status
+
+A machine that fails to upgrade keeps running its current version and holds
+back the domains after it. Cancelling the upgrade leaves machines already
+upgraded as they are.`,
+		Run: runUpgradeAgents,
+	}
+
+	flagUpgradeVersion   string
+	flagUpgradeDomainKey string
+	flagUpgradeStatus    bool
+	flagUpgradeCancel    bool
+)
+
+func init() {
+	cmdUpgradeAgents.Flags.StringVar(&flagUpgradeVersion, "version", "", "Version of fleetd to upgrade to.")
+	cmdUpgradeAgents.Flags.StringVar(&flagUpgradeDomainKey, "domain-key", "", "Metadata key whose value is the failure domain of a machine.")
+	cmdUpgradeAgents.Flags.BoolVar(&flagUpgradeStatus, "status", false, "Print the progress of the current upgrade.")
+	cmdUpgradeAgents.Flags.BoolVar(&flagUpgradeCancel, "cancel", false, "Cancel the current upgrade.")
+	cmdUpgradeAgents.Flags.BoolVar(&sharedFlags.NoLegend, "no-legend", false, "Do not print a legend (column headers)")
+	cmdUpgradeAgents.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+}
+
+func runUpgradeAgents(args []string) (exit int) {
+	if len(args) != 0 {
+		stderr("No arguments accepted.")
+		return 1
+	}
+
+	switch {
+	case flagUpgradeStatus:
+		return upgradeStatus()
+	case flagUpgradeCancel:
+		if err := cAPI.CancelUpgrade(); err != nil {
+			stderr("Error cancelling upgrade: %v", err)
+			return 1
+		}
+		stdout("Cancelled upgrade")
+		return
+	case flagUpgradeVersion == "":
+		stderr("One of --version, --status or --cancel must be provided.")
+		return 1
+	}
+
+	if _, err := semver.NewVersion(flagUpgradeVersion); err != nil {
+		stderr("Invalid version %q: %v", flagUpgradeVersion, err)
+		return 1
+	}
+	if err := cAPI.StartUpgrade(flagUpgradeVersion, flagUpgradeDomainKey); err != nil {
+		stderr("Error starting upgrade: %v", err)
+		return 1
+	}
+
+	stdout("Upgrading agents to fleetd %s", flagUpgradeVersion)
+	return
+}
+
+func upgradeStatus() (exit int) {
+	u, err := cAPI.Upgrade()
+	if err != nil {
+		stderr("Error retrieving upgrade: %v", err)
+		return 1
+	}
+	if u == nil {
+		stdout("No upgrade in progress.")
+		return
+	}
+
+	machines, err := cAPI.Machines()
+	if err != nil {
+		stderr("Error retrieving list of active machines: %v", err)
+		return 1
+	}
+	statuses := make(map[string]*schema.UpgradeStatus, len(u.Machines))
+	for _, us := range u.Machines {
+		statuses[us.MachineID] = us
+	}
+
+	if !sharedFlags.NoLegend {
+		fmt.Fprintln(out, "MACHINE\tDOMAIN\tVERSION\tSTATE\tREASON")
+	}
+	for _, ms := range machines {
+		domain := "-"
+		if u.DomainKey != "" {
+			domain = ms.Metadata[u.DomainKey]
+		}
+		state, reason := registry.UpgradeStatePending, ""
+		if ms.Version == u.Version {
+			state = registry.UpgradeStateDone
+		}
+		if us, ok := statuses[ms.ID]; ok {
+			state, reason = us.State, us.Reason
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n", machineIDLegend(ms, sharedFlags.Full), domain, ms.Version, state, reason)
+	}
+	out.Flush()
+	return
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunUpgradeAgents(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	cAPI = &client.RegistryClient{Registry: reg}
+	defer func() {
+		flagUpgradeVersion, flagUpgradeDomainKey = "", ""
+		flagUpgradeStatus, flagUpgradeCancel = false, false
+	}()
+
+	for i, tt := range []struct {
+		version string
+		args    []string
+		exit    int
+	}{
+		{"", nil, 1},
+		{"latest", nil, 1},
+		{"0.9.0", []string{"foo"}, 1},
+		{"0.9.0", nil, 0},
+	} {
+		flagUpgradeVersion, flagUpgradeDomainKey = tt.version, "az"
+		if exit := runUpgradeAgents(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+
+	plan, _ := reg.UpgradePlan()
+	if plan == nil || plan.Version != "0.9.0" || plan.DomainKey != "az" {
+		t.Fatalf("Unexpected upgrade plan: %#v", plan)
+	}
+
+	flagUpgradeCancel = true
+	if exit := runUpgradeAgents(nil); exit != 0 {
+		t.Fatalf("Unexpected exit %d cancelling upgrade", exit)
+	}
+	if plan, _ := reg.UpgradePlan(); plan != nil {
+		t.Errorf("Upgrade not cancelled: %#v", plan)
+	}
+}
+
+func TestRunUpgradeAgentsStatus(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetMachines([]machine.MachineState{
+		{ID: "c31e44e1-f858-436e-933e-59c642517860", Version: "0.9.0", Metadata: map[string]string{"az": "a"}},
+		{ID: "595989bb-cbb7-49ce-8726-722d6e157b4e", Version: "0.8.3", Metadata: map[string]string{"az": "b"}},
+		{ID: "deadbeef-cbb7-49ce-8726-722d6e157b4e", Version: "0.8.3", Metadata: map[string]string{"az": "c"}},
+	})
+	reg.SetUpgradePlan(registry.UpgradePlan{Version: "0.9.0", DomainKey: "az"})
+	reg.SaveUpgradeStatus(registry.UpgradeStatus{MachineID: "595989bb-cbb7-49ce-8726-722d6e157b4e", Version: "0.9.0", State: registry.UpgradeStateFailed, Reason: "exec failed"})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	flagUpgradeStatus = true
+	sharedFlags.NoLegend = true
+	defer func() {
+		flagUpgradeStatus = false
+		sharedFlags.NoLegend = false
+	}()
+
+	lines := runWithOutput(t, runUpgradeAgents)
+	want := []string{
+		"c31e44e1...\ta\t0.9.0\tdone\t",
+		"595989bb...\tb\t0.8.3\tfailed\texec failed",
+		"deadbeef...\tc\t0.8.3\tpending",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/coreos/fleet/api"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+	"github.com/coreos/fleet/unit"
+)
+
+var cmdValidateUnit = &Command{
+	Name:    "validate",
+	Summary: "Check unit files for mistakes without submitting them",
+	Usage:   "UNIT...",
+	Description: `Parse each of the given unit files and check the options of its [X-Fleet]
+section: unknown options, values fleet is unable to parse (such as resource
+reservations, workload windows or failure policies) and combinations of
+options that can never be satisfied. fleet ignores invalid options, so a
+mistake in one otherwise only shows as a unit scheduled in unexpected ways.
+
+Instances are validated against their template if no unit file of their own
+exists.
+
+Validate all units in a directory:
+	fleetctl validate units/*`,
+	Run: runValidateUnit,
+}
+
+func runValidateUnit(args []string) (exit int) {
+	if len(args) == 0 {
+		stderr("At least one unit file must be provided.")
+		return 1
+	}
+
+	for _, arg := range args {
+		name := unitNameMangle(arg)
+		uf, err := getLocalUnitFile(arg)
+		if err != nil {
+			stderr("%s: %v", name, err)
+			exit = 1
+			continue
+		}
+
+		problems := validateUnit(name, uf)
+		for _, err := range problems {
+			stderr("%s: %v", name, err)
+		}
+		if len(problems) != 0 {
+			exit = 1
+			continue
+		}
+		stdout("%s: valid", name)
+	}
+	return
+}
+
+// validateUnit returns the problems fleet would find with, or ignore in,
+// the named unit
+func validateUnit(name string, uf *unit.UnitFile) (problems []error) {
+	if err := api.ValidateName(name); err != nil {
+		problems = append(problems, err)
+	}
+
+	j := job.NewJob(name, *uf)
+	problems = append(problems, j.InvalidRequirements()...)
+
+	if err := api.ValidateOptions(schema.MapUnitFileToSchemaUnitOptions(uf)); err != nil {
+		problems = append(problems, err)
+	}
+	return
+}
+
+// getLocalUnitFile reads the unit file of the given name from the local
+// filesystem, falling back to the template of an instance unit
+func getLocalUnitFile(file string) (*unit.UnitFile, error) {
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		return getUnitFromFile(file)
+	}
+
+	name := unitNameMangle(file)
+	if uni := unit.NewUnitNameInfo(name); uni != nil && uni.IsInstance() {
+		tmpl := path.Join(path.Dir(file), uni.Template)
+		if _, err := os.Stat(tmpl); !os.IsNotExist(err) {
+			return getUnitFromFile(tmpl)
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find unit file %s", file)
+}
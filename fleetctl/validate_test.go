@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/coreos/fleet/unit"
+)
+
+func TestValidateUnit(t *testing.T) {
+	for i, tt := range []struct {
+		name     string
+		contents string
+		problems int
+	}{
+		{"foo.service", "[Service]\nExecStart=/bin/true\n[X-Fleet]\nMemoryReservation=512\nConflicts=foo*", 0},
+		{"foo.service", "[X-Fleet]\nMemoryReservation=512M\nCPUunits=100", 2},
+		{"foo.service", "[X-Fleet]\nGlobal=true\nConflicts=bar.service", 1},
+		{"foo", "[X-Fleet]\nPriority=high", 2},
+	} {
+		uf, err := unit.NewUnitFile(tt.contents)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error parsing unit: %v", i, err)
+		}
+		if problems := validateUnit(tt.name, uf); len(problems) != tt.problems {
+			t.Errorf("case %d: expected %d problems, got %v", i, tt.problems, problems)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+)
+
+const (
+	waitForActive   = "active"
+	waitForInactive = "inactive"
+	waitForHealthy  = "healthy"
+
+	// active state systemd reports for stopped units
+	unitActiveStateInactive = "inactive"
+)
+
+var (
+	cmdWaitUnits = &Command{
+		Name:    "wait",
+		Summary: "Wait for one or more units to reach a state",
+		Usage:   "[--for=active|inactive|healthy] [--timeout=DURATION] UNIT...",
+		Description: `Wait until each of the given units reaches the given state, exiting with a
+non-zero status if that does not happen within the timeout. A timeout of 0
+waits forever.
+
+--for selects the state to wait for:
+	active    systemd reports the unit active
+	inactive  systemd reports the unit inactive, or the unit is not loaded
+	healthy   the unit is active and passes its health check. Units without
+	          a health check are healthy once active.
+
+Global units reach a state once they report it on every machine they are
+loaded on.
+
+Start a unit and run a migration once it is healthy:
+	fleetctl start --no-block db.service
+	fleetctl wait --for=healthy --timeout=2m db.service && ./migrate`,
+		Run: runWaitUnits,
+	}
+
+	waitFlags = struct {
+		For     string
+		Timeout time.Duration
+	}{}
+
+	// interval at which the states of the units waited for are checked
+	waitPollInterval = 500 * time.Millisecond
+)
+
+func init() {
+	cmdWaitUnits.Flags.StringVar(&waitFlags.For, "for", waitForActive, "State to wait for: active, inactive or healthy.")
+	cmdWaitUnits.Flags.DurationVar(&waitFlags.Timeout, "timeout", time.Minute, "Time to wait for the units to reach the state. A value of 0 indicates no limit.")
+}
+
+func runWaitUnits(args []string) (exit int) {
+	if len(args) == 0 {
+		stderr("One or more units must be provided.")
+		return 1
+	}
+
+	switch waitFlags.For {
+	case waitForActive, waitForInactive, waitForHealthy:
+	default:
+		stderr("Invalid state %q: must be one of %s, %s or %s", waitFlags.For, waitForActive, waitForInactive, waitForHealthy)
+		return 1
+	}
+
+	// whether each unit waited for has a health check, indexed by name
+	checked := make(map[string]bool, len(args))
+	var names []string
+	for _, arg := range args {
+		name := unitNameMangle(arg)
+		u, err := cAPI.Unit(name)
+		if err != nil {
+			stderr("Error retrieving unit %s: %v", name, err)
+			return 1
+		} else if u == nil {
+			stderr("Unit %s does not exist.", name)
+			return 1
+		}
+
+		if _, ok := checked[name]; !ok {
+			names = append(names, name)
+		}
+		ju := job.Unit{Unit: *schema.MapSchemaUnitOptionsToUnitFile(u.Options)}
+		checked[name] = ju.HealthCheck() != nil
+	}
+
+	var deadline time.Time
+	if waitFlags.Timeout > 0 {
+		deadline = time.Now().Add(waitFlags.Timeout)
+	}
+
+	reached := make(map[string]bool, len(names))
+	for {
+		states, err := cAPI.UnitStates()
+		if err != nil {
+			stderr("Error retrieving unit states: %v", err)
+			return 1
+		}
+
+		byName := make(map[string][]*schema.UnitState)
+		for _, us := range states {
+			byName[us.Name] = append(byName[us.Name], us)
+		}
+
+		var pending []string
+		for _, name := range names {
+			if reached[name] {
+				continue
+			}
+			if !unitReached(byName[name], waitFlags.For, checked[name]) {
+				pending = append(pending, name)
+				continue
+			}
+			reached[name] = true
+			stdout("Unit %s %s", name, waitFlags.For)
+		}
+		if len(pending) == 0 {
+			return
+		}
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			stderr("Timed out waiting for units %v to become %s", pending, waitFlags.For)
+			return 1
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// unitReached determines whether all the given states of a unit report the
+// state waited for. Only inactive units may report no state at all.
+func unitReached(states []*schema.UnitState, state string, checked bool) bool {
+	if len(states) == 0 {
+		return state == waitForInactive
+	}
+
+	for _, us := range states {
+		var ok bool
+		switch state {
+		case waitForActive:
+			ok = us.SystemdActiveState == unitActiveStateActive
+		case waitForInactive:
+			ok = us.SystemdActiveState == unitActiveStateInactive
+		case waitForHealthy:
+			ok = us.SystemdActiveState == unitActiveStateActive && (!checked || us.Health == unitHealthHealthy)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestRunWaitUnits(t *testing.T) {
+	waitPollInterval = time.Millisecond
+	defer func() {
+		waitPollInterval = 500 * time.Millisecond
+		waitFlags.For = waitForActive
+		waitFlags.Timeout = time.Minute
+	}()
+
+	plain, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/hello")
+	checked, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/hello\n[X-Fleet]\nHealthCheckExec=/bin/true")
+
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		*job.NewJob("hello.service", *plain),
+		*job.NewJob("db.service", *checked),
+		*job.NewJob("global.service", *plain),
+		*job.NewJob("stopped.service", *plain),
+	})
+	reg.SetUnitStates([]unit.UnitState{
+		unit.UnitState{UnitName: "hello.service", ActiveState: "active", MachineID: "XXX"},
+		unit.UnitState{UnitName: "db.service", ActiveState: "active", MachineID: "XXX"},
+		unit.UnitState{UnitName: "global.service", ActiveState: "active", MachineID: "XXX"},
+		unit.UnitState{UnitName: "global.service", ActiveState: "failed", MachineID: "YYY"},
+	})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	for i, tt := range []struct {
+		state string
+		args  []string
+		exit  int
+	}{
+		{waitForActive, []string{"hello.service", "db"}, 0},
+		{waitForActive, []string{"global.service"}, 1},
+		{waitForActive, []string{"stopped.service"}, 1},
+		{waitForInactive, []string{"stopped.service"}, 0},
+		{waitForInactive, []string{"hello.service"}, 1},
+		{waitForHealthy, []string{"hello.service"}, 0},
+		{waitForHealthy, []string{"db.service"}, 1},
+		{waitForActive, []string{"missing.service"}, 1},
+		{"running", []string{"hello.service"}, 1},
+		{waitForActive, nil, 1},
+	} {
+		waitFlags.For = tt.state
+		waitFlags.Timeout = 10 * time.Millisecond
+		if exit := runWaitUnits(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+}
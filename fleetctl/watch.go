@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// clearScreen moves the cursor of the terminal home and clears it
+	clearScreen = "\033[H\033[2J"
+)
+
+// watchList prints a list with the given function, then prints it again
+// each time events are recorded in the cluster, e.g. units being scheduled
+// or changing state. Rather than listing repeatedly, it long-polls the
+// events of the cluster, as fleetctl events --follow does. It only returns
+// once printing or retrieving events fails, with the exit status to use.
+func watchList(print func() int) (exit int) {
+	events, err := cAPI.Events(0, 0)
+	if err != nil {
+		stderr("Error retrieving events: %v", err)
+		return 1
+	}
+	var since uint64
+	if len(events) > 0 {
+		since = uint64(events[len(events)-1].Index)
+	}
+
+	for {
+		fmt.Fprint(out, clearScreen)
+		if exit = print(); exit != 0 {
+			return
+		}
+
+		for {
+			wait := followWait()
+			events, err = cAPI.Events(since, wait)
+			if err != nil {
+				stderr("Error retrieving events: %v", err)
+				return 1
+			}
+			if len(events) > 0 {
+				since = uint64(events[len(events)-1].Index)
+				break
+			}
+			if wait == 0 {
+				time.Sleep(time.Second)
+			}
+		}
+	}
+}
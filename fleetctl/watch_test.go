@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestWatchList(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.RecordEvent(registry.ClusterEvent{Type: registry.EventMachineJoined, MachineID: "XXX"})
+	cAPI = &client.RegistryClient{Registry: reg}
+
+	// the list is printed right away, and again once an event is
+	// recorded, until printing fails
+	var printed int
+	exit := watchList(func() int {
+		printed++
+		if printed == 1 {
+			reg.RecordEvent(registry.ClusterEvent{Type: registry.EventUnitScheduled, UnitName: "foo.service", MachineID: "XXX"})
+			return 0
+		}
+		return 2
+	})
+	if exit != 2 {
+		t.Errorf("Expected exit 2, got %d", exit)
+	}
+	if printed != 2 {
+		t.Errorf("Expected list printed twice, got %d", printed)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/schema"
+)
+
+var cmdWhyUnit = &Command{
+	Name:    "why",
+	Summary: "Explain why a unit is not scheduled",
+	Usage:   "[-l|--full] UNIT",
+	Description: `Show why the engine was last unable to schedule a unit: the reason it gave up
+and, for each machine unable to run the unit, why that machine was rejected.
+
+The engine records these problems when it fails to schedule a unit, and forgets
+them once the unit is scheduled. Use "fleetctl schedule --dry-run" to find out
+where the engine would schedule a unit right now.
+
+Find out why a unit stays inactive:
+	fleetctl why hello.service`,
+	Run: runWhyUnit,
+}
+
+func init() {
+	cmdWhyUnit.Flags.BoolVar(&sharedFlags.Full, "full", false, "Do not ellipsize fields on output")
+	cmdWhyUnit.Flags.BoolVar(&sharedFlags.Full, "l", false, "Shorthand for --full")
+}
+
+func runWhyUnit(args []string) (exit int) {
+	if len(args) != 1 {
+		stderr("One unit must be provided.")
+		return 1
+	}
+
+	name := unitNameMangle(args[0])
+	u, err := cAPI.Unit(name)
+	if err != nil {
+		stderr("Error retrieving Unit(%s): %v", name, err)
+		return 1
+	} else if u == nil {
+		stderr("Unit %s does not exist.", name)
+		return 1
+	}
+
+	if u.MachineID != "" {
+		stdout("Unit %s is scheduled to %s.", name, machineLegend(u.MachineID))
+		return
+	}
+	if job.JobState(u.DesiredState) == job.JobStateInactive {
+		stdout("Unit %s is inactive; load or start it to have it scheduled.", name)
+		return
+	}
+
+	rej, err := cAPI.UnitRejections(name)
+	if err != nil {
+		stderr("Error retrieving scheduling problems of Unit(%s): %v", name, err)
+		return 1
+	}
+	if rej.Reason == "" {
+		stdout("No problems scheduling Unit %s have been recorded.", name)
+		return
+	}
+
+	if rej.BackoffUntil != "" {
+		stdout("Unit %s is in backoff until %s: %s.", name, rej.BackoffUntil, rej.Reason)
+		return 1
+	}
+	stdout("Unit %s could not be scheduled (as of %s): %s.", name, rej.Time, rej.Reason)
+	printMachineRejections(rej.Rejections)
+	return 1
+}
+
+// printMachineRejections prints why each of the given machines is unable to
+// run a Unit, listing each constraint a machine fails to meet on a line of
+// its own if it fails several
+func printMachineRejections(rejections []*schema.MachineRejection) {
+	if len(rejections) == 0 {
+		return
+	}
+	stdout("Machines unable to run it:")
+	for _, mr := range rejections {
+		if len(mr.Shortfalls) < 2 {
+			stdout("\t%s: %s", machineLegend(mr.MachineID), mr.Reason)
+			continue
+		}
+		stdout("\t%s:", machineLegend(mr.MachineID))
+		for _, sf := range mr.Shortfalls {
+			stdout("\t\t%s", sf.Reason)
+		}
+	}
+}
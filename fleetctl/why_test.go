@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/registry"
+)
+
+func TestRunWhyUnit(t *testing.T) {
+	reg := registry.NewFakeRegistry()
+	reg.SetJobs([]job.Job{
+		{Name: "scheduled.service", TargetMachineID: "XXX", TargetState: job.JobStateLaunched},
+		{Name: "stuck.service", TargetState: job.JobStateLaunched},
+		{Name: "fresh.service", TargetState: job.JobStateLaunched},
+		{Name: "flapping.service", TargetState: job.JobStateLaunched},
+		{Name: "inactive.service", TargetState: job.JobStateInactive},
+	})
+	reg.SaveUnitRejections("stuck.service", registry.UnitRejections{
+		Time:   time.Now(),
+		Reason: "no agents able to run job",
+		Machines: map[string]string{
+			"XXX": "insufficient CPU units: requested 200, available 100; insufficient memory: requested 2048MB, available 1024MB",
+			"YYY": "local Machine metadata insufficient",
+		},
+		Shortfalls: map[string][]job.Shortfall{
+			"XXX": []job.Shortfall{
+				{Constraint: job.ConstraintCPU, Reason: "insufficient CPU units: requested 200, available 100"},
+				{Constraint: job.ConstraintMemory, Reason: "insufficient memory: requested 2048MB, available 1024MB"},
+			},
+			"YYY": []job.Shortfall{{Constraint: job.ConstraintMetadata, Reason: "local Machine metadata insufficient"}},
+		},
+	}, time.Minute)
+	until := time.Now().Add(time.Minute)
+	reg.SaveUnitRejections("flapping.service", registry.UnitRejections{
+		Time:         time.Now(),
+		Reason:       "unit was rescheduled too often",
+		BackoffUntil: &until,
+	}, time.Minute)
+	cAPI = &client.RegistryClient{Registry: reg}
+	machineStates = nil
+	defer func() { machineStates = nil }()
+
+	for i, tt := range []struct {
+		args []string
+		exit int
+	}{
+		{[]string{"scheduled.service"}, 0},
+		{[]string{"stuck.service"}, 1},
+		{[]string{"fresh.service"}, 0},
+		{[]string{"flapping.service"}, 1},
+		{[]string{"inactive.service"}, 0},
+		{[]string{"missing.service"}, 1},
+		{nil, 1},
+	} {
+		if exit := runWhyUnit(tt.args); exit != tt.exit {
+			t.Errorf("case %d: expected exit %d, got %d", i, tt.exit, exit)
+		}
+	}
+}
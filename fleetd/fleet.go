@@ -13,8 +13,10 @@ import (
 
 	"github.com/coreos/fleet/agent"
 	"github.com/coreos/fleet/config"
+	"github.com/coreos/fleet/engine"
 	"github.com/coreos/fleet/log"
 	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/server"
 	"github.com/coreos/fleet/version"
 )
@@ -46,11 +48,72 @@ func main() {
 	cfgset.String("etcd_certfile", "", "SSL certification file used to secure etcd communication")
 	cfgset.String("etcd_cafile", "", "SSL Certificate Authority file used to secure etcd communication")
 	cfgset.String("etcd_key_prefix", registry.DefaultKeyPrefix, "Keyspace for fleet data in etcd")
+	cfgset.String("api_certfile", "", "SSL certification file used to serve the API over TLS on TCP sockets")
+	cfgset.String("api_keyfile", "", "SSL key file used to serve the API over TLS on TCP sockets")
+	cfgset.String("api_cafile", "", "SSL Certificate Authority file used to verify the certificates of API clients")
+	cfgset.String("api_tokens_file", "", "File listing the tokens API clients may authenticate with, along with their roles")
+	cfgset.String("api_advertise_url", "", "URL at which the other machines reach the API of this machine to relay requests for journals and commands")
+	cfgset.Bool("api_allow_exec", false, "Allow admin API clients to run arbitrary commands on this machine")
+	cfgset.String("api_standby", "off", "How the API handles requests changing the cluster while the local engine does not lead the cluster: off, proxy or redirect")
+	cfgset.String("cluster_key_file", "", "File holding the hex-encoded key secret configuration values passed to units are encrypted with")
+	cfgset.Var(&stringSlice{}, "registry_key_files", "Files holding the hex-encoded keys unit files and configuration values are encrypted with in etcd, the first of which encrypts new values")
+	cfgset.String("trusted_keys_file", "", "PEM file of the ECDSA P-256 public keys unit files must be signed with for units to be run on this machine")
+	cfgset.String("audit_log_file", "", "File to append a record of every change made to units through the API to")
+	cfgset.Bool("audit_registry", false, "Record every change made to units through the API in the audit log kept in etcd")
 	cfgset.Float64("etcd_request_timeout", 1.0, "Amount of time in seconds to allow a single etcd request before considering it failed.")
+	cfgset.String("etcd_request_timeouts", "", "List of per-action overrides of etcd_request_timeout, e.g. get=500ms,set=2s. Actions are get, set, create, update and delete.")
+	cfgset.String("etcd_retry_backoff", "100ms", "Time to wait before retrying an etcd request that failed against all etcd endpoints, doubling with each retry.")
+	cfgset.String("etcd_retry_max_backoff", "1s", "Longest time to wait between retries of an etcd request.")
+	cfgset.Float64("etcd_retry_jitter", 0.2, "Fraction, between 0 and 1, of each wait between retries of etcd requests that is randomized.")
+	cfgset.Int("etcd_breaker_threshold", 10, "Number of etcd requests in a row that must fail for further requests to fail right away until etcd_breaker_cooldown has passed. Disabled if 0.")
+	cfgset.String("etcd_breaker_cooldown", "5s", "Time etcd requests fail right away once etcd_breaker_threshold requests failed in a row, before a single trial request is let through.")
 	cfgset.Float64("engine_reconcile_interval", 2.0, "Interval at which the engine should reconcile the cluster schedule in etcd.")
-	cfgset.String("public_ip", "", "IP address that fleet machine should publish")
+	cfgset.Float64("engine_full_reconcile_interval", 60.0, "Interval at which the engine re-evaluates all placements rather than only those affected by changes. Zero always re-evaluates all placements.")
+	cfgset.Int("engine_reconcile_concurrency", 8, "Number of scheduling decisions the engine persists in etcd at once.")
+	cfgset.Int("engine_shards", 1, "Number of shards the scheduling work is split into, shared by the engines of the cluster. Must be the same on all machines.")
+	cfgset.String("scheduling_strategy", engine.SchedulingStrategyLeastLoaded, "Strategy used by the engine to choose a machine for a unit: least-loaded, binpack, spread or random.")
+	cfgset.Bool("evict_on_metadata_change", false, "Unschedule units from machines whose metadata no longer satisfies their MachineMetadata requirements.")
+	cfgset.String("engine_placement_webhook", "", "URL the engine consults to filter and score the machines able to run each unit it schedules.")
+	cfgset.Float64("engine_reschedule_delay", 0, "Amount of time in seconds the engine waits after a machine went away before moving its units elsewhere, unless they declare a RescheduleDelay.")
+	cfgset.Int("engine_reschedule_limit", 5, "Number of times a unit may be moved to another machine within engine_reschedule_window before the engine backs off rescheduling it. 0 disables backoff.")
+	cfgset.Float64("engine_reschedule_window", 600, "Amount of time in seconds within which moves of a unit count towards engine_reschedule_limit.")
+	cfgset.Var(&stringSlice{}, "engine_webhook_urls", "List of URLs the engine POSTs notifications of cluster events to.")
+	cfgset.Var(&stringSlice{}, "engine_webhook_events", "List of the types of cluster events POSTed to engine_webhook_urls. Defaults to UnitFailed, UnitRescheduled, UnitPreempted and MachineLeft.")
+	cfgset.String("engine_webhook_secret_file", "", "File holding the secret with which notifications POSTed to engine_webhook_urls are signed.")
+	cfgset.Float64("engine_gc_interval", 3600, "Interval in seconds at which the engine scans etcd for keys left behind by units and machines that no longer exist. Zero disables garbage collection.")
+	cfgset.Float64("engine_gc_grace", 86400, "Amount of time in seconds a key must be found orphaned, and unchanged, before the engine removes it.")
+	cfgset.Bool("engine_gc_dry_run", false, "Only log the orphaned keys the engine would remove from etcd.")
+	cfgset.Bool("fast_failure_detection", false, "Reschedule the units of a machine as soon as the engine observes its presence in etcd expire, rather than on the next reconciliation.")
+	cfgset.Bool("registry_cache", false, "Serve reads of units and unit states from an in-memory mirror of etcd kept up to date by watches.")
+	cfgset.String("unit_cache_dir", "", "Directory in which to keep the unit files read from etcd, reading them from there rather than from etcd from then on.")
+	cfgset.String("public_ip", "", "IP address that fleet machine should publish, or a comma-separated list of its public IPv4 and IPv6 addresses")
+	cfgset.String("private_ip", "", "Comma-separated list of the private IPv4 and IPv6 addresses the fleet machine should publish")
+	cfgset.String("ip_preference", "ipv4", "Family of the addresses, ipv4 or ipv6, the machine prefers to be reached at, deciding its primary IP")
 	cfgset.String("metadata", "", "List of key-value metadata to assign to the fleet machine")
+	cfgset.String("pool", "", "Pool of machines this machine belongs to, which only runs units targeting it with Pool=. Sets the pool metadata.")
+	cfgset.String("metadata_sources", "", "List of cloud providers (ec2, gce, openstack) from which to discover additional metadata")
+	cfgset.Bool("security_metadata", true, "Publish the security features of this machine (selinux, apparmor, seccomp, kernel_version, kernel_release) as metadata")
 	cfgset.String("agent_ttl", agent.DefaultTTL, "TTL in seconds of fleet machine state in etcd")
+	cfgset.String("agent_heartbeat_interval", "", "Interval at which the machine renews its state in etcd. Half of agent_ttl if empty.")
+	cfgset.String("zombie_cleanup", agent.ZombieCleanupStop, "How the agent handles units systemd runs from fleet's units directory that are not scheduled to the machine: stop, log or off.")
+	cfgset.Float64("handoff_timeout", 0, "Amount of time in seconds to wait on shutdown for units to be claimed by other machines. Disabled if 0.")
+	cfgset.String("disk_path", "/", "Path on the filesystem against which units' DiskReservation is accounted")
+	cfgset.Int("cpu_capacity", 0, "CPU units (hundredths of a core) published as the machine's CPU capacity. Determined from the online CPUs and fleet's cgroup if 0.")
+	cfgset.String("resources", "", "List of countable resources the machine offers to units besides CPU, memory and disk, e.g. gpu:2,hugepages1G:16")
+	cfgset.Int("reserved_memory", resource.HostMemory, "Memory (in MB) of the machine reserved for the OS, system daemons and fleet itself, which units cannot reserve")
+	cfgset.Int("reserved_cpu_units", resource.HostCores, "CPU units (hundredths of a core) of the machine reserved for the OS, system daemons and fleet itself, which units cannot reserve")
+	cfgset.Float64("cpu_overcommit", 1.0, "Factor by which the CPU reservations of units may exceed the machine's allocatable CPU. Overridden by the cpu-overcommit metadata of the machine.")
+	cfgset.Float64("memory_overcommit", 1.0, "Factor by which the memory reservations of units may exceed the machine's allocatable memory. Overridden by the memory-overcommit metadata of the machine.")
+	cfgset.Int("max_units_per_machine", 0, "Number of units that may be scheduled to the machine, regardless of their reservations. 0 means no limit. Overridden by the max-units-per-machine metadata of the machine.")
+	cfgset.Float64("usage_alert_factor", 1.5, "Factor by which a unit's CPU or memory usage may exceed its reservation before the agent records a UnitOverReservation event. 0 disables alerts.")
+	cfgset.Float64("usage_alert_period", 300, "Amount of time in seconds a unit must exceed its reservation by usage_alert_factor before an alert is raised.")
+	cfgset.Bool("usage_alert_fail", false, "Report units with an OnFailure policy that exceed their reservation as failed, so they are moved to another machine.")
+	cfgset.Int("max_concurrent_starts", 0, "Number of units the agent starts at once, higher StartPriority first. 0 means no limit.")
+	cfgset.Float64("cpu_reservable_fraction", 1.0, "Fraction of the machine's CPU capacity that units may reserve, keeping the rest for system daemons")
+	cfgset.String("memory_policy", "reserved", "Memory new units are admitted against: reserved (total memory less the reservations of scheduled units), available (the kernel's MemAvailable) or hybrid (the lower of both).")
+	cfgset.String("memory_refresh_interval", "5s", "Interval at which the agent refreshes the memory of the machine it admits units against.")
+	cfgset.String("metrics_listen", "", "Address (host:port) on which to serve Prometheus metrics at /metrics. Disabled if empty.")
+	cfgset.String("upgrade_binary_path", "", "Path of the fleetd binaries to restart into when the cluster is upgraded with fleetctl upgrade-agents, {version} standing for the version. Upgrades fail if empty.")
 	cfgset.Bool("verify_units", false, "DEPRECATED - This option is ignored")
 	cfgset.String("authorized_keys_file", "", "DEPRECATED - This option is ignored")
 
@@ -87,6 +150,7 @@ func main() {
 
 	shutdown := func() {
 		log.Infof("Gracefully shutting down")
+		srv.Handoff()
 		srv.Stop()
 		srv.Purge()
 		os.Exit(0)
@@ -152,19 +216,80 @@ func getConfig(flagset *flag.FlagSet, userCfgFile string) (*config.Config, error
 	gconf.ParseSet("", flagset)
 
 	cfg := config.Config{
-		Verbosity:               (*flagset.Lookup("verbosity")).Value.(flag.Getter).Get().(int),
-		EtcdServers:             (*flagset.Lookup("etcd_servers")).Value.(flag.Getter).Get().(stringSlice),
-		EtcdKeyPrefix:           (*flagset.Lookup("etcd_key_prefix")).Value.(flag.Getter).Get().(string),
-		EtcdKeyFile:             (*flagset.Lookup("etcd_keyfile")).Value.(flag.Getter).Get().(string),
-		EtcdCertFile:            (*flagset.Lookup("etcd_certfile")).Value.(flag.Getter).Get().(string),
-		EtcdCAFile:              (*flagset.Lookup("etcd_cafile")).Value.(flag.Getter).Get().(string),
-		EtcdRequestTimeout:      (*flagset.Lookup("etcd_request_timeout")).Value.(flag.Getter).Get().(float64),
-		EngineReconcileInterval: (*flagset.Lookup("engine_reconcile_interval")).Value.(flag.Getter).Get().(float64),
-		PublicIP:                (*flagset.Lookup("public_ip")).Value.(flag.Getter).Get().(string),
-		RawMetadata:             (*flagset.Lookup("metadata")).Value.(flag.Getter).Get().(string),
-		AgentTTL:                (*flagset.Lookup("agent_ttl")).Value.(flag.Getter).Get().(string),
-		VerifyUnits:             (*flagset.Lookup("verify_units")).Value.(flag.Getter).Get().(bool),
-		AuthorizedKeysFile:      (*flagset.Lookup("authorized_keys_file")).Value.(flag.Getter).Get().(string),
+		Verbosity:                   (*flagset.Lookup("verbosity")).Value.(flag.Getter).Get().(int),
+		EtcdServers:                 (*flagset.Lookup("etcd_servers")).Value.(flag.Getter).Get().(stringSlice),
+		EtcdKeyPrefix:               (*flagset.Lookup("etcd_key_prefix")).Value.(flag.Getter).Get().(string),
+		EtcdKeyFile:                 (*flagset.Lookup("etcd_keyfile")).Value.(flag.Getter).Get().(string),
+		EtcdCertFile:                (*flagset.Lookup("etcd_certfile")).Value.(flag.Getter).Get().(string),
+		EtcdCAFile:                  (*flagset.Lookup("etcd_cafile")).Value.(flag.Getter).Get().(string),
+		APICertFile:                 (*flagset.Lookup("api_certfile")).Value.(flag.Getter).Get().(string),
+		APIKeyFile:                  (*flagset.Lookup("api_keyfile")).Value.(flag.Getter).Get().(string),
+		APICAFile:                   (*flagset.Lookup("api_cafile")).Value.(flag.Getter).Get().(string),
+		APITokensFile:               (*flagset.Lookup("api_tokens_file")).Value.(flag.Getter).Get().(string),
+		APIAdvertiseURL:             (*flagset.Lookup("api_advertise_url")).Value.(flag.Getter).Get().(string),
+		APIAllowExec:                (*flagset.Lookup("api_allow_exec")).Value.(flag.Getter).Get().(bool),
+		APIStandby:                  (*flagset.Lookup("api_standby")).Value.(flag.Getter).Get().(string),
+		ClusterKeyFile:              (*flagset.Lookup("cluster_key_file")).Value.(flag.Getter).Get().(string),
+		RegistryKeyFiles:            (*flagset.Lookup("registry_key_files")).Value.(flag.Getter).Get().(stringSlice),
+		TrustedKeysFile:             (*flagset.Lookup("trusted_keys_file")).Value.(flag.Getter).Get().(string),
+		AuditLogFile:                (*flagset.Lookup("audit_log_file")).Value.(flag.Getter).Get().(string),
+		AuditRegistry:               (*flagset.Lookup("audit_registry")).Value.(flag.Getter).Get().(bool),
+		EtcdRequestTimeout:          (*flagset.Lookup("etcd_request_timeout")).Value.(flag.Getter).Get().(float64),
+		EtcdRequestTimeouts:         (*flagset.Lookup("etcd_request_timeouts")).Value.(flag.Getter).Get().(string),
+		EtcdRetryBackoff:            (*flagset.Lookup("etcd_retry_backoff")).Value.(flag.Getter).Get().(string),
+		EtcdRetryMaxBackoff:         (*flagset.Lookup("etcd_retry_max_backoff")).Value.(flag.Getter).Get().(string),
+		EtcdRetryJitter:             (*flagset.Lookup("etcd_retry_jitter")).Value.(flag.Getter).Get().(float64),
+		EtcdBreakerThreshold:        (*flagset.Lookup("etcd_breaker_threshold")).Value.(flag.Getter).Get().(int),
+		EtcdBreakerCooldown:         (*flagset.Lookup("etcd_breaker_cooldown")).Value.(flag.Getter).Get().(string),
+		EngineReconcileInterval:     (*flagset.Lookup("engine_reconcile_interval")).Value.(flag.Getter).Get().(float64),
+		EngineFullReconcileInterval: (*flagset.Lookup("engine_full_reconcile_interval")).Value.(flag.Getter).Get().(float64),
+		EngineReconcileConcurrency:  (*flagset.Lookup("engine_reconcile_concurrency")).Value.(flag.Getter).Get().(int),
+		EngineShards:                (*flagset.Lookup("engine_shards")).Value.(flag.Getter).Get().(int),
+		SchedulingStrategy:          (*flagset.Lookup("scheduling_strategy")).Value.(flag.Getter).Get().(string),
+		EvictOnMetadataChange:       (*flagset.Lookup("evict_on_metadata_change")).Value.(flag.Getter).Get().(bool),
+		EnginePlacementWebhook:      (*flagset.Lookup("engine_placement_webhook")).Value.(flag.Getter).Get().(string),
+		EngineRescheduleDelay:       (*flagset.Lookup("engine_reschedule_delay")).Value.(flag.Getter).Get().(float64),
+		EngineRescheduleLimit:       (*flagset.Lookup("engine_reschedule_limit")).Value.(flag.Getter).Get().(int),
+		EngineRescheduleWindow:      (*flagset.Lookup("engine_reschedule_window")).Value.(flag.Getter).Get().(float64),
+		EngineWebhookURLs:           (*flagset.Lookup("engine_webhook_urls")).Value.(flag.Getter).Get().(stringSlice),
+		EngineWebhookEvents:         (*flagset.Lookup("engine_webhook_events")).Value.(flag.Getter).Get().(stringSlice),
+		EngineWebhookSecretFile:     (*flagset.Lookup("engine_webhook_secret_file")).Value.(flag.Getter).Get().(string),
+		EngineGCInterval:            (*flagset.Lookup("engine_gc_interval")).Value.(flag.Getter).Get().(float64),
+		EngineGCGrace:               (*flagset.Lookup("engine_gc_grace")).Value.(flag.Getter).Get().(float64),
+		EngineGCDryRun:              (*flagset.Lookup("engine_gc_dry_run")).Value.(flag.Getter).Get().(bool),
+		FastFailureDetection:        (*flagset.Lookup("fast_failure_detection")).Value.(flag.Getter).Get().(bool),
+		RegistryCache:               (*flagset.Lookup("registry_cache")).Value.(flag.Getter).Get().(bool),
+		UnitCacheDir:                (*flagset.Lookup("unit_cache_dir")).Value.(flag.Getter).Get().(string),
+		PublicIP:                    (*flagset.Lookup("public_ip")).Value.(flag.Getter).Get().(string),
+		PrivateIP:                   (*flagset.Lookup("private_ip")).Value.(flag.Getter).Get().(string),
+		IPPreference:                (*flagset.Lookup("ip_preference")).Value.(flag.Getter).Get().(string),
+		RawMetadata:                 (*flagset.Lookup("metadata")).Value.(flag.Getter).Get().(string),
+		RawMetadataSources:          (*flagset.Lookup("metadata_sources")).Value.(flag.Getter).Get().(string),
+		SecurityMetadata:            (*flagset.Lookup("security_metadata")).Value.(flag.Getter).Get().(bool),
+		Pool:                        (*flagset.Lookup("pool")).Value.(flag.Getter).Get().(string),
+		AgentTTL:                    (*flagset.Lookup("agent_ttl")).Value.(flag.Getter).Get().(string),
+		AgentHeartbeatInterval:      (*flagset.Lookup("agent_heartbeat_interval")).Value.(flag.Getter).Get().(string),
+		ZombieCleanup:               (*flagset.Lookup("zombie_cleanup")).Value.(flag.Getter).Get().(string),
+		HandoffTimeout:              (*flagset.Lookup("handoff_timeout")).Value.(flag.Getter).Get().(float64),
+		DiskPath:                    (*flagset.Lookup("disk_path")).Value.(flag.Getter).Get().(string),
+		CPUCapacity:                 (*flagset.Lookup("cpu_capacity")).Value.(flag.Getter).Get().(int),
+		RawResources:                (*flagset.Lookup("resources")).Value.(flag.Getter).Get().(string),
+		ReservedMemory:              (*flagset.Lookup("reserved_memory")).Value.(flag.Getter).Get().(int),
+		ReservedCPUUnits:            (*flagset.Lookup("reserved_cpu_units")).Value.(flag.Getter).Get().(int),
+		CPUOvercommit:               (*flagset.Lookup("cpu_overcommit")).Value.(flag.Getter).Get().(float64),
+		MemoryOvercommit:            (*flagset.Lookup("memory_overcommit")).Value.(flag.Getter).Get().(float64),
+		MaxUnitsPerMachine:          (*flagset.Lookup("max_units_per_machine")).Value.(flag.Getter).Get().(int),
+		UsageAlertFactor:            (*flagset.Lookup("usage_alert_factor")).Value.(flag.Getter).Get().(float64),
+		UsageAlertPeriod:            (*flagset.Lookup("usage_alert_period")).Value.(flag.Getter).Get().(float64),
+		UsageAlertFail:              (*flagset.Lookup("usage_alert_fail")).Value.(flag.Getter).Get().(bool),
+		MaxConcurrentStarts:         (*flagset.Lookup("max_concurrent_starts")).Value.(flag.Getter).Get().(int),
+		CPUReservableFraction:       (*flagset.Lookup("cpu_reservable_fraction")).Value.(flag.Getter).Get().(float64),
+		MemoryPolicy:                (*flagset.Lookup("memory_policy")).Value.(flag.Getter).Get().(string),
+		MemoryRefreshInterval:       (*flagset.Lookup("memory_refresh_interval")).Value.(flag.Getter).Get().(string),
+		MetricsListen:               (*flagset.Lookup("metrics_listen")).Value.(flag.Getter).Get().(string),
+		UpgradeBinaryPath:           (*flagset.Lookup("upgrade_binary_path")).Value.(flag.Getter).Get().(string),
+		VerifyUnits:                 (*flagset.Lookup("verify_units")).Value.(flag.Getter).Get().(bool),
+		AuthorizedKeysFile:          (*flagset.Lookup("authorized_keys_file")).Value.(flag.Getter).Get().(string),
 	}
 
 	if cfg.VerifyUnits {
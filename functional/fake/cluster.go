@@ -0,0 +1,264 @@
+// Package fake runs the fleet engine against an in-memory registry and
+// scripted machines, so that the scheduling of units, e.g. combinations of
+// X-Fleet options or custom PlacementPlugins, can be tested without etcd,
+// systemd or a cluster of machines.
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/engine"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
+	"github.com/coreos/fleet/unit"
+	"github.com/coreos/fleet/version"
+)
+
+const (
+	// maxRounds is how many times Reconcile lets the engine reconcile and
+	// the machines run their units at most before giving up on the
+	// cluster settling
+	maxRounds = 100
+
+	// stateTTL is the TTL the machines publish the states of their units
+	// and report failures with, which the in-memory registry disregards
+	stateTTL = time.Minute
+)
+
+// Script decides the ActiveState of a unit once a fake machine runs it,
+// e.g. "active" or "failed". Units failing are reported to the engine as
+// they would be by an agent.
+type Script func(name string) string
+
+// Machine describes a fake machine of a Cluster
+type Machine struct {
+	ID       string
+	Metadata map[string]string
+
+	// Capacity is the CPU, memory and disk the machine offers to units,
+	// all of which units may reserve.
+	Capacity resource.ResourceTuple
+
+	// Script decides the state of the units the machine runs. If nil,
+	// all units become active.
+	Script Script
+}
+
+// Cluster is a fake fleet cluster. Its engine places units with Strategy,
+// consulting Plugins, much like the engine of a real cluster configured
+// with scheduling_strategy and placement plugins.
+type Cluster struct {
+	Registry *registry.FakeRegistry
+	Strategy string
+	Plugins  []engine.PlacementPlugin
+
+	mutex    sync.Mutex
+	machines map[string]*Machine
+}
+
+// NewCluster returns a Cluster without machines or units, whose engine
+// uses the default scheduling strategy
+func NewCluster() *Cluster {
+	return &Cluster{
+		Registry: registry.NewFakeRegistry(),
+		machines: make(map[string]*Machine),
+	}
+}
+
+// AddMachine lets the given Machine join the Cluster, replacing any of the
+// same ID
+func (c *Cluster) AddMachine(m Machine) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.machines[m.ID] = &m
+	c.publishMachines()
+}
+
+// RemoveMachine lets the identified Machine leave the Cluster, as if it
+// failed. The units it ran are left to the engine to reschedule.
+func (c *Cluster) RemoveMachine(id string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.machines, id)
+	c.publishMachines()
+	c.Registry.SaveUnitStates(id, nil, stateTTL)
+}
+
+func (c *Cluster) publishMachines() {
+	states := make([]machine.MachineState, 0, len(c.machines))
+	for _, id := range c.machineIDs() {
+		m := c.machines[id]
+		states = append(states, machine.MachineState{
+			ID:                m.ID,
+			Metadata:          m.Metadata,
+			Version:           version.Version,
+			TotalResources:    m.Capacity,
+			ReservedResources: &resource.ResourceTuple{},
+		})
+	}
+	c.Registry.SetMachines(states)
+}
+
+func (c *Cluster) machineIDs() []string {
+	ids := make([]string, 0, len(c.machines))
+	for id := range c.machines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Start submits a unit of the given name and unit file contents to the
+// Cluster, to be launched by the engine
+func (c *Cluster) Start(name, contents string) error {
+	return c.Submit(name, contents, job.JobStateLaunched)
+}
+
+// Submit submits a unit of the given name and unit file contents to the
+// Cluster, with the given target state
+func (c *Cluster) Submit(name, contents string, target job.JobState) error {
+	uf, err := unit.NewUnitFile(contents)
+	if err != nil {
+		return fmt.Errorf("invalid unit file of %s: %v", name, err)
+	}
+	j := job.Job{Name: name, Unit: *uf}
+	if err := j.ValidateRequirements(); err != nil {
+		return fmt.Errorf("invalid unit file of %s: %v", name, err)
+	}
+	return c.Registry.CreateUnit(&job.Unit{Name: name, Unit: *uf, TargetState: target})
+}
+
+// Reconcile lets the engine reconcile the Cluster and its machines run the
+// units scheduled to them, in turns, until the placements of units settle.
+// It returns why the units left unscheduled could not be scheduled,
+// indexed by unit name.
+func (c *Cluster) Reconcile() (map[string]registry.UnitRejections, error) {
+	sched, err := engine.NewScheduler(c.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	sched = engine.WithPlacementPlugins(sched, c.Plugins...)
+
+	for i := 0; i < maxRounds; i++ {
+		rejected, err := engine.SimulateScheduler(c.Registry, sched)
+		if err != nil {
+			return nil, err
+		}
+		failed, err := c.runUnits()
+		if err != nil {
+			return nil, err
+		}
+		if !failed {
+			return rejected, nil
+		}
+	}
+	return nil, fmt.Errorf("cluster did not settle after %d rounds", maxRounds)
+}
+
+// runUnits publishes the states of the units scheduled to each machine as
+// decided by its Script, and reports those newly failed, returning whether
+// there were any
+func (c *Cluster) runUnits() (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	units, err := c.Registry.Units()
+	if err != nil {
+		return false, err
+	}
+	targets := make(map[string]job.JobState, len(units))
+	for _, u := range units {
+		targets[u.Name] = u.TargetState
+	}
+	sUnits, err := c.Registry.Schedule()
+	if err != nil {
+		return false, err
+	}
+	failures, err := c.Registry.UnitFailures()
+	if err != nil {
+		return false, err
+	}
+
+	states := make(map[string]map[string]*unit.UnitState)
+	var failed bool
+	for _, su := range sUnits {
+		m, ok := c.machines[su.TargetMachineID]
+		if !ok || targets[su.Name] != job.JobStateLaunched {
+			continue
+		}
+
+		active := "active"
+		if m.Script != nil {
+			active = m.Script(su.Name)
+		}
+		if states[m.ID] == nil {
+			states[m.ID] = make(map[string]*unit.UnitState)
+		}
+		states[m.ID][su.Name] = &unit.UnitState{
+			LoadState:   "loaded",
+			ActiveState: active,
+			SubState:    active,
+			MachineID:   m.ID,
+			UnitName:    su.Name,
+		}
+
+		if active == "failed" {
+			if _, ok := failures[su.Name][m.ID]; ok {
+				continue
+			}
+			if err := c.Registry.ReportUnitFailure(su.Name, m.ID, "failed", stateTTL); err != nil {
+				return false, err
+			}
+			failed = true
+		}
+	}
+
+	for _, id := range c.machineIDs() {
+		if err := c.Registry.SaveUnitStates(id, states[id], stateTTL); err != nil {
+			return false, err
+		}
+	}
+	return failed, nil
+}
+
+// MachineOf returns the ID of the machine the named unit is scheduled to,
+// or an empty string if it is not scheduled
+func (c *Cluster) MachineOf(name string) string {
+	su, err := c.Registry.ScheduledUnit(name)
+	if err != nil || su == nil {
+		return ""
+	}
+	return su.TargetMachineID
+}
+
+// UnitsOf returns the names of the units scheduled to the identified
+// machine, sorted
+func (c *Cluster) UnitsOf(id string) []string {
+	sUnits, _ := c.Registry.Schedule()
+	var names []string
+	for _, su := range sUnits {
+		if su.TargetMachineID == id {
+			names = append(names, su.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnitState returns the state the machine the named unit is scheduled to
+// published for it, or nil if none did
+func (c *Cluster) UnitState(name string) *unit.UnitState {
+	states, _ := c.Registry.UnitStates()
+	machID := c.MachineOf(name)
+	for _, us := range states {
+		if us.UnitName == name && us.MachineID == machID {
+			return us
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package fake
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/agent"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/resource"
+)
+
+func TestClusterCapacity(t *testing.T) {
+	c := NewCluster()
+	c.AddMachine(Machine{ID: "small", Capacity: resource.ResourceTuple{Cores: 100, Memory: 1024}})
+	c.AddMachine(Machine{ID: "large", Capacity: resource.ResourceTuple{Cores: 400, Memory: 8192}})
+
+	if err := c.Start("db.service", "[X-Fleet]\nMemoryReservation=4096"); err != nil {
+		t.Fatalf("Failed starting db.service: %v", err)
+	}
+	if err := c.Start("huge.service", "[X-Fleet]\nMemoryReservation=16384"); err != nil {
+		t.Fatalf("Failed starting huge.service: %v", err)
+	}
+
+	rejected, err := c.Reconcile()
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if m := c.MachineOf("db.service"); m != "large" {
+		t.Errorf("db.service scheduled to %q, want large", m)
+	}
+	if us := c.UnitState("db.service"); us == nil || us.ActiveState != "active" {
+		t.Errorf("Unexpected state of db.service: %#v", us)
+	}
+	if _, ok := rejected["huge.service"]; !ok {
+		t.Errorf("huge.service not rejected: %v", rejected)
+	}
+
+	// the unit is left unscheduled once its machine is gone
+	c.RemoveMachine("large")
+	if _, err := c.Reconcile(); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if m := c.MachineOf("db.service"); m != "" {
+		t.Errorf("db.service scheduled to %q, want none", m)
+	}
+}
+
+func TestClusterScript(t *testing.T) {
+	c := NewCluster()
+	c.AddMachine(Machine{ID: "broken", Script: func(string) string { return "failed" }})
+
+	if err := c.Start("web.service", "[Service]\nExecStart=/bin/true"); err != nil {
+		t.Fatalf("Failed starting web.service: %v", err)
+	}
+	if err := c.Submit("idle.service", "[Service]\nExecStart=/bin/true", job.JobStateLoaded); err != nil {
+		t.Fatalf("Failed submitting idle.service: %v", err)
+	}
+	if _, err := c.Reconcile(); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// the failure is reported, and the unit moved away from the machine
+	failures, _ := c.Registry.UnitFailures()
+	if want := map[string]string{"broken": "failed"}; !reflect.DeepEqual(failures["web.service"], want) {
+		t.Errorf("Unexpected failures of web.service: %v", failures["web.service"])
+	}
+	if m := c.MachineOf("web.service"); m != "" {
+		t.Errorf("web.service scheduled to %q, want none", m)
+	}
+
+	c.AddMachine(Machine{ID: "working"})
+	if _, err := c.Reconcile(); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if m := c.MachineOf("web.service"); m != "working" {
+		t.Errorf("web.service scheduled to %q, want working", m)
+	}
+	if us := c.UnitState("web.service"); us == nil || us.ActiveState != "active" {
+		t.Errorf("Unexpected state of web.service: %#v", us)
+	}
+	if us := c.UnitState("idle.service"); us != nil {
+		t.Errorf("idle.service is not launched but has state %#v", us)
+	}
+}
+
+type preferPlugin string
+
+func (p preferPlugin) Name() string { return "prefer" }
+
+func (p preferPlugin) Score(j *job.Job, candidates []*agent.AgentState) (map[string]int, error) {
+	scores := make(map[string]int, len(candidates))
+	for _, as := range candidates {
+		if as.MState.ID == string(p) {
+			scores[as.MState.ID] = 1
+		} else {
+			scores[as.MState.ID] = 0
+		}
+	}
+	return scores, nil
+}
+
+func TestClusterPlugins(t *testing.T) {
+	c := NewCluster()
+	c.Plugins = append(c.Plugins, preferPlugin("YYY"))
+	for _, id := range []string{"XXX", "YYY", "ZZZ"} {
+		c.AddMachine(Machine{ID: id})
+	}
+
+	for _, name := range []string{"a.service", "b.service"} {
+		if err := c.Start(name, "[Service]\nExecStart=/bin/true"); err != nil {
+			t.Fatalf("Failed starting %s: %v", name, err)
+		}
+	}
+	if _, err := c.Reconcile(); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if got, want := c.UnitsOf("YYY"), []string{"a.service", "b.service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Units of YYY are %v, want %v", got, want)
+	}
+}
@@ -59,14 +59,14 @@ ExecStart=/usr/bin/sleep 3000
 		t.Fatalf("Expected [hello.service], got %v", units)
 	}
 
-	err = waitForUnitState(mgr, name, unit.UnitState{"loaded", "inactive", "dead", "", hash, ""})
+	err = waitForUnitState(mgr, name, unit.UnitState{LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitHash: hash})
 	if err != nil {
 		t.Error(err.Error())
 	}
 
 	mgr.TriggerStart(name)
 
-	err = waitForUnitState(mgr, name, unit.UnitState{"loaded", "active", "running", "", hash, ""})
+	err = waitForUnitState(mgr, name, unit.UnitState{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitHash: hash})
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -22,7 +22,10 @@ type machineHeart struct {
 }
 
 func (h *machineHeart) Beat(ttl time.Duration) (uint64, error) {
-	return h.reg.SetMachineState(h.mach.State(), ttl)
+	ms := h.mach.State()
+	now := time.Now()
+	ms.LastHeartbeat = &now
+	return h.reg.SetMachineState(ms, ttl)
 }
 
 func (h *machineHeart) Clear() error {
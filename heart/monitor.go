@@ -7,8 +7,13 @@ import (
 	"github.com/coreos/fleet/log"
 )
 
-func NewMonitor(ttl time.Duration) *Monitor {
-	return &Monitor{ttl, ttl / 2}
+// NewMonitor creates a Monitor beating a Heart with the given TTL at the
+// given interval, or at half the TTL if the interval is zero
+func NewMonitor(ttl, ival time.Duration) *Monitor {
+	if ival <= 0 {
+		ival = ttl / 2
+	}
+	return &Monitor{ttl, ival}
 }
 
 type Monitor struct {
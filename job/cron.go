@@ -0,0 +1,220 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	// ConcurrencyForbid skips a tick of a CronSchedule while an earlier
+	// run of the Job is still going
+	ConcurrencyForbid = "forbid"
+	// ConcurrencyAllow starts a run for every tick, regardless of earlier
+	// runs still going
+	ConcurrencyAllow = "allow"
+	// ConcurrencyReplace stops any earlier runs still going in favour of
+	// the run of the new tick
+	ConcurrencyReplace = "replace"
+
+	// a CronSchedule matching no time within this many years never fires
+	cronSearchYears = 5
+)
+
+// cronField describes the range of values of a field of a CronSchedule
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// CronSchedule describes the recurring times at which a Job runs, in the
+// form of a classic five-field crontab entry.
+type CronSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+
+	// restricted days of month and week match either, as with cron
+	daysRestricted, weekdaysRestricted bool
+
+	Location *time.Location
+}
+
+// ParseCronSchedule parses a string of the form `MIN HOUR DOM MON DOW
+// [location]` into a CronSchedule. Each field is `*`, a value or a range
+// like `1-5`, optionally followed by a step like `*/15`, or a
+// comma-separated list thereof. Sunday is day of week both 0 and 7.
+func ParseCronSchedule(s string) (*CronSchedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != len(cronFields) && len(fields) != len(cronFields)+1 {
+		return nil, fmt.Errorf("invalid schedule %q: must have %d fields and an optional location", s, len(cronFields))
+	}
+
+	var sets [5]uint64
+	for i, f := range cronFields {
+		set, err := parseCronField(fields[i], f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", s, err)
+		}
+		sets[i] = set
+	}
+
+	// both 0 and 7 are Sunday
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+
+	loc := time.UTC
+	if len(fields) > len(cronFields) {
+		var err error
+		loc, err = time.LoadLocation(fields[len(cronFields)])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule location %q: %v", fields[len(cronFields)], err)
+		}
+	}
+
+	cs := CronSchedule{
+		minutes:            sets[0],
+		hours:              sets[1],
+		days:               sets[2],
+		months:             sets[3],
+		weekdays:           sets[4],
+		daysRestricted:     fields[2] != "*",
+		weekdaysRestricted: fields[4] != "*",
+		Location:           loc,
+	}
+	return &cs, nil
+}
+
+// parseCronField parses a field of a CronSchedule into the set of values
+// it matches, with bit n set for value n
+func parseCronField(s string, f cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i != -1 {
+			rng = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q of %s", part[i+1:], f.name)
+			}
+			step = n
+		}
+
+		first, last := f.min, f.max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if first, err = parseCronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			last = first
+			if len(bounds) == 2 {
+				if last, err = parseCronValue(bounds[1], f); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// a single value with a step runs to the end of the range
+				last = f.max
+			}
+			if last < first {
+				return 0, fmt.Errorf("invalid range %q of %s", rng, f.name)
+			}
+		}
+
+		for n := first; n <= last; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(s string, f cronField) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s %q: must be %d-%d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+func (cs *CronSchedule) location() *time.Location {
+	if cs.Location == nil {
+		return time.UTC
+	}
+	return cs.Location
+}
+
+func (cs *CronSchedule) dayMatches(t time.Time) bool {
+	day := cs.days&(1<<uint(t.Day())) != 0
+	weekday := cs.weekdays&(1<<uint(t.Weekday())) != 0
+	if cs.daysRestricted && cs.weekdaysRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// Next returns the first time matching the schedule after the given time,
+// or the zero time if there is none in the foreseeable future, e.g. for
+// the 31st of February.
+func (cs *CronSchedule) Next(after time.Time) time.Time {
+	loc := cs.location()
+	t := after.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case cs.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !cs.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case cs.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case cs.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// CronSchedule returns the schedule declared with `Schedule=`, e.g.
+// `Schedule=*/15 * * * *`, at which the engine runs instances of the
+// template Job, or nil if the Job declares none or an invalid one.
+func (j *Job) CronSchedule() *CronSchedule {
+	val := lastValue(j.requirements()[fleetSchedule])
+	if val == "" {
+		return nil
+	}
+
+	cs, err := ParseCronSchedule(val)
+	if err != nil {
+		log.V(1).Infof("Ignoring %s of Job(%s): %v", fleetSchedule, j.Name, err)
+		return nil
+	}
+	return cs
+}
+
+// ConcurrencyPolicy returns how runs of a Job with a CronSchedule overlap,
+// as declared with `ConcurrencyPolicy=forbid|allow|replace`. Missing or
+// invalid declarations forbid overlapping runs.
+func (j *Job) ConcurrencyPolicy() string {
+	val := lastValue(j.requirements()[fleetConcurrencyPolicy])
+	switch val {
+	case ConcurrencyAllow, ConcurrencyReplace:
+		return val
+	case "", ConcurrencyForbid:
+	default:
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetConcurrencyPolicy, val, j.Name)
+	}
+	return ConcurrencyForbid
+}
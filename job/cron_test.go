@@ -0,0 +1,102 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	for i, tt := range []struct {
+		in  string
+		err bool
+	}{
+		{"*/15 * * * *", false},
+		{"0 9-17 * * 1-5", false},
+		{"0,30 0 1 1,7 *", false},
+		{"5/10 * * * 0,7 Europe/Berlin", false},
+		{"", true},
+		{"* * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"*/0 * * * *", true},
+		{"5-1 * * * *", true},
+		{"a * * * *", true},
+		{"* * * * * Nowhere/Special", true},
+		{"* * * * * UTC extra", true},
+	} {
+		_, err := ParseCronSchedule(tt.in)
+		if tt.err && err == nil {
+			t.Errorf("case %d: expected error parsing %q", i, tt.in)
+		} else if !tt.err && err != nil {
+			t.Errorf("case %d: unexpected error parsing %q: %v", i, tt.in, err)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2015, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	for i, tt := range []struct {
+		schedule string
+		after    time.Time
+		want     time.Time
+	}{
+		{"*/15 * * * *", at(time.March, 2, 10, 7), at(time.March, 2, 10, 15)},
+		// the next tick is strictly after the given time
+		{"*/15 * * * *", at(time.March, 2, 10, 15), at(time.March, 2, 10, 30)},
+		{"*/15 * * * *", at(time.March, 2, 23, 50), at(time.March, 3, 0, 0)},
+		{"0 9-17 * * 1-5", at(time.March, 6, 17, 0), at(time.March, 9, 9, 0)},
+		{"30 2 1 * *", at(time.December, 1, 3, 0), time.Date(2016, time.January, 1, 2, 30, 0, 0, time.UTC)},
+		// restricted days of month and week match either
+		{"0 0 15 * 0", at(time.March, 2, 0, 0), at(time.March, 8, 0, 0)},
+		{"0 0 15 * 7", at(time.March, 9, 0, 0), at(time.March, 15, 0, 0)},
+		{"0 0 31 2 *", at(time.March, 2, 0, 0), time.Time{}},
+	} {
+		cs, err := ParseCronSchedule(tt.schedule)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if got := cs.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("case %d: next tick of %q after %v is %v, want %v", i, tt.schedule, tt.after, got, tt.want)
+		}
+	}
+
+	cs, err := ParseCronSchedule("0 9 * * * America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cs.Next(at(time.March, 2, 0, 0)), at(time.March, 2, 14, 0); !got.Equal(want) {
+		t.Errorf("next tick in New York is %v, want %v", got, want)
+	}
+}
+
+func TestJobConcurrencyPolicy(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     string
+	}{
+		{"", ConcurrencyForbid},
+		{"[X-Fleet]\nConcurrencyPolicy=allow", ConcurrencyAllow},
+		{"[X-Fleet]\nConcurrencyPolicy=replace", ConcurrencyReplace},
+		{"[X-Fleet]\nConcurrencyPolicy=bogus", ConcurrencyForbid},
+	} {
+		j := NewJob("backup@.service", *newUnit(t, tt.contents))
+		if got := j.ConcurrencyPolicy(); got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, got, tt.want)
+		}
+	}
+
+	j := NewJob("backup@.service", *newUnit(t, "[X-Fleet]\nSchedule=0 3 * * *"))
+	if j.CronSchedule() == nil {
+		t.Errorf("expected a schedule")
+	}
+	j = NewJob("backup@.service", *newUnit(t, "[X-Fleet]\nSchedule=daily"))
+	if j.CronSchedule() != nil {
+		t.Errorf("expected no schedule")
+	}
+}
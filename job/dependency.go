@@ -0,0 +1,55 @@
+package job
+
+import (
+	"fmt"
+
+	"github.com/coreos/fleet/pkg"
+)
+
+// FleetRequires returns the names of the Jobs declared with
+// `FleetRequires=`. This Job is only scheduled and started once each of them
+// is active somewhere in the cluster.
+func (j *Job) FleetRequires() []string {
+	return nonEmpty(j.requirements()[fleetRequires])
+}
+
+// FleetAfter returns the names of the Jobs declared with `FleetAfter=`.
+// This Job is only scheduled and started once each of them that is meant to
+// be launched is active somewhere in the cluster.
+func (j *Job) FleetAfter() []string {
+	return nonEmpty(j.requirements()[fleetAfter])
+}
+
+// UnmetDependency describes the first dependency declared with
+// `FleetRequires=` or `FleetAfter=` that keeps this Job from starting, given
+// the names of the Jobs with target state launched and of those active in
+// the cluster. An empty string is returned if all dependencies are met.
+func (j *Job) UnmetDependency(launched, active pkg.Set) string {
+	for _, name := range j.FleetRequires() {
+		if !active.Contains(name) {
+			return fmt.Sprintf("required Unit(%s) is not active", name)
+		}
+	}
+	for _, name := range j.FleetAfter() {
+		if launched.Contains(name) && !active.Contains(name) {
+			return fmt.Sprintf("waiting for Unit(%s) to become active", name)
+		}
+	}
+	return ""
+}
+
+// HasDependencies reports whether the Job declares any `FleetRequires=` or
+// `FleetAfter=` dependency
+func (j *Job) HasDependencies() bool {
+	return len(j.FleetRequires()) > 0 || len(j.FleetAfter()) > 0
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if len(v) > 0 {
+			out = append(out, v)
+		}
+	}
+	return out
+}
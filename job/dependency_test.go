@@ -0,0 +1,49 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/pkg"
+)
+
+func TestJobDependencies(t *testing.T) {
+	contents := "[X-Fleet]\nFleetRequires=db.service\nFleetRequires=\nFleetAfter=cache@%i.service\nFleetAfter=log.service"
+	j := NewJob("web@1.service", *newUnit(t, contents))
+
+	if got, want := j.FleetRequires(), []string{"db.service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FleetRequires returned %v, want %v", got, want)
+	}
+	if got, want := j.FleetAfter(), []string{"cache@1.service", "log.service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FleetAfter returned %v, want %v", got, want)
+	}
+	if !j.HasDependencies() {
+		t.Errorf("Job with dependencies reported none")
+	}
+	if NewJob("foo.service", *newUnit(t, "")).HasDependencies() {
+		t.Errorf("Job without dependencies reported some")
+	}
+}
+
+func TestJobUnmetDependency(t *testing.T) {
+	j := NewJob("web.service", *newUnit(t, "[X-Fleet]\nFleetRequires=db.service\nFleetAfter=cache.service"))
+
+	for i, tt := range []struct {
+		launched []string
+		active   []string
+		met      bool
+	}{
+		// required Units must be active, launched or not
+		{nil, nil, false},
+		{[]string{"db.service"}, nil, false},
+		{nil, []string{"db.service"}, true},
+		// Units to start after only matter if they are launched
+		{[]string{"cache.service"}, []string{"db.service"}, false},
+		{[]string{"cache.service"}, []string{"db.service", "cache.service"}, true},
+	} {
+		reason := j.UnmetDependency(pkg.NewUnsafeSet(tt.launched...), pkg.NewUnsafeSet(tt.active...))
+		if (reason == "") != tt.met {
+			t.Errorf("case %d: UnmetDependency returned %q, want met=%t", i, reason, tt.met)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package job
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	configNamespaceMax = 255
+	validConfigChars   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./"
+)
+
+// FleetEnvironment returns the registry namespaces declared with
+// `FleetEnvironment=`. The configuration values stored in them are passed
+// to the Job as environment variables, values of later namespaces taking
+// precedence over those of earlier ones.
+func (j *Job) FleetEnvironment() []string {
+	return nonEmpty(j.requirements()[fleetEnvironment])
+}
+
+// RestartOnEnvironmentChange reports whether the agent restarts the Job
+// when the values of its FleetEnvironment change, which it does unless
+// `FleetEnvironmentRestart=false`
+func (j *Job) RestartOnEnvironmentChange() bool {
+	return strings.ToLower(lastValue(j.requirements()[fleetEnvironmentRestart])) != "false"
+}
+
+// ValidateConfigNamespace ensures that the given name of a namespace of
+// configuration values is valid: slash-separated path elements made of
+// letters, digits, "-", "_" and ".", e.g. myapp/prod
+func ValidateConfigNamespace(ns string) error {
+	if ns == "" {
+		return errors.New("namespace cannot be empty")
+	}
+	if len(ns) > configNamespaceMax {
+		return errors.New("namespace is too long")
+	}
+	for _, char := range ns {
+		if !strings.ContainsRune(validConfigChars, char) {
+			return errors.New("namespace may only contain letters, digits and any of -_./")
+		}
+	}
+	for _, elem := range strings.Split(ns, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return errors.New("namespace must consist of non-empty path elements other than . and ..")
+		}
+	}
+	return nil
+}
+
+// ValidateConfigKey ensures that the given key of a configuration value is a
+// valid environment variable name
+func ValidateConfigKey(key string) error {
+	if key == "" {
+		return errors.New("key cannot be empty")
+	}
+	for i, char := range key {
+		switch {
+		case char == '_', char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z':
+		case char >= '0' && char <= '9' && i > 0:
+		default:
+			return errors.New("key must be an environment variable name, e.g. DB_URL")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJobFleetEnvironment(t *testing.T) {
+	for i, tt := range []struct {
+		name     string
+		contents string
+		want     []string
+		restart  bool
+	}{
+		{"foo.service", "", nil, true},
+		{"foo.service", "[X-Fleet]\nFleetEnvironment=myapp/common\nFleetEnvironment=myapp/prod", []string{"myapp/common", "myapp/prod"}, true},
+		{"foo@prod.service", "[X-Fleet]\nFleetEnvironment=myapp/%i", []string{"myapp/prod"}, true},
+		{"foo.service", "[X-Fleet]\nFleetEnvironment=myapp\nFleetEnvironmentRestart=false", []string{"myapp"}, false},
+		{"foo.service", "[X-Fleet]\nFleetEnvironment=myapp\nFleetEnvironmentRestart=true", []string{"myapp"}, true},
+	} {
+		j := NewJob(tt.name, *newUnit(t, tt.contents))
+		if got := j.FleetEnvironment(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: FleetEnvironment returned %v, want %v", i, got, tt.want)
+		}
+		if got := j.RestartOnEnvironmentChange(); got != tt.restart {
+			t.Errorf("case %d: RestartOnEnvironmentChange returned %t, want %t", i, got, tt.restart)
+		}
+	}
+}
+
+func TestValidateConfigNamespace(t *testing.T) {
+	for _, ns := range []string{"myapp", "myapp/prod", "my-app_1.0/eu-west"} {
+		if err := ValidateConfigNamespace(ns); err != nil {
+			t.Errorf("Unexpected error validating %q: %v", ns, err)
+		}
+	}
+	for _, ns := range []string{"", "/myapp", "myapp/", "myapp//prod", "myapp/../other", "my app", "myapp?"} {
+		if err := ValidateConfigNamespace(ns); err == nil {
+			t.Errorf("Expected error validating %q", ns)
+		}
+	}
+}
+
+func TestValidateConfigKey(t *testing.T) {
+	for _, key := range []string{"DB_URL", "_private", "v2"} {
+		if err := ValidateConfigKey(key); err != nil {
+			t.Errorf("Unexpected error validating %q: %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "2FA", "DB-URL", "DB URL", "DB=URL"} {
+		if err := ValidateConfigKey(key); err == nil {
+			t.Errorf("Expected error validating %q", key)
+		}
+	}
+}
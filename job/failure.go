@@ -0,0 +1,116 @@
+package job
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	// OnFailure value asking for a Job to be moved to another machine
+	// once it has failed persistently on its current machine
+	OnFailureReschedule = "reschedule"
+
+	defaultMaxRestarts   = 3
+	defaultRestartWindow = 5 * time.Minute
+)
+
+// FailurePolicy describes how a Job that keeps failing on its machine is
+// handled. Within any RestartWindow, the agent restarts the Job locally up
+// to MaxRestarts times; the next failure is reported to the engine, which
+// moves the Job to another machine. The machine the Job failed on is then
+// avoided for the Job for Taint, if set.
+type FailurePolicy struct {
+	MaxRestarts   int
+	RestartWindow time.Duration
+	Taint         time.Duration
+}
+
+// FailurePolicy returns the policy declared with `OnFailure=reschedule` and
+// the optional `MaxRestarts=`, `RestartWindow=` and `FailureTaint=` options,
+// or nil if the Job should not be moved when it fails. Durations are given
+// in the form accepted by time.ParseDuration, e.g. `RestartWindow=10m`.
+// Invalid declarations fall back to the defaults.
+func (j *Job) FailurePolicy() *FailurePolicy {
+	reqs := j.requirements()
+	if lastValue(reqs[fleetOnFailure]) != OnFailureReschedule {
+		return nil
+	}
+
+	p := FailurePolicy{
+		MaxRestarts:   defaultMaxRestarts,
+		RestartWindow: defaultRestartWindow,
+	}
+
+	if val := lastValue(reqs[fleetMaxRestarts]); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			p.MaxRestarts = n
+		} else {
+			log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetMaxRestarts, val, j.Name)
+		}
+	}
+	if d, ok := j.requiredDuration(reqs, fleetRestartWindow); ok {
+		p.RestartWindow = d
+	}
+	if d, ok := j.requiredDuration(reqs, fleetFailureTaint); ok {
+		p.Taint = d
+	}
+
+	return &p
+}
+
+// RescheduleDelay returns how long the engine waits after the machine of the
+// Job went away before moving the Job to another machine, as declared with
+// `RescheduleDelay=`, e.g. `RescheduleDelay=2m`, and whether the Job
+// declares a valid delay. Zero moves the Job right away.
+func (j *Job) RescheduleDelay() (time.Duration, bool) {
+	val := lastValue(j.requirements()[fleetRescheduleDelay])
+	if val == "" {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetRescheduleDelay, val, j.Name)
+		return 0, false
+	}
+	return d, true
+}
+
+// ScheduleDeadline returns how long the Job may wait to be scheduled before
+// the engine gives up on it, as declared with `ScheduleDeadline=`, e.g.
+// `ScheduleDeadline=10m`, and whether the Job declares a valid deadline.
+func (j *Job) ScheduleDeadline() (time.Duration, bool) {
+	return j.requiredDuration(j.requirements(), fleetScheduleDeadline)
+}
+
+// TTL returns how long the Job may be active before the engine destroys it,
+// as declared with `TTL=`, e.g. `TTL=2h`, and whether the Job declares a
+// valid TTL.
+func (j *Job) TTL() (time.Duration, bool) {
+	return j.requiredDuration(j.requirements(), fleetTTL)
+}
+
+func (j *Job) requiredDuration(reqs map[string][]string, key string) (time.Duration, bool) {
+	val := lastValue(reqs[key])
+	if val == "" {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", key, val, j.Name)
+		return 0, false
+	}
+	return d, true
+}
+
+// lastValue returns the last of the given option values, as the last value
+// found wins, or an empty string if there are none.
+func lastValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
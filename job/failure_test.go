@@ -0,0 +1,84 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJobFailurePolicy(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     *FailurePolicy
+	}{
+		{"", nil},
+		{"[X-Fleet]\nMaxRestarts=2", nil},
+		{"[X-Fleet]\nOnFailure=ignore", nil},
+		{"[X-Fleet]\nOnFailure=reschedule", &FailurePolicy{MaxRestarts: 3, RestartWindow: 5 * time.Minute}},
+		{
+			"[X-Fleet]\nOnFailure=reschedule\nMaxRestarts=0\nRestartWindow=1h\nFailureTaint=30m",
+			&FailurePolicy{MaxRestarts: 0, RestartWindow: time.Hour, Taint: 30 * time.Minute},
+		},
+		// invalid values fall back to the defaults
+		{
+			"[X-Fleet]\nOnFailure=reschedule\nMaxRestarts=-1\nRestartWindow=often\nFailureTaint=-1m",
+			&FailurePolicy{MaxRestarts: 3, RestartWindow: 5 * time.Minute},
+		},
+		// multiple parameters - last wins
+		{
+			"[X-Fleet]\nOnFailure=reschedule\nMaxRestarts=1\nMaxRestarts=5",
+			&FailurePolicy{MaxRestarts: 5, RestartWindow: 5 * time.Minute},
+		},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.FailurePolicy(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: FailurePolicy returned %#v, want %#v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobRescheduleDelay(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		delay    time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"[X-Fleet]\nRescheduleDelay=2m", 2 * time.Minute, true},
+		{"[X-Fleet]\nRescheduleDelay=0", 0, true},
+		{"[X-Fleet]\nRescheduleDelay=-1m", 0, false},
+		{"[X-Fleet]\nRescheduleDelay=soon", 0, false},
+		// multiple parameters - last wins
+		{"[X-Fleet]\nRescheduleDelay=1m\nRescheduleDelay=30s", 30 * time.Second, true},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		delay, ok := j.RescheduleDelay()
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("case %d: RescheduleDelay returned (%v, %t), want (%v, %t)", i, delay, ok, tt.delay, tt.ok)
+		}
+	}
+}
+
+func TestJobLifetime(t *testing.T) {
+	for i, tt := range []struct {
+		contents   string
+		deadline   time.Duration
+		deadlineOK bool
+		ttl        time.Duration
+		ttlOK      bool
+	}{
+		{"", 0, false, 0, false},
+		{"[X-Fleet]\nScheduleDeadline=10m\nTTL=2h", 10 * time.Minute, true, 2 * time.Hour, true},
+		{"[X-Fleet]\nScheduleDeadline=0\nTTL=forever", 0, false, 0, false},
+		// multiple parameters - last wins
+		{"[X-Fleet]\nTTL=1h\nTTL=30m", 0, false, 30 * time.Minute, true},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if d, ok := j.ScheduleDeadline(); d != tt.deadline || ok != tt.deadlineOK {
+			t.Errorf("case %d: ScheduleDeadline returned (%v, %t), want (%v, %t)", i, d, ok, tt.deadline, tt.deadlineOK)
+		}
+		if d, ok := j.TTL(); d != tt.ttl || ok != tt.ttlOK {
+			t.Errorf("case %d: TTL returned (%v, %t), want (%v, %t)", i, d, ok, tt.ttl, tt.ttlOK)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package job
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	defaultHealthCheckInterval  = 30 * time.Second
+	defaultHealthCheckThreshold = 3
+)
+
+// HealthCheck describes how the agent probes the health of a running Job.
+// Exec is a command run with /bin/sh on the Job's machine, which succeeds if
+// it exits with status 0. HTTP is a URL requested with GET, which succeeds on
+// any 2xx or 3xx response. If both are given, both must succeed. A Job is
+// unhealthy once Threshold consecutive probes have failed.
+type HealthCheck struct {
+	Exec      string
+	HTTP      string
+	Interval  time.Duration
+	Threshold int
+}
+
+// HealthCheck returns the health check declared with `HealthCheckExec=`
+// and/or `HealthCheckHTTP=`, along with the optional `HealthCheckInterval=`
+// and `HealthCheckThreshold=`, or nil if the Job declares no health check.
+// Invalid intervals and thresholds fall back to the defaults.
+func (j *Job) HealthCheck() *HealthCheck {
+	reqs := j.requirements()
+	hc := HealthCheck{
+		Exec:      lastValue(reqs[fleetHealthCheckExec]),
+		HTTP:      lastValue(reqs[fleetHealthCheckHTTP]),
+		Interval:  defaultHealthCheckInterval,
+		Threshold: defaultHealthCheckThreshold,
+	}
+	if hc.Exec == "" && hc.HTTP == "" {
+		return nil
+	}
+
+	if d, ok := j.requiredDuration(reqs, fleetHealthCheckInterval); ok {
+		hc.Interval = d
+	}
+	if val := lastValue(reqs[fleetHealthCheckThreshold]); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			hc.Threshold = n
+		} else {
+			log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetHealthCheckThreshold, val, j.Name)
+		}
+	}
+
+	return &hc
+}
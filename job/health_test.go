@@ -0,0 +1,35 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJobHealthCheck(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     *HealthCheck
+	}{
+		{"", nil},
+		{"[X-Fleet]\nHealthCheckInterval=10s", nil},
+		{
+			"[X-Fleet]\nHealthCheckExec=/usr/bin/check",
+			&HealthCheck{Exec: "/usr/bin/check", Interval: 30 * time.Second, Threshold: 3},
+		},
+		{
+			"[X-Fleet]\nHealthCheckHTTP=http://127.0.0.1:8080/health\nHealthCheckInterval=5s\nHealthCheckThreshold=1",
+			&HealthCheck{HTTP: "http://127.0.0.1:8080/health", Interval: 5 * time.Second, Threshold: 1},
+		},
+		// invalid values fall back to the defaults
+		{
+			"[X-Fleet]\nHealthCheckExec=true\nHealthCheckInterval=soon\nHealthCheckThreshold=0",
+			&HealthCheck{Exec: "true", Interval: 30 * time.Second, Threshold: 3},
+		},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.HealthCheck(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: HealthCheck returned %#v, want %#v", i, got, tt.want)
+		}
+	}
+}
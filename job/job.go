@@ -1,10 +1,17 @@
 package job
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
+	gsunit "github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-systemd/unit"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/unit"
 )
 
@@ -32,6 +39,84 @@ const (
 	fleetMachineMetadata = "MachineMetadata"
 	// Require that the unit be scheduled on every machine in the cluster
 	fleetGlobal = "Global"
+	// Limit the time of day during which the unit may be scheduled
+	fleetWorkloadWindow = "WorkloadWindow"
+	// Reserve an amount of memory (in MB) on the target machine
+	fleetMemoryReservation = "MemoryReservation"
+	// Reserve an amount of CPU (in hundredths of a core) on the target machine
+	fleetCPUUnits = "CPUUnits"
+	// Reserve an amount of disk space (in MB) on the target machine
+	fleetDiskReservation = "DiskReservation"
+	// Reserve countable resources advertised by the target machine, e.g. gpu:1
+	fleetResourceRequest = "ResourceRequest"
+	// Have the agent enforce the memory and CPU reservations of the unit
+	fleetEnforceReservations = "EnforceReservations"
+	// Host ports bound by the unit, which no other unit on its machine may bind
+	fleetPorts = "Ports"
+	// Relative importance of the unit when machines run out of resources
+	fleetPriority = "Priority"
+	// Order in which the agent starts the unit among those waiting to start
+	fleetStartPriority = "StartPriority"
+	// Run the unit to completion, never rerunning it once it succeeded
+	fleetBatch = "Batch"
+	// Crontab-style schedule at which the engine runs instances of a template unit
+	fleetSchedule = "Schedule"
+	// How runs of a scheduled template unit may overlap
+	fleetConcurrencyPolicy = "ConcurrencyPolicy"
+	// Prefer, but do not require, machines with this specific metadata
+	fleetPreferredMachineMetadata = "PreferredMachineMetadata"
+	// Prefer machines not marked preemptible over the cheapest machines
+	fleetAvoidPreemptibleMachines = "AvoidPreemptibleMachines"
+	// Extend Conflicts to all machines sharing a value of this metadata key
+	fleetConflictsWithMetadata = "ConflictsWithMetadata"
+	// Distribute the instances of a template evenly across the values of this metadata key
+	fleetSpreadAcross = "SpreadAcross"
+	// Largest difference in instances between two values of the SpreadAcross key
+	fleetMaxSkew = "MaxSkew"
+	// Take the place of the given unit on the machine this unit is scheduled to
+	fleetReplaces = "Replaces"
+	// Allow scheduling the unit to machines carrying a matching taint
+	fleetTolerates = "Tolerates"
+	// Schedule the unit to the machines of the given pool
+	fleetPool = "Pool"
+	// Move the unit to another machine once it fails persistently
+	fleetOnFailure = "OnFailure"
+	// Number of times a failed unit is restarted locally before OnFailure applies
+	fleetMaxRestarts = "MaxRestarts"
+	// Period over which failures of a unit are counted against MaxRestarts
+	fleetRestartWindow = "RestartWindow"
+	// Avoid the machine a unit failed on for this long
+	fleetFailureTaint = "FailureTaint"
+	// Wait this long after the unit's machine went away before moving the unit
+	fleetRescheduleDelay = "RescheduleDelay"
+	// Command run on the unit's machine to probe the unit's health
+	fleetHealthCheckExec = "HealthCheckExec"
+	// URL requested to probe the unit's health
+	fleetHealthCheckHTTP = "HealthCheckHTTP"
+	// Time between two probes of the unit's health
+	fleetHealthCheckInterval = "HealthCheckInterval"
+	// Number of consecutive failed probes after which the unit is unhealthy
+	fleetHealthCheckThreshold = "HealthCheckThreshold"
+	// Only schedule and start the unit once the given unit is active anywhere in the cluster
+	fleetRequires = "FleetRequires"
+	// Only schedule and start the unit once the given unit, if launched, is active
+	fleetAfter = "FleetAfter"
+	// Registry namespace of configuration values passed to the unit as environment variables
+	fleetEnvironment = "FleetEnvironment"
+	// Restart the unit when the values of its FleetEnvironment change
+	fleetEnvironmentRestart = "FleetEnvironmentRestart"
+	// Container runtime running the unit's image in place of its commands
+	fleetRuntime = "Runtime"
+	// Container image run by the unit's Runtime
+	fleetRuntimeImage = "RuntimeImage"
+	// Arguments passed to the container run by the unit's Runtime
+	fleetRuntimeArgs = "RuntimeArgs"
+	// How the unit picks up a replaced unit file: restart, reschedule or manual
+	fleetUpdatePolicy = "UpdatePolicy"
+	// Give up on scheduling the unit once it has waited this long to be placed
+	fleetScheduleDeadline = "ScheduleDeadline"
+	// Destroy the unit once it has been active for this long
+	fleetTTL = "TTL"
 
 	deprecatedXPrefix          = "X-"
 	deprecatedXConditionPrefix = "X-Condition"
@@ -49,6 +134,45 @@ var validRequirements = pkg.NewUnsafeSet(
 	deprecatedXConditionPrefix+fleetMachineMetadata,
 	fleetMachineMetadata,
 	fleetGlobal,
+	fleetWorkloadWindow,
+	fleetMemoryReservation,
+	fleetCPUUnits,
+	fleetDiskReservation,
+	fleetResourceRequest,
+	fleetEnforceReservations,
+	fleetPorts,
+	fleetPriority,
+	fleetStartPriority,
+	fleetBatch,
+	fleetSchedule,
+	fleetConcurrencyPolicy,
+	fleetPreferredMachineMetadata,
+	fleetAvoidPreemptibleMachines,
+	fleetConflictsWithMetadata,
+	fleetSpreadAcross,
+	fleetMaxSkew,
+	fleetReplaces,
+	fleetTolerates,
+	fleetPool,
+	fleetOnFailure,
+	fleetMaxRestarts,
+	fleetRestartWindow,
+	fleetFailureTaint,
+	fleetRescheduleDelay,
+	fleetHealthCheckExec,
+	fleetHealthCheckHTTP,
+	fleetHealthCheckInterval,
+	fleetHealthCheckThreshold,
+	fleetRequires,
+	fleetAfter,
+	fleetEnvironment,
+	fleetEnvironmentRestart,
+	fleetRuntime,
+	fleetRuntimeImage,
+	fleetRuntimeArgs,
+	fleetUpdatePolicy,
+	fleetScheduleDeadline,
+	fleetTTL,
 )
 
 func ParseJobState(s string) (JobState, error) {
@@ -85,6 +209,9 @@ type Unit struct {
 	Name        string
 	Unit        unit.UnitFile
 	TargetState JobState
+	// Labels are arbitrary key/value pairs users attach to the Unit to
+	// select related Units by, see ParseSelector
+	Labels map[string]string
 }
 
 // IsGlobal returns whether a Unit is considered a global unit
@@ -102,6 +229,45 @@ func (u *Unit) IsGlobal() bool {
 	return strings.ToLower(last) == "true"
 }
 
+// IsTemplate returns whether a Unit is a template unit (e.g. foo@.service).
+// Templates cannot be scheduled directly; see Instantiate.
+func (u *Unit) IsTemplate() bool {
+	uni := unit.NewUnitNameInfo(u.Name)
+	return uni != nil && uni.IsTemplate()
+}
+
+// Instantiate creates an instance of a template Unit using the given
+// instance ID, e.g. instantiating foo@.service with "1" produces a Unit
+// named foo@1.service. The instance receives its own copy of the template's
+// UnitFile. Specifiers such as %i are left untouched in the unit file
+// itself, as systemd expands them at runtime and fleet expands them in the
+// [X-Fleet] section based on the name of the instance.
+func (u *Unit) Instantiate(instanceID string) (*Unit, error) {
+	if !u.IsTemplate() {
+		return nil, fmt.Errorf("unit %s is not a template", u.Name)
+	}
+	if instanceID == "" {
+		return nil, errors.New("instance ID cannot be empty")
+	}
+
+	uni := unit.NewUnitNameInfo(u.Name)
+	suffix := strings.TrimPrefix(uni.Template, uni.Prefix+"@")
+	name := fmt.Sprintf("%s@%s%s", uni.Prefix, instanceID, suffix)
+
+	opts := make([]*gsunit.UnitOption, len(u.Unit.Options))
+	for i, opt := range u.Unit.Options {
+		o := *opt
+		opts[i] = &o
+	}
+
+	inst := Unit{
+		Name:        name,
+		Unit:        *unit.NewUnitFromOptions(opts),
+		TargetState: u.TargetState,
+	}
+	return &inst, nil
+}
+
 // NewJob creates a new Job based on the given name and Unit.
 // The returned Job has a populated UnitHash and empty JobState.
 // nil is returned on failure.
@@ -124,6 +290,38 @@ func (u *Unit) Conflicts() []string {
 	return j.Conflicts()
 }
 
+func (u *Unit) ConflictDomains() []string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.ConflictDomains()
+}
+
+func (u *Unit) Replaces() []string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.Replaces()
+}
+
+func (u *Unit) Tolerations() []machine.Toleration {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.Tolerations()
+}
+
+func (u *Unit) Pools() []string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.Pools()
+}
+
 func (u *Unit) Peers() []string {
 	j := &Job{
 		Name: u.Name,
@@ -148,6 +346,164 @@ func (u *Unit) RequiredTargetMetadata() map[string]pkg.Set {
 	return j.RequiredTargetMetadata()
 }
 
+func (u *Unit) Resources() resource.ResourceTuple {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.Resources()
+}
+
+func (u *Unit) ResourceRequests() resource.Counts {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.ResourceRequests()
+}
+
+func (u *Unit) Ports() []string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.Ports()
+}
+
+func (u *Unit) Priority() int {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.Priority()
+}
+
+func (u *Unit) StartPriority() int {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.StartPriority()
+}
+
+func (u *Unit) WorkloadWindow() *WorkloadWindow {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.WorkloadWindow()
+}
+
+func (u *Unit) IsBatch() bool {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.IsBatch()
+}
+
+func (u *Unit) CronSchedule() *CronSchedule {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.CronSchedule()
+}
+
+func (u *Unit) ConcurrencyPolicy() string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.ConcurrencyPolicy()
+}
+
+func (u *Unit) FailurePolicy() *FailurePolicy {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.FailurePolicy()
+}
+
+func (u *Unit) ReservationDropIn() *unit.UnitFile {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.ReservationDropIn()
+}
+
+func (u *Unit) EnforceReservations() string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.EnforceReservations()
+}
+
+func (u *Unit) Runtime() *Runtime {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.Runtime()
+}
+
+func (u *Unit) HealthCheck() *HealthCheck {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.HealthCheck()
+}
+
+func (u *Unit) FleetEnvironment() []string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.FleetEnvironment()
+}
+
+func (u *Unit) RestartOnEnvironmentChange() bool {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.RestartOnEnvironmentChange()
+}
+
+func (u *Unit) UpdatePolicy() string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.UpdatePolicy()
+}
+
+func (u *Unit) UnmetDependency(launched, active pkg.Set) string {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.UnmetDependency(launched, active)
+}
+
+func (u *Unit) HasDependencies() bool {
+	j := &Job{
+		Name: u.Name,
+		Unit: u.Unit,
+	}
+	return j.HasDependencies()
+}
+
+// Requirements returns all X-Fleet options of the Job, with the systemd
+// specifiers of their values substituted
+func (j *Job) Requirements() map[string][]string {
+	return j.requirements()
+}
+
 // requirements returns all relevant options from the [X-Fleet] section of a unit file.
 // Relevant options are identified with a `X-` prefix in the unit.
 // This prefix is stripped from relevant options before being returned.
@@ -192,6 +548,51 @@ func (j *Job) Conflicts() []string {
 	return conflicts
 }
 
+// ConflictDomains returns the metadata keys declared with
+// `ConflictsWithMetadata=`. A Job's Conflicts apply not only to the machine it
+// is scheduled to, but to every machine sharing a value for any of these
+// keys, e.g. all machines in the same region.
+func (j *Job) ConflictDomains() []string {
+	var keys []string
+	for _, key := range j.requirements()[fleetConflictsWithMetadata] {
+		if len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Replaces returns a list of Job names that this Job takes the place of.
+// Once this Job is scheduled to a machine, the Jobs it replaces may no
+// longer run there and are rescheduled elsewhere.
+func (j *Job) Replaces() []string {
+	replaces := make([]string, 0)
+	replaces = append(replaces, j.requirements()[fleetReplaces]...)
+	return replaces
+}
+
+// Pools returns the pools of machines the Job may be scheduled to, declared
+// with `Pool=`, which may be given more than once. `Pool=*` allows any
+// machine; Jobs declaring no pool may only run on machines outside of pools.
+func (j *Job) Pools() []string {
+	return j.requirements()[fleetPool]
+}
+
+// Tolerations returns the taints of machines the Job may be scheduled to
+// regardless, declared with `Tolerates=`. Invalid declarations are ignored.
+func (j *Job) Tolerations() []machine.Toleration {
+	var tols []machine.Toleration
+	for _, val := range j.requirements()[fleetTolerates] {
+		tol, err := machine.ParseToleration(val)
+		if err != nil {
+			log.V(1).Infof("Ignoring invalid %s=%q of Job(%s): %v", fleetTolerates, val, j.Name, err)
+			continue
+		}
+		tols = append(tols, tol)
+	}
+	return tols
+}
+
 // Peers returns a list of Job names that must be scheduled to the same
 // machine as this Job.
 func (j *Job) Peers() []string {
@@ -239,12 +640,30 @@ func (j *Job) RequiredTarget() (string, bool) {
 // requirements. Valid metadata fields are strings of the form `key=value`,
 // where both key and value are not the empty string.
 func (j *Job) RequiredTargetMetadata() map[string]pkg.Set {
+	return j.targetMetadata(deprecatedXConditionPrefix+fleetMachineMetadata, fleetMachineMetadata)
+}
+
+// PreferredTargetMetadata returns the metadata a Job would prefer its target
+// machine to have, as declared with `PreferredMachineMetadata=`. Unlike
+// RequiredTargetMetadata, these are not requirements: a machine matching
+// none of them is still eligible.
+func (j *Job) PreferredTargetMetadata() map[string]pkg.Set {
+	return j.targetMetadata(fleetPreferredMachineMetadata)
+}
+
+// AvoidsPreemptibleMachines returns whether the Job prefers machines not
+// marked preemptible, as declared with `AvoidPreemptibleMachines=true`,
+// rather than the cheapest machines able to run it
+func (j *Job) AvoidsPreemptibleMachines() bool {
+	return strings.ToLower(lastValue(j.requirements()[fleetAvoidPreemptibleMachines])) == "true"
+}
+
+// targetMetadata collects the key=value pairs of the given options into a
+// map of metadata keys to acceptable values
+func (j *Job) targetMetadata(keys ...string) map[string]pkg.Set {
 	metadata := make(map[string]pkg.Set)
 
-	for _, key := range []string{
-		deprecatedXConditionPrefix + fleetMachineMetadata,
-		fleetMachineMetadata,
-	} {
+	for _, key := range keys {
 		for _, valuePair := range j.requirements()[key] {
 			s := strings.Split(valuePair, "=")
 
@@ -266,6 +685,118 @@ func (j *Job) RequiredTargetMetadata() map[string]pkg.Set {
 	return metadata
 }
 
+// WorkloadWindow returns the daily window during which the Job may be
+// scheduled, or nil if the Job may be scheduled at any time. The window is
+// declared as `WorkloadWindow=HH:MM-HH:MM [location]`, e.g.
+// `WorkloadWindow=22:00-06:00 Europe/Berlin`. Times are interpreted in UTC
+// unless a location is given. Invalid declarations are refused when Units
+// are submitted, and ignored otherwise.
+func (j *Job) WorkloadWindow() *WorkloadWindow {
+	values := j.requirements()[fleetWorkloadWindow]
+	if len(values) == 0 {
+		return nil
+	}
+
+	// Last value found wins
+	w, err := ParseWorkloadWindow(values[len(values)-1])
+	if err != nil {
+		log.V(1).Infof("Ignoring WorkloadWindow of Job(%s): %v", j.Name, err)
+		return nil
+	}
+	return w
+}
+
+// Resources returns the resources the Job has asked to have reserved on its
+// target machine. Memory and disk space are declared in MB with
+// `MemoryReservation=` and `DiskReservation=`, and CPU in hundredths of a core
+// with `CPUUnits=` (i.e. 100 is one core). Missing or invalid declarations
+// reserve nothing.
+func (j *Job) Resources() resource.ResourceTuple {
+	reqs := j.requirements()
+	return resource.ResourceTuple{
+		Cores:  j.requiredResource(reqs, fleetCPUUnits),
+		Memory: j.requiredResource(reqs, fleetMemoryReservation),
+		Disk:   j.requiredResource(reqs, fleetDiskReservation),
+	}
+}
+
+// ResourceRequests returns the countable resources the Job has asked to have
+// reserved on its target machine, declared with `ResourceRequest=` as a list
+// like `gpu:1,fpga:2`. Requests for the same resource add up. Invalid
+// declarations are ignored.
+func (j *Job) ResourceRequests() resource.Counts {
+	var requests []resource.Counts
+	for _, val := range j.requirements()[fleetResourceRequest] {
+		c, err := resource.ParseCounts(val)
+		if err != nil {
+			log.V(1).Infof("Ignoring invalid %s=%q of Job(%s): %v", fleetResourceRequest, val, j.Name, err)
+			continue
+		}
+		requests = append(requests, c)
+	}
+	return resource.SumCounts(requests...)
+}
+
+// Priority returns the scheduling priority of the Job as declared with
+// `Priority=`. Jobs with a higher priority may preempt Jobs with a lower
+// priority when no machine has enough free resources to run them. Jobs
+// without a valid declaration have priority 0.
+func (j *Job) Priority() int {
+	values := j.requirements()[fleetPriority]
+	if len(values) == 0 {
+		return 0
+	}
+
+	// Last value found wins
+	last := values[len(values)-1]
+	p, err := strconv.Atoi(last)
+	if err != nil {
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetPriority, last, j.Name)
+		return 0
+	}
+	return p
+}
+
+// StartPriority returns the priority with which the agent starts the Job as
+// declared with `StartPriority=`. When an agent limits the number of units
+// starting at once, e.g. after its machine rebooted, Jobs with a higher
+// priority start first. Jobs without a valid declaration have priority 0.
+func (j *Job) StartPriority() int {
+	last := lastValue(j.requirements()[fleetStartPriority])
+	if last == "" {
+		return 0
+	}
+	p, err := strconv.Atoi(last)
+	if err != nil {
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetStartPriority, last, j.Name)
+		return 0
+	}
+	return p
+}
+
+// IsBatch returns whether the Job runs to completion, as declared with
+// `Batch=true`. Once a batch Job has completed successfully, it is neither
+// restarted nor rescheduled.
+func (j *Job) IsBatch() bool {
+	return strings.ToLower(lastValue(j.requirements()[fleetBatch])) == "true"
+}
+
+func (j *Job) requiredResource(reqs map[string][]string, key string) int {
+	values := reqs[key]
+	if len(values) == 0 {
+		return 0
+	}
+
+	// Last value found wins
+	last := values[len(values)-1]
+	v, err := strconv.Atoi(last)
+	if err != nil || v < 0 {
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", key, last, j.Name)
+		return 0
+	}
+	return v
+}
+
 func (j *Job) Scheduled() bool {
 	return len(j.TargetMachineID) > 0
 }
@@ -5,7 +5,9 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/pkg"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/unit"
 )
 
@@ -410,6 +412,30 @@ X-ConditionMachineMetadata="one=abc" "two=def"`,
 	}
 }
 
+func TestJobPreferredTargetMetadata(t *testing.T) {
+	j := NewJob("echo.service", *newUnit(t, `[X-Fleet]
+MachineMetadata=region=us-east
+PreferredMachineMetadata=disk=ssd
+PreferredMachineMetadata=disk=nvme
+PreferredMachineMetadata="zone=a" "bad="`))
+
+	want := map[string]pkg.Set{
+		"disk": pkg.NewUnsafeSet("ssd", "nvme"),
+		"zone": pkg.NewUnsafeSet("a"),
+	}
+	if got := j.PreferredTargetMetadata(); !reflect.DeepEqual(want, got) {
+		t.Errorf("preferred metadata differs: got %#v, want %#v", got, want)
+	}
+
+	// preferences are not requirements
+	want = map[string]pkg.Set{
+		"region": pkg.NewUnsafeSet("us-east"),
+	}
+	if got := j.RequiredTargetMetadata(); !reflect.DeepEqual(want, got) {
+		t.Errorf("required metadata differs: got %#v, want %#v", got, want)
+	}
+}
+
 func TestInstanceUnitPrintf(t *testing.T) {
 	u := unit.NewUnitNameInfo("foo@bar.waldo")
 	if u == nil {
@@ -504,6 +530,268 @@ func TestUnitIsGlobal(t *testing.T) {
 	}
 }
 
+func TestJobWorkloadWindow(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     string
+	}{
+		// no window
+		{"", ""},
+		{"[X-Fleet]\nGlobal=true", ""},
+		// specified in wrong section
+		{"[Service]\nWorkloadWindow=22:00-06:00", ""},
+		// invalid windows are ignored
+		{"[X-Fleet]\nWorkloadWindow=late", ""},
+		// correct specifications
+		{"[X-Fleet]\nWorkloadWindow=22:00-06:00", "22:00-06:00 UTC"},
+		{"[X-Fleet]\nWorkloadWindow=22:00-06:00 UTC", "22:00-06:00 UTC"},
+		// multiple parameters - last wins
+		{"[X-Fleet]\nWorkloadWindow=22:00-06:00\nWorkloadWindow=01:00-02:00", "01:00-02:00 UTC"},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		w := j.WorkloadWindow()
+		var got string
+		if w != nil {
+			got = w.String()
+		}
+		if got != tt.want {
+			t.Errorf("case %d: WorkloadWindow returned %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobResources(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     resource.ResourceTuple
+	}{
+		{"", resource.ResourceTuple{}},
+		{"[Service]\nMemoryReservation=128", resource.ResourceTuple{}},
+		{"[X-Fleet]\nMemoryReservation=128", resource.ResourceTuple{Memory: 128}},
+		{"[X-Fleet]\nCPUUnits=50", resource.ResourceTuple{Cores: 50}},
+		{"[X-Fleet]\nCPUUnits=200\nMemoryReservation=512", resource.ResourceTuple{Cores: 200, Memory: 512}},
+		{"[X-Fleet]\nDiskReservation=4096", resource.ResourceTuple{Disk: 4096}},
+		// invalid values are ignored
+		{"[X-Fleet]\nMemoryReservation=lots", resource.ResourceTuple{}},
+		{"[X-Fleet]\nCPUUnits=-100", resource.ResourceTuple{}},
+		// multiple parameters - last wins
+		{"[X-Fleet]\nMemoryReservation=128\nMemoryReservation=256", resource.ResourceTuple{Memory: 256}},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.Resources(); got != tt.want {
+			t.Errorf("case %d: Resources returned %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobResourceRequests(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     resource.Counts
+	}{
+		{"", resource.Counts{}},
+		{"[X-Fleet]\nResourceRequest=gpu:1", resource.Counts{"gpu": 1}},
+		{"[X-Fleet]\nResourceRequest=gpu:1,fpga:2", resource.Counts{"gpu": 1, "fpga": 2}},
+		// requests add up
+		{"[X-Fleet]\nResourceRequest=gpu:1\nResourceRequest=gpu:2", resource.Counts{"gpu": 3}},
+		// invalid values are ignored
+		{"[X-Fleet]\nResourceRequest=gpu\nResourceRequest=fpga:1", resource.Counts{"fpga": 1}},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.ResourceRequests(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: ResourceRequests returned %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobConflictDomains(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     []string
+	}{
+		{"", nil},
+		{"[Service]\nConflictsWithMetadata=region", nil},
+		{"[X-Fleet]\nConflictsWithMetadata=region", []string{"region"}},
+		{"[X-Fleet]\nConflictsWithMetadata=region\nConflictsWithMetadata=rack", []string{"region", "rack"}},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.ConflictDomains(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: ConflictDomains returned %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobReplaces(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     []string
+	}{
+		{"", []string{}},
+		{"[Service]\nReplaces=bar.service", []string{}},
+		{"[X-Fleet]\nReplaces=bar.service", []string{"bar.service"}},
+		{"[X-Fleet]\nReplaces=bar.service\nReplaces=baz.service", []string{"bar.service", "baz.service"}},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.Replaces(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: Replaces returned %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobTolerations(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     []machine.Toleration
+	}{
+		{"", nil},
+		{"[X-Fleet]\nTolerates=dedicated=db:NoSchedule", []machine.Toleration{machine.Toleration{Key: "dedicated", Value: "db", Effect: machine.TaintEffectNoSchedule}}},
+		{"[X-Fleet]\nTolerates=maintenance\nTolerates=bogus:NoExecute", []machine.Toleration{machine.Toleration{Key: "maintenance"}}},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.Tolerations(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: Tolerations returned %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobPriority(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     int
+	}{
+		{"", 0},
+		{"[Service]\nPriority=10", 0},
+		{"[X-Fleet]\nPriority=10", 10},
+		{"[X-Fleet]\nPriority=-5", -5},
+		{"[X-Fleet]\nPriority=high", 0},
+		// multiple parameters - last wins
+		{"[X-Fleet]\nPriority=1\nPriority=2", 2},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.Priority(); got != tt.want {
+			t.Errorf("case %d: Priority returned %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobStartPriority(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     int
+	}{
+		{"", 0},
+		{"[X-Fleet]\nStartPriority=100", 100},
+		{"[X-Fleet]\nStartPriority=-1", -1},
+		{"[X-Fleet]\nStartPriority=first", 0},
+		{"[X-Fleet]\nPriority=10", 0},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.StartPriority(); got != tt.want {
+			t.Errorf("case %d: StartPriority returned %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobAvoidsPreemptibleMachines(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     bool
+	}{
+		{"", false},
+		{"[X-Fleet]\nAvoidPreemptibleMachines=true", true},
+		{"[X-Fleet]\nAvoidPreemptibleMachines=false", false},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.AvoidsPreemptibleMachines(); got != tt.want {
+			t.Errorf("case %d: AvoidsPreemptibleMachines returned %t, want %t", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobIsBatch(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     bool
+	}{
+		{"", false},
+		{"[X-Fleet]\nBatch=true", true},
+		{"[X-Fleet]\nBatch=True", true},
+		{"[X-Fleet]\nBatch=false", false},
+		{"[X-Fleet]\nBatch=true\nBatch=false", false},
+	} {
+		j := NewJob("foo.service", *newUnit(t, tt.contents))
+		if got := j.IsBatch(); got != tt.want {
+			t.Errorf("case %d: IsBatch returned %t, want %t", i, got, tt.want)
+		}
+	}
+}
+
+func TestUnitIsTemplate(t *testing.T) {
+	for i, tt := range []struct {
+		name string
+		want bool
+	}{
+		{"foo.service", false},
+		{"foo@.service", true},
+		{"foo@1.service", false},
+		{"ssh@.socket", true},
+		{"foo", false},
+	} {
+		u := Unit{Name: tt.name}
+		if got := u.IsTemplate(); got != tt.want {
+			t.Errorf("case %d: IsTemplate(%s) returned %t, want %t", i, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnitInstantiate(t *testing.T) {
+	tmpl := Unit{
+		Name:        "web@.service",
+		Unit:        *newUnit(t, "[Service]\nExecStart=/usr/bin/web %i\n\n[X-Fleet]\nConflicts=web@*.service\nMachineMetadata=instance=%i"),
+		TargetState: JobStateLaunched,
+	}
+
+	inst, err := tmpl.Instantiate("2")
+	if err != nil {
+		t.Fatalf("Unexpected error instantiating template: %v", err)
+	}
+	if inst.Name != "web@2.service" {
+		t.Errorf("Instance has unexpected name: got %q, want %q", inst.Name, "web@2.service")
+	}
+	if inst.TargetState != tmpl.TargetState {
+		t.Errorf("Instance has unexpected target state: got %q, want %q", inst.TargetState, tmpl.TargetState)
+	}
+	if inst.Unit.Hash() != tmpl.Unit.Hash() {
+		t.Errorf("Instance unit file differs from template")
+	}
+	if inst.IsTemplate() {
+		t.Errorf("Instance should not be considered a template")
+	}
+
+	md := inst.RequiredTargetMetadata()
+	if !md["instance"].Contains("2") {
+		t.Errorf("Specifier not expanded in instance requirements: %#v", md)
+	}
+
+	// expanding specifiers for the instance must not leak into the template
+	if vals := tmpl.Unit.Contents["X-Fleet"]["MachineMetadata"]; !reflect.DeepEqual(vals, []string{"instance=%i"}) {
+		t.Errorf("Template requirements modified by instance: %#v", vals)
+	}
+
+	for i, tt := range []struct {
+		u  Unit
+		id string
+	}{
+		{Unit{Name: "web@2.service"}, "3"},
+		{Unit{Name: "web.service"}, "3"},
+		{Unit{Name: "web@.service"}, ""},
+	} {
+		if _, err := tt.u.Instantiate(tt.id); err == nil {
+			t.Errorf("case %d: expected error instantiating %s with %q", i, tt.u.Name, tt.id)
+		}
+	}
+}
+
 func TestValidateRequirements(t *testing.T) {
 	tests := []string{
 		"MachineID=asdf",
@@ -0,0 +1,150 @@
+package job
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	labelMax        = 63
+	validLabelChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./"
+)
+
+// ValidateLabel ensures that the given key and value of a label of a Unit
+// are valid: up to 63 letters, digits, "-", "_", "." and "/" each, neither
+// of them empty
+func ValidateLabel(key, value string) error {
+	for i, s := range []string{key, value} {
+		what := [...]string{"key", "value"}[i]
+		if s == "" {
+			return fmt.Errorf("label %s cannot be empty", what)
+		}
+		if len(s) > labelMax {
+			return fmt.Errorf("label %s %q exceeds maximum length (%d)", what, s, labelMax)
+		}
+		for _, char := range s {
+			if !strings.ContainsRune(validLabelChars, char) {
+				return fmt.Errorf("invalid character %q in label %s %q", char, what, s)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateLabels ensures that all the given labels are valid
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if err := ValidateLabel(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseLabels parses labels given as a comma-separated list of key=value
+// pairs, e.g. app=web,env=staging
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if s == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("label %q not of the form key=value", pair)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if err := ValidateLabel(key, value); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// FormatLabels formats the given labels as ParseLabels parses them, sorted
+// by key
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Requirement is a condition on a label of a Unit a Selector imposes
+type Requirement struct {
+	Key string
+	// Value the label must have, or must not have if Negated. If empty,
+	// the label only has to exist, or not to exist if Negated.
+	Value   string
+	Negated bool
+}
+
+func (r Requirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	if r.Value == "" {
+		return ok != r.Negated
+	}
+	return (ok && value == r.Value) != r.Negated
+}
+
+// Selector selects Units by their labels. It matches the labels that meet
+// all of its Requirements, so an empty Selector matches any labels.
+type Selector []Requirement
+
+// ParseSelector parses a Selector given as a comma-separated list of
+// requirements, each of the form key=value, key!=value, key (the label
+// exists) or !key (the label does not exist), e.g. app=web,env!=staging
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		var r Requirement
+		switch {
+		case strings.Contains(term, "!="):
+			kv := strings.SplitN(term, "!=", 2)
+			r = Requirement{Key: kv[0], Value: kv[1], Negated: true}
+		case strings.Contains(term, "="):
+			kv := strings.SplitN(term, "=", 2)
+			r = Requirement{Key: kv[0], Value: kv[1]}
+		case strings.HasPrefix(term, "!"):
+			r = Requirement{Key: term[1:], Negated: true}
+		default:
+			r = Requirement{Key: term}
+		}
+		r.Key, r.Value = strings.TrimSpace(r.Key), strings.TrimSpace(r.Value)
+		if r.Key == "" {
+			return nil, fmt.Errorf("invalid selector %q: requirement %q has no label key", s, term)
+		}
+		value := r.Value
+		if value == "" {
+			if strings.Contains(term, "=") {
+				return nil, fmt.Errorf("invalid selector %q: requirement %q has no label value", s, term)
+			}
+			// only the key of existence requirements is validated
+			value = "x"
+		}
+		if err := ValidateLabel(r.Key, value); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", s, err)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches reports whether the given labels of a Unit meet all requirements
+// of the Selector
+func (sel Selector) Matches(labels map[string]string) bool {
+	for _, r := range sel {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
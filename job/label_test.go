@@ -0,0 +1,80 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+		err  bool
+	}{
+		{"", map[string]string{}, false},
+		{"app=web", map[string]string{"app": "web"}, false},
+		{"app=web, env=staging", map[string]string{"app": "web", "env": "staging"}, false},
+		{"team/owner=ops", map[string]string{"team/owner": "ops"}, false},
+		{"app", nil, true},
+		{"app=", nil, true},
+		{"=web", nil, true},
+		{"app=we b", nil, true},
+	}
+	for i, tt := range tests {
+		got, err := ParseLabels(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("case %d: unexpected error parsing %q: %v", i, tt.in, err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: parsed %q as %v, want %v", i, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	if got := FormatLabels(map[string]string{"env": "staging", "app": "web"}); got != "app=web,env=staging" {
+		t.Errorf("Unexpected formatted labels %q", got)
+	}
+	if got := FormatLabels(nil); got != "" {
+		t.Errorf("Unexpected formatted labels %q", got)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"app": "web", "env": "staging"}
+	tests := []struct {
+		selector string
+		match    bool
+	}{
+		{"", true},
+		{"app=web", true},
+		{"app=web,env=staging", true},
+		{"app=web,env=prod", false},
+		{"env!=prod", true},
+		{"env!=staging", false},
+		{"tier!=db", true},
+		{"app", true},
+		{"tier", false},
+		{"!tier", true},
+		{"!app", false},
+	}
+	for i, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Errorf("case %d: unexpected error parsing %q: %v", i, tt.selector, err)
+			continue
+		}
+		if got := sel.Matches(labels); got != tt.match {
+			t.Errorf("case %d: selector %q matches %v: got %t, want %t", i, tt.selector, labels, got, tt.match)
+		}
+	}
+}
+
+func TestParseSelectorInvalid(t *testing.T) {
+	for _, s := range []string{"=web", "app=", "!", "app!=", "app=web,", "a pp=web"} {
+		if _, err := ParseSelector(s); err == nil {
+			t.Errorf("Expected error parsing selector %q", s)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultNamespace is the namespace of Units whose name is not
+	// qualified with one
+	DefaultNamespace = "default"
+
+	// NamespaceSeparator separates the namespace of a Unit from the name
+	// of the Unit within it, e.g. team-a:web.service
+	NamespaceSeparator = ":"
+
+	namespaceMax        = 63
+	validNamespaceChars = "abcdefghijklmnopqrstuvwxyz0123456789-"
+)
+
+// SplitNamespace splits the given Unit name into the namespace of the Unit
+// and its name within the namespace
+func SplitNamespace(name string) (ns, base string) {
+	i := strings.Index(name, NamespaceSeparator)
+	if i < 0 {
+		return DefaultNamespace, name
+	}
+	return name[:i], name[i+1:]
+}
+
+// QualifyName returns the name of the Unit of the given name within the
+// given namespace
+func QualifyName(ns, base string) string {
+	if ns == "" || ns == DefaultNamespace {
+		return base
+	}
+	return ns + NamespaceSeparator + base
+}
+
+// InNamespace reports whether the named Unit belongs to one of the given
+// namespaces
+func InNamespace(name string, namespaces []string) bool {
+	ns, _ := SplitNamespace(name)
+	for _, allowed := range namespaces {
+		if ns == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateNamespace ensures that the given name of a namespace of Units is
+// valid: up to 63 lowercase letters, digits and "-", not starting or ending
+// with "-"
+func ValidateNamespace(ns string) error {
+	if ns == "" {
+		return errors.New("namespace cannot be empty")
+	}
+	if len(ns) > namespaceMax {
+		return fmt.Errorf("namespace exceeds maximum length (%d)", namespaceMax)
+	}
+	for _, char := range ns {
+		if !strings.ContainsRune(validNamespaceChars, char) {
+			return errors.New("namespace may only contain lowercase letters, digits and -")
+		}
+	}
+	if strings.HasPrefix(ns, "-") || strings.HasSuffix(ns, "-") {
+		return errors.New(`namespace cannot start or end in "-"`)
+	}
+	return nil
+}
+
+// referenceOptions are the X-Fleet options whose values name other Units,
+// or patterns matching their names
+var referenceOptions = []string{
+	deprecatedXConditionPrefix + fleetMachineOf,
+	fleetMachineOf,
+	deprecatedXPrefix + fleetConflicts,
+	fleetConflicts,
+	fleetReplaces,
+	fleetRequires,
+	fleetAfter,
+}
+
+// IsReferenceOption reports whether the values of the given X-Fleet option
+// name other Units
+func IsReferenceOption(name string) bool {
+	for _, opt := range referenceOptions {
+		if name == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// QualifyReference qualifies the given value of an X-Fleet option naming
+// another Unit with the given namespace, like QualifyName. Values starting
+// with the %n, %N or %p specifiers are left alone, as the name of the Unit
+// they expand to is already qualified.
+func QualifyReference(ns, ref string) string {
+	for _, spec := range []string{"%n", "%N", "%p"} {
+		if strings.HasPrefix(ref, spec) {
+			return ref
+		}
+	}
+	return QualifyName(ns, ref)
+}
+
+// References returns the names of the Units the Job refers to in its X-Fleet
+// options, and the patterns matching them given with `Conflicts=`
+func (j *Job) References() []string {
+	var refs []string
+	reqs := j.requirements()
+	for _, opt := range referenceOptions {
+		refs = append(refs, nonEmpty(reqs[opt])...)
+	}
+	return refs
+}
@@ -0,0 +1,52 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/fleet/log"
+)
+
+// ParsePorts parses a list of host ports like "8080 8443/tcp,53/udp",
+// separated by whitespace or commas. Ports without a protocol are TCP ports.
+// The ports are returned in the form "<port>/<protocol>".
+func ParsePorts(val string) ([]string, error) {
+	fields := strings.FieldsFunc(val, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	ports := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts := strings.SplitN(f, "/", 2)
+		proto := "tcp"
+		if len(parts) == 2 {
+			proto = strings.ToLower(parts[1])
+		}
+		if proto != "tcp" && proto != "udp" {
+			return nil, fmt.Errorf("invalid protocol of port %q: must be tcp or udp", f)
+		}
+		n, err := strconv.Atoi(parts[0])
+		if err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid port %q: must be between 1 and 65535", f)
+		}
+		ports = append(ports, fmt.Sprintf("%d/%s", n, proto))
+	}
+	return ports, nil
+}
+
+// Ports returns the host ports the Job binds on its machine, declared with
+// `Ports=`, in the form "<port>/<protocol>". Two Jobs binding the same port
+// cannot be scheduled to the same machine. Invalid declarations are ignored.
+func (j *Job) Ports() []string {
+	var ports []string
+	for _, val := range j.requirements()[fleetPorts] {
+		p, err := ParsePorts(val)
+		if err != nil {
+			log.V(1).Infof("Ignoring invalid %s=%q of Job(%s): %v", fleetPorts, val, j.Name, err)
+			continue
+		}
+		ports = append(ports, p...)
+	}
+	return ports
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	for i, tt := range []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{"", []string{}, false},
+		{"8080", []string{"8080/tcp"}, false},
+		{"8080 8443/tcp,53/UDP", []string{"8080/tcp", "8443/tcp", "53/udp"}, false},
+		{"http", nil, true},
+		{"0", nil, true},
+		{"65536", nil, true},
+		{"53/sctp", nil, true},
+	} {
+		got, err := ParsePorts(tt.in)
+		if tt.err != (err != nil) {
+			t.Errorf("case %d: unexpected error value: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJobPorts(t *testing.T) {
+	contents := "[X-Fleet]\nPorts=8080\nPorts=bogus\nPorts=53/udp"
+	j := NewJob("foo.service", *newUnit(t, contents))
+	want := []string{"8080/tcp", "53/udp"}
+	if got := j.Ports(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Ports returned %v, want %v", got, want)
+	}
+}
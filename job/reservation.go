@@ -0,0 +1,88 @@
+package job
+
+import (
+	"fmt"
+	"path"
+
+	gsunit "github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-systemd/unit"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	// EnforceNone uses the reservations of a Job for scheduling only
+	EnforceNone = "none"
+	// EnforceSoft protects the reserved memory of a Job from reclaim
+	// and weights its share of CPU by its reserved CPU
+	EnforceSoft = "soft"
+	// EnforceHard additionally limits a Job to its reservations
+	EnforceHard = "hard"
+)
+
+// resourceControlSections maps the types of the units whose resources
+// systemd controls to the section holding their resource control options
+var resourceControlSections = map[string]string{
+	".service": "Service",
+	".socket":  "Socket",
+	".mount":   "Mount",
+	".swap":    "Swap",
+}
+
+// EnforceReservations returns how the agent enforces the reservations of
+// the Job, as declared with `EnforceReservations=none|soft|hard`. Missing
+// or invalid declarations enforce nothing.
+func (j *Job) EnforceReservations() string {
+	val := lastValue(j.requirements()[fleetEnforceReservations])
+	switch val {
+	case EnforceSoft, EnforceHard:
+		return val
+	case "", EnforceNone:
+	default:
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetEnforceReservations, val, j.Name)
+	}
+	return EnforceNone
+}
+
+// ReservationDropIn returns the systemd drop-in enforcing the reservations
+// of the Job, or nil if the Job does not ask for its reservations to be
+// enforced or reserves nothing enforceable. Memory is protected with
+// MemoryLow= and CPU weighted with CPUWeight=, one core weighing as much
+// as the default. Hard enforcement also sets MemoryMax= and CPUQuota=.
+func (j *Job) ReservationDropIn() *unit.UnitFile {
+	mode := j.EnforceReservations()
+	if mode == EnforceNone {
+		return nil
+	}
+	section, ok := resourceControlSections[path.Ext(j.Name)]
+	if !ok {
+		return nil
+	}
+
+	res := j.Resources()
+	var opts []*gsunit.UnitOption
+	if res.Memory > 0 {
+		opts = append(opts, &gsunit.UnitOption{Section: section, Name: "MemoryLow", Value: fmt.Sprintf("%dM", res.Memory)})
+		if mode == EnforceHard {
+			opts = append(opts, &gsunit.UnitOption{Section: section, Name: "MemoryMax", Value: fmt.Sprintf("%dM", res.Memory)})
+		}
+	}
+	if res.Cores > 0 {
+		opts = append(opts, &gsunit.UnitOption{Section: section, Name: "CPUWeight", Value: fmt.Sprintf("%d", cpuWeight(res.Cores))})
+		if mode == EnforceHard {
+			opts = append(opts, &gsunit.UnitOption{Section: section, Name: "CPUQuota", Value: fmt.Sprintf("%d%%", res.Cores)})
+		}
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return unit.NewUnitFromOptions(opts)
+}
+
+// cpuWeight bounds the given CPU units to the weights systemd accepts
+func cpuWeight(cores int) int {
+	if cores > 10000 {
+		return 10000
+	}
+	return cores
+}
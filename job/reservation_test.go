@@ -0,0 +1,45 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestJobReservationDropIn(t *testing.T) {
+	for i, tt := range []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		// reservations are not enforced unless asked for
+		{"foo.service", "[X-Fleet]\nMemoryReservation=512\nCPUUnits=150", ""},
+		{"foo.service", "[X-Fleet]\nMemoryReservation=512\nEnforceReservations=none", ""},
+		{"foo.service", "[X-Fleet]\nMemoryReservation=512\nEnforceReservations=always", ""},
+		{"foo.service", "[X-Fleet]\nEnforceReservations=hard", ""},
+		{
+			"foo.service",
+			"[X-Fleet]\nMemoryReservation=512\nCPUUnits=150\nEnforceReservations=soft",
+			"[Service]\nMemoryLow=512M\nCPUWeight=150\n",
+		},
+		{
+			"foo.service",
+			"[X-Fleet]\nMemoryReservation=512\nCPUUnits=150\nEnforceReservations=hard",
+			"[Service]\nMemoryLow=512M\nMemoryMax=512M\nCPUWeight=150\nCPUQuota=150%\n",
+		},
+		{
+			"foo.socket",
+			"[X-Fleet]\nCPUUnits=20000\nEnforceReservations=soft",
+			"[Socket]\nCPUWeight=10000\n",
+		},
+		// timers have no resources to control
+		{"foo.timer", "[X-Fleet]\nCPUUnits=100\nEnforceReservations=hard", ""},
+	} {
+		j := NewJob(tt.name, *newUnit(t, tt.contents))
+		var got string
+		if d := j.ReservationDropIn(); d != nil {
+			got = d.String()
+		}
+		if got != tt.want {
+			t.Errorf("case %d: ReservationDropIn returned %q, want %q", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"fmt"
+)
+
+const (
+	// RuntimeDocker runs the container of a Job with Docker
+	RuntimeDocker = "docker"
+	// RuntimeRkt runs the container of a Job with rkt
+	RuntimeRkt = "rkt"
+)
+
+// Runtime describes the container a Job runs in place of its own commands,
+// as declared with `Runtime=`, `RuntimeImage=` and the optional
+// `RuntimeArgs=`, the arguments passed to the container as systemd would
+// split them.
+type Runtime struct {
+	Name  string
+	Image string
+	Args  string
+}
+
+// Runtime returns the container runtime the Job declares, or nil if it
+// declares none and runs as a plain systemd unit
+func (j *Job) Runtime() *Runtime {
+	reqs := j.requirements()
+	name := lastValue(reqs[fleetRuntime])
+	if name == "" {
+		return nil
+	}
+	return &Runtime{
+		Name:  name,
+		Image: lastValue(reqs[fleetRuntimeImage]),
+		Args:  lastValue(reqs[fleetRuntimeArgs]),
+	}
+}
+
+func checkRuntime(val string) error {
+	if val != RuntimeDocker && val != RuntimeRkt {
+		return fmt.Errorf("must be %s or %s", RuntimeDocker, RuntimeRkt)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJobRuntime(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     *Runtime
+	}{
+		{"", nil},
+		{"[X-Fleet]\nRuntimeImage=nginx", nil},
+		{"[X-Fleet]\nRuntime=docker\nRuntimeImage=nginx:1.9", &Runtime{Name: RuntimeDocker, Image: "nginx:1.9"}},
+		{"[X-Fleet]\nRuntime=rkt\nRuntimeImage=coreos.com/etcd:v2.0.0\nRuntimeArgs=--name db", &Runtime{Name: RuntimeRkt, Image: "coreos.com/etcd:v2.0.0", Args: "--name db"}},
+	} {
+		j := NewJob("web.service", *newUnit(t, tt.contents))
+		if got := j.Runtime(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: Runtime returned %#v, want %#v", i, got, tt.want)
+		}
+	}
+}
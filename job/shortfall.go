@@ -0,0 +1,45 @@
+package job
+
+import (
+	"strings"
+)
+
+const (
+	// Constraints a machine may fail to meet for a Job, see Shortfall
+	ConstraintTemplate       = "template"
+	ConstraintMachineID      = "machine-id"
+	ConstraintMetadata       = "metadata"
+	ConstraintPool           = "pool"
+	ConstraintTaint          = "taint"
+	ConstraintUnitLimit      = "unit-limit"
+	ConstraintDraining       = "draining"
+	ConstraintCordoned       = "cordoned"
+	ConstraintFailed         = "failed"
+	ConstraintPeer           = "peer"
+	ConstraintConflict       = "conflict"
+	ConstraintReplaced       = "replaced"
+	ConstraintPort           = "port"
+	ConstraintWorkloadWindow = "workload-window"
+	ConstraintCPU            = "cpu"
+	ConstraintMemory         = "memory"
+	ConstraintDisk           = "disk"
+	ConstraintResource       = "resource"
+	ConstraintConflictDomain = "conflict-domain"
+	ConstraintSpread         = "spread"
+)
+
+// Shortfall describes a constraint of a Job that a machine fails to meet,
+// e.g. because it has too little free memory
+type Shortfall struct {
+	Constraint string
+	Reason     string
+}
+
+// ShortfallReason combines the reasons of the given Shortfalls into one
+func ShortfallReason(sf []Shortfall) string {
+	reasons := make([]string, len(sf))
+	for i, s := range sf {
+		reasons[i] = s.Reason
+	}
+	return strings.Join(reasons, "; ")
+}
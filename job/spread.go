@@ -0,0 +1,52 @@
+package job
+
+import (
+	"strconv"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	defaultMaxSkew = 1
+)
+
+// SpreadConstraint describes how the instances of a template are
+// distributed across the values of a machine metadata key, e.g. the zones
+// of the cluster. An instance may only be scheduled to a machine if
+// afterwards no value has more than MaxSkew instances more than the value
+// with the fewest, among the values of the machines able to run it.
+type SpreadConstraint struct {
+	Key     string
+	MaxSkew int
+}
+
+// SpreadConstraint returns the constraint declared with `SpreadAcross=`
+// and the optional `MaxSkew=`, e.g. `SpreadAcross=zone`, or nil if the Job
+// declares none. Invalid MaxSkew declarations fall back to the default of 1.
+func (j *Job) SpreadConstraint() *SpreadConstraint {
+	reqs := j.requirements()
+	key := lastValue(reqs[fleetSpreadAcross])
+	if key == "" {
+		return nil
+	}
+
+	sc := SpreadConstraint{Key: key, MaxSkew: defaultMaxSkew}
+	if val := lastValue(reqs[fleetMaxSkew]); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			sc.MaxSkew = n
+		} else {
+			log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetMaxSkew, val, j.Name)
+		}
+	}
+	return &sc
+}
+
+// SpreadGroup returns the name of the group of Units the named Unit is
+// spread with: the template of an instance, or the Unit itself otherwise
+func SpreadGroup(name string) string {
+	if uni := unit.NewUnitNameInfo(name); uni != nil && uni.IsInstance() {
+		return uni.Template
+	}
+	return name
+}
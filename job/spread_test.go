@@ -0,0 +1,37 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJobSpreadConstraint(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     *SpreadConstraint
+	}{
+		{"", nil},
+		{"[X-Fleet]\nMaxSkew=2", nil},
+		{"[X-Fleet]\nSpreadAcross=zone", &SpreadConstraint{Key: "zone", MaxSkew: 1}},
+		{"[X-Fleet]\nSpreadAcross=rack\nMaxSkew=3", &SpreadConstraint{Key: "rack", MaxSkew: 3}},
+		// invalid skews fall back to the default
+		{"[X-Fleet]\nSpreadAcross=rack\nMaxSkew=0", &SpreadConstraint{Key: "rack", MaxSkew: 1}},
+	} {
+		j := NewJob("web@1.service", *newUnit(t, tt.contents))
+		if got := j.SpreadConstraint(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: SpreadConstraint returned %#v, want %#v", i, got, tt.want)
+		}
+	}
+}
+
+func TestSpreadGroup(t *testing.T) {
+	for name, want := range map[string]string{
+		"web@1.service": "web@.service",
+		"web@.service":  "web@.service",
+		"web.service":   "web.service",
+	} {
+		if got := SpreadGroup(name); got != want {
+			t.Errorf("SpreadGroup(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package job
+
+import (
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	// UpdateRestart has the agent running a Job load its new unit file
+	// in place and restart it if launched
+	UpdateRestart = "restart"
+	// UpdateReschedule has the engine unschedule a Job running an
+	// earlier unit file, so that it is scheduled again from scratch
+	UpdateReschedule = "reschedule"
+	// UpdateManual leaves a Job running its earlier unit file until it
+	// is next restarted or rescheduled by other means
+	UpdateManual = "manual"
+)
+
+// UpdatePolicy returns how a Job whose unit file is replaced picks up the
+// new unit file, as declared with `UpdatePolicy=restart|reschedule|manual`.
+// Missing or invalid declarations restart the Job in place. The policy of
+// the new unit file applies.
+func (j *Job) UpdatePolicy() string {
+	val := lastValue(j.requirements()[fleetUpdatePolicy])
+	switch val {
+	case UpdateReschedule, UpdateManual:
+		return val
+	case "", UpdateRestart:
+	default:
+		log.V(1).Infof("Ignoring invalid %s=%q of Job(%s)", fleetUpdatePolicy, val, j.Name)
+	}
+	return UpdateRestart
+}
@@ -0,0 +1,28 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestJobUpdatePolicy(t *testing.T) {
+	for i, tt := range []struct {
+		contents string
+		want     string
+	}{
+		{"", UpdateRestart},
+		{"[X-Fleet]\nUpdatePolicy=restart", UpdateRestart},
+		{"[X-Fleet]\nUpdatePolicy=reschedule", UpdateReschedule},
+		{"[X-Fleet]\nUpdatePolicy=manual", UpdateManual},
+		{"[X-Fleet]\nUpdatePolicy=bogus", UpdateRestart},
+	} {
+		j := NewJob("web.service", *newUnit(t, tt.contents))
+		if got := j.UpdatePolicy(); got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, got, tt.want)
+		}
+	}
+
+	j := NewJob("web.service", *newUnit(t, "[X-Fleet]\nUpdatePolicy=never"))
+	if errs := j.InvalidRequirements(); len(errs) != 1 {
+		t.Errorf("expected one invalid requirement, got %v", errs)
+	}
+}
@@ -0,0 +1,200 @@
+package job
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/resource"
+)
+
+// valueCheckers check the values of the [X-Fleet] options that take a
+// value of a particular form, returning why a value is invalid
+var valueCheckers = map[string]func(string) error{
+	fleetGlobal:                   checkBool,
+	fleetWorkloadWindow:           checkWorkloadWindow,
+	fleetMemoryReservation:        checkNonNegativeInt,
+	fleetCPUUnits:                 checkNonNegativeInt,
+	fleetDiskReservation:          checkNonNegativeInt,
+	fleetResourceRequest:          checkResourceRequest,
+	fleetEnforceReservations:      checkEnforceReservations,
+	fleetPorts:                    checkPorts,
+	fleetPriority:                 checkInt,
+	fleetBatch:                    checkBool,
+	fleetSchedule:                 checkSchedule,
+	fleetConcurrencyPolicy:        checkConcurrencyPolicy,
+	fleetMachineMetadata:          checkMetadata,
+	fleetPreferredMachineMetadata: checkMetadata,
+	fleetTolerates:                checkToleration,
+	fleetPool:                     checkPool,
+	fleetMaxSkew:                  checkPositiveInt,
+	fleetOnFailure:                checkOnFailure,
+	fleetMaxRestarts:              checkNonNegativeInt,
+	fleetRestartWindow:            checkDuration,
+	fleetFailureTaint:             checkDuration,
+	fleetRescheduleDelay:          checkNonNegativeDuration,
+	fleetHealthCheckHTTP:          checkHTTPURL,
+	fleetHealthCheckInterval:      checkDuration,
+	fleetHealthCheckThreshold:     checkPositiveInt,
+	fleetEnvironment:              ValidateConfigNamespace,
+	fleetEnvironmentRestart:       checkBool,
+	fleetRuntime:                  checkRuntime,
+	fleetUpdatePolicy:             checkUpdatePolicy,
+	fleetScheduleDeadline:         checkDuration,
+	fleetTTL:                      checkDuration,
+
+	deprecatedXConditionPrefix + fleetMachineMetadata: checkMetadata,
+}
+
+// InvalidRequirements returns an error for each option in the [X-Fleet]
+// section of the Job's unit file that is unknown or has an invalid value,
+// in the order in which they appear. fleet ignores such options.
+func (j *Job) InvalidRequirements() []error {
+	var errs []error
+	for _, opt := range j.Unit.Options {
+		if opt.Section != "X-Fleet" {
+			continue
+		}
+
+		if !validRequirements.Contains(opt.Name) {
+			errs = append(errs, fmt.Errorf("unrecognized requirement in [X-Fleet] section: %q", opt.Name))
+			continue
+		}
+
+		check, ok := valueCheckers[opt.Name]
+		if !ok {
+			continue
+		}
+		if err := check(opt.Value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: %v", opt.Name, opt.Value, err))
+		}
+	}
+	return errs
+}
+
+func checkResourceRequest(val string) error {
+	c, err := resource.ParseCounts(val)
+	if err == nil && len(c) == 0 {
+		err = fmt.Errorf("must name at least one resource")
+	}
+	return err
+}
+
+func checkPorts(val string) error {
+	p, err := ParsePorts(val)
+	if err == nil && len(p) == 0 {
+		err = fmt.Errorf("must name at least one port")
+	}
+	return err
+}
+
+func checkEnforceReservations(val string) error {
+	if val != EnforceNone && val != EnforceSoft && val != EnforceHard {
+		return fmt.Errorf("must be %s, %s or %s", EnforceNone, EnforceSoft, EnforceHard)
+	}
+	return nil
+}
+
+func checkSchedule(val string) error {
+	_, err := ParseCronSchedule(val)
+	return err
+}
+
+func checkConcurrencyPolicy(val string) error {
+	if val != ConcurrencyForbid && val != ConcurrencyAllow && val != ConcurrencyReplace {
+		return fmt.Errorf("must be %s, %s or %s", ConcurrencyForbid, ConcurrencyAllow, ConcurrencyReplace)
+	}
+	return nil
+}
+
+func checkUpdatePolicy(val string) error {
+	if val != UpdateRestart && val != UpdateReschedule && val != UpdateManual {
+		return fmt.Errorf("must be %s, %s or %s", UpdateRestart, UpdateReschedule, UpdateManual)
+	}
+	return nil
+}
+
+func checkBool(val string) error {
+	if v := strings.ToLower(val); v != "true" && v != "false" {
+		return fmt.Errorf("must be true or false")
+	}
+	return nil
+}
+
+func checkWorkloadWindow(val string) error {
+	_, err := ParseWorkloadWindow(val)
+	return err
+}
+
+func checkInt(val string) error {
+	if _, err := strconv.Atoi(val); err != nil {
+		return fmt.Errorf("must be an integer")
+	}
+	return nil
+}
+
+func checkNonNegativeInt(val string) error {
+	if n, err := strconv.Atoi(val); err != nil || n < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	return nil
+}
+
+func checkPositiveInt(val string) error {
+	if n, err := strconv.Atoi(val); err != nil || n <= 0 {
+		return fmt.Errorf("must be a positive integer")
+	}
+	return nil
+}
+
+func checkDuration(val string) error {
+	if d, err := time.ParseDuration(val); err != nil || d <= 0 {
+		return fmt.Errorf("must be a positive duration, e.g. 10m")
+	}
+	return nil
+}
+
+func checkNonNegativeDuration(val string) error {
+	if d, err := time.ParseDuration(val); err != nil || d < 0 {
+		return fmt.Errorf("must be a non-negative duration, e.g. 2m")
+	}
+	return nil
+}
+
+func checkMetadata(val string) error {
+	s := strings.Split(val, "=")
+	if len(s) != 2 || len(s[0]) == 0 || len(s[1]) == 0 {
+		return fmt.Errorf("must be of the form key=value")
+	}
+	return nil
+}
+
+func checkToleration(val string) error {
+	_, err := machine.ParseToleration(val)
+	return err
+}
+
+func checkPool(val string) error {
+	if val == machine.PoolAny {
+		return nil
+	}
+	return machine.ValidatePool(val)
+}
+
+func checkOnFailure(val string) error {
+	if val != OnFailureReschedule {
+		return fmt.Errorf("must be %s", OnFailureReschedule)
+	}
+	return nil
+}
+
+func checkHTTPURL(val string) error {
+	u, err := url.Parse(val)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an http or https URL")
+	}
+	return nil
+}
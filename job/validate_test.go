@@ -0,0 +1,95 @@
+package job
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInvalidRequirements(t *testing.T) {
+	valid := []string{
+		"MachineID=asdf",
+		"Global=True",
+		"MachineMetadata=region=us",
+		"X-ConditionMachineMetadata=up=down",
+		"WorkloadWindow=22:00-06:00 UTC",
+		"MemoryReservation=512",
+		"CPUUnits=0",
+		"EnforceReservations=soft",
+		"ResourceRequest=gpu:1,fpga:2",
+		"Ports=8080 53/udp",
+		"Priority=-5",
+		"Batch=true",
+		"Schedule=*/15 * * * *",
+		"ConcurrencyPolicy=replace",
+		"OnFailure=reschedule",
+		"MaxRestarts=0",
+		"RestartWindow=10m",
+		"RescheduleDelay=0",
+		"RescheduleDelay=90s",
+		"HealthCheckHTTP=http://localhost:8080/health",
+		"HealthCheckThreshold=1",
+		"Tolerates=dedicated=db:NoSchedule",
+		"Tolerates=maintenance",
+		"SpreadAcross=zone",
+		"MaxSkew=2",
+		"Runtime=docker",
+		"Runtime=rkt",
+		"Pool=batch",
+		"Pool=*",
+		"ScheduleDeadline=10m",
+		"TTL=2h",
+	}
+	for i, req := range valid {
+		j := NewJob("echo.service", *newUnit(t, fmt.Sprintf("[X-Fleet]\n%s", req)))
+		if errs := j.InvalidRequirements(); len(errs) != 0 {
+			t.Errorf("case %d: unexpected errors for %q: %v", i, req, errs)
+		}
+	}
+
+	invalid := []string{
+		"global=true",
+		"Global=yes",
+		"MachineMetadata=region",
+		"PreferredMachineMetadata==us",
+		"WorkloadWindow=22:00",
+		"MemoryReservation=512MB",
+		"DiskReservation=-1",
+		"EnforceReservations=true",
+		"ResourceRequest=gpu",
+		"Ports=http",
+		"Priority=high",
+		"Batch=once",
+		"Schedule=@hourly",
+		"ConcurrencyPolicy=queue",
+		"OnFailure=restart",
+		"RestartWindow=10",
+		"FailureTaint=-1h",
+		"RescheduleDelay=-5m",
+		"HealthCheckHTTP=localhost:8080",
+		"HealthCheckThreshold=0",
+		"Tolerates=dedicated:NoExecute",
+		"MaxSkew=0",
+		"Runtime=lxc",
+		"Pool=web,batch",
+		"ScheduleDeadline=0",
+		"TTL=1d",
+	}
+	for i, req := range invalid {
+		j := NewJob("echo.service", *newUnit(t, fmt.Sprintf("[X-Fleet]\n%s", req)))
+		if errs := j.InvalidRequirements(); len(errs) != 1 {
+			t.Errorf("case %d: expected one error for %q, got %v", i, req, errs)
+		}
+	}
+
+	j := NewJob("echo.service", *newUnit(t, "[Service]\nExecStart=/bin/true\n[X-Fleet]\nCPUUnits=lots\nBogus=1\nMemoryReservation=1"))
+	errs := j.InvalidRequirements()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %v", errs)
+	}
+	if want := `invalid CPUUnits="lots": must be a non-negative integer`; errs[0].Error() != want {
+		t.Errorf("Unexpected first error %q, want %q", errs[0], want)
+	}
+	if want := `unrecognized requirement in [X-Fleet] section: "Bogus"`; errs[1].Error() != want {
+		t.Errorf("Unexpected second error %q, want %q", errs[1], want)
+	}
+}
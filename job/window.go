@@ -0,0 +1,105 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const windowTimeLayout = "15:04"
+
+// WorkloadWindow describes a recurring daily period during which a Job may be
+// scheduled. Only the hour and minute of Start and End are significant. A
+// window whose End is earlier than its Start crosses midnight, e.g.
+// 22:00-06:00.
+type WorkloadWindow struct {
+	Start    time.Time
+	End      time.Time
+	Location *time.Location
+}
+
+// ParseWorkloadWindow parses a string of the form `HH:MM-HH:MM [location]`
+// into a WorkloadWindow.
+func ParseWorkloadWindow(s string) (*WorkloadWindow, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid workload window %q", s)
+	}
+
+	bounds := strings.Split(fields[0], "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("invalid workload window %q", s)
+	}
+
+	start, err := time.Parse(windowTimeLayout, bounds[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid workload window start %q", bounds[0])
+	}
+	end, err := time.Parse(windowTimeLayout, bounds[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid workload window end %q", bounds[1])
+	}
+	if start.Equal(end) {
+		return nil, fmt.Errorf("workload window %q is empty", s)
+	}
+
+	loc := time.UTC
+	if len(fields) == 2 {
+		loc, err = time.LoadLocation(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid workload window location %q: %v", fields[1], err)
+		}
+	}
+
+	w := WorkloadWindow{
+		Start:    start,
+		End:      end,
+		Location: loc,
+	}
+	return &w, nil
+}
+
+func (w *WorkloadWindow) location() *time.Location {
+	if w.Location == nil {
+		return time.UTC
+	}
+	return w.Location
+}
+
+// minuteOfDay returns the number of minutes since midnight of the given time
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// Contains determines whether the given time falls within the window
+func (w *WorkloadWindow) Contains(t time.Time) bool {
+	now := minuteOfDay(t.In(w.location()))
+	start := minuteOfDay(w.Start)
+	end := minuteOfDay(w.End)
+
+	if start < end {
+		return start <= now && now < end
+	}
+
+	// window crosses midnight
+	return now >= start || now < end
+}
+
+// NextOpen returns the earliest time at or after the given time at which the
+// window is open.
+func (w *WorkloadWindow) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	local := t.In(w.location())
+	open := time.Date(local.Year(), local.Month(), local.Day(), w.Start.Hour(), w.Start.Minute(), 0, 0, w.location())
+	if open.Before(local) {
+		open = open.AddDate(0, 0, 1)
+	}
+	return open
+}
+
+func (w *WorkloadWindow) String() string {
+	return fmt.Sprintf("%s-%s %s", w.Start.Format(windowTimeLayout), w.End.Format(windowTimeLayout), w.location())
+}
@@ -0,0 +1,115 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWorkloadWindow(t *testing.T) {
+	for i, tt := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{"22:00-06:00", "22:00-06:00 UTC", false},
+		{"09:30-17:00 UTC", "09:30-17:00 UTC", false},
+		{"  01:00-02:00  ", "01:00-02:00 UTC", false},
+		{"", "", true},
+		{"22:00", "", true},
+		{"22:00-06:00-07:00", "", true},
+		{"25:00-06:00", "", true},
+		{"22:00-6pm", "", true},
+		{"10:00-10:00", "", true},
+		{"22:00-06:00 Nowhere/Special", "", true},
+		{"22:00-06:00 UTC extra", "", true},
+	} {
+		w, err := ParseWorkloadWindow(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("case %d: expected error parsing %q", i, tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error parsing %q: %v", i, tt.in, err)
+			continue
+		}
+		if got := w.String(); got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestWorkloadWindowContains(t *testing.T) {
+	day, err := ParseWorkloadWindow("09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, err := ParseWorkloadWindow("22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(hour, min int) time.Time {
+		return time.Date(2014, time.October, 1, hour, min, 0, 0, time.UTC)
+	}
+
+	for i, tt := range []struct {
+		window *WorkloadWindow
+		t      time.Time
+		want   bool
+	}{
+		{day, at(8, 59), false},
+		{day, at(9, 0), true},
+		{day, at(12, 0), true},
+		{day, at(16, 59), true},
+		{day, at(17, 0), false},
+		{night, at(21, 59), false},
+		{night, at(22, 0), true},
+		{night, at(23, 59), true},
+		{night, at(0, 0), true},
+		{night, at(5, 59), true},
+		{night, at(6, 0), false},
+		{night, at(12, 0), false},
+		// times are compared in the window's location
+		{day, time.Date(2014, time.October, 1, 12, 0, 0, 0, time.FixedZone("X", 10*60*60)), false},
+	} {
+		if got := tt.window.Contains(tt.t); got != tt.want {
+			t.Errorf("case %d: Contains(%v) on %s returned %t, want %t", i, tt.t, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestWorkloadWindowNextOpen(t *testing.T) {
+	w, err := ParseWorkloadWindow("22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range []struct {
+		t    time.Time
+		want time.Time
+	}{
+		// already open
+		{time.Date(2014, time.October, 1, 23, 0, 0, 0, time.UTC), time.Date(2014, time.October, 1, 23, 0, 0, 0, time.UTC)},
+		{time.Date(2014, time.October, 1, 3, 0, 0, 0, time.UTC), time.Date(2014, time.October, 1, 3, 0, 0, 0, time.UTC)},
+		// opens later the same day
+		{time.Date(2014, time.October, 1, 12, 0, 0, 0, time.UTC), time.Date(2014, time.October, 1, 22, 0, 0, 0, time.UTC)},
+		{time.Date(2014, time.October, 1, 6, 0, 0, 0, time.UTC), time.Date(2014, time.October, 1, 22, 0, 0, 0, time.UTC)},
+	} {
+		if got := w.NextOpen(tt.t); !got.Equal(tt.want) {
+			t.Errorf("case %d: NextOpen(%v) returned %v, want %v", i, tt.t, got, tt.want)
+		}
+	}
+
+	// window that already closed today opens tomorrow
+	w, err = ParseWorkloadWindow("01:00-02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := w.NextOpen(time.Date(2014, time.October, 1, 3, 0, 0, 0, time.UTC))
+	want := time.Date(2014, time.October, 2, 1, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("NextOpen returned %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package machine
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+const (
+	// Scopes of the addresses of a machine, see Address
+	AddressScopePublic  = "public"
+	AddressScopePrivate = "private"
+
+	// Families of addresses a machine may prefer, see SortAddresses
+	IPPreferenceIPv4 = "ipv4"
+	IPPreferenceIPv6 = "ipv6"
+)
+
+// privateNetworks holds the networks addresses are private in, unless
+// configured otherwise: those of RFC 1918 and IPv6 unique local addresses
+var privateNetworks = mustParseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+
+// An Address is an IP address, IPv4 or IPv6, a machine can be reached at.
+// Public addresses are reachable from outside the network of the cluster,
+// private ones only from within.
+type Address struct {
+	IP    string
+	Scope string
+}
+
+// IsIPv6 determines whether the Address is an IPv6 address
+func (a Address) IsIPv6() bool {
+	ip := net.ParseIP(a.IP)
+	return ip != nil && ip.To4() == nil
+}
+
+func (a Address) String() string {
+	return fmt.Sprintf("%s (%s)", a.IP, a.Scope)
+}
+
+// ParseAddresses parses comma-separated lists of public and private IP
+// addresses, as given in the public_ip and private_ip options of fleet
+func ParseAddresses(public, private string) ([]Address, error) {
+	var addrs []Address
+	for _, list := range []struct {
+		scope string
+		ips   string
+	}{
+		{AddressScopePublic, public},
+		{AddressScopePrivate, private},
+	} {
+		for _, field := range strings.Split(list.ips, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			ip := net.ParseIP(field)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid %s IP address %q", list.scope, field)
+			}
+			addrs = append(addrs, Address{IP: ip.String(), Scope: list.scope})
+		}
+	}
+	return addrs, nil
+}
+
+// ValidateIPPreference returns an error if the given address family is
+// unknown
+func ValidateIPPreference(pref string) error {
+	switch pref {
+	case IPPreferenceIPv4, IPPreferenceIPv6:
+		return nil
+	}
+	return fmt.Errorf("invalid IP preference %q: must be %s or %s", pref, IPPreferenceIPv4, IPPreferenceIPv6)
+}
+
+// SortAddresses orders the given addresses by preference: addresses of the
+// preferred family, ipv4 or ipv6, before the others, and public addresses
+// before private ones of the same family. Addresses otherwise keep their
+// order.
+func SortAddresses(addrs []Address, pref string) {
+	sort.Stable(addressesByPreference{addrs: addrs, preferIPv6: pref == IPPreferenceIPv6})
+}
+
+type addressesByPreference struct {
+	addrs      []Address
+	preferIPv6 bool
+}
+
+func (s addressesByPreference) Len() int      { return len(s.addrs) }
+func (s addressesByPreference) Swap(i, j int) { s.addrs[i], s.addrs[j] = s.addrs[j], s.addrs[i] }
+func (s addressesByPreference) Less(i, j int) bool {
+	return s.rank(s.addrs[i]) < s.rank(s.addrs[j])
+}
+
+func (s addressesByPreference) rank(a Address) int {
+	r := 0
+	if a.IsIPv6() != s.preferIPv6 {
+		r += 2
+	}
+	if a.Scope != AddressScopePublic {
+		r++
+	}
+	return r
+}
+
+// addressScope returns the scope of the given IP address as determined by
+// the network it belongs to
+func addressScope(ip net.IP) string {
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return AddressScopePrivate
+		}
+	}
+	return AddressScopePublic
+}
+
+// AllAddresses returns the addresses the machine can be reached at, most
+// preferred first. Machines running older versions of fleet only publish
+// their PublicIP.
+func (ms MachineState) AllAddresses() []Address {
+	if len(ms.Addresses) > 0 {
+		return ms.Addresses
+	}
+	if ms.PublicIP == "" {
+		return nil
+	}
+	return []Address{{IP: ms.PublicIP, Scope: AddressScopePublic}}
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
@@ -0,0 +1,97 @@
+package machine
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseAddresses(t *testing.T) {
+	got, err := ParseAddresses("203.0.113.5, 2001:db8::5", "10.0.0.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []Address{
+		{IP: "203.0.113.5", Scope: AddressScopePublic},
+		{IP: "2001:db8::5", Scope: AddressScopePublic},
+		{IP: "10.0.0.5", Scope: AddressScopePrivate},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, err := ParseAddresses("", ""); err != nil || got != nil {
+		t.Errorf("Expected no addresses, got %v, %v", got, err)
+	}
+	if _, err := ParseAddresses("", "10.0.0"); err == nil {
+		t.Errorf("Expected error parsing invalid address")
+	}
+}
+
+func TestSortAddresses(t *testing.T) {
+	pub4 := Address{IP: "203.0.113.5", Scope: AddressScopePublic}
+	priv4 := Address{IP: "10.0.0.5", Scope: AddressScopePrivate}
+	pub6 := Address{IP: "2001:db8::5", Scope: AddressScopePublic}
+	priv6 := Address{IP: "fd00::5", Scope: AddressScopePrivate}
+
+	addrs := []Address{priv6, priv4, pub6, pub4}
+	SortAddresses(addrs, IPPreferenceIPv4)
+	if want := []Address{pub4, priv4, pub6, priv6}; !reflect.DeepEqual(want, addrs) {
+		t.Errorf("ipv4 preference: got %v, want %v", addrs, want)
+	}
+
+	SortAddresses(addrs, IPPreferenceIPv6)
+	if want := []Address{pub6, priv6, pub4, priv4}; !reflect.DeepEqual(want, addrs) {
+		t.Errorf("ipv6 preference: got %v, want %v", addrs, want)
+	}
+}
+
+func TestAddressScope(t *testing.T) {
+	for ip, want := range map[string]string{
+		"10.1.2.3":    AddressScopePrivate,
+		"172.20.0.1":  AddressScopePrivate,
+		"192.168.1.1": AddressScopePrivate,
+		"fd12::1":     AddressScopePrivate,
+		"8.8.8.8":     AddressScopePublic,
+		"2001:db8::1": AddressScopePublic,
+	} {
+		if got := addressScope(net.ParseIP(ip)); got != want {
+			t.Errorf("%s: got scope %s, want %s", ip, got, want)
+		}
+	}
+}
+
+func TestAllAddresses(t *testing.T) {
+	if got := (MachineState{}).AllAddresses(); got != nil {
+		t.Errorf("Expected no addresses, got %v", got)
+	}
+
+	old := MachineState{PublicIP: "1.2.3.4"}
+	if want := []Address{{IP: "1.2.3.4", Scope: AddressScopePublic}}; !reflect.DeepEqual(want, old.AllAddresses()) {
+		t.Errorf("got %v, want %v", old.AllAddresses(), want)
+	}
+
+	addrs := []Address{{IP: "2001:db8::5", Scope: AddressScopePublic}, {IP: "1.2.3.4", Scope: AddressScopePublic}}
+	ms := MachineState{PublicIP: "2001:db8::5", Addresses: addrs}
+	if !reflect.DeepEqual(addrs, ms.AllAddresses()) {
+		t.Errorf("got %v, want %v", ms.AllAddresses(), addrs)
+	}
+}
+
+func TestStackStateAddresses(t *testing.T) {
+	detected := MachineState{
+		PublicIP:  "5.6.7.8",
+		Addresses: []Address{{IP: "5.6.7.8", Scope: AddressScopePublic}},
+	}
+
+	// configuring a single public IP replaces the detected addresses
+	stacked := stackState(MachineState{PublicIP: "1.2.3.4"}, detected)
+	if stacked.PublicIP != "1.2.3.4" || stacked.Addresses != nil {
+		t.Errorf("Unexpected addresses %s %v", stacked.PublicIP, stacked.Addresses)
+	}
+
+	stacked = stackState(MachineState{}, detected)
+	if !reflect.DeepEqual(detected.Addresses, stacked.Addresses) {
+		t.Errorf("Unexpected addresses %v", stacked.Addresses)
+	}
+}
@@ -0,0 +1,154 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	MetadataSourceEC2       = "ec2"
+	MetadataSourceGCE       = "gce"
+	MetadataSourceOpenStack = "openstack"
+
+	// amount of time to wait for a cloud provider's metadata service
+	// before giving up on it
+	cloudMetadataTimeout = 2 * time.Second
+)
+
+var (
+	ec2MetadataURL       = "http://169.254.169.254/latest/meta-data/"
+	gceMetadataURL       = "http://metadata.google.internal/computeMetadata/v1/instance/"
+	openStackMetadataURL = "http://169.254.169.254/openstack/latest/meta_data.json"
+
+	metadataSources = map[string]func(*http.Client) (map[string]string, error){
+		MetadataSourceEC2:       discoverEC2Metadata,
+		MetadataSourceGCE:       discoverGCEMetadata,
+		MetadataSourceOpenStack: discoverOpenStackMetadata,
+	}
+)
+
+// DiscoverMetadata queries the metadata services of the named cloud
+// providers for the region, availability zone ("az") and instance type
+// ("instance_type") of the local machine. Where several sources provide
+// the same key, the first one listed wins. Sources that cannot be reached
+// are skipped; an error is only returned for unknown source names.
+func DiscoverMetadata(sources []string) (map[string]string, error) {
+	for _, name := range sources {
+		if _, ok := metadataSources[name]; !ok {
+			return nil, fmt.Errorf("unknown metadata source %q", name)
+		}
+	}
+
+	client := &http.Client{Timeout: cloudMetadataTimeout}
+	metadata := make(map[string]string)
+	for _, name := range sources {
+		md, err := metadataSources[name](client)
+		if err != nil {
+			log.Warningf("Unable to discover metadata from %s: %v", name, err)
+			continue
+		}
+
+		log.V(1).Infof("Discovered metadata from %s: %v", name, md)
+		for key, val := range md {
+			if _, ok := metadata[key]; !ok && val != "" {
+				metadata[key] = val
+			}
+		}
+	}
+
+	return metadata, nil
+}
+
+func discoverEC2Metadata(client *http.Client) (map[string]string, error) {
+	az, err := fetchMetadata(client, ec2MetadataURL+"placement/availability-zone", nil)
+	if err != nil {
+		return nil, err
+	}
+	instanceType, err := fetchMetadata(client, ec2MetadataURL+"instance-type", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Availability zones are named after their region with a
+	// single-letter suffix, e.g. us-west-1b
+	return map[string]string{
+		"region":        strings.TrimRight(az, "abcdefghijklmnopqrstuvwxyz"),
+		"az":            az,
+		"instance_type": instanceType,
+	}, nil
+}
+
+func discoverGCEMetadata(client *http.Client) (map[string]string, error) {
+	header := map[string]string{"Metadata-Flavor": "Google"}
+
+	// Values take the form projects/<project>/zones/<zone>
+	zone, err := fetchMetadata(client, gceMetadataURL+"zone", header)
+	if err != nil {
+		return nil, err
+	}
+	machineType, err := fetchMetadata(client, gceMetadataURL+"machine-type", header)
+	if err != nil {
+		return nil, err
+	}
+
+	zone = path.Base(zone)
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+
+	return map[string]string{
+		"region":        region,
+		"az":            zone,
+		"instance_type": path.Base(machineType),
+	}, nil
+}
+
+func discoverOpenStackMetadata(client *http.Client) (map[string]string, error) {
+	body, err := fetchMetadata(client, openStackMetadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var md struct {
+		AvailabilityZone string `json:"availability_zone"`
+	}
+	if err := json.Unmarshal([]byte(body), &md); err != nil {
+		return nil, fmt.Errorf("unable to decode metadata: %v", err)
+	}
+
+	return map[string]string{"az": md.AvailabilityZone}, nil
+}
+
+func fetchMetadata(client *http.Client, url string, header map[string]string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, val := range header {
+		req.Header.Set(key, val)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response fetching %s: %s", url, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,74 @@
+package machine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newMetadataServer(t *testing.T, responses map[string]string, header string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header != "" && r.Header.Get(header) == "" {
+			t.Errorf("Request for %s missing header %s", r.URL.Path, header)
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestDiscoverMetadata(t *testing.T) {
+	ec2 := newMetadataServer(t, map[string]string{
+		"/placement/availability-zone": "us-west-1b",
+		"/instance-type":               "m3.medium",
+	}, "")
+	defer ec2.Close()
+	gce := newMetadataServer(t, map[string]string{
+		"/zone":         "projects/1234/zones/europe-west1-c",
+		"/machine-type": "projects/1234/machineTypes/n1-standard-1",
+	}, "Metadata-Flavor")
+	defer gce.Close()
+	openstack := newMetadataServer(t, map[string]string{
+		"/meta_data.json": `{"availability_zone": "nova", "name": "test"}`,
+	}, "")
+	defer openstack.Close()
+
+	ec2MetadataURL = ec2.URL + "/"
+	gceMetadataURL = gce.URL + "/"
+	openStackMetadataURL = openstack.URL + "/meta_data.json"
+
+	for i, tt := range []struct {
+		sources []string
+		want    map[string]string
+	}{
+		{nil, map[string]string{}},
+		{[]string{"ec2"}, map[string]string{"region": "us-west-1", "az": "us-west-1b", "instance_type": "m3.medium"}},
+		{[]string{"gce"}, map[string]string{"region": "europe-west1", "az": "europe-west1-c", "instance_type": "n1-standard-1"}},
+		// earlier sources take precedence
+		{[]string{"openstack", "ec2"}, map[string]string{"region": "us-west-1", "az": "nova", "instance_type": "m3.medium"}},
+	} {
+		got, err := DiscoverMetadata(tt.sources)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: unexpected metadata: got %v, want %v", i, got, tt.want)
+		}
+	}
+
+	// unreachable sources are skipped
+	ec2.Close()
+	got, err := DiscoverMetadata([]string{"ec2", "openstack"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	} else if want := map[string]string{"az": "nova"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected metadata: got %v, want %v", got, want)
+	}
+
+	if _, err := DiscoverMetadata([]string{"azure"}); err == nil {
+		t.Errorf("Expected error for unknown metadata source")
+	}
+}
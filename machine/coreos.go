@@ -2,28 +2,49 @@ package machine
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/coreos/fleet/Godeps/_workspace/src/github.com/docker/libcontainer/netlink"
 
 	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/unit"
 )
 
 const (
 	machineIDPath = "/etc/machine-id"
+	meminfoPath   = "/proc/meminfo"
 )
 
-func NewCoreOSMachine(static MachineState, um unit.UnitManager) *CoreOSMachine {
-	log.V(1).Infof("Created CoreOSMachine with static state %s", static)
+// A MemorySource reports the memory (in MB) available to new processes on
+// the local machine, and whether it is known
+type MemorySource interface {
+	MemoryAvailable() (int, bool)
+}
+
+// NewCoreOSMachine creates a CoreOSMachine. The capacity of the filesystem
+// containing diskPath is published as the machine's disk resources, and
+// that determined by cpu as its CPU resources. If cpu is nil, the CPU
+// capacity available to fleet on the local machine is published.
+func NewCoreOSMachine(static MachineState, um unit.UnitManager, diskPath string, cpu CPUCapacity) *CoreOSMachine {
+	log.V(1).Infof("Created CoreOSMachine with static state %v", static)
+	if cpu == nil {
+		cpu = &LocalCPUCapacity{Root: "/"}
+	}
 	m := &CoreOSMachine{
 		staticState: static,
 		um:          um,
+		diskPath:    diskPath,
+		cpu:         cpu,
 	}
 	return m
 }
@@ -32,8 +53,22 @@ type CoreOSMachine struct {
 	sync.RWMutex
 
 	um           unit.UnitManager
+	diskPath     string
+	cpu          CPUCapacity
 	staticState  MachineState
 	dynamicState *MachineState
+
+	// metadata set at runtime, which takes precedence over the static
+	// metadata the machine was configured with
+	metadataOverrides map[string]string
+
+	// mem, if set, provides the AvailableMemory of machines whose
+	// MemoryPolicy uses it
+	mem MemorySource
+
+	// ipPreference is the family of the addresses the machine prefers
+	// to be reached at, see SortAddresses
+	ipPreference string
 }
 
 func (m *CoreOSMachine) String() string {
@@ -52,9 +87,64 @@ func (m *CoreOSMachine) State() (state MachineState) {
 		state = stackState(m.staticState, *m.dynamicState)
 	}
 
+	if len(m.metadataOverrides) > 0 {
+		state.Metadata = overlayMetadata(state.Metadata, m.metadataOverrides)
+	}
+
+	if len(state.Addresses) > 0 {
+		state.Addresses = append([]Address(nil), state.Addresses...)
+		SortAddresses(state.Addresses, m.ipPreference)
+		state.PublicIP = state.Addresses[0].IP
+	}
+
+	if m.mem != nil && state.UsesAvailableMemory() {
+		if avail, ok := m.mem.MemoryAvailable(); ok {
+			state.AvailableMemory = avail
+		}
+	}
+
 	return
 }
 
+// SetMemorySource sets where the CoreOSMachine learns the memory available
+// on the local machine from, which it reports if its MemoryPolicy uses it
+func (m *CoreOSMachine) SetMemorySource(mem MemorySource) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.mem = mem
+}
+
+// SetIPPreference sets the family of the addresses, ipv4 or ipv6, the
+// CoreOSMachine prefers to be reached at, which decides its PublicIP
+func (m *CoreOSMachine) SetIPPreference(pref string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.ipPreference = pref
+}
+
+// SetMetadataOverrides replaces the metadata set for the CoreOSMachine at
+// runtime. These values are overlaid on the metadata the machine was
+// configured with.
+func (m *CoreOSMachine) SetMetadataOverrides(metadata map[string]string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.metadataOverrides = metadata
+}
+
+// SetOvercommit replaces the factors by which the CoreOSMachine was
+// configured to overcommit its CPU and memory. The cpu-overcommit and
+// memory-overcommit metadata of the machine still take precedence.
+func (m *CoreOSMachine) SetOvercommit(cpu, memory float64) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.staticState.CPUOvercommit = cpu
+	m.staticState.MemoryOvercommit = memory
+}
+
 // Refresh updates the current state of the CoreOSMachine.
 func (m *CoreOSMachine) Refresh() {
 	m.RLock()
@@ -93,10 +183,19 @@ func (m *CoreOSMachine) currentState() *MachineState {
 		return nil
 	}
 	publicIP := getLocalIP()
+	addrs := getLocalAddresses()
+	// Machines that cannot determine their capacity publish none, which
+	// disables resource checks against them
+	totalResources, err := readLocalResources("/", m.diskPath, m.cpu)
+	if err != nil {
+		log.Warningf("Unable to determine local resources: %v", err)
+	}
 	return &MachineState{
-		ID:       id,
-		PublicIP: publicIP,
-		Metadata: make(map[string]string, 0),
+		ID:             id,
+		PublicIP:       publicIP,
+		Addresses:      addrs,
+		Metadata:       make(map[string]string, 0),
+		TotalResources: totalResources,
 	}
 }
 
@@ -119,6 +218,107 @@ func readLocalMachineID(root string) (string, error) {
 	return mID, nil
 }
 
+// readLocalResources determines the total memory of the local machine, its
+// CPU capacity as determined by cpu, and the size of the filesystem
+// containing diskPath. If the CPU capacity cannot be determined, every
+// processor is counted. If the disk cannot be inspected, the CPU and memory
+// are still returned along with the error.
+func readLocalResources(root, diskPath string, cpu CPUCapacity) (resource.ResourceTuple, error) {
+	var res resource.ResourceTuple
+
+	mem, err := readMemTotal(filepath.Join(root, meminfoPath))
+	if err != nil {
+		return res, err
+	}
+
+	res.Cores, err = cpu.CPUUnits()
+	if err != nil {
+		log.Warningf("Unable to determine CPU capacity, counting all processors: %v", err)
+		res.Cores = runtime.NumCPU() * 100
+	}
+	res.Memory = mem
+
+	disk, err := readDiskTotal(diskPath)
+	if err != nil {
+		return res, err
+	}
+
+	res.Disk = disk
+	return res, nil
+}
+
+// readDiskTotal returns the size (in MB) of the filesystem containing the
+// given path. The size rather than the free space is used so that disk, like
+// memory, is accounted for by reservation rather than by live usage.
+func readDiskTotal(path string) (int, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("unable to stat filesystem at %s: %v", path, err)
+	}
+	return int(st.Blocks * uint64(st.Bsize) / 1024 / 1024), nil
+}
+
+// Meminfo describes the memory of a machine, in MB
+type Meminfo struct {
+	// Total is the memory reported as MemTotal
+	Total int
+	// Available is the memory reported as MemAvailable, the kernel's
+	// estimate of the memory available to new processes without swapping
+	Available int
+}
+
+// ReadLocalMeminfo reads the memory of the local machine from the meminfo
+// file below root
+func ReadLocalMeminfo(root string) (Meminfo, error) {
+	vals, err := readMeminfo(filepath.Join(root, meminfoPath), "MemTotal", "MemAvailable")
+	if err != nil {
+		return Meminfo{}, err
+	}
+	return Meminfo{Total: vals[0], Available: vals[1]}, nil
+}
+
+// readMemTotal returns the amount of memory (in MB) reported as MemTotal
+// in the given meminfo file
+func readMemTotal(path string) (int, error) {
+	vals, err := readMeminfo(path, "MemTotal")
+	if err != nil {
+		return 0, err
+	}
+	return vals[0], nil
+}
+
+// readMeminfo returns the amounts of memory (in MB) reported as each of the
+// named values in the given meminfo file
+func readMeminfo(path string, names ...string) ([]int, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	kbs := make(map[string]string)
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kbs[strings.TrimSuffix(fields[0], ":")] = fields[1]
+	}
+
+	vals := make([]int, len(names))
+	for i, name := range names {
+		field, ok := kbs[name]
+		if !ok {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		kb, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", name, field)
+		}
+		vals[i] = kb / 1024
+	}
+	return vals, nil
+}
+
 func getLocalIP() (got string) {
 	iface := getDefaultGatewayIface()
 	if iface == nil {
@@ -149,6 +349,30 @@ func getLocalIP() (got string) {
 	return
 }
 
+// getLocalAddresses returns the global unicast addresses, IPv4 and IPv6, of
+// the interface of the default route
+func getLocalAddresses() []Address {
+	iface := getDefaultGatewayIface()
+	if iface == nil {
+		return nil
+	}
+
+	ifaddrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+
+	var addrs []Address
+	for _, addr := range ifaddrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err != nil || !ip.IsGlobalUnicast() {
+			continue
+		}
+		addrs = append(addrs, Address{IP: ip.String(), Scope: addressScope(ip)})
+	}
+	return addrs
+}
+
 func usableAddress(ip net.IP) bool {
 	return ip.To4() != nil && ip.IsGlobalUnicast()
 }
@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -51,6 +52,106 @@ func TestReadLocalMachineIDFound(t *testing.T) {
 	}
 }
 
+func TestReadMemTotal(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "fleet-")
+	if err != nil {
+		t.Fatalf("Failed creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, tt := range []struct {
+		contents string
+		want     int
+		err      bool
+	}{
+		{"MemTotal:        2048000 kB\nMemFree:          102400 kB\n", 2000, false},
+		{"MemFree:          102400 kB\nMemTotal:        1048576 kB\n", 1024, false},
+		{"MemFree:          102400 kB\n", 0, true},
+		{"MemTotal:        lots kB\n", 0, true},
+		{"", 0, true},
+	} {
+		path := filepath.Join(dir, "meminfo")
+		if err := ioutil.WriteFile(path, []byte(tt.contents), os.FileMode(0644)); err != nil {
+			t.Fatalf("Failed writing fake meminfo file: %v", err)
+		}
+
+		got, err := readMemTotal(path)
+		if tt.err != (err != nil) {
+			t.Errorf("case %d: unexpected error value: %v", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("case %d: got %d, want %d", i, got, tt.want)
+		}
+	}
+
+	if _, err := readMemTotal(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected error for missing meminfo file")
+	}
+}
+
+func TestReadDiskTotal(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "fleet-")
+	if err != nil {
+		t.Fatalf("Failed creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := readDiskTotal(dir); err != nil {
+		t.Errorf("Unexpected error reading disk size of %s: %v", dir, err)
+	}
+
+	if _, err := readDiskTotal(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected error reading disk size of missing path")
+	}
+}
+
+func TestMetadataOverrides(t *testing.T) {
+	static := MachineState{ID: "XXX", Metadata: map[string]string{"region": "us-east", "rack": "1"}}
+	m := NewCoreOSMachine(static, nil, "/", nil)
+
+	m.SetMetadataOverrides(map[string]string{"region": "us-west", "role": "db"})
+	want := map[string]string{"region": "us-west", "rack": "1", "role": "db"}
+	if got := m.State().Metadata; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected metadata: got %v, want %v", got, want)
+	}
+
+	// the configured metadata must be left untouched
+	if static.Metadata["region"] != "us-east" {
+		t.Errorf("Static metadata was modified: %v", static.Metadata)
+	}
+
+	m.SetMetadataOverrides(nil)
+	if got := m.State().Metadata; !reflect.DeepEqual(got, static.Metadata) {
+		t.Errorf("Unexpected metadata after clearing overrides: got %v, want %v", got, static.Metadata)
+	}
+}
+
+type fakeMemorySource int
+
+func (f fakeMemorySource) MemoryAvailable() (int, bool) {
+	return int(f), f > 0
+}
+
+func TestMemorySource(t *testing.T) {
+	for i, tt := range []struct {
+		policy string
+		mem    fakeMemorySource
+		want   int
+	}{
+		{MemoryPolicyReserved, 512, 0},
+		{MemoryPolicyAvailable, 512, 512},
+		{MemoryPolicyHybrid, 512, 512},
+		// unknown available memory is not published
+		{MemoryPolicyAvailable, 0, 0},
+	} {
+		m := NewCoreOSMachine(MachineState{ID: "XXX", MemoryPolicy: tt.policy}, nil, "/", nil)
+		m.SetMemorySource(tt.mem)
+		if got := m.State().AvailableMemory; got != tt.want {
+			t.Errorf("case %d: got AvailableMemory %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
 func TestUsableAddress(t *testing.T) {
 	tests := []struct {
 		ip net.IP
@@ -0,0 +1,229 @@
+package machine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cpuOnlinePath  = "/sys/devices/system/cpu/online"
+	procCgroupPath = "/proc/self/cgroup"
+	cgroupRoot     = "/sys/fs/cgroup"
+)
+
+// CPUCapacity determines the CPU capacity of a machine in CPU units, i.e.
+// hundredths of a core
+type CPUCapacity interface {
+	CPUUnits() (int, error)
+}
+
+// StaticCPUCapacity is a CPU capacity configured by the operator
+type StaticCPUCapacity int
+
+func (c StaticCPUCapacity) CPUUnits() (int, error) {
+	return int(c), nil
+}
+
+// LocalCPUCapacity determines the CPU capacity available to fleet on the
+// local machine: the online CPUs, limited by the cpuset and CFS quota of
+// fleet's own cgroup, if any. Paths are resolved relative to Root.
+type LocalCPUCapacity struct {
+	Root string
+}
+
+func (c *LocalCPUCapacity) CPUUnits() (int, error) {
+	online, err := readCPUList(filepath.Join(c.Root, cpuOnlinePath))
+	if err != nil {
+		return 0, err
+	}
+	units := online * 100
+
+	cgroups, err := readProcCgroups(filepath.Join(c.Root, procCgroupPath))
+	if err != nil {
+		// outside of cgroups, all online CPUs are available
+		return units, nil
+	}
+
+	if cpus, ok := c.cgroupCPUs(cgroups); ok && cpus*100 < units {
+		units = cpus * 100
+	}
+	if quota, ok := c.cgroupQuota(cgroups); ok && quota < units {
+		units = quota
+	}
+
+	return units, nil
+}
+
+// cgroupCPUs returns the number of CPUs in the cpuset of fleet's cgroup
+func (c *LocalCPUCapacity) cgroupCPUs(cgroups map[string]procCgroup) (int, bool) {
+	if f, ok := c.cgroupFile(cgroups, "cpuset", "cpuset.cpus"); ok {
+		if cpus, err := readCPUList(f); err == nil && cpus > 0 {
+			return cpus, true
+		}
+	}
+	if f, ok := c.cgroupFile(cgroups, "", "cpuset.cpus.effective"); ok {
+		if cpus, err := readCPUList(f); err == nil && cpus > 0 {
+			return cpus, true
+		}
+	}
+	return 0, false
+}
+
+// cgroupQuota returns the CFS quota of fleet's cgroup in CPU units
+func (c *LocalCPUCapacity) cgroupQuota(cgroups map[string]procCgroup) (int, bool) {
+	var quota, period string
+	if f, ok := c.cgroupFile(cgroups, "cpu", "cpu.cfs_quota_us"); ok {
+		quota = readTrimmed(f)
+		period = readTrimmed(filepath.Join(filepath.Dir(f), "cpu.cfs_period_us"))
+	} else if f, ok := c.cgroupFile(cgroups, "", "cpu.max"); ok {
+		// cgroup v2 holds both as "<quota> <period>"
+		fields := strings.Fields(readTrimmed(f))
+		if len(fields) == 2 {
+			quota, period = fields[0], fields[1]
+		}
+	}
+
+	q, err := strconv.Atoi(quota)
+	if err != nil || q <= 0 {
+		// a quota of -1 or "max" means no limit
+		return 0, false
+	}
+	p, err := strconv.Atoi(period)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q * 100 / p, true
+}
+
+// procCgroup is the cgroup a process belongs to in one hierarchy
+type procCgroup struct {
+	// hierarchy names the controllers of the hierarchy, e.g.
+	// "cpu,cpuacct", and is empty for the unified hierarchy
+	hierarchy string
+	path      string
+}
+
+// cgroupFile finds the given file of the cgroup fleet belongs to in the
+// hierarchy of the given controller, or in the unified hierarchy if the
+// controller is empty. Within containers, the cgroup of fleet is commonly
+// mounted at the root of the hierarchy, which is tried as well.
+func (c *LocalCPUCapacity) cgroupFile(cgroups map[string]procCgroup, controller, file string) (string, bool) {
+	cg, ok := cgroups[controller]
+	if !ok {
+		return "", false
+	}
+
+	base := filepath.Join(c.Root, cgroupRoot)
+	var mounts []string
+	if controller == "" {
+		mounts = []string{base, filepath.Join(base, "unified")}
+	} else {
+		mounts = []string{filepath.Join(base, cg.hierarchy), filepath.Join(base, controller)}
+	}
+
+	for _, mount := range mounts {
+		for _, dir := range []string{filepath.Join(mount, cg.path), mount} {
+			f := filepath.Join(dir, file)
+			if _, err := os.Stat(f); err == nil {
+				return f, true
+			}
+		}
+	}
+	return "", false
+}
+
+// readProcCgroups parses the given /proc/<pid>/cgroup file, returning the
+// cgroups of the process by controller. The cgroup in the unified hierarchy
+// is indexed by the empty string.
+func readProcCgroups(path string) (map[string]procCgroup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cgroups := make(map[string]procCgroup)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		cg := procCgroup{hierarchy: parts[1], path: parts[2]}
+		if cg.hierarchy == "" {
+			cgroups[""] = cg
+			continue
+		}
+		for _, controller := range strings.Split(cg.hierarchy, ",") {
+			cgroups[controller] = cg
+		}
+	}
+	return cgroups, scanner.Err()
+}
+
+// readCPUList returns the number of CPUs in the given file, holding a list
+// of CPUs like "0-3,6"
+func readCPUList(path string) (int, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return parseCPUList(strings.TrimSpace(string(contents)))
+}
+
+func parseCPUList(list string) (int, error) {
+	if list == "" {
+		return 0, errors.New("empty CPU list")
+	}
+
+	var count int
+	for _, r := range strings.Split(list, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU list %q", list)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return 0, fmt.Errorf("invalid CPU list %q", list)
+			}
+		}
+		count += last - first + 1
+	}
+	return count, nil
+}
+
+func readTrimmed(path string) string {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+// NewReservableCPUCapacity returns a CPUCapacity offering only the given
+// fraction of the given capacity to units, keeping the rest as headroom for
+// the system daemons of the machine
+func NewReservableCPUCapacity(c CPUCapacity, fraction float64) CPUCapacity {
+	return &reservableCPUCapacity{c, fraction}
+}
+
+type reservableCPUCapacity struct {
+	capacity CPUCapacity
+	fraction float64
+}
+
+func (c *reservableCPUCapacity) CPUUnits() (int, error) {
+	units, err := c.capacity.CPUUnits()
+	if err != nil {
+		return 0, err
+	}
+	return int(float64(units) * c.fraction), nil
+}
@@ -0,0 +1,141 @@
+package machine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for i, tt := range []struct {
+		list string
+		want int
+		err  bool
+	}{
+		{"0", 1, false},
+		{"0-3", 4, false},
+		{"0-3,6,8-9", 7, false},
+		{"", 0, true},
+		{"0-", 0, true},
+		{"3-1", 0, true},
+		{"a-b", 0, true},
+	} {
+		got, err := parseCPUList(tt.list)
+		if tt.err != (err != nil) {
+			t.Errorf("case %d: unexpected error value: %v", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("case %d: got %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestLocalCPUCapacity(t *testing.T) {
+	for i, tt := range []struct {
+		files map[string]string
+		want  int
+		err   bool
+	}{
+		// outside of cgroups, all online CPUs are counted
+		{
+			map[string]string{cpuOnlinePath: "0-3\n"},
+			400,
+			false,
+		},
+		{
+			map[string]string{},
+			0,
+			true,
+		},
+		// cgroup v1 with a quota of one and a half cores
+		{
+			map[string]string{
+				cpuOnlinePath:  "0-3\n",
+				procCgroupPath: "4:cpu,cpuacct:/system.slice/fleet.service\n3:cpuset:/\n",
+				"/sys/fs/cgroup/cpu,cpuacct/system.slice/fleet.service/cpu.cfs_quota_us":  "150000\n",
+				"/sys/fs/cgroup/cpu,cpuacct/system.slice/fleet.service/cpu.cfs_period_us": "100000\n",
+				"/sys/fs/cgroup/cpuset/cpuset.cpus":                                       "0-3\n",
+			},
+			150,
+			false,
+		},
+		// no quota, but a container restricted to two CPUs, mounted
+		// at the root of the hierarchy
+		{
+			map[string]string{
+				cpuOnlinePath:  "0-7\n",
+				procCgroupPath: "4:cpu,cpuacct:/docker/abc\n3:cpuset:/docker/abc\n",
+				"/sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "-1\n",
+				"/sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+				"/sys/fs/cgroup/cpuset/cpuset.cpus":            "2,5\n",
+			},
+			200,
+			false,
+		},
+		// cgroup v2
+		{
+			map[string]string{
+				cpuOnlinePath:  "0-3\n",
+				procCgroupPath: "0::/system.slice/fleet.service\n",
+				"/sys/fs/cgroup/system.slice/fleet.service/cpu.max":               "250000 100000\n",
+				"/sys/fs/cgroup/system.slice/fleet.service/cpuset.cpus.effective": "0-3\n",
+			},
+			250,
+			false,
+		},
+		{
+			map[string]string{
+				cpuOnlinePath:  "0-3\n",
+				procCgroupPath: "0::/system.slice/fleet.service\n",
+				"/sys/fs/cgroup/system.slice/fleet.service/cpu.max": "max 100000\n",
+			},
+			400,
+			false,
+		},
+	} {
+		dir, err := ioutil.TempDir(os.TempDir(), "fleet-")
+		if err != nil {
+			t.Fatalf("Failed creating tempdir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		for name, contents := range tt.files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+				t.Fatalf("Failed creating directory: %v", err)
+			}
+			if err := ioutil.WriteFile(path, []byte(contents), os.FileMode(0644)); err != nil {
+				t.Fatalf("Failed writing %s: %v", name, err)
+			}
+		}
+
+		c := &LocalCPUCapacity{Root: dir}
+		got, err := c.CPUUnits()
+		if tt.err != (err != nil) {
+			t.Errorf("case %d: unexpected error value: %v", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("case %d: got %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+type failingCPUCapacity struct{}
+
+func (failingCPUCapacity) CPUUnits() (int, error) {
+	return 0, errors.New("no CPUs")
+}
+
+func TestReservableCPUCapacity(t *testing.T) {
+	c := NewReservableCPUCapacity(StaticCPUCapacity(400), 0.9)
+	if got, err := c.CPUUnits(); err != nil || got != 360 {
+		t.Errorf("Expected 360 CPU units, got %d, err %v", got, err)
+	}
+
+	c = NewReservableCPUCapacity(failingCPUCapacity{}, 0.9)
+	if _, err := c.CPUUnits(); err == nil {
+		t.Errorf("Expected error to be passed through")
+	}
+}
@@ -31,3 +31,15 @@ func HasMetadata(state *MachineState, metadata map[string]pkg.Set) bool {
 
 	return true
 }
+
+// MetadataScore counts the keys of the indicated metadata for which the
+// given MachineState has a matching value.
+func MetadataScore(state *MachineState, metadata map[string]pkg.Set) int {
+	score := 0
+	for key, values := range metadata {
+		if local, ok := state.Metadata[key]; ok && values.Contains(local) {
+			score++
+		}
+	}
+	return score
+}
@@ -60,3 +60,25 @@ func TestHasMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestMetadataScore(t *testing.T) {
+	ms := &MachineState{Metadata: map[string]string{
+		"region": "us-east-1",
+		"disk":   "ssd",
+	}}
+
+	for i, tt := range []struct {
+		match map[string]pkg.Set
+		want  int
+	}{
+		{map[string]pkg.Set{}, 0},
+		{map[string]pkg.Set{"region": pkg.NewUnsafeSet("us-west-1")}, 0},
+		{map[string]pkg.Set{"zone": pkg.NewUnsafeSet("a")}, 0},
+		{map[string]pkg.Set{"region": pkg.NewUnsafeSet("us-east-1", "us-west-1")}, 1},
+		{map[string]pkg.Set{"region": pkg.NewUnsafeSet("us-east-1"), "disk": pkg.NewUnsafeSet("ssd"), "zone": pkg.NewUnsafeSet("a")}, 2},
+	} {
+		if got := MetadataScore(ms, tt.match); got != tt.want {
+			t.Errorf("case %d: MetadataScore returned %d, expected %d", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Metadata of a machine declaring a maintenance window, as START/END
+	// in RFC 3339, and how long before its start units are moved off the
+	// machine, as a duration
+	MetadataMaintenanceWindow = "maintenance-window"
+	MetadataMaintenanceLead   = "maintenance-lead"
+
+	// DefaultMaintenanceLead is how long before the start of a
+	// maintenance window units are moved off the machine unless the
+	// maintenance-lead metadata says otherwise
+	DefaultMaintenanceLead = 15 * time.Minute
+)
+
+// A MaintenanceWindow is a period during which a machine is expected to be
+// unavailable. From Lead before Start until End, the machine is drained:
+// the engine schedules no new units to it and moves its units elsewhere.
+// Once the window ends, the machine accepts units again.
+type MaintenanceWindow struct {
+	Start time.Time
+	End   time.Time
+	Lead  time.Duration
+}
+
+// ParseMaintenanceWindow parses a MaintenanceWindow of the form START/END,
+// with both times in RFC 3339. The Lead of the window is left empty.
+func ParseMaintenanceWindow(s string) (MaintenanceWindow, error) {
+	var mw MaintenanceWindow
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return mw, fmt.Errorf("maintenance window %q must be of the form START/END", s)
+	}
+
+	var err error
+	if mw.Start, err = time.Parse(time.RFC3339, parts[0]); err != nil {
+		return mw, fmt.Errorf("invalid start of maintenance window: %v", err)
+	}
+	if mw.End, err = time.Parse(time.RFC3339, parts[1]); err != nil {
+		return mw, fmt.Errorf("invalid end of maintenance window: %v", err)
+	}
+	if !mw.End.After(mw.Start) {
+		return mw, fmt.Errorf("maintenance window %q must end after it starts", s)
+	}
+	return mw, nil
+}
+
+// String formats the MaintenanceWindow as parsed by ParseMaintenanceWindow
+func (mw MaintenanceWindow) String() string {
+	return mw.Start.Format(time.RFC3339) + "/" + mw.End.Format(time.RFC3339)
+}
+
+// Drains determines whether the machine is drained for the window at the
+// given time
+func (mw MaintenanceWindow) Drains(now time.Time) bool {
+	return !now.Before(mw.Start.Add(-mw.Lead)) && now.Before(mw.End)
+}
+
+// Active determines whether the window has started but not yet ended at the
+// given time
+func (mw MaintenanceWindow) Active(now time.Time) bool {
+	return !now.Before(mw.Start) && now.Before(mw.End)
+}
+
+// MaintenanceWindow returns the maintenance window declared by the metadata
+// of the machine, or nil if it declares none or an invalid one.
+func (ms MachineState) MaintenanceWindow() *MaintenanceWindow {
+	val, ok := ms.Metadata[MetadataMaintenanceWindow]
+	if !ok {
+		return nil
+	}
+	mw, err := ParseMaintenanceWindow(val)
+	if err != nil {
+		return nil
+	}
+
+	mw.Lead = DefaultMaintenanceLead
+	if val, ok := ms.Metadata[MetadataMaintenanceLead]; ok {
+		if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+			mw.Lead = d
+		}
+	}
+	return &mw
+}
+
+// ApplyMaintenance drains the machine if its maintenance window drains it
+// at the given time. Machines cordoned or drained by an operator stay so
+// regardless of their maintenance window.
+func (ms *MachineState) ApplyMaintenance(now time.Time) {
+	if mw := ms.MaintenanceWindow(); mw != nil && mw.Drains(now) {
+		ms.Cordoned = true
+		ms.Draining = true
+	}
+}
@@ -0,0 +1,79 @@
+package machine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	start := time.Date(2015, time.March, 1, 2, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	tests := []struct {
+		in  string
+		ok  bool
+		out MaintenanceWindow
+	}{
+		{"2015-03-01T02:00:00Z/2015-03-01T04:00:00Z", true, MaintenanceWindow{Start: start, End: end}},
+		{"2015-03-01T02:00:00Z", false, MaintenanceWindow{}},
+		{"2015-03-01 02:00/2015-03-01T04:00:00Z", false, MaintenanceWindow{}},
+		{"2015-03-01T02:00:00Z/tomorrow", false, MaintenanceWindow{}},
+		// windows must not end before they start
+		{"2015-03-01T04:00:00Z/2015-03-01T02:00:00Z", false, MaintenanceWindow{}},
+		{"2015-03-01T02:00:00Z/2015-03-01T02:00:00Z", false, MaintenanceWindow{}},
+	}
+
+	for i, tt := range tests {
+		mw, err := ParseMaintenanceWindow(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if tt.ok && (!mw.Start.Equal(tt.out.Start) || !mw.End.Equal(tt.out.End)) {
+			t.Errorf("case %d: got %v, want %v", i, mw, tt.out)
+		}
+		if tt.ok && mw.String() != tt.in {
+			t.Errorf("case %d: formatted as %q", i, mw.String())
+		}
+	}
+}
+
+func TestApplyMaintenance(t *testing.T) {
+	start := time.Date(2015, time.March, 1, 2, 0, 0, 0, time.UTC)
+	window := "2015-03-01T02:00:00Z/2015-03-01T04:00:00Z"
+
+	tests := []struct {
+		metadata map[string]string
+		cordoned bool
+		now      time.Time
+		draining bool
+	}{
+		// no window
+		{nil, false, start, false},
+		// invalid windows are disregarded
+		{map[string]string{MetadataMaintenanceWindow: "soon"}, false, start, false},
+		// units are moved off the machine ahead of the window
+		{map[string]string{MetadataMaintenanceWindow: window}, false, start.Add(-DefaultMaintenanceLead - time.Second), false},
+		{map[string]string{MetadataMaintenanceWindow: window}, false, start.Add(-DefaultMaintenanceLead), true},
+		{map[string]string{MetadataMaintenanceWindow: window}, false, start.Add(time.Hour), true},
+		// and the machine is schedulable again once it ends
+		{map[string]string{MetadataMaintenanceWindow: window}, false, start.Add(2 * time.Hour), false},
+		// the lead may be changed
+		{map[string]string{MetadataMaintenanceWindow: window, MetadataMaintenanceLead: "1h"}, false, start.Add(-30 * time.Minute), true},
+		{map[string]string{MetadataMaintenanceWindow: window, MetadataMaintenanceLead: "0"}, false, start.Add(-time.Second), false},
+		{map[string]string{MetadataMaintenanceWindow: window, MetadataMaintenanceLead: "-1h"}, false, start.Add(-30 * time.Minute), false},
+		// cordons set by operators remain after the window
+		{map[string]string{MetadataMaintenanceWindow: window}, true, start.Add(2 * time.Hour), false},
+	}
+
+	for i, tt := range tests {
+		ms := MachineState{Metadata: tt.metadata, Cordoned: tt.cordoned}
+		ms.ApplyMaintenance(tt.now)
+		if ms.Draining != tt.draining {
+			t.Errorf("case %d: draining %t, want %t", i, ms.Draining, tt.draining)
+		}
+		if want := tt.cordoned || tt.draining; ms.Cordoned != want {
+			t.Errorf("case %d: cordoned %t, want %t", i, ms.Cordoned, want)
+		}
+	}
+}
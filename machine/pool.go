@@ -0,0 +1,47 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MetadataPool is the metadata key naming the pool a machine belongs
+	// to, e.g. pool=batch
+	MetadataPool = "pool"
+
+	// PoolAny lets a unit run on machines of any pool, and on machines
+	// outside of pools
+	PoolAny = "*"
+)
+
+// Pool returns the pool the machine belongs to, as given by its pool
+// metadata, or an empty string if it belongs to none
+func (ms MachineState) Pool() string {
+	return ms.Metadata[MetadataPool]
+}
+
+// AcceptsPools determines whether a unit targeting the given pools may run
+// on the machine. Machines in a pool only run units targeting that pool,
+// and units targeting no pool only run on machines outside of pools, unless
+// the unit targets PoolAny.
+func (ms MachineState) AcceptsPools(pools []string) bool {
+	pool := ms.Pool()
+	if len(pools) == 0 {
+		return pool == ""
+	}
+	for _, p := range pools {
+		if p == PoolAny || p == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePool returns an error if the given name cannot name a pool
+func ValidatePool(name string) error {
+	if name == "" || strings.ContainsAny(name, ",=* \t") {
+		return fmt.Errorf("invalid pool name %q", name)
+	}
+	return nil
+}
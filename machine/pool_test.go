@@ -0,0 +1,40 @@
+package machine
+
+import (
+	"testing"
+)
+
+func TestAcceptsPools(t *testing.T) {
+	for i, tt := range []struct {
+		metadata map[string]string
+		pools    []string
+		want     bool
+	}{
+		{nil, nil, true},
+		{nil, []string{"web"}, false},
+		{nil, []string{PoolAny}, true},
+		{map[string]string{"pool": "web"}, nil, false},
+		{map[string]string{"pool": "web"}, []string{"web"}, true},
+		{map[string]string{"pool": "web"}, []string{"batch"}, false},
+		{map[string]string{"pool": "web"}, []string{"batch", "web"}, true},
+		{map[string]string{"pool": "web"}, []string{PoolAny}, true},
+	} {
+		ms := MachineState{ID: "XXX", Metadata: tt.metadata}
+		if got := ms.AcceptsPools(tt.pools); got != tt.want {
+			t.Errorf("case %d: AcceptsPools(%v) returned %t, want %t", i, tt.pools, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePool(t *testing.T) {
+	for _, name := range []string{"web", "batch-1", "gpu.large"} {
+		if err := ValidatePool(name); err != nil {
+			t.Errorf("Unexpected error for %q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "*", "web,batch", "pool=web", "web pool"} {
+		if err := ValidatePool(name); err == nil {
+			t.Errorf("Expected error for %q", name)
+		}
+	}
+}
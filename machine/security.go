@@ -0,0 +1,103 @@
+package machine
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Metadata keys describing the security features of a machine, as
+	// published by DiscoverSecurityMetadata
+	MetadataSELinux       = "selinux"
+	MetadataAppArmor      = "apparmor"
+	MetadataSeccomp       = "seccomp"
+	MetadataKernelVersion = "kernel_version"
+	MetadataKernelRelease = "kernel_release"
+
+	selinuxEnforcePath  = "/sys/fs/selinux/enforce"
+	apparmorEnabledPath = "/sys/module/apparmor/parameters/enabled"
+	procStatusPath      = "/proc/self/status"
+	osReleasePath       = "/proc/sys/kernel/osrelease"
+)
+
+// DiscoverSecurityMetadata determines the security features of the local
+// machine, so units may require them through MachineMetadata:
+//   - selinux: enforcing, permissive or disabled
+//   - apparmor: enabled or disabled
+//   - seccomp: enabled or disabled, depending on kernel support
+//   - kernel_version: the major and minor version of the kernel, e.g. 4.19
+//   - kernel_release: the full kernel release, e.g. 4.19.0-coreos
+//
+// Paths are resolved relative to root. Keys whose value cannot be
+// determined are left out.
+func DiscoverSecurityMetadata(root string) map[string]string {
+	metadata := map[string]string{
+		MetadataSELinux:  selinuxMode(root),
+		MetadataAppArmor: "disabled",
+		MetadataSeccomp:  "disabled",
+	}
+
+	if readTrimmed(filepath.Join(root, apparmorEnabledPath)) == "Y" {
+		metadata[MetadataAppArmor] = "enabled"
+	}
+	if seccompSupported(root) {
+		metadata[MetadataSeccomp] = "enabled"
+	}
+	if release := readTrimmed(filepath.Join(root, osReleasePath)); release != "" {
+		metadata[MetadataKernelRelease] = release
+		if version := kernelVersion(release); version != "" {
+			metadata[MetadataKernelVersion] = version
+		}
+	}
+
+	return metadata
+}
+
+// selinuxMode returns the mode SELinux runs in, or "disabled" if it is not
+// mounted
+func selinuxMode(root string) string {
+	switch readTrimmed(filepath.Join(root, selinuxEnforcePath)) {
+	case "1":
+		return "enforcing"
+	case "0":
+		return "permissive"
+	}
+	return "disabled"
+}
+
+// seccompSupported determines whether the kernel supports seccomp, which it
+// reports in the status of every process
+func seccompSupported(root string) bool {
+	f, err := os.Open(filepath.Join(root, procStatusPath))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "Seccomp:") {
+			return true
+		}
+	}
+	return false
+}
+
+// kernelVersion returns the major and minor version of the given kernel
+// release, e.g. 4.19 for 4.19.0-coreos
+func kernelVersion(release string) string {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	minor := parts[1]
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i]
+	}
+	if parts[0] == "" || minor == "" {
+		return ""
+	}
+	return parts[0] + "." + minor
+}
@@ -0,0 +1,68 @@
+package machine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverSecurityMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "fleet-")
+	if err != nil {
+		t.Fatalf("Failed creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := map[string]string{
+		"selinux":  "disabled",
+		"apparmor": "disabled",
+		"seccomp":  "disabled",
+	}
+	if got := DiscoverSecurityMetadata(dir); !reflect.DeepEqual(want, got) {
+		t.Errorf("Unexpected metadata of bare machine: got %v, want %v", got, want)
+	}
+
+	for path, contents := range map[string]string{
+		selinuxEnforcePath:  "1",
+		apparmorEnabledPath: "Y\n",
+		procStatusPath:      "Name:\tfleetd\nSeccomp:\t0\n",
+		osReleasePath:       "4.19.0-coreos-r1\n",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+			t.Fatalf("Failed creating %s: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), os.FileMode(0644)); err != nil {
+			t.Fatalf("Failed writing %s: %v", path, err)
+		}
+	}
+
+	want = map[string]string{
+		"selinux":        "enforcing",
+		"apparmor":       "enabled",
+		"seccomp":        "enabled",
+		"kernel_version": "4.19",
+		"kernel_release": "4.19.0-coreos-r1",
+	}
+	if got := DiscoverSecurityMetadata(dir); !reflect.DeepEqual(want, got) {
+		t.Errorf("Unexpected metadata of hardened machine: got %v, want %v", got, want)
+	}
+}
+
+func TestKernelVersion(t *testing.T) {
+	for release, want := range map[string]string{
+		"3.19.3":           "3.19",
+		"4.4.0-21-generic": "4.4",
+		"5.10-rc1":         "5.10",
+		"4":                "",
+		"":                 "",
+		".1":               "",
+		"4.x.0":            "",
+	} {
+		if got := kernelVersion(release); got != want {
+			t.Errorf("kernelVersion(%q): got %q, want %q", release, got, want)
+		}
+	}
+}
@@ -1,7 +1,36 @@
 package machine
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/resource"
+)
+
 const (
 	shortIDLen = 8
+
+	// Metadata of a machine overriding the overcommit factors it was
+	// configured with
+	MetadataCPUOvercommit    = "cpu-overcommit"
+	MetadataMemoryOvercommit = "memory-overcommit"
+
+	// Metadata of a machine overriding the limit of units it was
+	// configured with
+	MetadataMaxUnits = "max-units-per-machine"
+
+	// Metadata of a machine weighing it for placement, see
+	// MachineState.Cost and MachineState.Preemptible
+	MetadataCost        = "cost"
+	MetadataPreemptible = "preemptible"
+
+	// Policies deciding the memory of a machine units are admitted
+	// against, see MachineState.MemoryPolicy
+	MemoryPolicyReserved  = "reserved"
+	MemoryPolicyAvailable = "available"
+	MemoryPolicyHybrid    = "hybrid"
 )
 
 // MachineState represents a point-in-time snapshot of the
@@ -11,6 +40,167 @@ type MachineState struct {
 	PublicIP string
 	Metadata map[string]string
 	Version  string
+
+	// Addresses holds all addresses the machine can be reached at, IPv4
+	// and IPv6, public and private, most preferred first. PublicIP is the
+	// most preferred of them. It is empty for machines running older
+	// versions of fleet, see AllAddresses.
+	Addresses []Address `json:",omitempty"`
+
+	// TotalResources describes the capacity of the machine. It is
+	// empty if the machine does not publish its capacity.
+	TotalResources resource.ResourceTuple
+
+	// ReservedResources describes the part of the capacity of the
+	// machine set aside for the OS, system daemons and fleet itself,
+	// which units cannot reserve. If nil, as for machines running older
+	// versions of fleet, resource.HostResources is reserved.
+	ReservedResources *resource.ResourceTuple `json:",omitempty"`
+
+	// CPUOvercommit and MemoryOvercommit are the factors by which the
+	// reservations of units may exceed the allocatable CPU and memory of
+	// the machine. Zero, as for machines running older versions of
+	// fleet, means no overcommit. The cpu-overcommit and
+	// memory-overcommit metadata of the machine take precedence.
+	CPUOvercommit    float64 `json:",omitempty"`
+	MemoryOvercommit float64 `json:",omitempty"`
+
+	// MaxUnits is the number of units that may be scheduled to the
+	// machine, regardless of the resources they reserve. Zero, as for
+	// machines running older versions of fleet, means no limit. The
+	// max-units-per-machine metadata of the machine takes precedence.
+	MaxUnits int `json:",omitempty"`
+
+	// MemoryPolicy decides the memory of the machine new units are
+	// admitted against: with "reserved", the allocatable memory less the
+	// reservations of the units scheduled to the machine; with
+	// "available", the AvailableMemory of the machine; and with "hybrid",
+	// the lower of both. Empty, as for machines running older versions of
+	// fleet, means "reserved".
+	MemoryPolicy string `json:",omitempty"`
+
+	// AvailableMemory is the memory (in MB) the kernel of the machine
+	// estimates to be available to new processes without swapping. It is
+	// only published by machines whose MemoryPolicy uses it.
+	AvailableMemory int `json:",omitempty"`
+
+	// AllocatedResources describes the sum of the reservations of all
+	// units scheduled to the machine. It is not published by the machine
+	// itself and is never stored in the registry; clients derive it from
+	// the current schedule.
+	AllocatedResources resource.ResourceTuple `json:"-"`
+
+	// ExtendedResources describes the countable resources besides CPU,
+	// memory and disk the machine offers to units, like GPUs, and
+	// AllocatedExtendedResources the sum of the requests for them of all
+	// units scheduled to the machine. Like AllocatedResources, the latter
+	// is derived by clients.
+	ExtendedResources          resource.Counts `json:",omitempty"`
+	AllocatedExtendedResources resource.Counts `json:"-"`
+
+	// Cordoned machines accept no new units. Draining machines are also
+	// cordoned, and additionally have their non-global units moved to
+	// other machines. Both are set by operators rather than published by
+	// the machine itself, or follow from its maintenance window, see
+	// MaintenanceWindow.
+	Cordoned bool `json:"-"`
+	Draining bool `json:"-"`
+
+	// Taints repel the units not tolerating them, see Taint. Like
+	// cordons, they are set by operators, and ordered by key.
+	Taints []Taint `json:"-"`
+
+	// LastHeartbeat is when the machine last renewed its presence in the
+	// registry. It is nil for machines running older versions of fleet.
+	LastHeartbeat *time.Time `json:",omitempty"`
+
+	// APIURL is the URL at which the fleet API of the machine can be
+	// reached by the other machines, which relay requests for journals
+	// and commands run on the machine to it. It is empty if the machine
+	// does not advertise its API.
+	APIURL string `json:",omitempty"`
+}
+
+// Reserved returns the resources the machine sets aside for the host
+func (ms MachineState) Reserved() resource.ResourceTuple {
+	if ms.ReservedResources == nil {
+		return resource.HostResources
+	}
+	return *ms.ReservedResources
+}
+
+// AllocatableResources returns the resources the units scheduled to the
+// machine may reserve, i.e. its total resources less those reserved for the
+// host, with CPU and memory multiplied by their overcommit factors
+func (ms MachineState) AllocatableResources() resource.ResourceTuple {
+	res := resource.Sub(ms.TotalResources, ms.Reserved())
+	cpu, mem := ms.Overcommit()
+	res.Cores = int(float64(res.Cores) * cpu)
+	res.Memory = int(float64(res.Memory) * mem)
+	return res
+}
+
+// UsesAvailableMemory determines whether new units are admitted against the
+// AvailableMemory of the machine
+func (ms MachineState) UsesAvailableMemory() bool {
+	return ms.MemoryPolicy == MemoryPolicyAvailable || ms.MemoryPolicy == MemoryPolicyHybrid
+}
+
+// ValidateMemoryPolicy returns an error if the given MemoryPolicy is unknown
+func ValidateMemoryPolicy(policy string) error {
+	switch policy {
+	case MemoryPolicyReserved, MemoryPolicyAvailable, MemoryPolicyHybrid:
+		return nil
+	}
+	return fmt.Errorf("invalid memory policy %q: must be %s, %s or %s", policy, MemoryPolicyReserved, MemoryPolicyAvailable, MemoryPolicyHybrid)
+}
+
+// Overcommit returns the factors by which the machine overcommits its CPU
+// and memory
+func (ms MachineState) Overcommit() (cpu, memory float64) {
+	return ms.overcommit(MetadataCPUOvercommit, ms.CPUOvercommit), ms.overcommit(MetadataMemoryOvercommit, ms.MemoryOvercommit)
+}
+
+func (ms MachineState) overcommit(key string, configured float64) float64 {
+	if val, ok := ms.Metadata[key]; ok {
+		if f, err := strconv.ParseFloat(val, 64); err == nil && f > 0 {
+			return f
+		}
+	}
+	if configured > 0 {
+		return configured
+	}
+	return 1
+}
+
+// UnitLimit returns the number of units that may be scheduled to the
+// machine, or zero if there is no limit
+func (ms MachineState) UnitLimit() int {
+	if val, ok := ms.Metadata[MetadataMaxUnits]; ok {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return ms.MaxUnits
+}
+
+// Cost returns the cost of running units on the machine, as given by its
+// cost metadata, e.g. lower for spot instances than for on-demand ones.
+// Among the machines able to run a unit, the engine prefers the cheapest.
+// Machines without a valid, non-negative cost cost nothing.
+func (ms MachineState) Cost() float64 {
+	if val, ok := ms.Metadata[MetadataCost]; ok {
+		if f, err := strconv.ParseFloat(val, 64); err == nil && f >= 0 {
+			return f
+		}
+	}
+	return 0
+}
+
+// Preemptible determines whether the machine may be taken away at any time,
+// like a spot instance, as declared with its preemptible=true metadata
+func (ms MachineState) Preemptible() bool {
+	return strings.ToLower(ms.Metadata[MetadataPreemptible]) == "true"
 }
 
 func (ms MachineState) ShortID() string {
@@ -33,6 +223,11 @@ func stackState(top, bottom MachineState) MachineState {
 		state.PublicIP = top.PublicIP
 	}
 
+	// Configured addresses replace all detected ones
+	if top.PublicIP != "" || len(top.Addresses) > 0 {
+		state.Addresses = top.Addresses
+	}
+
 	if top.ID != "" {
 		state.ID = top.ID
 	}
@@ -48,5 +243,54 @@ func stackState(top, bottom MachineState) MachineState {
 		state.Version = top.Version
 	}
 
+	if !top.TotalResources.Empty() {
+		state.TotalResources = top.TotalResources
+	}
+
+	if top.ReservedResources != nil {
+		state.ReservedResources = top.ReservedResources
+	}
+
+	if len(top.ExtendedResources) > 0 {
+		state.ExtendedResources = top.ExtendedResources
+	}
+
+	if top.CPUOvercommit != 0 {
+		state.CPUOvercommit = top.CPUOvercommit
+	}
+
+	if top.MemoryOvercommit != 0 {
+		state.MemoryOvercommit = top.MemoryOvercommit
+	}
+
+	if top.MaxUnits != 0 {
+		state.MaxUnits = top.MaxUnits
+	}
+
+	if top.APIURL != "" {
+		state.APIURL = top.APIURL
+	}
+
+	if top.MemoryPolicy != "" {
+		state.MemoryPolicy = top.MemoryPolicy
+	}
+
+	if top.AvailableMemory != 0 {
+		state.AvailableMemory = top.AvailableMemory
+	}
+
 	return state
 }
+
+// overlayMetadata returns a copy of the bottom metadata with the values of
+// the top metadata set on it.
+func overlayMetadata(bottom, top map[string]string) map[string]string {
+	metadata := make(map[string]string, len(bottom)+len(top))
+	for key, val := range bottom {
+		metadata[key] = val
+	}
+	for key, val := range top {
+		metadata[key] = val
+	}
+	return metadata
+}
@@ -1,19 +1,25 @@
 package machine
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/coreos/fleet/resource"
+)
 
 func TestStackState(t *testing.T) {
 	top := MachineState{
-		ID:       "c31e44e1-f858-436e-933e-59c642517860",
-		PublicIP: "1.2.3.4",
-		Metadata: map[string]string{"ping": "pong"},
-		Version:  "1",
+		ID:             "c31e44e1-f858-436e-933e-59c642517860",
+		PublicIP:       "1.2.3.4",
+		Metadata:       map[string]string{"ping": "pong"},
+		Version:        "1",
+		TotalResources: resource.ResourceTuple{Cores: 200, Memory: 1024},
 	}
 	bottom := MachineState{
-		ID:       "595989bb-cbb7-49ce-8726-722d6e157b4e",
-		PublicIP: "5.6.7.8",
-		Metadata: map[string]string{"foo": "bar"},
-		Version:  "",
+		ID:             "595989bb-cbb7-49ce-8726-722d6e157b4e",
+		PublicIP:       "5.6.7.8",
+		Metadata:       map[string]string{"foo": "bar"},
+		Version:        "",
+		TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048},
 	}
 	stacked := stackState(top, bottom)
 
@@ -32,14 +38,19 @@ func TestStackState(t *testing.T) {
 	if stacked.Version != "1" {
 		t.Errorf("Unexpected Version value %s", stacked.Version)
 	}
+
+	if stacked.TotalResources != top.TotalResources {
+		t.Errorf("Unexpected TotalResources value %v", stacked.TotalResources)
+	}
 }
 
 func TestStackStateEmptyTop(t *testing.T) {
 	top := MachineState{}
 	bottom := MachineState{
-		ID:       "595989bb-cbb7-49ce-8726-722d6e157b4e",
-		PublicIP: "5.6.7.8",
-		Metadata: map[string]string{"foo": "bar"},
+		ID:             "595989bb-cbb7-49ce-8726-722d6e157b4e",
+		PublicIP:       "5.6.7.8",
+		Metadata:       map[string]string{"foo": "bar"},
+		TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048},
 	}
 	stacked := stackState(top, bottom)
 
@@ -58,6 +69,10 @@ func TestStackStateEmptyTop(t *testing.T) {
 	if stacked.Version != "" {
 		t.Errorf("Unexpected Version value %s", stacked.Version)
 	}
+
+	if stacked.TotalResources != bottom.TotalResources {
+		t.Errorf("Unexpected TotalResources value %v", stacked.TotalResources)
+	}
 }
 
 var shortIDTests = []struct {
@@ -76,6 +91,22 @@ var shortIDTests = []struct {
 			"5.6.7.8",
 			map[string]string{"foo": "bar"},
 			"",
+			nil,
+			resource.ResourceTuple{},
+			nil,
+			0,
+			0,
+			0,
+			"",
+			0,
+			resource.ResourceTuple{},
+			nil,
+			nil,
+			false,
+			false,
+			nil,
+			nil,
+			"",
 		},
 		s: "595989bb",
 		l: "595989bb-cbb7-49ce-8726-722d6e157b4e",
@@ -120,3 +151,98 @@ func TestStateMatchID(t *testing.T) {
 		}
 	}
 }
+
+func TestAllocatableResources(t *testing.T) {
+	ms := MachineState{TotalResources: resource.ResourceTuple{Cores: 400, Memory: 2048, Disk: 10240}}
+	if got, want := ms.AllocatableResources(), (resource.ResourceTuple{Cores: 300, Memory: 1792, Disk: 10240}); got != want {
+		t.Errorf("Machine without reservation has allocatable resources %v, want %v", got, want)
+	}
+
+	ms.ReservedResources = &resource.ResourceTuple{}
+	if got := ms.AllocatableResources(); got != ms.TotalResources {
+		t.Errorf("Machine reserving nothing has allocatable resources %v, want %v", got, ms.TotalResources)
+	}
+}
+
+func TestOvercommit(t *testing.T) {
+	for i, tt := range []struct {
+		ms       MachineState
+		cpu, mem float64
+	}{
+		{MachineState{}, 1, 1},
+		{MachineState{CPUOvercommit: 1.5, MemoryOvercommit: 1.2}, 1.5, 1.2},
+		{MachineState{CPUOvercommit: 1.5, Metadata: map[string]string{"cpu-overcommit": "2", "memory-overcommit": "1.1"}}, 2, 1.1},
+		// invalid metadata is ignored
+		{MachineState{CPUOvercommit: 1.5, Metadata: map[string]string{"cpu-overcommit": "lots", "memory-overcommit": "-1"}}, 1.5, 1},
+	} {
+		cpu, mem := tt.ms.Overcommit()
+		if cpu != tt.cpu || mem != tt.mem {
+			t.Errorf("case %d: got overcommit %v/%v, want %v/%v", i, cpu, mem, tt.cpu, tt.mem)
+		}
+	}
+
+	ms := MachineState{
+		TotalResources:    resource.ResourceTuple{Cores: 400, Memory: 2048, Disk: 1024},
+		ReservedResources: &resource.ResourceTuple{Cores: 200, Memory: 1024},
+		CPUOvercommit:     1.5,
+		MemoryOvercommit:  2,
+	}
+	if got, want := ms.AllocatableResources(), (resource.ResourceTuple{Cores: 300, Memory: 2048, Disk: 1024}); got != want {
+		t.Errorf("Overcommitting machine has allocatable resources %v, want %v", got, want)
+	}
+}
+
+func TestUnitLimit(t *testing.T) {
+	for i, tt := range []struct {
+		ms   MachineState
+		want int
+	}{
+		{MachineState{}, 0},
+		{MachineState{MaxUnits: 200}, 200},
+		{MachineState{MaxUnits: 200, Metadata: map[string]string{"max-units-per-machine": "50"}}, 50},
+		// metadata may lift the configured limit
+		{MachineState{MaxUnits: 200, Metadata: map[string]string{"max-units-per-machine": "0"}}, 0},
+		// invalid metadata is ignored
+		{MachineState{MaxUnits: 200, Metadata: map[string]string{"max-units-per-machine": "-1"}}, 200},
+	} {
+		if got := tt.ms.UnitLimit(); got != tt.want {
+			t.Errorf("case %d: got limit %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestCost(t *testing.T) {
+	for i, tt := range []struct {
+		metadata    map[string]string
+		cost        float64
+		preemptible bool
+	}{
+		{nil, 0, false},
+		{map[string]string{"cost": "0.25", "preemptible": "true"}, 0.25, true},
+		{map[string]string{"cost": "3", "preemptible": "false"}, 3, false},
+		// invalid costs are ignored
+		{map[string]string{"cost": "-1"}, 0, false},
+		{map[string]string{"cost": "cheap", "preemptible": "True"}, 0, true},
+	} {
+		ms := MachineState{Metadata: tt.metadata}
+		if got := ms.Cost(); got != tt.cost {
+			t.Errorf("case %d: got cost %v, want %v", i, got, tt.cost)
+		}
+		if got := ms.Preemptible(); got != tt.preemptible {
+			t.Errorf("case %d: got preemptible %t, want %t", i, got, tt.preemptible)
+		}
+	}
+}
+
+func TestValidateMemoryPolicy(t *testing.T) {
+	for _, policy := range []string{MemoryPolicyReserved, MemoryPolicyAvailable, MemoryPolicyHybrid} {
+		if err := ValidateMemoryPolicy(policy); err != nil {
+			t.Errorf("Unexpected error for %q: %v", policy, err)
+		}
+	}
+	for _, policy := range []string{"", "free", "Available"} {
+		if err := ValidateMemoryPolicy(policy); err == nil {
+			t.Errorf("Expected error for %q", policy)
+		}
+	}
+}
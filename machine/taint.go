@@ -0,0 +1,114 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// TaintEffectNoSchedule keeps units not tolerating a taint from being
+	// scheduled to the tainted machine. Units already scheduled to it
+	// stay where they are.
+	TaintEffectNoSchedule = "NoSchedule"
+)
+
+// A Taint set on a machine by an operator repels all units that do not
+// declare a matching toleration.
+type Taint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+// ParseTaint parses a Taint of the form key[=value]:effect
+func ParseTaint(s string) (Taint, error) {
+	t, err := parseTaint(s)
+	if err != nil {
+		return t, err
+	}
+	if t.Effect == "" {
+		return t, fmt.Errorf("taint %q must be of the form key[=value]:%s", s, TaintEffectNoSchedule)
+	}
+	return t, nil
+}
+
+// Validate returns why the Taint cannot be set on a machine, if it cannot
+func (t Taint) Validate() error {
+	if err := validateTaintKey(t.Key); err != nil {
+		return err
+	}
+	if t.Effect != TaintEffectNoSchedule {
+		return fmt.Errorf("unknown effect %q, must be %s", t.Effect, TaintEffectNoSchedule)
+	}
+	return nil
+}
+
+func (t Taint) String() string {
+	s := t.Key
+	if t.Value != "" {
+		s += "=" + t.Value
+	}
+	if t.Effect != "" {
+		s += ":" + t.Effect
+	}
+	return s
+}
+
+// A Toleration lets a unit be scheduled to machines carrying a matching
+// Taint. An empty Value or Effect matches any.
+type Toleration Taint
+
+// ParseToleration parses a Toleration of the form key[=value][:effect]
+func ParseToleration(s string) (Toleration, error) {
+	t, err := parseTaint(s)
+	return Toleration(t), err
+}
+
+func (tol Toleration) String() string {
+	return Taint(tol).String()
+}
+
+// Tolerates determines whether the Toleration matches the given Taint
+func (tol Toleration) Tolerates(t Taint) bool {
+	return tol.Key == t.Key && (tol.Value == "" || tol.Value == t.Value) && (tol.Effect == "" || tol.Effect == t.Effect)
+}
+
+func parseTaint(s string) (t Taint, err error) {
+	rest := s
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		rest, t.Effect = rest[:i], rest[i+1:]
+		if t.Effect != TaintEffectNoSchedule {
+			return t, fmt.Errorf("unknown effect %q of %q, must be %s", t.Effect, s, TaintEffectNoSchedule)
+		}
+	}
+	if i := strings.Index(rest, "="); i >= 0 {
+		rest, t.Value = rest[:i], rest[i+1:]
+	}
+	t.Key = rest
+	return t, validateTaintKey(t.Key)
+}
+
+func validateTaintKey(key string) error {
+	if key == "" || strings.ContainsAny(key, "/=: ") {
+		return fmt.Errorf("invalid taint key %q", key)
+	}
+	return nil
+}
+
+// UntoleratedTaint returns the first of the machine's Taints that none of
+// the given Tolerations matches, if any
+func (ms MachineState) UntoleratedTaint(tolerations []Toleration) (Taint, bool) {
+	for _, t := range ms.Taints {
+		tolerated := false
+		for _, tol := range tolerations {
+			if tol.Tolerates(t) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return t, true
+		}
+	}
+	return Taint{}, false
+}
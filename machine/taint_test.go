@@ -0,0 +1,65 @@
+package machine
+
+import (
+	"testing"
+)
+
+func TestParseTaint(t *testing.T) {
+	for i, tt := range []struct {
+		in   string
+		want Taint
+		ok   bool
+	}{
+		{"dedicated=db:NoSchedule", Taint{Key: "dedicated", Value: "db", Effect: TaintEffectNoSchedule}, true},
+		{"maintenance:NoSchedule", Taint{Key: "maintenance", Effect: TaintEffectNoSchedule}, true},
+		{"dedicated=db", Taint{}, false},
+		{"dedicated=db:NoExecute", Taint{}, false},
+		{"=db:NoSchedule", Taint{}, false},
+		{"a/b=db:NoSchedule", Taint{}, false},
+	} {
+		got, err := ParseTaint(tt.in)
+		if tt.ok != (err == nil) {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if tt.ok && got != tt.want {
+			t.Errorf("case %d: got %#v, want %#v", i, got, tt.want)
+		}
+		if tt.ok && got.String() != tt.in {
+			t.Errorf("case %d: String returned %q, want %q", i, got.String(), tt.in)
+		}
+	}
+}
+
+func TestUntoleratedTaint(t *testing.T) {
+	ms := MachineState{Taints: []Taint{
+		Taint{Key: "dedicated", Value: "db", Effect: TaintEffectNoSchedule},
+		Taint{Key: "maintenance", Effect: TaintEffectNoSchedule},
+	}}
+
+	for i, tt := range []struct {
+		tolerations []string
+		tainted     bool
+		key         string
+	}{
+		{nil, true, "dedicated"},
+		{[]string{"dedicated=db:NoSchedule"}, true, "maintenance"},
+		{[]string{"dedicated=db", "maintenance"}, false, ""},
+		{[]string{"dedicated", "maintenance:NoSchedule"}, false, ""},
+		{[]string{"dedicated=web", "maintenance"}, true, "dedicated"},
+	} {
+		var tols []Toleration
+		for _, s := range tt.tolerations {
+			tol, err := ParseToleration(s)
+			if err != nil {
+				t.Fatalf("case %d: unexpected error parsing %q: %v", i, s, err)
+			}
+			tols = append(tols, tol)
+		}
+
+		taint, tainted := ms.UntoleratedTaint(tols)
+		if tainted != tt.tainted || taint.Key != tt.key {
+			t.Errorf("case %d: got %v/%t, want %s/%t", i, taint, tainted, tt.key, tt.tainted)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package metrics implements the counters, gauges and histograms fleetd
+// exports about itself, and serves them in the Prometheus text exposition
+// format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+
+	// contentType is the media type of the Prometheus text format
+	contentType = "text/plain; version=0.0.4"
+)
+
+// DefaultBuckets are histogram buckets suited to the duration of requests
+// and reconciliations, in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	registeredMutex sync.Mutex
+	registered      = make(map[string]*metric)
+)
+
+// metric is a named family of series, one per combination of label values
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+
+	// histograms only; counts[i] is the number of observations in
+	// (buckets[i-1], buckets[i]]
+	counts []uint64
+	count  uint64
+}
+
+func newMetric(name, help, kind string, buckets []float64, labels []string) *metric {
+	return &metric{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+func register(m *metric) *metric {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	if _, ok := registered[m.name]; ok {
+		panic(fmt.Sprintf("metric %s registered twice", m.name))
+	}
+	registered[m.name] = m
+	return m
+}
+
+// unregister removes the named metric, so that it may be registered again
+func unregister(name string) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	delete(registered, name)
+}
+
+// with calls fn with the series identified by the given label values while
+// holding the metric's lock
+func (m *metric) with(labelValues []string, fn func(s *series)) {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues}
+		if m.kind == kindHistogram {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	fn(s)
+}
+
+// Counter is a metric that only ever goes up
+type Counter struct {
+	m *metric
+}
+
+// NewCounter registers a Counter with the given name, partitioned by the
+// given labels
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{register(newMetric(name, help, kindCounter, nil, labels))}
+}
+
+// Inc increments the Counter for the given label values by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the Counter for the given label values by v, which must
+// not be negative
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.m.with(labelValues, func(s *series) { s.value += v })
+}
+
+// Gauge is a metric that can be set to arbitrary values
+type Gauge struct {
+	m *metric
+}
+
+// NewGauge registers a Gauge with the given name, partitioned by the given
+// labels
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{register(newMetric(name, help, kindGauge, nil, labels))}
+}
+
+// Set sets the Gauge for the given label values to v
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.m.with(labelValues, func(s *series) { s.value = v })
+}
+
+// Histogram counts observations, e.g. request durations, in buckets
+type Histogram struct {
+	m *metric
+}
+
+// NewHistogram registers a Histogram with the given name and upper bucket
+// boundaries, partitioned by the given labels. The buckets must be sorted.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{register(newMetric(name, help, kindHistogram, buckets, labels))}
+}
+
+// Observe adds an observation of v to the Histogram for the given label
+// values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.m.with(labelValues, func(s *series) {
+		i := sort.SearchFloat64s(h.m.buckets, v)
+		if i < len(s.counts) {
+			s.counts[i]++
+		}
+		s.count++
+		s.value += v
+	})
+}
+
+// ObserveSince adds the number of seconds elapsed since start to the
+// Histogram for the given label values
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Now().Sub(start).Seconds(), labelValues...)
+}
+
+// WriteTo writes all registered metrics to w in the Prometheus text format
+func WriteTo(w io.Writer) error {
+	registeredMutex.Lock()
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	registeredMutex.Unlock()
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		registeredMutex.Lock()
+		m := registered[name]
+		registeredMutex.Unlock()
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an http.Handler serving all registered metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", contentType)
+		WriteTo(rw)
+	})
+}
+
+func (m *metric) write(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, helpEscaper.Replace(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+		if m.kind != kindHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, formatLabels(m.labels, s.labelValues), formatValue(s.value))
+			continue
+		}
+
+		labels := append(append([]string{}, m.labels...), "le")
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += s.counts[i]
+			values := append(append([]string{}, s.labelValues...), formatValue(bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(labels, values), cumulative)
+		}
+		values := append(append([]string{}, s.labelValues...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(labels, values), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, formatLabels(m.labels, s.labelValues), formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, formatLabels(m.labels, s.labelValues), s.count)
+	}
+}
+
+func formatLabels(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, l, labelEscaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterWrite(t *testing.T) {
+	c := &Counter{newMetric("test_requests_total", "Requests handled.", kindCounter, nil, []string{"code"})}
+	c.Inc("200")
+	c.Inc("200")
+	c.Add(3, "5\"0\"0")
+
+	var buf bytes.Buffer
+	c.m.write(&buf)
+	want := `# HELP test_requests_total Requests handled.
+# TYPE test_requests_total counter
+test_requests_total{code="200"} 2
+test_requests_total{code="5\"0\"0"} 3
+`
+	if buf.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestGaugeWrite(t *testing.T) {
+	g := &Gauge{newMetric("test_temperature", "Current\ntemperature.", kindGauge, nil, nil)}
+	g.Set(21.5)
+	g.Set(-4)
+
+	var buf bytes.Buffer
+	g.m.write(&buf)
+	want := `# HELP test_temperature Current\ntemperature.
+# TYPE test_temperature gauge
+test_temperature -4
+`
+	if buf.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestHistogramWrite(t *testing.T) {
+	h := &Histogram{newMetric("test_duration_seconds", "Durations.", kindHistogram, []float64{0.1, 1}, []string{"op"})}
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v, "get")
+	}
+
+	var buf bytes.Buffer
+	h.m.write(&buf)
+	want := `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{op="get",le="0.1"} 2
+test_duration_seconds_bucket{op="get",le="1"} 3
+test_duration_seconds_bucket{op="get",le="+Inf"} 4
+test_duration_seconds_sum{op="get"} 3.65
+test_duration_seconds_count{op="get"} 4
+`
+	if buf.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestLabelValueCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic on wrong number of label values")
+		}
+	}()
+
+	c := &Counter{newMetric("test_total", "", kindCounter, nil, []string{"a", "b"})}
+	c.Inc("x")
+}
+
+func TestHandler(t *testing.T) {
+	c := NewCounter("test_handler_total", "Handler test.")
+	defer unregister("test_handler_total")
+	c.Inc()
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatalf("Failed creating http.Request: %v", err)
+	}
+	rw := httptest.NewRecorder()
+	Handler().ServeHTTP(rw, req)
+
+	if ct := rw.Header().Get("Content-Type"); ct != contentType {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+	if !strings.Contains(rw.Body.String(), "\ntest_handler_total 1\n") {
+		t.Errorf("Registered metric missing from output:\n%s", rw.Body.String())
+	}
+}
@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// diffOp is a line kept, removed or added by a diff
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns the differences between the lines of a and b in the
+// unified format, labelled with the given names and with the given number of
+// lines of context around each change. It returns an empty string if the
+// lines are the same.
+func UnifiedDiff(a, b []string, aName, bName string, context int) string {
+	ops := diffLines(a, b)
+
+	var buf bytes.Buffer
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// A hunk starts with the context before the first change and
+		// extends until a run of unchanged lines longer than twice the
+		// context separates it from the next change
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				if run-end > context {
+					run = end + context
+				}
+				end = run
+				break
+			}
+			end = run
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+		}
+		writeHunk(&buf, ops, start, end)
+		i = end
+	}
+	return buf.String()
+}
+
+// writeHunk writes the hunk of the given operations between start and end
+func writeHunk(buf *bytes.Buffer, ops []diffOp, start, end int) {
+	// line numbers of the first line of the hunk in a and b
+	aLine, bLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+	}
+
+	var aCount, bCount int
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+	for _, op := range ops[start:end] {
+		fmt.Fprintf(buf, "%c%s\n", op.kind, op.line)
+	}
+}
+
+// hunkRange formats the range of lines of a hunk. Empty ranges refer to the
+// line before them.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		line--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// diffLines returns the operations turning a into b, based on their longest
+// common subsequence of lines
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	for i, tt := range []struct {
+		a, b    string
+		context int
+		want    string
+	}{
+		// no differences
+		{"a\nb", "a\nb", 3, ""},
+		// a changed line
+		{
+			"[Service]\nExecStart=/bin/foo\nRestart=always", "[Service]\nExecStart=/bin/bar\nRestart=always", 3,
+			"--- a\n+++ b\n@@ -1,3 +1,3 @@\n [Service]\n-ExecStart=/bin/foo\n+ExecStart=/bin/bar\n Restart=always\n",
+		},
+		// an added line at the end
+		{"a\nb", "a\nb\nc", 1, "--- a\n+++ b\n@@ -2 +2,2 @@\n b\n+c\n"},
+		// a removed line with no context
+		{"a\nb\nc", "a\nc", 0, "--- a\n+++ b\n@@ -2 +1,0 @@\n-b\n"},
+		// changes far apart are separate hunks
+		{
+			"1\n2\n3\n4\n5\n6\n7", "x\n2\n3\n4\n5\n6\ny", 1,
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+y\n",
+		},
+		// changes close together share a hunk
+		{
+			"1\n2\n3\n4", "x\n2\n3\ny", 1,
+			"--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+	} {
+		got := UnifiedDiff(strings.Split(tt.a, "\n"), strings.Split(tt.b, "\n"), "a", "b", tt.context)
+		if got != tt.want {
+			t.Errorf("case %d: got\n%s\nwant\n%s", i, got, tt.want)
+		}
+	}
+}
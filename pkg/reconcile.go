@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"sync"
 	"time"
 
 	"github.com/coreos/fleet/log"
@@ -15,8 +16,58 @@ type EventStream interface {
 	Next(stop chan struct{}) chan Event
 }
 
+// MergeEventStreams creates an EventStream emitting an event as soon as any of
+// the given EventStreams does
+func MergeEventStreams(streams ...EventStream) EventStream {
+	return mergedEventStream(streams)
+}
+
+type mergedEventStream []EventStream
+
+func (ms mergedEventStream) Next(stop chan struct{}) chan Event {
+	evchan := make(chan Event)
+
+	// done stops the remaining streams once one of them emitted an
+	// event, or stop is closed
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		select {
+		case <-stop:
+			once.Do(func() { close(done) })
+		case <-done:
+		}
+	}()
+
+	for _, s := range ms {
+		go func(ch chan Event) {
+			select {
+			case ev := <-ch:
+				first := false
+				once.Do(func() {
+					close(done)
+					first = true
+				})
+				if first {
+					select {
+					case evchan <- ev:
+					case <-stop:
+					}
+				}
+			case <-done:
+			}
+		}(s.Next(done))
+	}
+
+	return evchan
+}
+
 type PeriodicReconciler interface {
 	Run(stop chan bool)
+
+	// SetInterval changes the interval at which recFunc runs at least,
+	// taking effect the next time the interval starts over
+	SetInterval(ival time.Duration)
 }
 
 // NewPeriodicReconciler creates a PeriodicReconciler that will run recFunc at least every
@@ -31,6 +82,7 @@ func NewPeriodicReconciler(interval time.Duration, recFunc func(), eStream Event
 }
 
 type reconciler struct {
+	mutex   sync.Mutex
 	ival    time.Duration
 	rFunc   func()
 	eStream EventStream
@@ -52,7 +104,7 @@ func (r *reconciler) Run(stop chan bool) {
 		}
 	}()
 
-	ticker := r.clock.After(r.ival)
+	ticker := r.clock.After(r.interval())
 
 	// When starting up, reconcile once immediately
 	log.V(1).Info("Initial reconcilation commencing")
@@ -64,14 +116,26 @@ func (r *reconciler) Run(stop chan bool) {
 			log.V(1).Info("Reconciler exiting due to stop signal")
 			return
 		case <-ticker:
-			ticker = r.clock.After(r.ival)
+			ticker = r.clock.After(r.interval())
 			log.V(1).Info("Reconciler tick")
 			r.rFunc()
 		case <-trigger:
-			ticker = r.clock.After(r.ival)
+			ticker = r.clock.After(r.interval())
 			log.V(1).Info("Reconciler triggered")
 			r.rFunc()
 		}
 	}
 
 }
+
+func (r *reconciler) SetInterval(ival time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ival = ival
+}
+
+func (r *reconciler) interval() time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.ival
+}
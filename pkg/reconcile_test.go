@@ -120,3 +120,59 @@ func TestPeriodicReconcilerRun(t *testing.T) {
 		t.Fatalf("PeriodicReconciler.Run did not return after stop signal!")
 	}
 }
+
+func TestPeriodicReconcilerSetInterval(t *testing.T) {
+	fclock := &FakeClock{}
+	fes := &fakeEventStream{make(chan Event)}
+	called := make(chan struct{})
+	pr := &reconciler{
+		ival:    5 * time.Hour,
+		rFunc:   func() { go func() { called <- struct{}{} }() },
+		eStream: fes,
+		clock:   fclock,
+	}
+	stop := make(chan bool)
+	defer close(stop)
+	go pr.Run(stop)
+	<-called
+
+	// the interval restarting after the trigger is the new one
+	pr.SetInterval(time.Hour)
+	fes.trigger()
+	<-called
+	fclock.Tick(time.Hour)
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatalf("rFunc() not called after new interval!")
+	}
+}
+
+func TestMergeEventStreams(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for i := 0; i < 2; i++ {
+		fes := []*fakeEventStream{{make(chan Event)}, {make(chan Event)}}
+		evchan := MergeEventStreams(fes[0], fes[1]).Next(stop)
+		fes[i].trigger()
+		select {
+		case ev := <-evchan:
+			if ev != Event("asdf") {
+				t.Fatalf("received unexpected event %v", ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("merged stream emitted no event")
+		}
+	}
+
+	// no event is emitted once stopped
+	halt := make(chan struct{})
+	evchan := MergeEventStreams(&fakeEventStream{make(chan Event)}).Next(halt)
+	close(halt)
+	select {
+	case ev := <-evchan:
+		t.Fatalf("received unexpected event %v", ev)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
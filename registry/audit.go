@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"path"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	auditPrefix = "audit"
+
+	// auditLimit is the number of AuditEntries kept in the registry
+	auditLimit = 1000
+
+	// A Unit was created
+	AuditUnitCreated = "create"
+	// A Unit was destroyed
+	AuditUnitDestroyed = "destroy"
+	// The target state of a Unit was set, e.g. by starting or stopping it
+	AuditUnitTargetStateSet = "set-target-state"
+	// The number of instances of a template Unit was set
+	AuditUnitScaled = "scale"
+	// The unit file of a Unit was replaced. The states of the entry hold
+	// the hashes of the unit files.
+	AuditUnitReplaced = "replace"
+	// The labels of a Unit were replaced. The states of the entry hold
+	// the labels as key=value pairs.
+	AuditUnitLabeled = "label"
+)
+
+// AuditEntry records a change made to the Units of the cluster on behalf of
+// a user. Index orders AuditEntries and is assigned when they are recorded.
+type AuditEntry struct {
+	Index     uint64 `json:"-"`
+	Time      time.Time
+	User      string
+	Action    string
+	UnitName  string
+	PrevState string `json:",omitempty"`
+	State     string `json:",omitempty"`
+}
+
+// RecordAudit adds the given AuditEntry to the cluster's audit log. Only
+// the latest 1000 entries are kept.
+func (r *EtcdRegistry) RecordAudit(ae AuditEntry) error {
+	if ae.Time.IsZero() {
+		ae.Time = time.Now()
+	}
+
+	val, err := marshal(ae)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.CreateInOrder{
+		Dir:   path.Join(r.keyPrefix, auditPrefix),
+		Value: val,
+	}
+	if _, err := r.etcd.Do(&req); err != nil {
+		return err
+	}
+
+	return r.trimAuditLog()
+}
+
+// trimAuditLog removes the oldest AuditEntries beyond the audit limit
+func (r *EtcdRegistry) trimAuditLog() error {
+	nodes, err := r.auditNodes()
+	if err != nil {
+		return err
+	}
+
+	for len(nodes) > auditLimit {
+		req := etcd.Delete{
+			Key: nodes[0].Key,
+		}
+		if _, err := r.etcd.Do(&req); err != nil && !isKeyNotFound(err) {
+			return err
+		}
+		nodes = nodes[1:]
+	}
+	return nil
+}
+
+// AuditLog returns the AuditEntries in the cluster's audit log, oldest
+// first
+func (r *EtcdRegistry) AuditLog() ([]AuditEntry, error) {
+	nodes, err := r.auditNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []AuditEntry
+	for _, node := range nodes {
+		var ae AuditEntry
+		if err := unmarshal(node.Value, &ae); err != nil {
+			log.Errorf("Failed parsing AuditEntry from %s: %v", node.Key, err)
+			continue
+		}
+		ae.Index = node.CreatedIndex
+		entries = append(entries, ae)
+	}
+	return entries, nil
+}
+
+func (r *EtcdRegistry) auditNodes() (etcd.Nodes, error) {
+	req := etcd.Get{
+		Key:       path.Join(r.keyPrefix, auditPrefix),
+		Sorted:    true,
+		Recursive: true,
+	}
+
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+	return res.Node.Nodes, nil
+}
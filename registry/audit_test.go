@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestAuditLog(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/audit",
+			Nodes: []etcd.Node{
+				etcd.Node{
+					Key:          "/fleet/audit/00000000000000000011",
+					Value:        `{"Time":"2014-10-01T12:00:00Z","User":"cert:alice","Action":"create","UnitName":"foo.service","State":"launched"}`,
+					CreatedIndex: 11,
+				},
+				etcd.Node{
+					Key:          "/fleet/audit/00000000000000000012",
+					Value:        `garbage`,
+					CreatedIndex: 12,
+				},
+				etcd.Node{
+					Key:          "/fleet/audit/00000000000000000013",
+					Value:        `{"Time":"2014-10-01T12:00:01Z","User":"bob@host","Action":"destroy","UnitName":"foo.service","PrevState":"launched"}`,
+					CreatedIndex: 13,
+				},
+			},
+		},
+	}
+
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.AuditLog()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []AuditEntry{
+		AuditEntry{Index: 11, Time: time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC), User: "cert:alice", Action: AuditUnitCreated, UnitName: "foo.service", State: "launched"},
+		AuditEntry{Index: 13, Time: time.Date(2014, 10, 1, 12, 0, 1, 0, time.UTC), User: "bob@host", Action: AuditUnitDestroyed, UnitName: "foo.service", PrevState: "launched"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected AuditEntries:\ngot  %#v\nwant %#v", got, want)
+	}
+
+	e = &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if got, err := r.AuditLog(); err != nil || len(got) != 0 {
+		t.Errorf("Expected empty audit log, got %v, err %v", got, err)
+	}
+}
+
+func TestRecordAuditTrims(t *testing.T) {
+	var nodes []etcd.Node
+	for i := 1; i <= auditLimit+2; i++ {
+		nodes = append(nodes, etcd.Node{Key: fmt.Sprintf("/fleet/audit/%020d", i), CreatedIndex: uint64(i)})
+	}
+	res := &etcd.Result{Node: &etcd.Node{Key: "/fleet/audit", Nodes: nodes}}
+
+	// the first result belongs to the creation of the entry
+	e := &testEtcdClient{res: []*etcd.Result{nil, res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	if err := r.RecordAudit(AuditEntry{User: "bob", Action: AuditUnitCreated, UnitName: "foo.service"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []action{
+		action{key: "/fleet/audit/00000000000000000001"},
+		action{key: "/fleet/audit/00000000000000000002"},
+	}
+	if !reflect.DeepEqual(e.deletes, want) {
+		t.Errorf("Unexpected deletes: got %v, want %v", e.deletes, want)
+	}
+}
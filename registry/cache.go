@@ -0,0 +1,374 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+// cachedPrefixes are the namespaces read in full on every reconciliation,
+// relative to the registry's key prefix
+var cachedPrefixes = []string{jobPrefix, unitPrefix, statePrefix, statesPrefix, machineStatesPrefix}
+
+// CachedClient is an etcd.Client that serves reads of the job, unit and
+// unit state namespaces from an in-memory mirror of those namespaces. Each
+// mirror is loaded with a single recursive GET and then kept up to date by
+// watching etcd. All other actions, as well as reads of a namespace whose
+// mirror is not in sync, are passed through to etcd.
+//
+// Writes made through the CachedClient are visible to subsequent reads
+// through it: a namespace is read from etcd until its mirror has caught up
+// with the last write made to it.
+type CachedClient struct {
+	etcd    etcd.Client
+	mirrors []*mirror
+}
+
+func NewCachedClient(client etcd.Client, keyPrefix string) *CachedClient {
+	cc := &CachedClient{etcd: client}
+	for _, p := range cachedPrefixes {
+		cc.mirrors = append(cc.mirrors, newMirror(path.Join(keyPrefix, p)))
+	}
+	return cc
+}
+
+// Run keeps the mirrors in sync with etcd until the stop channel is closed,
+// after which all reads are passed through to etcd again.
+func (cc *CachedClient) Run(stop chan bool) {
+	cancel := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, m := range cc.mirrors {
+		wg.Add(1)
+		go func(m *mirror) {
+			defer wg.Done()
+			m.run(cc.etcd, cancel)
+		}(m)
+	}
+
+	<-stop
+	close(cancel)
+	wg.Wait()
+}
+
+func (cc *CachedClient) Do(act etcd.Action) (*etcd.Result, error) {
+	if get, ok := act.(*etcd.Get); ok {
+		if m := cc.mirrorOf(get.Key); m != nil {
+			if res, err, ok := m.get(get.Key, get.Recursive); ok {
+				return res, err
+			}
+		}
+		return cc.etcd.Do(act)
+	}
+
+	res, err := cc.etcd.Do(act)
+	if err == nil && res != nil && res.Node != nil {
+		key := actionKey(act)
+		for _, m := range cc.mirrors {
+			if m.overlaps(key) {
+				m.require(res.Node.ModifiedIndex)
+			}
+		}
+	}
+	return res, err
+}
+
+func (cc *CachedClient) Wait(act etcd.Action, stop <-chan struct{}) (*etcd.Result, error) {
+	return cc.etcd.Wait(act, stop)
+}
+
+// mirrorOf returns the mirror holding the given key, if any
+func (cc *CachedClient) mirrorOf(key string) *mirror {
+	key = cleanKey(key)
+	for _, m := range cc.mirrors {
+		if m.contains(key) {
+			return m
+		}
+	}
+	return nil
+}
+
+// actionKey returns the key written by the given action
+func actionKey(act etcd.Action) string {
+	switch a := act.(type) {
+	case *etcd.Set:
+		return a.Key
+	case *etcd.Create:
+		return a.Key
+	case *etcd.CreateInOrder:
+		return a.Dir
+	case *etcd.Update:
+		return a.Key
+	case *etcd.Delete:
+		return a.Key
+	}
+	return ""
+}
+
+func cleanKey(key string) string {
+	return path.Join("/", key)
+}
+
+// mirror holds the leaf nodes below a prefix in etcd
+type mirror struct {
+	prefix string
+
+	mutex    sync.RWMutex
+	synced   bool
+	index    uint64
+	required uint64
+	nodes    map[string]etcd.Node
+}
+
+func newMirror(prefix string) *mirror {
+	return &mirror{prefix: cleanKey(prefix)}
+}
+
+func (m *mirror) contains(key string) bool {
+	return key == m.prefix || strings.HasPrefix(key, m.prefix+"/")
+}
+
+// overlaps determines whether a write to the given key may change any node
+// in the mirror, e.g. by deleting one of its parents
+func (m *mirror) overlaps(key string) bool {
+	if key == "" {
+		return false
+	}
+	key = cleanKey(key)
+	return m.contains(key) || key == "/" || strings.HasPrefix(m.prefix, key+"/")
+}
+
+// require ensures reads are not served from the mirror before it has
+// applied the change with the given index
+func (m *mirror) require(index uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if index > m.required {
+		m.required = index
+	}
+}
+
+func (m *mirror) run(client etcd.Client, stop <-chan struct{}) {
+	defer m.reset()
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		m.mutex.RLock()
+		synced, index := m.synced, m.index
+		m.mutex.RUnlock()
+
+		if !synced {
+			if err := m.load(client); err != nil {
+				log.Errorf("Failed loading registry cache of %s: %v", m.prefix, err)
+				sleep(stop)
+			}
+			continue
+		}
+
+		req := &etcd.Watch{
+			Key:       m.prefix,
+			Recursive: true,
+			WaitIndex: index + 1,
+		}
+		res, err := client.Wait(req, stop)
+		if err != nil {
+			if e, ok := err.(etcd.Error); ok && e.ErrorCode == etcd.ErrorEventIndexCleared {
+				log.V(1).Infof("Registry cache of %s fell behind, reloading", m.prefix)
+				m.reset()
+				continue
+			}
+			log.Errorf("etcd watcher %v returned error: %v", req, err)
+			sleep(stop)
+			continue
+		}
+
+		m.apply(res)
+	}
+}
+
+// sleep waits a second, so an etcd server failing requests is not retried
+// in a tight loop, or until the stop channel is closed
+func sleep(stop <-chan struct{}) {
+	select {
+	case <-stop:
+	case <-time.After(time.Second):
+	}
+}
+
+// load replaces the contents of the mirror with the current contents of
+// its prefix in etcd
+func (m *mirror) load(client etcd.Client) error {
+	req := &etcd.Get{
+		Key:       m.prefix,
+		Recursive: true,
+	}
+
+	nodes := make(map[string]etcd.Node)
+	var index uint64
+	res, err := client.Do(req)
+	if err != nil {
+		e, ok := err.(etcd.Error)
+		if !ok || e.ErrorCode != etcd.ErrorKeyNotFound {
+			return err
+		}
+		index = e.Index
+	} else {
+		index = res.Index
+		if res.Node != nil {
+			addLeaves(nodes, *res.Node)
+		}
+	}
+
+	if index == 0 {
+		return fmt.Errorf("etcd did not report an index for %s", m.prefix)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.nodes = nodes
+	m.index = index
+	m.synced = true
+	log.V(1).Infof("Loaded registry cache of %s at index %d", m.prefix, index)
+	return nil
+}
+
+func addLeaves(nodes map[string]etcd.Node, n etcd.Node) {
+	if len(n.Nodes) == 0 {
+		n.Nodes = nil
+		nodes[n.Key] = n
+		return
+	}
+	for _, child := range n.Nodes {
+		addLeaves(nodes, child)
+	}
+}
+
+func (m *mirror) reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.synced = false
+	m.nodes = nil
+}
+
+// apply updates the mirror with a change reported by a watch
+func (m *mirror) apply(res *etcd.Result) {
+	if res == nil || res.Node == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.synced {
+		return
+	}
+
+	key := res.Node.Key
+	switch res.Action {
+	case "delete", "expire", "compareAndDelete":
+		for k := range m.nodes {
+			if k == key || strings.HasPrefix(k, key+"/") {
+				delete(m.nodes, k)
+			}
+		}
+	default:
+		n := *res.Node
+		n.Nodes = nil
+		m.nodes[key] = n
+	}
+
+	if res.Node.ModifiedIndex > m.index {
+		m.index = res.Node.ModifiedIndex
+	}
+}
+
+// get reads the given key from the mirror the way etcd would, returning
+// false if the mirror cannot serve the read
+func (m *mirror) get(key string, recursive bool) (*etcd.Result, error, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if !m.synced || m.index < m.required {
+		return nil, nil, false
+	}
+
+	key = cleanKey(key)
+	if n, ok := m.nodes[key]; ok {
+		return &etcd.Result{Action: "get", Node: &n, Index: m.index}, nil, true
+	}
+
+	root := &tree{node: etcd.Node{Key: key}}
+	for k, n := range m.nodes {
+		if strings.HasPrefix(k, key+"/") {
+			root.insert(strings.Split(strings.TrimPrefix(k, key+"/"), "/"), n, recursive)
+		}
+	}
+
+	if len(root.children) == 0 {
+		err := etcd.Error{ErrorCode: etcd.ErrorKeyNotFound, Message: "Key not found", Cause: key, Index: m.index}
+		return nil, err, true
+	}
+
+	dir := root.build()
+	return &etcd.Result{Action: "get", Node: &dir, Index: m.index}, nil, true
+}
+
+// tree is used to assemble the leaf nodes below a key into the etcd.Node
+// tree etcd would return for it
+type tree struct {
+	node     etcd.Node
+	children map[string]*tree
+}
+
+// insert adds the leaf node n, found at the given path below the tree.
+// Unless recursive, only the direct children of the tree are added,
+// without their own children.
+func (t *tree) insert(parts []string, n etcd.Node, recursive bool) {
+	if t.children == nil {
+		t.children = make(map[string]*tree)
+	}
+
+	if len(parts) == 1 {
+		t.children[parts[0]] = &tree{node: n}
+		return
+	}
+
+	child, ok := t.children[parts[0]]
+	if !ok {
+		child = &tree{node: etcd.Node{Key: path.Join(t.node.Key, parts[0])}}
+		t.children[parts[0]] = child
+	}
+
+	if recursive {
+		child.insert(parts[1:], n, recursive)
+	}
+}
+
+// build returns the node of the tree with its children, ordered by key
+func (t *tree) build() etcd.Node {
+	n := t.node
+	for _, child := range t.children {
+		n.Nodes = append(n.Nodes, child.build())
+	}
+	sort.Sort(nodesByKey(n.Nodes))
+	return n
+}
+
+type nodesByKey etcd.Nodes
+
+func (nk nodesByKey) Len() int           { return len(nk) }
+func (nk nodesByKey) Swap(i, j int)      { nk[i], nk[j] = nk[j], nk[i] }
+func (nk nodesByKey) Less(i, j int) bool { return nk[i].Key < nk[j].Key }
@@ -0,0 +1,157 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func newTestMirror(t *testing.T) *mirror {
+	e := &testEtcdClient{
+		res: []*etcd.Result{
+			&etcd.Result{
+				Action: "get",
+				Index:  10,
+				Node: &etcd.Node{
+					Key: "/fleet/job",
+					Nodes: etcd.Nodes{
+						etcd.Node{
+							Key: "/fleet/job/foo.service",
+							Nodes: etcd.Nodes{
+								etcd.Node{Key: "/fleet/job/foo.service/target", Value: "XXX", ModifiedIndex: 7},
+								etcd.Node{Key: "/fleet/job/foo.service/object", Value: "{}", ModifiedIndex: 5},
+							},
+						},
+						etcd.Node{
+							Key: "/fleet/job/bar.service",
+							Nodes: etcd.Nodes{
+								etcd.Node{Key: "/fleet/job/bar.service/object", Value: "{}", ModifiedIndex: 3},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	m := newMirror("/fleet/job")
+	if err := m.load(e); err != nil {
+		t.Fatalf("Failed loading mirror: %v", err)
+	}
+	return m
+}
+
+func TestMirrorGet(t *testing.T) {
+	m := newTestMirror(t)
+
+	res, err, ok := m.get("/fleet/job", true)
+	if !ok || err != nil {
+		t.Fatalf("Recursive get failed: ok=%t err=%v", ok, err)
+	}
+	want := etcd.Node{
+		Key: "/fleet/job",
+		Nodes: etcd.Nodes{
+			etcd.Node{
+				Key: "/fleet/job/bar.service",
+				Nodes: etcd.Nodes{
+					etcd.Node{Key: "/fleet/job/bar.service/object", Value: "{}", ModifiedIndex: 3},
+				},
+			},
+			etcd.Node{
+				Key: "/fleet/job/foo.service",
+				Nodes: etcd.Nodes{
+					etcd.Node{Key: "/fleet/job/foo.service/object", Value: "{}", ModifiedIndex: 5},
+					etcd.Node{Key: "/fleet/job/foo.service/target", Value: "XXX", ModifiedIndex: 7},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(*res.Node, want) {
+		t.Errorf("Unexpected tree:\n%#v\nwant:\n%#v", *res.Node, want)
+	}
+	if res.Index != 10 {
+		t.Errorf("Result has index %d, want 10", res.Index)
+	}
+
+	res, _, _ = m.get("/fleet/job", false)
+	want = etcd.Node{
+		Key: "/fleet/job",
+		Nodes: etcd.Nodes{
+			etcd.Node{Key: "/fleet/job/bar.service"},
+			etcd.Node{Key: "/fleet/job/foo.service"},
+		},
+	}
+	if !reflect.DeepEqual(*res.Node, want) {
+		t.Errorf("Unexpected non-recursive tree:\n%#v\nwant:\n%#v", *res.Node, want)
+	}
+
+	res, _, _ = m.get("/fleet/job/foo.service/target", false)
+	if res.Node.Value != "XXX" {
+		t.Errorf("Unexpected leaf node: %v", res.Node)
+	}
+
+	_, err, ok = m.get("/fleet/job/baz.service", true)
+	if !ok || !isKeyNotFound(err) {
+		t.Errorf("Expected key not found, got ok=%t err=%v", ok, err)
+	}
+}
+
+func TestMirrorApply(t *testing.T) {
+	m := newTestMirror(t)
+
+	m.apply(&etcd.Result{Action: "set", Node: &etcd.Node{Key: "/fleet/job/baz.service/object", Value: "{}", ModifiedIndex: 11}})
+	m.apply(&etcd.Result{Action: "delete", Node: &etcd.Node{Key: "/fleet/job/foo.service", ModifiedIndex: 12}})
+	m.apply(&etcd.Result{Action: "expire", Node: &etcd.Node{Key: "/fleet/job/bar.service/object", ModifiedIndex: 13}})
+
+	if m.index != 13 {
+		t.Errorf("Mirror at index %d, want 13", m.index)
+	}
+
+	var keys []string
+	for k := range m.nodes {
+		keys = append(keys, k)
+	}
+	if want := []string{"/fleet/job/baz.service/object"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Mirror holds %v, want %v", keys, want)
+	}
+}
+
+func TestCachedClientReadYourWrites(t *testing.T) {
+	e := &testEtcdClient{
+		res: []*etcd.Result{
+			&etcd.Result{Action: "set", Node: &etcd.Node{Key: "/fleet/job/foo.service/target", Value: "YYY", ModifiedIndex: 20}},
+		},
+	}
+	cc := NewCachedClient(e, "/fleet/")
+	cc.mirrors[0] = newTestMirror(t)
+
+	if _, err := cc.Do(&etcd.Get{Key: "/fleet/job", Recursive: true}); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(e.gets) != 0 {
+		t.Fatalf("Get of a synced namespace reached etcd: %v", e.gets)
+	}
+
+	if _, err := cc.Do(&etcd.Set{Key: "/fleet/job/foo.service/target", Value: "YYY"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// until the mirror has seen the write, reads go to etcd
+	cc.Do(&etcd.Get{Key: "/fleet/job", Recursive: true})
+	if len(e.gets) != 1 {
+		t.Fatalf("Get before the mirror caught up was not passed through")
+	}
+
+	cc.mirrors[0].apply(&etcd.Result{Action: "set", Node: &etcd.Node{Key: "/fleet/job/foo.service/target", Value: "YYY", ModifiedIndex: 20}})
+	res, err := cc.Do(&etcd.Get{Key: "/fleet/job/foo.service/target"})
+	if err != nil || res.Node.Value != "YYY" || len(e.gets) != 1 {
+		t.Errorf("Expected write to be read from the mirror, got res=%v err=%v", res, err)
+	}
+
+	// other namespaces are always read from etcd
+	cc.Do(&etcd.Get{Key: "/fleet/machines", Recursive: true})
+	if len(e.gets) != 2 {
+		t.Errorf("Get of an uncached namespace was not passed through")
+	}
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"errors"
+	"path"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+const (
+	clusterConfigKey = "cluster-config"
+)
+
+// ErrClusterConfigChanged is returned when the ClusterConfig changed since
+// the version an update was based on
+var ErrClusterConfigChanged = errors.New("cluster configuration changed concurrently")
+
+// ClusterConfig holds options of fleet.conf set for the whole cluster,
+// which take precedence over the values configured on each machine. Its
+// Version counts the changes made to it, so that concurrent changes are
+// detected rather than overwritten.
+type ClusterConfig struct {
+	Version int
+	Values  map[string]string
+	Updated time.Time
+}
+
+// ClusterConfig returns the current ClusterConfig. It is empty, at
+// version 0, if no option was ever set.
+func (r *EtcdRegistry) ClusterConfig() (*ClusterConfig, error) {
+	cc, _, err := r.clusterConfig()
+	return cc, err
+}
+
+func (r *EtcdRegistry) clusterConfig() (*ClusterConfig, string, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, clusterConfigKey),
+	}
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			return &ClusterConfig{Values: map[string]string{}}, "", nil
+		}
+		return nil, "", err
+	}
+
+	var cc ClusterConfig
+	if err := unmarshal(res.Node.Value, &cc); err != nil {
+		return nil, "", err
+	}
+	if cc.Values == nil {
+		cc.Values = map[string]string{}
+	}
+	return &cc, res.Node.Value, nil
+}
+
+// UpdateClusterConfig replaces the options of the ClusterConfig with the
+// given ones as its next version, provided it is still at the given
+// version. Otherwise ErrClusterConfigChanged is returned.
+func (r *EtcdRegistry) UpdateClusterConfig(version int, values map[string]string) (*ClusterConfig, error) {
+	current, raw, err := r.clusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrClusterConfigChanged
+	}
+
+	cc := ClusterConfig{Version: version + 1, Values: values, Updated: time.Now()}
+	val, err := marshal(cc)
+	if err != nil {
+		return nil, err
+	}
+
+	key := path.Join(r.keyPrefix, clusterConfigKey)
+	if raw == "" {
+		_, err = r.etcd.Do(&etcd.Create{Key: key, Value: val})
+		if isNodeExist(err) {
+			err = ErrClusterConfigChanged
+		}
+	} else {
+		_, err = r.etcd.Do(&etcd.Set{Key: key, Value: val, PreviousValue: raw})
+		if isTestFailed(err) {
+			err = ErrClusterConfigChanged
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cc, nil
+}
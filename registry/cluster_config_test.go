@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestClusterConfig(t *testing.T) {
+	e := &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	cc, err := r.ClusterConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cc.Version != 0 || len(cc.Values) != 0 {
+		t.Errorf("Expected empty config, got %#v", cc)
+	}
+
+	res := etcd.Result{Node: &etcd.Node{Key: "/fleet/cluster-config", Value: `{"Version":3,"Values":{"cpu_overcommit":"1.5"}}`}}
+	e = &testEtcdClient{res: []*etcd.Result{&res}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	cc, err = r.ClusterConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cc.Version != 3 || !reflect.DeepEqual(cc.Values, map[string]string{"cpu_overcommit": "1.5"}) {
+		t.Errorf("Unexpected config: %#v", cc)
+	}
+}
+
+func TestUpdateClusterConfig(t *testing.T) {
+	raw := `{"Version":3,"Values":{"cpu_overcommit":"1.5"}}`
+	res := etcd.Result{Node: &etcd.Node{Key: "/fleet/cluster-config", Value: raw}}
+
+	// updates based on an older version are refused
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	if _, err := r.UpdateClusterConfig(2, map[string]string{}); err != ErrClusterConfigChanged {
+		t.Errorf("Expected ErrClusterConfigChanged, got %v", err)
+	}
+	if len(e.sets) != 0 {
+		t.Errorf("Unexpected sets: %#v", e.sets)
+	}
+
+	e = &testEtcdClient{res: []*etcd.Result{&res}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	cc, err := r.UpdateClusterConfig(3, map[string]string{"cpu_overcommit": "2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cc.Version != 4 {
+		t.Errorf("Expected version 4, got %d", cc.Version)
+	}
+	if len(e.sets) != 1 || e.sets[0].key != "/fleet/cluster-config" {
+		t.Errorf("Unexpected sets: %#v", e.sets)
+	}
+
+	// concurrent updates are detected by etcd
+	e = &testEtcdClient{res: []*etcd.Result{&res}, err: []error{nil, etcd.Error{ErrorCode: etcd.ErrorTestFailed}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if _, err := r.UpdateClusterConfig(3, map[string]string{}); err != ErrClusterConfigChanged {
+		t.Errorf("Expected ErrClusterConfigChanged, got %v", err)
+	}
+}
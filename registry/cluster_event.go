@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"path"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	eventPrefix = "event"
+
+	// eventTTL is how long recorded ClusterEvents are kept
+	eventTTL = time.Hour
+
+	// A Unit was scheduled to a machine
+	EventUnitScheduled = "UnitScheduled"
+	// A Unit was unscheduled from a machine
+	EventUnitUnscheduled = "UnitUnscheduled"
+	// A Unit was unscheduled to make room for a higher-priority Unit
+	EventUnitPreempted = "UnitPreempted"
+	// A Unit was unscheduled from a machine it failed on persistently
+	EventUnitFailed = "UnitFailed"
+	// A Unit was unscheduled from a machine that went away, to be
+	// rescheduled elsewhere
+	EventUnitRescheduled = "UnitRescheduled"
+	// The engine gave up on scheduling a Unit past its ScheduleDeadline
+	EventUnitScheduleFailed = "UnitScheduleFailed"
+	// The engine destroyed a Unit active for longer than its TTL
+	EventUnitExpired = "UnitExpired"
+	// The engine held back the rescheduling of a Unit moved too often
+	EventUnitBackoff = "UnitBackoff"
+	// The engine relaxed the constraints of a Unit displaced from a
+	// machine that went away to reschedule it
+	EventUnitPlacementRelaxed = "UnitPlacementRelaxed"
+	// A Unit used more resources than it reserved for a sustained period
+	EventUnitOverReservation = "UnitOverReservation"
+	// The systemd state of a Unit on a machine changed
+	EventUnitStateChanged = "UnitStateChanged"
+	// A machine joined the cluster
+	EventMachineJoined = "MachineJoined"
+	// A machine left the cluster
+	EventMachineLeft = "MachineLeft"
+)
+
+var eventTypes = map[string]bool{
+	EventUnitScheduled:        true,
+	EventUnitUnscheduled:      true,
+	EventUnitPreempted:        true,
+	EventUnitFailed:           true,
+	EventUnitRescheduled:      true,
+	EventUnitScheduleFailed:   true,
+	EventUnitExpired:          true,
+	EventUnitBackoff:          true,
+	EventUnitPlacementRelaxed: true,
+	EventUnitOverReservation:  true,
+	EventUnitStateChanged:     true,
+	EventMachineJoined:        true,
+	EventMachineLeft:          true,
+}
+
+// IsEventType determines whether the given string is the type of a
+// ClusterEvent
+func IsEventType(typ string) bool {
+	return eventTypes[typ]
+}
+
+// ClusterEvent is a notable change in the cluster, e.g. a scheduling
+// decision. Index orders ClusterEvents and is assigned when they are
+// recorded.
+type ClusterEvent struct {
+	Index     uint64 `json:"-"`
+	Time      time.Time
+	Type      string
+	UnitName  string `json:",omitempty"`
+	MachineID string `json:",omitempty"`
+	Reason    string `json:",omitempty"`
+}
+
+// RecordEvent adds the given ClusterEvent to the cluster's event log. Events
+// are dropped from the log once they are older than an hour. Events
+// concerning a Unit are also added to its history.
+func (r *EtcdRegistry) RecordEvent(ev ClusterEvent) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	val, err := marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.CreateInOrder{
+		Dir:   path.Join(r.keyPrefix, eventPrefix),
+		Value: val,
+		TTL:   eventTTL,
+	}
+	if _, err := r.etcd.Do(&req); err != nil {
+		return err
+	}
+
+	if ev.UnitName == "" {
+		return nil
+	}
+	return r.recordUnitHistory(ev)
+}
+
+// Events returns the ClusterEvents recorded after the given index, oldest
+// first
+func (r *EtcdRegistry) Events(since uint64) ([]ClusterEvent, error) {
+	req := etcd.Get{
+		Key:       path.Join(r.keyPrefix, eventPrefix),
+		Sorted:    true,
+		Recursive: true,
+	}
+
+	var events []ClusterEvent
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return events, err
+	}
+
+	for _, node := range res.Node.Nodes {
+		if node.CreatedIndex <= since {
+			continue
+		}
+		ev, err := nodeToClusterEvent(node)
+		if err != nil {
+			log.Errorf("Failed parsing ClusterEvent from %s: %v", node.Key, err)
+			continue
+		}
+		events = append(events, *ev)
+	}
+
+	return events, nil
+}
+
+// WaitForEvents returns the ClusterEvents recorded after the given index.
+// If there are none, it blocks until the next ClusterEvent is recorded or
+// stop is closed, in which case no ClusterEvents are returned.
+func (r *EtcdRegistry) WaitForEvents(since uint64, stop <-chan struct{}) ([]ClusterEvent, error) {
+	events, err := r.Events(since)
+	if err != nil || len(events) > 0 {
+		return events, err
+	}
+
+	waitIndex := since + 1
+	for {
+		req := etcd.Watch{
+			Key:       path.Join(r.keyPrefix, eventPrefix),
+			Recursive: true,
+			WaitIndex: waitIndex,
+		}
+
+		res, err := r.etcd.Wait(&req, stop)
+		if err != nil {
+			// Events older than the watch history of etcd are
+			// known to no longer exist; wait for new ones instead
+			if e, ok := err.(etcd.Error); ok && e.ErrorCode == etcd.ErrorEventIndexCleared {
+				waitIndex = e.Index + 1
+				continue
+			}
+
+			select {
+			case <-stop:
+				return nil, nil
+			default:
+			}
+			return nil, err
+		}
+
+		if res == nil || res.Node == nil {
+			return nil, nil
+		}
+
+		// expiring events are of no interest
+		if res.Action != "create" {
+			waitIndex = res.Node.ModifiedIndex + 1
+			continue
+		}
+
+		ev, err := nodeToClusterEvent(*res.Node)
+		if err != nil {
+			return nil, err
+		}
+		return []ClusterEvent{*ev}, nil
+	}
+}
+
+func nodeToClusterEvent(node etcd.Node) (*ClusterEvent, error) {
+	var ev ClusterEvent
+	if err := unmarshal(node.Value, &ev); err != nil {
+		return nil, err
+	}
+	ev.Index = node.CreatedIndex
+	return &ev, nil
+}
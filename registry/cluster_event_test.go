@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestEvents(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/event",
+			Nodes: []etcd.Node{
+				etcd.Node{
+					Key:          "/fleet/event/00000000000000000011",
+					Value:        `{"Time":"2014-10-01T12:00:00Z","Type":"UnitScheduled","UnitName":"foo.service","MachineID":"XXX"}`,
+					CreatedIndex: 11,
+				},
+				etcd.Node{
+					Key:          "/fleet/event/00000000000000000012",
+					Value:        `{"Time":"2014-10-01T12:00:01Z","Type":"MachineLeft","MachineID":"YYY"}`,
+					CreatedIndex: 12,
+				},
+				etcd.Node{
+					Key:          "/fleet/event/00000000000000000013",
+					Value:        `garbage`,
+					CreatedIndex: 13,
+				},
+			},
+		},
+	}
+
+	for i, tt := range []struct {
+		since uint64
+		want  []ClusterEvent
+	}{
+		{
+			0,
+			[]ClusterEvent{
+				ClusterEvent{Index: 11, Time: time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC), Type: EventUnitScheduled, UnitName: "foo.service", MachineID: "XXX"},
+				ClusterEvent{Index: 12, Time: time.Date(2014, 10, 1, 12, 0, 1, 0, time.UTC), Type: EventMachineLeft, MachineID: "YYY"},
+			},
+		},
+		{
+			11,
+			[]ClusterEvent{
+				ClusterEvent{Index: 12, Time: time.Date(2014, 10, 1, 12, 0, 1, 0, time.UTC), Type: EventMachineLeft, MachineID: "YYY"},
+			},
+		},
+		{12, nil},
+	} {
+		e := &testEtcdClient{res: []*etcd.Result{&res}}
+		r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+		got, err := r.Events(tt.since)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: unexpected events:\ngot\n%#v\nwant\n%#v", i, got, tt.want)
+		}
+	}
+}
+
+func TestEventsEmpty(t *testing.T) {
+	e := &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.Events(0)
+	if err != nil || len(got) != 0 {
+		t.Errorf("Expected no events and no error, got %v, %v", got, err)
+	}
+}
+
+func TestWaitForEvents(t *testing.T) {
+	e := &testEtcdClient{
+		res: []*etcd.Result{
+			// no events recorded yet
+			nil,
+			// a watch on an event index that was cleared
+			nil,
+			// an event expired
+			&etcd.Result{Action: "expire", Node: &etcd.Node{Key: "/fleet/event/00000000000000000003", ModifiedIndex: 40}},
+			&etcd.Result{Action: "create", Node: &etcd.Node{
+				Key:          "/fleet/event/00000000000000000041",
+				Value:        `{"Time":"2014-10-01T12:00:00Z","Type":"MachineJoined","MachineID":"XXX"}`,
+				CreatedIndex: 41,
+			}},
+		},
+		err: []error{
+			etcd.Error{ErrorCode: etcd.ErrorKeyNotFound},
+			etcd.Error{ErrorCode: etcd.ErrorEventIndexCleared, Index: 30},
+		},
+	}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.WaitForEvents(5, make(chan struct{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []ClusterEvent{
+		ClusterEvent{Index: 41, Time: time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC), Type: EventMachineJoined, MachineID: "XXX"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected events:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+}
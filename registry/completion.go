@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"path"
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	completionKey = "completion"
+
+	// result systemd records for units that exited successfully
+	completionResultSuccess = "success"
+)
+
+// UnitCompletion records how the last run of a batch Unit ended. It is
+// kept alongside the Unit, and destroyed with it.
+type UnitCompletion struct {
+	Name      string
+	MachineID string
+	// Result is the result systemd recorded for the run, e.g. "success"
+	// or "exit-code"
+	Result   string
+	ExitCode int
+	Started  time.Time
+	Finished time.Time
+}
+
+// Succeeded determines whether the run completed successfully
+func (uc *UnitCompletion) Succeeded() bool {
+	return uc.Result == completionResultSuccess
+}
+
+// Duration returns how long the run took, or zero if unknown
+func (uc *UnitCompletion) Duration() time.Duration {
+	if uc.Started.IsZero() || uc.Finished.Before(uc.Started) {
+		return 0
+	}
+	return uc.Finished.Sub(uc.Started)
+}
+
+// SaveUnitCompletion records how the last run of a batch Unit ended,
+// replacing any earlier record
+func (r *EtcdRegistry) SaveUnitCompletion(uc UnitCompletion) error {
+	val, err := marshal(uc)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, jobPrefix, uc.Name, completionKey),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// UnitCompletions returns how the last runs of all batch Units that have
+// completed ended, ordered by when they finished
+func (r *EtcdRegistry) UnitCompletions() ([]UnitCompletion, error) {
+	req := etcd.Get{
+		Key:       path.Join(r.keyPrefix, jobPrefix),
+		Recursive: true,
+	}
+
+	var completions []UnitCompletion
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return completions, err
+	}
+
+	for _, dir := range res.Node.Nodes {
+		val := getValueInDir(&dir, completionKey)
+		if val == "" {
+			continue
+		}
+		var uc UnitCompletion
+		if err := unmarshal(val, &uc); err != nil {
+			log.Errorf("Ignoring invalid completion of Unit(%s): %v", path.Base(dir.Key), err)
+			continue
+		}
+		completions = append(completions, uc)
+	}
+	sort.Sort(completionsByFinish(completions))
+
+	return completions, nil
+}
+
+type completionsByFinish []UnitCompletion
+
+func (s completionsByFinish) Len() int      { return len(s) }
+func (s completionsByFinish) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s completionsByFinish) Less(i, j int) bool {
+	if !s[i].Finished.Equal(s[j].Finished) {
+		return s[i].Finished.Before(s[j].Finished)
+	}
+	return s[i].Name < s[j].Name
+}
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestUnitCompletions(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/job",
+			Nodes: etcd.Nodes{
+				etcd.Node{
+					Key: "/fleet/job/report.service",
+					Nodes: etcd.Nodes{
+						etcd.Node{Key: "/fleet/job/report.service/object", Value: `{}`},
+						etcd.Node{Key: "/fleet/job/report.service/completion", Value: `{"Name":"report.service","MachineID":"XXX","Result":"exit-code","ExitCode":2,"Started":"2015-03-02T10:00:00Z","Finished":"2015-03-02T10:05:00Z"}`},
+					},
+				},
+				etcd.Node{
+					Key: "/fleet/job/web.service",
+					Nodes: etcd.Nodes{
+						etcd.Node{Key: "/fleet/job/web.service/object", Value: `{}`},
+					},
+				},
+				etcd.Node{
+					Key: "/fleet/job/import.service",
+					Nodes: etcd.Nodes{
+						etcd.Node{Key: "/fleet/job/import.service/completion", Value: `{"Name":"import.service","MachineID":"YYY","Result":"success","Started":"2015-03-02T09:00:00Z","Finished":"2015-03-02T09:01:00Z"}`},
+					},
+				},
+				etcd.Node{
+					Key: "/fleet/job/bogus.service",
+					Nodes: etcd.Nodes{
+						etcd.Node{Key: "/fleet/job/bogus.service/completion", Value: `{`},
+					},
+				},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.UnitCompletions()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2015, time.March, 2, hour, min, 0, 0, time.UTC)
+	}
+	want := []UnitCompletion{
+		UnitCompletion{Name: "import.service", MachineID: "YYY", Result: "success", Started: at(9, 0), Finished: at(9, 1)},
+		UnitCompletion{Name: "report.service", MachineID: "XXX", Result: "exit-code", ExitCode: 2, Started: at(10, 0), Finished: at(10, 5)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected completions:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	if !got[0].Succeeded() || got[1].Succeeded() {
+		t.Errorf("Unexpected success of completions %v", got)
+	}
+	if d := got[1].Duration(); d != 5*time.Minute {
+		t.Errorf("Unexpected duration %v", d)
+	}
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	configPrefix = "config"
+
+	// size in bytes of the AES-256 cluster key secret values are
+	// encrypted with
+	clusterKeySize = 32
+)
+
+// ConfigValue is a value stored in a namespace of the Registry, passed to
+// Units as an environment variable. A Secret value is encrypted with the
+// cluster key, which the Registry never sees.
+type ConfigValue struct {
+	Value  string
+	Secret bool
+}
+
+// SetConfigValue stores the given value under a key of the named namespace
+func (r *EtcdRegistry) SetConfigValue(namespace, key string, cv ConfigValue) error {
+	val, err := marshal(cv)
+	if err != nil {
+		return err
+	}
+	val, err = r.seal(val)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, configPrefix, namespace, key),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// DeleteConfigValue removes a key of the named namespace
+func (r *EtcdRegistry) DeleteConfigValue(namespace, key string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, configPrefix, namespace, key),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// ConfigValues returns the values stored in the named namespace, indexed by
+// key. Values of namespaces nested in it are not included.
+func (r *EtcdRegistry) ConfigValues(namespace string) (map[string]ConfigValue, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, configPrefix, namespace),
+	}
+
+	values := make(map[string]ConfigValue)
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return values, err
+	}
+
+	for _, node := range res.Node.Nodes {
+		// nested namespaces are directories, which hold no value
+		if node.Value == "" {
+			continue
+		}
+		val, err := r.open(node.Value)
+		if err != nil {
+			log.Errorf("Failed decrypting ConfigValue from %s: %v", node.Key, err)
+			continue
+		}
+		var cv ConfigValue
+		if err := unmarshal(val, &cv); err != nil {
+			log.Errorf("Failed parsing ConfigValue from %s: %v", node.Key, err)
+			continue
+		}
+		values[path.Base(node.Key)] = cv
+	}
+	return values, nil
+}
+
+// ReadClusterKey reads the cluster key secret values are encrypted with from
+// the given file, which holds 32 random bytes hex-encoded, as generated
+// with `openssl rand -hex 32`
+func ReadClusterKey(file string) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != clusterKeySize {
+		return nil, fmt.Errorf("cluster key in %s must be %d hex-encoded bytes", file, clusterKeySize)
+	}
+	return key, nil
+}
+
+// EncryptConfigValue returns a Secret ConfigValue holding the given value
+// encrypted with the cluster key
+func EncryptConfigValue(key []byte, value string) (ConfigValue, error) {
+	gcm, err := newClusterCipher(key)
+	if err != nil {
+		return ConfigValue{}, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return ConfigValue{}, err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return ConfigValue{Value: base64.StdEncoding.EncodeToString(sealed), Secret: true}, nil
+}
+
+// Plaintext returns the value, decrypting a Secret value with the given
+// cluster key
+func (cv ConfigValue) Plaintext(key []byte) (string, error) {
+	if !cv.Secret {
+		return cv.Value, nil
+	}
+	if key == nil {
+		return "", errors.New("no cluster key to decrypt secret value with")
+	}
+
+	gcm, err := newClusterCipher(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(cv.Value)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("malformed secret value")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("unable to decrypt secret value with cluster key")
+	}
+	return string(plain), nil
+}
+
+func newClusterCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != clusterKeySize {
+		return nil, fmt.Errorf("cluster key must be %d bytes", clusterKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	r.SetConfigValue("myapp/prod", "DB_URL", ConfigValue{Value: "db:5432"})
+
+	want := []action{action{key: "/fleet/config/myapp/prod/DB_URL", val: `{"Value":"db:5432","Secret":false}`}}
+	if !reflect.DeepEqual(e.sets, want) {
+		t.Errorf("Unexpected sets:\ngot\n%#v\nwant\n%#v", e.sets, want)
+	}
+}
+
+func TestConfigValues(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/config/myapp",
+			Nodes: []etcd.Node{
+				etcd.Node{Key: "/fleet/config/myapp/DB_URL", Value: `{"Value":"db:5432"}`},
+				etcd.Node{Key: "/fleet/config/myapp/TOKEN", Value: `{"Value":"c2VjcmV0","Secret":true}`},
+				etcd.Node{Key: "/fleet/config/myapp/BROKEN", Value: "{"},
+				// a nested namespace
+				etcd.Node{Key: "/fleet/config/myapp/prod"},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	values, err := r.ConfigValues("myapp")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]ConfigValue{
+		"DB_URL": ConfigValue{Value: "db:5432"},
+		"TOKEN":  ConfigValue{Value: "c2VjcmV0", Secret: true},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Unexpected values:\ngot\n%#v\nwant\n%#v", values, want)
+	}
+}
+
+func TestConfigValueEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, clusterKeySize)
+
+	cv, err := EncryptConfigValue(key, "hunter2")
+	if err != nil {
+		t.Fatalf("Unexpected error encrypting: %v", err)
+	}
+	if !cv.Secret || cv.Value == "hunter2" {
+		t.Fatalf("Value not encrypted: %#v", cv)
+	}
+
+	if got, err := cv.Plaintext(key); err != nil || got != "hunter2" {
+		t.Errorf("Expected hunter2, got %q (err=%v)", got, err)
+	}
+	if _, err := cv.Plaintext(nil); err == nil {
+		t.Error("Expected error decrypting without cluster key")
+	}
+	if _, err := cv.Plaintext(bytes.Repeat([]byte{8}, clusterKeySize)); err == nil {
+		t.Error("Expected error decrypting with another key")
+	}
+	if _, err := EncryptConfigValue([]byte("short"), "hunter2"); err == nil {
+		t.Error("Expected error encrypting with a key of the wrong size")
+	}
+
+	plain := ConfigValue{Value: "db:5432"}
+	if got, err := plain.Plaintext(nil); err != nil || got != "db:5432" {
+		t.Errorf("Expected plain values to be returned as is, got %q (err=%v)", got, err)
+	}
+}
+
+func TestReadClusterKey(t *testing.T) {
+	f, err := ioutil.TempFile("", "fleet-cluster-key")
+	if err != nil {
+		t.Fatalf("Failed creating temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	for i, tt := range []struct {
+		contents string
+		ok       bool
+	}{
+		{"0707070707070707070707070707070707070707070707070707070707070707\n", true},
+		{"0707", false},
+		{"not hex", false},
+	} {
+		if err := ioutil.WriteFile(f.Name(), []byte(tt.contents), 0600); err != nil {
+			t.Fatalf("Failed writing key: %v", err)
+		}
+		key, err := ReadClusterKey(f.Name())
+		if tt.ok != (err == nil) {
+			t.Errorf("case %d: unexpected error %v", i, err)
+		}
+		if tt.ok && !bytes.Equal(key, bytes.Repeat([]byte{7}, clusterKeySize)) {
+			t.Errorf("case %d: unexpected key %x", i, key)
+		}
+	}
+}
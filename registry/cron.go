@@ -0,0 +1,178 @@
+package registry
+
+import (
+	"path"
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	cronRunPrefix    = "cron"
+	cronResultPrefix = "cron-result"
+
+	// CronRunSucceeded is the Result of a run whose Unit exited cleanly
+	CronRunSucceeded = "succeeded"
+	// CronRunFailed is the Result of a run whose Unit failed
+	CronRunFailed = "failed"
+	// CronRunSkipped is the Result of a tick that started no run, as an
+	// earlier run was still going and the ConcurrencyPolicy forbids
+	// overlapping runs
+	CronRunSkipped = "skipped"
+	// CronRunReplaced is the Result of a run stopped in favour of the run of
+	// a later tick
+	CronRunReplaced = "replaced"
+	// CronRunLost is the Result of a run whose Unit disappeared before any
+	// agent reported its result
+	CronRunLost = "lost"
+)
+
+// CronRun records a run of a template Unit with a Schedule, started by the
+// engine as an instance of the template for one tick of the Schedule. The
+// Result is empty while the run is going.
+type CronRun struct {
+	Name      string
+	Template  string
+	Tick      time.Time
+	MachineID string
+	Finished  time.Time
+	Result    string
+}
+
+// CronRunResult is the outcome of a run reported by the agent that ran it
+type CronRunResult struct {
+	Name      string
+	MachineID string
+	Finished  time.Time
+	Result    string
+}
+
+// SaveCronRun records the given run in the run history of its template
+func (r *EtcdRegistry) SaveCronRun(run CronRun) error {
+	val, err := marshal(run)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, cronRunPrefix, run.Template, run.Name),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// RemoveCronRun drops the named run from the run history of the given
+// template
+func (r *EtcdRegistry) RemoveCronRun(tmpl, name string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, cronRunPrefix, tmpl, name),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// CronRuns returns the run history of all templates, ordered by template
+// and then by tick
+func (r *EtcdRegistry) CronRuns() ([]CronRun, error) {
+	req := etcd.Get{
+		Key:       path.Join(r.keyPrefix, cronRunPrefix),
+		Recursive: true,
+	}
+
+	var runs []CronRun
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return runs, err
+	}
+
+	for _, dir := range res.Node.Nodes {
+		for _, node := range dir.Nodes {
+			var run CronRun
+			if err := unmarshal(node.Value, &run); err != nil {
+				log.Errorf("Ignoring invalid run %s of template Unit(%s): %v", path.Base(node.Key), path.Base(dir.Key), err)
+				continue
+			}
+			runs = append(runs, run)
+		}
+	}
+	sort.Sort(cronRunsByTick(runs))
+
+	return runs, nil
+}
+
+// ReportCronRunResult records the outcome of a run for the engine to add to
+// the run history
+func (r *EtcdRegistry) ReportCronRunResult(res CronRunResult) error {
+	val, err := marshal(res)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, cronResultPrefix, res.Name),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// CronRunResults returns the reported outcomes of runs not yet added to the
+// run history
+func (r *EtcdRegistry) CronRunResults() ([]CronRunResult, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, cronResultPrefix),
+	}
+
+	var results []CronRunResult
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return results, err
+	}
+
+	for _, node := range res.Node.Nodes {
+		var cr CronRunResult
+		if err := unmarshal(node.Value, &cr); err != nil {
+			log.Errorf("Ignoring invalid result of run %s: %v", path.Base(node.Key), err)
+			continue
+		}
+		results = append(results, cr)
+	}
+
+	return results, nil
+}
+
+// ClearCronRunResult drops the reported outcome of the named run
+func (r *EtcdRegistry) ClearCronRunResult(name string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, cronResultPrefix, name),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// cronRunsByTick orders runs by template and then by tick
+type cronRunsByTick []CronRun
+
+func (s cronRunsByTick) Len() int      { return len(s) }
+func (s cronRunsByTick) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s cronRunsByTick) Less(i, j int) bool {
+	if s[i].Template != s[j].Template {
+		return s[i].Template < s[j].Template
+	}
+	return s[i].Tick.Before(s[j].Tick)
+}
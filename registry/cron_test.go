@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestCronRuns(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/cron",
+			Nodes: etcd.Nodes{
+				etcd.Node{
+					Key: "/fleet/cron/sync@.service",
+					Nodes: etcd.Nodes{
+						etcd.Node{Key: "/fleet/cron/sync@.service/sync@60.service", Value: `{"Name":"sync@60.service","Template":"sync@.service","Tick":"1970-01-01T00:01:00Z"}`},
+					},
+				},
+				etcd.Node{
+					Key: "/fleet/cron/backup@.service",
+					Nodes: etcd.Nodes{
+						etcd.Node{Key: "/fleet/cron/backup@.service/backup@120.service", Value: `{"Name":"backup@120.service","Template":"backup@.service","Tick":"1970-01-01T00:02:00Z"}`},
+						etcd.Node{Key: "/fleet/cron/backup@.service/bogus", Value: `{`},
+						etcd.Node{Key: "/fleet/cron/backup@.service/backup@60.service", Value: `{"Name":"backup@60.service","Template":"backup@.service","Tick":"1970-01-01T00:01:00Z","MachineID":"XXX","Finished":"1970-01-01T00:01:30Z","Result":"succeeded"}`},
+					},
+				},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.CronRuns()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	at := func(sec int64) time.Time { return time.Unix(sec, 0).UTC() }
+	want := []CronRun{
+		CronRun{Name: "backup@60.service", Template: "backup@.service", Tick: at(60), MachineID: "XXX", Finished: at(90), Result: CronRunSucceeded},
+		CronRun{Name: "backup@120.service", Template: "backup@.service", Tick: at(120)},
+		CronRun{Name: "sync@60.service", Template: "sync@.service", Tick: at(60)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected runs:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	e = &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if got, err := r.CronRuns(); len(got) != 0 || err != nil {
+		t.Errorf("Expected no runs, got %v, err %v", got, err)
+	}
+}
@@ -0,0 +1,254 @@
+package registry
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+// A Keyring holds the keys the Registry encrypts unit files and
+// configuration values with at rest. Values are encrypted with the first
+// key, and may be decrypted with any of them, so that a new key can be
+// introduced ahead of the one it replaces.
+type Keyring struct {
+	keys [][]byte
+}
+
+// NewKeyring returns a Keyring encrypting with the first of the given keys,
+// each of which must be 32 bytes
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no registry encryption keys")
+	}
+	for _, key := range keys {
+		if len(key) != clusterKeySize {
+			return nil, fmt.Errorf("registry encryption keys must be %d bytes", clusterKeySize)
+		}
+	}
+	return &Keyring{keys: keys}, nil
+}
+
+// ReadKeyring reads the keys of a Keyring from the given files, in the
+// format of ReadClusterKey
+func ReadKeyring(files []string) (*Keyring, error) {
+	keys := make([][]byte, len(files))
+	for i, file := range files {
+		key, err := ReadClusterKey(file)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return NewKeyring(keys...)
+}
+
+// keyID identifies a key in the values encrypted with it, without revealing
+// it
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// sealedModel is an envelope-encrypted value. The value is encrypted with a
+// random data key, itself encrypted with the identified key of the Keyring.
+type sealedModel struct {
+	KeyID   string
+	DataKey string
+	Data    string
+}
+
+// sealedValueModel is how sealed values are stored, telling them apart from
+// the plain JSON objects stored otherwise
+type sealedValueModel struct {
+	Sealed *sealedModel
+}
+
+func (kr *Keyring) seal(plain string) (string, error) {
+	dataKey := make([]byte, clusterKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	data, err := sealWith(dataKey, []byte(plain))
+	if err != nil {
+		return "", err
+	}
+	sealedKey, err := sealWith(kr.keys[0], dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return marshal(sealedValueModel{&sealedModel{
+		KeyID:   keyID(kr.keys[0]),
+		DataKey: base64.StdEncoding.EncodeToString(sealedKey),
+		Data:    base64.StdEncoding.EncodeToString(data),
+	}})
+}
+
+func (kr *Keyring) open(sm *sealedModel) (string, error) {
+	var key []byte
+	for _, k := range kr.keys {
+		if keyID(k) == sm.KeyID {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return "", fmt.Errorf("value encrypted with unknown registry key %s", sm.KeyID)
+	}
+
+	sealedKey, err := base64.StdEncoding.DecodeString(sm.DataKey)
+	if err != nil {
+		return "", errors.New("malformed data key")
+	}
+	dataKey, err := openWith(key, sealedKey)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sm.Data)
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := openWith(dataKey, data)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func sealWith(key, plain []byte) ([]byte, error) {
+	gcm, err := newClusterCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func openWith(key, sealed []byte) ([]byte, error) {
+	gcm, err := newClusterCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("malformed encrypted value")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("unable to decrypt value with registry key")
+	}
+	return plain, nil
+}
+
+// SetKeyring makes the Registry encrypt the unit files and configuration
+// values it stores with the given Keyring. Values stored unencrypted
+// remain readable.
+func (r *EtcdRegistry) SetKeyring(kr *Keyring) {
+	r.keyring = kr
+}
+
+// seal encrypts the given value for storage, if the Registry has a Keyring
+func (r *EtcdRegistry) seal(val string) (string, error) {
+	if r.keyring == nil {
+		return val, nil
+	}
+	return r.keyring.seal(val)
+}
+
+// open decrypts the given stored value, if it is encrypted
+func (r *EtcdRegistry) open(val string) (string, error) {
+	sm := sealedOf(val)
+	if sm == nil {
+		return val, nil
+	}
+	if r.keyring == nil {
+		return "", errors.New("value encrypted at rest, but no registry encryption keys configured")
+	}
+	return r.keyring.open(sm)
+}
+
+// sealedOf returns the envelope of the given stored value, or nil if it is
+// not encrypted
+func sealedOf(val string) *sealedModel {
+	var svm sealedValueModel
+	if unmarshal(val, &svm) != nil {
+		return nil
+	}
+	return svm.Sealed
+}
+
+// Reencrypt encrypts all unit files and configuration values not yet
+// encrypted with the first key of the Registry's Keyring with it, and
+// returns how many it encrypted. Values the Keyring cannot decrypt are left
+// alone. Values are only replaced if they did not change in the meantime.
+func (r *EtcdRegistry) Reencrypt() (int, error) {
+	if r.keyring == nil {
+		return 0, nil
+	}
+	active := keyID(r.keyring.keys[0])
+
+	var n int
+	for _, prefix := range []string{unitPrefix, configPrefix} {
+		req := etcd.Get{
+			Key:       path.Join(r.keyPrefix, prefix),
+			Recursive: true,
+		}
+		res, err := r.etcd.Do(&req)
+		if err != nil {
+			if isKeyNotFound(err) {
+				continue
+			}
+			return n, err
+		}
+
+		var walk func(nodes etcd.Nodes) error
+		walk = func(nodes etcd.Nodes) error {
+			for _, node := range nodes {
+				if len(node.Nodes) > 0 || node.Value == "" {
+					if err := walk(node.Nodes); err != nil {
+						return err
+					}
+					continue
+				}
+				if sm := sealedOf(node.Value); sm != nil && sm.KeyID == active {
+					continue
+				}
+
+				plain, err := r.open(node.Value)
+				if err != nil {
+					log.Errorf("Unable to re-encrypt %s: %v", node.Key, err)
+					continue
+				}
+				val, err := r.keyring.seal(plain)
+				if err != nil {
+					return err
+				}
+				set := etcd.Set{
+					Key:           node.Key,
+					Value:         val,
+					PreviousIndex: node.ModifiedIndex,
+				}
+				if _, err := r.etcd.Do(&set); err != nil {
+					log.Errorf("Failed re-encrypting %s: %v", node.Key, err)
+					continue
+				}
+				n++
+			}
+			return nil
+		}
+		if err := walk(res.Node.Nodes); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,173 @@
+package registry
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, clusterKeySize)
+	newKey := bytes.Repeat([]byte{2}, clusterKeySize)
+
+	oldKr, _ := NewKeyring(oldKey)
+	sealed, err := oldKr.seal(`{"Raw":"[Service]\nExecStart=/bin/login hunter2"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error sealing: %v", err)
+	}
+	if strings.Contains(sealed, "hunter2") {
+		t.Fatalf("Value not encrypted: %s", sealed)
+	}
+
+	// the old key keeps decrypting once a new key is introduced ahead of it
+	r := &EtcdRegistry{nil, "/fleet", nil, nil}
+	kr, _ := NewKeyring(newKey, oldKey)
+	r.SetKeyring(kr)
+	if got, err := r.open(sealed); err != nil || got != `{"Raw":"[Service]\nExecStart=/bin/login hunter2"}` {
+		t.Errorf("Unexpected value %q (err=%v)", got, err)
+	}
+
+	resealed, _ := r.seal("value")
+	if sm := sealedOf(resealed); sm == nil || sm.KeyID != keyID(newKey) {
+		t.Errorf("Expected value sealed with new key, got %s", resealed)
+	}
+
+	// a removed key no longer decrypts
+	kr, _ = NewKeyring(newKey)
+	r.SetKeyring(kr)
+	if _, err := r.open(sealed); err == nil {
+		t.Error("Expected error opening value sealed with removed key")
+	}
+
+	// plain values remain readable, sealed ones need a key
+	if got, err := r.open(`{"Value":"db:5432"}`); err != nil || got != `{"Value":"db:5432"}` {
+		t.Errorf("Unexpected plain value %q (err=%v)", got, err)
+	}
+	r.SetKeyring(nil)
+	if _, err := r.open(resealed); err == nil {
+		t.Error("Expected error opening sealed value without keyring")
+	}
+
+	if _, err := NewKeyring(); err == nil {
+		t.Error("Expected error creating empty keyring")
+	}
+	if _, err := NewKeyring([]byte("short")); err == nil {
+		t.Error("Expected error creating keyring with a key of the wrong size")
+	}
+}
+
+func TestEncryptedUnitFile(t *testing.T) {
+	kr, _ := NewKeyring(bytes.Repeat([]byte{1}, clusterKeySize))
+	r := &EtcdRegistry{nil, "/fleet", nil, nil}
+	r.SetKeyring(kr)
+
+	uf, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/login hunter2")
+	raw, _ := marshal(unitModel{Raw: uf.String()})
+	sealed, err := r.seal(raw)
+	if err != nil {
+		t.Fatalf("Unexpected error sealing: %v", err)
+	}
+
+	res := etcd.Result{Node: &etcd.Node{Key: r.hashedUnitPath(uf.Hash()), Value: sealed}}
+	r.etcd = &testEtcdClient{res: []*etcd.Result{&res}}
+	got := r.getUnitByHash(uf.Hash())
+	if got == nil || got.Hash() != uf.Hash() {
+		t.Errorf("Unexpected unit file: %v", got)
+	}
+}
+
+func TestEncryptedConfigValues(t *testing.T) {
+	kr, _ := NewKeyring(bytes.Repeat([]byte{1}, clusterKeySize))
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	r.SetKeyring(kr)
+
+	if err := r.SetConfigValue("myapp", "DB_URL", ConfigValue{Value: "db:5432"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(e.sets) != 1 || strings.Contains(e.sets[0].val, "db:5432") {
+		t.Fatalf("Value not encrypted: %#v", e.sets)
+	}
+
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/config/myapp",
+			Nodes: []etcd.Node{
+				etcd.Node{Key: "/fleet/config/myapp/DB_URL", Value: e.sets[0].val},
+				etcd.Node{Key: "/fleet/config/myapp/PLAIN", Value: `{"Value":"x"}`},
+			},
+		},
+	}
+	r.etcd = &testEtcdClient{res: []*etcd.Result{&res}}
+	values, err := r.ConfigValues("myapp")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if values["DB_URL"].Value != "db:5432" || values["PLAIN"].Value != "x" {
+		t.Errorf("Unexpected values: %#v", values)
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, clusterKeySize)
+	newKey := bytes.Repeat([]byte{2}, clusterKeySize)
+	oldKr, _ := NewKeyring(oldKey)
+	oldSealed, _ := oldKr.seal(`{"Raw":"old"}`)
+	newKr, _ := NewKeyring(newKey)
+	newSealed, _ := newKr.seal(`{"Raw":"new"}`)
+
+	units := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/unit",
+			Nodes: []etcd.Node{
+				etcd.Node{Key: "/fleet/unit/aaa", Value: oldSealed},
+				etcd.Node{Key: "/fleet/unit/bbb", Value: newSealed},
+				etcd.Node{Key: "/fleet/unit/ccc", Value: `{"Raw":"plain"}`},
+			},
+		},
+	}
+	config := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/config",
+			Nodes: []etcd.Node{
+				etcd.Node{Key: "/fleet/config/myapp", Nodes: []etcd.Node{
+					etcd.Node{Key: "/fleet/config/myapp/DB_URL", Value: `{"Value":"db:5432"}`},
+				}},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&units, nil, nil, &config, nil}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	kr, _ := NewKeyring(newKey, oldKey)
+	r.SetKeyring(kr)
+
+	n, err := r.Reencrypt()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 values re-encrypted, got %d", n)
+	}
+
+	want := map[string]string{
+		"/fleet/unit/aaa":            `{"Raw":"old"}`,
+		"/fleet/unit/ccc":            `{"Raw":"plain"}`,
+		"/fleet/config/myapp/DB_URL": `{"Value":"db:5432"}`,
+	}
+	if len(e.sets) != len(want) {
+		t.Fatalf("Unexpected sets: %#v", e.sets)
+	}
+	for _, set := range e.sets {
+		sm := sealedOf(set.val)
+		if sm == nil || sm.KeyID != keyID(newKey) {
+			t.Errorf("Expected %s sealed with new key, got %s", set.key, set.val)
+			continue
+		}
+		if got, _ := kr.open(sm); got != want[set.key] {
+			t.Errorf("Unexpected value of %s: %q", set.key, got)
+		}
+	}
+}
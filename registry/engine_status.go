@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"path"
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	engineStatusPrefix   = "engines"
+	engineStepDownPrefix = "engine-step-down"
+)
+
+// EngineStatus is published by each engine holding the lease of the lead
+// engine, or of shards of the scheduling work, after each reconciliation
+type EngineStatus struct {
+	MachineID string `json:"-"`
+	Version   int
+
+	// Shards holds the shards of the scheduling work the engine holds the
+	// leases of, or nil if it schedules all Units as the lead engine
+	Shards []int `json:",omitempty"`
+	// LeaseExpires is when the leases of the engine expire unless renewed
+	LeaseExpires time.Time
+
+	// Reconciles is the number of reconciliations carried out since the
+	// engine acquired its first lease, the last of which started at
+	// LastReconcile and took LastReconcileDuration
+	Reconciles            int
+	LastReconcile         time.Time
+	LastReconcileDuration time.Duration
+}
+
+// SaveEngineStatus publishes the given EngineStatus of an engine. It
+// expires after the given TTL, so that the status of engines that went away
+// is not kept.
+func (r *EtcdRegistry) SaveEngineStatus(es EngineStatus, ttl time.Duration) error {
+	val, err := marshal(es)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, engineStatusPrefix, es.MachineID),
+		Value: val,
+		TTL:   ttl,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// RemoveEngineStatus removes the EngineStatus of the engine of the given
+// machine, e.g. once it no longer holds any lease
+func (r *EtcdRegistry) RemoveEngineStatus(machID string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, engineStatusPrefix, machID),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// EngineStatuses returns the EngineStatus published by each engine holding
+// a lease, sorted by machine ID
+func (r *EtcdRegistry) EngineStatuses() ([]EngineStatus, error) {
+	req := etcd.Get{
+		Key:    path.Join(r.keyPrefix, engineStatusPrefix),
+		Sorted: true,
+	}
+
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+
+	var statuses []EngineStatus
+	for _, node := range res.Node.Nodes {
+		var es EngineStatus
+		if err := unmarshal(node.Value, &es); err != nil {
+			log.Errorf("Failed parsing EngineStatus from %s: %v", node.Key, err)
+			continue
+		}
+		es.MachineID = path.Base(node.Key)
+		statuses = append(statuses, es)
+	}
+	sort.Sort(engineStatusesByMachineID(statuses))
+	return statuses, nil
+}
+
+// RequestEngineStepDown asks the engine of the given machine to release all
+// its leases and not to acquire any for the given amount of time
+func (r *EtcdRegistry) RequestEngineStepDown(machID string, hold time.Duration) error {
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, engineStepDownPrefix, machID),
+		Value: time.Now().Add(hold).UTC().Format(time.RFC3339),
+		TTL:   hold,
+	}
+	_, err := r.etcd.Do(&req)
+	return err
+}
+
+// EngineStepDownRequested determines whether the engine of the given machine
+// was asked to step down and must not hold any lease
+func (r *EtcdRegistry) EngineStepDownRequested(machID string) (bool, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, engineStepDownPrefix, machID),
+	}
+	_, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+type engineStatusesByMachineID []EngineStatus
+
+func (s engineStatusesByMachineID) Len() int           { return len(s) }
+func (s engineStatusesByMachineID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s engineStatusesByMachineID) Less(i, j int) bool { return s[i].MachineID < s[j].MachineID }
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestEngineStatuses(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/engines",
+			Nodes: []etcd.Node{
+				etcd.Node{Key: "/fleet/engines/YYY", Value: `{"Version":1,"Shards":[1,3],"LeaseExpires":"2014-10-01T12:00:25Z","Reconciles":2,"LastReconcile":"2014-10-01T12:00:00Z","LastReconcileDuration":1500000000}`},
+				etcd.Node{Key: "/fleet/engines/XXX", Value: `{"Version":1,"Shards":[0,2],"LeaseExpires":"2014-10-01T12:00:25Z"}`},
+				etcd.Node{Key: "/fleet/engines/ZZZ", Value: `garbage`},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.EngineStatuses()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expires := time.Date(2014, 10, 1, 12, 0, 25, 0, time.UTC)
+	want := []EngineStatus{
+		EngineStatus{MachineID: "XXX", Version: 1, Shards: []int{0, 2}, LeaseExpires: expires},
+		EngineStatus{
+			MachineID:             "YYY",
+			Version:               1,
+			Shards:                []int{1, 3},
+			LeaseExpires:          expires,
+			Reconciles:            2,
+			LastReconcile:         time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC),
+			LastReconcileDuration: 1500 * time.Millisecond,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected statuses:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestEngineStepDownRequested(t *testing.T) {
+	for i, tt := range []struct {
+		res  *etcd.Result
+		err  error
+		want bool
+		ok   bool
+	}{
+		{&etcd.Result{Node: &etcd.Node{Key: "/fleet/engine-step-down/XXX", Value: "2014-10-01T12:01:00Z"}}, nil, true, true},
+		{nil, etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}, false, true},
+		{nil, etcd.Error{ErrorCode: etcd.ErrorNodeExist}, false, false},
+	} {
+		e := &testEtcdClient{res: []*etcd.Result{tt.res}, err: []error{tt.err}}
+		r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+		got, err := r.EngineStepDownRequested("XXX")
+		if tt.ok != (err == nil) {
+			t.Errorf("case %d: unexpected error %v", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("case %d: got %t, want %t", i, got, tt.want)
+		}
+		if want := []action{action{key: "/fleet/engine-step-down/XXX"}}; !reflect.DeepEqual(e.gets, want) {
+			t.Errorf("case %d: unexpected gets %#v", i, e.gets)
+		}
+	}
+}
@@ -15,6 +15,8 @@ const (
 	JobTargetChangeEvent = pkg.Event("JobTargetChangeEvent")
 	// Occurs when any Job's target state is touched
 	JobTargetStateChangeEvent = pkg.Event("JobTargetStateChangeEvent")
+	// Occurs when the presence of any machine expires or is removed
+	MachineLeftEvent = pkg.Event("MachineLeftEvent")
 )
 
 type etcdEventStream struct {
@@ -49,6 +51,57 @@ func (es *etcdEventStream) Next(stop chan struct{}) chan pkg.Event {
 	return evchan
 }
 
+type etcdMachineEventStream struct {
+	etcd       etcd.Client
+	rootPrefix string
+}
+
+// NewEtcdMachineEventStream creates an EventStream emitting a
+// MachineLeftEvent whenever the presence of a machine expires or is removed
+func NewEtcdMachineEventStream(client etcd.Client, rootPrefix string) pkg.EventStream {
+	return &etcdMachineEventStream{client, rootPrefix}
+}
+
+// Next returns a channel which will emit an Event as soon as a machine leaves
+func (es *etcdMachineEventStream) Next(stop chan struct{}) chan pkg.Event {
+	evchan := make(chan pkg.Event)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			res := watch(es.etcd, path.Join(es.rootPrefix, machinePrefix), stop)
+			if ev, ok := parseMachineEvent(res, es.rootPrefix); ok {
+				evchan <- ev
+				return
+			}
+		}
+	}()
+
+	return evchan
+}
+
+func parseMachineEvent(res *etcd.Result, prefix string) (ev pkg.Event, ok bool) {
+	if res == nil || res.Node == nil {
+		return
+	}
+
+	if !strings.HasPrefix(res.Node.Key, path.Join(prefix, machinePrefix)+"/") || path.Base(res.Node.Key) != "object" {
+		return
+	}
+
+	switch res.Action {
+	case "expire", "delete":
+		ev = MachineLeftEvent
+		ok = true
+	}
+
+	return
+}
+
 func parse(res *etcd.Result, prefix string) (ev pkg.Event, ok bool) {
 	if res == nil || res.Node == nil {
 		return
@@ -77,3 +77,38 @@ func TestFilterEtcdEvents(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterEtcdMachineEvents(t *testing.T) {
+	tests := []struct {
+		key    string
+		action string
+		ok     bool
+	}{
+		{"/fleet/machines/XXX/object", "expire", true},
+		{"/fleet/machines/XXX/object", "delete", true},
+		{"/fleet/machines/XXX/object", "set", false},
+		{"/fleet/machines/XXX/object", "update", false},
+		{"/fleet/machines/XXX/cordon", "delete", false},
+		{"/fleet/job/XXX/object", "expire", false},
+		{"/fleet/machines", "delete", false},
+	}
+
+	for i, tt := range tests {
+		res := &etcd.Result{
+			Node:   &etcd.Node{Key: tt.key},
+			Action: tt.action,
+		}
+		ev, ok := parseMachineEvent(res, "/fleet")
+		if ok != tt.ok {
+			t.Errorf("case %d: expected ok=%t, got %t", i, tt.ok, ok)
+			continue
+		}
+		if ok && ev != MachineLeftEvent {
+			t.Errorf("case %d: expected %v, got %v", i, MachineLeftEvent, ev)
+		}
+	}
+
+	if _, ok := parseMachineEvent(nil, "/fleet"); ok {
+		t.Errorf("expected no event for nil result")
+	}
+}
@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"path"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+const (
+	failurePrefix = "failure"
+)
+
+// ReportUnitFailure records that the named Unit failed persistently on the
+// identified machine. The report expires after the given TTL.
+func (r *EtcdRegistry) ReportUnitFailure(name, machID, reason string, ttl time.Duration) error {
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, failurePrefix, name, machID),
+		Value: reason,
+		TTL:   ttl,
+	}
+	_, err := r.etcd.Do(&req)
+	return err
+}
+
+// UnitFailures returns the reasons of all unexpired failure reports, indexed
+// by Unit name and then by the ID of the machine the Unit failed on.
+func (r *EtcdRegistry) UnitFailures() (map[string]map[string]string, error) {
+	req := etcd.Get{
+		Key:       path.Join(r.keyPrefix, failurePrefix),
+		Recursive: true,
+	}
+
+	failures := make(map[string]map[string]string)
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return failures, err
+	}
+
+	for _, dir := range res.Node.Nodes {
+		name := path.Base(dir.Key)
+		for _, node := range dir.Nodes {
+			if _, ok := failures[name]; !ok {
+				failures[name] = make(map[string]string)
+			}
+			failures[name][path.Base(node.Key)] = node.Value
+		}
+	}
+
+	return failures, nil
+}
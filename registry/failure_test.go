@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestReportUnitFailure(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	r.ReportUnitFailure("foo.service", "XXX", "failed 4 times", time.Minute)
+
+	want := []action{action{key: "/fleet/failure/foo.service/XXX", val: "failed 4 times"}}
+	if !reflect.DeepEqual(e.sets, want) {
+		t.Errorf("Unexpected sets:\ngot\n%#v\nwant\n%#v", e.sets, want)
+	}
+}
+
+func TestUnitFailures(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/failure",
+			Nodes: []etcd.Node{
+				etcd.Node{
+					Key: "/fleet/failure/foo.service",
+					Nodes: []etcd.Node{
+						etcd.Node{Key: "/fleet/failure/foo.service/XXX", Value: "ping"},
+						etcd.Node{Key: "/fleet/failure/foo.service/YYY", Value: "pong"},
+					},
+				},
+				// all reports of a Unit expired
+				etcd.Node{Key: "/fleet/failure/bar.service"},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	failures, err := r.UnitFailures()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]map[string]string{
+		"foo.service": map[string]string{"XXX": "ping", "YYY": "pong"},
+	}
+	if !reflect.DeepEqual(failures, want) {
+		t.Errorf("Unexpected failures:\ngot\n%#v\nwant\n%#v", failures, want)
+	}
+}
@@ -2,7 +2,10 @@ package registry
 
 import (
 	"errors"
+	"fmt"
+	"path"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,10 +18,28 @@ import (
 
 func NewFakeRegistry() *FakeRegistry {
 	return &FakeRegistry{
-		machines:      []machine.MachineState{},
-		jobStates:     map[string]map[string]*unit.UnitState{},
-		jobs:          map[string]job.Job{},
-		daemonVersion: nil,
+		machines:        []machine.MachineState{},
+		machineMetadata: map[string]map[string]string{},
+		jobStates:       map[string]map[string]*unit.UnitState{},
+		jobs:            map[string]job.Job{},
+		labels:          map[string]map[string]string{},
+		failures:        map[string]map[string]string{},
+		rejections:      map[string]UnitRejections{},
+		scales:          map[string]int{},
+		stacks:          map[string]Stack{},
+		cronRuns:        map[string]CronRun{},
+		cronResults:     map[string]CronRunResult{},
+		completions:     map[string]UnitCompletion{},
+		versions:        map[string][]UnitVersion{},
+		unitFiles:       map[unit.Hash]unit.UnitFile{},
+		config:          map[string]map[string]ConfigValue{},
+		quotas:          map[string]Quota{},
+		signatures:      map[unit.Hash]string{},
+		engines:         map[string]EngineStatus{},
+		stepDowns:       map[string]bool{},
+		upgrades:        map[string]UpgradeStatus{},
+		clusterConfig:   ClusterConfig{Values: map[string]string{}},
+		daemonVersion:   nil,
 	}
 }
 
@@ -29,10 +50,31 @@ type FakeRegistry struct {
 	Registry
 	sync.RWMutex
 
-	machines      []machine.MachineState
-	jobStates     map[string]map[string]*unit.UnitState
-	jobs          map[string]job.Job
-	daemonVersion *semver.Version
+	machines        []machine.MachineState
+	machineMetadata map[string]map[string]string
+	jobStates       map[string]map[string]*unit.UnitState
+	jobs            map[string]job.Job
+	labels          map[string]map[string]string
+	failures        map[string]map[string]string
+	rejections      map[string]UnitRejections
+	scales          map[string]int
+	stacks          map[string]Stack
+	cronRuns        map[string]CronRun
+	cronResults     map[string]CronRunResult
+	completions     map[string]UnitCompletion
+	versions        map[string][]UnitVersion
+	unitFiles       map[unit.Hash]unit.UnitFile
+	config          map[string]map[string]ConfigValue
+	quotas          map[string]Quota
+	signatures      map[unit.Hash]string
+	engines         map[string]EngineStatus
+	stepDowns       map[string]bool
+	upgradePlan     *UpgradePlan
+	upgrades        map[string]UpgradeStatus
+	clusterConfig   ClusterConfig
+	events          []ClusterEvent
+	audit           []AuditEntry
+	daemonVersion   *semver.Version
 }
 
 func (f *FakeRegistry) SetMachines(machines []machine.MachineState) {
@@ -49,6 +91,7 @@ func (f *FakeRegistry) SetJobs(jobs []job.Job) {
 	f.jobs = make(map[string]job.Job, len(jobs))
 	for _, j := range jobs {
 		f.jobs[j.Name] = j
+		f.unitFiles[j.Unit.Hash()] = j.Unit
 	}
 }
 
@@ -74,6 +117,131 @@ func (f *FakeRegistry) Machines() ([]machine.MachineState, error) {
 	return f.machines, nil
 }
 
+func (f *FakeRegistry) MachineMetadata(machID string) (map[string]string, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	metadata := make(map[string]string, len(f.machineMetadata[machID]))
+	for key, val := range f.machineMetadata[machID] {
+		metadata[key] = val
+	}
+	return metadata, nil
+}
+
+func (f *FakeRegistry) SetMachineMetadata(machID, key, value string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.machineMetadata[machID]; !ok {
+		f.machineMetadata[machID] = make(map[string]string)
+	}
+	f.machineMetadata[machID][key] = value
+	return nil
+}
+
+func (f *FakeRegistry) DeleteMachineMetadata(machID, key string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.machineMetadata[machID], key)
+	return nil
+}
+
+func (f *FakeRegistry) ConfigValues(namespace string) (map[string]ConfigValue, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	values := make(map[string]ConfigValue, len(f.config[namespace]))
+	for key, cv := range f.config[namespace] {
+		values[key] = cv
+	}
+	return values, nil
+}
+
+func (f *FakeRegistry) SetConfigValue(namespace, key string, cv ConfigValue) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.config[namespace]; !ok {
+		f.config[namespace] = make(map[string]ConfigValue)
+	}
+	f.config[namespace][key] = cv
+	return nil
+}
+
+func (f *FakeRegistry) DeleteConfigValue(namespace, key string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.config[namespace], key)
+	return nil
+}
+
+func (f *FakeRegistry) CordonMachine(machID string, drain bool) error {
+	f.Lock()
+	defer f.Unlock()
+
+	for i := range f.machines {
+		if f.machines[i].ID == machID {
+			f.machines[i].Cordoned = true
+			f.machines[i].Draining = drain
+		}
+	}
+	return nil
+}
+
+func (f *FakeRegistry) UncordonMachine(machID string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	for i := range f.machines {
+		if f.machines[i].ID == machID {
+			f.machines[i].Cordoned = false
+			f.machines[i].Draining = false
+		}
+	}
+	return nil
+}
+
+func (f *FakeRegistry) TaintMachine(machID string, t machine.Taint) error {
+	f.Lock()
+	defer f.Unlock()
+
+	for i := range f.machines {
+		if f.machines[i].ID != machID {
+			continue
+		}
+		taints := []machine.Taint{t}
+		for _, et := range f.machines[i].Taints {
+			if et.Key != t.Key {
+				taints = append(taints, et)
+			}
+		}
+		sort.Sort(taintsByKey(taints))
+		f.machines[i].Taints = taints
+	}
+	return nil
+}
+
+func (f *FakeRegistry) UntaintMachine(machID, key string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	for i := range f.machines {
+		if f.machines[i].ID != machID {
+			continue
+		}
+		var taints []machine.Taint
+		for _, et := range f.machines[i].Taints {
+			if et.Key != key {
+				taints = append(taints, et)
+			}
+		}
+		f.machines[i].Taints = taints
+	}
+	return nil
+}
+
 func (f *FakeRegistry) Units() ([]job.Unit, error) {
 	f.RLock()
 	defer f.RUnlock()
@@ -91,6 +259,7 @@ func (f *FakeRegistry) Units() ([]job.Unit, error) {
 			Name:        j.Name,
 			Unit:        j.Unit,
 			TargetState: j.TargetState,
+			Labels:      copyLabels(f.labels[j.Name]),
 		}
 		units[i] = u
 	}
@@ -135,6 +304,7 @@ func (f *FakeRegistry) Unit(name string) (*job.Unit, error) {
 		Name:        j.Name,
 		Unit:        j.Unit,
 		TargetState: j.TargetState,
+		Labels:      copyLabels(f.labels[j.Name]),
 	}
 	return &u, nil
 }
@@ -171,17 +341,64 @@ func (f *FakeRegistry) CreateUnit(u *job.Unit) error {
 	}
 
 	f.jobs[u.Name] = j
+	f.unitFiles[u.Unit.Hash()] = u.Unit
+	if len(u.Labels) > 0 {
+		f.labels[u.Name] = copyLabels(u.Labels)
+	}
 	return f.unsafeSetUnitTargetState(u.Name, u.TargetState)
 }
 
+func (f *FakeRegistry) ReplaceUnit(u *job.Unit) error {
+	f.Lock()
+	defer f.Unlock()
+
+	j, ok := f.jobs[u.Name]
+	if !ok {
+		return errors.New("unit does not exist")
+	}
+
+	j.Unit = u.Unit
+	f.jobs[u.Name] = j
+	f.unitFiles[u.Unit.Hash()] = u.Unit
+	return nil
+}
+
 func (f *FakeRegistry) DestroyUnit(name string) error {
 	f.Lock()
 	defer f.Unlock()
 
 	delete(f.jobs, name)
+	delete(f.labels, name)
+	delete(f.completions, name)
+	return nil
+}
+
+func (f *FakeRegistry) SetUnitLabels(name string, labels map[string]string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.jobs[name]; !ok {
+		return errors.New("unit does not exist")
+	}
+	if len(labels) == 0 {
+		delete(f.labels, name)
+	} else {
+		f.labels[name] = copyLabels(labels)
+	}
 	return nil
 }
 
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+	return c
+}
+
 func (f *FakeRegistry) SetUnitTargetState(name string, target job.JobState) error {
 	f.Lock()
 	defer f.Unlock()
@@ -218,6 +435,24 @@ func (f *FakeRegistry) ScheduleUnit(name string, machID string) error {
 	return nil
 }
 
+func (f *FakeRegistry) UnscheduleUnit(name, machID string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	j, ok := f.jobs[name]
+	if !ok || j.TargetMachineID == "" {
+		return nil
+	}
+	if j.TargetMachineID != machID {
+		return errors.New("unit scheduled to another machine")
+	}
+
+	j.TargetMachineID = ""
+	f.jobs[name] = j
+
+	return nil
+}
+
 func (f *FakeRegistry) SaveUnitState(jobName string, unitState *unit.UnitState, ttl time.Duration) {
 	f.Lock()
 	defer f.Unlock()
@@ -228,11 +463,562 @@ func (f *FakeRegistry) SaveUnitState(jobName string, unitState *unit.UnitState,
 	f.jobStates[jobName][unitState.MachineID] = unitState
 }
 
+func (f *FakeRegistry) SaveUnitStates(machID string, states map[string]*unit.UnitState, ttl time.Duration) error {
+	f.Lock()
+	defer f.Unlock()
+
+	for name, byMachine := range f.jobStates {
+		delete(byMachine, machID)
+		if len(byMachine) == 0 {
+			delete(f.jobStates, name)
+		}
+	}
+	for name, us := range states {
+		if _, ok := f.jobStates[name]; !ok {
+			f.jobStates[name] = make(map[string]*unit.UnitState)
+		}
+		f.jobStates[name][machID] = us
+	}
+	return nil
+}
+
 func (f *FakeRegistry) RemoveUnitState(jobName string) error {
 	delete(f.jobStates, jobName)
 	return nil
 }
 
+func (f *FakeRegistry) ReportUnitFailure(name, machID, reason string, ttl time.Duration) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.failures[name]; !ok {
+		f.failures[name] = make(map[string]string)
+	}
+	f.failures[name][machID] = reason
+	return nil
+}
+
+func (f *FakeRegistry) UnitFailures() (map[string]map[string]string, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	failures := make(map[string]map[string]string, len(f.failures))
+	for name, reports := range f.failures {
+		failures[name] = make(map[string]string, len(reports))
+		for machID, reason := range reports {
+			failures[name][machID] = reason
+		}
+	}
+	return failures, nil
+}
+
+func (f *FakeRegistry) SaveUnitRejections(name string, rej UnitRejections, ttl time.Duration) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.rejections[name] = rej
+	return nil
+}
+
+func (f *FakeRegistry) ClearUnitRejections(name string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.rejections, name)
+	return nil
+}
+
+func (f *FakeRegistry) UnitRejections(name string) (*UnitRejections, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	rej, ok := f.rejections[name]
+	if !ok {
+		return nil, nil
+	}
+	return &rej, nil
+}
+
+func (f *FakeRegistry) SetUnitScale(tmpl string, count int) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.scales[tmpl] = count
+	return nil
+}
+
+func (f *FakeRegistry) SetUnitSignature(hash unit.Hash, sig string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.signatures[hash] = sig
+	return nil
+}
+
+func (f *FakeRegistry) UnitSignature(hash unit.Hash) (string, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.signatures[hash], nil
+}
+
+func (f *FakeRegistry) UnitScales() (map[string]int, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	scales := make(map[string]int, len(f.scales))
+	for tmpl, count := range f.scales {
+		scales[tmpl] = count
+	}
+	return scales, nil
+}
+
+func (f *FakeRegistry) CreateStack(s *Stack) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.stacks[s.Name]; ok {
+		return ErrStackExists
+	}
+	f.stacks[s.Name] = *s
+	return nil
+}
+
+func (f *FakeRegistry) DestroyStack(name string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.stacks, name)
+	return nil
+}
+
+func (f *FakeRegistry) Stack(name string) (*Stack, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	s, ok := f.stacks[name]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (f *FakeRegistry) Stacks() ([]Stack, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var stacks []Stack
+	for _, s := range f.stacks {
+		stacks = append(stacks, s)
+	}
+	sort.Sort(stacksByName(stacks))
+	return stacks, nil
+}
+
+func (f *FakeRegistry) SetQuota(q Quota) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.quotas[q.Namespace] = q
+	return nil
+}
+
+func (f *FakeRegistry) DeleteQuota(namespace string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.quotas, namespace)
+	return nil
+}
+
+func (f *FakeRegistry) Quotas() ([]Quota, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var quotas []Quota
+	for _, q := range f.quotas {
+		quotas = append(quotas, q)
+	}
+	sort.Sort(quotasByNamespace(quotas))
+	return quotas, nil
+}
+
+func (f *FakeRegistry) SaveCronRun(run CronRun) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.cronRuns[run.Name] = run
+	return nil
+}
+
+func (f *FakeRegistry) RemoveCronRun(tmpl, name string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if run, ok := f.cronRuns[name]; ok && run.Template == tmpl {
+		delete(f.cronRuns, name)
+	}
+	return nil
+}
+
+func (f *FakeRegistry) CronRuns() ([]CronRun, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var runs []CronRun
+	for _, run := range f.cronRuns {
+		runs = append(runs, run)
+	}
+	sort.Sort(cronRunsByTick(runs))
+	return runs, nil
+}
+
+func (f *FakeRegistry) ReportCronRunResult(res CronRunResult) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.cronResults[res.Name] = res
+	return nil
+}
+
+func (f *FakeRegistry) CronRunResults() ([]CronRunResult, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var results []CronRunResult
+	for _, res := range f.cronResults {
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (f *FakeRegistry) ClearCronRunResult(name string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.cronResults, name)
+	return nil
+}
+
+func (f *FakeRegistry) SaveUnitCompletion(uc UnitCompletion) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.completions[uc.Name] = uc
+	return nil
+}
+
+func (f *FakeRegistry) UnitCompletions() ([]UnitCompletion, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var completions []UnitCompletion
+	for _, uc := range f.completions {
+		completions = append(completions, uc)
+	}
+	sort.Sort(completionsByFinish(completions))
+	return completions, nil
+}
+
+func (f *FakeRegistry) RecordUnitVersion(name string, hash unit.Hash) error {
+	f.Lock()
+	defer f.Unlock()
+
+	versions := f.versions[name]
+	next := 1
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if latest.Hash == hash {
+			return nil
+		}
+		next = latest.Version + 1
+	}
+
+	uv := UnitVersion{Version: next, Hash: hash, Created: time.Now()}
+	if uf, ok := f.unitFiles[hash]; ok {
+		uv.Unit = &uf
+	}
+
+	versions = append(versions, uv)
+	if len(versions) > unitVersionLimit {
+		versions = versions[len(versions)-unitVersionLimit:]
+	}
+	f.versions[name] = versions
+	return nil
+}
+
+func (f *FakeRegistry) UnitFile(hash unit.Hash) (*unit.UnitFile, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	uf, ok := f.unitFiles[hash]
+	if !ok {
+		return nil, nil
+	}
+	return &uf, nil
+}
+
+func (f *FakeRegistry) UnitVersions(name string) ([]UnitVersion, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	return append([]UnitVersion(nil), f.versions[name]...), nil
+}
+
+// Orphans finds the unit states of Units or machines that do not exist, and
+// the unit files and signatures used by no Unit nor recorded version
+func (f *FakeRegistry) Orphans() ([]Orphan, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	machines := make(map[string]bool, len(f.machines))
+	for _, ms := range f.machines {
+		machines[ms.ID] = true
+	}
+	hashes := make(map[unit.Hash]bool)
+	for _, j := range f.jobs {
+		hashes[j.Unit.Hash()] = true
+	}
+	for _, versions := range f.versions {
+		for _, uv := range versions {
+			hashes[uv.Hash] = true
+		}
+	}
+
+	var orphans []Orphan
+	for name, states := range f.jobStates {
+		_, ok := f.jobs[name]
+		for machID := range states {
+			reason := "unit destroyed"
+			if ok {
+				if machines[machID] {
+					continue
+				}
+				reason = fmt.Sprintf("Machine(%s) left the cluster", machID)
+			}
+			orphans = append(orphans, Orphan{Key: path.Join(statesPrefix, name, machID), Kind: OrphanUnitState, Reason: reason, Keys: 1})
+		}
+	}
+	for hash := range f.unitFiles {
+		if !hashes[hash] {
+			orphans = append(orphans, Orphan{Key: path.Join(unitPrefix, hash.String()), Kind: OrphanUnitFile, Reason: "unit file used by no unit or unit version", Keys: 1})
+		}
+	}
+	for hash := range f.signatures {
+		if !hashes[hash] {
+			orphans = append(orphans, Orphan{Key: path.Join("/", signaturePrefix, hash.String()), Kind: OrphanUnitFile, Reason: "signature of a unit file used by no unit or unit version", Keys: 1})
+		}
+	}
+	sort.Sort(orphansByKey(orphans))
+	return orphans, nil
+}
+
+func (f *FakeRegistry) RemoveOrphan(o Orphan) error {
+	f.Lock()
+	defer f.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(o.Key, "/"), "/")
+	switch {
+	case o.Kind == OrphanUnitState && len(parts) == 3:
+		delete(f.jobStates[parts[1]], parts[2])
+		if len(f.jobStates[parts[1]]) == 0 {
+			delete(f.jobStates, parts[1])
+		}
+	case o.Kind == OrphanUnitFile && len(parts) == 2:
+		hash, err := unit.ParseHash(parts[1])
+		if err != nil {
+			return err
+		}
+		if parts[0] == signaturePrefix {
+			delete(f.signatures, hash)
+		} else {
+			delete(f.unitFiles, hash)
+		}
+	default:
+		return errors.New("orphan does not exist")
+	}
+	return nil
+}
+
+func (f *FakeRegistry) SaveEngineStatus(es EngineStatus, ttl time.Duration) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.engines[es.MachineID] = es
+	return nil
+}
+
+func (f *FakeRegistry) RemoveEngineStatus(machID string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.engines, machID)
+	return nil
+}
+
+func (f *FakeRegistry) EngineStatuses() ([]EngineStatus, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var statuses []EngineStatus
+	for _, es := range f.engines {
+		statuses = append(statuses, es)
+	}
+	sort.Sort(engineStatusesByMachineID(statuses))
+	return statuses, nil
+}
+
+// RequestEngineStepDown records a request that never expires, regardless
+// of the hold
+func (f *FakeRegistry) RequestEngineStepDown(machID string, hold time.Duration) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.stepDowns[machID] = true
+	return nil
+}
+
+func (f *FakeRegistry) EngineStepDownRequested(machID string) (bool, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.stepDowns[machID], nil
+}
+
+func (f *FakeRegistry) ClusterConfig() (*ClusterConfig, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	cc := f.clusterConfig
+	cc.Values = make(map[string]string, len(f.clusterConfig.Values))
+	for k, v := range f.clusterConfig.Values {
+		cc.Values[k] = v
+	}
+	return &cc, nil
+}
+
+func (f *FakeRegistry) UpdateClusterConfig(version int, values map[string]string) (*ClusterConfig, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.clusterConfig.Version != version {
+		return nil, ErrClusterConfigChanged
+	}
+	f.clusterConfig = ClusterConfig{Version: version + 1, Values: values, Updated: time.Now()}
+	cc := f.clusterConfig
+	return &cc, nil
+}
+
+func (f *FakeRegistry) SetUpgradePlan(p UpgradePlan) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.upgradePlan = &p
+	f.upgrades = map[string]UpgradeStatus{}
+	return nil
+}
+
+func (f *FakeRegistry) UpgradePlan() (*UpgradePlan, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	if f.upgradePlan == nil {
+		return nil, nil
+	}
+	p := *f.upgradePlan
+	return &p, nil
+}
+
+func (f *FakeRegistry) ClearUpgradePlan() error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.upgradePlan = nil
+	f.upgrades = map[string]UpgradeStatus{}
+	return nil
+}
+
+func (f *FakeRegistry) SaveUpgradeStatus(us UpgradeStatus) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.upgrades[us.MachineID] = us
+	return nil
+}
+
+func (f *FakeRegistry) UpgradeStatuses() ([]UpgradeStatus, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var statuses []UpgradeStatus
+	for _, us := range f.upgrades {
+		statuses = append(statuses, us)
+	}
+	sort.Sort(upgradeStatusesByMachineID(statuses))
+	return statuses, nil
+}
+
+func (f *FakeRegistry) RecordEvent(ev ClusterEvent) error {
+	f.Lock()
+	defer f.Unlock()
+
+	ev.Index = uint64(len(f.events) + 1)
+	f.events = append(f.events, ev)
+	return nil
+}
+
+func (f *FakeRegistry) Events(since uint64) ([]ClusterEvent, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var events []ClusterEvent
+	for _, ev := range f.events {
+		if ev.Index > since {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+func (f *FakeRegistry) UnitHistory(name string) ([]ClusterEvent, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var events []ClusterEvent
+	for _, ev := range f.events {
+		if ev.UnitName == name {
+			events = append(events, ev)
+		}
+	}
+	if len(events) > unitHistoryLimit {
+		events = events[len(events)-unitHistoryLimit:]
+	}
+	return events, nil
+}
+
+func (f *FakeRegistry) RecordAudit(ae AuditEntry) error {
+	f.Lock()
+	defer f.Unlock()
+
+	ae.Index = uint64(len(f.audit) + 1)
+	f.audit = append(f.audit, ae)
+	return nil
+}
+
+func (f *FakeRegistry) AuditLog() ([]AuditEntry, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	entries := make([]AuditEntry, len(f.audit))
+	copy(entries, f.audit)
+	return entries, nil
+}
+
+// WaitForEvents never blocks, returning the same as Events
+func (f *FakeRegistry) WaitForEvents(since uint64, stop <-chan struct{}) ([]ClusterEvent, error) {
+	return f.Events(since)
+}
+
 func (f *FakeRegistry) UnitStates() ([]*unit.UnitState, error) {
 	f.Lock()
 	defer f.Unlock()
@@ -0,0 +1,284 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/pkg"
+)
+
+const (
+	// Unit states published for Units that no longer exist, or by
+	// machines that no longer exist
+	OrphanUnitState = "unit-state"
+	// The scheduling decision, target state and labels left behind by a
+	// Unit that was destroyed, e.g. as it was scheduled concurrently
+	OrphanSchedule = "schedule"
+	// Unit files, and their signatures, used by no Unit nor by any
+	// recorded version of a Unit
+	OrphanUnitFile = "unit-file"
+)
+
+// ErrOrphanChanged is returned by RemoveOrphan if the key changed since it
+// was found orphaned, so it may no longer be
+var ErrOrphanChanged = errors.New("orphaned key changed since it was found")
+
+// Orphan is a key, or a directory of keys, of the Registry left behind by a
+// Unit or machine that no longer exists. Nothing reads it any more, but
+// nothing expires it either.
+type Orphan struct {
+	Key    string
+	Kind   string
+	Reason string
+
+	// Index is the highest ModifiedIndex of the key, or of the keys in
+	// the directory, when it was found orphaned. The Orphan is only
+	// removed as long as it has not changed since.
+	Index uint64
+
+	// Keys is the number of keys removing the Orphan frees
+	Keys int
+
+	// dir is set if the Orphan is a directory of keys
+	dir bool
+}
+
+// Orphans scans the Registry for data left behind by Units and machines
+// that no longer exist, ordered by key. The data possibly orphaned is read
+// before the data that refers to it, so that data written during the scan
+// is never found orphaned for want of its referrer. Data may still be
+// found orphaned as it is being written, e.g. the unit file of a Unit
+// being created, so an Orphan should only be removed once it was found
+// orphaned, and unchanged, a while apart.
+func (r *EtcdRegistry) Orphans() ([]Orphan, error) {
+	var trees []*etcd.Node
+	for _, prefix := range []string{unitPrefix, signaturePrefix, statePrefix, statesPrefix, machineStatesPrefix, jobPrefix, unitVersionPrefix, machinePrefix} {
+		node, err := r.tree(path.Join(r.keyPrefix, prefix))
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, node)
+	}
+	files, sigs, legacyStates, states, machStates, jobs, versions, machs := trees[0], trees[1], trees[2], trees[3], trees[4], trees[5], trees[6], trees[7]
+
+	var orphans []Orphan
+	add := func(node *etcd.Node, kind, reason string) {
+		o := Orphan{Key: node.Key, Kind: kind, Reason: reason, dir: isDir(node)}
+		walkNodes(node, func(n *etcd.Node) {
+			if n.ModifiedIndex > o.Index {
+				o.Index = n.ModifiedIndex
+			}
+			if len(n.Nodes) == 0 {
+				o.Keys++
+			}
+		})
+		orphans = append(orphans, o)
+	}
+
+	units := pkg.NewUnsafeSet()
+	hashes := pkg.NewUnsafeSet()
+	for _, dir := range jobs.Nodes {
+		name := path.Base(dir.Key)
+		obj := findNode(&dir, "object")
+		if obj == nil {
+			reason := "unit destroyed"
+			if tgt := dirToTargetMachineID(&dir); tgt != "" {
+				reason = fmt.Sprintf("unit destroyed while scheduled to Machine(%s)", tgt)
+			}
+			add(&dir, OrphanSchedule, reason)
+			continue
+		}
+		units.Add(name)
+		var jm jobModel
+		if err := unmarshal(obj.Value, &jm); err == nil {
+			hashes.Add(jm.UnitHash.String())
+		}
+	}
+	for _, dir := range versions.Nodes {
+		for _, node := range dir.Nodes {
+			var uv UnitVersion
+			if err := unmarshal(node.Value, &uv); err == nil {
+				hashes.Add(uv.Hash.String())
+			}
+		}
+	}
+
+	// The metadata, cordon and taints of machines are set by operators,
+	// so they are kept until removed explicitly, even once the machine
+	// left the cluster
+	machines := pkg.NewUnsafeSet()
+	for _, dir := range machs.Nodes {
+		if findNode(&dir, "object") != nil {
+			machines.Add(path.Base(dir.Key))
+		}
+	}
+
+	for _, node := range files.Nodes {
+		if !hashes.Contains(path.Base(node.Key)) {
+			add(&node, OrphanUnitFile, "unit file used by no unit or unit version")
+		}
+	}
+	for _, node := range sigs.Nodes {
+		if !hashes.Contains(path.Base(node.Key)) {
+			add(&node, OrphanUnitFile, "signature of a unit file used by no unit or unit version")
+		}
+	}
+
+	for _, node := range legacyStates.Nodes {
+		if !units.Contains(path.Base(node.Key)) {
+			add(&node, OrphanUnitState, "unit destroyed")
+		}
+	}
+	for _, dir := range states.Nodes {
+		if !units.Contains(path.Base(dir.Key)) {
+			add(&dir, OrphanUnitState, "unit destroyed")
+			continue
+		}
+		for _, node := range dir.Nodes {
+			if machID := path.Base(node.Key); !machines.Contains(machID) {
+				add(&node, OrphanUnitState, fmt.Sprintf("Machine(%s) left the cluster", machID))
+			}
+		}
+	}
+	for _, node := range machStates.Nodes {
+		if machID := path.Base(node.Key); !machines.Contains(machID) {
+			add(&node, OrphanUnitState, fmt.Sprintf("Machine(%s) left the cluster", machID))
+		}
+	}
+
+	sort.Sort(orphansByKey(orphans))
+	return orphans, nil
+}
+
+// RemoveOrphan removes the given Orphan from the Registry, provided it did
+// not change since it was found orphaned. Otherwise ErrOrphanChanged is
+// returned. As directories cannot be compared and deleted as a whole, the
+// keys of an orphaned directory are removed one by one, each only if it did
+// not change, and the directory only once empty.
+func (r *EtcdRegistry) RemoveOrphan(o Orphan) error {
+	if !o.dir {
+		return r.removeOrphanedKey(o.Key, o.Index)
+	}
+
+	node, err := r.tree(o.Key)
+	if err != nil {
+		return err
+	}
+	var latest uint64
+	walkNodes(node, func(n *etcd.Node) {
+		if n.ModifiedIndex > latest {
+			latest = n.ModifiedIndex
+		}
+	})
+	if latest == 0 {
+		// already removed
+		return nil
+	}
+	if latest != o.Index {
+		return ErrOrphanChanged
+	}
+	return r.removeOrphanedDir(node)
+}
+
+// removeOrphanedDir removes the keys in the given directory, and those of
+// the directories it holds, then the directory itself
+func (r *EtcdRegistry) removeOrphanedDir(dir *etcd.Node) error {
+	for i := range dir.Nodes {
+		node := &dir.Nodes[i]
+		var err error
+		if isDir(node) {
+			err = r.removeOrphanedDir(node)
+		} else {
+			err = r.removeOrphanedKey(node.Key, node.ModifiedIndex)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	req := etcd.Delete{
+		Key: dir.Key,
+		Dir: true,
+	}
+	_, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			return nil
+		}
+		if e, ok := err.(etcd.Error); ok && e.ErrorCode == etcd.ErrorDirNotEmpty {
+			return ErrOrphanChanged
+		}
+	}
+	return err
+}
+
+// removeOrphanedKey removes the given key if it was last modified at the
+// given index
+func (r *EtcdRegistry) removeOrphanedKey(key string, index uint64) error {
+	req := etcd.Delete{
+		Key:           key,
+		PreviousIndex: index,
+	}
+	_, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			return nil
+		}
+		if isTestFailed(err) {
+			return ErrOrphanChanged
+		}
+	}
+	return err
+}
+
+// tree reads the given key recursively, returning an empty directory if it
+// does not exist
+func (r *EtcdRegistry) tree(key string) (*etcd.Node, error) {
+	req := etcd.Get{
+		Key:       key,
+		Recursive: true,
+	}
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			return &etcd.Node{Key: key, Dir: true}, nil
+		}
+		return nil, err
+	}
+	return res.Node, nil
+}
+
+// findNode returns the node of the given name in the given directory, or
+// nil if there is none
+func findNode(dir *etcd.Node, name string) *etcd.Node {
+	for i := range dir.Nodes {
+		if path.Base(dir.Nodes[i].Key) == name {
+			return &dir.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// walkNodes calls fn for the given node and all the nodes under it
+func walkNodes(node *etcd.Node, fn func(*etcd.Node)) {
+	fn(node)
+	for i := range node.Nodes {
+		walkNodes(&node.Nodes[i], fn)
+	}
+}
+
+// isDir determines whether the given node is a directory. The directories
+// of etcd results served from a CachedClient are not flagged as such, but
+// they are never empty.
+func isDir(node *etcd.Node) bool {
+	return node.Dir || len(node.Nodes) > 0
+}
+
+type orphansByKey []Orphan
+
+func (s orphansByKey) Len() int           { return len(s) }
+func (s orphansByKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s orphansByKey) Less(i, j int) bool { return s[i].Key < s[j].Key }
@@ -0,0 +1,169 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestOrphans(t *testing.T) {
+	newHash := func(contents string) unit.Hash {
+		uf, err := unit.NewUnitFile(contents)
+		if err != nil {
+			t.Fatalf("Unexpected error creating unit file: %v", err)
+		}
+		return uf.Hash()
+	}
+	used, versioned, unused := newHash("[Service]\nExecStart=/bin/web"), newHash("[Service]\nExecStart=/bin/web-1"), newHash("[Service]\nExecStart=/bin/gone")
+	obj, _ := marshal(jobModel{Name: "web.service", UnitHash: used})
+	ver, _ := marshal(UnitVersion{Hash: versioned})
+
+	dir := func(key string, nodes ...etcd.Node) etcd.Node {
+		return etcd.Node{Key: key, Dir: true, Nodes: nodes, ModifiedIndex: 1}
+	}
+	key := func(key, value string, index uint64) etcd.Node {
+		return etcd.Node{Key: key, Value: value, ModifiedIndex: index}
+	}
+	results := []etcd.Node{
+		dir("/fleet/unit",
+			key("/fleet/unit/"+used.String(), "{}", 2),
+			key("/fleet/unit/"+versioned.String(), "{}", 3),
+			key("/fleet/unit/"+unused.String(), "{}", 4),
+		),
+		dir("/fleet/signature",
+			key("/fleet/signature/"+unused.String(), "sig", 5),
+		),
+		dir("/fleet/state",
+			key("/fleet/state/web.service", "{}", 6),
+			key("/fleet/state/old.service", "{}", 7),
+		),
+		dir("/fleet/states",
+			dir("/fleet/states/web.service",
+				key("/fleet/states/web.service/XXX", "{}", 8),
+				key("/fleet/states/web.service/YYY", "{}", 9),
+			),
+			dir("/fleet/states/old.service",
+				key("/fleet/states/old.service/XXX", "{}", 10),
+				key("/fleet/states/old.service/YYY", "{}", 11),
+			),
+		),
+		dir("/fleet/states-by-machine",
+			key("/fleet/states-by-machine/XXX", "{}", 12),
+			key("/fleet/states-by-machine/YYY", "{}", 13),
+		),
+		dir("/fleet/job",
+			dir("/fleet/job/web.service",
+				key("/fleet/job/web.service/object", obj, 14),
+				key("/fleet/job/web.service/target", "XXX", 15),
+			),
+			dir("/fleet/job/old.service",
+				key("/fleet/job/old.service/target", "YYY", 16),
+				key("/fleet/job/old.service/target-state", "launched", 17),
+			),
+		),
+		dir("/fleet/unit-versions",
+			dir("/fleet/unit-versions/web.service",
+				key("/fleet/unit-versions/web.service/1", ver, 18),
+			),
+		),
+		dir("/fleet/machines",
+			dir("/fleet/machines/XXX",
+				key("/fleet/machines/XXX/object", `{"ID":"XXX"}`, 19),
+			),
+			dir("/fleet/machines/YYY",
+				key("/fleet/machines/YYY/cordon", "cordon", 20),
+				dir("/fleet/machines/YYY/taints",
+					key("/fleet/machines/YYY/taints/gpu", "{}", 21),
+				),
+			),
+		),
+	}
+	e := &testEtcdClient{}
+	for i := range results {
+		e.res = append(e.res, &etcd.Result{Node: &results[i]})
+	}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.Orphans()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []Orphan{
+		{Key: "/fleet/job/old.service", Kind: OrphanSchedule, Reason: "unit destroyed while scheduled to Machine(YYY)", Index: 17, Keys: 2, dir: true},
+		{Key: "/fleet/signature/" + unused.String(), Kind: OrphanUnitFile, Reason: "signature of a unit file used by no unit or unit version", Index: 5, Keys: 1},
+		{Key: "/fleet/state/old.service", Kind: OrphanUnitState, Reason: "unit destroyed", Index: 7, Keys: 1},
+		{Key: "/fleet/states-by-machine/YYY", Kind: OrphanUnitState, Reason: "Machine(YYY) left the cluster", Index: 13, Keys: 1},
+		{Key: "/fleet/states/old.service", Kind: OrphanUnitState, Reason: "unit destroyed", Index: 11, Keys: 2, dir: true},
+		{Key: "/fleet/states/web.service/YYY", Kind: OrphanUnitState, Reason: "Machine(YYY) left the cluster", Index: 9, Keys: 1},
+		{Key: "/fleet/unit/" + unused.String(), Kind: OrphanUnitFile, Reason: "unit file used by no unit or unit version", Index: 4, Keys: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected orphans:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestRemoveOrphan(t *testing.T) {
+	// keys are only removed if unchanged
+	for i, tt := range []struct {
+		err  error
+		want error
+	}{
+		{nil, nil},
+		{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}, nil},
+		{etcd.Error{ErrorCode: etcd.ErrorTestFailed}, ErrOrphanChanged},
+	} {
+		e := &testEtcdClient{err: []error{tt.err}}
+		r := &EtcdRegistry{e, "/fleet", nil, nil}
+		if err := r.RemoveOrphan(Orphan{Key: "/fleet/state/old.service", Index: 7}); err != tt.want {
+			t.Errorf("case %d: got error %v, want %v", i, err, tt.want)
+		}
+		if want := []action{{key: "/fleet/state/old.service"}}; !reflect.DeepEqual(e.deletes, want) {
+			t.Errorf("case %d: unexpected deletes %v", i, e.deletes)
+		}
+	}
+
+	// directories are only removed if none of their keys changed
+	tree := &etcd.Result{Node: &etcd.Node{
+		Key: "/fleet/job/old.service",
+		Dir: true,
+		Nodes: []etcd.Node{
+			{Key: "/fleet/job/old.service/target", Value: "YYY", ModifiedIndex: 16},
+			{Key: "/fleet/job/old.service/target-state", Value: "launched", ModifiedIndex: 17},
+		},
+	}}
+	o := Orphan{Key: "/fleet/job/old.service", Index: 17, dir: true}
+
+	e := &testEtcdClient{res: []*etcd.Result{tree}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.RemoveOrphan(o); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	want := []action{
+		{key: "/fleet/job/old.service/target"},
+		{key: "/fleet/job/old.service/target-state"},
+		{key: "/fleet/job/old.service"},
+	}
+	if !reflect.DeepEqual(e.deletes, want) {
+		t.Errorf("Unexpected deletes %v", e.deletes)
+	}
+
+	o.Index = 16
+	e = &testEtcdClient{res: []*etcd.Result{tree}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.RemoveOrphan(o); err != ErrOrphanChanged {
+		t.Errorf("Expected ErrOrphanChanged, got %v", err)
+	}
+	if len(e.deletes) != 0 {
+		t.Errorf("Unexpected deletes %v", e.deletes)
+	}
+
+	// a key added to the directory meanwhile is left alone
+	e = &testEtcdClient{res: []*etcd.Result{tree}, err: []error{nil, nil, nil, etcd.Error{ErrorCode: etcd.ErrorDirNotEmpty}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	o.Index = 17
+	if err := r.RemoveOrphan(o); err != ErrOrphanChanged {
+		t.Errorf("Expected ErrOrphanChanged, got %v", err)
+	}
+}
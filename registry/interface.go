@@ -11,28 +11,92 @@ import (
 )
 
 type Registry interface {
+	ClearCronRunResult(name string) error
 	ClearUnitHeartbeat(name string)
+	CordonMachine(machID string, drain bool) error
+	CreateStack(*Stack) error
 	CreateUnit(*job.Unit) error
+	DeleteConfigValue(namespace, key string) error
+	DeleteQuota(namespace string) error
+	DestroyStack(name string) error
 	DestroyUnit(string) error
+	EngineStatuses() ([]EngineStatus, error)
+	EngineStepDownRequested(machID string) (bool, error)
+	UncordonMachine(machID string) error
 	UnitHeartbeat(name, machID string, ttl time.Duration) error
+	DeleteMachineMetadata(machID, key string) error
+	MachineMetadata(machID string) (map[string]string, error)
 	Machines() ([]machine.MachineState, error)
+	RemoveCronRun(tmpl, name string) error
+	RemoveEngineStatus(machID string) error
 	RemoveMachineState(machID string) error
+	RemoveOrphan(o Orphan) error
 	RemoveUnitState(jobName string) error
+	RecordUnitVersion(name string, hash unit.Hash) error
+	ReportCronRunResult(res CronRunResult) error
+	ReplaceUnit(*job.Unit) error
+	ReportUnitFailure(name, machID, reason string, ttl time.Duration) error
+	RequestEngineStepDown(machID string, hold time.Duration) error
+	SaveEngineStatus(es EngineStatus, ttl time.Duration) error
+	SaveCronRun(run CronRun) error
+	SaveUnitCompletion(uc UnitCompletion) error
+	SaveUnitRejections(name string, rej UnitRejections, ttl time.Duration) error
+	ClearUnitRejections(name string) error
+	ClearUpgradePlan() error
+	SaveUpgradeStatus(us UpgradeStatus) error
 	SaveUnitState(jobName string, unitState *unit.UnitState, ttl time.Duration)
+	SaveUnitStates(machID string, states map[string]*unit.UnitState, ttl time.Duration) error
 	ScheduleUnit(name, machID string) error
 	SetUnitTargetState(name string, state job.JobState) error
+	SetConfigValue(namespace, key string, cv ConfigValue) error
+	SetMachineMetadata(machID, key, value string) error
 	SetMachineState(ms machine.MachineState, ttl time.Duration) (uint64, error)
+	SetQuota(q Quota) error
+	SetUnitLabels(name string, labels map[string]string) error
+	SetUnitScale(tmpl string, count int) error
+	SetUnitSignature(hash unit.Hash, sig string) error
+	SetUpgradePlan(p UpgradePlan) error
+	TaintMachine(machID string, t machine.Taint) error
+	UpdateClusterConfig(version int, values map[string]string) (*ClusterConfig, error)
 	UnscheduleUnit(name, machID string) error
+	UntaintMachine(machID, key string) error
 
 	UnitRegistry
+	EventRegistry
 }
 
 type UnitRegistry interface {
+	ClusterConfig() (*ClusterConfig, error)
+	ConfigValues(namespace string) (map[string]ConfigValue, error)
+	CronRunResults() ([]CronRunResult, error)
+	CronRuns() ([]CronRun, error)
+	Orphans() ([]Orphan, error)
+	Quotas() ([]Quota, error)
 	Schedule() ([]job.ScheduledUnit, error)
 	ScheduledUnit(name string) (*job.ScheduledUnit, error)
+	Stack(name string) (*Stack, error)
+	Stacks() ([]Stack, error)
 	Unit(name string) (*job.Unit, error)
+	UnitCompletions() ([]UnitCompletion, error)
 	Units() ([]job.Unit, error)
+	UnitFailures() (map[string]map[string]string, error)
+	UnitFile(hash unit.Hash) (*unit.UnitFile, error)
+	UnitRejections(name string) (*UnitRejections, error)
+	UnitScales() (map[string]int, error)
+	UnitSignature(hash unit.Hash) (string, error)
 	UnitStates() ([]*unit.UnitState, error)
+	UnitVersions(name string) ([]UnitVersion, error)
+	UpgradePlan() (*UpgradePlan, error)
+	UpgradeStatuses() ([]UpgradeStatus, error)
+}
+
+type EventRegistry interface {
+	RecordEvent(ev ClusterEvent) error
+	Events(since uint64) ([]ClusterEvent, error)
+	WaitForEvents(since uint64, stop <-chan struct{}) ([]ClusterEvent, error)
+	UnitHistory(name string) ([]ClusterEvent, error)
+	RecordAudit(ae AuditEntry) error
+	AuditLog() ([]AuditEntry, error)
 }
 
 type ClusterRegistry interface {
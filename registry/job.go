@@ -162,6 +162,11 @@ func (r *EtcdRegistry) dirToUnit(dir *etcd.Node) (*job.Unit, error) {
 		}
 		u.TargetState = ts
 	}
+	if labels := dirToLabels(dir); labels != "" {
+		if err := unmarshal(labels, &u.Labels); err != nil {
+			return nil, fmt.Errorf("failed to parse Unit(%s) labels: %v", u.Name, err)
+		}
+	}
 
 	return u, nil
 }
@@ -232,6 +237,10 @@ func dirToTargetState(dir *etcd.Node) (tgtState string) {
 	return getValueInDir(dir, "target-state")
 }
 
+func dirToLabels(dir *etcd.Node) (labels string) {
+	return getValueInDir(dir, "labels")
+}
+
 func dirToHeartbeat(dir *etcd.Node) (heartbeat string) {
 	return getValueInDir(dir, "job-state")
 }
@@ -268,8 +277,8 @@ type jobModel struct {
 	UnitHash unit.Hash
 }
 
-// DestroyUnit removes a Job object from the repository. It does not yet remove underlying
-// UnitFiles from the repository.
+// DestroyUnit removes a Job object from the repository. The underlying UnitFiles
+// are left for garbage collection once no Unit nor recorded version uses them.
 func (r *EtcdRegistry) DestroyUnit(name string) error {
 	req := etcd.Delete{
 		Key:       path.Join(r.keyPrefix, jobPrefix, name),
@@ -285,7 +294,6 @@ func (r *EtcdRegistry) DestroyUnit(name string) error {
 		return err
 	}
 
-	// TODO(jonboulle): add unit reference counting and actually destroying Units
 	return nil
 }
 
@@ -317,9 +325,42 @@ func (r *EtcdRegistry) CreateUnit(u *job.Unit) (err error) {
 		return
 	}
 
+	if len(u.Labels) > 0 {
+		if err = r.SetUnitLabels(u.Name, u.Labels); err != nil {
+			return
+		}
+	}
 	return r.SetUnitTargetState(u.Name, u.TargetState)
 }
 
+// ReplaceUnit stores the unit file of the given Unit in place of that of the
+// existing Unit of the same name, leaving its target state and schedule
+// alone. It fails if the Unit does not exist.
+func (r *EtcdRegistry) ReplaceUnit(u *job.Unit) error {
+	if err := r.storeOrGetUnitFile(u.Unit); err != nil {
+		return err
+	}
+
+	jm := jobModel{
+		Name:     u.Name,
+		UnitHash: u.Unit.Hash(),
+	}
+	json, err := marshal(jm)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Update{
+		Key:   path.Join(r.keyPrefix, jobPrefix, u.Name, "object"),
+		Value: json,
+	}
+	_, err = r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = errors.New("job does not exist")
+	}
+	return err
+}
+
 func (r *EtcdRegistry) SetUnitTargetState(name string, state job.JobState) error {
 	req := etcd.Set{
 		Key:   r.jobTargetStatePath(name),
@@ -329,6 +370,32 @@ func (r *EtcdRegistry) SetUnitTargetState(name string, state job.JobState) error
 	return err
 }
 
+// SetUnitLabels replaces the labels of the named Unit with the given ones,
+// removing them all if none are given
+func (r *EtcdRegistry) SetUnitLabels(name string, labels map[string]string) error {
+	if len(labels) == 0 {
+		req := etcd.Delete{
+			Key: r.jobLabelsPath(name),
+		}
+		_, err := r.etcd.Do(&req)
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return err
+	}
+
+	json, err := marshal(labels)
+	if err != nil {
+		return err
+	}
+	req := etcd.Set{
+		Key:   r.jobLabelsPath(name),
+		Value: json,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
 func (r *EtcdRegistry) ScheduleUnit(name string, machID string) error {
 	req := etcd.Create{
 		Key:   r.jobTargetAgentPath(name),
@@ -342,6 +409,10 @@ func (r *EtcdRegistry) jobTargetAgentPath(jobName string) string {
 	return path.Join(r.keyPrefix, jobPrefix, jobName, "target")
 }
 
+func (r *EtcdRegistry) jobLabelsPath(jobName string) string {
+	return path.Join(r.keyPrefix, jobPrefix, jobName, "labels")
+}
+
 func (r *EtcdRegistry) jobTargetStatePath(jobName string) string {
 	return path.Join(r.keyPrefix, jobPrefix, jobName, "target-state")
 }
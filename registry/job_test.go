@@ -0,0 +1,89 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestReplaceUnit(t *testing.T) {
+	uf, err := unit.NewUnitFile("[Service]\nExecStart=/bin/true")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u := job.Unit{Name: "foo.service", Unit: *uf}
+
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.ReplaceUnit(&u); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// a unit file already stored is reused, but the Unit must exist
+	e = &testEtcdClient{err: []error{
+		etcd.Error{ErrorCode: etcd.ErrorNodeExist},
+		etcd.Error{ErrorCode: etcd.ErrorKeyNotFound},
+	}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.ReplaceUnit(&u); err == nil || err.Error() != "job does not exist" {
+		t.Errorf("Expected error replacing missing Unit, got %v", err)
+	}
+	if e.ei != 2 {
+		t.Errorf("Expected 2 requests, made %d", e.ei)
+	}
+}
+
+func TestSetUnitLabels(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	if err := r.SetUnitLabels("foo.service", map[string]string{"app": "web"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []action{action{key: "/fleet/job/foo.service/labels", val: `{"app":"web"}`}}
+	if !reflect.DeepEqual(e.sets, want) {
+		t.Errorf("Unexpected sets:\ngot\n%#v\nwant\n%#v", e.sets, want)
+	}
+
+	// removing all labels tolerates there being none
+	e = &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.SetUnitLabels("foo.service", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = []action{action{key: "/fleet/job/foo.service/labels"}}
+	if !reflect.DeepEqual(e.deletes, want) {
+		t.Errorf("Unexpected deletes:\ngot\n%#v\nwant\n%#v", e.deletes, want)
+	}
+}
+
+func TestDirToUnitLabels(t *testing.T) {
+	uf, err := unit.NewUnitFile("[Service]\nExecStart=/bin/true")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	obj, _ := marshal(jobModel{Name: "foo.service", UnitHash: uf.Hash()})
+	um, _ := marshal(unitModel{Raw: uf.String()})
+	dir := etcd.Node{
+		Key: "/fleet/job/foo.service",
+		Nodes: []etcd.Node{
+			etcd.Node{Key: "/fleet/job/foo.service/object", Value: obj},
+			etcd.Node{Key: "/fleet/job/foo.service/labels", Value: `{"app":"web","env":"staging"}`},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{
+		&etcd.Result{Node: &etcd.Node{Key: "/fleet/unit/" + uf.Hash().String(), Value: um}},
+	}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	u, err := r.dirToUnit(&dir)
+	if err != nil || u == nil {
+		t.Fatalf("Failed parsing Unit: %v", err)
+	}
+	if want := map[string]string{"app": "web", "env": "staging"}; !reflect.DeepEqual(u.Labels, want) {
+		t.Errorf("Unexpected labels %v, want %v", u.Labels, want)
+	}
+}
@@ -2,15 +2,21 @@ package registry
 
 import (
 	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
 	"github.com/coreos/fleet/machine"
 )
 
 const (
 	machinePrefix = "machines"
+
+	// values stored under a machine's cordon key
+	cordonValue = "cordon"
+	drainValue  = "drain"
 )
 
 func (r *EtcdRegistry) Machines() (machines []machine.MachineState, err error) {
@@ -29,24 +35,65 @@ func (r *EtcdRegistry) Machines() (machines []machine.MachineState, err error) {
 	}
 
 	for _, node := range resp.Node.Nodes {
+		var mach *machine.MachineState
+		var cordon string
+		var taints []machine.Taint
 		for _, obj := range node.Nodes {
+			if strings.HasSuffix(obj.Key, "/cordon") {
+				cordon = obj.Value
+				continue
+			}
+			if strings.HasSuffix(obj.Key, "/taints") {
+				taints = readTaints(obj.Nodes)
+				continue
+			}
 			if !strings.HasSuffix(obj.Key, "/object") {
 				continue
 			}
 
-			var mach machine.MachineState
-			err = unmarshal(obj.Value, &mach)
+			mach = &machine.MachineState{}
+			err = unmarshal(obj.Value, mach)
 			if err != nil {
 				return
 			}
+		}
 
-			machines = append(machines, mach)
+		if mach == nil {
+			continue
 		}
+
+		mach.Draining = cordon == drainValue
+		mach.Cordoned = mach.Draining || cordon == cordonValue
+		mach.Taints = taints
+		mach.ApplyMaintenance(time.Now())
+		machines = append(machines, *mach)
 	}
 
 	return
 }
 
+// readTaints decodes the Taints stored in the given nodes, ordered by key,
+// disregarding any it fails to decode
+func readTaints(nodes []etcd.Node) []machine.Taint {
+	var taints []machine.Taint
+	for _, node := range nodes {
+		var t machine.Taint
+		if err := unmarshal(node.Value, &t); err != nil {
+			log.Errorf("Error unmarshaling Taint(%s): %v", node.Key, err)
+			continue
+		}
+		taints = append(taints, t)
+	}
+	sort.Sort(taintsByKey(taints))
+	return taints
+}
+
+type taintsByKey []machine.Taint
+
+func (tk taintsByKey) Len() int           { return len(tk) }
+func (tk taintsByKey) Less(i, j int) bool { return tk[i].Key < tk[j].Key }
+func (tk taintsByKey) Swap(i, j int)      { tk[i], tk[j] = tk[j], tk[i] }
+
 func (r *EtcdRegistry) SetMachineState(ms machine.MachineState, ttl time.Duration) (uint64, error) {
 	json, err := marshal(ms)
 	if err != nil {
@@ -80,6 +127,116 @@ func (r *EtcdRegistry) SetMachineState(ms machine.MachineState, ttl time.Duratio
 	return resp.Node.ModifiedIndex, nil
 }
 
+// MachineMetadata returns the metadata set for the identified machine at
+// runtime, keyed by metadata key.
+func (r *EtcdRegistry) MachineMetadata(machID string) (map[string]string, error) {
+	req := etcd.Get{
+		Key:       r.machineMetadataPath(machID),
+		Recursive: true,
+	}
+
+	metadata := make(map[string]string)
+	resp, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return metadata, err
+	}
+
+	for _, node := range resp.Node.Nodes {
+		metadata[path.Base(node.Key)] = node.Value
+	}
+
+	return metadata, nil
+}
+
+// SetMachineMetadata sets the value of a metadata key for the identified
+// machine. Unlike the machine's own state, the value does not expire and
+// takes precedence over the metadata the machine was configured with.
+func (r *EtcdRegistry) SetMachineMetadata(machID, key, value string) error {
+	req := etcd.Set{
+		Key:   path.Join(r.machineMetadataPath(machID), key),
+		Value: value,
+	}
+	_, err := r.etcd.Do(&req)
+	return err
+}
+
+// DeleteMachineMetadata removes a metadata key previously set for the
+// identified machine through SetMachineMetadata.
+func (r *EtcdRegistry) DeleteMachineMetadata(machID, key string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.machineMetadataPath(machID), key),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// CordonMachine prevents new units from being scheduled to the identified
+// machine. If drain is set, the units already scheduled to it are moved to
+// other machines as well.
+func (r *EtcdRegistry) CordonMachine(machID string, drain bool) error {
+	val := cordonValue
+	if drain {
+		val = drainValue
+	}
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, machinePrefix, machID, "cordon"),
+		Value: val,
+	}
+	_, err := r.etcd.Do(&req)
+	return err
+}
+
+// UncordonMachine allows units to be scheduled to the identified machine
+// again after it was cordoned or drained.
+func (r *EtcdRegistry) UncordonMachine(machID string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, machinePrefix, machID, "cordon"),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// TaintMachine sets a Taint on the identified machine, replacing any Taint
+// with the same key.
+func (r *EtcdRegistry) TaintMachine(machID string, t machine.Taint) error {
+	val, err := marshal(t)
+	if err != nil {
+		return err
+	}
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, machinePrefix, machID, "taints", t.Key),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// UntaintMachine removes the Taint with the given key from the identified
+// machine.
+func (r *EtcdRegistry) UntaintMachine(machID, key string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, machinePrefix, machID, "taints", key),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+func (r *EtcdRegistry) machineMetadataPath(machID string) string {
+	return path.Join(r.keyPrefix, machinePrefix, machID, "metadata")
+}
+
 func (r *EtcdRegistry) RemoveMachineState(machID string) error {
 	req := etcd.Delete{
 		Key: path.Join(r.keyPrefix, machinePrefix, machID, "object"),
@@ -0,0 +1,187 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/machine"
+)
+
+func TestMachinesCordoned(t *testing.T) {
+	node := func(id, cordon string) etcd.Node {
+		n := etcd.Node{
+			Key: "/fleet/machines/" + id,
+			Nodes: []etcd.Node{
+				etcd.Node{
+					Key:   "/fleet/machines/" + id + "/object",
+					Value: `{"ID":"` + id + `"}`,
+				},
+				etcd.Node{
+					Key: "/fleet/machines/" + id + "/metadata",
+				},
+			},
+		}
+		if cordon != "" {
+			n.Nodes = append(n.Nodes, etcd.Node{Key: "/fleet/machines/" + id + "/cordon", Value: cordon})
+		}
+		return n
+	}
+
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/machines",
+			Nodes: []etcd.Node{
+				node("XXX", ""),
+				node("YYY", "cordon"),
+				node("ZZZ", "drain"),
+				// machines that went away are not listed
+				etcd.Node{
+					Key:   "/fleet/machines/AAA",
+					Nodes: []etcd.Node{etcd.Node{Key: "/fleet/machines/AAA/cordon", Value: "drain"}},
+				},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	machines, err := r.Machines()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []machine.MachineState{
+		machine.MachineState{ID: "XXX"},
+		machine.MachineState{ID: "YYY", Cordoned: true},
+		machine.MachineState{ID: "ZZZ", Cordoned: true, Draining: true},
+	}
+	if !reflect.DeepEqual(machines, want) {
+		t.Errorf("Unexpected machines:\ngot\n%#v\nwant\n%#v", machines, want)
+	}
+}
+
+func TestCordonMachine(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	r.CordonMachine("XXX", false)
+	r.CordonMachine("XXX", true)
+	r.UncordonMachine("XXX")
+
+	wantSets := []action{
+		action{key: "/fleet/machines/XXX/cordon", val: "cordon"},
+		action{key: "/fleet/machines/XXX/cordon", val: "drain"},
+	}
+	if !reflect.DeepEqual(e.sets, wantSets) {
+		t.Errorf("Unexpected sets:\ngot\n%#v\nwant\n%#v", e.sets, wantSets)
+	}
+	wantDeletes := []action{action{key: "/fleet/machines/XXX/cordon"}}
+	if !reflect.DeepEqual(e.deletes, wantDeletes) {
+		t.Errorf("Unexpected deletes:\ngot\n%#v\nwant\n%#v", e.deletes, wantDeletes)
+	}
+}
+
+func TestMachinesTainted(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/machines",
+			Nodes: []etcd.Node{
+				etcd.Node{
+					Key: "/fleet/machines/XXX",
+					Nodes: []etcd.Node{
+						etcd.Node{Key: "/fleet/machines/XXX/object", Value: `{"ID":"XXX"}`},
+						etcd.Node{
+							Key: "/fleet/machines/XXX/taints",
+							Nodes: []etcd.Node{
+								etcd.Node{Key: "/fleet/machines/XXX/taints/maintenance", Value: `{"Key":"maintenance","Effect":"NoSchedule"}`},
+								etcd.Node{Key: "/fleet/machines/XXX/taints/broken", Value: "{"},
+								etcd.Node{Key: "/fleet/machines/XXX/taints/dedicated", Value: `{"Key":"dedicated","Value":"db","Effect":"NoSchedule"}`},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	machines, err := r.Machines()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []machine.MachineState{
+		machine.MachineState{ID: "XXX", Taints: []machine.Taint{
+			machine.Taint{Key: "dedicated", Value: "db", Effect: machine.TaintEffectNoSchedule},
+			machine.Taint{Key: "maintenance", Effect: machine.TaintEffectNoSchedule},
+		}},
+	}
+	if !reflect.DeepEqual(machines, want) {
+		t.Errorf("Unexpected machines:\ngot\n%#v\nwant\n%#v", machines, want)
+	}
+}
+
+func TestTaintMachine(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	r.TaintMachine("XXX", machine.Taint{Key: "dedicated", Value: "db", Effect: machine.TaintEffectNoSchedule})
+	r.UntaintMachine("XXX", "dedicated")
+
+	wantSets := []action{action{key: "/fleet/machines/XXX/taints/dedicated", val: `{"Key":"dedicated","Value":"db","Effect":"NoSchedule"}`}}
+	if !reflect.DeepEqual(e.sets, wantSets) {
+		t.Errorf("Unexpected sets:\ngot\n%#v\nwant\n%#v", e.sets, wantSets)
+	}
+	wantDeletes := []action{action{key: "/fleet/machines/XXX/taints/dedicated"}}
+	if !reflect.DeepEqual(e.deletes, wantDeletes) {
+		t.Errorf("Unexpected deletes:\ngot\n%#v\nwant\n%#v", e.deletes, wantDeletes)
+	}
+}
+
+func TestMachinesInMaintenance(t *testing.T) {
+	node := func(id string, start, end time.Time) etcd.Node {
+		window := start.UTC().Format(time.RFC3339) + "/" + end.UTC().Format(time.RFC3339)
+		return etcd.Node{
+			Key: "/fleet/machines/" + id,
+			Nodes: []etcd.Node{
+				etcd.Node{
+					Key:   "/fleet/machines/" + id + "/object",
+					Value: `{"ID":"` + id + `","Metadata":{"maintenance-window":"` + window + `"}}`,
+				},
+			},
+		}
+	}
+
+	now := time.Now()
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/machines",
+			Nodes: []etcd.Node{
+				node("XXX", now.Add(time.Hour), now.Add(2*time.Hour)),
+				node("YYY", now.Add(time.Minute), now.Add(time.Hour)),
+				node("ZZZ", now.Add(-time.Hour), now.Add(time.Hour)),
+				node("AAA", now.Add(-2*time.Hour), now.Add(-time.Hour)),
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	machines, err := r.Machines()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]bool{"XXX": false, "YYY": true, "ZZZ": true, "AAA": false}
+	if len(machines) != len(want) {
+		t.Fatalf("Expected %d machines, got %d", len(want), len(machines))
+	}
+	for _, ms := range machines {
+		if ms.Draining != want[ms.ID] || ms.Cordoned != want[ms.ID] {
+			t.Errorf("Machine %s: draining %t, cordoned %t, want %t", ms.ID, ms.Draining, ms.Cordoned, want[ms.ID])
+		}
+	}
+}
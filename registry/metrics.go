@@ -0,0 +1,13 @@
+package registry
+
+import (
+	"github.com/coreos/fleet/metrics"
+)
+
+var (
+	metricUnitCacheLookups = metrics.NewCounter(
+		"fleet_registry_unit_cache_lookups_total",
+		"Number of unit files looked up in the local unit cache, by result (hit or miss).",
+		"result",
+	)
+)
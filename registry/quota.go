@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	quotaPrefix = "quotas"
+)
+
+// Quota limits what the Units of a namespace may reserve. Cores are in
+// hundreds of a core and Memory in MB, like the reservations of Units.
+// Limits of zero are not enforced.
+type Quota struct {
+	Namespace string `json:"-"`
+	Cores     int    `json:",omitempty"`
+	Memory    int    `json:",omitempty"`
+	Units     int    `json:",omitempty"`
+}
+
+// QuotaUsage is what Units count against the Quota of their namespace
+type QuotaUsage struct {
+	Cores  int
+	Memory int
+	Units  int
+}
+
+// Add counts the given Job against the usage
+func (qu *QuotaUsage) Add(j *job.Job) {
+	res := j.Resources()
+	qu.Cores += res.Cores
+	qu.Memory += res.Memory
+	qu.Units++
+}
+
+// Remove no longer counts the given Job against the usage
+func (qu *QuotaUsage) Remove(j *job.Job) {
+	res := j.Resources()
+	qu.Cores -= res.Cores
+	qu.Memory -= res.Memory
+	qu.Units--
+}
+
+// Exceeded describes the first limit of the Quota the given usage exceeds,
+// or returns an empty string if it exceeds none
+func (q *Quota) Exceeded(qu QuotaUsage) string {
+	switch {
+	case q.Units > 0 && qu.Units > q.Units:
+		return fmt.Sprintf("unit quota of namespace %s exceeded: %d units of %d", q.Namespace, qu.Units, q.Units)
+	case q.Cores > 0 && qu.Cores > q.Cores:
+		return fmt.Sprintf("CPU quota of namespace %s exceeded: %d CPU units of %d", q.Namespace, qu.Cores, q.Cores)
+	case q.Memory > 0 && qu.Memory > q.Memory:
+		return fmt.Sprintf("memory quota of namespace %s exceeded: %dMB of %dMB", q.Namespace, qu.Memory, q.Memory)
+	}
+	return ""
+}
+
+// NamespaceUsage returns what the given Units count against the Quotas of
+// their namespaces, indexed by namespace
+func NamespaceUsage(units []job.Unit) map[string]QuotaUsage {
+	usage := make(map[string]QuotaUsage)
+	for _, u := range units {
+		ns, _ := job.SplitNamespace(u.Name)
+		qu := usage[ns]
+		qu.Add(&job.Job{Name: u.Name, Unit: u.Unit})
+		usage[ns] = qu
+	}
+	return usage
+}
+
+// SetQuota stores the given Quota, replacing any Quota of its namespace
+func (r *EtcdRegistry) SetQuota(q Quota) error {
+	val, err := marshal(q)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, quotaPrefix, q.Namespace),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// DeleteQuota removes the Quota of the named namespace, if any
+func (r *EtcdRegistry) DeleteQuota(namespace string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, quotaPrefix, namespace),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// Quotas returns the Quotas of all namespaces, ordered by namespace
+func (r *EtcdRegistry) Quotas() ([]Quota, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, quotaPrefix),
+	}
+
+	var quotas []Quota
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return quotas, err
+	}
+
+	for _, node := range res.Node.Nodes {
+		var q Quota
+		if err := unmarshal(node.Value, &q); err != nil {
+			log.Errorf("Ignoring invalid Quota of namespace %s: %v", path.Base(node.Key), err)
+			continue
+		}
+		q.Namespace = path.Base(node.Key)
+		quotas = append(quotas, q)
+	}
+	sort.Sort(quotasByNamespace(quotas))
+
+	return quotas, nil
+}
+
+type quotasByNamespace []Quota
+
+func (q quotasByNamespace) Len() int           { return len(q) }
+func (q quotasByNamespace) Less(i, j int) bool { return q[i].Namespace < q[j].Namespace }
+func (q quotasByNamespace) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
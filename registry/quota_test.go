@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/unit"
+)
+
+func TestQuotas(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/quotas",
+			Nodes: etcd.Nodes{
+				etcd.Node{Key: "/fleet/quotas/team-b", Value: `{"Units":10}`},
+				etcd.Node{Key: "/fleet/quotas/bogus", Value: `{`},
+				etcd.Node{Key: "/fleet/quotas/team-a", Value: `{"Cores":400,"Memory":8192}`},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.Quotas()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []Quota{
+		Quota{Namespace: "team-a", Cores: 400, Memory: 8192},
+		Quota{Namespace: "team-b", Units: 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected quotas:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	e = &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if got, err := r.Quotas(); len(got) != 0 || err != nil {
+		t.Errorf("Expected no quotas, got %v, err %v", got, err)
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	q := Quota{Namespace: "team-a", Cores: 200, Memory: 1024, Units: 2}
+	for i, tt := range []struct {
+		usage QuotaUsage
+		want  string
+	}{
+		{QuotaUsage{Cores: 200, Memory: 1024, Units: 2}, ""},
+		{QuotaUsage{Units: 3}, "unit quota of namespace team-a exceeded: 3 units of 2"},
+		{QuotaUsage{Cores: 250, Units: 1}, "CPU quota of namespace team-a exceeded: 250 CPU units of 200"},
+		{QuotaUsage{Memory: 2048, Units: 1}, "memory quota of namespace team-a exceeded: 2048MB of 1024MB"},
+	} {
+		if got := q.Exceeded(tt.usage); got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, got, tt.want)
+		}
+	}
+
+	if got := (&Quota{Namespace: "team-b"}).Exceeded(QuotaUsage{Cores: 1000, Memory: 1000, Units: 1000}); got != "" {
+		t.Errorf("Quota without limits unexpectedly exceeded: %q", got)
+	}
+}
+
+func TestNamespaceUsage(t *testing.T) {
+	uf, err := unit.NewUnitFile("[X-Fleet]\nCPUUnits=50\nMemoryReservation=128")
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit file: %v", err)
+	}
+	units := []job.Unit{
+		job.Unit{Name: "a.service", Unit: *uf},
+		job.Unit{Name: "team-a:b.service", Unit: *uf},
+		job.Unit{Name: "team-a:c.service"},
+	}
+
+	got := NamespaceUsage(units)
+	want := map[string]QuotaUsage{
+		job.DefaultNamespace: QuotaUsage{Cores: 50, Memory: 128, Units: 1},
+		"team-a":             QuotaUsage{Cores: 50, Memory: 128, Units: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected usage: got %v, want %v", got, want)
+	}
+}
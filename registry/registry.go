@@ -13,10 +13,12 @@ const DefaultKeyPrefix = "/_coreos.com/fleet/"
 type EtcdRegistry struct {
 	etcd      etcd.Client
 	keyPrefix string
+	keyring   *Keyring
+	unitCache *UnitCache
 }
 
 func NewEtcdRegistry(client etcd.Client, keyPrefix string) *EtcdRegistry {
-	return &EtcdRegistry{client, keyPrefix}
+	return &EtcdRegistry{client, keyPrefix, nil, nil}
 }
 
 func marshal(obj interface{}) (string, error) {
@@ -44,3 +46,8 @@ func isNodeExist(err error) bool {
 	e, ok := err.(etcd.Error)
 	return ok && e.ErrorCode == etcd.ErrorNodeExist
 }
+
+func isTestFailed(err error) bool {
+	e, ok := err.(etcd.Error)
+	return ok && e.ErrorCode == etcd.ErrorTestFailed
+}
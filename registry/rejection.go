@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"path"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/job"
+)
+
+const (
+	rejectionPrefix = "rejection"
+)
+
+// UnitRejections records why the engine was last unable to schedule a Unit
+type UnitRejections struct {
+	Time time.Time
+	// Reason is why the Unit could not be scheduled to any machine
+	Reason string
+	// Machines holds the reason each machine was unable to run the Unit,
+	// indexed by machine ID
+	Machines map[string]string `json:",omitempty"`
+	// Shortfalls holds, if known, each constraint of the Unit that each
+	// machine failed to meet, indexed by machine ID
+	Shortfalls map[string][]job.Shortfall `json:",omitempty"`
+	// BackoffUntil is, if set, until when the rescheduling of the Unit
+	// is held back because it was moved between machines too often
+	BackoffUntil *time.Time `json:",omitempty"`
+}
+
+// SaveUnitRejections records why the named Unit could not be scheduled. The
+// record expires after the given TTL.
+func (r *EtcdRegistry) SaveUnitRejections(name string, rej UnitRejections, ttl time.Duration) error {
+	val, err := marshal(rej)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, rejectionPrefix, name),
+		Value: val,
+		TTL:   ttl,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// ClearUnitRejections removes the record of why the named Unit could not be
+// scheduled, e.g. once it has been scheduled
+func (r *EtcdRegistry) ClearUnitRejections(name string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, rejectionPrefix, name),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// UnitRejections returns the latest record of why the named Unit could not be
+// scheduled, or nil if there is none
+func (r *EtcdRegistry) UnitRejections(name string) (*UnitRejections, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, rejectionPrefix, name),
+	}
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+
+	var rej UnitRejections
+	if err := unmarshal(res.Node.Value, &rej); err != nil {
+		return nil, err
+	}
+	return &rej, nil
+}
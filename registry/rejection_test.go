@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestSaveUnitRejections(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	rej := UnitRejections{
+		Time:     time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC),
+		Reason:   "no agents able to run job",
+		Machines: map[string]string{"XXX": "local Machine metadata insufficient"},
+	}
+	if err := r.SaveUnitRejections("foo.service", rej, time.Minute); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []action{action{key: "/fleet/rejection/foo.service", val: `{"Time":"2014-10-01T12:00:00Z","Reason":"no agents able to run job","Machines":{"XXX":"local Machine metadata insufficient"}}`}}
+	if !reflect.DeepEqual(e.sets, want) {
+		t.Errorf("Unexpected sets:\ngot\n%#v\nwant\n%#v", e.sets, want)
+	}
+}
+
+func TestUnitRejections(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key:   "/fleet/rejection/foo.service",
+			Value: `{"Time":"2014-10-01T12:00:00Z","Reason":"no agents able to run job","Machines":{"XXX":"local Machine metadata insufficient"}}`,
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.UnitRejections("foo.service")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &UnitRejections{
+		Time:     time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC),
+		Reason:   "no agents able to run job",
+		Machines: map[string]string{"XXX": "local Machine metadata insufficient"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected rejections:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	e = &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if got, err := r.UnitRejections("foo.service"); got != nil || err != nil {
+		t.Errorf("Expected no rejections, got %v, err %v", got, err)
+	}
+}
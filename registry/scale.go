@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	scalePrefix = "scale"
+)
+
+// SetUnitScale records the number of instances the engine should maintain
+// of the named template Unit.
+func (r *EtcdRegistry) SetUnitScale(tmpl string, count int) error {
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, scalePrefix, tmpl),
+		Value: strconv.Itoa(count),
+	}
+	_, err := r.etcd.Do(&req)
+	return err
+}
+
+// UnitScales returns the number of instances to maintain of each scaled
+// template Unit, indexed by template name.
+func (r *EtcdRegistry) UnitScales() (map[string]int, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, scalePrefix),
+	}
+
+	scales := make(map[string]int)
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return scales, err
+	}
+
+	for _, node := range res.Node.Nodes {
+		tmpl := path.Base(node.Key)
+		count, err := strconv.Atoi(node.Value)
+		if err != nil {
+			log.Errorf("Ignoring invalid instance count %q of template Unit(%s)", node.Value, tmpl)
+			continue
+		}
+		scales[tmpl] = count
+	}
+
+	return scales, nil
+}
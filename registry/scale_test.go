@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestSetUnitScale(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	r.SetUnitScale("foo@.service", 3)
+
+	want := []action{action{key: "/fleet/scale/foo@.service", val: "3"}}
+	if !reflect.DeepEqual(e.sets, want) {
+		t.Errorf("Unexpected sets:\ngot\n%#v\nwant\n%#v", e.sets, want)
+	}
+}
+
+func TestUnitScales(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/scale",
+			Nodes: []etcd.Node{
+				etcd.Node{Key: "/fleet/scale/foo@.service", Value: "3"},
+				etcd.Node{Key: "/fleet/scale/bar@.service", Value: "0"},
+				etcd.Node{Key: "/fleet/scale/baz@.service", Value: "many"},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	scales, err := r.UnitScales()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]int{"foo@.service": 3, "bar@.service": 0}
+	if !reflect.DeepEqual(scales, want) {
+		t.Errorf("Unexpected scales:\ngot\n%#v\nwant\n%#v", scales, want)
+	}
+}
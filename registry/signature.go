@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"path"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	signaturePrefix = "signature"
+)
+
+// SetUnitSignature records the signature of the unit file of the given
+// Hash. As unit files are addressed by their Hash, the signature covers
+// every Unit using the unit file.
+func (r *EtcdRegistry) SetUnitSignature(hash unit.Hash, sig string) error {
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, signaturePrefix, hash.String()),
+		Value: sig,
+	}
+	_, err := r.etcd.Do(&req)
+	return err
+}
+
+// UnitSignature returns the signature recorded for the unit file of the
+// given Hash, or an empty string if it has none.
+func (r *EtcdRegistry) UnitSignature(hash unit.Hash) (string, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, signaturePrefix, hash.String()),
+	}
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return "", err
+	}
+	return res.Node.Value, nil
+}
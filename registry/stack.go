@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"errors"
+	"path"
+	"sort"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	stackPrefix = "stacks"
+)
+
+// ErrStackExists is returned when creating a Stack with the name of an
+// existing Stack
+var ErrStackExists = errors.New("stack already exists")
+
+// Stack is a group of Units submitted and destroyed together. The engine
+// schedules the unscheduled Units of a Stack all at once or not at all.
+type Stack struct {
+	Name  string
+	Units []string
+}
+
+// CreateStack records the given Stack, failing if a Stack of the same name
+// exists
+func (r *EtcdRegistry) CreateStack(s *Stack) error {
+	val, err := marshal(s)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Create{
+		Key:   path.Join(r.keyPrefix, stackPrefix, s.Name),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	if isNodeExist(err) {
+		err = ErrStackExists
+	}
+	return err
+}
+
+// DestroyStack removes the record of the named Stack, leaving its Units
+// alone
+func (r *EtcdRegistry) DestroyStack(name string) error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, stackPrefix, name),
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// Stack returns the named Stack, or nil if it does not exist
+func (r *EtcdRegistry) Stack(name string) (*Stack, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, stackPrefix, name),
+	}
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+
+	var s Stack
+	if err := unmarshal(res.Node.Value, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Stacks returns all Stacks, ordered by name
+func (r *EtcdRegistry) Stacks() ([]Stack, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, stackPrefix),
+	}
+
+	var stacks []Stack
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return stacks, err
+	}
+
+	for _, node := range res.Node.Nodes {
+		var s Stack
+		if err := unmarshal(node.Value, &s); err != nil {
+			log.Errorf("Ignoring invalid Stack(%s): %v", path.Base(node.Key), err)
+			continue
+		}
+		stacks = append(stacks, s)
+	}
+	sort.Sort(stacksByName(stacks))
+
+	return stacks, nil
+}
+
+type stacksByName []Stack
+
+func (s stacksByName) Len() int           { return len(s) }
+func (s stacksByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s stacksByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestStacks(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/stacks",
+			Nodes: etcd.Nodes{
+				etcd.Node{Key: "/fleet/stacks/web", Value: `{"Name":"web","Units":["web@1.service","db.service"]}`},
+				etcd.Node{Key: "/fleet/stacks/bogus", Value: `{`},
+				etcd.Node{Key: "/fleet/stacks/cache", Value: `{"Name":"cache","Units":["cache.service"]}`},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.Stacks()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []Stack{
+		Stack{Name: "cache", Units: []string{"cache.service"}},
+		Stack{Name: "web", Units: []string{"web@1.service", "db.service"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected stacks:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	e = &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if got, err := r.Stacks(); len(got) != 0 || err != nil {
+		t.Errorf("Expected no stacks, got %v, err %v", got, err)
+	}
+}
+
+func TestCreateStackExists(t *testing.T) {
+	e := &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorNodeExist}}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	if err := r.CreateStack(&Stack{Name: "web"}); err != ErrStackExists {
+		t.Errorf("Expected ErrStackExists, got %v", err)
+	}
+}
+
+func TestStackNotFound(t *testing.T) {
+	e := &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	if got, err := r.Stack("web"); got != nil || err != nil {
+		t.Errorf("Expected no stack, got %v, err %v", got, err)
+	}
+}
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"fmt"
 	"path"
 
 	"github.com/coreos/fleet/etcd"
@@ -21,6 +22,10 @@ func (r *EtcdRegistry) storeOrGetUnitFile(u unit.UnitFile) (err error) {
 	if err != nil {
 		return err
 	}
+	json, err = r.seal(json)
+	if err != nil {
+		return err
+	}
 
 	req := etcd.Create{
 		Key:   r.hashedUnitPath(u.Hash()),
@@ -35,8 +40,15 @@ func (r *EtcdRegistry) storeOrGetUnitFile(u unit.UnitFile) (err error) {
 	return
 }
 
-// getUnitByHash retrieves from the Registry the Unit associated with the given Hash
-func (r *EtcdRegistry) getUnitByHash(hash unit.Hash) *unit.UnitFile {
+// UnitFile retrieves from the Registry the unit file of the given Hash.
+// Returns nil if no such unit file exists, and any error encountered.
+func (r *EtcdRegistry) UnitFile(hash unit.Hash) (*unit.UnitFile, error) {
+	if r.unitCache != nil {
+		if u := r.unitCache.Get(hash); u != nil {
+			return u, nil
+		}
+	}
+
 	req := etcd.Get{
 		Key:       r.hashedUnitPath(hash),
 		Recursive: true,
@@ -46,20 +58,36 @@ func (r *EtcdRegistry) getUnitByHash(hash unit.Hash) *unit.UnitFile {
 		if isKeyNotFound(err) {
 			err = nil
 		}
-		return nil
+		return nil, err
+	}
+	val, err := r.open(resp.Node.Value)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting Unit(%s): %v", hash, err)
 	}
 	var um unitModel
-	if err := unmarshal(resp.Node.Value, &um); err != nil {
-		log.Errorf("error unmarshaling Unit(%s): %v", hash, err)
-		return nil
+	if err := unmarshal(val, &um); err != nil {
+		return nil, fmt.Errorf("error unmarshaling Unit(%s): %v", hash, err)
 	}
 
 	u, err := unit.NewUnitFile(um.Raw)
 	if err != nil {
-		log.Errorf("error parsing Unit(%s): %v", hash, err)
-		return nil
+		return nil, fmt.Errorf("error parsing Unit(%s): %v", hash, err)
 	}
 
+	if r.unitCache != nil {
+		if err := r.unitCache.Put(u); err != nil {
+			log.Errorf("Failed caching Unit(%s): %v", hash, err)
+		}
+	}
+	return u, nil
+}
+
+// getUnitByHash retrieves from the Registry the Unit associated with the given Hash
+func (r *EtcdRegistry) getUnitByHash(hash unit.Hash) *unit.UnitFile {
+	u, err := r.UnitFile(hash)
+	if err != nil {
+		log.Errorf("Failed fetching Unit(%s): %v", hash, err)
+	}
 	return u
 }
 
@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	// unitCachePruneInterval is how often unit files no longer read are
+	// removed from a UnitCache
+	unitCachePruneInterval = 24 * time.Hour
+)
+
+// UnitCache keeps the unit files read from the Registry in a local
+// directory, one file per unit file named after its hash. As the hash
+// identifies the contents of a unit file, cached unit files never go stale,
+// and reading them locally rather than from etcd spares it the reads of all
+// unit files by every agent, e.g. when many units are rescheduled at once
+// after a machine failure or when fleetd restarts.
+type UnitCache struct {
+	dir string
+
+	mutex sync.Mutex
+	// used holds the hashes of the unit files read since the cache was
+	// last pruned
+	used map[unit.Hash]bool
+}
+
+// NewUnitCache creates a UnitCache keeping unit files in the given
+// directory, creating it if necessary
+func NewUnitCache(dir string) (*UnitCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &UnitCache{dir: dir, used: make(map[unit.Hash]bool)}, nil
+}
+
+// SetUnitCache has unit files read from the given UnitCache, falling back
+// to etcd for those not cached yet
+func (r *EtcdRegistry) SetUnitCache(c *UnitCache) {
+	r.unitCache = c
+}
+
+// Get returns the cached unit file of the given hash, or nil if it is not
+// cached. Cached unit files whose contents do not match their hash, e.g.
+// because they were only partially written, are removed.
+func (c *UnitCache) Get(hash unit.Hash) *unit.UnitFile {
+	raw, err := ioutil.ReadFile(c.path(hash))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Failed reading Unit(%s) from cache: %v", hash, err)
+		}
+		metricUnitCacheLookups.Inc("miss")
+		return nil
+	}
+
+	uf, err := unit.NewUnitFile(string(raw))
+	if err != nil || uf.Hash() != hash {
+		log.Warningf("Removing corrupt Unit(%s) from cache", hash)
+		os.Remove(c.path(hash))
+		metricUnitCacheLookups.Inc("miss")
+		return nil
+	}
+
+	c.mutex.Lock()
+	c.used[hash] = true
+	c.mutex.Unlock()
+	metricUnitCacheLookups.Inc("hit")
+	return uf
+}
+
+// Put adds the given unit file to the cache
+func (c *UnitCache) Put(uf *unit.UnitFile) error {
+	hash := uf.Hash()
+	tmp, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(uf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(hash))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mutex.Lock()
+	c.used[hash] = true
+	c.mutex.Unlock()
+	return nil
+}
+
+// Run prunes the cache periodically until the stop channel is closed
+func (c *UnitCache) Run(stop chan bool) {
+	ticker := time.NewTicker(unitCachePruneInterval)
+	for {
+		select {
+		case <-stop:
+			log.V(1).Info("Halting UnitCache")
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			c.prune()
+		}
+	}
+}
+
+// prune removes the unit files not read since the cache was last pruned,
+// e.g. those of destroyed Units
+func (c *UnitCache) prune() {
+	c.mutex.Lock()
+	used := c.used
+	c.used = make(map[unit.Hash]bool)
+	c.mutex.Unlock()
+
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		log.Errorf("Failed pruning unit cache: %v", err)
+		return
+	}
+	for _, fi := range files {
+		hash, err := unit.ParseHash(fi.Name())
+		if err == nil && used[hash] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, fi.Name())); err != nil {
+			log.Errorf("Failed pruning %s from unit cache: %v", fi.Name(), err)
+		}
+	}
+}
+
+func (c *UnitCache) path(hash unit.Hash) string {
+	return filepath.Join(c.dir, hash.String())
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/unit"
+)
+
+func newTestUnitCache(t *testing.T) (*UnitCache, string) {
+	dir, err := ioutil.TempDir(os.TempDir(), "fleet-")
+	if err != nil {
+		t.Fatalf("Failed creating tempdir: %v", err)
+	}
+	c, err := NewUnitCache(filepath.Join(dir, "units"))
+	if err != nil {
+		t.Fatalf("Failed creating unit cache: %v", err)
+	}
+	return c, dir
+}
+
+func TestUnitCache(t *testing.T) {
+	c, dir := newTestUnitCache(t)
+	defer os.RemoveAll(dir)
+
+	uf, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/sleep 100\n")
+	if got := c.Get(uf.Hash()); got != nil {
+		t.Fatalf("Unexpected unit file in empty cache: %v", got)
+	}
+	if err := c.Put(uf); err != nil {
+		t.Fatalf("Failed caching unit file: %v", err)
+	}
+	if got := c.Get(uf.Hash()); got == nil || got.Hash() != uf.Hash() {
+		t.Fatalf("Unexpected cached unit file: %v", got)
+	}
+
+	// unit files not matching their hash are dropped
+	other, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/sleep 200\n")
+	if err := ioutil.WriteFile(c.path(other.Hash()), uf.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed writing unit file: %v", err)
+	}
+	if got := c.Get(other.Hash()); got != nil {
+		t.Errorf("Corrupt unit file returned: %v", got)
+	}
+	if _, err := os.Stat(c.path(other.Hash())); !os.IsNotExist(err) {
+		t.Errorf("Corrupt unit file not removed: %v", err)
+	}
+
+	// unit files not read since the last prune are removed
+	c.prune()
+	if got := c.Get(uf.Hash()); got == nil {
+		t.Fatalf("Unit file read before prune removed")
+	}
+	c.prune()
+	c.prune()
+	if got := c.Get(uf.Hash()); got != nil {
+		t.Errorf("Unit file unused since last prune not removed")
+	}
+}
+
+func TestUnitFileCached(t *testing.T) {
+	c, dir := newTestUnitCache(t)
+	defer os.RemoveAll(dir)
+
+	uf, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/sleep 100\n")
+	raw, _ := marshal(unitModel{Raw: uf.String()})
+	e := &testEtcdClient{res: []*etcd.Result{{Node: &etcd.Node{Value: raw}}}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	r.SetUnitCache(c)
+
+	for i := 0; i < 2; i++ {
+		got, err := r.UnitFile(uf.Hash())
+		if err != nil || got == nil || got.Hash() != uf.Hash() {
+			t.Fatalf("read %d: unexpected unit file %v (err=%v)", i, got, err)
+		}
+	}
+	if len(e.gets) != 1 {
+		t.Errorf("Expected unit file to be read from etcd once, got %d reads", len(e.gets))
+	}
+
+	// unit files missing from etcd are not cached
+	missing, _ := unit.NewUnitFile("[Service]\nExecStart=/bin/false\n")
+	e.err = []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}
+	e.ei = 0
+	if got, err := r.UnitFile(missing.Hash()); got != nil || err != nil {
+		t.Errorf("Unexpected result for missing unit file: %v (err=%v)", got, err)
+	}
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"path"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	unitHistoryPrefix = "unit-history"
+
+	// unitHistoryLimit is the number of ClusterEvents kept in the history
+	// of each Unit
+	unitHistoryLimit = 50
+)
+
+// recordUnitHistory adds the given ClusterEvent to the history of the Unit
+// it concerns, dropping the oldest ClusterEvents beyond the latest 50. Unlike
+// the cluster's event log, the history does not expire, so that it survives
+// for post-mortems.
+func (r *EtcdRegistry) recordUnitHistory(ev ClusterEvent) error {
+	val, err := marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.CreateInOrder{
+		Dir:   r.unitHistoryPath(ev.UnitName),
+		Value: val,
+	}
+	if _, err := r.etcd.Do(&req); err != nil {
+		return err
+	}
+
+	nodes, err := r.unitHistoryNodes(ev.UnitName)
+	if err != nil {
+		return err
+	}
+	for len(nodes) > unitHistoryLimit {
+		req := etcd.Delete{
+			Key: nodes[0].Key,
+		}
+		if _, err := r.etcd.Do(&req); err != nil && !isKeyNotFound(err) {
+			return err
+		}
+		nodes = nodes[1:]
+	}
+	return nil
+}
+
+// UnitHistory returns the latest ClusterEvents concerning the named Unit,
+// oldest first, even if they are no longer in the cluster's event log or
+// the Unit no longer exists
+func (r *EtcdRegistry) UnitHistory(name string) ([]ClusterEvent, error) {
+	nodes, err := r.unitHistoryNodes(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ClusterEvent
+	for _, node := range nodes {
+		ev, err := nodeToClusterEvent(node)
+		if err != nil {
+			log.Errorf("Failed parsing ClusterEvent from %s: %v", node.Key, err)
+			continue
+		}
+		events = append(events, *ev)
+	}
+	return events, nil
+}
+
+// unitHistoryNodes returns the nodes holding the history of the named Unit,
+// oldest first
+func (r *EtcdRegistry) unitHistoryNodes(name string) (etcd.Nodes, error) {
+	req := etcd.Get{
+		Key:    r.unitHistoryPath(name),
+		Sorted: true,
+	}
+
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+	return res.Node.Nodes, nil
+}
+
+func (r *EtcdRegistry) unitHistoryPath(name string) string {
+	return path.Join(r.keyPrefix, unitHistoryPrefix, name)
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestRecordEventUnitHistory(t *testing.T) {
+	var nodes []etcd.Node
+	for i := 1; i <= unitHistoryLimit+2; i++ {
+		nodes = append(nodes, etcd.Node{
+			Key:          fmt.Sprintf("/fleet/unit-history/foo.service/%020d", i),
+			Value:        `{"Type":"UnitStateChanged","UnitName":"foo.service"}`,
+			CreatedIndex: uint64(i),
+		})
+	}
+	history := etcd.Result{Node: &etcd.Node{Key: "/fleet/unit-history/foo.service", Nodes: nodes}}
+
+	// the event log and the history are created in order, after which
+	// the history is trimmed to its limit
+	e := &testEtcdClient{res: []*etcd.Result{nil, nil, &history}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.RecordEvent(ClusterEvent{Type: EventUnitStateChanged, UnitName: "foo.service"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantDeletes := []action{
+		action{key: "/fleet/unit-history/foo.service/00000000000000000001"},
+		action{key: "/fleet/unit-history/foo.service/00000000000000000002"},
+	}
+	if !reflect.DeepEqual(e.deletes, wantDeletes) {
+		t.Errorf("Unexpected deletes:\ngot\n%#v\nwant\n%#v", e.deletes, wantDeletes)
+	}
+
+	// events concerning no Unit have no history
+	e = &testEtcdClient{}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.RecordEvent(ClusterEvent{Type: EventMachineLeft, MachineID: "XXX"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(e.gets) != 0 {
+		t.Errorf("Unexpected gets: %#v", e.gets)
+	}
+}
+
+func TestUnitHistory(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/unit-history/foo.service",
+			Nodes: []etcd.Node{
+				etcd.Node{
+					Key:          "/fleet/unit-history/foo.service/00000000000000000011",
+					Value:        `{"Time":"2014-10-01T12:00:00Z","Type":"UnitScheduled","UnitName":"foo.service","MachineID":"XXX"}`,
+					CreatedIndex: 11,
+				},
+				etcd.Node{
+					Key:          "/fleet/unit-history/foo.service/00000000000000000012",
+					Value:        `garbage`,
+					CreatedIndex: 12,
+				},
+				etcd.Node{
+					Key:          "/fleet/unit-history/foo.service/00000000000000000014",
+					Value:        `{"Time":"2014-10-01T12:00:05Z","Type":"UnitStateChanged","UnitName":"foo.service","MachineID":"XXX","Reason":"activating/start -> active/running"}`,
+					CreatedIndex: 14,
+				},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.UnitHistory("foo.service")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []ClusterEvent{
+		ClusterEvent{Index: 11, Time: time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC), Type: EventUnitScheduled, UnitName: "foo.service", MachineID: "XXX"},
+		ClusterEvent{Index: 14, Time: time.Date(2014, 10, 1, 12, 0, 5, 0, time.UTC), Type: EventUnitStateChanged, UnitName: "foo.service", MachineID: "XXX", Reason: "activating/start -> active/running"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected history:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+
+	wantGets := []action{action{key: "/fleet/unit-history/foo.service"}}
+	if !reflect.DeepEqual(e.gets, wantGets) {
+		t.Errorf("Unexpected gets:\ngot\n%#v\nwant\n%#v", e.gets, wantGets)
+	}
+}
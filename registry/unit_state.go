@@ -16,6 +16,9 @@ const (
 	statePrefix = "/state/"
 	// Namespace for unit states stored per-machine
 	statesPrefix = "/states/"
+	// Namespace for the unit states of each machine, stored as a single
+	// document per machine
+	machineStatesPrefix = "/states-by-machine/"
 )
 
 // legacyUnitStatePath returns the path where UnitState objects were formerly
@@ -37,6 +40,12 @@ func (r *EtcdRegistry) unitStatePath(machID, jobName string) string {
 	return path.Join(r.unitStatesNamespace(jobName), machID)
 }
 
+// machineStatesPath generates a keypath where the UnitStates of all units on
+// a given machine are stored
+func (r *EtcdRegistry) machineStatesPath(machID string) string {
+	return path.Join(r.keyPrefix, machineStatesPrefix, machID)
+}
+
 // UnitStates returns a list of all UnitStates stored in the registry, sorted
 // by unit name and then machine ID.
 func (r *EtcdRegistry) UnitStates() (states []*unit.UnitState, err error) {
@@ -133,6 +142,33 @@ func (r *EtcdRegistry) statesByMUSKey() (map[MUSKey]*unit.UnitState, error) {
 			}
 		}
 	}
+
+	// Finally, overlay the states published by each machine as a whole
+	req = etcd.Get{
+		Key:       path.Join(r.keyPrefix, machineStatesPrefix),
+		Recursive: true,
+	}
+	res, err = r.etcd.Do(&req)
+	if err != nil && !isKeyNotFound(err) {
+		return nil, err
+	}
+	if res != nil {
+		for _, node := range res.Node.Nodes {
+			_, machID := path.Split(node.Key)
+			var usms map[string]*unitStateModel
+			if err := unmarshal(node.Value, &usms); err != nil {
+				log.Errorf("Error unmarshalling UnitStates of Machine(%s): %v", machID, err)
+				continue
+			}
+			for name, usm := range usms {
+				us := modelToUnitState(usm, name)
+				if us != nil {
+					key := MUSKey{name, machID}
+					mus[key] = us
+				}
+			}
+		}
+	}
 	return mus, nil
 }
 
@@ -192,6 +228,40 @@ func (r *EtcdRegistry) SaveUnitState(jobName string, unitState *unit.UnitState,
 	r.etcd.Do(&req)
 }
 
+// SaveUnitStates persists the UnitStates of all units on the given machine
+// to the Registry as a single document, replacing any states saved for the
+// machine before. If no states are given, the document is removed.
+func (r *EtcdRegistry) SaveUnitStates(machID string, states map[string]*unit.UnitState, ttl time.Duration) error {
+	key := r.machineStatesPath(machID)
+	if len(states) == 0 {
+		_, err := r.etcd.Do(&etcd.Delete{Key: key})
+		if err != nil && !isKeyNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	usms := make(map[string]*unitStateModel, len(states))
+	for name, us := range states {
+		if usm := unitStateToModel(us); usm != nil {
+			usms[name] = usm
+		}
+	}
+
+	json, err := marshal(usms)
+	if err != nil {
+		return err
+	}
+
+	req := etcd.Set{
+		Key:   key,
+		Value: json,
+		TTL:   ttl,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
 // Delete the state from the Registry for the given Job's Unit
 func (r *EtcdRegistry) RemoveUnitState(jobName string) error {
 	// TODO(jonboulle): consider https://github.com/coreos/fleet/issues/465
@@ -223,6 +293,10 @@ type unitStateModel struct {
 	SubState     string                `json:"subState"`
 	MachineState *machine.MachineState `json:"machineState"`
 	UnitHash     string                `json:"unitHash"`
+	Reason       string                `json:"reason,omitempty"`
+	Health       string                `json:"health,omitempty"`
+	UsedCPUUnits int                   `json:"usedCPUUnits,omitempty"`
+	UsedMemory   int                   `json:"usedMemory,omitempty"`
 }
 
 func modelToUnitState(usm *unitStateModel, name string) *unit.UnitState {
@@ -236,6 +310,11 @@ func modelToUnitState(usm *unitStateModel, name string) *unit.UnitState {
 		SubState:    usm.SubState,
 		UnitHash:    usm.UnitHash,
 		UnitName:    name,
+		Reason:      usm.Reason,
+		Health:      usm.Health,
+
+		UsedCPUUnits: usm.UsedCPUUnits,
+		UsedMemory:   usm.UsedMemory,
 	}
 
 	if usm.MachineState != nil {
@@ -261,6 +340,11 @@ func unitStateToModel(us *unit.UnitState) *unitStateModel {
 		ActiveState: us.ActiveState,
 		SubState:    us.SubState,
 		UnitHash:    us.UnitHash,
+		Reason:      us.Reason,
+		Health:      us.Health,
+
+		UsedCPUUnits: us.UsedCPUUnits,
+		UsedMemory:   us.UsedMemory,
 	}
 
 	if us.MachineID != "" {
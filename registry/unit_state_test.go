@@ -2,6 +2,7 @@ package registry
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"testing"
@@ -52,7 +53,7 @@ func (t *testEtcdClient) Wait(req etcd.Action, ch <-chan struct{}) (*etcd.Result
 }
 
 func TestUnitStatePaths(t *testing.T) {
-	r := &EtcdRegistry{nil, "/fleet/"}
+	r := &EtcdRegistry{nil, "/fleet/", nil, nil}
 	j := "foo.service"
 	want := "/fleet/state/foo.service"
 	got := r.legacyUnitStatePath(j)
@@ -69,7 +70,7 @@ func TestUnitStatePaths(t *testing.T) {
 
 func TestSaveUnitState(t *testing.T) {
 	e := &testEtcdClient{}
-	r := &EtcdRegistry{e, "/fleet/"}
+	r := &EtcdRegistry{e, "/fleet/", nil, nil}
 	j := "foo.service"
 	mID := "mymachine"
 	us := unit.NewUnitState("abc", "def", "ghi", mID)
@@ -94,7 +95,7 @@ func TestSaveUnitState(t *testing.T) {
 	us.UnitHash = "quickbrownfox"
 	r.SaveUnitState(j, us, time.Second)
 
-	json := `{"loadState":"abc","activeState":"def","subState":"ghi","machineState":{"ID":"mymachine","PublicIP":"","Metadata":null,"Version":""},"unitHash":"quickbrownfox"}`
+	json := `{"loadState":"abc","activeState":"def","subState":"ghi","machineState":{"ID":"mymachine","PublicIP":"","Metadata":null,"Version":"","TotalResources":{"Cores":0,"Memory":0,"Disk":0}},"unitHash":"quickbrownfox"}`
 	p1 := "/fleet/state/foo.service"
 	p2 := "/fleet/states/foo.service/mymachine"
 	want := []action{
@@ -113,9 +114,40 @@ func TestSaveUnitState(t *testing.T) {
 	}
 }
 
+func TestSaveUnitStates(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet/", nil, nil}
+	us := unit.NewUnitState("abc", "def", "ghi", "mymachine")
+	us.UnitHash = "quickbrownfox"
+
+	err := r.SaveUnitStates("mymachine", map[string]*unit.UnitState{"foo.service": us}, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error from SaveUnitStates: %v", err)
+	}
+
+	json := `{"foo.service":{"loadState":"abc","activeState":"def","subState":"ghi","machineState":{"ID":"mymachine","PublicIP":"","Metadata":null,"Version":"","TotalResources":{"Cores":0,"Memory":0,"Disk":0}},"unitHash":"quickbrownfox"}}`
+	want := []action{
+		action{key: "/fleet/states-by-machine/mymachine", val: json},
+	}
+	if !reflect.DeepEqual(e.sets, want) {
+		t.Errorf("bad result from SaveUnitStates: \ngot\n%#v\nwant\n%#v", e.sets, want)
+	}
+
+	// Saving no states removes the document
+	if err := r.SaveUnitStates("mymachine", nil, time.Second); err != nil {
+		t.Fatalf("unexpected error from SaveUnitStates: %v", err)
+	}
+	want = []action{
+		action{key: "/fleet/states-by-machine/mymachine"},
+	}
+	if !reflect.DeepEqual(e.deletes, want) {
+		t.Errorf("bad deletes from SaveUnitStates: \ngot\n%#v\nwant\n%#v", e.deletes, want)
+	}
+}
+
 func TestRemoveUnitState(t *testing.T) {
 	e := &testEtcdClient{}
-	r := &EtcdRegistry{e, "/fleet/"}
+	r := &EtcdRegistry{e, "/fleet/", nil, nil}
 	j := "foo.service"
 	err := r.RemoveUnitState(j)
 	if err != nil {
@@ -148,7 +180,7 @@ func TestRemoveUnitState(t *testing.T) {
 		{[]error{nil, errors.New("ur registry don't work")}, true},
 	} {
 		e = &testEtcdClient{err: tt.errs}
-		r = &EtcdRegistry{e, "/fleet"}
+		r = &EtcdRegistry{e, "/fleet", nil, nil}
 		err = r.RemoveUnitState("foo.service")
 		if (err != nil) != tt.fail {
 			t.Errorf("case %d: unexpected error state calling UnitStates(): got %v, want %v", i, err, tt.fail)
@@ -168,17 +200,17 @@ func TestUnitStateToModel(t *testing.T) {
 		{
 			// Unit state with no hash and no machineID is OK
 			// See https://github.com/coreos/fleet/issues/720
-			in:   &unit.UnitState{"foo", "bar", "baz", "", "", "name"},
-			want: &unitStateModel{"foo", "bar", "baz", nil, ""},
+			in:   &unit.UnitState{LoadState: "foo", ActiveState: "bar", SubState: "baz", UnitName: "name"},
+			want: &unitStateModel{"foo", "bar", "baz", nil, "", "", "", 0, 0},
 		},
 		{
 			// Unit state with hash but no machineID is OK
-			in:   &unit.UnitState{"foo", "bar", "baz", "", "heh", "name"},
-			want: &unitStateModel{"foo", "bar", "baz", nil, "heh"},
+			in:   &unit.UnitState{LoadState: "foo", ActiveState: "bar", SubState: "baz", UnitHash: "heh", UnitName: "name"},
+			want: &unitStateModel{"foo", "bar", "baz", nil, "heh", "", "", 0, 0},
 		},
 		{
-			in:   &unit.UnitState{"foo", "bar", "baz", "woof", "miaow", "name"},
-			want: &unitStateModel{"foo", "bar", "baz", &machine.MachineState{ID: "woof"}, "miaow"},
+			in:   &unit.UnitState{LoadState: "foo", ActiveState: "bar", SubState: "baz", MachineID: "woof", UnitHash: "miaow", UnitName: "name"},
+			want: &unitStateModel{"foo", "bar", "baz", &machine.MachineState{ID: "woof"}, "miaow", "", "", 0, 0},
 		},
 	} {
 		got := unitStateToModel(tt.in)
@@ -198,7 +230,7 @@ func TestModelToUnitState(t *testing.T) {
 			want: nil,
 		},
 		{
-			in: &unitStateModel{"foo", "bar", "baz", nil, ""},
+			in: &unitStateModel{"foo", "bar", "baz", nil, "", "", "", 0, 0},
 			want: &unit.UnitState{
 				LoadState:   "foo",
 				ActiveState: "bar",
@@ -209,7 +241,7 @@ func TestModelToUnitState(t *testing.T) {
 			},
 		},
 		{
-			in: &unitStateModel{"z", "x", "y", &machine.MachineState{ID: "abcd"}, ""},
+			in: &unitStateModel{"z", "x", "y", &machine.MachineState{ID: "abcd"}, "", "", "", 0, 0},
 			want: &unit.UnitState{
 				LoadState:   "z",
 				ActiveState: "x",
@@ -245,19 +277,19 @@ func TestGetUnitState(t *testing.T) {
 			// Unit state with no UnitHash should be OK
 			res: makeResult(`{"loadState":"abc","activeState":"def","subState":"ghi","machineState":{"ID":"mymachine","PublicIP":"","Metadata":null,"Version":"","TotalResources":{"Cores":0,"Memory":0,"Disk":0},"FreeResources":{"Cores":0,"Memory":0,"Disk":0}}}`),
 			err: nil,
-			us:  &unit.UnitState{"abc", "def", "ghi", "mymachine", "", "foo.service"},
+			us:  &unit.UnitState{LoadState: "abc", ActiveState: "def", SubState: "ghi", MachineID: "mymachine", UnitName: "foo.service"},
 		},
 		{
 			// Unit state with UnitHash should be OK
 			res: makeResult(`{"loadState":"abc","activeState":"def","subState":"ghi","machineState":{"ID":"mymachine","PublicIP":"","Metadata":null,"Version":"","TotalResources":{"Cores":0,"Memory":0,"Disk":0},"FreeResources":{"Cores":0,"Memory":0,"Disk":0}},"unitHash":"quickbrownfox"}`),
 			err: nil,
-			us:  &unit.UnitState{"abc", "def", "ghi", "mymachine", "quickbrownfox", "foo.service"},
+			us:  &unit.UnitState{LoadState: "abc", ActiveState: "def", SubState: "ghi", MachineID: "mymachine", UnitHash: "quickbrownfox", UnitName: "foo.service"},
 		},
 		{
 			// Unit state with no MachineState should be OK
 			res: makeResult(`{"loadState":"abc","activeState":"def","subState":"ghi"}`),
 			err: nil,
-			us:  &unit.UnitState{"abc", "def", "ghi", "", "", "foo.service"},
+			us:  &unit.UnitState{LoadState: "abc", ActiveState: "def", SubState: "ghi", UnitName: "foo.service"},
 		},
 		{
 			// Bad unit state object should simply result in nil returned
@@ -282,7 +314,7 @@ func TestGetUnitState(t *testing.T) {
 			res: []*etcd.Result{tt.res},
 			err: []error{tt.err},
 		}
-		r := &EtcdRegistry{e, "/fleet/"}
+		r := &EtcdRegistry{e, "/fleet/", nil, nil}
 		j := "foo.service"
 		us := r.getUnitState(j)
 		want := []action{
@@ -307,8 +339,8 @@ func usToJson(t *testing.T, us *unit.UnitState) string {
 }
 
 func TestUnitStates(t *testing.T) {
-	fus1 := unit.UnitState{"abc", "def", "ghi", "mID1", "zzz", "foo"}
-	fus2 := unit.UnitState{"cat", "dog", "cow", "mID2", "xxx", "foo"}
+	fus1 := unit.UnitState{LoadState: "abc", ActiveState: "def", SubState: "ghi", MachineID: "mID1", UnitHash: "zzz", UnitName: "foo"}
+	fus2 := unit.UnitState{LoadState: "cat", ActiveState: "dog", SubState: "cow", MachineID: "mID2", UnitHash: "xxx", UnitName: "foo"}
 	// Multiple new unit states reported for the same unit
 	foo := etcd.Node{
 		Key: "/fleet/states/foo",
@@ -335,13 +367,13 @@ func TestUnitStates(t *testing.T) {
 	}
 	// Legacy unit state which we expect to be overridden by fus1 (from the
 	// same machine ID)
-	fus3 := unit.UnitState{"cba", "fed", "ihg", "mID1", "zzz", "foo"}
+	fus3 := unit.UnitState{LoadState: "cba", ActiveState: "fed", SubState: "ihg", MachineID: "mID1", UnitHash: "zzz", UnitName: "foo"}
 	bfoo := etcd.Node{
 		Key:   "/fleet/state/foo",
 		Value: usToJson(t, &fus3),
 	}
 	// Legacy unit state which we expect to see in the results
-	bus := unit.UnitState{"111", "222", "333", "mID3", "aaa", "bar"}
+	bus := unit.UnitState{LoadState: "111", ActiveState: "222", SubState: "333", MachineID: "mID3", UnitHash: "aaa", UnitName: "bar"}
 	baz := etcd.Node{
 		Key:   "/fleet/state/bar",
 		Value: usToJson(t, &bus),
@@ -361,10 +393,25 @@ func TestUnitStates(t *testing.T) {
 			Nodes: []etcd.Node{foo, bar},
 		},
 	}
+	// Per-machine document which we expect to override fus2 (from the
+	// same machine ID)
+	fus4 := unit.UnitState{LoadState: "act", ActiveState: "ive", SubState: "run", MachineID: "mID2", UnitHash: "yyy", UnitName: "foo"}
+	fus5 := unit.UnitState{LoadState: "act", ActiveState: "ive", SubState: "run", MachineID: "mID2", UnitHash: "yyy", UnitName: "baz"}
+	mID2 := etcd.Node{
+		Key:   "/fleet/states-by-machine/mID2",
+		Value: fmt.Sprintf(`{"foo":%s,"baz":%s}`, usToJson(t, &fus4), usToJson(t, &fus5)),
+	}
+	// Result from crawling the per-machine "states-by-machine" namespace
+	res3 := &etcd.Result{
+		Node: &etcd.Node{
+			Key:   "/fleet/states-by-machine",
+			Nodes: []etcd.Node{mID2},
+		},
+	}
 	e := &testEtcdClient{
-		res: []*etcd.Result{res1, res2},
+		res: []*etcd.Result{res1, res2, res3},
 	}
-	r := &EtcdRegistry{e, "/fleet/"}
+	r := &EtcdRegistry{e, "/fleet/", nil, nil}
 
 	got, err := r.UnitStates()
 	if err != nil {
@@ -373,8 +420,9 @@ func TestUnitStates(t *testing.T) {
 
 	want := []*unit.UnitState{
 		&bus,
+		&fus5,
 		&fus1,
-		&fus2,
+		&fus4,
 	}
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("UnitStates() returned unexpected result")
@@ -400,7 +448,7 @@ func TestUnitStates(t *testing.T) {
 		{[]error{nil, errors.New("ur registry don't work")}, true},
 	} {
 		e = &testEtcdClient{err: tt.errs}
-		r = &EtcdRegistry{e, "/fleet"}
+		r = &EtcdRegistry{e, "/fleet", nil, nil}
 		got, err = r.UnitStates()
 		if (err != nil) != tt.fail {
 			t.Errorf("case %d: unexpected error state calling UnitStates(): got %v, want %v", i, err, tt.fail)
@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/unit"
+)
+
+const (
+	unitVersionPrefix = "unit-versions"
+
+	// unitVersionLimit is the number of versions of each Unit kept in the
+	// registry
+	unitVersionLimit = 20
+
+	// number of times recording a version is attempted while other
+	// versions of the same Unit are recorded concurrently
+	unitVersionAttempts = 3
+)
+
+// UnitVersion records a unit file a Unit was submitted with. Versions of a
+// Unit are numbered from 1 in the order they were submitted, and are kept
+// after the Unit is destroyed, so that it can be rolled back to any of them.
+// Unit files are stored by their Hash, so versions cost little besides
+// their record.
+type UnitVersion struct {
+	Version int `json:"-"`
+	Hash    unit.Hash
+	Created time.Time
+
+	// Unit is the unit file of the version, or nil if it is no longer
+	// stored
+	Unit *unit.UnitFile `json:"-"`
+}
+
+// RecordUnitVersion records the unit file with the given Hash as the latest
+// version of the named Unit, unless it already is. Only the latest 20
+// versions are kept.
+func (r *EtcdRegistry) RecordUnitVersion(name string, hash unit.Hash) error {
+	for i := 0; i < unitVersionAttempts; i++ {
+		nodes, err := r.unitVersionNodes(name)
+		if err != nil {
+			return err
+		}
+
+		next := 1
+		if len(nodes) > 0 {
+			latest := nodes[len(nodes)-1]
+			var uv UnitVersion
+			if err := unmarshal(latest.Value, &uv); err == nil && uv.Hash == hash {
+				return nil
+			}
+			next = unitVersionNumber(latest.Key) + 1
+		}
+
+		val, err := marshal(UnitVersion{Hash: hash, Created: time.Now()})
+		if err != nil {
+			return err
+		}
+		req := etcd.Create{
+			Key:   r.unitVersionPath(name, next),
+			Value: val,
+		}
+		if _, err := r.etcd.Do(&req); err != nil {
+			if isNodeExist(err) {
+				continue
+			}
+			return err
+		}
+
+		for len(nodes)+1 > unitVersionLimit {
+			req := etcd.Delete{
+				Key: nodes[0].Key,
+			}
+			if _, err := r.etcd.Do(&req); err != nil && !isKeyNotFound(err) {
+				return err
+			}
+			nodes = nodes[1:]
+		}
+		return nil
+	}
+	return errors.New("unit versions changed concurrently")
+}
+
+// UnitVersions returns the recorded versions of the named Unit, oldest
+// first, along with their unit files
+func (r *EtcdRegistry) UnitVersions(name string) ([]UnitVersion, error) {
+	nodes, err := r.unitVersionNodes(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []UnitVersion
+	for _, node := range nodes {
+		var uv UnitVersion
+		if err := unmarshal(node.Value, &uv); err != nil {
+			log.Errorf("Failed parsing UnitVersion from %s: %v", node.Key, err)
+			continue
+		}
+		uv.Version = unitVersionNumber(node.Key)
+		uv.Unit = r.getUnitByHash(uv.Hash)
+		versions = append(versions, uv)
+	}
+	return versions, nil
+}
+
+// unitVersionNodes returns the nodes holding the versions of the named
+// Unit, ordered by version
+func (r *EtcdRegistry) unitVersionNodes(name string) (etcd.Nodes, error) {
+	req := etcd.Get{
+		Key:       path.Join(r.keyPrefix, unitVersionPrefix, name),
+		Recursive: true,
+	}
+
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+
+	nodes := res.Node.Nodes
+	sort.Sort(nodesByVersion(nodes))
+	return nodes, nil
+}
+
+func (r *EtcdRegistry) unitVersionPath(name string, version int) string {
+	return path.Join(r.keyPrefix, unitVersionPrefix, name, strconv.Itoa(version))
+}
+
+// unitVersionNumber returns the version recorded under the given key, or 0
+// if the key holds no version
+func unitVersionNumber(key string) int {
+	n, _ := strconv.Atoi(path.Base(key))
+	return n
+}
+
+type nodesByVersion etcd.Nodes
+
+func (s nodesByVersion) Len() int      { return len(s) }
+func (s nodesByVersion) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s nodesByVersion) Less(i, j int) bool {
+	return unitVersionNumber(s[i].Key) < unitVersionNumber(s[j].Key)
+}
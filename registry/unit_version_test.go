@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/unit"
+)
+
+func unitVersionNode(t *testing.T, name string, version int, hash unit.Hash) etcd.Node {
+	val, err := marshal(UnitVersion{Hash: hash, Created: time.Date(2014, 10, 1, 12, 0, version, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("Failed marshaling UnitVersion: %v", err)
+	}
+	return etcd.Node{Key: fmt.Sprintf("/fleet/unit-versions/%s/%d", name, version), Value: val}
+}
+
+func TestRecordUnitVersion(t *testing.T) {
+	old := unit.Hash{1}
+	cur := unit.Hash{2}
+
+	// versions are ordered numerically, not by key
+	res := &etcd.Result{Node: &etcd.Node{Key: "/fleet/unit-versions/foo.service", Nodes: []etcd.Node{
+		unitVersionNode(t, "foo.service", 10, cur),
+		unitVersionNode(t, "foo.service", 9, old),
+	}}}
+
+	// recording the latest version again records nothing
+	e := &testEtcdClient{res: []*etcd.Result{res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.RecordUnitVersion("foo.service", cur); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.ri != 1 {
+		t.Errorf("Expected only the versions to be fetched, made %d requests", e.ri)
+	}
+
+	// a version lost to a concurrent one is recorded after it
+	e = &testEtcdClient{
+		res: []*etcd.Result{res, nil, res, nil},
+		err: []error{nil, etcd.Error{ErrorCode: etcd.ErrorNodeExist}},
+	}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.RecordUnitVersion("foo.service", old); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.ri != 4 || len(e.deletes) != 0 {
+		t.Errorf("Unexpected requests: %d, deletes %v", e.ri, e.deletes)
+	}
+}
+
+func TestRecordUnitVersionTrims(t *testing.T) {
+	var nodes []etcd.Node
+	for i := 1; i <= unitVersionLimit; i++ {
+		nodes = append(nodes, unitVersionNode(t, "foo.service", i, unit.Hash{byte(i)}))
+	}
+	res := &etcd.Result{Node: &etcd.Node{Key: "/fleet/unit-versions/foo.service", Nodes: nodes}}
+
+	e := &testEtcdClient{res: []*etcd.Result{res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	if err := r.RecordUnitVersion("foo.service", unit.Hash{0xff}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []action{action{key: "/fleet/unit-versions/foo.service/1"}}
+	if !reflect.DeepEqual(e.deletes, want) {
+		t.Errorf("Unexpected deletes: got %v, want %v", e.deletes, want)
+	}
+}
+
+func TestUnitVersions(t *testing.T) {
+	uf, err := unit.NewUnitFile("[Service]\nExecStart=/bin/true\n")
+	if err != nil {
+		t.Fatalf("Failed creating unit file: %v", err)
+	}
+	raw, err := marshal(unitModel{Raw: uf.String()})
+	if err != nil {
+		t.Fatalf("Failed marshaling unit file: %v", err)
+	}
+
+	versions := &etcd.Result{Node: &etcd.Node{Key: "/fleet/unit-versions/foo.service", Nodes: []etcd.Node{
+		unitVersionNode(t, "foo.service", 1, uf.Hash()),
+		etcd.Node{Key: "/fleet/unit-versions/foo.service/2", Value: "garbage"},
+		unitVersionNode(t, "foo.service", 3, unit.Hash{3}),
+	}}}
+	stored := &etcd.Result{Node: &etcd.Node{Value: raw}}
+
+	// the unit file of the third version is no longer stored
+	e := &testEtcdClient{
+		res: []*etcd.Result{versions, stored, nil},
+		err: []error{nil, nil, etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}},
+	}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.UnitVersions("foo.service")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []UnitVersion{
+		UnitVersion{Version: 1, Hash: uf.Hash(), Created: time.Date(2014, 10, 1, 12, 0, 1, 0, time.UTC), Unit: uf},
+		UnitVersion{Version: 3, Hash: unit.Hash{3}, Created: time.Date(2014, 10, 1, 12, 0, 3, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected UnitVersions:\ngot  %#v\nwant %#v", got, want)
+	}
+
+	e = &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	if got, err := r.UnitVersions("foo.service"); err != nil || len(got) != 0 {
+		t.Errorf("Expected no versions, got %v, err %v", got, err)
+	}
+}
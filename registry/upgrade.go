@@ -0,0 +1,160 @@
+package registry
+
+import (
+	"path"
+	"sort"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+	"github.com/coreos/fleet/log"
+)
+
+const (
+	upgradePlanKey      = "upgrade"
+	upgradeStatusPrefix = "upgrade-status"
+
+	// States a machine goes through while upgrading fleetd, see
+	// UpgradeStatus
+	UpgradeStatePending    = "pending"
+	UpgradeStateRestarting = "restarting"
+	UpgradeStateDone       = "done"
+	UpgradeStateFailed     = "failed"
+)
+
+// UpgradePlan asks the agents of the cluster to restart into the given
+// version of fleetd. Machines upgrade one failure domain at a time, the
+// domain of a machine being the value of its DomainKey metadata. Without
+// a DomainKey, machines upgrade one at a time.
+type UpgradePlan struct {
+	Version   string
+	DomainKey string `json:",omitempty"`
+	Created   time.Time
+}
+
+// UpgradeStatus is published by the agent of each machine as it follows
+// an UpgradePlan
+type UpgradeStatus struct {
+	MachineID string `json:"-"`
+	// Version is the version of fleetd the machine is upgrading to
+	Version string
+	State   string
+	// Reason is why the upgrade failed, if it did
+	Reason string `json:",omitempty"`
+	// Cordoned is set if the machine was cordoned for the upgrade, and
+	// is to be uncordoned once it is done
+	Cordoned bool `json:",omitempty"`
+	Time     time.Time
+}
+
+// SetUpgradePlan replaces the current UpgradePlan, if any, and clears the
+// UpgradeStatus published by each machine for it
+func (r *EtcdRegistry) SetUpgradePlan(p UpgradePlan) error {
+	if err := r.clearUpgradeStatuses(); err != nil {
+		return err
+	}
+
+	val, err := marshal(p)
+	if err != nil {
+		return err
+	}
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, upgradePlanKey),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// UpgradePlan returns the current UpgradePlan, or nil if there is none
+func (r *EtcdRegistry) UpgradePlan() (*UpgradePlan, error) {
+	req := etcd.Get{
+		Key: path.Join(r.keyPrefix, upgradePlanKey),
+	}
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+
+	var p UpgradePlan
+	if err := unmarshal(res.Node.Value, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ClearUpgradePlan removes the current UpgradePlan, along with the
+// UpgradeStatus published by each machine for it
+func (r *EtcdRegistry) ClearUpgradePlan() error {
+	req := etcd.Delete{
+		Key: path.Join(r.keyPrefix, upgradePlanKey),
+	}
+	_, err := r.etcd.Do(&req)
+	if err != nil && !isKeyNotFound(err) {
+		return err
+	}
+	return r.clearUpgradeStatuses()
+}
+
+func (r *EtcdRegistry) clearUpgradeStatuses() error {
+	req := etcd.Delete{
+		Key:       path.Join(r.keyPrefix, upgradeStatusPrefix),
+		Recursive: true,
+	}
+	_, err := r.etcd.Do(&req)
+	if isKeyNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// SaveUpgradeStatus publishes the given UpgradeStatus of a machine
+func (r *EtcdRegistry) SaveUpgradeStatus(us UpgradeStatus) error {
+	val, err := marshal(us)
+	if err != nil {
+		return err
+	}
+	req := etcd.Set{
+		Key:   path.Join(r.keyPrefix, upgradeStatusPrefix, us.MachineID),
+		Value: val,
+	}
+	_, err = r.etcd.Do(&req)
+	return err
+}
+
+// UpgradeStatuses returns the UpgradeStatus published by each machine for
+// the current UpgradePlan, sorted by machine ID
+func (r *EtcdRegistry) UpgradeStatuses() ([]UpgradeStatus, error) {
+	req := etcd.Get{
+		Key:    path.Join(r.keyPrefix, upgradeStatusPrefix),
+		Sorted: true,
+	}
+	res, err := r.etcd.Do(&req)
+	if err != nil {
+		if isKeyNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+
+	var statuses []UpgradeStatus
+	for _, node := range res.Node.Nodes {
+		var us UpgradeStatus
+		if err := unmarshal(node.Value, &us); err != nil {
+			log.Errorf("Failed parsing UpgradeStatus from %s: %v", node.Key, err)
+			continue
+		}
+		us.MachineID = path.Base(node.Key)
+		statuses = append(statuses, us)
+	}
+	sort.Sort(upgradeStatusesByMachineID(statuses))
+	return statuses, nil
+}
+
+type upgradeStatusesByMachineID []UpgradeStatus
+
+func (s upgradeStatusesByMachineID) Len() int           { return len(s) }
+func (s upgradeStatusesByMachineID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s upgradeStatusesByMachineID) Less(i, j int) bool { return s[i].MachineID < s[j].MachineID }
@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/etcd"
+)
+
+func TestSetUpgradePlan(t *testing.T) {
+	e := &testEtcdClient{}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	p := UpgradePlan{Version: "0.9.0", DomainKey: "az", Created: time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)}
+	if err := r.SetUpgradePlan(p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := []action{{key: "/fleet/upgrade-status", rec: true}}; !reflect.DeepEqual(want, e.deletes) {
+		t.Errorf("Unexpected deletes: got %#v, want %#v", e.deletes, want)
+	}
+	want := []action{{key: "/fleet/upgrade", val: `{"Version":"0.9.0","DomainKey":"az","Created":"2014-10-01T12:00:00Z"}`}}
+	if !reflect.DeepEqual(want, e.sets) {
+		t.Errorf("Unexpected sets: got %#v, want %#v", e.sets, want)
+	}
+}
+
+func TestUpgradePlan(t *testing.T) {
+	e := &testEtcdClient{err: []error{etcd.Error{ErrorCode: etcd.ErrorKeyNotFound}}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+	if p, err := r.UpgradePlan(); p != nil || err != nil {
+		t.Errorf("Expected no plan, got %v, err %v", p, err)
+	}
+
+	res := etcd.Result{Node: &etcd.Node{Key: "/fleet/upgrade", Value: `{"Version":"0.9.0"}`}}
+	e = &testEtcdClient{res: []*etcd.Result{&res}}
+	r = &EtcdRegistry{e, "/fleet", nil, nil}
+	p, err := r.UpgradePlan()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p == nil || p.Version != "0.9.0" || p.DomainKey != "" {
+		t.Errorf("Unexpected plan: %#v", p)
+	}
+}
+
+func TestUpgradeStatuses(t *testing.T) {
+	res := etcd.Result{
+		Node: &etcd.Node{
+			Key: "/fleet/upgrade-status",
+			Nodes: etcd.Nodes{
+				etcd.Node{Key: "/fleet/upgrade-status/YYY", Value: `{"Version":"0.9.0","State":"failed","Reason":"boom","Time":"2014-10-01T12:00:00Z"}`},
+				etcd.Node{Key: "/fleet/upgrade-status/XXX", Value: `{"Version":"0.9.0","State":"done","Time":"2014-10-01T12:00:00Z"}`},
+				etcd.Node{Key: "/fleet/upgrade-status/ZZZ", Value: `garbage`},
+			},
+		},
+	}
+	e := &testEtcdClient{res: []*etcd.Result{&res}}
+	r := &EtcdRegistry{e, "/fleet", nil, nil}
+
+	got, err := r.UpgradeStatuses()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+	want := []UpgradeStatus{
+		{MachineID: "XXX", Version: "0.9.0", State: UpgradeStateDone, Time: now},
+		{MachineID: "YYY", Version: "0.9.0", State: UpgradeStateFailed, Reason: "boom", Time: now},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected statuses:\ngot\n%#v\nwant\n%#v", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Counts holds amounts of countable resources besides CPU, memory and disk,
+// like GPUs, indexed by name
+type Counts map[string]int
+
+// ParseCounts parses a comma-separated list of resources and their amounts
+// like "gpu:2,hugepages1G:16". Amounts of the same resource add up.
+func ParseCounts(s string) (Counts, error) {
+	counts := make(Counts)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("invalid resource %q: must be NAME:COUNT", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid count of resource %q: must be a non-negative integer", name)
+		}
+		counts[name] += n
+	}
+	return counts, nil
+}
+
+// String formats the Counts like they are parsed, ordered by name
+func (c Counts) String() string {
+	pairs := make([]string, 0, len(c))
+	for _, name := range c.Names() {
+		pairs = append(pairs, fmt.Sprintf("%s:%d", name, c[name]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Names returns the names of the resources in the Counts in order
+func (c Counts) Names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SumCounts aggregates a number of Counts into a single entity
+func SumCounts(counts ...Counts) Counts {
+	res := make(Counts)
+	for _, c := range counts {
+		for name, n := range c {
+			res[name] += n
+		}
+	}
+	return res
+}
@@ -17,14 +17,14 @@ func (rt ResourceTuple) Empty() bool {
 }
 
 const (
-	// TODO(jonboulle): make these configurable
 	HostCores  = 100
 	HostMemory = 256
 	HostDisk   = 0
 )
 
-// HostResources represents a set of resources that fleet considers reserved
-// for the host, i.e. outside of any units it is running
+// HostResources represents the set of resources that fleet considers
+// reserved for the host, i.e. outside of any units it is running, unless
+// machines are configured to reserve others
 var HostResources = ResourceTuple{
 	HostCores,
 	HostMemory,
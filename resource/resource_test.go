@@ -56,3 +56,38 @@ func TestSub(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCounts(t *testing.T) {
+	for i, tt := range []struct {
+		in   string
+		want Counts
+		err  bool
+	}{
+		{"", Counts{}, false},
+		{"gpu:2", Counts{"gpu": 2}, false},
+		{"gpu:2, hugepages1G:16", Counts{"gpu": 2, "hugepages1G": 16}, false},
+		{"gpu:1,gpu:1", Counts{"gpu": 2}, false},
+		{"gpu", nil, true},
+		{":2", nil, true},
+		{"gpu:two", nil, true},
+		{"gpu:-1", nil, true},
+	} {
+		got, err := ParseCounts(tt.in)
+		if tt.err != (err != nil) {
+			t.Errorf("case %d: unexpected error value: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("case %d: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestCounts(t *testing.T) {
+	c := SumCounts(Counts{"gpu": 1}, Counts{"gpu": 1, "fpga": 1}, nil)
+	if got := c.String(); got != "fpga:1,gpu:2" {
+		t.Errorf("Unexpected sum of counts %q", got)
+	}
+	if got := Counts(nil).String(); got != "" {
+		t.Errorf("Unexpected empty counts %q", got)
+	}
+}
@@ -1,10 +1,16 @@
 package schema
 
 import (
+	"sort"
+	"time"
+
 	gsunit "github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-systemd/unit"
 
+	"github.com/coreos/fleet/engine"
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/unit"
 )
 
@@ -20,6 +26,13 @@ func MapUnitFileToSchemaUnitOptions(uf *unit.UnitFile) []*UnitOption {
 	return sopts
 }
 
+func MapUnitFileToSchemaUnitFile(uf *unit.UnitFile) *UnitFile {
+	return &UnitFile{
+		Hash:    uf.Hash().String(),
+		Options: MapUnitFileToSchemaUnitOptions(uf),
+	}
+}
+
 func MapSchemaUnitOptionsToUnitFile(sopts []*UnitOption) *unit.UnitFile {
 	opts := make([]*gsunit.UnitOption, len(sopts))
 	for i, sopt := range sopts {
@@ -36,8 +49,9 @@ func MapSchemaUnitOptionsToUnitFile(sopts []*UnitOption) *unit.UnitFile {
 func MapSchemaUnitToUnit(entity *Unit) *job.Unit {
 	uf := MapSchemaUnitOptionsToUnitFile(entity.Options)
 	j := job.Unit{
-		Name: entity.Name,
-		Unit: *uf,
+		Name:   entity.Name,
+		Unit:   *uf,
+		Labels: entity.Labels,
 	}
 	return &j
 }
@@ -47,6 +61,7 @@ func MapUnitToSchemaUnit(u *job.Unit, su *job.ScheduledUnit) *Unit {
 		Name:         u.Name,
 		Options:      MapUnitFileToSchemaUnitOptions(&(u.Unit)),
 		DesiredState: string(u.TargetState),
+		Labels:       u.Labels,
 	}
 
 	if su != nil {
@@ -70,9 +85,37 @@ func MapSchemaUnitsToUnits(entities []*Unit) []job.Unit {
 }
 
 func MapMachineStateToSchema(ms *machine.MachineState) *Machine {
+	// the reservation of machines not publishing their capacity is
+	// meaningless
+	var reserved resource.ResourceTuple
+	if !ms.TotalResources.Empty() {
+		reserved = ms.Reserved()
+	}
+
 	sm := Machine{
-		Id:        ms.ID,
-		PrimaryIP: ms.PublicIP,
+		Id:                         ms.ID,
+		PrimaryIP:                  ms.PublicIP,
+		TotalCPUUnits:              int64(ms.TotalResources.Cores),
+		TotalMemory:                int64(ms.TotalResources.Memory),
+		TotalDisk:                  int64(ms.TotalResources.Disk),
+		AllocatedCPUUnits:          int64(ms.AllocatedResources.Cores),
+		AllocatedMemory:            int64(ms.AllocatedResources.Memory),
+		AllocatedDisk:              int64(ms.AllocatedResources.Disk),
+		ReservedCPUUnits:           int64(reserved.Cores),
+		ReservedMemory:             int64(reserved.Memory),
+		ReservedDisk:               int64(reserved.Disk),
+		CpuOvercommit:              ms.CPUOvercommit,
+		MemoryOvercommit:           ms.MemoryOvercommit,
+		MaxUnits:                   int64(ms.MaxUnits),
+		MemoryPolicy:               ms.MemoryPolicy,
+		AvailableMemory:            int64(ms.AvailableMemory),
+		ExtendedResources:          ms.ExtendedResources.String(),
+		AllocatedExtendedResources: ms.AllocatedExtendedResources.String(),
+		Cordoned:                   ms.Cordoned,
+		Draining:                   ms.Draining,
+	}
+	if ms.LastHeartbeat != nil {
+		sm.LastHeartbeat = ms.LastHeartbeat.UTC().Format(time.RFC3339)
 	}
 
 	sm.Metadata = make(map[string]string, len(ms.Metadata))
@@ -80,9 +123,27 @@ func MapMachineStateToSchema(ms *machine.MachineState) *Machine {
 		sm.Metadata[k] = v
 	}
 
+	for _, t := range ms.Taints {
+		sm.Taints = append(sm.Taints, &Taint{Key: t.Key, Value: t.Value, Effect: t.Effect})
+	}
+
+	for _, a := range ms.Addresses {
+		sm.Addresses = append(sm.Addresses, &Address{Ip: a.IP, Scope: a.Scope})
+	}
+
 	return &sm
 }
 
+// mapSchemaCounts parses the given list of extended resources, of which
+// invalid ones are disregarded
+func mapSchemaCounts(s string) resource.Counts {
+	if s == "" {
+		return nil
+	}
+	c, _ := resource.ParseCounts(s)
+	return c
+}
+
 func MapSchemaToMachineStates(entities []*Machine) []machine.MachineState {
 	machines := make([]machine.MachineState, len(entities))
 	for i, _ := range entities {
@@ -91,6 +152,37 @@ func MapSchemaToMachineStates(entities []*Machine) []machine.MachineState {
 		ms := machine.MachineState{
 			ID:       me.Id,
 			PublicIP: me.PrimaryIP,
+			TotalResources: resource.ResourceTuple{
+				Cores:  int(me.TotalCPUUnits),
+				Memory: int(me.TotalMemory),
+				Disk:   int(me.TotalDisk),
+			},
+			AllocatedResources: resource.ResourceTuple{
+				Cores:  int(me.AllocatedCPUUnits),
+				Memory: int(me.AllocatedMemory),
+				Disk:   int(me.AllocatedDisk),
+			},
+			CPUOvercommit:              me.CpuOvercommit,
+			MemoryOvercommit:           me.MemoryOvercommit,
+			MaxUnits:                   int(me.MaxUnits),
+			MemoryPolicy:               me.MemoryPolicy,
+			AvailableMemory:            int(me.AvailableMemory),
+			Cordoned:                   me.Cordoned,
+			Draining:                   me.Draining,
+			ExtendedResources:          mapSchemaCounts(me.ExtendedResources),
+			AllocatedExtendedResources: mapSchemaCounts(me.AllocatedExtendedResources),
+		}
+
+		if t, err := time.Parse(time.RFC3339, me.LastHeartbeat); err == nil {
+			ms.LastHeartbeat = &t
+		}
+
+		if !ms.TotalResources.Empty() {
+			ms.ReservedResources = &resource.ResourceTuple{
+				Cores:  int(me.ReservedCPUUnits),
+				Memory: int(me.ReservedMemory),
+				Disk:   int(me.ReservedDisk),
+			}
 		}
 
 		ms.Metadata = make(map[string]string, len(me.Metadata))
@@ -98,6 +190,14 @@ func MapSchemaToMachineStates(entities []*Machine) []machine.MachineState {
 			ms.Metadata[k] = v
 		}
 
+		for _, t := range me.Taints {
+			ms.Taints = append(ms.Taints, machine.Taint{Key: t.Key, Value: t.Value, Effect: t.Effect})
+		}
+
+		for _, a := range me.Addresses {
+			ms.Addresses = append(ms.Addresses, machine.Address{IP: a.Ip, Scope: a.Scope})
+		}
+
 		machines[i] = ms
 	}
 
@@ -121,6 +221,10 @@ func MapUnitStateToSchemaUnitState(entity *unit.UnitState) *UnitState {
 		SystemdLoadState:   entity.LoadState,
 		SystemdActiveState: entity.ActiveState,
 		SystemdSubState:    entity.SubState,
+		Reason:             entity.Reason,
+		Health:             entity.Health,
+		UsedCPUUnits:       int64(entity.UsedCPUUnits),
+		UsedMemory:         int64(entity.UsedMemory),
 	}
 
 	return &us
@@ -136,6 +240,11 @@ func MapSchemaUnitStatesToUnitStates(entities []*UnitState) []*unit.UnitState {
 			LoadState:   e.SystemdLoadState,
 			ActiveState: e.SystemdActiveState,
 			SubState:    e.SystemdSubState,
+			Reason:      e.Reason,
+			Health:      e.Health,
+
+			UsedCPUUnits: int(e.UsedCPUUnits),
+			UsedMemory:   int(e.UsedMemory),
 		}
 	}
 
@@ -159,3 +268,241 @@ func MapSchemaUnitsToScheduledUnits(entities []*Unit) []job.ScheduledUnit {
 
 	return su
 }
+
+func MapClusterEventToSchemaEvent(ev *registry.ClusterEvent) *Event {
+	return &Event{
+		Index:     int64(ev.Index),
+		Time:      ev.Time.UTC().Format(time.RFC3339),
+		Type:      ev.Type,
+		UnitName:  ev.UnitName,
+		MachineID: ev.MachineID,
+		Reason:    ev.Reason,
+	}
+}
+
+func MapAuditEntryToSchemaAuditEntry(ae *registry.AuditEntry) *AuditEntry {
+	return &AuditEntry{
+		Index:         int64(ae.Index),
+		Time:          ae.Time.UTC().Format(time.RFC3339),
+		User:          ae.User,
+		Action:        ae.Action,
+		UnitName:      ae.UnitName,
+		PreviousState: ae.PrevState,
+		State:         ae.State,
+	}
+}
+
+func MapStackToSchemaStack(s *registry.Stack) *Stack {
+	return &Stack{
+		Name:  s.Name,
+		Units: s.Units,
+	}
+}
+
+func MapSchemaStackToStack(s *Stack) *registry.Stack {
+	return &registry.Stack{
+		Name:  s.Name,
+		Units: s.Units,
+	}
+}
+
+// MapConfigValuesToSchemaConfigValues returns the given values of a
+// namespace sorted by key
+func MapConfigValuesToSchemaConfigValues(values map[string]registry.ConfigValue) []*ConfigValue {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sValues := make([]*ConfigValue, len(keys))
+	for i, key := range keys {
+		sValues[i] = &ConfigValue{
+			Key:    key,
+			Value:  values[key].Value,
+			Secret: values[key].Secret,
+		}
+	}
+	return sValues
+}
+
+func MapSchemaConfigValueToConfigValue(cv *ConfigValue) registry.ConfigValue {
+	return registry.ConfigValue{
+		Value:  cv.Value,
+		Secret: cv.Secret,
+	}
+}
+
+// MapQuotaToSchemaQuota returns the given Quota along with what the Units
+// of its namespace count against it
+func MapQuotaToSchemaQuota(q *registry.Quota, qu registry.QuotaUsage) *Quota {
+	return &Quota{
+		Namespace:  q.Namespace,
+		Cores:      int64(q.Cores),
+		Memory:     int64(q.Memory),
+		Units:      int64(q.Units),
+		UsedCores:  int64(qu.Cores),
+		UsedMemory: int64(qu.Memory),
+		UsedUnits:  int64(qu.Units),
+	}
+}
+
+func MapSchemaQuotaToQuota(q *Quota) registry.Quota {
+	return registry.Quota{
+		Namespace: q.Namespace,
+		Cores:     int(q.Cores),
+		Memory:    int(q.Memory),
+		Units:     int(q.Units),
+	}
+}
+
+func MapCronRunsToSchemaCronRuns(runs []registry.CronRun) []*CronRun {
+	sRuns := make([]*CronRun, len(runs))
+	for i, run := range runs {
+		sRuns[i] = &CronRun{
+			Name:      run.Name,
+			Tick:      run.Tick.UTC().Format(time.RFC3339),
+			MachineID: run.MachineID,
+			Result:    run.Result,
+		}
+		if !run.Finished.IsZero() {
+			sRuns[i].Finished = run.Finished.UTC().Format(time.RFC3339)
+		}
+	}
+	return sRuns
+}
+
+func MapUnitVersionsToSchemaUnitVersions(versions []registry.UnitVersion) []*UnitVersion {
+	sVersions := make([]*UnitVersion, len(versions))
+	for i, uv := range versions {
+		sVersions[i] = &UnitVersion{
+			Version: int64(uv.Version),
+			Hash:    uv.Hash.String(),
+			Created: uv.Created.UTC().Format(time.RFC3339),
+		}
+		if uv.Unit != nil {
+			sVersions[i].Options = MapUnitFileToSchemaUnitOptions(uv.Unit)
+		}
+	}
+	return sVersions
+}
+
+func MapEngineStatusesToSchemaEngineStatuses(statuses []registry.EngineStatus) []*EngineStatus {
+	sStatuses := make([]*EngineStatus, len(statuses))
+	for i, es := range statuses {
+		sStatuses[i] = &EngineStatus{
+			MachineID:             es.MachineID,
+			Version:               int64(es.Version),
+			LeaseExpires:          es.LeaseExpires.UTC().Format(time.RFC3339),
+			Reconciles:            int64(es.Reconciles),
+			LastReconcile:         es.LastReconcile.UTC().Format(time.RFC3339),
+			LastReconcileDuration: es.LastReconcileDuration.Seconds(),
+		}
+		for _, shard := range es.Shards {
+			sStatuses[i].Shards = append(sStatuses[i].Shards, int64(shard))
+		}
+	}
+	return sStatuses
+}
+
+// MapUpgradeToSchemaUpgrade maps the given UpgradePlan, along with the
+// UpgradeStatus published for it by each machine
+func MapUpgradeToSchemaUpgrade(p *registry.UpgradePlan, statuses []registry.UpgradeStatus) *Upgrade {
+	su := &Upgrade{
+		Version:   p.Version,
+		DomainKey: p.DomainKey,
+		Created:   p.Created.UTC().Format(time.RFC3339),
+		Machines:  make([]*UpgradeStatus, len(statuses)),
+	}
+	for i, us := range statuses {
+		su.Machines[i] = &UpgradeStatus{
+			MachineID: us.MachineID,
+			Version:   us.Version,
+			State:     us.State,
+			Reason:    us.Reason,
+			Time:      us.Time.UTC().Format(time.RFC3339),
+		}
+	}
+	return su
+}
+
+// MapClusterConfigToSchemaClusterConfig maps the given ClusterConfig
+func MapClusterConfigToSchemaClusterConfig(cc *registry.ClusterConfig) *ClusterConfig {
+	sc := &ClusterConfig{
+		Version: int64(cc.Version),
+		Values:  cc.Values,
+	}
+	if !cc.Updated.IsZero() {
+		sc.Updated = cc.Updated.UTC().Format(time.RFC3339)
+	}
+	return sc
+}
+
+func MapUnitCompletionsToSchemaUnitCompletions(completions []registry.UnitCompletion) []*UnitCompletion {
+	sCompletions := make([]*UnitCompletion, len(completions))
+	for i, uc := range completions {
+		sCompletions[i] = &UnitCompletion{
+			Name:      uc.Name,
+			MachineID: uc.MachineID,
+			Result:    uc.Result,
+			ExitCode:  int64(uc.ExitCode),
+			Finished:  uc.Finished.UTC().Format(time.RFC3339),
+		}
+		if !uc.Started.IsZero() {
+			sCompletions[i].Started = uc.Started.UTC().Format(time.RFC3339)
+		}
+	}
+	return sCompletions
+}
+
+func MapPlacementToSchemaUnitPlacement(p *engine.Placement) *UnitPlacement {
+	return &UnitPlacement{
+		MachineID:  p.MachineID,
+		Preempts:   p.Preempts,
+		Reason:     p.Reason,
+		Rejections: mapMachineRejections(p.Rejections, p.Shortfalls),
+	}
+}
+
+func MapUnitRejectionsToSchemaUnitRejections(ur *registry.UnitRejections) *UnitRejections {
+	sur := &UnitRejections{
+		Time:       ur.Time.UTC().Format(time.RFC3339),
+		Reason:     ur.Reason,
+		Rejections: mapMachineRejections(ur.Machines, ur.Shortfalls),
+	}
+	if ur.BackoffUntil != nil {
+		sur.BackoffUntil = ur.BackoffUntil.UTC().Format(time.RFC3339)
+	}
+	return sur
+}
+
+// mapMachineRejections maps the given reasons and Shortfalls, indexed by
+// machine ID, to MachineRejections ordered by machine ID
+func mapMachineRejections(reasons map[string]string, shortfalls map[string][]job.Shortfall) []*MachineRejection {
+	machIDs := make([]string, 0, len(reasons))
+	for machID := range reasons {
+		machIDs = append(machIDs, machID)
+	}
+	sort.Strings(machIDs)
+
+	rejections := make([]*MachineRejection, len(machIDs))
+	for i, machID := range machIDs {
+		rejections[i] = &MachineRejection{
+			MachineID:  machID,
+			Reason:     reasons[machID],
+			Shortfalls: mapShortfalls(shortfalls[machID]),
+		}
+	}
+	return rejections
+}
+
+func mapShortfalls(sf []job.Shortfall) []*Shortfall {
+	if len(sf) == 0 {
+		return nil
+	}
+	ssf := make([]*Shortfall, len(sf))
+	for i, s := range sf {
+		ssf[i] = &Shortfall{Constraint: s.Constraint, Reason: s.Reason}
+	}
+	return ssf
+}
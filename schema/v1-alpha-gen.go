@@ -45,9 +45,19 @@ func New(client *http.Client) (*Service, error) {
 		return nil, errors.New("client is nil")
 	}
 	s := &Service{client: client, BasePath: basePath}
+	s.Audit = NewAuditService(s)
+	s.ClusterConfig = NewClusterConfigService(s)
+	s.Completions = NewCompletionsService(s)
+	s.Config = NewConfigService(s)
+	s.Engine = NewEngineService(s)
+	s.Events = NewEventsService(s)
 	s.Machines = NewMachinesService(s)
+	s.Quotas = NewQuotasService(s)
+	s.Stacks = NewStacksService(s)
+	s.UnitFiles = NewUnitFilesService(s)
 	s.UnitState = NewUnitStateService(s)
 	s.Units = NewUnitsService(s)
+	s.Upgrade = NewUpgradeService(s)
 	return s, nil
 }
 
@@ -55,11 +65,85 @@ type Service struct {
 	client   *http.Client
 	BasePath string // API endpoint base URL
 
+	Audit *AuditService
+
+	ClusterConfig *ClusterConfigService
+
+	Completions *CompletionsService
+
+	Config *ConfigService
+
+	Engine *EngineService
+
+	Events *EventsService
+
 	Machines *MachinesService
 
+	Quotas *QuotasService
+
+	Stacks *StacksService
+
+	UnitFiles *UnitFilesService
+
 	UnitState *UnitStateService
 
 	Units *UnitsService
+
+	Upgrade *UpgradeService
+}
+
+func NewAuditService(s *Service) *AuditService {
+	rs := &AuditService{s: s}
+	return rs
+}
+
+type AuditService struct {
+	s *Service
+}
+
+func NewClusterConfigService(s *Service) *ClusterConfigService {
+	rs := &ClusterConfigService{s: s}
+	return rs
+}
+
+type ClusterConfigService struct {
+	s *Service
+}
+
+func NewCompletionsService(s *Service) *CompletionsService {
+	rs := &CompletionsService{s: s}
+	return rs
+}
+
+type CompletionsService struct {
+	s *Service
+}
+
+func NewConfigService(s *Service) *ConfigService {
+	rs := &ConfigService{s: s}
+	return rs
+}
+
+type ConfigService struct {
+	s *Service
+}
+
+func NewEngineService(s *Service) *EngineService {
+	rs := &EngineService{s: s}
+	return rs
+}
+
+type EngineService struct {
+	s *Service
+}
+
+func NewEventsService(s *Service) *EventsService {
+	rs := &EventsService{s: s}
+	return rs
+}
+
+type EventsService struct {
+	s *Service
 }
 
 func NewMachinesService(s *Service) *MachinesService {
@@ -71,6 +155,33 @@ type MachinesService struct {
 	s *Service
 }
 
+func NewQuotasService(s *Service) *QuotasService {
+	rs := &QuotasService{s: s}
+	return rs
+}
+
+type QuotasService struct {
+	s *Service
+}
+
+func NewStacksService(s *Service) *StacksService {
+	rs := &StacksService{s: s}
+	return rs
+}
+
+type StacksService struct {
+	s *Service
+}
+
+func NewUnitFilesService(s *Service) *UnitFilesService {
+	rs := &UnitFilesService{s: s}
+	return rs
+}
+
+type UnitFilesService struct {
+	s *Service
+}
+
 func NewUnitStateService(s *Service) *UnitStateService {
 	rs := &UnitStateService{s: s}
 	return rs
@@ -89,12 +200,171 @@ type UnitsService struct {
 	s *Service
 }
 
+func NewUpgradeService(s *Service) *UpgradeService {
+	rs := &UpgradeService{s: s}
+	return rs
+}
+
+type UpgradeService struct {
+	s *Service
+}
+
+type Address struct {
+	Ip string `json:"ip,omitempty"`
+
+	Scope string `json:"scope,omitempty"`
+}
+
+type AuditEntry struct {
+	Action string `json:"action,omitempty"`
+
+	Index int64 `json:"index,omitempty"`
+
+	PreviousState string `json:"previousState,omitempty"`
+
+	State string `json:"state,omitempty"`
+
+	Time string `json:"time,omitempty"`
+
+	UnitName string `json:"unitName,omitempty"`
+
+	User string `json:"user,omitempty"`
+}
+
+type AuditEntryPage struct {
+	Entries []*AuditEntry `json:"entries,omitempty"`
+}
+
+type ClusterConfig struct {
+	Updated string `json:"updated,omitempty"`
+
+	Values map[string]string `json:"values,omitempty"`
+
+	Version int64 `json:"version,omitempty"`
+}
+
+type CommandExecution struct {
+	Command []string `json:"command,omitempty"`
+}
+
+type ConfigValue struct {
+	Key string `json:"key,omitempty"`
+
+	Secret bool `json:"secret,omitempty"`
+
+	Value string `json:"value,omitempty"`
+}
+
+type ConfigValuePage struct {
+	Values []*ConfigValue `json:"values,omitempty"`
+}
+
+type Cordon struct {
+	Drain bool `json:"drain,omitempty"`
+}
+
+type CronRun struct {
+	Finished string `json:"finished,omitempty"`
+
+	MachineID string `json:"machineID,omitempty"`
+
+	Name string `json:"name,omitempty"`
+
+	Result string `json:"result,omitempty"`
+
+	Tick string `json:"tick,omitempty"`
+}
+
+type CronRunPage struct {
+	Runs []*CronRun `json:"runs,omitempty"`
+}
+
+type EngineStatus struct {
+	LastReconcile string `json:"lastReconcile,omitempty"`
+
+	LastReconcileDuration float64 `json:"lastReconcileDuration,omitempty"`
+
+	LeaseExpires string `json:"leaseExpires,omitempty"`
+
+	MachineID string `json:"machineID,omitempty"`
+
+	Reconciles int64 `json:"reconciles,omitempty"`
+
+	Shards []int64 `json:"shards,omitempty"`
+
+	Version int64 `json:"version,omitempty"`
+}
+
+type EngineStatusPage struct {
+	Engines []*EngineStatus `json:"engines,omitempty"`
+}
+
+type Event struct {
+	Index int64 `json:"index,omitempty"`
+
+	MachineID string `json:"machineID,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+
+	Time string `json:"time,omitempty"`
+
+	Type string `json:"type,omitempty"`
+
+	UnitName string `json:"unitName,omitempty"`
+}
+
+type EventPage struct {
+	Events []*Event `json:"events,omitempty"`
+}
+
 type Machine struct {
+	Addresses []*Address `json:"addresses,omitempty"`
+
+	AllocatedCPUUnits int64 `json:"allocatedCPUUnits,omitempty"`
+
+	AllocatedDisk int64 `json:"allocatedDisk,omitempty"`
+
+	AllocatedExtendedResources string `json:"allocatedExtendedResources,omitempty"`
+
+	AllocatedMemory int64 `json:"allocatedMemory,omitempty"`
+
+	AvailableMemory int64 `json:"availableMemory,omitempty"`
+
+	Cordoned bool `json:"cordoned,omitempty"`
+
+	CpuOvercommit float64 `json:"cpuOvercommit,omitempty"`
+
+	Draining bool `json:"draining,omitempty"`
+
+	ExtendedResources string `json:"extendedResources,omitempty"`
+
 	Id string `json:"id,omitempty"`
 
+	LastHeartbeat string `json:"lastHeartbeat,omitempty"`
+
+	MaxUnits int64 `json:"maxUnits,omitempty"`
+
+	MemoryOvercommit float64 `json:"memoryOvercommit,omitempty"`
+
+	MemoryPolicy string `json:"memoryPolicy,omitempty"`
+
 	Metadata map[string]string `json:"metadata,omitempty"`
 
 	PrimaryIP string `json:"primaryIP,omitempty"`
+
+	ReservedCPUUnits int64 `json:"reservedCPUUnits,omitempty"`
+
+	ReservedDisk int64 `json:"reservedDisk,omitempty"`
+
+	ReservedMemory int64 `json:"reservedMemory,omitempty"`
+
+	Taints []*Taint `json:"taints,omitempty"`
+
+	TotalCPUUnits int64 `json:"totalCPUUnits,omitempty"`
+
+	TotalDisk int64 `json:"totalDisk,omitempty"`
+
+	TotalMemory int64 `json:"totalMemory,omitempty"`
 }
 
 type MachinePage struct {
@@ -103,18 +373,116 @@ type MachinePage struct {
 	NextPageToken string `json:"nextPageToken,omitempty"`
 }
 
+type MachineRejection struct {
+	MachineID string `json:"machineID,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+
+	Shortfalls []*Shortfall `json:"shortfalls,omitempty"`
+}
+
+type MetadataValue struct {
+	Value string `json:"value,omitempty"`
+}
+
+type Quota struct {
+	Cores int64 `json:"cores,omitempty"`
+
+	Memory int64 `json:"memory,omitempty"`
+
+	Namespace string `json:"namespace,omitempty"`
+
+	Units int64 `json:"units,omitempty"`
+
+	UsedCores int64 `json:"usedCores,omitempty"`
+
+	UsedMemory int64 `json:"usedMemory,omitempty"`
+
+	UsedUnits int64 `json:"usedUnits,omitempty"`
+}
+
+type QuotaPage struct {
+	Quotas []*Quota `json:"quotas,omitempty"`
+}
+
+type Scale struct {
+	Count int64 `json:"count,omitempty"`
+}
+
+type Shortfall struct {
+	Constraint string `json:"constraint,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+type Stack struct {
+	Name string `json:"name,omitempty"`
+
+	Units []string `json:"units,omitempty"`
+}
+
+type StackPage struct {
+	Stacks []*Stack `json:"stacks,omitempty"`
+}
+
+type StepDown struct {
+	Hold int64 `json:"hold,omitempty"`
+
+	MachineID string `json:"machineID,omitempty"`
+}
+
+type Taint struct {
+	Effect string `json:"effect,omitempty"`
+
+	Key string `json:"key,omitempty"`
+
+	Value string `json:"value,omitempty"`
+}
+
 type Unit struct {
 	CurrentState string `json:"currentState,omitempty"`
 
 	DesiredState string `json:"desiredState,omitempty"`
 
+	Labels map[string]string `json:"labels,omitempty"`
+
+	MachineID string `json:"machineID,omitempty"`
+
+	Name string `json:"name,omitempty"`
+
+	Options []*UnitOption `json:"options,omitempty"`
+
+	Signature string `json:"signature,omitempty"`
+}
+
+type UnitCompletion struct {
+	ExitCode int64 `json:"exitCode,omitempty"`
+
+	Finished string `json:"finished,omitempty"`
+
 	MachineID string `json:"machineID,omitempty"`
 
 	Name string `json:"name,omitempty"`
 
+	Result string `json:"result,omitempty"`
+
+	Started string `json:"started,omitempty"`
+}
+
+type UnitCompletionPage struct {
+	Completions []*UnitCompletion `json:"completions,omitempty"`
+}
+
+type UnitFile struct {
+	Hash string `json:"hash,omitempty"`
+
 	Options []*UnitOption `json:"options,omitempty"`
 }
 
+type UnitLabels struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
 type UnitOption struct {
 	Name string `json:"name,omitempty"`
 
@@ -129,18 +497,46 @@ type UnitPage struct {
 	Units []*Unit `json:"units,omitempty"`
 }
 
+type UnitPlacement struct {
+	MachineID string `json:"machineID,omitempty"`
+
+	Preempts []string `json:"preempts,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+
+	Rejections []*MachineRejection `json:"rejections,omitempty"`
+}
+
+type UnitRejections struct {
+	BackoffUntil string `json:"backoffUntil,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+
+	Rejections []*MachineRejection `json:"rejections,omitempty"`
+
+	Time string `json:"time,omitempty"`
+}
+
 type UnitState struct {
 	Hash string `json:"hash,omitempty"`
 
+	Health string `json:"health,omitempty"`
+
 	MachineID string `json:"machineID,omitempty"`
 
 	Name string `json:"name,omitempty"`
 
+	Reason string `json:"reason,omitempty"`
+
 	SystemdActiveState string `json:"systemdActiveState,omitempty"`
 
 	SystemdLoadState string `json:"systemdLoadState,omitempty"`
 
 	SystemdSubState string `json:"systemdSubState,omitempty"`
+
+	UsedCPUUnits int64 `json:"usedCPUUnits,omitempty"`
+
+	UsedMemory int64 `json:"usedMemory,omitempty"`
 }
 
 type UnitStatePage struct {
@@ -149,180 +545,2374 @@ type UnitStatePage struct {
 	States []*UnitState `json:"states,omitempty"`
 }
 
-// method id "fleet.Machine.List":
+type UnitVersion struct {
+	Created string `json:"created,omitempty"`
 
-type MachinesListCall struct {
+	Hash string `json:"hash,omitempty"`
+
+	Options []*UnitOption `json:"options,omitempty"`
+
+	Version int64 `json:"version,omitempty"`
+}
+
+type UnitVersionPage struct {
+	Versions []*UnitVersion `json:"versions,omitempty"`
+}
+
+type Upgrade struct {
+	Created string `json:"created,omitempty"`
+
+	DomainKey string `json:"domainKey,omitempty"`
+
+	Machines []*UpgradeStatus `json:"machines,omitempty"`
+
+	Version string `json:"version,omitempty"`
+}
+
+type UpgradeStatus struct {
+	MachineID string `json:"machineID,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+
+	State string `json:"state,omitempty"`
+
+	Time string `json:"time,omitempty"`
+
+	Version string `json:"version,omitempty"`
+}
+
+// method id "fleet.Audit.List":
+
+type AuditListCall struct {
 	s    *Service
 	opt_ map[string]interface{}
 }
 
-// List: Retrieve a page of Machine objects.
-func (r *MachinesService) List() *MachinesListCall {
-	c := &MachinesListCall{s: r.s, opt_: make(map[string]interface{})}
-	return c
+// List: Retrieve the audit log of changes made to the Units of the
+// cluster.
+func (r *AuditService) List() *AuditListCall {
+	c := &AuditListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+func (c *AuditListCall) Do() (*AuditEntryPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "audit")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *AuditEntryPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the audit log of changes made to the Units of the cluster.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Audit.List",
+	//   "path": "audit",
+	//   "response": {
+	//     "$ref": "AuditEntryPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.ClusterConfig.Get":
+
+type ClusterConfigGetCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// Get: Retrieve the options of fleet.conf set for the whole cluster.
+func (r *ClusterConfigService) Get() *ClusterConfigGetCall {
+	c := &ClusterConfigGetCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+func (c *ClusterConfigGetCall) Do() (*ClusterConfig, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "cluster-config")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *ClusterConfig
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the options of fleet.conf set for the whole cluster.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.ClusterConfig.Get",
+	//   "path": "cluster-config",
+	//   "response": {
+	//     "$ref": "ClusterConfig"
+	//   }
+	// }
+
+}
+
+// method id "fleet.ClusterConfig.Set":
+
+type ClusterConfigSetCall struct {
+	s             *Service
+	clusterconfig *ClusterConfig
+	opt_          map[string]interface{}
+}
+
+// Set: Replace the options of fleet.conf set for the whole cluster,
+// provided the version given is still current.
+func (r *ClusterConfigService) Set(clusterconfig *ClusterConfig) *ClusterConfigSetCall {
+	c := &ClusterConfigSetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.clusterconfig = clusterconfig
+	return c
+}
+
+func (c *ClusterConfigSetCall) Do() (*ClusterConfig, error) {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.clusterconfig)
+	if err != nil {
+		return nil, err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "cluster-config")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("PUT", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *ClusterConfig
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Replace the options of fleet.conf set for the whole cluster, provided the version given is still current.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.ClusterConfig.Set",
+	//   "path": "cluster-config",
+	//   "request": {
+	//     "$ref": "ClusterConfig"
+	//   },
+	//   "response": {
+	//     "$ref": "ClusterConfig"
+	//   }
+	// }
+
+}
+
+// method id "fleet.UnitCompletion.List":
+
+type CompletionsListCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// List: Retrieve how the last runs of all batch Units ended.
+func (r *CompletionsService) List() *CompletionsListCall {
+	c := &CompletionsListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+func (c *CompletionsListCall) Do() (*UnitCompletionPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "completions")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *UnitCompletionPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve how the last runs of all batch Units ended.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.UnitCompletion.List",
+	//   "path": "completions",
+	//   "response": {
+	//     "$ref": "UnitCompletionPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Config.Delete":
+
+type ConfigDeleteCall struct {
+	s         *Service
+	namespace string
+	key       string
+	opt_      map[string]interface{}
+}
+
+// Delete: Delete the ConfigValue stored under a key of a namespace.
+func (r *ConfigService) Delete(namespace string, key string) *ConfigDeleteCall {
+	c := &ConfigDeleteCall{s: r.s, opt_: make(map[string]interface{})}
+	c.namespace = namespace
+	c.key = key
+	return c
+}
+
+func (c *ConfigDeleteCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "config/{namespace}/{key}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{namespace}", url.QueryEscape(c.namespace), 1)
+	req.URL.Path = strings.Replace(req.URL.Path, "{key}", url.QueryEscape(c.key), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Delete the ConfigValue stored under a key of a namespace.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Config.Delete",
+	//   "parameterOrder": [
+	//     "namespace",
+	//     "key"
+	//   ],
+	//   "parameters": {
+	//     "key": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     },
+	//     "namespace": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "config/{namespace}/{key}"
+	// }
+
+}
+
+// method id "fleet.Config.List":
+
+type ConfigListCall struct {
+	s         *Service
+	namespace string
+	opt_      map[string]interface{}
+}
+
+// List: Retrieve all ConfigValue objects of a namespace.
+func (r *ConfigService) List(namespace string) *ConfigListCall {
+	c := &ConfigListCall{s: r.s, opt_: make(map[string]interface{})}
+	c.namespace = namespace
+	return c
+}
+
+func (c *ConfigListCall) Do() (*ConfigValuePage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "config/{namespace}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{namespace}", url.QueryEscape(c.namespace), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *ConfigValuePage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve all ConfigValue objects of a namespace.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Config.List",
+	//   "parameterOrder": [
+	//     "namespace"
+	//   ],
+	//   "parameters": {
+	//     "namespace": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "config/{namespace}",
+	//   "response": {
+	//     "$ref": "ConfigValuePage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Config.Set":
+
+type ConfigSetCall struct {
+	s           *Service
+	namespace   string
+	key         string
+	configvalue *ConfigValue
+	opt_        map[string]interface{}
+}
+
+// Set: Store a ConfigValue under a key of a namespace.
+func (r *ConfigService) Set(namespace string, key string, configvalue *ConfigValue) *ConfigSetCall {
+	c := &ConfigSetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.namespace = namespace
+	c.key = key
+	c.configvalue = configvalue
+	return c
+}
+
+func (c *ConfigSetCall) Do() error {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.configvalue)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "config/{namespace}/{key}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{namespace}", url.QueryEscape(c.namespace), 1)
+	req.URL.Path = strings.Replace(req.URL.Path, "{key}", url.QueryEscape(c.key), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Store a ConfigValue under a key of a namespace.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Config.Set",
+	//   "parameterOrder": [
+	//     "namespace",
+	//     "key"
+	//   ],
+	//   "parameters": {
+	//     "key": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     },
+	//     "namespace": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "config/{namespace}/{key}",
+	//   "request": {
+	//     "$ref": "ConfigValue"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Engine.Get":
+
+type EngineGetCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// Get: Retrieve the status of the engines holding the lease of the lead
+// engine or of shards of the scheduling work.
+func (r *EngineService) Get() *EngineGetCall {
+	c := &EngineGetCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+func (c *EngineGetCall) Do() (*EngineStatusPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "engine")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *EngineStatusPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the status of the engines holding the lease of the lead engine or of shards of the scheduling work.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Engine.Get",
+	//   "path": "engine",
+	//   "response": {
+	//     "$ref": "EngineStatusPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Engine.StepDown":
+
+type EngineStepDownCall struct {
+	s        *Service
+	stepdown *StepDown
+	opt_     map[string]interface{}
+}
+
+// StepDown: Ask the engine of a Machine to release its leases and not
+// to acquire any for a number of seconds.
+func (r *EngineService) StepDown(stepdown *StepDown) *EngineStepDownCall {
+	c := &EngineStepDownCall{s: r.s, opt_: make(map[string]interface{})}
+	c.stepdown = stepdown
+	return c
+}
+
+func (c *EngineStepDownCall) Do() error {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.stepdown)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "engine/step-down")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("POST", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Ask the engine of a Machine to release its leases and not to acquire any for a number of seconds.",
+	//   "httpMethod": "POST",
+	//   "id": "fleet.Engine.StepDown",
+	//   "path": "engine/step-down",
+	//   "request": {
+	//     "$ref": "StepDown"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Events.List":
+
+type EventsListCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// List: Retrieve the cluster events recorded after a given index.
+func (r *EventsService) List() *EventsListCall {
+	c := &EventsListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+// Since sets the optional parameter "since":
+func (c *EventsListCall) Since(since int64) *EventsListCall {
+	c.opt_["since"] = since
+	return c
+}
+
+// Wait sets the optional parameter "wait":
+func (c *EventsListCall) Wait(wait int64) *EventsListCall {
+	c.opt_["wait"] = wait
+	return c
+}
+
+func (c *EventsListCall) Do() (*EventPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	if v, ok := c.opt_["since"]; ok {
+		params.Set("since", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["wait"]; ok {
+		params.Set("wait", fmt.Sprintf("%v", v))
+	}
+	urls := googleapi.ResolveRelative(c.s.BasePath, "events")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *EventPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the cluster events recorded after a given index.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Events.List",
+	//   "parameters": {
+	//     "since": {
+	//       "location": "query",
+	//       "type": "integer"
+	//     },
+	//     "wait": {
+	//       "location": "query",
+	//       "type": "integer"
+	//     }
+	//   },
+	//   "path": "events",
+	//   "response": {
+	//     "$ref": "EventPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Machine.Cordon":
+
+type MachinesCordonCall struct {
+	s         *Service
+	machineID string
+	cordon    *Cordon
+	opt_      map[string]interface{}
+}
+
+// Cordon: Stop scheduling new Units to a Machine, optionally draining
+// the Units it already runs.
+func (r *MachinesService) Cordon(machineID string, cordon *Cordon) *MachinesCordonCall {
+	c := &MachinesCordonCall{s: r.s, opt_: make(map[string]interface{})}
+	c.machineID = machineID
+	c.cordon = cordon
+	return c
+}
+
+func (c *MachinesCordonCall) Do() error {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.cordon)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "machines/{machineID}/cordon")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{machineID}", url.QueryEscape(c.machineID), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Stop scheduling new Units to a Machine, optionally draining the Units it already runs.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Machine.Cordon",
+	//   "parameterOrder": [
+	//     "machineID"
+	//   ],
+	//   "parameters": {
+	//     "machineID": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "machines/{machineID}/cordon",
+	//   "request": {
+	//     "$ref": "Cordon"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Machine.DeleteMetadata":
+
+type MachinesDeleteMetadataCall struct {
+	s         *Service
+	machineID string
+	key       string
+	opt_      map[string]interface{}
+}
+
+// DeleteMetadata: Remove a metadata key previously set for a Machine.
+func (r *MachinesService) DeleteMetadata(machineID string, key string) *MachinesDeleteMetadataCall {
+	c := &MachinesDeleteMetadataCall{s: r.s, opt_: make(map[string]interface{})}
+	c.machineID = machineID
+	c.key = key
+	return c
+}
+
+func (c *MachinesDeleteMetadataCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "machines/{machineID}/metadata/{key}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{machineID}", url.QueryEscape(c.machineID), 1)
+	req.URL.Path = strings.Replace(req.URL.Path, "{key}", url.QueryEscape(c.key), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Remove a metadata key previously set for a Machine.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Machine.DeleteMetadata",
+	//   "parameterOrder": [
+	//     "machineID",
+	//     "key"
+	//   ],
+	//   "parameters": {
+	//     "key": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     },
+	//     "machineID": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "machines/{machineID}/metadata/{key}"
+	// }
+
+}
+
+// method id "fleet.Machine.List":
+
+type MachinesListCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// List: Retrieve a page of Machine objects.
+func (r *MachinesService) List() *MachinesListCall {
+	c := &MachinesListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+// NextPageToken sets the optional parameter "nextPageToken":
+func (c *MachinesListCall) NextPageToken(nextPageToken string) *MachinesListCall {
+	c.opt_["nextPageToken"] = nextPageToken
+	return c
+}
+
+func (c *MachinesListCall) Do() (*MachinePage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	if v, ok := c.opt_["nextPageToken"]; ok {
+		params.Set("nextPageToken", fmt.Sprintf("%v", v))
+	}
+	urls := googleapi.ResolveRelative(c.s.BasePath, "machines")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *MachinePage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve a page of Machine objects.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Machine.List",
+	//   "parameters": {
+	//     "nextPageToken": {
+	//       "location": "query",
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "machines",
+	//   "response": {
+	//     "$ref": "MachinePage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Machine.SetMetadata":
+
+type MachinesSetMetadataCall struct {
+	s             *Service
+	machineID     string
+	key           string
+	metadatavalue *MetadataValue
+	opt_          map[string]interface{}
+}
+
+// SetMetadata: Set the value of a metadata key of a Machine.
+func (r *MachinesService) SetMetadata(machineID string, key string, metadatavalue *MetadataValue) *MachinesSetMetadataCall {
+	c := &MachinesSetMetadataCall{s: r.s, opt_: make(map[string]interface{})}
+	c.machineID = machineID
+	c.key = key
+	c.metadatavalue = metadatavalue
+	return c
+}
+
+func (c *MachinesSetMetadataCall) Do() error {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.metadatavalue)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "machines/{machineID}/metadata/{key}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{machineID}", url.QueryEscape(c.machineID), 1)
+	req.URL.Path = strings.Replace(req.URL.Path, "{key}", url.QueryEscape(c.key), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Set the value of a metadata key of a Machine.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Machine.SetMetadata",
+	//   "parameterOrder": [
+	//     "machineID",
+	//     "key"
+	//   ],
+	//   "parameters": {
+	//     "key": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     },
+	//     "machineID": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "machines/{machineID}/metadata/{key}",
+	//   "request": {
+	//     "$ref": "MetadataValue"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Machine.Taint":
+
+type MachinesTaintCall struct {
+	s         *Service
+	machineID string
+	key       string
+	taint     *Taint
+	opt_      map[string]interface{}
+}
+
+// Taint: Set a Taint on a Machine, keeping Units that do not tolerate
+// it from being scheduled to it.
+func (r *MachinesService) Taint(machineID string, key string, taint *Taint) *MachinesTaintCall {
+	c := &MachinesTaintCall{s: r.s, opt_: make(map[string]interface{})}
+	c.machineID = machineID
+	c.key = key
+	c.taint = taint
+	return c
+}
+
+func (c *MachinesTaintCall) Do() error {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.taint)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "machines/{machineID}/taints/{key}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{machineID}", url.QueryEscape(c.machineID), 1)
+	req.URL.Path = strings.Replace(req.URL.Path, "{key}", url.QueryEscape(c.key), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Set a Taint on a Machine, keeping Units that do not tolerate it from being scheduled to it.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Machine.Taint",
+	//   "parameterOrder": [
+	//     "machineID",
+	//     "key"
+	//   ],
+	//   "parameters": {
+	//     "key": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     },
+	//     "machineID": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "machines/{machineID}/taints/{key}",
+	//   "request": {
+	//     "$ref": "Taint"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Machine.Uncordon":
+
+type MachinesUncordonCall struct {
+	s         *Service
+	machineID string
+	opt_      map[string]interface{}
+}
+
+// Uncordon: Allow Units to be scheduled to a previously-cordoned
+// Machine again.
+func (r *MachinesService) Uncordon(machineID string) *MachinesUncordonCall {
+	c := &MachinesUncordonCall{s: r.s, opt_: make(map[string]interface{})}
+	c.machineID = machineID
+	return c
+}
+
+func (c *MachinesUncordonCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "machines/{machineID}/cordon")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{machineID}", url.QueryEscape(c.machineID), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Allow Units to be scheduled to a previously-cordoned Machine again.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Machine.Uncordon",
+	//   "parameterOrder": [
+	//     "machineID"
+	//   ],
+	//   "parameters": {
+	//     "machineID": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "machines/{machineID}/cordon"
+	// }
+
+}
+
+// method id "fleet.Machine.Untaint":
+
+type MachinesUntaintCall struct {
+	s         *Service
+	machineID string
+	key       string
+	opt_      map[string]interface{}
+}
+
+// Untaint: Remove a Taint from a Machine.
+func (r *MachinesService) Untaint(machineID string, key string) *MachinesUntaintCall {
+	c := &MachinesUntaintCall{s: r.s, opt_: make(map[string]interface{})}
+	c.machineID = machineID
+	c.key = key
+	return c
+}
+
+func (c *MachinesUntaintCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "machines/{machineID}/taints/{key}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{machineID}", url.QueryEscape(c.machineID), 1)
+	req.URL.Path = strings.Replace(req.URL.Path, "{key}", url.QueryEscape(c.key), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Remove a Taint from a Machine.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Machine.Untaint",
+	//   "parameterOrder": [
+	//     "machineID",
+	//     "key"
+	//   ],
+	//   "parameters": {
+	//     "key": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     },
+	//     "machineID": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "machines/{machineID}/taints/{key}"
+	// }
+
+}
+
+// method id "fleet.Quota.Delete":
+
+type QuotasDeleteCall struct {
+	s         *Service
+	namespace string
+	opt_      map[string]interface{}
+}
+
+// Delete: Delete the Quota of a namespace.
+func (r *QuotasService) Delete(namespace string) *QuotasDeleteCall {
+	c := &QuotasDeleteCall{s: r.s, opt_: make(map[string]interface{})}
+	c.namespace = namespace
+	return c
+}
+
+func (c *QuotasDeleteCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "quotas/{namespace}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{namespace}", url.QueryEscape(c.namespace), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Delete the Quota of a namespace.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Quota.Delete",
+	//   "parameterOrder": [
+	//     "namespace"
+	//   ],
+	//   "parameters": {
+	//     "namespace": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "quotas/{namespace}"
+	// }
+
+}
+
+// method id "fleet.Quota.List":
+
+type QuotasListCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// List: Retrieve the Quotas of all namespaces along with their usage.
+func (r *QuotasService) List() *QuotasListCall {
+	c := &QuotasListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+func (c *QuotasListCall) Do() (*QuotaPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "quotas")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *QuotaPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the Quotas of all namespaces along with their usage.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Quota.List",
+	//   "path": "quotas",
+	//   "response": {
+	//     "$ref": "QuotaPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Quota.Set":
+
+type QuotasSetCall struct {
+	s         *Service
+	namespace string
+	quota     *Quota
+	opt_      map[string]interface{}
+}
+
+// Set: Set the Quota of a namespace.
+func (r *QuotasService) Set(namespace string, quota *Quota) *QuotasSetCall {
+	c := &QuotasSetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.namespace = namespace
+	c.quota = quota
+	return c
+}
+
+func (c *QuotasSetCall) Do() error {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.quota)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "quotas/{namespace}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{namespace}", url.QueryEscape(c.namespace), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Set the Quota of a namespace.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Quota.Set",
+	//   "parameterOrder": [
+	//     "namespace"
+	//   ],
+	//   "parameters": {
+	//     "namespace": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "quotas/{namespace}",
+	//   "request": {
+	//     "$ref": "Quota"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Stack.Create":
+
+type StacksCreateCall struct {
+	s         *Service
+	stackName string
+	stack     *Stack
+	opt_      map[string]interface{}
+}
+
+// Create: Create a Stack of Units the engine schedules together.
+func (r *StacksService) Create(stackName string, stack *Stack) *StacksCreateCall {
+	c := &StacksCreateCall{s: r.s, opt_: make(map[string]interface{})}
+	c.stackName = stackName
+	c.stack = stack
+	return c
+}
+
+func (c *StacksCreateCall) Do() error {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.stack)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "stacks/{stackName}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{stackName}", url.QueryEscape(c.stackName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Create a Stack of Units the engine schedules together.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Stack.Create",
+	//   "parameterOrder": [
+	//     "stackName"
+	//   ],
+	//   "parameters": {
+	//     "stackName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "stacks/{stackName}",
+	//   "request": {
+	//     "$ref": "Stack"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Stack.Delete":
+
+type StacksDeleteCall struct {
+	s         *Service
+	stackName string
+	opt_      map[string]interface{}
+}
+
+// Delete: Delete the referenced Stack object, leaving its Units alone.
+func (r *StacksService) Delete(stackName string) *StacksDeleteCall {
+	c := &StacksDeleteCall{s: r.s, opt_: make(map[string]interface{})}
+	c.stackName = stackName
+	return c
+}
+
+func (c *StacksDeleteCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "stacks/{stackName}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{stackName}", url.QueryEscape(c.stackName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Delete the referenced Stack object, leaving its Units alone.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Stack.Delete",
+	//   "parameterOrder": [
+	//     "stackName"
+	//   ],
+	//   "parameters": {
+	//     "stackName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "stacks/{stackName}"
+	// }
+
+}
+
+// method id "fleet.Stack.Get":
+
+type StacksGetCall struct {
+	s         *Service
+	stackName string
+	opt_      map[string]interface{}
+}
+
+// Get: Retrieve a single Stack object.
+func (r *StacksService) Get(stackName string) *StacksGetCall {
+	c := &StacksGetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.stackName = stackName
+	return c
+}
+
+func (c *StacksGetCall) Do() (*Stack, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "stacks/{stackName}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{stackName}", url.QueryEscape(c.stackName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *Stack
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve a single Stack object.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Stack.Get",
+	//   "parameterOrder": [
+	//     "stackName"
+	//   ],
+	//   "parameters": {
+	//     "stackName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "stacks/{stackName}",
+	//   "response": {
+	//     "$ref": "Stack"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Stack.List":
+
+type StacksListCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// List: Retrieve all Stack objects.
+func (r *StacksService) List() *StacksListCall {
+	c := &StacksListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+func (c *StacksListCall) Do() (*StackPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "stacks")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *StackPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve all Stack objects.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Stack.List",
+	//   "path": "stacks",
+	//   "response": {
+	//     "$ref": "StackPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.UnitFiles.Get":
+
+type UnitFilesGetCall struct {
+	s    *Service
+	hash string
+	opt_ map[string]interface{}
+}
+
+// Get: Retrieve a unit file by the hash of its contents.
+func (r *UnitFilesService) Get(hash string) *UnitFilesGetCall {
+	c := &UnitFilesGetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.hash = hash
+	return c
+}
+
+func (c *UnitFilesGetCall) Do() (*UnitFile, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "unit-files/{hash}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{hash}", url.QueryEscape(c.hash), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *UnitFile
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve a unit file by the hash of its contents.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.UnitFiles.Get",
+	//   "parameterOrder": [
+	//     "hash"
+	//   ],
+	//   "parameters": {
+	//     "hash": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "unit-files/{hash}",
+	//   "response": {
+	//     "$ref": "UnitFile"
+	//   }
+	// }
+
+}
+
+// method id "fleet.UnitState.List":
+
+type UnitStateListCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// List: Retrieve a page of UnitState objects.
+func (r *UnitStateService) List() *UnitStateListCall {
+	c := &UnitStateListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+// Fields sets the optional parameter "fields":
+func (c *UnitStateListCall) Fields(fields string) *UnitStateListCall {
+	c.opt_["fields"] = fields
+	return c
+}
+
+// MachineID sets the optional parameter "machineID":
+func (c *UnitStateListCall) MachineID(machineID string) *UnitStateListCall {
+	c.opt_["machineID"] = machineID
+	return c
+}
+
+// NextPageToken sets the optional parameter "nextPageToken":
+func (c *UnitStateListCall) NextPageToken(nextPageToken string) *UnitStateListCall {
+	c.opt_["nextPageToken"] = nextPageToken
+	return c
+}
+
+// State sets the optional parameter "state":
+func (c *UnitStateListCall) State(state string) *UnitStateListCall {
+	c.opt_["state"] = state
+	return c
+}
+
+// UnitName sets the optional parameter "unitName":
+func (c *UnitStateListCall) UnitName(unitName string) *UnitStateListCall {
+	c.opt_["unitName"] = unitName
+	return c
+}
+
+func (c *UnitStateListCall) Do() (*UnitStatePage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	if v, ok := c.opt_["fields"]; ok {
+		params.Set("fields", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["machineID"]; ok {
+		params.Set("machineID", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["nextPageToken"]; ok {
+		params.Set("nextPageToken", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["state"]; ok {
+		params.Set("state", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["unitName"]; ok {
+		params.Set("unitName", fmt.Sprintf("%v", v))
+	}
+	urls := googleapi.ResolveRelative(c.s.BasePath, "state")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *UnitStatePage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve a page of UnitState objects.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.UnitState.List",
+	//   "parameters": {
+	//     "fields": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "machineID": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "nextPageToken": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "state": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "unitName": {
+	//       "location": "query",
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "state",
+	//   "response": {
+	//     "$ref": "UnitStatePage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.Delete":
+
+type UnitsDeleteCall struct {
+	s        *Service
+	unitName string
+	opt_     map[string]interface{}
+}
+
+// Delete: Delete the referenced Unit object.
+func (r *UnitsService) Delete(unitName string) *UnitsDeleteCall {
+	c := &UnitsDeleteCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	return c
+}
+
+func (c *UnitsDeleteCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Delete the referenced Unit object.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Unit.Delete",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
+	//   "parameters": {
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units/{unitName}"
+	// }
+
+}
+
+// method id "fleet.Unit.Get":
+
+type UnitsGetCall struct {
+	s        *Service
+	unitName string
+	opt_     map[string]interface{}
+}
+
+// Get: Retrieve a single Unit object.
+func (r *UnitsService) Get(unitName string) *UnitsGetCall {
+	c := &UnitsGetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	return c
+}
+
+func (c *UnitsGetCall) Do() (*Unit, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *Unit
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve a single Unit object.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Unit.Get",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
+	//   "parameters": {
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units/{unitName}",
+	//   "response": {
+	//     "$ref": "Unit"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.GetScale":
+
+type UnitsGetScaleCall struct {
+	s        *Service
+	unitName string
+	opt_     map[string]interface{}
+}
+
+// GetScale: Retrieve the number of instances the engine maintains of a
+// template Unit.
+func (r *UnitsService) GetScale(unitName string) *UnitsGetScaleCall {
+	c := &UnitsGetScaleCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	return c
+}
+
+func (c *UnitsGetScaleCall) Do() (*Scale, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/scale")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *Scale
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the number of instances the engine maintains of a template Unit.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Unit.GetScale",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
+	//   "parameters": {
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units/{unitName}/scale",
+	//   "response": {
+	//     "$ref": "Scale"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.History":
+
+type UnitsHistoryCall struct {
+	s        *Service
+	unitName string
+	opt_     map[string]interface{}
+}
+
+// History: Retrieve the latest events concerning a Unit, such as its
+// scheduling and changes of its state, which are kept after the Unit is
+// destroyed.
+func (r *UnitsService) History(unitName string) *UnitsHistoryCall {
+	c := &UnitsHistoryCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	return c
+}
+
+func (c *UnitsHistoryCall) Do() (*EventPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/history")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *EventPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the latest events concerning a Unit, such as its scheduling and changes of its state, which are kept after the Unit is destroyed.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Unit.History",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
+	//   "parameters": {
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units/{unitName}/history",
+	//   "response": {
+	//     "$ref": "EventPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.List":
+
+type UnitsListCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// List: Retrieve a page of Unit objects.
+func (r *UnitsService) List() *UnitsListCall {
+	c := &UnitsListCall{s: r.s, opt_: make(map[string]interface{})}
+	return c
+}
+
+// Fields sets the optional parameter "fields":
+func (c *UnitsListCall) Fields(fields string) *UnitsListCall {
+	c.opt_["fields"] = fields
+	return c
+}
+
+// MachineID sets the optional parameter "machineID":
+func (c *UnitsListCall) MachineID(machineID string) *UnitsListCall {
+	c.opt_["machineID"] = machineID
+	return c
+}
+
+// NextPageToken sets the optional parameter "nextPageToken":
+func (c *UnitsListCall) NextPageToken(nextPageToken string) *UnitsListCall {
+	c.opt_["nextPageToken"] = nextPageToken
+	return c
+}
+
+// Selector sets the optional parameter "selector": Select the Units
+// whose labels match the given selector, e.g. app=web,env!=staging.
+func (c *UnitsListCall) Selector(selector string) *UnitsListCall {
+	c.opt_["selector"] = selector
+	return c
+}
+
+// State sets the optional parameter "state":
+func (c *UnitsListCall) State(state string) *UnitsListCall {
+	c.opt_["state"] = state
+	return c
+}
+
+// UnitName sets the optional parameter "unitName":
+func (c *UnitsListCall) UnitName(unitName string) *UnitsListCall {
+	c.opt_["unitName"] = unitName
+	return c
+}
+
+func (c *UnitsListCall) Do() (*UnitPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	if v, ok := c.opt_["fields"]; ok {
+		params.Set("fields", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["machineID"]; ok {
+		params.Set("machineID", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["nextPageToken"]; ok {
+		params.Set("nextPageToken", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["selector"]; ok {
+		params.Set("selector", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["state"]; ok {
+		params.Set("state", fmt.Sprintf("%v", v))
+	}
+	if v, ok := c.opt_["unitName"]; ok {
+		params.Set("unitName", fmt.Sprintf("%v", v))
+	}
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *UnitPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve a page of Unit objects.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Unit.List",
+	//   "parameters": {
+	//     "fields": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "machineID": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "nextPageToken": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "selector": {
+	//       "description": "Select the Units whose labels match the given selector, e.g. app=web,env!=staging.",
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "state": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "unitName": {
+	//       "location": "query",
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units",
+	//   "response": {
+	//     "$ref": "UnitPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.Plan":
+
+type UnitsPlanCall struct {
+	s        *Service
+	unitName string
+	unit     *Unit
+	opt_     map[string]interface{}
+}
+
+// Plan: Determine where the engine would schedule a Unit, without
+// submitting it.
+func (r *UnitsService) Plan(unitName string, unit *Unit) *UnitsPlanCall {
+	c := &UnitsPlanCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	c.unit = unit
+	return c
+}
+
+// Strategy sets the optional parameter "strategy":
+func (c *UnitsPlanCall) Strategy(strategy string) *UnitsPlanCall {
+	c.opt_["strategy"] = strategy
+	return c
+}
+
+func (c *UnitsPlanCall) Do() (*UnitPlacement, error) {
+	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.unit)
+	if err != nil {
+		return nil, err
+	}
+	ctype := "application/json"
+	params := make(url.Values)
+	params.Set("alt", "json")
+	if v, ok := c.opt_["strategy"]; ok {
+		params.Set("strategy", fmt.Sprintf("%v", v))
+	}
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/plan")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("POST", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *UnitPlacement
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Determine where the engine would schedule a Unit, without submitting it.",
+	//   "httpMethod": "POST",
+	//   "id": "fleet.Unit.Plan",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
+	//   "parameters": {
+	//     "strategy": {
+	//       "location": "query",
+	//       "type": "string"
+	//     },
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units/{unitName}/plan",
+	//   "request": {
+	//     "$ref": "Unit"
+	//   },
+	//   "response": {
+	//     "$ref": "UnitPlacement"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.Rejections":
+
+type UnitsRejectionsCall struct {
+	s        *Service
+	unitName string
+	opt_     map[string]interface{}
+}
+
+// Rejections: Retrieve why the engine was last unable to schedule a
+// Unit.
+func (r *UnitsService) Rejections(unitName string) *UnitsRejectionsCall {
+	c := &UnitsRejectionsCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	return c
+}
+
+func (c *UnitsRejectionsCall) Do() (*UnitRejections, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/rejections")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *UnitRejections
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve why the engine was last unable to schedule a Unit.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Unit.Rejections",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
+	//   "parameters": {
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units/{unitName}/rejections",
+	//   "response": {
+	//     "$ref": "UnitRejections"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.Runs":
+
+type UnitsRunsCall struct {
+	s        *Service
+	unitName string
+	opt_     map[string]interface{}
+}
+
+// Runs: Retrieve the run history of a template Unit with a Schedule.
+func (r *UnitsService) Runs(unitName string) *UnitsRunsCall {
+	c := &UnitsRunsCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	return c
+}
+
+func (c *UnitsRunsCall) Do() (*CronRunPage, error) {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/runs")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("GET", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var ret *CronRunPage
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+	// {
+	//   "description": "Retrieve the run history of a template Unit with a Schedule.",
+	//   "httpMethod": "GET",
+	//   "id": "fleet.Unit.Runs",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
+	//   "parameters": {
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
+	//       "type": "string"
+	//     }
+	//   },
+	//   "path": "units/{unitName}/runs",
+	//   "response": {
+	//     "$ref": "CronRunPage"
+	//   }
+	// }
+
+}
+
+// method id "fleet.Unit.Scale":
+
+type UnitsScaleCall struct {
+	s        *Service
+	unitName string
+	scale    *Scale
+	opt_     map[string]interface{}
 }
 
-// NextPageToken sets the optional parameter "nextPageToken":
-func (c *MachinesListCall) NextPageToken(nextPageToken string) *MachinesListCall {
-	c.opt_["nextPageToken"] = nextPageToken
+// Scale: Set the number of instances the engine maintains of a template
+// Unit.
+func (r *UnitsService) Scale(unitName string, scale *Scale) *UnitsScaleCall {
+	c := &UnitsScaleCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	c.scale = scale
 	return c
 }
 
-func (c *MachinesListCall) Do() (*MachinePage, error) {
+func (c *UnitsScaleCall) Do() error {
 	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.scale)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
 	params := make(url.Values)
 	params.Set("alt", "json")
-	if v, ok := c.opt_["nextPageToken"]; ok {
-		params.Set("nextPageToken", fmt.Sprintf("%v", v))
-	}
-	urls := googleapi.ResolveRelative(c.s.BasePath, "machines")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/scale")
 	urls += "?" + params.Encode()
-	req, _ := http.NewRequest("GET", urls, body)
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
 	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
 	req.Header.Set("User-Agent", "google-api-go-client/0.5")
 	res, err := c.s.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer googleapi.CloseBody(res)
 	if err := googleapi.CheckResponse(res); err != nil {
-		return nil, err
-	}
-	var ret *MachinePage
-	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
-		return nil, err
+		return err
 	}
-	return ret, nil
+	return nil
 	// {
-	//   "description": "Retrieve a page of Machine objects.",
-	//   "httpMethod": "GET",
-	//   "id": "fleet.Machine.List",
+	//   "description": "Set the number of instances the engine maintains of a template Unit.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Unit.Scale",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
 	//   "parameters": {
-	//     "nextPageToken": {
-	//       "location": "query",
+	//     "unitName": {
+	//       "location": "path",
+	//       "required": true,
 	//       "type": "string"
 	//     }
 	//   },
-	//   "path": "machines",
-	//   "response": {
-	//     "$ref": "MachinePage"
+	//   "path": "units/{unitName}/scale",
+	//   "request": {
+	//     "$ref": "Scale"
 	//   }
 	// }
 
 }
 
-// method id "fleet.UnitState.List":
-
-type UnitStateListCall struct {
-	s    *Service
-	opt_ map[string]interface{}
-}
-
-// List: Retrieve a page of UnitState objects.
-func (r *UnitStateService) List() *UnitStateListCall {
-	c := &UnitStateListCall{s: r.s, opt_: make(map[string]interface{})}
-	return c
-}
+// method id "fleet.Unit.Set":
 
-// MachineID sets the optional parameter "machineID":
-func (c *UnitStateListCall) MachineID(machineID string) *UnitStateListCall {
-	c.opt_["machineID"] = machineID
-	return c
+type UnitsSetCall struct {
+	s        *Service
+	unitName string
+	unit     *Unit
+	opt_     map[string]interface{}
 }
 
-// NextPageToken sets the optional parameter "nextPageToken":
-func (c *UnitStateListCall) NextPageToken(nextPageToken string) *UnitStateListCall {
-	c.opt_["nextPageToken"] = nextPageToken
+// Set: Create or update a Unit.
+func (r *UnitsService) Set(unitName string, unit *Unit) *UnitsSetCall {
+	c := &UnitsSetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.unitName = unitName
+	c.unit = unit
 	return c
 }
 
-// UnitName sets the optional parameter "unitName":
-func (c *UnitStateListCall) UnitName(unitName string) *UnitStateListCall {
-	c.opt_["unitName"] = unitName
+// Replace sets the optional parameter "replace": Replace the unit file
+// of an existing Unit with the options given.
+func (c *UnitsSetCall) Replace(replace bool) *UnitsSetCall {
+	c.opt_["replace"] = replace
 	return c
 }
 
-func (c *UnitStateListCall) Do() (*UnitStatePage, error) {
+func (c *UnitsSetCall) Do() error {
 	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.unit)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
 	params := make(url.Values)
 	params.Set("alt", "json")
-	if v, ok := c.opt_["machineID"]; ok {
-		params.Set("machineID", fmt.Sprintf("%v", v))
-	}
-	if v, ok := c.opt_["nextPageToken"]; ok {
-		params.Set("nextPageToken", fmt.Sprintf("%v", v))
-	}
-	if v, ok := c.opt_["unitName"]; ok {
-		params.Set("unitName", fmt.Sprintf("%v", v))
+	if v, ok := c.opt_["replace"]; ok {
+		params.Set("replace", fmt.Sprintf("%v", v))
 	}
-	urls := googleapi.ResolveRelative(c.s.BasePath, "state")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}")
 	urls += "?" + params.Encode()
-	req, _ := http.NewRequest("GET", urls, body)
+	req, _ := http.NewRequest("PUT", urls, body)
+	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
 	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
 	req.Header.Set("User-Agent", "google-api-go-client/0.5")
 	res, err := c.s.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer googleapi.CloseBody(res)
 	if err := googleapi.CheckResponse(res); err != nil {
-		return nil, err
-	}
-	var ret *UnitStatePage
-	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
-		return nil, err
+		return err
 	}
-	return ret, nil
+	return nil
 	// {
-	//   "description": "Retrieve a page of UnitState objects.",
-	//   "httpMethod": "GET",
-	//   "id": "fleet.UnitState.List",
+	//   "description": "Create or update a Unit.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Unit.Set",
+	//   "parameterOrder": [
+	//     "unitName"
+	//   ],
 	//   "parameters": {
-	//     "machineID": {
-	//       "location": "query",
-	//       "type": "string"
-	//     },
-	//     "nextPageToken": {
+	//     "replace": {
+	//       "description": "Replace the unit file of an existing Unit with the options given.",
 	//       "location": "query",
-	//       "type": "string"
+	//       "type": "boolean"
 	//     },
 	//     "unitName": {
-	//       "location": "query",
+	//       "location": "path",
+	//       "required": true,
 	//       "type": "string"
 	//     }
 	//   },
-	//   "path": "state",
-	//   "response": {
-	//     "$ref": "UnitStatePage"
+	//   "path": "units/{unitName}",
+	//   "request": {
+	//     "$ref": "Unit"
 	//   }
 	// }
 
 }
 
-// method id "fleet.Unit.Delete":
+// method id "fleet.Unit.SetLabels":
 
-type UnitsDeleteCall struct {
-	s        *Service
-	unitName string
-	opt_     map[string]interface{}
+type UnitsSetLabelsCall struct {
+	s          *Service
+	unitName   string
+	unitlabels *UnitLabels
+	opt_       map[string]interface{}
 }
 
-// Delete: Delete the referenced Unit object.
-func (r *UnitsService) Delete(unitName string) *UnitsDeleteCall {
-	c := &UnitsDeleteCall{s: r.s, opt_: make(map[string]interface{})}
+// SetLabels: Replace the labels of a Unit.
+func (r *UnitsService) SetLabels(unitName string, unitlabels *UnitLabels) *UnitsSetLabelsCall {
+	c := &UnitsSetLabelsCall{s: r.s, opt_: make(map[string]interface{})}
 	c.unitName = unitName
+	c.unitlabels = unitlabels
 	return c
 }
 
-func (c *UnitsDeleteCall) Do() error {
+func (c *UnitsSetLabelsCall) Do() error {
 	var body io.Reader = nil
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.unitlabels)
+	if err != nil {
+		return err
+	}
+	ctype := "application/json"
 	params := make(url.Values)
 	params.Set("alt", "json")
-	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/labels")
 	urls += "?" + params.Encode()
-	req, _ := http.NewRequest("DELETE", urls, body)
+	req, _ := http.NewRequest("PUT", urls, body)
 	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
 	googleapi.SetOpaque(req.URL)
+	req.Header.Set("Content-Type", ctype)
 	req.Header.Set("User-Agent", "google-api-go-client/0.5")
 	res, err := c.s.client.Do(req)
 	if err != nil {
@@ -334,9 +2924,9 @@ func (c *UnitsDeleteCall) Do() error {
 	}
 	return nil
 	// {
-	//   "description": "Delete the referenced Unit object.",
-	//   "httpMethod": "DELETE",
-	//   "id": "fleet.Unit.Delete",
+	//   "description": "Replace the labels of a Unit.",
+	//   "httpMethod": "PUT",
+	//   "id": "fleet.Unit.SetLabels",
 	//   "parameterOrder": [
 	//     "unitName"
 	//   ],
@@ -347,31 +2937,35 @@ func (c *UnitsDeleteCall) Do() error {
 	//       "type": "string"
 	//     }
 	//   },
-	//   "path": "units/{unitName}"
+	//   "path": "units/{unitName}/labels",
+	//   "request": {
+	//     "$ref": "UnitLabels"
+	//   }
 	// }
 
 }
 
-// method id "fleet.Unit.Get":
+// method id "fleet.Unit.Versions":
 
-type UnitsGetCall struct {
+type UnitsVersionsCall struct {
 	s        *Service
 	unitName string
 	opt_     map[string]interface{}
 }
 
-// Get: Retrieve a single Unit object.
-func (r *UnitsService) Get(unitName string) *UnitsGetCall {
-	c := &UnitsGetCall{s: r.s, opt_: make(map[string]interface{})}
+// Versions: Retrieve the recorded versions of the unit file of a Unit,
+// which are kept after the Unit is destroyed.
+func (r *UnitsService) Versions(unitName string) *UnitsVersionsCall {
+	c := &UnitsVersionsCall{s: r.s, opt_: make(map[string]interface{})}
 	c.unitName = unitName
 	return c
 }
 
-func (c *UnitsGetCall) Do() (*Unit, error) {
+func (c *UnitsVersionsCall) Do() (*UnitVersionPage, error) {
 	var body io.Reader = nil
 	params := make(url.Values)
 	params.Set("alt", "json")
-	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}/versions")
 	urls += "?" + params.Encode()
 	req, _ := http.NewRequest("GET", urls, body)
 	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
@@ -385,15 +2979,15 @@ func (c *UnitsGetCall) Do() (*Unit, error) {
 	if err := googleapi.CheckResponse(res); err != nil {
 		return nil, err
 	}
-	var ret *Unit
+	var ret *UnitVersionPage
 	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
 		return nil, err
 	}
 	return ret, nil
 	// {
-	//   "description": "Retrieve a single Unit object.",
+	//   "description": "Retrieve the recorded versions of the unit file of a Unit, which are kept after the Unit is destroyed.",
 	//   "httpMethod": "GET",
-	//   "id": "fleet.Unit.Get",
+	//   "id": "fleet.Unit.Versions",
 	//   "parameterOrder": [
 	//     "unitName"
 	//   ],
@@ -404,41 +2998,74 @@ func (c *UnitsGetCall) Do() (*Unit, error) {
 	//       "type": "string"
 	//     }
 	//   },
-	//   "path": "units/{unitName}",
+	//   "path": "units/{unitName}/versions",
 	//   "response": {
-	//     "$ref": "Unit"
+	//     "$ref": "UnitVersionPage"
 	//   }
 	// }
 
 }
 
-// method id "fleet.Unit.List":
+// method id "fleet.Upgrade.Cancel":
 
-type UnitsListCall struct {
+type UpgradeCancelCall struct {
 	s    *Service
 	opt_ map[string]interface{}
 }
 
-// List: Retrieve a page of Unit objects.
-func (r *UnitsService) List() *UnitsListCall {
-	c := &UnitsListCall{s: r.s, opt_: make(map[string]interface{})}
+// Cancel: Cancel the current upgrade of fleetd. Machines already
+// upgraded keep their version.
+func (r *UpgradeService) Cancel() *UpgradeCancelCall {
+	c := &UpgradeCancelCall{s: r.s, opt_: make(map[string]interface{})}
 	return c
 }
 
-// NextPageToken sets the optional parameter "nextPageToken":
-func (c *UnitsListCall) NextPageToken(nextPageToken string) *UnitsListCall {
-	c.opt_["nextPageToken"] = nextPageToken
+func (c *UpgradeCancelCall) Do() error {
+	var body io.Reader = nil
+	params := make(url.Values)
+	params.Set("alt", "json")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "upgrade")
+	urls += "?" + params.Encode()
+	req, _ := http.NewRequest("DELETE", urls, body)
+	googleapi.SetOpaque(req.URL)
+	req.Header.Set("User-Agent", "google-api-go-client/0.5")
+	res, err := c.s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	return nil
+	// {
+	//   "description": "Cancel the current upgrade of fleetd. Machines already upgraded keep their version.",
+	//   "httpMethod": "DELETE",
+	//   "id": "fleet.Upgrade.Cancel",
+	//   "path": "upgrade"
+	// }
+
+}
+
+// method id "fleet.Upgrade.Get":
+
+type UpgradeGetCall struct {
+	s    *Service
+	opt_ map[string]interface{}
+}
+
+// Get: Retrieve the current upgrade of fleetd across the cluster, along
+// with the progress of each Machine.
+func (r *UpgradeService) Get() *UpgradeGetCall {
+	c := &UpgradeGetCall{s: r.s, opt_: make(map[string]interface{})}
 	return c
 }
 
-func (c *UnitsListCall) Do() (*UnitPage, error) {
+func (c *UpgradeGetCall) Do() (*Upgrade, error) {
 	var body io.Reader = nil
 	params := make(url.Values)
 	params.Set("alt", "json")
-	if v, ok := c.opt_["nextPageToken"]; ok {
-		params.Set("nextPageToken", fmt.Sprintf("%v", v))
-	}
-	urls := googleapi.ResolveRelative(c.s.BasePath, "units")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "upgrade")
 	urls += "?" + params.Encode()
 	req, _ := http.NewRequest("GET", urls, body)
 	googleapi.SetOpaque(req.URL)
@@ -451,59 +3078,51 @@ func (c *UnitsListCall) Do() (*UnitPage, error) {
 	if err := googleapi.CheckResponse(res); err != nil {
 		return nil, err
 	}
-	var ret *UnitPage
+	var ret *Upgrade
 	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
 		return nil, err
 	}
 	return ret, nil
 	// {
-	//   "description": "Retrieve a page of Unit objects.",
+	//   "description": "Retrieve the current upgrade of fleetd across the cluster, along with the progress of each Machine.",
 	//   "httpMethod": "GET",
-	//   "id": "fleet.Unit.List",
-	//   "parameters": {
-	//     "nextPageToken": {
-	//       "location": "query",
-	//       "type": "string"
-	//     }
-	//   },
-	//   "path": "units",
+	//   "id": "fleet.Upgrade.Get",
+	//   "path": "upgrade",
 	//   "response": {
-	//     "$ref": "UnitPage"
+	//     "$ref": "Upgrade"
 	//   }
 	// }
 
 }
 
-// method id "fleet.Unit.Set":
+// method id "fleet.Upgrade.Set":
 
-type UnitsSetCall struct {
-	s        *Service
-	unitName string
-	unit     *Unit
-	opt_     map[string]interface{}
+type UpgradeSetCall struct {
+	s       *Service
+	upgrade *Upgrade
+	opt_    map[string]interface{}
 }
 
-// Set: Create or update a Unit.
-func (r *UnitsService) Set(unitName string, unit *Unit) *UnitsSetCall {
-	c := &UnitsSetCall{s: r.s, opt_: make(map[string]interface{})}
-	c.unitName = unitName
-	c.unit = unit
+// Set: Start upgrading the agents of the cluster to a version of
+// fleetd, replacing any current upgrade.
+func (r *UpgradeService) Set(upgrade *Upgrade) *UpgradeSetCall {
+	c := &UpgradeSetCall{s: r.s, opt_: make(map[string]interface{})}
+	c.upgrade = upgrade
 	return c
 }
 
-func (c *UnitsSetCall) Do() error {
+func (c *UpgradeSetCall) Do() error {
 	var body io.Reader = nil
-	body, err := googleapi.WithoutDataWrapper.JSONReader(c.unit)
+	body, err := googleapi.WithoutDataWrapper.JSONReader(c.upgrade)
 	if err != nil {
 		return err
 	}
 	ctype := "application/json"
 	params := make(url.Values)
 	params.Set("alt", "json")
-	urls := googleapi.ResolveRelative(c.s.BasePath, "units/{unitName}")
+	urls := googleapi.ResolveRelative(c.s.BasePath, "upgrade")
 	urls += "?" + params.Encode()
 	req, _ := http.NewRequest("PUT", urls, body)
-	req.URL.Path = strings.Replace(req.URL.Path, "{unitName}", url.QueryEscape(c.unitName), 1)
 	googleapi.SetOpaque(req.URL)
 	req.Header.Set("Content-Type", ctype)
 	req.Header.Set("User-Agent", "google-api-go-client/0.5")
@@ -517,22 +3136,12 @@ func (c *UnitsSetCall) Do() error {
 	}
 	return nil
 	// {
-	//   "description": "Create or update a Unit.",
+	//   "description": "Start upgrading the agents of the cluster to a version of fleetd, replacing any current upgrade.",
 	//   "httpMethod": "PUT",
-	//   "id": "fleet.Unit.Set",
-	//   "parameterOrder": [
-	//     "unitName"
-	//   ],
-	//   "parameters": {
-	//     "unitName": {
-	//       "location": "path",
-	//       "required": true,
-	//       "type": "string"
-	//     }
-	//   },
-	//   "path": "units/{unitName}",
+	//   "id": "fleet.Upgrade.Set",
+	//   "path": "upgrade",
 	//   "request": {
-	//     "$ref": "Unit"
+	//     "$ref": "Upgrade"
 	//   }
 	// }
 
@@ -37,12 +37,128 @@ const DiscoveryJSON = `{
         "primaryIP": {
           "type": "string"
         },
+        "addresses": {
+          "type": "array",
+          "items": {
+            "$ref": "Address"
+          }
+        },
         "metadata": {
           "type": "object",
           "properties": {},
           "additionalProperties": {
             "type": "string"
           }
+        },
+        "totalCPUUnits": {
+          "type": "integer"
+        },
+        "totalMemory": {
+          "type": "integer"
+        },
+        "totalDisk": {
+          "type": "integer"
+        },
+        "allocatedCPUUnits": {
+          "type": "integer"
+        },
+        "allocatedMemory": {
+          "type": "integer"
+        },
+        "allocatedDisk": {
+          "type": "integer"
+        },
+        "reservedCPUUnits": {
+          "type": "integer"
+        },
+        "reservedMemory": {
+          "type": "integer"
+        },
+        "reservedDisk": {
+          "type": "integer"
+        },
+        "cpuOvercommit": {
+          "type": "number",
+          "format": "double"
+        },
+        "memoryOvercommit": {
+          "type": "number",
+          "format": "double"
+        },
+        "maxUnits": {
+          "type": "integer"
+        },
+        "memoryPolicy": {
+          "type": "string"
+        },
+        "availableMemory": {
+          "type": "integer"
+        },
+        "extendedResources": {
+          "type": "string"
+        },
+        "allocatedExtendedResources": {
+          "type": "string"
+        },
+        "cordoned": {
+          "type": "boolean"
+        },
+        "draining": {
+          "type": "boolean"
+        },
+        "taints": {
+          "type": "array",
+          "items": {
+            "$ref": "Taint"
+          }
+        },
+        "lastHeartbeat": {
+          "type": "string"
+        }
+      }
+    },
+    "MetadataValue": {
+      "id": "MetadataValue",
+      "type": "object",
+      "properties": {
+        "value": {
+          "type": "string"
+        }
+      }
+    },
+    "Cordon": {
+      "id": "Cordon",
+      "type": "object",
+      "properties": {
+        "drain": {
+          "type": "boolean"
+        }
+      }
+    },
+    "Address": {
+      "id": "Address",
+      "type": "object",
+      "properties": {
+        "ip": {
+          "type": "string"
+        },
+        "scope": {
+          "type": "string"
+        }
+      }
+    },
+    "Taint": {
+      "id": "Taint",
+      "type": "object",
+      "properties": {
+        "key": {
+          "type": "string"
+        },
+        "value": {
+          "type": "string"
+        },
+        "effect": {
+          "type": "string"
         }
       }
     },
@@ -108,6 +224,16 @@ const DiscoveryJSON = `{
         "machineID": {
           "type": "string",
           "required": true
+        },
+        "signature": {
+          "type": "string"
+        },
+        "labels": {
+          "type": "object",
+          "properties": {},
+          "additionalProperties": {
+            "type": "string"
+          }
         }
       }
     },
@@ -147,6 +273,18 @@ const DiscoveryJSON = `{
         },
         "systemdSubState": {
           "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        },
+        "health": {
+          "type": "string"
+        },
+        "usedCPUUnits": {
+          "type": "integer"
+        },
+        "usedMemory": {
+          "type": "integer"
         }
       }
     },
@@ -164,124 +302,1288 @@ const DiscoveryJSON = `{
           "type": "string"
         }
       }
-    }
-  },
-  "resources": {
-    "Machines": {
-      "methods": {
-        "List": {
-          "id": "fleet.Machine.List",
-          "description": "Retrieve a page of Machine objects.",
-          "httpMethod": "GET",
-          "path": "machines",
-          "parameters": {
-            "nextPageToken": {
-              "type": "string",
-              "location": "query"
-            }
-          },
-          "response": {
-            "$ref": "MachinePage"
+    },
+    "Event": {
+      "id": "Event",
+      "type": "object",
+      "properties": {
+        "index": {
+          "type": "integer"
+        },
+        "time": {
+          "type": "string"
+        },
+        "type": {
+          "type": "string"
+        },
+        "unitName": {
+          "type": "string"
+        },
+        "machineID": {
+          "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        }
+      }
+    },
+    "Scale": {
+      "id": "Scale",
+      "type": "object",
+      "properties": {
+        "count": {
+          "type": "integer"
+        }
+      }
+    },
+    "UnitLabels": {
+      "id": "UnitLabels",
+      "type": "object",
+      "properties": {
+        "labels": {
+          "type": "object",
+          "properties": {},
+          "additionalProperties": {
+            "type": "string"
           }
         }
       }
     },
-    "Units": {
-      "methods": {
-        "List": {
-          "id": "fleet.Unit.List",
-          "description": "Retrieve a page of Unit objects.",
-          "httpMethod": "GET",
-          "path": "units",
-          "parameters": {
-            "nextPageToken": {
-              "type": "string",
-              "location": "query"
-            }
-          },
-          "response": {
-            "$ref": "UnitPage"
+    "EventPage": {
+      "id": "EventPage",
+      "type": "object",
+      "properties": {
+        "events": {
+          "type": "array",
+          "items": {
+            "$ref": "Event"
           }
+        }
+      }
+    },
+    "AuditEntry": {
+      "id": "AuditEntry",
+      "type": "object",
+      "properties": {
+        "index": {
+          "type": "integer"
         },
-        "Get": {
-          "id": "fleet.Unit.Get",
-          "description": "Retrieve a single Unit object.",
-          "httpMethod": "GET",
-          "path": "units/{unitName}",
-          "parameters": {
-            "unitName": {
-              "type": "string",
-              "location": "path",
-              "required": true
-            }
-          },
-          "parameterOrder": [
-            "unitName"
-          ],
-          "response": {
-            "$ref": "Unit"
+        "time": {
+          "type": "string"
+        },
+        "user": {
+          "type": "string"
+        },
+        "action": {
+          "type": "string"
+        },
+        "unitName": {
+          "type": "string"
+        },
+        "previousState": {
+          "type": "string"
+        },
+        "state": {
+          "type": "string"
+        }
+      }
+    },
+    "AuditEntryPage": {
+      "id": "AuditEntryPage",
+      "type": "object",
+      "properties": {
+        "entries": {
+          "type": "array",
+          "items": {
+            "$ref": "AuditEntry"
           }
+        }
+      }
+    },
+    "UnitPlacement": {
+      "id": "UnitPlacement",
+      "type": "object",
+      "properties": {
+        "machineID": {
+          "type": "string"
         },
-        "Delete": {
-          "id": "fleet.Unit.Delete",
-          "description": "Delete the referenced Unit object.",
-          "httpMethod": "DELETE",
-          "path": "units/{unitName}",
-          "parameters": {
-            "unitName": {
-              "type": "string",
-              "location": "path",
-              "required": true
-            }
-          },
-          "parameterOrder": [
-            "unitName"
-          ]
+        "preempts": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
         },
-        "Set": {
-          "id": "fleet.Unit.Set",
-          "description": "Create or update a Unit.",
-          "httpMethod": "PUT",
-          "path": "units/{unitName}",
-          "parameters": {
-            "unitName": {
-              "type": "string",
-              "location": "path",
-              "required": true
-            }
-          },
-          "parameterOrder": [
-            "unitName"
-          ],
-          "request": {
-            "$ref": "Unit"
+        "reason": {
+          "type": "string"
+        },
+        "rejections": {
+          "type": "array",
+          "items": {
+            "$ref": "MachineRejection"
           }
         }
       }
     },
-    "UnitState": {
-      "methods": {
-        "List": {
-          "id": "fleet.UnitState.List",
-          "description": "Retrieve a page of UnitState objects.",
-          "httpMethod": "GET",
-          "path": "state",
-          "parameters": {
-            "nextPageToken": {
-              "type": "string",
-              "location": "query"
-            },
-            "unitName": {
-              "type": "string",
-              "location": "query"
-            },
-            "machineID": {
-              "type": "string",
-              "location": "query"
-            }
-          },
-          "response": {
-            "$ref": "UnitStatePage"
+    "MachineRejection": {
+      "id": "MachineRejection",
+      "type": "object",
+      "properties": {
+        "machineID": {
+          "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        },
+        "shortfalls": {
+          "type": "array",
+          "items": {
+            "$ref": "Shortfall"
+          }
+        }
+      }
+    },
+    "Shortfall": {
+      "id": "Shortfall",
+      "type": "object",
+      "properties": {
+        "constraint": {
+          "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        }
+      }
+    },
+    "UnitRejections": {
+      "id": "UnitRejections",
+      "type": "object",
+      "properties": {
+        "time": {
+          "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        },
+        "rejections": {
+          "type": "array",
+          "items": {
+            "$ref": "MachineRejection"
+          }
+        },
+        "backoffUntil": {
+          "type": "string"
+        }
+      }
+    },
+    "CronRun": {
+      "id": "CronRun",
+      "type": "object",
+      "properties": {
+        "name": {
+          "type": "string"
+        },
+        "tick": {
+          "type": "string"
+        },
+        "machineID": {
+          "type": "string"
+        },
+        "finished": {
+          "type": "string"
+        },
+        "result": {
+          "type": "string"
+        }
+      }
+    },
+    "CronRunPage": {
+      "id": "CronRunPage",
+      "type": "object",
+      "properties": {
+        "runs": {
+          "type": "array",
+          "items": {
+            "$ref": "CronRun"
+          }
+        }
+      }
+    },
+    "UnitVersion": {
+      "id": "UnitVersion",
+      "type": "object",
+      "properties": {
+        "version": {
+          "type": "integer"
+        },
+        "hash": {
+          "type": "string"
+        },
+        "created": {
+          "type": "string"
+        },
+        "options": {
+          "type": "array",
+          "items": {
+            "$ref": "UnitOption"
+          }
+        }
+      }
+    },
+    "UnitFile": {
+      "id": "UnitFile",
+      "type": "object",
+      "properties": {
+        "hash": {
+          "type": "string"
+        },
+        "options": {
+          "type": "array",
+          "items": {
+            "$ref": "UnitOption"
+          }
+        }
+      }
+    },
+    "UnitVersionPage": {
+      "id": "UnitVersionPage",
+      "type": "object",
+      "properties": {
+        "versions": {
+          "type": "array",
+          "items": {
+            "$ref": "UnitVersion"
+          }
+        }
+      }
+    },
+    "Stack": {
+      "id": "Stack",
+      "type": "object",
+      "properties": {
+        "name": {
+          "type": "string"
+        },
+        "units": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "StackPage": {
+      "id": "StackPage",
+      "type": "object",
+      "properties": {
+        "stacks": {
+          "type": "array",
+          "items": {
+            "$ref": "Stack"
+          }
+        }
+      }
+    },
+    "Quota": {
+      "id": "Quota",
+      "type": "object",
+      "properties": {
+        "namespace": {
+          "type": "string"
+        },
+        "cores": {
+          "type": "integer"
+        },
+        "memory": {
+          "type": "integer"
+        },
+        "units": {
+          "type": "integer"
+        },
+        "usedCores": {
+          "type": "integer"
+        },
+        "usedMemory": {
+          "type": "integer"
+        },
+        "usedUnits": {
+          "type": "integer"
+        }
+      }
+    },
+    "QuotaPage": {
+      "id": "QuotaPage",
+      "type": "object",
+      "properties": {
+        "quotas": {
+          "type": "array",
+          "items": {
+            "$ref": "Quota"
+          }
+        }
+      }
+    },
+    "UnitCompletion": {
+      "id": "UnitCompletion",
+      "type": "object",
+      "properties": {
+        "name": {
+          "type": "string"
+        },
+        "machineID": {
+          "type": "string"
+        },
+        "result": {
+          "type": "string"
+        },
+        "exitCode": {
+          "type": "integer"
+        },
+        "started": {
+          "type": "string"
+        },
+        "finished": {
+          "type": "string"
+        }
+      }
+    },
+    "UnitCompletionPage": {
+      "id": "UnitCompletionPage",
+      "type": "object",
+      "properties": {
+        "completions": {
+          "type": "array",
+          "items": {
+            "$ref": "UnitCompletion"
+          }
+        }
+      }
+    },
+    "CommandExecution": {
+      "id": "CommandExecution",
+      "type": "object",
+      "properties": {
+        "command": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "ConfigValue": {
+      "id": "ConfigValue",
+      "type": "object",
+      "properties": {
+        "key": {
+          "type": "string"
+        },
+        "value": {
+          "type": "string"
+        },
+        "secret": {
+          "type": "boolean"
+        }
+      }
+    },
+    "ConfigValuePage": {
+      "id": "ConfigValuePage",
+      "type": "object",
+      "properties": {
+        "values": {
+          "type": "array",
+          "items": {
+            "$ref": "ConfigValue"
+          }
+        }
+      }
+    },
+    "EngineStatus": {
+      "id": "EngineStatus",
+      "type": "object",
+      "properties": {
+        "machineID": {
+          "type": "string"
+        },
+        "version": {
+          "type": "integer"
+        },
+        "shards": {
+          "type": "array",
+          "items": {
+            "type": "integer"
+          }
+        },
+        "leaseExpires": {
+          "type": "string"
+        },
+        "reconciles": {
+          "type": "integer"
+        },
+        "lastReconcile": {
+          "type": "string"
+        },
+        "lastReconcileDuration": {
+          "type": "number",
+          "format": "double"
+        }
+      }
+    },
+    "EngineStatusPage": {
+      "id": "EngineStatusPage",
+      "type": "object",
+      "properties": {
+        "engines": {
+          "type": "array",
+          "items": {
+            "$ref": "EngineStatus"
+          }
+        }
+      }
+    },
+    "StepDown": {
+      "id": "StepDown",
+      "type": "object",
+      "properties": {
+        "machineID": {
+          "type": "string"
+        },
+        "hold": {
+          "type": "integer"
+        }
+      }
+    },
+    "Upgrade": {
+      "id": "Upgrade",
+      "type": "object",
+      "properties": {
+        "version": {
+          "type": "string"
+        },
+        "domainKey": {
+          "type": "string"
+        },
+        "created": {
+          "type": "string"
+        },
+        "machines": {
+          "type": "array",
+          "items": {
+            "$ref": "UpgradeStatus"
+          }
+        }
+      }
+    },
+    "UpgradeStatus": {
+      "id": "UpgradeStatus",
+      "type": "object",
+      "properties": {
+        "machineID": {
+          "type": "string"
+        },
+        "version": {
+          "type": "string"
+        },
+        "state": {
+          "type": "string"
+        },
+        "reason": {
+          "type": "string"
+        },
+        "time": {
+          "type": "string"
+        }
+      }
+    },
+    "ClusterConfig": {
+      "id": "ClusterConfig",
+      "type": "object",
+      "properties": {
+        "version": {
+          "type": "integer"
+        },
+        "values": {
+          "type": "object",
+          "properties": {},
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "updated": {
+          "type": "string"
+        }
+      }
+    }
+  },
+  "resources": {
+    "Machines": {
+      "methods": {
+        "List": {
+          "id": "fleet.Machine.List",
+          "description": "Retrieve a page of Machine objects.",
+          "httpMethod": "GET",
+          "path": "machines",
+          "parameters": {
+            "nextPageToken": {
+              "type": "string",
+              "location": "query"
+            }
+          },
+          "response": {
+            "$ref": "MachinePage"
+          }
+        },
+        "SetMetadata": {
+          "id": "fleet.Machine.SetMetadata",
+          "description": "Set the value of a metadata key of a Machine.",
+          "httpMethod": "PUT",
+          "path": "machines/{machineID}/metadata/{key}",
+          "parameters": {
+            "machineID": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "key": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "machineID",
+            "key"
+          ],
+          "request": {
+            "$ref": "MetadataValue"
+          }
+        },
+        "DeleteMetadata": {
+          "id": "fleet.Machine.DeleteMetadata",
+          "description": "Remove a metadata key previously set for a Machine.",
+          "httpMethod": "DELETE",
+          "path": "machines/{machineID}/metadata/{key}",
+          "parameters": {
+            "machineID": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "key": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "machineID",
+            "key"
+          ]
+        },
+        "Cordon": {
+          "id": "fleet.Machine.Cordon",
+          "description": "Stop scheduling new Units to a Machine, optionally draining the Units it already runs.",
+          "httpMethod": "PUT",
+          "path": "machines/{machineID}/cordon",
+          "parameters": {
+            "machineID": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "machineID"
+          ],
+          "request": {
+            "$ref": "Cordon"
+          }
+        },
+        "Uncordon": {
+          "id": "fleet.Machine.Uncordon",
+          "description": "Allow Units to be scheduled to a previously-cordoned Machine again.",
+          "httpMethod": "DELETE",
+          "path": "machines/{machineID}/cordon",
+          "parameters": {
+            "machineID": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "machineID"
+          ]
+        },
+        "Taint": {
+          "id": "fleet.Machine.Taint",
+          "description": "Set a Taint on a Machine, keeping Units that do not tolerate it from being scheduled to it.",
+          "httpMethod": "PUT",
+          "path": "machines/{machineID}/taints/{key}",
+          "parameters": {
+            "machineID": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "key": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "machineID",
+            "key"
+          ],
+          "request": {
+            "$ref": "Taint"
+          }
+        },
+        "Untaint": {
+          "id": "fleet.Machine.Untaint",
+          "description": "Remove a Taint from a Machine.",
+          "httpMethod": "DELETE",
+          "path": "machines/{machineID}/taints/{key}",
+          "parameters": {
+            "machineID": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "key": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "machineID",
+            "key"
+          ]
+        }
+      }
+    },
+    "Units": {
+      "methods": {
+        "List": {
+          "id": "fleet.Unit.List",
+          "description": "Retrieve a page of Unit objects.",
+          "httpMethod": "GET",
+          "path": "units",
+          "parameters": {
+            "nextPageToken": {
+              "type": "string",
+              "location": "query"
+            },
+            "machineID": {
+              "type": "string",
+              "location": "query"
+            },
+            "unitName": {
+              "type": "string",
+              "location": "query"
+            },
+            "state": {
+              "type": "string",
+              "location": "query"
+            },
+            "selector": {
+              "type": "string",
+              "description": "Select the Units whose labels match the given selector, e.g. app=web,env!=staging.",
+              "location": "query"
+            },
+            "fields": {
+              "type": "string",
+              "location": "query"
+            }
+          },
+          "response": {
+            "$ref": "UnitPage"
+          }
+        },
+        "Get": {
+          "id": "fleet.Unit.Get",
+          "description": "Retrieve a single Unit object.",
+          "httpMethod": "GET",
+          "path": "units/{unitName}",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "response": {
+            "$ref": "Unit"
+          }
+        },
+        "Delete": {
+          "id": "fleet.Unit.Delete",
+          "description": "Delete the referenced Unit object.",
+          "httpMethod": "DELETE",
+          "path": "units/{unitName}",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ]
+        },
+        "Set": {
+          "id": "fleet.Unit.Set",
+          "description": "Create or update a Unit.",
+          "httpMethod": "PUT",
+          "path": "units/{unitName}",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "replace": {
+              "type": "boolean",
+              "description": "Replace the unit file of an existing Unit with the options given.",
+              "location": "query"
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "request": {
+            "$ref": "Unit"
+          }
+        },
+        "Scale": {
+          "id": "fleet.Unit.Scale",
+          "description": "Set the number of instances the engine maintains of a template Unit.",
+          "httpMethod": "PUT",
+          "path": "units/{unitName}/scale",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "request": {
+            "$ref": "Scale"
+          }
+        },
+        "SetLabels": {
+          "id": "fleet.Unit.SetLabels",
+          "description": "Replace the labels of a Unit.",
+          "httpMethod": "PUT",
+          "path": "units/{unitName}/labels",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "request": {
+            "$ref": "UnitLabels"
+          }
+        },
+        "GetScale": {
+          "id": "fleet.Unit.GetScale",
+          "description": "Retrieve the number of instances the engine maintains of a template Unit.",
+          "httpMethod": "GET",
+          "path": "units/{unitName}/scale",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "response": {
+            "$ref": "Scale"
+          }
+        },
+        "Plan": {
+          "id": "fleet.Unit.Plan",
+          "description": "Determine where the engine would schedule a Unit, without submitting it.",
+          "httpMethod": "POST",
+          "path": "units/{unitName}/plan",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "strategy": {
+              "type": "string",
+              "location": "query"
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "request": {
+            "$ref": "Unit"
+          },
+          "response": {
+            "$ref": "UnitPlacement"
+          }
+        },
+        "Rejections": {
+          "id": "fleet.Unit.Rejections",
+          "description": "Retrieve why the engine was last unable to schedule a Unit.",
+          "httpMethod": "GET",
+          "path": "units/{unitName}/rejections",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "response": {
+            "$ref": "UnitRejections"
+          }
+        },
+        "Runs": {
+          "id": "fleet.Unit.Runs",
+          "description": "Retrieve the run history of a template Unit with a Schedule.",
+          "httpMethod": "GET",
+          "path": "units/{unitName}/runs",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "response": {
+            "$ref": "CronRunPage"
+          }
+        },
+        "Versions": {
+          "id": "fleet.Unit.Versions",
+          "description": "Retrieve the recorded versions of the unit file of a Unit, which are kept after the Unit is destroyed.",
+          "httpMethod": "GET",
+          "path": "units/{unitName}/versions",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "response": {
+            "$ref": "UnitVersionPage"
+          }
+        },
+        "History": {
+          "id": "fleet.Unit.History",
+          "description": "Retrieve the latest events concerning a Unit, such as its scheduling and changes of its state, which are kept after the Unit is destroyed.",
+          "httpMethod": "GET",
+          "path": "units/{unitName}/history",
+          "parameters": {
+            "unitName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "unitName"
+          ],
+          "response": {
+            "$ref": "EventPage"
+          }
+        }
+      }
+    },
+    "UnitState": {
+      "methods": {
+        "List": {
+          "id": "fleet.UnitState.List",
+          "description": "Retrieve a page of UnitState objects.",
+          "httpMethod": "GET",
+          "path": "state",
+          "parameters": {
+            "nextPageToken": {
+              "type": "string",
+              "location": "query"
+            },
+            "unitName": {
+              "type": "string",
+              "location": "query"
+            },
+            "machineID": {
+              "type": "string",
+              "location": "query"
+            },
+            "state": {
+              "type": "string",
+              "location": "query"
+            },
+            "fields": {
+              "type": "string",
+              "location": "query"
+            }
+          },
+          "response": {
+            "$ref": "UnitStatePage"
+          }
+        }
+      }
+    },
+    "Events": {
+      "methods": {
+        "List": {
+          "id": "fleet.Events.List",
+          "description": "Retrieve the cluster events recorded after a given index.",
+          "httpMethod": "GET",
+          "path": "events",
+          "parameters": {
+            "since": {
+              "type": "integer",
+              "location": "query"
+            },
+            "wait": {
+              "type": "integer",
+              "location": "query"
+            }
+          },
+          "response": {
+            "$ref": "EventPage"
+          }
+        }
+      }
+    },
+    "Audit": {
+      "methods": {
+        "List": {
+          "id": "fleet.Audit.List",
+          "description": "Retrieve the audit log of changes made to the Units of the cluster.",
+          "httpMethod": "GET",
+          "path": "audit",
+          "response": {
+            "$ref": "AuditEntryPage"
+          }
+        }
+      }
+    },
+    "Stacks": {
+      "methods": {
+        "List": {
+          "id": "fleet.Stack.List",
+          "description": "Retrieve all Stack objects.",
+          "httpMethod": "GET",
+          "path": "stacks",
+          "response": {
+            "$ref": "StackPage"
+          }
+        },
+        "Get": {
+          "id": "fleet.Stack.Get",
+          "description": "Retrieve a single Stack object.",
+          "httpMethod": "GET",
+          "path": "stacks/{stackName}",
+          "parameters": {
+            "stackName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "stackName"
+          ],
+          "response": {
+            "$ref": "Stack"
+          }
+        },
+        "Create": {
+          "id": "fleet.Stack.Create",
+          "description": "Create a Stack of Units the engine schedules together.",
+          "httpMethod": "PUT",
+          "path": "stacks/{stackName}",
+          "parameters": {
+            "stackName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "stackName"
+          ],
+          "request": {
+            "$ref": "Stack"
+          }
+        },
+        "Delete": {
+          "id": "fleet.Stack.Delete",
+          "description": "Delete the referenced Stack object, leaving its Units alone.",
+          "httpMethod": "DELETE",
+          "path": "stacks/{stackName}",
+          "parameters": {
+            "stackName": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "stackName"
+          ]
+        }
+      }
+    },
+    "Completions": {
+      "methods": {
+        "List": {
+          "id": "fleet.UnitCompletion.List",
+          "description": "Retrieve how the last runs of all batch Units ended.",
+          "httpMethod": "GET",
+          "path": "completions",
+          "response": {
+            "$ref": "UnitCompletionPage"
+          }
+        }
+      }
+    },
+    "Config": {
+      "methods": {
+        "List": {
+          "id": "fleet.Config.List",
+          "description": "Retrieve all ConfigValue objects of a namespace.",
+          "httpMethod": "GET",
+          "path": "config/{namespace}",
+          "parameters": {
+            "namespace": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "namespace"
+          ],
+          "response": {
+            "$ref": "ConfigValuePage"
+          }
+        },
+        "Set": {
+          "id": "fleet.Config.Set",
+          "description": "Store a ConfigValue under a key of a namespace.",
+          "httpMethod": "PUT",
+          "path": "config/{namespace}/{key}",
+          "parameters": {
+            "namespace": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "key": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "namespace",
+            "key"
+          ],
+          "request": {
+            "$ref": "ConfigValue"
+          }
+        },
+        "Delete": {
+          "id": "fleet.Config.Delete",
+          "description": "Delete the ConfigValue stored under a key of a namespace.",
+          "httpMethod": "DELETE",
+          "path": "config/{namespace}/{key}",
+          "parameters": {
+            "namespace": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            },
+            "key": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "namespace",
+            "key"
+          ]
+        }
+      }
+    },
+    "Quotas": {
+      "methods": {
+        "List": {
+          "id": "fleet.Quota.List",
+          "description": "Retrieve the Quotas of all namespaces along with their usage.",
+          "httpMethod": "GET",
+          "path": "quotas",
+          "response": {
+            "$ref": "QuotaPage"
+          }
+        },
+        "Set": {
+          "id": "fleet.Quota.Set",
+          "description": "Set the Quota of a namespace.",
+          "httpMethod": "PUT",
+          "path": "quotas/{namespace}",
+          "parameters": {
+            "namespace": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "namespace"
+          ],
+          "request": {
+            "$ref": "Quota"
+          }
+        },
+        "Delete": {
+          "id": "fleet.Quota.Delete",
+          "description": "Delete the Quota of a namespace.",
+          "httpMethod": "DELETE",
+          "path": "quotas/{namespace}",
+          "parameters": {
+            "namespace": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "namespace"
+          ]
+        }
+      }
+    },
+    "Engine": {
+      "methods": {
+        "Get": {
+          "id": "fleet.Engine.Get",
+          "description": "Retrieve the status of the engines holding the lease of the lead engine or of shards of the scheduling work.",
+          "httpMethod": "GET",
+          "path": "engine",
+          "response": {
+            "$ref": "EngineStatusPage"
+          }
+        },
+        "StepDown": {
+          "id": "fleet.Engine.StepDown",
+          "description": "Ask the engine of a Machine to release its leases and not to acquire any for a number of seconds.",
+          "httpMethod": "POST",
+          "path": "engine/step-down",
+          "request": {
+            "$ref": "StepDown"
+          }
+        }
+      }
+    },
+    "UnitFiles": {
+      "methods": {
+        "Get": {
+          "id": "fleet.UnitFiles.Get",
+          "description": "Retrieve a unit file by the hash of its contents.",
+          "httpMethod": "GET",
+          "path": "unit-files/{hash}",
+          "parameters": {
+            "hash": {
+              "type": "string",
+              "location": "path",
+              "required": true
+            }
+          },
+          "parameterOrder": [
+            "hash"
+          ],
+          "response": {
+            "$ref": "UnitFile"
+          }
+        }
+      }
+    },
+    "Upgrade": {
+      "methods": {
+        "Get": {
+          "id": "fleet.Upgrade.Get",
+          "description": "Retrieve the current upgrade of fleetd across the cluster, along with the progress of each Machine.",
+          "httpMethod": "GET",
+          "path": "upgrade",
+          "response": {
+            "$ref": "Upgrade"
+          }
+        },
+        "Set": {
+          "id": "fleet.Upgrade.Set",
+          "description": "Start upgrading the agents of the cluster to a version of fleetd, replacing any current upgrade.",
+          "httpMethod": "PUT",
+          "path": "upgrade",
+          "request": {
+            "$ref": "Upgrade"
+          }
+        },
+        "Cancel": {
+          "id": "fleet.Upgrade.Cancel",
+          "description": "Cancel the current upgrade of fleetd. Machines already upgraded keep their version.",
+          "httpMethod": "DELETE",
+          "path": "upgrade"
+        }
+      }
+    },
+    "ClusterConfig": {
+      "methods": {
+        "Get": {
+          "id": "fleet.ClusterConfig.Get",
+          "description": "Retrieve the options of fleet.conf set for the whole cluster.",
+          "httpMethod": "GET",
+          "path": "cluster-config",
+          "response": {
+            "$ref": "ClusterConfig"
+          }
+        },
+        "Set": {
+          "id": "fleet.ClusterConfig.Set",
+          "description": "Replace the options of fleet.conf set for the whole cluster, provided the version given is still current.",
+          "httpMethod": "PUT",
+          "path": "cluster-config",
+          "request": {
+            "$ref": "ClusterConfig"
+          },
+          "response": {
+            "$ref": "ClusterConfig"
           }
         }
       }
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/coreos/fleet/client"
+	"github.com/coreos/fleet/config"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/registry"
+)
+
+// newAuditFunc returns the function recording changes made through the API,
+// as configured. It returns nil if auditing is disabled.
+func newAuditFunc(cfg config.Config, reg registry.Registry) (client.AuditFunc, error) {
+	var records []client.AuditFunc
+
+	if cfg.AuditLogFile != "" {
+		f, err := os.OpenFile(cfg.AuditLogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, newAuditFileFunc(f))
+	}
+
+	if cfg.AuditRegistry {
+		records = append(records, func(ae registry.AuditEntry) {
+			if err := reg.RecordAudit(ae); err != nil {
+				log.Errorf("Failed recording AuditEntry in Registry: %v", err)
+			}
+		})
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	return func(ae registry.AuditEntry) {
+		for _, record := range records {
+			record(ae)
+		}
+	}, nil
+}
+
+// newAuditFileFunc returns a function appending each AuditEntry to the given
+// file as a line of JSON
+func newAuditFileFunc(f *os.File) client.AuditFunc {
+	var mutex sync.Mutex
+	enc := json.NewEncoder(f)
+
+	return func(ae registry.AuditEntry) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if err := enc.Encode(&ae); err != nil {
+			log.Errorf("Failed writing AuditEntry to %s: %v", f.Name(), err)
+		}
+	}
+}
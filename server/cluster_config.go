@@ -0,0 +1,80 @@
+package server
+
+import (
+	"time"
+
+	"github.com/coreos/fleet/config"
+	"github.com/coreos/fleet/engine"
+	"github.com/coreos/fleet/log"
+	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/registry"
+)
+
+// clusterConfigWatcher applies the options set for the whole cluster
+// through the Registry to the local Engine and machine, falling back to
+// fleet.conf for the options which are not set.
+type clusterConfigWatcher struct {
+	reg      registry.Registry
+	engine   *engine.Engine
+	mach     *machine.CoreOSMachine
+	local    config.Config
+	webhooks []engine.PlacementPlugin
+
+	version int
+}
+
+// Run applies changes of the cluster configuration at the interval
+// indicated until the stop channel is closed.
+func (w *clusterConfigWatcher) Run(ival time.Duration, stop chan bool) {
+	ticker := time.NewTicker(ival)
+	for {
+		select {
+		case <-stop:
+			log.V(1).Info("Halting clusterConfigWatcher")
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			w.refresh()
+		}
+	}
+}
+
+func (w *clusterConfigWatcher) refresh() {
+	cc, err := w.reg.ClusterConfig()
+	if err != nil {
+		log.Errorf("Failed fetching cluster configuration from Registry: %v", err)
+		return
+	}
+	if cc.Version == w.version {
+		return
+	}
+
+	log.Infof("Applying cluster configuration version %d: %v", cc.Version, cc.Values)
+	w.engine.Tune(w.tuning(cc.Values))
+	w.mach.SetOvercommit(
+		config.ClusterFloat(cc.Values, config.ClusterCPUOvercommit, w.local.CPUOvercommit),
+		config.ClusterFloat(cc.Values, config.ClusterMemoryOvercommit, w.local.MemoryOvercommit),
+	)
+	w.version = cc.Version
+}
+
+func (w *clusterConfigWatcher) tuning(values map[string]string) engine.Tuning {
+	ival := config.ClusterFloat(values, config.ClusterEngineReconcileInterval, w.local.EngineReconcileInterval)
+	delay := config.ClusterFloat(values, config.ClusterEngineRescheduleDelay, w.local.EngineRescheduleDelay)
+
+	// the local strategy was validated on startup
+	sched, _ := engine.NewScheduler(w.local.SchedulingStrategy)
+	if strategy, ok := values[config.ClusterSchedulingStrategy]; ok {
+		if s, err := engine.NewScheduler(strategy); err != nil {
+			log.Errorf("Ignoring scheduling_strategy of cluster configuration: %v", err)
+		} else {
+			sched = s
+		}
+	}
+
+	return engine.Tuning{
+		ReconcileInterval: time.Duration(ival*1000) * time.Millisecond,
+		Scheduler:         engine.WithPlacementPlugins(sched, w.webhooks...),
+		RescheduleDelay:   time.Duration(delay*1000) * time.Millisecond,
+	}
+}
@@ -3,7 +3,11 @@ package server
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"syscall"
 	"time"
 
 	"github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-systemd/activation"
@@ -16,8 +20,10 @@ import (
 	"github.com/coreos/fleet/heart"
 	"github.com/coreos/fleet/log"
 	"github.com/coreos/fleet/machine"
+	"github.com/coreos/fleet/metrics"
 	"github.com/coreos/fleet/pkg"
 	"github.com/coreos/fleet/registry"
+	"github.com/coreos/fleet/resource"
 	"github.com/coreos/fleet/systemd"
 	"github.com/coreos/fleet/unit"
 	"github.com/coreos/fleet/version"
@@ -27,6 +33,14 @@ const (
 	// machineStateRefreshInterval is the amount of time the server will
 	// wait before each attempt to refresh the local machine state
 	machineStateRefreshInterval = time.Minute
+
+	// metadataRefreshInterval is the amount of time the server will wait
+	// before each check for metadata set for the local machine at runtime
+	metadataRefreshInterval = 5 * time.Second
+
+	// upgradeCheckInterval is the amount of time the server will wait
+	// before each check whether the local machine is to upgrade fleetd
+	upgradeCheckInterval = 10 * time.Second
 )
 
 type Server struct {
@@ -36,11 +50,20 @@ type Server struct {
 	usGen       *unit.UnitStateGenerator
 	engine      *engine.Engine
 	mach        *machine.CoreOSMachine
+	mWatcher    *agent.MetadataWatcher
+	ccWatcher   *clusterConfigWatcher
+	upgrader    *agent.Upgrader
+	cache       *registry.CachedClient
+	unitCache   *registry.UnitCache
+	reg         *registry.EtcdRegistry
 	hrt         heart.Heart
 	mon         *heart.Monitor
 	api         *api.Server
 
 	engineReconcileInterval time.Duration
+	handoffTimeout          time.Duration
+	metricsListen           string
+	metricsListener         net.Listener
 
 	stop chan bool
 }
@@ -51,17 +74,42 @@ func New(cfg config.Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	var hbIval time.Duration
+	if cfg.AgentHeartbeatInterval != "" {
+		hbIval, err = time.ParseDuration(cfg.AgentHeartbeatInterval)
+		if err != nil {
+			return nil, err
+		}
+		if hbIval <= 0 || hbIval >= agentTTL {
+			return nil, fmt.Errorf("agent_heartbeat_interval %s must be positive and shorter than agent_ttl %s", hbIval, agentTTL)
+		}
+	}
 
 	mgr, err := systemd.NewSystemdUnitManager(systemd.DefaultUnitsDirectory)
 	if err != nil {
 		return nil, err
 	}
 
-	mach, err := newMachineFromConfig(cfg, mgr)
+	cpu, err := newCPUCapacityFromConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	memIval, err := time.ParseDuration(cfg.MemoryRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+	if memIval <= 0 {
+		return nil, fmt.Errorf("memory_refresh_interval %s must be positive", memIval)
+	}
+	capacity := agent.NewCapacityProvider("/", cpu, memIval)
+
+	mach, err := newMachineFromConfig(cfg, mgr, cpu)
+	if err != nil {
+		return nil, err
+	}
+	mach.SetMemorySource(capacity)
+
 	tlsConfig, err := etcd.ReadTLSConfigFiles(cfg.EtcdCAFile, cfg.EtcdCertFile, cfg.EtcdKeyFile)
 	if err != nil {
 		return nil, err
@@ -72,19 +120,111 @@ func New(cfg config.Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	policy, err := newRetryPolicyFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	eClient.SetRetryPolicy(policy)
 
-	reg := registry.NewEtcdRegistry(eClient, cfg.EtcdKeyPrefix)
+	var cache *registry.CachedClient
+	var rClient etcd.Client = eClient
+	if cfg.RegistryCache {
+		cache = registry.NewCachedClient(eClient, cfg.EtcdKeyPrefix)
+		rClient = cache
+	}
+
+	reg := registry.NewEtcdRegistry(rClient, cfg.EtcdKeyPrefix)
+	if len(cfg.RegistryKeyFiles) > 0 {
+		kr, err := registry.ReadKeyring(cfg.RegistryKeyFiles)
+		if err != nil {
+			return nil, err
+		}
+		reg.SetKeyring(kr)
+	}
+	var unitCache *registry.UnitCache
+	if cfg.UnitCacheDir != "" {
+		unitCache, err = registry.NewUnitCache(cfg.UnitCacheDir)
+		if err != nil {
+			return nil, err
+		}
+		reg.SetUnitCache(unitCache)
+	}
 
 	pub := agent.NewUnitStatePublisher(reg, mach, agentTTL)
 	gen := unit.NewUnitStateGenerator(mgr)
 
 	a := agent.New(mgr, gen, reg, mach, agentTTL)
+	a.Capacity = capacity
+	if cfg.UsageAlertFactor < 0 || (cfg.UsageAlertFactor > 0 && cfg.UsageAlertPeriod < 0) {
+		return nil, errors.New("usage_alert_factor and usage_alert_period must not be negative")
+	}
+	a.SetUsageAlerts(cfg.UsageAlertFactor, time.Duration(cfg.UsageAlertPeriod*1000)*time.Millisecond, cfg.UsageAlertFail)
+	if cfg.MaxConcurrentStarts < 0 {
+		return nil, errors.New("max_concurrent_starts must not be negative")
+	}
+	a.SetStartConcurrency(cfg.MaxConcurrentStarts)
+	if cfg.ClusterKeyFile != "" {
+		a.ClusterKey, err = registry.ReadClusterKey(cfg.ClusterKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.TrustedKeysFile != "" {
+		a.TrustedKeys, err = unit.ReadTrustedKeys(cfg.TrustedKeysFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	rStream := registry.NewEtcdEventStream(eClient, cfg.EtcdKeyPrefix)
 
+	if err := agent.ValidateZombieCleanup(cfg.ZombieCleanup); err != nil {
+		return nil, err
+	}
 	ar := agent.NewReconciler(reg, rStream)
+	ar.ZombieCleanup = cfg.ZombieCleanup
+
+	sched, err := engine.NewScheduler(cfg.SchedulingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	var webhooks []engine.PlacementPlugin
+	if cfg.EnginePlacementWebhook != "" {
+		webhooks = append(webhooks, engine.NewWebhookPlacementPlugin(cfg.EnginePlacementWebhook))
+	}
+	sched = engine.WithPlacementPlugins(sched, webhooks...)
 
-	e := engine.New(reg, rStream, mach)
+	// With fast failure detection, the engine also reconciles as soon as
+	// a machine leaves, rescheduling its units right away
+	eStream := rStream
+	if cfg.FastFailureDetection {
+		eStream = pkg.MergeEventStreams(rStream, registry.NewEtcdMachineEventStream(eClient, cfg.EtcdKeyPrefix))
+	}
+
+	fIval := time.Duration(cfg.EngineFullReconcileInterval*1000) * time.Millisecond
+	rDelay := time.Duration(cfg.EngineRescheduleDelay*1000) * time.Millisecond
+	e := engine.New(reg, eStream, mach, sched, cfg.EvictOnMetadataChange, rDelay, fIval, cfg.EngineReconcileConcurrency, cfg.EngineShards)
+	if cfg.EngineRescheduleLimit < 0 || (cfg.EngineRescheduleLimit > 0 && cfg.EngineRescheduleWindow <= 0) {
+		return nil, errors.New("engine_reschedule_limit must not be negative, and engine_reschedule_window must be positive")
+	}
+	e.SetFlapDamping(cfg.EngineRescheduleLimit, time.Duration(cfg.EngineRescheduleWindow*1000)*time.Millisecond)
+	if cfg.EngineGCInterval < 0 || cfg.EngineGCGrace < 0 {
+		return nil, errors.New("engine_gc_interval and engine_gc_grace must not be negative")
+	}
+	e.SetGarbageCollection(time.Duration(cfg.EngineGCInterval*1000)*time.Millisecond, time.Duration(cfg.EngineGCGrace*1000)*time.Millisecond, cfg.EngineGCDryRun)
+	if len(cfg.EngineWebhookURLs) > 0 {
+		var secret []byte
+		if cfg.EngineWebhookSecretFile != "" {
+			if secret, err = engine.ReadNotifySecret(cfg.EngineWebhookSecretFile); err != nil {
+				return nil, fmt.Errorf("invalid engine_webhook_secret_file: %v", err)
+			}
+		}
+		n, err := engine.NewWebhookNotifier(cfg.EngineWebhookURLs, cfg.EngineWebhookEvents, secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid engine_webhook_events: %v", err)
+		}
+		e.SetNotifier(n)
+	}
 
 	listeners, err := activation.Listeners(false)
 	if err != nil {
@@ -92,12 +232,32 @@ func New(cfg config.Config) (*Server, error) {
 	}
 
 	hrt := heart.New(reg, mach)
-	mon := heart.NewMonitor(agentTTL)
+	mon := heart.NewMonitor(agentTTL, hbIval)
+
+	record, err := newAuditFunc(cfg, reg)
+	if err != nil {
+		return nil, err
+	}
 
-	apiServer := api.NewServer(listeners, api.NewServeMux(reg))
+	proxy, err := api.NewProxyClient(cfg.APICAFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.ValidateStandby(cfg.APIStandby); err != nil {
+		return nil, err
+	}
+	node := api.Node{Machine: mach, AllowExec: cfg.APIAllowExec, Proxy: proxy, Standby: cfg.APIStandby}
+
+	secure, listeners, err := secureAPI(cfg, listeners)
+	if err != nil {
+		return nil, err
+	}
+
+	apiServer := api.NewServer(listeners, api.NewServeMux(reg, record, &node), secure)
 	apiServer.Serve()
 
 	eIval := time.Duration(cfg.EngineReconcileInterval*1000) * time.Millisecond
+	hTimeout := time.Duration(cfg.HandoffTimeout*1000) * time.Millisecond
 
 	srv := Server{
 		agent:       a,
@@ -106,24 +266,146 @@ func New(cfg config.Config) (*Server, error) {
 		usPub:       pub,
 		engine:      e,
 		mach:        mach,
+		mWatcher:    agent.NewMetadataWatcher(reg, mach),
+		ccWatcher:   &clusterConfigWatcher{reg: reg, engine: e, mach: mach, local: cfg, webhooks: webhooks},
+		upgrader:    agent.NewUpgrader(reg, mach, cfg.UpgradeBinaryPath, execBinary),
+		cache:       cache,
+		unitCache:   unitCache,
+		reg:         reg,
 		hrt:         hrt,
 		mon:         mon,
 		api:         apiServer,
 		stop:        nil,
 		engineReconcileInterval: eIval,
+		handoffTimeout:          hTimeout,
+		metricsListen:           cfg.MetricsListen,
 	}
 
 	return &srv, nil
 }
 
-func newMachineFromConfig(cfg config.Config, mgr unit.UnitManager) (*machine.CoreOSMachine, error) {
+// newRetryPolicyFromConfig returns the policy etcd requests are retried
+// with, as configured
+func newRetryPolicyFromConfig(cfg config.Config) (etcd.RetryPolicy, error) {
+	var p etcd.RetryPolicy
+	var err error
+	if p.Timeouts, err = etcd.ParseTimeouts(cfg.EtcdRequestTimeouts); err != nil {
+		return p, fmt.Errorf("invalid etcd_request_timeouts: %v", err)
+	}
+	if p.InitialBackoff, err = time.ParseDuration(cfg.EtcdRetryBackoff); err != nil {
+		return p, fmt.Errorf("invalid etcd_retry_backoff: %v", err)
+	}
+	if p.MaxBackoff, err = time.ParseDuration(cfg.EtcdRetryMaxBackoff); err != nil {
+		return p, fmt.Errorf("invalid etcd_retry_max_backoff: %v", err)
+	}
+	if p.BreakerCooldown, err = time.ParseDuration(cfg.EtcdBreakerCooldown); err != nil {
+		return p, fmt.Errorf("invalid etcd_breaker_cooldown: %v", err)
+	}
+	p.Jitter = cfg.EtcdRetryJitter
+	p.BreakerThreshold = cfg.EtcdBreakerThreshold
+
+	if err := p.Validate(); err != nil {
+		return p, fmt.Errorf("invalid etcd retry policy: %v", err)
+	}
+	return p, nil
+}
+
+// newCPUCapacityFromConfig returns the CPU capacity of the local machine,
+// as configured
+func newCPUCapacityFromConfig(cfg config.Config) (machine.CPUCapacity, error) {
+	if cfg.CPUCapacity < 0 {
+		return nil, fmt.Errorf("invalid cpu_capacity %d: must not be negative", cfg.CPUCapacity)
+	}
+	if cfg.CPUReservableFraction <= 0 || cfg.CPUReservableFraction > 1 {
+		return nil, fmt.Errorf("invalid cpu_reservable_fraction %v: must be greater than 0 and at most 1", cfg.CPUReservableFraction)
+	}
+
+	var cpu machine.CPUCapacity = &machine.LocalCPUCapacity{Root: "/"}
+	if cfg.CPUCapacity > 0 {
+		cpu = machine.StaticCPUCapacity(cfg.CPUCapacity)
+	}
+	if cfg.CPUReservableFraction < 1 {
+		cpu = machine.NewReservableCPUCapacity(cpu, cfg.CPUReservableFraction)
+	}
+	return cpu, nil
+}
+
+func newMachineFromConfig(cfg config.Config, mgr unit.UnitManager, cpu machine.CPUCapacity) (*machine.CoreOSMachine, error) {
+	// Explicitly configured metadata takes precedence over that
+	// discovered from cloud providers, which in turn takes precedence
+	// over the detected security features of the machine
+	metadata, err := machine.DiscoverMetadata(cfg.MetadataSources())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SecurityMetadata {
+		for key, val := range machine.DiscoverSecurityMetadata("/") {
+			if _, ok := metadata[key]; !ok {
+				metadata[key] = val
+			}
+		}
+	}
+	for key, val := range cfg.Metadata() {
+		metadata[key] = val
+	}
+	if cfg.Pool != "" {
+		if err := machine.ValidatePool(cfg.Pool); err != nil {
+			return nil, err
+		}
+		if p, ok := metadata[machine.MetadataPool]; ok && p != cfg.Pool {
+			return nil, fmt.Errorf("pool %q differs from pool metadata %q", cfg.Pool, p)
+		}
+		metadata[machine.MetadataPool] = cfg.Pool
+	}
+
+	if cfg.ReservedMemory < 0 || cfg.ReservedCPUUnits < 0 {
+		return nil, errors.New("reserved_memory and reserved_cpu_units must not be negative")
+	}
+	if cfg.CPUOvercommit <= 0 || cfg.MemoryOvercommit <= 0 {
+		return nil, errors.New("cpu_overcommit and memory_overcommit must be greater than 0")
+	}
+	if cfg.MaxUnitsPerMachine < 0 {
+		return nil, errors.New("max_units_per_machine must not be negative")
+	}
+	if err := machine.ValidateMemoryPolicy(cfg.MemoryPolicy); err != nil {
+		return nil, err
+	}
+	extended, err := resource.ParseCounts(cfg.RawResources)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resources: %v", err)
+	}
+	if err := machine.ValidateIPPreference(cfg.IPPreference); err != nil {
+		return nil, err
+	}
+	addrs, err := machine.ParseAddresses(cfg.PublicIP, cfg.PrivateIP)
+	if err != nil {
+		return nil, err
+	}
+	var publicIP string
+	if len(addrs) > 0 {
+		publicIP = addrs[0].IP
+	}
+
 	state := machine.MachineState{
-		PublicIP: cfg.PublicIP,
-		Metadata: cfg.Metadata(),
+		PublicIP:  publicIP,
+		Addresses: addrs,
+		APIURL:   cfg.APIAdvertiseURL,
+		Metadata: metadata,
 		Version:  version.Version,
+		ReservedResources: &resource.ResourceTuple{
+			Cores:  cfg.ReservedCPUUnits,
+			Memory: cfg.ReservedMemory,
+			Disk:   resource.HostDisk,
+		},
+		CPUOvercommit:     cfg.CPUOvercommit,
+		MemoryOvercommit:  cfg.MemoryOvercommit,
+		MaxUnits:          cfg.MaxUnitsPerMachine,
+		MemoryPolicy:      cfg.MemoryPolicy,
+		ExtendedResources: extended,
 	}
 
-	mach := machine.NewCoreOSMachine(state, mgr)
+	mach := machine.NewCoreOSMachine(state, mgr, cfg.DiskPath, cpu)
+	mach.SetIPPreference(cfg.IPPreference)
 	mach.Refresh()
 
 	if mach.State().ID == "" {
@@ -133,6 +415,41 @@ func newMachineFromConfig(cfg config.Config, mgr unit.UnitManager) (*machine.Cor
 	return mach, nil
 }
 
+// secureAPI serves the API over TLS on TCP listeners, and returns the
+// function wrapping the API served on them so that clients must
+// authenticate, as configured
+func secureAPI(cfg config.Config, listeners []net.Listener) (func(http.Handler) http.Handler, []net.Listener, error) {
+	if cfg.APICAFile != "" && cfg.APICertFile == "" {
+		return nil, nil, errors.New("api_cafile requires api_certfile and api_keyfile")
+	}
+
+	var tokens map[string]api.Token
+	if cfg.APITokensFile != "" {
+		var err error
+		tokens, err = api.ReadTokensFile(cfg.APITokensFile)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cfg.APICertFile != "" {
+		tlsConfig, err := api.ReadServerTLSConfig(cfg.APICAFile, cfg.APICertFile, cfg.APIKeyFile, tokens != nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		listeners = api.NewTLSListeners(listeners, tlsConfig)
+	}
+
+	var secure func(http.Handler) http.Handler
+	if cfg.APICAFile != "" || tokens != nil {
+		secure = func(hdlr http.Handler) http.Handler {
+			return api.NewAuthHandler(hdlr, tokens)
+		}
+	}
+
+	return secure, listeners, nil
+}
+
 func (s *Server) Run() {
 	log.Infof("Establishing etcd connectivity")
 
@@ -149,16 +466,41 @@ func (s *Server) Run() {
 
 	s.stop = make(chan bool)
 
+	if s.cache != nil {
+		go s.cache.Run(s.stop)
+	}
+	if s.unitCache != nil {
+		go s.unitCache.Run(s.stop)
+	}
+
+	s.serveMetrics()
+
 	go s.Monitor()
 	go s.api.Available(s.stop)
 	go s.mach.PeriodicRefresh(machineStateRefreshInterval, s.stop)
+	go s.mWatcher.Run(metadataRefreshInterval, s.stop)
+	go s.ccWatcher.Run(metadataRefreshInterval, s.stop)
+	go s.upgrader.Run(upgradeCheckInterval, s.stop)
 	go s.agent.Heartbeat(s.stop)
+	go s.agent.Capacity.Run(s.stop)
 	go s.aReconciler.Run(s.agent, s.stop)
 	go s.engine.Run(s.engineReconcileInterval, s.stop)
+	go s.reencrypt()
 
 	beatchan := make(chan *unit.UnitStateHeartbeat)
 	go s.usGen.Run(beatchan, s.stop)
-	go s.usPub.Run(beatchan, s.stop)
+	go s.usPub.Run(s.agent.AnnotateUnitStates(beatchan, s.stop), s.stop)
+}
+
+// reencrypt encrypts the values stored in the registry with the active
+// registry key, completing the rotation to a new key
+func (s *Server) reencrypt() {
+	n, err := s.reg.Reencrypt()
+	if err != nil {
+		log.Errorf("Failed re-encrypting registry values: %v", err)
+	} else if n > 0 {
+		log.Infof("Re-encrypted %d registry values with the active registry key", n)
+	}
 }
 
 // Monitor tracks the health of the Server. If the Server is ever deemed
@@ -173,8 +515,50 @@ func (s *Server) Monitor() {
 	}
 }
 
+// Handoff gives other machines the chance to claim the Units scheduled to
+// the local machine before it shuts down. It does nothing if no handoff
+// timeout is configured.
+func (s *Server) Handoff() {
+	if s.handoffTimeout <= 0 {
+		return
+	}
+
+	log.Infof("Handing off Units to other machines")
+	s.agent.Handoff(s.handoffTimeout)
+}
+
+// serveMetrics serves the metrics of all server components over HTTP on
+// the configured address, if any, until the Server is stopped
+func (s *Server) serveMetrics() {
+	if s.metricsListen == "" {
+		return
+	}
+
+	l, err := net.Listen("tcp", s.metricsListen)
+	if err != nil {
+		log.Errorf("Unable to serve metrics on %s: %v", s.metricsListen, err)
+		return
+	}
+	s.metricsListener = l
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	go http.Serve(l, mux)
+}
+
+// execBinary replaces the running fleetd with the binary at the given path,
+// passing it the same arguments and environment. Sockets passed by systemd
+// are inherited, as the process keeps its PID. It only returns on failure.
+func execBinary(path string) error {
+	return syscall.Exec(path, append([]string{path}, os.Args[1:]...), os.Environ())
+}
+
 func (s *Server) Stop() {
 	close(s.stop)
+	if s.metricsListener != nil {
+		s.metricsListener.Close()
+		s.metricsListener = nil
+	}
 }
 
 func (s *Server) Purge() {
@@ -182,6 +566,7 @@ func (s *Server) Purge() {
 	s.usPub.Purge()
 	s.engine.Purge()
 	s.hrt.Clear()
+	s.agent.FinishHandoff()
 }
 
 func (s *Server) MarshalJSON() ([]byte, error) {
@@ -178,11 +178,13 @@ func sshClientConfig(user string, checker *HostKeyChecker) (*gossh.ClientConfig,
 	return &cfg, nil
 }
 
+// maybeAddDefaultPort adds the default SSH port to the given host unless it
+// already has a port. IPv6 addresses may be given with or without brackets.
 func maybeAddDefaultPort(addr string) string {
-	if strings.Contains(addr, ":") {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
 		return addr
 	}
-	return net.JoinHostPort(addr, strconv.Itoa(sshDefaultPort))
+	return net.JoinHostPort(strings.Trim(addr, "[]"), strconv.Itoa(sshDefaultPort))
 }
 
 func NewSSHClient(user, addr string, checker *HostKeyChecker, agentForwarding bool) (*SSHForwardingClient, error) {
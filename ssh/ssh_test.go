@@ -0,0 +1,20 @@
+package ssh
+
+import (
+	"testing"
+)
+
+func TestMaybeAddDefaultPort(t *testing.T) {
+	for addr, want := range map[string]string{
+		"1.2.3.4":           "1.2.3.4:22",
+		"1.2.3.4:2222":      "1.2.3.4:2222",
+		"example.com":       "example.com:22",
+		"2001:db8::5":       "[2001:db8::5]:22",
+		"[2001:db8::5]":     "[2001:db8::5]:22",
+		"[2001:db8::5]:222": "[2001:db8::5]:222",
+	} {
+		if got := maybeAddDefaultPort(addr); got != want {
+			t.Errorf("maybeAddDefaultPort(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
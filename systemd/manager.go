@@ -1,11 +1,15 @@
 package systemd
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-systemd/dbus"
 
@@ -16,11 +20,28 @@ import (
 
 const (
 	DefaultUnitsDirectory = "/run/fleet/units/"
+
+	// Units are linked into the runtime directory of systemd, so their
+	// drop-ins are written there too
+	dropInsDirectory = "/run/systemd/system/"
+	dropInName       = "50-fleet.conf"
+
+	// Environment files hold configuration values, possibly secret, so
+	// they are only readable by root. Units read them through a drop-in
+	// of their own.
+	environmentDirectory  = "/run/fleet/environment/"
+	environmentDropInName = "60-fleet-environment.conf"
+
+	// number of unit state changes buffered as they are received from
+	// systemd
+	subStateUpdateBuffer = 256
 )
 
 type systemdUnitManager struct {
-	systemd  *dbus.Conn
-	UnitsDir string
+	systemd    *dbus.Conn
+	UnitsDir   string
+	DropInsDir string
+	EnvDir     string
 
 	hashes map[string]unit.Hash
 	mutex  sync.RWMutex
@@ -37,10 +58,12 @@ func NewSystemdUnitManager(uDir string) (*systemdUnitManager, error) {
 	}
 
 	mgr := systemdUnitManager{
-		systemd:  systemd,
-		UnitsDir: uDir,
-		hashes:   make(map[string]unit.Hash),
-		mutex:    sync.RWMutex{},
+		systemd:    systemd,
+		UnitsDir:   uDir,
+		DropInsDir: dropInsDirectory,
+		EnvDir:     environmentDirectory,
+		hashes:     make(map[string]unit.Hash),
+		mutex:      sync.RWMutex{},
 	}
 	return &mgr, nil
 }
@@ -49,9 +72,16 @@ func NewSystemdUnitManager(uDir string) (*systemdUnitManager, error) {
 // events, caching the Unit's Hash, and, if necessary, instructing the systemd
 // daemon to reload.
 func (m *systemdUnitManager) Load(name string, u unit.UnitFile) error {
+	return m.LoadRendered(name, u, u)
+}
+
+// LoadRendered writes the rendered contents of the given Unit to disk in
+// place of the Unit itself, caching the Hash of the Unit, and instructs the
+// systemd daemon to reload.
+func (m *systemdUnitManager) LoadRendered(name string, u unit.UnitFile, rendered unit.UnitFile) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
-	err := m.writeUnit(name, u.String())
+	err := m.writeUnit(name, rendered.String())
 	if err != nil {
 		return err
 	}
@@ -67,9 +97,73 @@ func (m *systemdUnitManager) Unload(name string) {
 	defer m.mutex.Unlock()
 	delete(m.hashes, name)
 	m.removeUnit(name)
+	m.removeDropIn(name, dropInName)
+	m.removeEnvironment(name)
 	m.daemonReload()
 }
 
+// SetDropIn writes the given drop-in for the indicated unit, or removes the
+// drop-in written earlier if nil. It takes effect once systemd reloads, as
+// done by Load.
+func (m *systemdUnitManager) SetDropIn(name string, d *unit.UnitFile) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if d == nil {
+		m.removeDropIn(name, dropInName)
+		return nil
+	}
+	return m.writeDropIn(name, dropInName, d)
+}
+
+// SetEnvironment writes the given environment variables to an environment
+// file of the indicated unit, along with a drop-in having the unit read it,
+// or removes both if nil. Like other drop-ins, it takes effect once systemd
+// reloads, while changes to the variables take effect when the unit is next
+// started.
+func (m *systemdUnitManager) SetEnvironment(name string, env map[string]string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if env == nil {
+		m.removeEnvironment(name)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.EnvDir, os.FileMode(0700)); err != nil {
+		return err
+	}
+	envPath := m.getEnvFilePath(name)
+	log.Infof("Writing environment of unit %s (%d variables)", name, len(env))
+	if err := ioutil.WriteFile(envPath, environmentFileContents(env), os.FileMode(0600)); err != nil {
+		return err
+	}
+
+	d, err := unit.NewUnitFile(fmt.Sprintf("[Service]\nEnvironmentFile=%s\n", envPath))
+	if err != nil {
+		return err
+	}
+	return m.writeDropIn(name, environmentDropInName, d)
+}
+
+// environmentFileContents renders environment variables in the format of
+// systemd environment files, double-quoting all values so that they are
+// taken literally, newlines included
+func environmentFileContents(env map[string]string) []byte {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	quoter := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	var buf bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s=\"%s\"\n", key, quoter.Replace(env[key]))
+	}
+	return buf.Bytes()
+}
+
 // TriggerStart asynchronously starts the unit identified by the given name.
 // This function does not block for the underlying unit to actually start.
 func (m *systemdUnitManager) TriggerStart(name string) {
@@ -92,6 +186,17 @@ func (m *systemdUnitManager) TriggerStop(name string) {
 	}
 }
 
+// TriggerRestart asynchronously restarts the unit identified by the given
+// name if it is running.
+func (m *systemdUnitManager) TriggerRestart(name string) {
+	jobID, err := m.systemd.TryRestartUnit(name, "replace", nil)
+	if err == nil {
+		log.Infof("Triggered systemd unit %s restart: job=%d", name, jobID)
+	} else {
+		log.Errorf("Failed to trigger systemd unit %s restart: %v", name, err)
+	}
+}
+
 // GetUnitState generates a UnitState object representing the
 // current state of a Unit
 func (m *systemdUnitManager) GetUnitState(name string) (*unit.UnitState, error) {
@@ -107,6 +212,43 @@ func (m *systemdUnitManager) GetUnitState(name string) (*unit.UnitState, error)
 	return us, nil
 }
 
+// NotifyUnitStates subscribes to the signals systemd sends on D-Bus when
+// units change, reporting the names of the units whose states changed.
+func (m *systemdUnitManager) NotifyUnitStates(changed chan<- string, stop chan bool) error {
+	if err := m.systemd.Subscribe(); err != nil {
+		return err
+	}
+
+	updates := make(chan *dbus.SubStateUpdate, subStateUpdateBuffer)
+	errs := make(chan error, subStateUpdateBuffer)
+	m.systemd.SetSubStateSubscriber(updates, errs)
+
+	go func() {
+		defer func() {
+			m.systemd.SetSubStateSubscriber(nil, nil)
+			if err := m.systemd.Unsubscribe(); err != nil {
+				log.V(1).Infof("Failed unsubscribing from systemd signals: %v", err)
+			}
+		}()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case update := <-updates:
+				select {
+				case changed <- update.UnitName:
+				default:
+					log.V(1).Infof("Dropped state change of unit %s, receiver is busy", update.UnitName)
+				}
+			case err := <-errs:
+				log.V(1).Infof("Missed unit state change from systemd: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
 func (m *systemdUnitManager) getUnitState(name string) (*unit.UnitState, error) {
 	info, err := m.systemd.GetUnitProperties(name)
 	if err != nil {
@@ -120,6 +262,36 @@ func (m *systemdUnitManager) getUnitState(name string) (*unit.UnitState, error)
 	return &us, nil
 }
 
+// GetUnitExit describes how the main process of the named service unit
+// last exited, as recorded by systemd
+func (m *systemdUnitManager) GetUnitExit(name string) (*unit.UnitExit, error) {
+	props, err := m.systemd.GetUnitTypeProperties(name, "Service")
+	if err != nil {
+		return nil, err
+	}
+
+	var ue unit.UnitExit
+	if result, ok := props["Result"].(string); ok {
+		ue.Result = result
+	}
+	if status, ok := props["ExecMainStatus"].(int32); ok {
+		ue.Status = int(status)
+	}
+	ue.Started = usecTimestamp(props["ExecMainStartTimestamp"])
+	ue.Finished = usecTimestamp(props["ExecMainExitTimestamp"])
+	return &ue, nil
+}
+
+// usecTimestamp converts a timestamp property of systemd, in microseconds
+// since the epoch, to a time, or the zero time if it is unset
+func usecTimestamp(prop interface{}) time.Time {
+	usec, ok := prop.(uint64)
+	if !ok || usec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(usec)*int64(time.Microsecond))
+}
+
 func (m *systemdUnitManager) readUnit(name string) (string, error) {
 	path := m.getUnitFilePath(name)
 	contents, err := ioutil.ReadFile(path)
@@ -162,6 +334,32 @@ func (m *systemdUnitManager) Units() (units []string, err error) {
 	return
 }
 
+// ActiveUnits enumerates the units systemd has not deactivated whose unit
+// files, as systemd loaded them, are in this manager's units directory.
+// Units keep running after their unit files are removed, until stopped.
+func (m *systemdUnitManager) ActiveUnits() ([]string, error) {
+	statuses, err := m.systemd.ListUnits()
+	if err != nil {
+		return nil, err
+	}
+
+	var units []string
+	for _, us := range statuses {
+		if us.ActiveState == "inactive" || us.ActiveState == "failed" || !unit.RecognizedUnitType(us.Name) {
+			continue
+		}
+		prop, err := m.systemd.GetUnitProperty(us.Name, "FragmentPath")
+		if err != nil {
+			log.V(1).Infof("Failed determining unit file of %s: %v", us.Name, err)
+			continue
+		}
+		if fp, ok := prop.Value.Value().(string); ok && path.Dir(fp) == path.Clean(m.UnitsDir) {
+			units = append(units, us.Name)
+		}
+	}
+	return units, nil
+}
+
 func (m *systemdUnitManager) GetUnitStates(filter pkg.Set) (map[string]*unit.UnitState, error) {
 	// Unfortunately we need to lock for the entire operation to ensure we
 	// have a consistent view of the hashes. Otherwise, Load/Unload
@@ -237,6 +435,40 @@ func (m *systemdUnitManager) removeUnit(name string) {
 	os.Remove(ufPath)
 }
 
+func (m *systemdUnitManager) writeDropIn(name, file string, d *unit.UnitFile) error {
+	dir := m.getDropInDirPath(name)
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	log.Infof("Writing systemd drop-in %s for unit %s", file, name)
+	return ioutil.WriteFile(path.Join(dir, file), d.Bytes(), os.FileMode(0644))
+}
+
+func (m *systemdUnitManager) removeDropIn(name, file string) {
+	dir := m.getDropInDirPath(name)
+	if err := os.Remove(path.Join(dir, file)); err == nil {
+		log.Infof("Removed systemd drop-in %s for unit %s", file, name)
+	}
+	// only succeeds once no other drop-ins are left
+	os.Remove(dir)
+}
+
+func (m *systemdUnitManager) removeEnvironment(name string) {
+	m.removeDropIn(name, environmentDropInName)
+	if err := os.Remove(m.getEnvFilePath(name)); err == nil {
+		log.Infof("Removed environment of unit %s", name)
+	}
+}
+
+func (m *systemdUnitManager) getEnvFilePath(name string) string {
+	return path.Join(m.EnvDir, name)
+}
+
+func (m *systemdUnitManager) getDropInDirPath(name string) string {
+	return path.Join(m.DropInsDir, name+".d")
+}
+
 func (m *systemdUnitManager) getUnitFilePath(name string) string {
 	return path.Join(m.UnitsDir, name)
 }
@@ -7,12 +7,18 @@ import (
 )
 
 func NewFakeUnitManager() *FakeUnitManager {
-	return &FakeUnitManager{u: map[string]bool{}}
+	return &FakeUnitManager{u: map[string]bool{}, d: map[string]*UnitFile{}, r: map[string]*UnitFile{}, env: map[string]map[string]string{}}
 }
 
 type FakeUnitManager struct {
 	sync.RWMutex
-	u map[string]bool
+	u   map[string]bool
+	d   map[string]*UnitFile
+	r   map[string]*UnitFile
+	env map[string]map[string]string
+
+	// Restarted holds the names of the units restarted, in order
+	Restarted []string
 }
 
 func (fum *FakeUnitManager) Load(name string, u UnitFile) error {
@@ -20,19 +26,88 @@ func (fum *FakeUnitManager) Load(name string, u UnitFile) error {
 	defer fum.Unlock()
 
 	fum.u[name] = false
+	delete(fum.r, name)
 	return nil
 }
 
+func (fum *FakeUnitManager) LoadRendered(name string, u UnitFile, rendered UnitFile) error {
+	fum.Lock()
+	defer fum.Unlock()
+
+	fum.u[name] = false
+	fum.r[name] = &rendered
+	return nil
+}
+
+// Rendered returns the rendering the named unit was loaded with, if any
+func (fum *FakeUnitManager) Rendered(name string) *UnitFile {
+	fum.RLock()
+	defer fum.RUnlock()
+
+	return fum.r[name]
+}
+
 func (fum *FakeUnitManager) Unload(name string) {
 	fum.Lock()
 	defer fum.Unlock()
 
 	delete(fum.u, name)
+	delete(fum.d, name)
+	delete(fum.r, name)
+	delete(fum.env, name)
+}
+
+func (fum *FakeUnitManager) SetDropIn(name string, d *UnitFile) error {
+	fum.Lock()
+	defer fum.Unlock()
+
+	if d == nil {
+		delete(fum.d, name)
+	} else {
+		fum.d[name] = d
+	}
+	return nil
+}
+
+// DropIn returns the drop-in set for the named unit, if any
+func (fum *FakeUnitManager) DropIn(name string) *UnitFile {
+	fum.RLock()
+	defer fum.RUnlock()
+
+	return fum.d[name]
+}
+
+func (fum *FakeUnitManager) SetEnvironment(name string, env map[string]string) error {
+	fum.Lock()
+	defer fum.Unlock()
+
+	if env == nil {
+		delete(fum.env, name)
+	} else {
+		fum.env[name] = env
+	}
+	return nil
+}
+
+// Environment returns the environment variables passed to the named unit,
+// if any
+func (fum *FakeUnitManager) Environment(name string) map[string]string {
+	fum.RLock()
+	defer fum.RUnlock()
+
+	return fum.env[name]
 }
 
 func (fum *FakeUnitManager) TriggerStart(string) {}
 func (fum *FakeUnitManager) TriggerStop(string)  {}
 
+func (fum *FakeUnitManager) TriggerRestart(name string) {
+	fum.Lock()
+	defer fum.Unlock()
+
+	fum.Restarted = append(fum.Restarted, name)
+}
+
 func (fum *FakeUnitManager) Units() ([]string, error) {
 	fum.RLock()
 	defer fum.RUnlock()
@@ -44,6 +119,21 @@ func (fum *FakeUnitManager) Units() ([]string, error) {
 	return lst, nil
 }
 
+// ActiveUnits returns the loaded units, all of which are reported active
+func (fum *FakeUnitManager) ActiveUnits() ([]string, error) {
+	return fum.Units()
+}
+
+func (fum *FakeUnitManager) GetUnitExit(name string) (ue *UnitExit, err error) {
+	fum.RLock()
+	defer fum.RUnlock()
+
+	if _, ok := fum.u[name]; ok {
+		ue = &UnitExit{Result: "success"}
+	}
+	return
+}
+
 func (fum *FakeUnitManager) GetUnitState(name string) (us *UnitState, err error) {
 	fum.RLock()
 	defer fum.RUnlock()
@@ -65,7 +155,7 @@ func (fum *FakeUnitManager) GetUnitStates(filter pkg.Set) (map[string]*UnitState
 	states := make(map[string]*UnitState)
 	for _, name := range filter.Values() {
 		if _, ok := fum.u[name]; ok {
-			states[name] = &UnitState{"loaded", "active", "running", "", "", name}
+			states[name] = &UnitState{"loaded", "active", "running", "", "", name, "", "", 0, 0}
 		}
 	}
 
@@ -8,6 +8,20 @@ import (
 	"github.com/coreos/fleet/pkg"
 )
 
+const (
+	// interval at which the states of units are polled from, or, for
+	// UnitStateNotifiers, at which changes to the subscriptions are reported
+	pollInterval = time.Second
+
+	// interval at which the states of all units are polled from
+	// UnitStateNotifiers, in case any of their notifications were lost
+	reconcileInterval = 30 * time.Second
+
+	// number of notified unit state changes buffered while heartbeats are
+	// sent
+	notifyBuffer = 256
+)
+
 type UnitStateHeartbeat struct {
 	Name  string
 	State *UnitState
@@ -38,15 +52,36 @@ func (g *UnitStateGenerator) MarshalJSON() ([]byte, error) {
 }
 
 // Run periodically calls Generate and sends received *UnitStateHeartbeat
-// objects to the provided channel.
+// objects to the provided channel. If the UnitManager is a UnitStateNotifier,
+// heartbeats are instead sent as the states of units change, and the states
+// of all units are only polled every reconcileInterval. Should notifications
+// be unavailable, Run falls back to polling.
 func (g *UnitStateGenerator) Run(receiver chan<- *UnitStateHeartbeat, stop chan bool) {
-	tick := time.Tick(time.Second)
+	var changed chan string
+	if n, ok := g.mgr.(UnitStateNotifier); ok {
+		changed = make(chan string, notifyBuffer)
+		if err := n.NotifyUnitStates(changed, stop); err != nil {
+			log.Errorf("Failed subscribing to unit state changes, polling unit states instead: %v", err)
+			changed = nil
+		}
+	}
+
+	tick := time.Tick(pollInterval)
+	lastPoll := time.Now()
 	for {
 		select {
 		case <-stop:
 			return
-		case <-tick:
-			beatchan, err := g.Generate()
+		case name := <-changed:
+			if ush := g.notified(name); ush != nil {
+				receiver <- ush
+			}
+		case now := <-tick:
+			all := changed == nil || now.Sub(lastPoll) >= reconcileInterval
+			if all {
+				lastPoll = now
+			}
+			beatchan, err := g.generate(all)
 			if err != nil {
 				log.Errorf("Failed fetching current unit states: %v", err)
 				continue
@@ -59,9 +94,30 @@ func (g *UnitStateGenerator) Run(receiver chan<- *UnitStateHeartbeat, stop chan
 	}
 }
 
+// notified returns a heartbeat carrying the current state of the named unit,
+// whose state changed, or nil if the generator is not subscribed to it
+func (g *UnitStateGenerator) notified(name string) *UnitStateHeartbeat {
+	if !g.subscribed.Contains(name) {
+		return nil
+	}
+	us, err := g.mgr.GetUnitState(name)
+	if err != nil {
+		log.Errorf("Failed fetching current state of unit %s: %v", name, err)
+		return nil
+	}
+	return &UnitStateHeartbeat{Name: name, State: us}
+}
+
 // Generate returns and fills a channel with *UnitStateHeartbeat objects. Objects will
 // only be returned for units to which this generator is currently subscribed.
 func (g *UnitStateGenerator) Generate() (<-chan *UnitStateHeartbeat, error) {
+	return g.generate(true)
+}
+
+// generate works like Generate, but unless all is set only returns
+// heartbeats of the units subscribed to or unsubscribed from since it last
+// ran
+func (g *UnitStateGenerator) generate(all bool) (<-chan *UnitStateHeartbeat, error) {
 	var lastSubscribed pkg.Set
 	if g.lastSubscribed != nil {
 		lastSubscribed = g.lastSubscribed.Copy()
@@ -70,9 +126,17 @@ func (g *UnitStateGenerator) Generate() (<-chan *UnitStateHeartbeat, error) {
 	subscribed := g.subscribed.Copy()
 	g.lastSubscribed = subscribed
 
-	reportable, err := g.mgr.GetUnitStates(subscribed)
-	if err != nil {
-		return nil, err
+	reportable := make(map[string]*UnitState)
+	filter := subscribed
+	if !all && lastSubscribed != nil {
+		filter = subscribed.Sub(lastSubscribed)
+	}
+	if all || filter.Length() > 0 {
+		var err error
+		reportable, err = g.mgr.GetUnitStates(filter)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	beatchan := make(chan *UnitStateHeartbeat)
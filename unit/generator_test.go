@@ -3,6 +3,7 @@ package unit
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func assertGenerateUnitStateHeartbeats(t *testing.T, um UnitManager, gen *UnitStateGenerator, expect []UnitStateHeartbeat) {
@@ -35,7 +36,7 @@ func TestUnitStateGeneratorSubscribeLifecycle(t *testing.T) {
 
 	// subscribed to foo.service so we should get a heartbeat
 	expect := []UnitStateHeartbeat{
-		UnitStateHeartbeat{Name: "foo.service", State: &UnitState{"loaded", "active", "running", "", "", "foo.service"}},
+		UnitStateHeartbeat{Name: "foo.service", State: &UnitState{"loaded", "active", "running", "", "", "foo.service", "", "", 0, 0}},
 	}
 	assertGenerateUnitStateHeartbeats(t, um, gen, expect)
 
@@ -63,3 +64,101 @@ func TestUnitStateGeneratorNoState(t *testing.T) {
 	// subscribed to foo.service but no underlying state so no heartbeat
 	assertGenerateUnitStateHeartbeats(t, um, gen, []UnitStateHeartbeat{})
 }
+
+func TestUnitStateGeneratorSubscriptionChanges(t *testing.T) {
+	um := NewFakeUnitManager()
+	um.Load("foo.service", UnitFile{})
+	um.Load("bar.service", UnitFile{})
+
+	gen := NewUnitStateGenerator(um)
+	gen.Subscribe("foo.service")
+	beatchan, err := gen.generate(false)
+	if err != nil {
+		t.Fatalf("Unexpected error from generate(): %v", err)
+	}
+	if got := len(drainHeartbeats(beatchan)); got != 1 {
+		t.Fatalf("Expected 1 heartbeat on first run, got %d", got)
+	}
+
+	// only the units whose subscription changed are reported
+	gen.Subscribe("bar.service")
+	beatchan, err = gen.generate(false)
+	if err != nil {
+		t.Fatalf("Unexpected error from generate(): %v", err)
+	}
+	expect := []UnitStateHeartbeat{
+		UnitStateHeartbeat{Name: "bar.service", State: &UnitState{"loaded", "active", "running", "", "", "bar.service", "", "", 0, 0}},
+	}
+	if got := drainHeartbeats(beatchan); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %#v, expected %#v", got, expect)
+	}
+
+	gen.Unsubscribe("foo.service")
+	beatchan, err = gen.generate(false)
+	if err != nil {
+		t.Fatalf("Unexpected error from generate(): %v", err)
+	}
+	expect = []UnitStateHeartbeat{
+		UnitStateHeartbeat{Name: "foo.service", State: nil},
+	}
+	if got := drainHeartbeats(beatchan); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %#v, expected %#v", got, expect)
+	}
+}
+
+// notifyingUnitManager notifies the changes sent to it
+type notifyingUnitManager struct {
+	*FakeUnitManager
+	changes chan string
+}
+
+func (n *notifyingUnitManager) NotifyUnitStates(changed chan<- string, stop chan bool) error {
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case name := <-n.changes:
+				changed <- name
+			}
+		}
+	}()
+	return nil
+}
+
+func TestUnitStateGeneratorRunNotified(t *testing.T) {
+	um := &notifyingUnitManager{NewFakeUnitManager(), make(chan string)}
+	um.Load("foo.service", UnitFile{})
+	um.Load("bar.service", UnitFile{})
+
+	gen := NewUnitStateGenerator(um)
+	gen.Subscribe("foo.service")
+	// the subscription is reported by the first poll
+	gen.generate(true)
+
+	receiver := make(chan *UnitStateHeartbeat)
+	stop := make(chan bool)
+	defer close(stop)
+	go gen.Run(receiver, stop)
+
+	// changes of units not subscribed to are ignored
+	um.changes <- "bar.service"
+	um.changes <- "foo.service"
+	select {
+	case ush := <-receiver:
+		want := &UnitStateHeartbeat{Name: "foo.service", State: &UnitState{LoadState: "loaded", ActiveState: "active", SubState: "running"}}
+		if !reflect.DeepEqual(ush, want) {
+			t.Fatalf("got %#v, expected %#v", ush, want)
+		}
+	case <-time.After(pollInterval / 2):
+		t.Fatalf("Expected heartbeat before the next poll")
+	}
+}
+
+func drainHeartbeats(beatchan <-chan *UnitStateHeartbeat) []UnitStateHeartbeat {
+	got := []UnitStateHeartbeat{}
+	for beat := range beatchan {
+		got = append(got, *beat)
+	}
+	return got
+}
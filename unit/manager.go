@@ -6,12 +6,50 @@ import (
 
 type UnitManager interface {
 	Load(string, UnitFile) error
+
+	// LoadRendered loads the named unit like Load, but writes the given
+	// rendering of the UnitFile to disk in its place. The Hash of the
+	// UnitFile itself is reported in the unit's state, so that the unit
+	// can still be matched with the UnitFile in the registry.
+	LoadRendered(name string, u UnitFile, rendered UnitFile) error
+
 	Unload(string)
 
+	// SetDropIn replaces the drop-in fleet maintains for the named unit
+	// with the given one, or removes it if nil. It takes effect when the
+	// unit is next loaded.
+	SetDropIn(string, *UnitFile) error
+
+	// SetEnvironment passes the given environment variables to the named
+	// unit, or stops passing any if nil. The unit sees them when it is
+	// next started.
+	SetEnvironment(string, map[string]string) error
+
 	TriggerStart(string)
 	TriggerStop(string)
 
+	// TriggerRestart restarts the named unit if it is running.
+	TriggerRestart(string)
+
 	Units() ([]string, error)
+
+	// ActiveUnits returns the names of the units systemd loaded from the
+	// manager's units directory and has not deactivated, including those
+	// whose unit files were removed since.
+	ActiveUnits() ([]string, error)
 	GetUnitStates(pkg.Set) (map[string]*UnitState, error)
 	GetUnitState(string) (*UnitState, error)
+
+	// GetUnitExit describes how the main process of the named service
+	// unit last exited.
+	GetUnitExit(string) (*UnitExit, error)
+}
+
+// A UnitStateNotifier is a UnitManager able to report changes to the states
+// of its units as they happen, sparing the UnitStateGenerator from polling.
+type UnitStateNotifier interface {
+	// NotifyUnitStates sends the name of each unit whose state changes to
+	// the given channel, until stop is closed. Notifications may be lost
+	// when the channel is full.
+	NotifyUnitStates(changed chan<- string, stop chan bool) error
 }
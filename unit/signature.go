@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+)
+
+// ReadSigningKey reads the ECDSA P-256 private key unit files are signed
+// with from the given PEM file, as generated with
+// `openssl ecparam -name prime256v1 -genkey -noout`
+func ReadSigningKey(file string) (*ecdsa.PrivateKey, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no private key found in %s", file)
+	}
+
+	var key *ecdsa.PrivateKey
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var pkey interface{}
+		pkey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if key, ok = pkey.(*ecdsa.PrivateKey); !ok {
+				return nil, fmt.Errorf("private key in %s is not an ECDSA key", file)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no private key found in %s", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing private key in %s: %v", file, err)
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("private key in %s is not a P-256 key", file)
+	}
+	return key, nil
+}
+
+// ReadTrustedKeys reads the ECDSA P-256 public keys trusted to sign unit
+// files from the given PEM file, which may hold any number of them, as
+// extracted from private keys with `openssl ec -pubout`
+func ReadTrustedKeys(file string) ([]*ecdsa.PublicKey, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*ecdsa.PublicKey
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing public key in %s: %v", file, err)
+		}
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("public key in %s is not an ECDSA P-256 key", file)
+		}
+		keys = append(keys, ecKey)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public keys found in %s", file)
+	}
+	return keys, nil
+}
+
+// ecdsaSignature is the ASN.1 encoding of an ECDSA signature, as made by
+// `openssl dgst -sha256 -sign`
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// Sign returns the signature of the SHA-256 digest of the contents of the
+// UnitFile made with the given key
+func (u *UnitFile) Sign(key *ecdsa.PrivateKey) (string, error) {
+	digest := sha256.Sum256(u.Bytes())
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	b, err := asn1.Marshal(ecdsaSignature{r, s})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// VerifySignature ensures that the given signature of the contents of the
+// UnitFile was made with any of the given keys
+func (u *UnitFile) VerifySignature(keys []*ecdsa.PublicKey, sig string) error {
+	if sig == "" {
+		return errors.New("unit file is not signed")
+	}
+	b, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return errors.New("malformed signature")
+	}
+	var es ecdsaSignature
+	if rest, err := asn1.Unmarshal(b, &es); err != nil || len(rest) != 0 || es.R == nil || es.S == nil {
+		return errors.New("malformed signature")
+	}
+
+	digest := sha256.Sum256(u.Bytes())
+	for _, key := range keys {
+		if ecdsa.Verify(key, digest[:], es.R, es.S) {
+			return nil
+		}
+	}
+	return errors.New("unit file signature not made with a trusted key")
+}
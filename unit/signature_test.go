@@ -0,0 +1,128 @@
+package unit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func writePEM(t *testing.T, blocks ...*pem.Block) string {
+	f, err := ioutil.TempFile("", "fleet-signature")
+	if err != nil {
+		t.Fatalf("Failed creating temporary file: %v", err)
+	}
+	defer f.Close()
+	for _, b := range blocks {
+		if err := pem.Encode(f, b); err != nil {
+			t.Fatalf("Failed writing PEM: %v", err)
+		}
+	}
+	return f.Name()
+}
+
+func newSigningKey(t *testing.T) (*ecdsa.PublicKey, *ecdsa.PrivateKey) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed generating key: %v", err)
+	}
+	return &priv.PublicKey, priv
+}
+
+func sameKey(a, b *ecdsa.PublicKey) bool {
+	return a.Curve == b.Curve && a.X.Cmp(b.X) == 0 && a.Y.Cmp(b.Y) == 0
+}
+
+func TestReadSigningKeys(t *testing.T) {
+	pub1, priv1 := newSigningKey(t)
+	pub2, _ := newSigningKey(t)
+
+	privDER, err := x509.MarshalECPrivateKey(priv1)
+	if err != nil {
+		t.Fatalf("Failed marshaling private key: %v", err)
+	}
+	privFile := writePEM(t, &pem.Block{Type: "EC PRIVATE KEY", Bytes: privDER})
+	defer os.Remove(privFile)
+
+	key, err := ReadSigningKey(privFile)
+	if err != nil {
+		t.Fatalf("Unexpected error reading signing key: %v", err)
+	}
+	if key.D.Cmp(priv1.D) != 0 || !sameKey(&key.PublicKey, pub1) {
+		t.Error("Unexpected signing key")
+	}
+
+	var blocks []*pem.Block
+	for _, pub := range []*ecdsa.PublicKey{pub1, pub2} {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatalf("Failed marshaling public key: %v", err)
+		}
+		blocks = append(blocks, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+	pubFile := writePEM(t, blocks...)
+	defer os.Remove(pubFile)
+
+	keys, err := ReadTrustedKeys(pubFile)
+	if err != nil {
+		t.Fatalf("Unexpected error reading trusted keys: %v", err)
+	}
+	if len(keys) != 2 || !sameKey(keys[0], pub1) || !sameKey(keys[1], pub2) {
+		t.Errorf("Unexpected trusted keys: %v", keys)
+	}
+
+	if _, err := ReadTrustedKeys(privFile); err == nil {
+		t.Error("Expected error reading trusted keys from private key file")
+	}
+	if _, err := ReadSigningKey(pubFile); err == nil {
+		t.Error("Expected error reading signing key from public key file")
+	}
+
+	// keys on other curves are refused
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(p384)
+	p384File := writePEM(t, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	defer os.Remove(p384File)
+	if _, err := ReadSigningKey(p384File); err == nil {
+		t.Error("Expected error reading P-384 signing key")
+	}
+}
+
+func TestUnitFileSignature(t *testing.T) {
+	pub, priv := newSigningKey(t)
+	other, _ := newSigningKey(t)
+
+	u, err := NewUnitFile("[Service]\nExecStart=/bin/foo\n")
+	if err != nil {
+		t.Fatalf("Unexpected error creating unit: %v", err)
+	}
+	sig, err := u.Sign(priv)
+	if err != nil {
+		t.Fatalf("Unexpected error signing unit: %v", err)
+	}
+
+	if err := u.VerifySignature([]*ecdsa.PublicKey{other, pub}, sig); err != nil {
+		t.Errorf("Unexpected error verifying signature: %v", err)
+	}
+	if err := u.VerifySignature([]*ecdsa.PublicKey{other}, sig); err == nil {
+		t.Error("Expected error verifying signature made with untrusted key")
+	}
+	if err := u.VerifySignature([]*ecdsa.PublicKey{pub}, ""); err == nil {
+		t.Error("Expected error verifying unsigned unit file")
+	}
+	if err := u.VerifySignature([]*ecdsa.PublicKey{pub}, "!!"); err == nil {
+		t.Error("Expected error verifying malformed signature")
+	}
+	if err := u.VerifySignature([]*ecdsa.PublicKey{pub}, "AAAA"); err == nil {
+		t.Error("Expected error verifying malformed signature")
+	}
+
+	tampered, _ := NewUnitFile("[Service]\nExecStart=/bin/evil\n")
+	if err := tampered.VerifySignature([]*ecdsa.PublicKey{pub}, sig); err == nil {
+		t.Error("Expected error verifying tampered unit file")
+	}
+}
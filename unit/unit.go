@@ -3,9 +3,11 @@ package unit
 import (
 	"bytes"
 	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/coreos/fleet/Godeps/_workspace/src/github.com/coreos/go-systemd/unit"
 )
@@ -121,6 +123,57 @@ func (u *UnitFile) Hash() Hash {
 	return Hash(sha1.Sum(u.Bytes()))
 }
 
+// ExpandVariables returns a copy of the UnitFile in which each ${NAME}
+// placeholder whose NAME is one of the given variables is replaced by the
+// variable's value, along with whether any placeholder was replaced. Other
+// placeholders are left for systemd to expand from the environment, and
+// systemd's $$ escape is kept as is. X-Fleet options are not expanded.
+func (u *UnitFile) ExpandVariables(vars map[string]string) (*UnitFile, bool) {
+	var expanded bool
+	opts := make([]*unit.UnitOption, len(u.Options))
+	for i, opt := range u.Options {
+		val := opt.Value
+		if opt.Section != "X-Fleet" {
+			var ok bool
+			val, ok = expandVariables(val, vars)
+			expanded = expanded || ok
+		}
+		opts[i] = &unit.UnitOption{Section: opt.Section, Name: opt.Name, Value: val}
+	}
+	if !expanded {
+		return u, false
+	}
+	return NewUnitFromOptions(opts), true
+}
+
+func expandVariables(s string, vars map[string]string) (string, bool) {
+	var buf bytes.Buffer
+	var expanded bool
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+		if s[i+1] == '$' {
+			buf.WriteString("$$")
+			i++
+			continue
+		}
+		if s[i+1] == '{' {
+			if end := strings.IndexByte(s[i+2:], '}'); end >= 0 {
+				if val, ok := vars[s[i+2:i+2+end]]; ok {
+					buf.WriteString(val)
+					expanded = true
+					i += end + 2
+					continue
+				}
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String(), expanded
+}
+
 // RecognizedUnitType determines whether or not the given unit name represents
 // a recognized unit type.
 func RecognizedUnitType(name string) bool {
@@ -155,6 +208,21 @@ func (h *Hash) Empty() bool {
 	return *h == Hash{}
 }
 
+// ParseHash parses the hex encoding of a Hash, as returned by its String
+// method
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return h, fmt.Errorf("invalid unit hash %q: %v", s, err)
+	}
+	if len(b) != len(h) {
+		return h, fmt.Errorf("invalid unit hash %q: must be %d bytes", s, len(h))
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
 // UnitState encodes the current state of a unit loaded into a fleet agent
 type UnitState struct {
 	LoadState   string
@@ -163,6 +231,24 @@ type UnitState struct {
 	MachineID   string
 	UnitHash    string
 	UnitName    string
+	Reason      string
+	Health      string
+
+	// Resources used by the unit, in hundredths of a core and MB
+	UsedCPUUnits int
+	UsedMemory   int
+}
+
+// UnitExit describes how the main process of a service unit last exited
+type UnitExit struct {
+	// Result is the result systemd recorded for the unit, e.g. "success"
+	// or "exit-code"
+	Result string
+	// Status is the exit status of the process, or the number of the
+	// signal that killed it
+	Status   int
+	Started  time.Time
+	Finished time.Time
 }
 
 func NewUnitState(loadState, activeState, subState, mID string) *UnitState {
@@ -194,6 +280,12 @@ func (nu UnitNameInfo) IsInstance() bool {
 	return len(nu.Instance) > 0
 }
 
+// IsTemplate returns a boolean indicating whether the UnitNameInfo appears to
+// be a Template unit, i.e. one that has no Instance of its own
+func (nu UnitNameInfo) IsTemplate() bool {
+	return len(nu.Template) > 0 && !nu.IsInstance()
+}
+
 // NewUnitNameInfo generates a UnitNameInfo from the given name. If the given string
 // is not a correct unit name, nil is returned.
 func NewUnitNameInfo(un string) *UnitNameInfo {
@@ -28,6 +28,15 @@ func TestUnitHash(t *testing.T) {
 	if !eh.Empty() {
 		t.Fatalf("Empty hash check failed: %v", eh.Empty())
 	}
+
+	if parsed, err := ParseHash(expectHashString); err != nil || parsed != gotHash {
+		t.Fatalf("ParseHash returned %v (%v), want %v", parsed, err, gotHash)
+	}
+	for _, bad := range []string{"", "1c6fb6f", "zz6fb6f3684bafb0c173d8b8b957ceff031180c1"} {
+		if _, err := ParseHash(bad); err == nil {
+			t.Errorf("ParseHash(%q) did not fail", bad)
+		}
+	}
 }
 
 func TestRecognizedUnitTypes(t *testing.T) {
@@ -87,7 +96,7 @@ func TestNewUnitState(t *testing.T) {
 
 	got := NewUnitState("ls", "as", "ss", "id")
 	if !reflect.DeepEqual(got, want) {
-		t.Fatalf("NewUnitState did not create a correct UnitState: got %s, want %s", got, want)
+		t.Fatalf("NewUnitState did not create a correct UnitState: got %#v, want %#v", got, want)
 	}
 
 }
@@ -135,6 +144,10 @@ func TestNamedUnit(t *testing.T) {
 		if i != tt.isinst {
 			t.Errorf("NewUnitNameInfo(%s).IsInstance returned %t, want %t", tt.name, i, tt.isinst)
 		}
+		wantTmpl := tt.tmpl != "" && !tt.isinst
+		if tmpl := u.IsTemplate(); tmpl != wantTmpl {
+			t.Errorf("NewUnitNameInfo(%s).IsTemplate returned %t, want %t", tt.name, tmpl, wantTmpl)
+		}
 	}
 
 	bad := []string{"foo", "bar@baz"}
@@ -362,3 +375,37 @@ func TestParseMultivalueLine(t *testing.T) {
 		}
 	}
 }
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{"FLEET_MACHINE_ID": "c31e44e1", "FLEET_UNIT_INSTANCE": "1"}
+	for i, tt := range []struct {
+		contents string
+		want     string
+		expanded bool
+	}{
+		{"[Service]\nExecStart=/bin/foo\n", "[Service]\nExecStart=/bin/foo\n", false},
+		{
+			"[Service]\nExecStart=/bin/foo --id ${FLEET_MACHINE_ID} --n=${FLEET_UNIT_INSTANCE}\n",
+			"[Service]\nExecStart=/bin/foo --id c31e44e1 --n=1\n",
+			true,
+		},
+		// unknown placeholders, escapes and X-Fleet options are left alone
+		{
+			"[Service]\nExecStart=/bin/foo ${HOME} $${FLEET_MACHINE_ID} ${FLEET_MACHINE_ID\n\n[X-Fleet]\nMachineID=${FLEET_MACHINE_ID}\n",
+			"[Service]\nExecStart=/bin/foo ${HOME} $${FLEET_MACHINE_ID} ${FLEET_MACHINE_ID\n\n[X-Fleet]\nMachineID=${FLEET_MACHINE_ID}\n",
+			false,
+		},
+	} {
+		u, err := NewUnitFile(tt.contents)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error creating unit: %v", i, err)
+		}
+		got, expanded := u.ExpandVariables(vars)
+		if expanded != tt.expanded {
+			t.Errorf("case %d: expected expanded=%t, got %t", i, tt.expanded, expanded)
+		}
+		if got.String() != tt.want {
+			t.Errorf("case %d: unexpected expansion:\n%s\nwant:\n%s", i, got, tt.want)
+		}
+	}
+}